@@ -0,0 +1,214 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// Address describes an Elastic IP address. Addresses in the
+// "standard" domain are identified by their public IP, and those
+// in the "vpc" domain by their allocation id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_Address.html for more details.
+type Address struct {
+	PublicIP                string `xml:"publicIp"`
+	AllocationId            string `xml:"allocationId"`
+	Domain                  string `xml:"domain"`
+	InstanceId              string `xml:"instanceId"`
+	AssociationId           string `xml:"associationId"`
+	NetworkInterfaceId      string `xml:"networkInterfaceId"`
+	NetworkInterfaceOwnerId string `xml:"networkInterfaceOwnerId"`
+	PrivateIPAddress        string `xml:"privateIpAddress"`
+	Tags                    []Tag  `xml:"tagSet>item"`
+}
+
+// AllocateAddressResp is the response to an AllocateAddress request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AllocateAddress.html for more details.
+type AllocateAddressResp struct {
+	RequestId    string `xml:"requestId"`
+	PublicIP     string `xml:"publicIp"`
+	Domain       string `xml:"domain"`
+	AllocationId string `xml:"allocationId"`
+}
+
+// AllocateAddress allocates an Elastic IP address. The domain is
+// either "standard", for use with EC2-Classic instances, or "vpc";
+// if empty, "standard" is used.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AllocateAddress.html for more details.
+func (ec2 *EC2) AllocateAddress(domain string) (resp *AllocateAddressResp, err error) {
+	params := makeParamsVPC("AllocateAddress")
+	if domain != "" {
+		params["Domain"] = domain
+	}
+	resp = &AllocateAddressResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AddressesResp is the response to an Addresses request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeAddresses.html for more details.
+type AddressesResp struct {
+	RequestId string    `xml:"requestId"`
+	Addresses []Address `xml:"addressesSet>item"`
+}
+
+// Addresses returns one or more Elastic IP addresses. All parameters
+// are optional, and if specified will limit the returned addresses
+// to the matching public IPs, allocation ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeAddresses.html for more details.
+func (ec2 *EC2) Addresses(publicIPs []string, allocationIds []string, filter *Filter) (resp *AddressesResp, err error) {
+	params := makeParamsVPC("DescribeAddresses")
+	for i, ip := range publicIPs {
+		params["PublicIp."+strconv.Itoa(i+1)] = ip
+	}
+	for i, id := range allocationIds {
+		params["AllocationId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &AddressesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AssociateAddress holds the options for an AssociateAddress request.
+//
+// Addresses in the "standard" domain are given by PublicIP and
+// associated with InstanceId. Addresses in the "vpc" domain are
+// given by AllocationId and associated with either InstanceId, in
+// which case the primary network interface of the instance is used,
+// or NetworkInterfaceId. PrivateIPAddress optionally selects one of
+// the private IP addresses of the interface; by default the primary
+// one is used.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateAddress.html for more details.
+type AssociateAddress struct {
+	InstanceId         string
+	PublicIP           string
+	AllocationId       string
+	NetworkInterfaceId string
+	PrivateIPAddress   string
+
+	// AllowReassociation allows an address in the "vpc" domain
+	// that is already associated to be associated again.
+	AllowReassociation bool
+}
+
+// AssociateAddressResp is the response to an AssociateAddress request.
+// AssociationId is only set for addresses in the "vpc" domain.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateAddress.html for more details.
+type AssociateAddressResp struct {
+	RequestId     string `xml:"requestId"`
+	Return        bool   `xml:"return"`
+	AssociationId string `xml:"associationId"`
+}
+
+// AssociateAddress associates an Elastic IP address with an instance
+// or a network interface.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateAddress.html for more details.
+func (ec2 *EC2) AssociateAddress(options *AssociateAddress) (resp *AssociateAddressResp, err error) {
+	params := makeParamsVPC("AssociateAddress")
+	if options.InstanceId != "" {
+		params["InstanceId"] = options.InstanceId
+	}
+	if options.PublicIP != "" {
+		params["PublicIp"] = options.PublicIP
+	}
+	if options.AllocationId != "" {
+		params["AllocationId"] = options.AllocationId
+	}
+	if options.NetworkInterfaceId != "" {
+		params["NetworkInterfaceId"] = options.NetworkInterfaceId
+	}
+	if options.PrivateIPAddress != "" {
+		params["PrivateIpAddress"] = options.PrivateIPAddress
+	}
+	if options.AllowReassociation {
+		params["AllowReassociation"] = "true"
+	}
+	resp = &AssociateAddressResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DisassociateAddress disassociates the Elastic IP address in the
+// "standard" domain with the given public IP from its instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateAddress.html for more details.
+func (ec2 *EC2) DisassociateAddress(publicIP string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("DisassociateAddress")
+	params["PublicIp"] = publicIP
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DisassociateAddressVPC removes the association of an Elastic IP
+// address in the "vpc" domain with the given association id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateAddress.html for more details.
+func (ec2 *EC2) DisassociateAddressVPC(associationId string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("DisassociateAddress")
+	params["AssociationId"] = associationId
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReleaseAddress releases the Elastic IP address in the "standard"
+// domain with the given public IP, disassociating it if necessary.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReleaseAddress.html for more details.
+func (ec2 *EC2) ReleaseAddress(publicIP string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("ReleaseAddress")
+	params["PublicIp"] = publicIP
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReleaseAddressVPC releases the Elastic IP address in the "vpc"
+// domain with the given allocation id, which must not be associated.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReleaseAddress.html for more details.
+func (ec2 *EC2) ReleaseAddressVPC(allocationId string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("ReleaseAddress")
+	params["AllocationId"] = allocationId
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
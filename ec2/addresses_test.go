@@ -0,0 +1,399 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/aws"
+	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/ec2/ec2test"
+)
+
+// Elastic IP address tests with example responses
+
+func (s *S) TestAllocateAddressExample(c *C) {
+	testServer.Response(200, nil, AllocateAddressExample)
+
+	resp, err := s.ec2.AllocateAddress("vpc")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AllocateAddress"})
+	c.Assert(req.Form["Domain"], DeepEquals, []string{"vpc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp, DeepEquals, &ec2.AllocateAddressResp{
+		RequestId:    "59dbff89-35bd-4eac-99ed-be587EXAMPLE",
+		PublicIP:     "198.51.100.1",
+		Domain:       "vpc",
+		AllocationId: "eipalloc-5723d13e",
+	})
+}
+
+func (s *S) TestAddressesExample(c *C) {
+	testServer.Response(200, nil, DescribeAddressesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("domain", "vpc")
+	resp, err := s.ec2.Addresses([]string{"203.0.113.41"}, []string{"eipalloc-08229861"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeAddresses"})
+	c.Assert(req.Form["PublicIp.1"], DeepEquals, []string{"203.0.113.41"})
+	c.Assert(req.Form["AllocationId.1"], DeepEquals, []string{"eipalloc-08229861"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"domain"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"vpc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "f7de5e98-491a-4c19-a92d-908d6EXAMPLE")
+	c.Assert(resp.Addresses, DeepEquals, []ec2.Address{{
+		PublicIP:                "203.0.113.41",
+		AllocationId:            "eipalloc-08229861",
+		Domain:                  "vpc",
+		InstanceId:              "i-64600030",
+		AssociationId:           "eipassoc-f0229899",
+		NetworkInterfaceId:      "eni-ef229886",
+		NetworkInterfaceOwnerId: "053230519467",
+		PrivateIPAddress:        "10.0.0.228",
+	}, {
+		PublicIP: "198.51.100.2",
+		Domain:   "standard",
+	}})
+}
+
+func (s *S) TestAssociateAddressExample(c *C) {
+	testServer.Response(200, nil, AssociateAddressExample)
+
+	resp, err := s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId:       "eipalloc-5723d13e",
+		NetworkInterfaceId: "eni-ef229886",
+		PrivateIPAddress:   "10.0.0.228",
+		AllowReassociation: true,
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AssociateAddress"})
+	c.Assert(req.Form["AllocationId"], DeepEquals, []string{"eipalloc-5723d13e"})
+	c.Assert(req.Form["NetworkInterfaceId"], DeepEquals, []string{"eni-ef229886"})
+	c.Assert(req.Form["PrivateIpAddress"], DeepEquals, []string{"10.0.0.228"})
+	c.Assert(req.Form["AllowReassociation"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["InstanceId"], IsNil)
+	c.Assert(req.Form["PublicIp"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Return, Equals, true)
+	c.Assert(resp.AssociationId, Equals, "eipassoc-fc5ca095")
+}
+
+func (s *S) TestDisassociateAddressExample(c *C) {
+	testServer.Response(200, nil, DisassociateAddressExample)
+
+	resp, err := s.ec2.DisassociateAddress("198.51.100.2")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DisassociateAddress"})
+	c.Assert(req.Form["PublicIp"], DeepEquals, []string{"198.51.100.2"})
+	c.Assert(req.Form["AssociationId"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestDisassociateAddressVPCExample(c *C) {
+	testServer.Response(200, nil, DisassociateAddressExample)
+
+	resp, err := s.ec2.DisassociateAddressVPC("eipassoc-fc5ca095")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DisassociateAddress"})
+	c.Assert(req.Form["AssociationId"], DeepEquals, []string{"eipassoc-fc5ca095"})
+	c.Assert(req.Form["PublicIp"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestReleaseAddressExample(c *C) {
+	testServer.Response(200, nil, ReleaseAddressExample)
+
+	resp, err := s.ec2.ReleaseAddress("198.51.100.2")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ReleaseAddress"})
+	c.Assert(req.Form["PublicIp"], DeepEquals, []string{"198.51.100.2"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestReleaseAddressVPCExample(c *C) {
+	testServer.Response(200, nil, ReleaseAddressExample)
+
+	resp, err := s.ec2.ReleaseAddressVPC("eipalloc-5723d13e")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ReleaseAddress"})
+	c.Assert(req.Form["AllocationId"], DeepEquals, []string{"eipalloc-5723d13e"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+// Elastic IP address tests run against either a local test server
+// or live on EC2.
+
+func (s *ServerTests) TestVPCAddresses(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.4.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	defer s.deleteVPCs(c, []string{vpcId})
+
+	subResp := s.createSubnet(c, vpcId, "10.4.1.0/24", "")
+	subId := subResp.Subnet.Id
+	defer s.deleteSubnets(c, []string{subId})
+
+	ips := []ec2.PrivateIP{
+		{Address: "10.4.1.10", IsPrimary: true},
+		{Address: "10.4.1.11", IsPrimary: false},
+	}
+	nicResp, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+		SubnetId:   subId,
+		PrivateIPs: ips,
+	})
+	c.Assert(err, IsNil)
+	nicId := nicResp.NetworkInterface.Id
+	defer s.ec2.DeleteNetworkInterface(nicId)
+
+	alloc, err := s.ec2.AllocateAddress("vpc")
+	c.Assert(err, IsNil)
+	defer s.ec2.ReleaseAddressVPC(alloc.AllocationId)
+	c.Check(alloc.Domain, Equals, "vpc")
+	c.Check(alloc.AllocationId, Matches, "eipalloc-.+")
+
+	assoc, err := s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId:       alloc.AllocationId,
+		NetworkInterfaceId: nicId,
+		PrivateIPAddress:   "10.4.1.11",
+	})
+	c.Assert(err, IsNil)
+	c.Check(assoc.Return, Equals, true)
+	c.Check(assoc.AssociationId, Matches, "eipassoc-.+")
+
+	resp, err := s.ec2.Addresses(nil, []string{alloc.AllocationId}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Addresses, HasLen, 1)
+	addr := resp.Addresses[0]
+	c.Check(addr.PublicIP, Equals, alloc.PublicIP)
+	c.Check(addr.AssociationId, Equals, assoc.AssociationId)
+	c.Check(addr.NetworkInterfaceId, Equals, nicId)
+	c.Check(addr.PrivateIPAddress, Equals, "10.4.1.11")
+
+	f := ec2.NewFilter()
+	f.Add("association.public-ip", alloc.PublicIP)
+	nics, err := s.ec2.NetworkInterfaces(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(nics.Interfaces, HasLen, 1)
+	nic := nics.Interfaces[0]
+	c.Check(nic.Association, Equals, ec2.NetworkInterfaceAssociation{})
+	c.Assert(nic.PrivateIPs, HasLen, 2)
+	for _, ip := range nic.PrivateIPs {
+		if ip.Address != "10.4.1.11" {
+			continue
+		}
+		c.Check(ip.Association.PublicIP, Equals, alloc.PublicIP)
+		c.Check(ip.Association.AllocationId, Equals, alloc.AllocationId)
+		c.Check(ip.Association.AssociationId, Equals, assoc.AssociationId)
+	}
+
+	// The address cannot be moved without allowing reassociation.
+	_, err = s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId:       alloc.AllocationId,
+		NetworkInterfaceId: nicId,
+	})
+	c.Check(errorCode(err), Equals, "Resource.AlreadyAssociated")
+
+	_, err = s.ec2.ReleaseAddressVPC(alloc.AllocationId)
+	c.Check(errorCode(err), Equals, "InvalidIPAddress.InUse")
+
+	_, err = s.ec2.DisassociateAddressVPC(assoc.AssociationId)
+	c.Assert(err, IsNil)
+
+	f = ec2.NewFilter()
+	f.Add("allocation-id", alloc.AllocationId)
+	resp, err = s.ec2.Addresses(nil, nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Addresses, HasLen, 1)
+	c.Check(resp.Addresses[0].AssociationId, Equals, "")
+	c.Check(resp.Addresses[0].NetworkInterfaceId, Equals, "")
+
+	_, err = s.ec2.ReleaseAddressVPC(alloc.AllocationId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.Addresses(nil, []string{alloc.AllocationId}, nil)
+	c.Check(errorCode(err), Equals, "InvalidAllocationID.NotFound")
+}
+
+func (s *LocalServerSuite) TestStandardAddresses(c *C) {
+	// Standard addresses can only be associated with EC2-Classic
+	// instances, so use a server without a default VPC.
+	srv, err := ec2test.NewServer()
+	c.Assert(err, IsNil)
+	defer srv.Quit()
+	addTestImages(srv)
+	e := ec2.New(s.srv.auth, aws.Region{EC2Endpoint: srv.URL(), Sign: aws.SignV2})
+
+	alloc, err := e.AllocateAddress("")
+	c.Assert(err, IsNil)
+	c.Check(alloc.Domain, Equals, "standard")
+	c.Check(alloc.AllocationId, Equals, "")
+
+	list, err := e.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		MinCount:     2,
+	})
+	c.Assert(err, IsNil)
+	id0 := list.Instances[0].InstanceId
+	id1 := list.Instances[1].InstanceId
+	defer terminateInstances(c, e, []string{id0, id1})
+
+	_, err = e.AssociateAddress(&ec2.AssociateAddress{
+		PublicIP:   alloc.PublicIP,
+		InstanceId: id0,
+	})
+	c.Assert(err, IsNil)
+	assertInstanceIP(c, e, id0, alloc.PublicIP)
+
+	// Standard addresses are moved silently.
+	_, err = e.AssociateAddress(&ec2.AssociateAddress{
+		PublicIP:   alloc.PublicIP,
+		InstanceId: id1,
+	})
+	c.Assert(err, IsNil)
+	assertInstanceIP(c, e, id1, alloc.PublicIP)
+	insts, err := e.Instances([]string{id0}, nil)
+	c.Assert(err, IsNil)
+	c.Check(insts.Reservations[0].Instances[0].IPAddress, Not(Equals), alloc.PublicIP)
+
+	f := ec2.NewFilter()
+	f.Add("instance-id", id1)
+	resp, err := e.Addresses(nil, nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Addresses, DeepEquals, []ec2.Address{{
+		PublicIP:   alloc.PublicIP,
+		Domain:     "standard",
+		InstanceId: id1,
+	}})
+
+	_, err = e.DisassociateAddress(alloc.PublicIP)
+	c.Assert(err, IsNil)
+	resp, err = e.Addresses([]string{alloc.PublicIP}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.Addresses[0].InstanceId, Equals, "")
+
+	// Releasing a standard address disassociates it.
+	_, err = e.AssociateAddress(&ec2.AssociateAddress{
+		PublicIP:   alloc.PublicIP,
+		InstanceId: id0,
+	})
+	c.Assert(err, IsNil)
+	_, err = e.ReleaseAddress(alloc.PublicIP)
+	c.Assert(err, IsNil)
+	insts, err = e.Instances([]string{id0}, nil)
+	c.Assert(err, IsNil)
+	c.Check(insts.Reservations[0].Instances[0].IPAddress, Not(Equals), alloc.PublicIP)
+	_, err = e.Addresses([]string{alloc.PublicIP}, nil, nil)
+	c.Check(errorCode(err), Equals, "InvalidAddress.NotFound")
+}
+
+func (s *LocalServerSuite) TestVPCAddressInstance(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.5.0.0/16", "")
+	c.Assert(err, IsNil)
+	subResp := s.createSubnet(c, vpcResp.VPC.Id, "10.5.1.0/24", "")
+	list, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		SubnetId:     subResp.Subnet.Id,
+	})
+	c.Assert(err, IsNil)
+	instId := list.Instances[0].InstanceId
+	nicId := list.Instances[0].NetworkInterfaces[0].Id
+
+	alloc, err := s.ec2.AllocateAddress("vpc")
+	c.Assert(err, IsNil)
+
+	_, err = s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		PublicIP:   alloc.PublicIP,
+		InstanceId: instId,
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId:       alloc.AllocationId,
+		InstanceId:         instId,
+		NetworkInterfaceId: nicId,
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId:     alloc.AllocationId,
+		InstanceId:       instId,
+		PrivateIPAddress: "10.5.1.99",
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+
+	assoc, err := s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId: alloc.AllocationId,
+		InstanceId:   instId,
+	})
+	c.Assert(err, IsNil)
+	assertInstanceIP(c, s.ec2, instId, alloc.PublicIP)
+	insts, err := s.ec2.Instances([]string{instId}, nil)
+	c.Assert(err, IsNil)
+	nic := insts.Reservations[0].Instances[0].NetworkInterfaces[0]
+	c.Check(nic.Association.PublicIP, Equals, alloc.PublicIP)
+	c.Check(nic.Association.AssociationId, Equals, assoc.AssociationId)
+	c.Check(nic.PrivateIPs[0].Association, Equals, nic.Association)
+
+	// A second address replaces the first one when allowed.
+	other, err := s.ec2.AllocateAddress("vpc")
+	c.Assert(err, IsNil)
+	_, err = s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId:       other.AllocationId,
+		NetworkInterfaceId: nicId,
+	})
+	c.Check(errorCode(err), Equals, "Resource.AlreadyAssociated")
+	_, err = s.ec2.AssociateAddress(&ec2.AssociateAddress{
+		AllocationId:       other.AllocationId,
+		NetworkInterfaceId: nicId,
+		AllowReassociation: true,
+	})
+	c.Assert(err, IsNil)
+	assertInstanceIP(c, s.ec2, instId, other.PublicIP)
+	resp, err := s.ec2.Addresses(nil, []string{alloc.AllocationId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.Addresses[0].AssociationId, Equals, "")
+
+	// Terminating the instance disassociates its addresses.
+	terminateInstances(c, s.ec2, []string{instId})
+	resp, err = s.ec2.Addresses(nil, []string{other.AllocationId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.Addresses[0].InstanceId, Equals, "")
+	_, err = s.ec2.ReleaseAddressVPC(other.AllocationId)
+	c.Assert(err, IsNil)
+}
+
+func assertInstanceIP(c *C, e *ec2.EC2, instId, ip string) {
+	f := ec2.NewFilter()
+	f.Add("ip-address", ip)
+	insts, err := e.Instances(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(insts.Reservations, HasLen, 1)
+	c.Assert(insts.Reservations[0].Instances, HasLen, 1)
+	c.Check(insts.Reservations[0].Instances[0].InstanceId, Equals, instId)
+	c.Check(insts.Reservations[0].Instances[0].IPAddress, Equals, ip)
+}
@@ -0,0 +1,123 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// DHCPOptions describes a set of DHCP options, which can be
+// associated with VPCs.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DhcpOptions.html for more details.
+type DHCPOptions struct {
+	Id             string              `xml:"dhcpOptionsId"`
+	Configurations []DHCPConfiguration `xml:"dhcpConfigurationSet>item"`
+	Tags           []Tag               `xml:"tagSet>item"`
+}
+
+// DHCPConfiguration holds the values of a single DHCP option. Key is
+// one of "domain-name", "domain-name-servers", "ntp-servers",
+// "netbios-name-servers" or "netbios-node-type".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DhcpConfiguration.html for more details.
+type DHCPConfiguration struct {
+	Key    string   `xml:"key"`
+	Values []string `xml:"valueSet>item>value"`
+}
+
+// CreateDHCPOptionsResp is the response to a CreateDHCPOptions
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateDhcpOptions.html for more details.
+type CreateDHCPOptionsResp struct {
+	RequestId   string      `xml:"requestId"`
+	DHCPOptions DHCPOptions `xml:"dhcpOptions"`
+}
+
+// CreateDHCPOptions creates a set of DHCP options with the given
+// configurations.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateDhcpOptions.html for more details.
+func (ec2 *EC2) CreateDHCPOptions(configs []DHCPConfiguration) (resp *CreateDHCPOptionsResp, err error) {
+	params := makeParamsVPC("CreateDhcpOptions")
+	for i, config := range configs {
+		prefix := "DhcpConfiguration." + strconv.Itoa(i+1)
+		params[prefix+".Key"] = config.Key
+		for j, value := range config.Values {
+			params[prefix+".Value."+strconv.Itoa(j+1)] = value
+		}
+	}
+	resp = &CreateDHCPOptionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteDHCPOptions deletes the set of DHCP options with the given
+// id, which must not be associated with any VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteDhcpOptions.html for more details.
+func (ec2 *EC2) DeleteDHCPOptions(id string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("DeleteDhcpOptions")
+	params["DhcpOptionsId"] = id
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DHCPOptionsResp is the response to a DHCPOptions request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeDhcpOptions.html for more details.
+type DHCPOptionsResp struct {
+	RequestId   string        `xml:"requestId"`
+	DHCPOptions []DHCPOptions `xml:"dhcpOptionsSet>item"`
+}
+
+// DHCPOptions describes one or more sets of DHCP options. Both
+// parameters are optional, and if specified will limit the returned
+// sets to the matching ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeDhcpOptions.html for more details.
+func (ec2 *EC2) DHCPOptions(ids []string, filter *Filter) (resp *DHCPOptionsResp, err error) {
+	params := makeParamsVPC("DescribeDhcpOptions")
+	for i, id := range ids {
+		params["DhcpOptionsId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &DHCPOptionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AssociateDHCPOptions associates the set of DHCP options with the
+// given id with a VPC. If id is "default", the VPC uses the default
+// DHCP options.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateDhcpOptions.html for more details.
+func (ec2 *EC2) AssociateDHCPOptions(id, vpcId string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("AssociateDhcpOptions")
+	params["DhcpOptionsId"] = id
+	params["VpcId"] = vpcId
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
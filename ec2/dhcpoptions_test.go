@@ -0,0 +1,159 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// DHCP options tests with example responses
+
+func (s *S) TestCreateDHCPOptionsExample(c *C) {
+	testServer.Response(200, nil, CreateDhcpOptionsExample)
+
+	configs := []ec2.DHCPConfiguration{
+		{Key: "domain-name", Values: []string{"example.com"}},
+		{Key: "domain-name-servers", Values: []string{"10.2.5.1", "10.2.5.2"}},
+	}
+	resp, err := s.ec2.CreateDHCPOptions(configs)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateDhcpOptions"})
+	c.Assert(req.Form["DhcpConfiguration.1.Key"], DeepEquals, []string{"domain-name"})
+	c.Assert(req.Form["DhcpConfiguration.1.Value.1"], DeepEquals, []string{"example.com"})
+	c.Assert(req.Form["DhcpConfiguration.2.Key"], DeepEquals, []string{"domain-name-servers"})
+	c.Assert(req.Form["DhcpConfiguration.2.Value.1"], DeepEquals, []string{"10.2.5.1"})
+	c.Assert(req.Form["DhcpConfiguration.2.Value.2"], DeepEquals, []string{"10.2.5.2"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.DHCPOptions, DeepEquals, ec2.DHCPOptions{
+		Id:             "dopt-7a8b9c2d",
+		Configurations: configs,
+	})
+}
+
+func (s *S) TestDHCPOptionsExample(c *C) {
+	testServer.Response(200, nil, DescribeDhcpOptionsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key", "domain-name")
+	resp, err := s.ec2.DHCPOptions([]string{"dopt-7a8b9c2d"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeDhcpOptions"})
+	c.Assert(req.Form["DhcpOptionsId.1"], DeepEquals, []string{"dopt-7a8b9c2d"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"key"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"domain-name"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.DHCPOptions, DeepEquals, []ec2.DHCPOptions{{
+		Id: "dopt-7a8b9c2d",
+		Configurations: []ec2.DHCPConfiguration{
+			{Key: "domain-name", Values: []string{"example.com"}},
+		},
+	}})
+}
+
+func (s *S) TestAssociateDHCPOptionsExample(c *C) {
+	testServer.Response(200, nil, AssociateDhcpOptionsExample)
+
+	resp, err := s.ec2.AssociateDHCPOptions("dopt-7a8b9c2d", "vpc-1a2b3c4d")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AssociateDhcpOptions"})
+	c.Assert(req.Form["DhcpOptionsId"], DeepEquals, []string{"dopt-7a8b9c2d"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-1a2b3c4d"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestDeleteDHCPOptionsExample(c *C) {
+	testServer.Response(200, nil, AssociateDhcpOptionsExample)
+
+	_, err := s.ec2.DeleteDHCPOptions("dopt-7a8b9c2d")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteDhcpOptions"})
+	c.Assert(req.Form["DhcpOptionsId"], DeepEquals, []string{"dopt-7a8b9c2d"})
+	c.Assert(err, IsNil)
+}
+
+// DHCP options tests run against either a local test server or live
+// on EC2.
+
+func (s *ServerTests) TestDHCPOptions(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.12.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	defer s.deleteVPCs(c, []string{vpcId})
+	c.Check(vpcResp.VPC.DHCPOptionsId, Not(Equals), "")
+
+	_, err = s.ec2.CreateDHCPOptions(nil)
+	c.Check(errorCode(err), Equals, "MissingParameter")
+
+	configs := []ec2.DHCPConfiguration{
+		{Key: "domain-name", Values: []string{"example.com"}},
+		{Key: "ntp-servers", Values: []string{"10.12.0.5", "10.12.0.6"}},
+	}
+	created, err := s.ec2.CreateDHCPOptions(configs)
+	c.Assert(err, IsNil)
+	id := created.DHCPOptions.Id
+	c.Check(id, Matches, "dopt-.+")
+	c.Check(created.DHCPOptions.Configurations, DeepEquals, configs)
+
+	_, err = s.ec2.AssociateDHCPOptions(id, vpcId)
+	c.Assert(err, IsNil)
+
+	f := ec2.NewFilter()
+	f.Add("dhcp-options-id", id)
+	vpcs, err := s.ec2.VPCs([]string{vpcId}, f)
+	c.Assert(err, IsNil)
+	c.Assert(vpcs.VPCs, HasLen, 1)
+
+	_, err = s.ec2.DeleteDHCPOptions(id)
+	c.Check(errorCode(err), Equals, "DependencyViolation")
+
+	f = ec2.NewFilter()
+	f.Add("value", "10.12.0.6")
+	resp, err := s.ec2.DHCPOptions([]string{id}, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.DHCPOptions, HasLen, 1)
+	c.Check(resp.DHCPOptions[0].Id, Equals, id)
+
+	// Associating "default" leaves the VPC without DHCP options.
+	_, err = s.ec2.AssociateDHCPOptions("default", vpcId)
+	c.Assert(err, IsNil)
+	vpcs, err = s.ec2.VPCs([]string{vpcId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(vpcs.VPCs[0].DHCPOptionsId, Equals, "default")
+
+	_, err = s.ec2.DeleteDHCPOptions(id)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.DHCPOptions([]string{id}, nil)
+	c.Check(errorCode(err), Equals, "InvalidDhcpOptionID.NotFound")
+}
+
+func (s *LocalServerSuite) TestDefaultDHCPOptions(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.13.0.0/16", "")
+	c.Assert(err, IsNil)
+
+	resp, err := s.ec2.DHCPOptions([]string{vpcResp.VPC.DHCPOptionsId}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.DHCPOptions, HasLen, 1)
+	c.Check(resp.DHCPOptions[0].Configurations, DeepEquals, []ec2.DHCPConfiguration{
+		{Key: "domain-name", Values: []string{"ec2.internal"}},
+		{Key: "domain-name-servers", Values: []string{"AmazonProvidedDNS"}},
+	})
+
+	_, err = s.ec2.CreateDHCPOptions([]ec2.DHCPConfiguration{{Key: "bogus", Values: []string{"x"}}})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+}
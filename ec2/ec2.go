@@ -14,12 +14,16 @@ import (
 	"encoding/hex"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/amz.v1/aws"
@@ -34,6 +38,11 @@ const (
 
 	// AWS API version used for VPC-related calls.
 	vpcAPIVersion = "2013-10-15"
+
+	// currentAPIVersion is the AWS API version used for calls
+	// that are not available in the older versions, such as
+	// volume modification.
+	currentAPIVersion = "2016-11-15"
 )
 
 // The EC2 type encapsulates operations with a specific EC2 region.
@@ -127,8 +136,53 @@ type xmlErrors struct {
 
 var timeNow = time.Now
 
+var (
+	attempts        = defaultAttempts
+	defaultAttempts = aws.AttemptStrategy{
+		Min:   5,
+		Total: 5 * time.Second,
+		Delay: 200 * time.Millisecond,
+	}
+)
+
+// RetryAttempts sets whether EC2 requests failing because of
+// temporary conditions, such as internal errors, throttling or
+// connections dropped, may be retried. Only requests that are safe
+// to repeat are retried: those describing resources, and those
+// made idempotent by a client token. It should not be called
+// while operations are in progress.
+func RetryAttempts(retry bool) {
+	if retry {
+		attempts = defaultAttempts
+	} else {
+		attempts = aws.AttemptStrategy{}
+	}
+}
+
 // resp = response structure that will get inflated by XML unmarshaling.
-func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
+func (ec2 *EC2) query(params map[string]string, resp interface{}) (err error) {
+	for attempt := attempts.Start(); attempt.Next(); {
+		err = ec2.run(params, resp)
+		if !shouldRetry(err) || !idempotent(params) {
+			break
+		}
+	}
+	return err
+}
+
+// idempotent reports whether the request with the given parameters
+// may be sent more than once without changing its outcome. A request
+// that failed half way through may have been carried out, so only
+// these are retried.
+func idempotent(params map[string]string) bool {
+	action := params["Action"]
+	return strings.HasPrefix(action, "Describe") ||
+		strings.HasPrefix(action, "Get") ||
+		params["ClientToken"] != ""
+}
+
+// run sends a single request with the given parameters.
+func (ec2 *EC2) run(params map[string]string, resp interface{}) error {
 
 	req, err := http.NewRequest("GET", ec2.Region.EC2Endpoint, nil)
 	if err != nil {
@@ -159,7 +213,40 @@ func (ec2 *EC2) query(params map[string]string, resp interface{}) error {
 	if r.StatusCode != 200 {
 		return buildError(r)
 	}
-	return xml.NewDecoder(r.Body).Decode(resp)
+	// Read the whole body before decoding it, so that a response
+	// cut short is reported as an error that may be retried.
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, resp)
+}
+
+// shouldRetry returns whether a request that failed with err may
+// be sent again.
+func shouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch err {
+	case io.ErrUnexpectedEOF, io.EOF:
+		return true
+	}
+	switch e := err.(type) {
+	case *url.Error:
+		return shouldRetry(e.Err)
+	case *net.OpError:
+		switch e.Op {
+		case "read", "write":
+			return true
+		}
+	case *Error:
+		switch e.Code {
+		case "InternalError", "Unavailable", "RequestLimitExceeded":
+			return true
+		}
+	}
+	return false
 }
 
 func multimap(p map[string]string) url.Values {
@@ -193,6 +280,10 @@ func makeParamsVPC(action string) map[string]string {
 	return makeParamsWithVersion(action, vpcAPIVersion)
 }
 
+func makeParamsCurrent(action string) map[string]string {
+	return makeParamsWithVersion(action, currentAPIVersion)
+}
+
 func makeParamsWithVersion(action, version string) map[string]string {
 	params := make(map[string]string)
 	params["Action"] = action
@@ -229,15 +320,24 @@ func addParamsList(params map[string]string, label string, ids []string) {
 // of IP addresses from within the subnet range and sets them as
 // secondary IPs. The number of IP addresses that can be assigned to a
 // network interface varies by instance type.
+//
+// IPv6Addresses and IPv6AddressCount assign IPv6 addresses from the
+// subnet's IPv6 CIDR block to a created interface, like PrivateIPs
+// and SecondaryPrivateIPCount do for private IPv4 addresses.
+//
+// RunNetworkInterface also describes the network interfaces of launch
+// template versions.
 type RunNetworkInterface struct {
-	Id                      string
-	DeviceIndex             int
-	SubnetId                string
-	Description             string
-	PrivateIPs              []PrivateIP
-	SecurityGroupIds        []string
-	DeleteOnTermination     bool
-	SecondaryPrivateIPCount int
+	Id                      string      `xml:"networkInterfaceId"`
+	DeviceIndex             int         `xml:"deviceIndex"`
+	SubnetId                string      `xml:"subnetId"`
+	Description             string      `xml:"description"`
+	PrivateIPs              []PrivateIP `xml:"privateIpAddressesSet>item"`
+	SecurityGroupIds        []string    `xml:"groupSet>item"`
+	DeleteOnTermination     bool        `xml:"deleteOnTermination"`
+	SecondaryPrivateIPCount int         `xml:"secondaryPrivateIpAddressCount"`
+	IPv6Addresses           []string    `xml:"ipv6AddressesSet>item>ipv6Address"`
+	IPv6AddressCount        int         `xml:"ipv6AddressCount"`
 }
 
 // The RunInstances type encapsulates options for the respective request in EC2.
@@ -262,6 +362,30 @@ type RunInstances struct {
 	PrivateIPAddress      string
 	BlockDeviceMappings   []BlockDeviceMapping
 	NetworkInterfaces     []RunNetworkInterface
+
+	// PartitionNumber, if not zero, selects the partition of a
+	// "partition" placement group the instances are launched in.
+	PartitionNumber int
+
+	// Tenancy is "default", "dedicated", for instances that run on
+	// hardware dedicated to the account, or "host", for instances
+	// that run on a Dedicated Host. HostId selects the host and
+	// Affinity, "default" or "host", whether a stopped instance
+	// restarts on the same host.
+	Tenancy  string
+	HostId   string
+	Affinity string
+
+	// LaunchTemplate, if set, selects a launch template holding
+	// the parameters of the instances. The other fields override
+	// the parameters of the template, so ImageId and InstanceType
+	// may be left empty.
+	LaunchTemplate *LaunchTemplateSpecification
+
+	// TagSpecifications holds the tags to apply to the launched
+	// instances ("instance") and to the volumes ("volume") and
+	// network interfaces ("network-interface") created for them.
+	TagSpecifications []TagSpecification
 }
 
 // Response to a RunInstances request.
@@ -290,16 +414,28 @@ type Instance struct {
 	VPCId              string             `xml:"vpcId"`
 	SourceDestCheck    bool               `xml:"sourceDestCheck"`
 	KeyName            string             `xml:"keyName"`
+	LaunchTime         string             `xml:"launchTime"`
 	AMILaunchIndex     int                `xml:"amiLaunchIndex"`
 	Hypervisor         string             `xml:"hypervisor"`
 	VirtType           string             `xml:"virtualizationType"`
 	Monitoring         string             `xml:"monitoring>state"`
 	AvailZone          string             `xml:"placement>availabilityZone"`
 	PlacementGroupName string             `xml:"placement>groupName"`
+	PartitionNumber    int                `xml:"placement>partitionNumber"`
+	Tenancy            string             `xml:"placement>tenancy"`
+	HostId             string             `xml:"placement>hostId"`
+	Affinity           string             `xml:"placement>affinity"`
 	State              InstanceState      `xml:"instanceState"`
 	Tags               []Tag              `xml:"tagSet>item"`
 	SecurityGroups     []SecurityGroup    `xml:"groupSet>item"`
 	NetworkInterfaces  []NetworkInterface `xml:"networkInterfaceSet>item"`
+
+	BlockDeviceMappings []InstanceBlockDeviceMapping `xml:"blockDeviceMapping>item"`
+
+	// InstanceLifecycle is "spot" for spot instances, which were
+	// launched to fulfil the spot request SpotInstanceRequestId.
+	InstanceLifecycle     string `xml:"instanceLifecycle"`
+	SpotInstanceRequestId string `xml:"spotInstanceRequestId"`
 }
 
 // RunInstances starts new instances in EC2.
@@ -310,8 +446,7 @@ type Instance struct {
 // See http://goo.gl/Mcm3b for more details.
 func (ec2 *EC2) RunInstances(options *RunInstances) (resp *RunInstancesResp, err error) {
 	params := prepareRunParams(*options)
-	params["ImageId"] = options.ImageId
-	params["InstanceType"] = options.InstanceType
+	addLaunchParams(params, "", options)
 	var min, max int
 	if options.MinCount == 0 && options.MaxCount == 0 {
 		min = 1
@@ -325,69 +460,114 @@ func (ec2 *EC2) RunInstances(options *RunInstances) (resp *RunInstancesResp, err
 	}
 	params["MinCount"] = strconv.Itoa(min)
 	params["MaxCount"] = strconv.Itoa(max)
+	addTagSpecParams(params, options.TagSpecifications)
+	token, err := clientToken()
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	if options.DisableAPITermination {
+		params["DisableApiTermination"] = "true"
+	}
+	if options.ShutdownBehavior != "" {
+		params["InstanceInitiatedShutdownBehavior"] = options.ShutdownBehavior
+	}
+	if options.PrivateIPAddress != "" {
+		params["PrivateIpAddress"] = options.PrivateIPAddress
+	}
+	if options.PartitionNumber != 0 {
+		params["Placement.PartitionNumber"] = strconv.Itoa(options.PartitionNumber)
+	}
+	if options.HostId != "" {
+		params["Placement.HostId"] = options.HostId
+	}
+	if options.Affinity != "" {
+		params["Placement.Affinity"] = options.Affinity
+	}
+	if options.LaunchTemplate != nil {
+		addLaunchTemplateParams(params, "LaunchTemplate.", options.LaunchTemplate)
+		if options.LaunchTemplate.Version != "" {
+			params["LaunchTemplate.Version"] = options.LaunchTemplate.Version
+		}
+	}
+
+	resp = &RunInstancesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// addLaunchParams adds to params the parameters of options which
+// describe the instances to launch, with the given prefix. They are
+// shared by RunInstances and the launch specifications of other
+// requests.
+func addLaunchParams(params map[string]string, prefix string, options *RunInstances) {
+	if options.ImageId != "" {
+		params[prefix+"ImageId"] = options.ImageId
+	}
+	if options.InstanceType != "" {
+		params[prefix+"InstanceType"] = options.InstanceType
+	}
 	i, j := 1, 1
 	for _, g := range options.SecurityGroups {
 		if g.Id != "" {
-			params["SecurityGroupId."+strconv.Itoa(i)] = g.Id
+			params[prefix+"SecurityGroupId."+strconv.Itoa(i)] = g.Id
 			i++
 		} else {
-			params["SecurityGroup."+strconv.Itoa(j)] = g.Name
+			params[prefix+"SecurityGroup."+strconv.Itoa(j)] = g.Name
 			j++
 		}
 	}
-	prepareBlockDevices(params, options.BlockDeviceMappings)
-	prepareNetworkInterfaces(params, options.NetworkInterfaces)
-	token, err := clientToken()
-	if err != nil {
-		return nil, err
-	}
-	params["ClientToken"] = token
+	prepareBlockDevices(params, prefix, options.BlockDeviceMappings)
+	prepareNetworkInterfaces(params, prefix, options.NetworkInterfaces)
 
 	if options.KeyName != "" {
-		params["KeyName"] = options.KeyName
+		params[prefix+"KeyName"] = options.KeyName
 	}
 	if options.KernelId != "" {
-		params["KernelId"] = options.KernelId
+		params[prefix+"KernelId"] = options.KernelId
 	}
 	if options.RamdiskId != "" {
-		params["RamdiskId"] = options.RamdiskId
+		params[prefix+"RamdiskId"] = options.RamdiskId
 	}
 	if options.UserData != nil {
 		userData := make([]byte, base64.StdEncoding.EncodedLen(len(options.UserData)))
 		base64.StdEncoding.Encode(userData, options.UserData)
-		params["UserData"] = string(userData)
+		params[prefix+"UserData"] = string(userData)
 	}
 	if options.AvailZone != "" {
-		params["Placement.AvailabilityZone"] = options.AvailZone
+		params[prefix+"Placement.AvailabilityZone"] = options.AvailZone
 	}
 	if options.PlacementGroupName != "" {
-		params["Placement.GroupName"] = options.PlacementGroupName
+		params[prefix+"Placement.GroupName"] = options.PlacementGroupName
+	}
+	if options.Tenancy != "" {
+		params[prefix+"Placement.Tenancy"] = options.Tenancy
 	}
 	if options.Monitoring {
-		params["Monitoring.Enabled"] = "true"
+		params[prefix+"Monitoring.Enabled"] = "true"
 	}
 	if options.SubnetId != "" {
-		params["SubnetId"] = options.SubnetId
-	}
-	if options.DisableAPITermination {
-		params["DisableApiTermination"] = "true"
-	}
-	if options.ShutdownBehavior != "" {
-		params["InstanceInitiatedShutdownBehavior"] = options.ShutdownBehavior
+		params[prefix+"SubnetId"] = options.SubnetId
 	}
-	if options.PrivateIPAddress != "" {
-		params["PrivateIpAddress"] = options.PrivateIPAddress
-	}
-
-	resp = &RunInstancesResp{}
-	err = ec2.query(params, resp)
-	if err != nil {
-		return nil, err
-	}
-	return
 }
 
 func prepareRunParams(options RunInstances) map[string]string {
+	if len(options.TagSpecifications) > 0 || options.PartitionNumber != 0 || options.Tenancy == "host" || options.HostId != "" || options.Affinity != "" || options.LaunchTemplate != nil {
+		// Tagging on creation, the placement options of Dedicated
+		// Hosts and partition placement groups, and launch
+		// templates need the current API version.
+		return makeParamsCurrent("RunInstances")
+	}
+	for _, ni := range options.NetworkInterfaces {
+		if len(ni.IPv6Addresses) > 0 || ni.IPv6AddressCount > 0 {
+			// IPv6 addresses need the current API version too.
+			return makeParamsCurrent("RunInstances")
+		}
+	}
 	if options.SubnetId != "" || len(options.NetworkInterfaces) > 0 {
 		// When either SubnetId or NetworkInterfaces are specified, we
 		// need to use the API version with complete VPC support.
@@ -397,10 +577,10 @@ func prepareRunParams(options RunInstances) map[string]string {
 	}
 }
 
-func prepareBlockDevices(params map[string]string, blockDevs []BlockDeviceMapping) {
+func prepareBlockDevices(params map[string]string, prefix string, blockDevs []BlockDeviceMapping) {
 	for i, b := range blockDevs {
 		n := strconv.Itoa(i + 1)
-		prefix := "BlockDeviceMapping." + n
+		prefix := prefix + "BlockDeviceMapping." + n
 		if b.DeviceName != "" {
 			params[prefix+".DeviceName"] = b.DeviceName
 		}
@@ -422,16 +602,19 @@ func prepareBlockDevices(params map[string]string, blockDevs []BlockDeviceMappin
 		if b.DeleteOnTermination {
 			params[prefix+".Ebs.DeleteOnTermination"] = "true"
 		}
+		if b.Encrypted {
+			params[prefix+".Ebs.Encrypted"] = "true"
+		}
 	}
 }
 
-func prepareNetworkInterfaces(params map[string]string, nics []RunNetworkInterface) {
+func prepareNetworkInterfaces(params map[string]string, prefix string, nics []RunNetworkInterface) {
 	for i, ni := range nics {
 		// Unlike other lists, NetworkInterface and PrivateIpAddresses
 		// should start from 0, not 1, according to the examples
 		// requests in the API documentation here http://goo.gl/Mcm3b.
 		n := strconv.Itoa(i)
-		prefix := "NetworkInterface." + n
+		prefix := prefix + "NetworkInterface." + n
 		if ni.Id != "" {
 			params[prefix+".NetworkInterfaceId"] = ni.Id
 		}
@@ -459,6 +642,13 @@ func prepareNetworkInterfaces(params map[string]string, nics []RunNetworkInterfa
 			params[subprefix+".PrivateIpAddress"] = ip.Address
 			params[subprefix+".Primary"] = strconv.FormatBool(ip.IsPrimary)
 		}
+		for j, ip := range ni.IPv6Addresses {
+			k := strconv.Itoa(j + 1)
+			params[prefix+".Ipv6Addresses."+k+".Ipv6Address"] = ip
+		}
+		if ni.IPv6AddressCount > 0 {
+			params[prefix+".Ipv6AddressCount"] = strconv.Itoa(ni.IPv6AddressCount)
+		}
 	}
 }
 
@@ -517,6 +707,7 @@ func (ec2 *EC2) TerminateInstances(instIds []string) (resp *TerminateInstancesRe
 type InstancesResp struct {
 	RequestId    string        `xml:"requestId"`
 	Reservations []Reservation `xml:"reservationSet>item"`
+	NextToken    string        `xml:"nextToken"`
 }
 
 // Reservation represents details about a reservation in EC2.
@@ -536,9 +727,19 @@ type Reservation struct {
 //
 // See http://goo.gl/4No7c for more details.
 func (ec2 *EC2) Instances(instIds []string, filter *Filter) (resp *InstancesResp, err error) {
+	return ec2.InstancesPage(instIds, filter, 0, "")
+}
+
+// InstancesPage is like Instances, but it returns a single page of
+// at most maxResults instances, or all of them if maxResults is zero.
+// An empty nextToken selects the first page; the NextToken field of
+// the response selects the following one, and is empty after the
+// last page. See also InstancesIter.
+func (ec2 *EC2) InstancesPage(instIds []string, filter *Filter, maxResults int, nextToken string) (resp *InstancesResp, err error) {
 	params := makeParams("DescribeInstances")
 	addParamsList(params, "InstanceId", instIds)
 	filter.addParams(params)
+	addPageParams(params, maxResults, nextToken)
 	resp = &InstancesResp{}
 	err = ec2.query(params, resp)
 	if err != nil {
@@ -556,6 +757,7 @@ func (ec2 *EC2) Instances(instIds []string, filter *Filter) (resp *InstancesResp
 type ImagesResp struct {
 	RequestId string  `xml:"requestId"`
 	Images    []Image `xml:"imagesSet>item"`
+	NextToken string  `xml:"nextToken"`
 }
 
 // BlockDeviceMapping represents the association of a block device with an image.
@@ -568,6 +770,7 @@ type BlockDeviceMapping struct {
 	VolumeType          string `xml:"ebs>volumeType"`
 	VolumeSize          int64  `xml:"ebs>volumeSize"` // Size is given in GB
 	DeleteOnTermination bool   `xml:"ebs>deleteOnTermination"`
+	Encrypted           bool   `xml:"ebs>encrypted"`
 
 	// The number of I/O operations per second (IOPS) that the volume supports.
 	IOPS int64 `xml:"ebs>iops"`
@@ -597,6 +800,7 @@ type Image struct {
 	VirtualizationType string               `xml:"virtualizationType"`
 	Hypervisor         string               `xml:"hypervisor"`
 	BlockDevices       []BlockDeviceMapping `xml:"blockDeviceMapping>item"`
+	Tags               []Tag                `xml:"tagSet>item"`
 }
 
 // Images returns details about available images.
@@ -609,11 +813,18 @@ type Image struct {
 //
 // See http://goo.gl/SRBhW for more details.
 func (ec2 *EC2) Images(ids []string, filter *Filter) (resp *ImagesResp, err error) {
+	return ec2.ImagesPage(ids, filter, 0, "")
+}
+
+// ImagesPage is like Images, but it returns a single page of
+// results. See InstancesPage for how pages are selected.
+func (ec2 *EC2) ImagesPage(ids []string, filter *Filter, maxResults int, nextToken string) (resp *ImagesResp, err error) {
 	params := makeParams("DescribeImages")
 	for i, id := range ids {
 		params["ImageId."+strconv.Itoa(i+1)] = id
 	}
 	filter.addParams(params)
+	addPageParams(params, maxResults, nextToken)
 
 	resp = &ImagesResp{}
 	err = ec2.query(params, resp)
@@ -635,7 +846,21 @@ type CreateSnapshotResp struct {
 //
 // See http://goo.gl/ttcda for more details.
 func (ec2 *EC2) CreateSnapshot(volumeId, description string) (resp *CreateSnapshotResp, err error) {
+	return ec2.CreateSnapshotWithTags(volumeId, description, nil)
+}
+
+// CreateSnapshotWithTags creates a volume snapshot, applying the
+// given tags to it. If tags is empty, this call is equivalent to
+// CreateSnapshot.
+//
+// See http://goo.gl/ttcda for more details.
+func (ec2 *EC2) CreateSnapshotWithTags(volumeId, description string, tags []Tag) (resp *CreateSnapshotResp, err error) {
 	params := makeParams("CreateSnapshot")
+	if len(tags) > 0 {
+		// Tagging on creation needs the current API version.
+		params = makeParamsCurrent("CreateSnapshot")
+		addTagSpecParams(params, []TagSpecification{{ResourceType: "snapshot", Tags: tags}})
+	}
 	params["VolumeId"] = volumeId
 	params["Description"] = description
 
@@ -677,6 +902,7 @@ func (ec2 *EC2) DeleteSnapshots(ids []string) (resp *SimpleResp, err error) {
 type SnapshotsResp struct {
 	RequestId string     `xml:"requestId"`
 	Snapshots []Snapshot `xml:"snapshotSet>item"`
+	NextToken string     `xml:"nextToken"`
 }
 
 // Snapshot represents details about a volume snapshot.
@@ -692,6 +918,8 @@ type Snapshot struct {
 	Progress    string `xml:"progress"`
 	OwnerId     string `xml:"ownerId"`
 	OwnerAlias  string `xml:"ownerAlias"`
+	Encrypted   bool   `xml:"encrypted"`
+	KMSKeyId    string `xml:"kmsKeyId"`
 	Tags        []Tag  `xml:"tagSet>item"`
 }
 
@@ -700,11 +928,18 @@ type Snapshot struct {
 //
 // See http://goo.gl/ogJL4 for more details.
 func (ec2 *EC2) Snapshots(ids []string, filter *Filter) (resp *SnapshotsResp, err error) {
+	return ec2.SnapshotsPage(ids, filter, 0, "")
+}
+
+// SnapshotsPage is like Snapshots, but it returns a single page of
+// results. See InstancesPage for how pages are selected.
+func (ec2 *EC2) SnapshotsPage(ids []string, filter *Filter, maxResults int, nextToken string) (resp *SnapshotsResp, err error) {
 	params := makeParams("DescribeSnapshots")
 	for i, id := range ids {
 		params["SnapshotId."+strconv.Itoa(i+1)] = id
 	}
 	filter.addParams(params)
+	addPageParams(params, maxResults, nextToken)
 
 	resp = &SnapshotsResp{}
 	err = ec2.query(params, resp)
@@ -767,6 +1002,7 @@ func (ec2 *EC2) CreateSecurityGroupVPC(vpcId, name, description string) (resp *C
 type SecurityGroupsResp struct {
 	RequestId string              `xml:"requestId"`
 	Groups    []SecurityGroupInfo `xml:"securityGroupInfo>item"`
+	NextToken string              `xml:"nextToken"`
 }
 
 // SecurityGroup encapsulates details for a security group in EC2.
@@ -778,25 +1014,118 @@ type SecurityGroupInfo struct {
 	OwnerId     string   `xml:"ownerId"`
 	Description string   `xml:"groupDescription"`
 	IPPerms     []IPPerm `xml:"ipPermissions>item"`
+	Tags        []Tag    `xml:"tagSet>item"`
+
+	// IPPermsEgress holds the outbound rules of the group.
+	// Only VPC security groups have outbound rules.
+	IPPermsEgress []IPPerm `xml:"ipPermissionsEgress>item"`
 }
 
 // IPPerm represents an allowance within an EC2 security group.
+// For inbound rules the source fields describe where traffic
+// may come from; for outbound rules they describe where it may
+// go to.
 //
 // See http://goo.gl/4oTxv for more details.
 type IPPerm struct {
-	Protocol     string              `xml:"ipProtocol"`
-	FromPort     int                 `xml:"fromPort"`
-	ToPort       int                 `xml:"toPort"`
-	SourceIPs    []string            `xml:"ipRanges>item>cidrIp"`
-	SourceGroups []UserSecurityGroup `xml:"groups>item"`
+	Protocol      string
+	FromPort      int
+	ToPort        int
+	SourceIPs     []string
+	SourceIPv6s   []string
+	SourceGroups  []UserSecurityGroup
+	PrefixListIds []string
+
+	// Descriptions maps the IP ranges of SourceIPs and SourceIPv6s
+	// and the ids of PrefixListIds to their descriptions. Sources
+	// without an entry have no description. Source groups hold
+	// their own descriptions.
+	Descriptions map[string]string
+}
+
+// ipPermXML holds the XML representation of an IPPerm, in which
+// the rule description is held by each IP range and prefix list.
+type ipPermXML struct {
+	Protocol      string              `xml:"ipProtocol"`
+	FromPort      int                 `xml:"fromPort"`
+	ToPort        int                 `xml:"toPort"`
+	Groups        []UserSecurityGroup `xml:"groups>item"`
+	IPRanges      []ipPermSource      `xml:"ipRanges>item"`
+	IPv6Ranges    []ipPermSource      `xml:"ipv6Ranges>item"`
+	PrefixListIds []ipPermSource      `xml:"prefixListIds>item"`
+}
+
+type ipPermSource struct {
+	CIDRIP       string `xml:"cidrIp,omitempty"`
+	CIDRIPv6     string `xml:"cidrIpv6,omitempty"`
+	PrefixListId string `xml:"prefixListId,omitempty"`
+	Description  string `xml:"description,omitempty"`
+}
+
+// MarshalXML implements xml.Marshaler.
+func (p IPPerm) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := ipPermXML{
+		Protocol: p.Protocol,
+		FromPort: p.FromPort,
+		ToPort:   p.ToPort,
+		Groups:   p.SourceGroups,
+	}
+	for _, ip := range p.SourceIPs {
+		v.IPRanges = append(v.IPRanges, ipPermSource{CIDRIP: ip, Description: p.Descriptions[ip]})
+	}
+	for _, ip := range p.SourceIPv6s {
+		v.IPv6Ranges = append(v.IPv6Ranges, ipPermSource{CIDRIPv6: ip, Description: p.Descriptions[ip]})
+	}
+	for _, id := range p.PrefixListIds {
+		v.PrefixListIds = append(v.PrefixListIds, ipPermSource{PrefixListId: id, Description: p.Descriptions[id]})
+	}
+	return e.EncodeElement(v, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler.
+func (p *IPPerm) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v ipPermXML
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*p = IPPerm{
+		Protocol:     v.Protocol,
+		FromPort:     v.FromPort,
+		ToPort:       v.ToPort,
+		SourceGroups: v.Groups,
+	}
+	for _, src := range v.IPRanges {
+		p.SourceIPs = append(p.SourceIPs, src.CIDRIP)
+		p.setDescription(src.CIDRIP, src.Description)
+	}
+	for _, src := range v.IPv6Ranges {
+		p.SourceIPv6s = append(p.SourceIPv6s, src.CIDRIPv6)
+		p.setDescription(src.CIDRIPv6, src.Description)
+	}
+	for _, src := range v.PrefixListIds {
+		p.PrefixListIds = append(p.PrefixListIds, src.PrefixListId)
+		p.setDescription(src.PrefixListId, src.Description)
+	}
+	return nil
+}
+
+func (p *IPPerm) setDescription(source, description string) {
+	if description == "" {
+		return
+	}
+	if p.Descriptions == nil {
+		p.Descriptions = make(map[string]string)
+	}
+	p.Descriptions[source] = description
 }
 
 // UserSecurityGroup holds a security group and the owner
 // of that group.
 type UserSecurityGroup struct {
-	Id      string `xml:"groupId"`
-	Name    string `xml:"groupName"`
-	OwnerId string `xml:"userId"`
+	Id          string `xml:"groupId"`
+	Name        string `xml:"groupName"`
+	OwnerId     string `xml:"userId"`
+	Description string `xml:"description,omitempty"`
 }
 
 // SecurityGroup represents an EC2 security group.
@@ -833,7 +1162,13 @@ func SecurityGroupIds(ids ...string) []SecurityGroup {
 //
 // See http://goo.gl/k12Uy for more details.
 func (ec2 *EC2) SecurityGroups(groups []SecurityGroup, filter *Filter) (resp *SecurityGroupsResp, err error) {
-	params := makeParams("DescribeSecurityGroups")
+	return ec2.SecurityGroupsPage(groups, filter, 0, "")
+}
+
+// SecurityGroupsPage is like SecurityGroups, but it returns a single
+// page of results. See InstancesPage for how pages are selected.
+func (ec2 *EC2) SecurityGroupsPage(groups []SecurityGroup, filter *Filter, maxResults int, nextToken string) (resp *SecurityGroupsResp, err error) {
+	params := makeParamsCurrent("DescribeSecurityGroups")
 	i, j := 1, 1
 	for _, g := range groups {
 		if g.Id != "" {
@@ -845,6 +1180,7 @@ func (ec2 *EC2) SecurityGroups(groups []SecurityGroup, filter *Filter) (resp *Se
 		}
 	}
 	filter.addParams(params)
+	addPageParams(params, maxResults, nextToken)
 
 	resp = &SecurityGroupsResp{}
 	err = ec2.query(params, resp)
@@ -888,8 +1224,27 @@ func (ec2 *EC2) RevokeSecurityGroup(group SecurityGroup, perms []IPPerm) (resp *
 	return ec2.authOrRevoke("RevokeSecurityGroupIngress", group, perms)
 }
 
+// AuthorizeSecurityGroupEgress allows instances within the given VPC
+// security group to send traffic to destinations matching the provided
+// rules. The group must be identified by its Id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AuthorizeSecurityGroupEgress.html
+// for more details.
+func (ec2 *EC2) AuthorizeSecurityGroupEgress(group SecurityGroup, perms []IPPerm) (resp *SimpleResp, err error) {
+	return ec2.authOrRevoke("AuthorizeSecurityGroupEgress", group, perms)
+}
+
+// RevokeSecurityGroupEgress revokes outbound permissions from a VPC
+// security group. The group must be identified by its Id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RevokeSecurityGroupEgress.html
+// for more details.
+func (ec2 *EC2) RevokeSecurityGroupEgress(group SecurityGroup, perms []IPPerm) (resp *SimpleResp, err error) {
+	return ec2.authOrRevoke("RevokeSecurityGroupEgress", group, perms)
+}
+
 func (ec2 *EC2) authOrRevoke(op string, group SecurityGroup, perms []IPPerm) (resp *SimpleResp, err error) {
-	params := makeParams(op)
+	params := prepareAuthOrRevokeParams(op, perms)
 	if group.Id != "" {
 		params["GroupId"] = group.Id
 	} else {
@@ -902,7 +1257,14 @@ func (ec2 *EC2) authOrRevoke(op string, group SecurityGroup, perms []IPPerm) (re
 		params[prefix+".FromPort"] = strconv.Itoa(perm.FromPort)
 		params[prefix+".ToPort"] = strconv.Itoa(perm.ToPort)
 		for j, ip := range perm.SourceIPs {
-			params[prefix+".IpRanges."+strconv.Itoa(j+1)+".CidrIp"] = ip
+			subprefix := prefix + ".IpRanges." + strconv.Itoa(j+1)
+			params[subprefix+".CidrIp"] = ip
+			addDescription(params, subprefix, perm.Descriptions[ip])
+		}
+		for j, ip := range perm.SourceIPv6s {
+			subprefix := prefix + ".Ipv6Ranges." + strconv.Itoa(j+1)
+			params[subprefix+".CidrIpv6"] = ip
+			addDescription(params, subprefix, perm.Descriptions[ip])
 		}
 		for j, g := range perm.SourceGroups {
 			subprefix := prefix + ".Groups." + strconv.Itoa(j+1)
@@ -914,6 +1276,12 @@ func (ec2 *EC2) authOrRevoke(op string, group SecurityGroup, perms []IPPerm) (re
 			} else {
 				params[subprefix+".GroupName"] = g.Name
 			}
+			addDescription(params, subprefix, g.Description)
+		}
+		for j, id := range perm.PrefixListIds {
+			subprefix := prefix + ".PrefixListIds." + strconv.Itoa(j+1)
+			params[subprefix+".PrefixListId"] = id
+			addDescription(params, subprefix, perm.Descriptions[id])
 		}
 	}
 
@@ -925,33 +1293,30 @@ func (ec2 *EC2) authOrRevoke(op string, group SecurityGroup, perms []IPPerm) (re
 	return resp, nil
 }
 
-// Tag represents key-value metadata used to classify and organize
-// EC2 instances.
-//
-// See http://goo.gl/bncl3 for more details
-type Tag struct {
-	Key   string `xml:"key"`
-	Value string `xml:"value"`
-}
-
-// CreateTags adds or overwrites one or more tags for the specified instance ids.
-//
-// See http://goo.gl/Vmkqc for more details
-func (ec2 *EC2) CreateTags(instIds []string, tags []Tag) (resp *SimpleResp, err error) {
-	params := makeParams("CreateTags")
-	addParamsList(params, "ResourceId", instIds)
-
-	for j, tag := range tags {
-		params["Tag."+strconv.Itoa(j+1)+".Key"] = tag.Key
-		params["Tag."+strconv.Itoa(j+1)+".Value"] = tag.Value
+func prepareAuthOrRevokeParams(op string, perms []IPPerm) map[string]string {
+	for _, perm := range perms {
+		if len(perm.SourceIPv6s) > 0 || len(perm.PrefixListIds) > 0 || len(perm.Descriptions) > 0 {
+			// IPv6 ranges, prefix lists and rule descriptions
+			// need the current API version.
+			return makeParamsCurrent(op)
+		}
+		for _, g := range perm.SourceGroups {
+			if g.Description != "" {
+				return makeParamsCurrent(op)
+			}
+		}
+	}
+	if strings.HasSuffix(op, "Egress") {
+		// Outbound rules need the API version with VPC support.
+		return makeParamsVPC(op)
 	}
+	return makeParams(op)
+}
 
-	resp = &SimpleResp{}
-	err = ec2.query(params, resp)
-	if err != nil {
-		return nil, err
+func addDescription(params map[string]string, prefix, description string) {
+	if description != "" {
+		params[prefix+".Description"] = description
 	}
-	return resp, nil
 }
 
 // Response to a StartInstances request.
@@ -1016,6 +1381,199 @@ func (ec2 *EC2) RebootInstances(ids ...string) (resp *SimpleResp, err error) {
 	return resp, nil
 }
 
+// InstanceAttributeResp is the response to an InstanceAttribute
+// request. Only the field of the requested attribute is set.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceAttribute.html for more details.
+type InstanceAttributeResp struct {
+	RequestId             string `xml:"requestId"`
+	InstanceId            string `xml:"instanceId"`
+	InstanceType          string `xml:"instanceType>value"`
+	SourceDestCheck       bool   `xml:"sourceDestCheck>value"`
+	DisableAPITermination bool   `xml:"disableApiTermination>value"`
+	ShutdownBehavior      string `xml:"instanceInitiatedShutdownBehavior>value"`
+
+	// UserData holds the user data of the instance, decoded.
+	UserData []byte `xml:"userData>value"`
+}
+
+// InstanceAttribute describes an attribute of the instance with the
+// given id: one of "instanceType", "sourceDestCheck",
+// "disableApiTermination", "instanceInitiatedShutdownBehavior" and
+// "userData".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceAttribute.html for more details.
+func (ec2 *EC2) InstanceAttribute(instId, attribute string) (resp *InstanceAttributeResp, err error) {
+	params := makeParamsCurrent("DescribeInstanceAttribute")
+	params["InstanceId"] = instId
+	params["Attribute"] = attribute
+
+	resp = &InstanceAttributeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.UserData) > 0 {
+		resp.UserData, err = base64.StdEncoding.DecodeString(string(resp.UserData))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode user data: %v", err)
+		}
+	}
+	return resp, nil
+}
+
+// ModifyInstanceAttribute holds the changes of a
+// ModifyInstanceAttribute request. Fields left nil or empty are not
+// changed. EC2 only changes one attribute per request, and the
+// instance type and user data only of stopped instances.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyInstanceAttribute.html for more details.
+type ModifyInstanceAttribute struct {
+	InstanceType          string
+	SourceDestCheck       *bool
+	DisableAPITermination *bool
+	ShutdownBehavior      string // "stop" or "terminate".
+	UserData              []byte
+}
+
+// ModifyInstanceAttribute modifies an attribute of the instance with
+// the given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyInstanceAttribute.html for more details.
+func (ec2 *EC2) ModifyInstanceAttribute(instId string, options *ModifyInstanceAttribute) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("ModifyInstanceAttribute")
+	params["InstanceId"] = instId
+	if options.InstanceType != "" {
+		params["InstanceType.Value"] = options.InstanceType
+	}
+	if options.SourceDestCheck != nil {
+		params["SourceDestCheck.Value"] = strconv.FormatBool(*options.SourceDestCheck)
+	}
+	if options.DisableAPITermination != nil {
+		params["DisableApiTermination.Value"] = strconv.FormatBool(*options.DisableAPITermination)
+	}
+	if options.ShutdownBehavior != "" {
+		params["InstanceInitiatedShutdownBehavior.Value"] = options.ShutdownBehavior
+	}
+	if options.UserData != nil {
+		params["UserData.Value"] = base64.StdEncoding.EncodeToString(options.UserData)
+	}
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ConsoleOutputResp is the response to a ConsoleOutput request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_GetConsoleOutput.html for more details.
+type ConsoleOutputResp struct {
+	RequestId  string `xml:"requestId"`
+	InstanceId string `xml:"instanceId"`
+	Timestamp  string `xml:"timestamp"`
+
+	// Output holds the console output of the instance, decoded.
+	Output []byte `xml:"output"`
+}
+
+// ConsoleOutput returns the most recent console output of the
+// instance with the given id. EC2 only updates the output shortly
+// after the instance starts, stops or reboots.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_GetConsoleOutput.html for more details.
+func (ec2 *EC2) ConsoleOutput(instId string) (resp *ConsoleOutputResp, err error) {
+	params := makeParamsCurrent("GetConsoleOutput")
+	params["InstanceId"] = instId
+
+	resp = &ConsoleOutputResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Output) > 0 {
+		resp.Output, err = base64.StdEncoding.DecodeString(string(resp.Output))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode console output: %v", err)
+		}
+	}
+	return resp, nil
+}
+
+// InstanceStatusDetail holds the result of one of the checks made on
+// an instance.
+type InstanceStatusDetail struct {
+	Name          string `xml:"name"`   // "reachability".
+	Status        string `xml:"status"` // "passed", "failed", "insufficient-data" or "initializing".
+	ImpairedSince string `xml:"impairedSince"`
+}
+
+// InstanceStatusSummary holds the results of the system or instance
+// status checks of an instance.
+type InstanceStatusSummary struct {
+	// Status is one of "ok", "impaired", "insufficient-data",
+	// "not-applicable" and "initializing".
+	Status  string                 `xml:"status"`
+	Details []InstanceStatusDetail `xml:"details>item"`
+}
+
+// InstanceStatusEvent describes an event scheduled for an instance.
+type InstanceStatusEvent struct {
+	// Code is one of "instance-reboot", "system-reboot",
+	// "system-maintenance", "instance-retirement" and
+	// "instance-stop".
+	Code        string `xml:"code"`
+	Description string `xml:"description"`
+	NotBefore   string `xml:"notBefore"`
+	NotAfter    string `xml:"notAfter"`
+}
+
+// InstanceStatus holds the status of an instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InstanceStatus.html for more details.
+type InstanceStatus struct {
+	InstanceId     string                `xml:"instanceId"`
+	AvailZone      string                `xml:"availabilityZone"`
+	State          InstanceState         `xml:"instanceState"`
+	SystemStatus   InstanceStatusSummary `xml:"systemStatus"`
+	InstanceStatus InstanceStatusSummary `xml:"instanceStatus"`
+	Events         []InstanceStatusEvent `xml:"eventsSet>item"`
+}
+
+// InstanceStatusResp is the response to an InstanceStatus request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceStatus.html for more details.
+type InstanceStatusResp struct {
+	RequestId string           `xml:"requestId"`
+	Statuses  []InstanceStatus `xml:"instanceStatusSet>item"`
+}
+
+// InstanceStatus returns the status of the instances with the given
+// ids, or of all instances if ids is empty, that match the given
+// filter. Only running instances are reported, unless includeAll is
+// true.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceStatus.html for more details.
+func (ec2 *EC2) InstanceStatus(ids []string, includeAll bool, filter *Filter) (resp *InstanceStatusResp, err error) {
+	params := makeParamsCurrent("DescribeInstanceStatus")
+	for i, id := range ids {
+		params["InstanceId."+strconv.Itoa(i+1)] = id
+	}
+	if includeAll {
+		params["IncludeAllInstances"] = "true"
+	}
+	filter.addParams(params)
+
+	resp = &InstanceStatusResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // ----------------------------------------------------------------------------
 // Availability zone management functions and types.
 // See http://goo.gl/ylxT4R for more details.
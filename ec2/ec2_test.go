@@ -197,6 +197,7 @@ func (s *S) TestRunInstancesExample(c *C) {
 	c.Assert(i0.Monitoring, Equals, "enabled")
 	c.Assert(i0.KeyName, Equals, "example-key-name")
 	c.Assert(i0.AMILaunchIndex, Equals, 0)
+	c.Assert(i0.LaunchTime, Equals, "2007-08-07T11:51:50.000Z")
 	c.Assert(i0.VirtType, Equals, "paravirtual")
 	c.Assert(i0.Hypervisor, Equals, "xen")
 	c.Assert(i0.SubnetId, Equals, "subnet-id")
@@ -426,6 +427,7 @@ func (s *S) TestCreateSnapshotExample(c *C) {
 
 	req := testServer.WaitRequest()
 	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateSnapshot"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2011-12-15"})
 	c.Assert(req.Form["VolumeId"], DeepEquals, []string{"vol-4d826724"})
 	c.Assert(req.Form["Description"], DeepEquals, []string{"Daily Backup"})
 
@@ -652,6 +654,7 @@ func (s *S) TestAuthorizeSecurityGroupExample1(c *C) {
 	req := testServer.WaitRequest()
 
 	c.Assert(req.Form["Action"], DeepEquals, []string{"AuthorizeSecurityGroupIngress"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2011-12-15"})
 	c.Assert(req.Form["GroupName"], DeepEquals, []string{"websrv"})
 	c.Assert(req.Form["IpPermissions.1.IpProtocol"], DeepEquals, []string{"tcp"})
 	c.Assert(req.Form["IpPermissions.1.FromPort"], DeepEquals, []string{"80"})
@@ -727,12 +730,111 @@ func (s *S) TestRevokeSecurityGroupExample(c *C) {
 	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
 }
 
+func (s *S) TestAuthorizeSecurityGroupEgressExample(c *C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupEgressExample)
+
+	perms := []ec2.IPPerm{{
+		Protocol:      "tcp",
+		FromPort:      443,
+		ToPort:        443,
+		SourceIPs:     []string{"10.0.0.0/16"},
+		SourceIPv6s:   []string{"2001:db8::/32"},
+		PrefixListIds: []string{"pl-12c4e678"},
+		SourceGroups: []ec2.UserSecurityGroup{
+			{Id: "sg-1a2b3c4d"},
+			{Id: "sg-2a2b3c4d", Description: "Proxies"},
+		},
+		Descriptions: map[string]string{
+			"10.0.0.0/16":   "HTTPS out",
+			"2001:db8::/32": "HTTPS out over IPv6",
+		},
+	}}
+	resp, err := s.ec2.AuthorizeSecurityGroupEgress(ec2.SecurityGroup{Id: "sg-67ad940e"}, perms)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AuthorizeSecurityGroupEgress"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["GroupId"], DeepEquals, []string{"sg-67ad940e"})
+	c.Assert(req.Form["IpPermissions.1.IpProtocol"], DeepEquals, []string{"tcp"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.CidrIp"], DeepEquals, []string{"10.0.0.0/16"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.Description"], DeepEquals, []string{"HTTPS out"})
+	c.Assert(req.Form["IpPermissions.1.Ipv6Ranges.1.CidrIpv6"], DeepEquals, []string{"2001:db8::/32"})
+	c.Assert(req.Form["IpPermissions.1.Ipv6Ranges.1.Description"], DeepEquals, []string{"HTTPS out over IPv6"})
+	c.Assert(req.Form["IpPermissions.1.PrefixListIds.1.PrefixListId"], DeepEquals, []string{"pl-12c4e678"})
+	c.Assert(req.Form["IpPermissions.1.PrefixListIds.1.Description"], IsNil)
+	c.Assert(req.Form["IpPermissions.1.Groups.1.GroupId"], DeepEquals, []string{"sg-1a2b3c4d"})
+	c.Assert(req.Form["IpPermissions.1.Groups.1.Description"], IsNil)
+	c.Assert(req.Form["IpPermissions.1.Groups.2.GroupId"], DeepEquals, []string{"sg-2a2b3c4d"})
+	c.Assert(req.Form["IpPermissions.1.Groups.2.Description"], DeepEquals, []string{"Proxies"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestRevokeSecurityGroupEgressExample(c *C) {
+	testServer.Response(200, nil, AuthorizeSecurityGroupEgressExample)
+
+	perms := []ec2.IPPerm{{
+		Protocol:  "-1",
+		SourceIPs: []string{"0.0.0.0/0"},
+	}}
+	_, err := s.ec2.RevokeSecurityGroupEgress(ec2.SecurityGroup{Id: "sg-67ad940e"}, perms)
+
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RevokeSecurityGroupEgress"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2013-10-15"})
+	c.Assert(req.Form["GroupId"], DeepEquals, []string{"sg-67ad940e"})
+	c.Assert(req.Form["IpPermissions.1.IpProtocol"], DeepEquals, []string{"-1"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.CidrIp"], DeepEquals, []string{"0.0.0.0/0"})
+	c.Assert(req.Form["IpPermissions.1.IpRanges.1.Description"], IsNil)
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestDescribeSecurityGroupsVPCExample(c *C) {
+	testServer.Response(200, nil, DescribeSecurityGroupsVPCExample)
+
+	resp, err := s.ec2.SecurityGroups(ec2.SecurityGroupIds("sg-1a2b3c4d"), nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Groups, HasLen, 1)
+	g := resp.Groups[0]
+	c.Assert(g.VPCId, Equals, "vpc-81326ae4")
+	c.Assert(g.IPPerms, DeepEquals, []ec2.IPPerm{{
+		Protocol:    "tcp",
+		FromPort:    22,
+		ToPort:      22,
+		SourceIPs:   []string{"203.0.113.0/24", "198.51.100.0/24"},
+		SourceIPv6s: []string{"2001:db8:1234:1a00::/64"},
+		SourceGroups: []ec2.UserSecurityGroup{{
+			Id:          "sg-2a2b3c4d",
+			OwnerId:     "123456789012",
+			Description: "Access from bastion",
+		}},
+		Descriptions: map[string]string{
+			"203.0.113.0/24":          "Office network",
+			"2001:db8:1234:1a00::/64": "Office IPv6 network",
+		},
+	}})
+	c.Assert(g.IPPermsEgress, DeepEquals, []ec2.IPPerm{{
+		Protocol:  "-1",
+		SourceIPs: []string{"0.0.0.0/0"},
+	}, {
+		Protocol:      "tcp",
+		FromPort:      443,
+		ToPort:        443,
+		PrefixListIds: []string{"pl-12c4e678"},
+		Descriptions:  map[string]string{"pl-12c4e678": "S3 endpoint"},
+	}})
+}
+
 func (s *S) TestCreateTags(c *C) {
 	testServer.Response(200, nil, CreateTagsExample)
 
 	resp, err := s.ec2.CreateTags([]string{"ami-1a2b3c4d", "i-7f4d3a2b"}, []ec2.Tag{{"webserver", ""}, {"stack", "Production"}})
 
 	req := testServer.WaitRequest()
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2011-12-15"})
 	c.Assert(req.Form["ResourceId.1"], DeepEquals, []string{"ami-1a2b3c4d"})
 	c.Assert(req.Form["ResourceId.2"], DeepEquals, []string{"i-7f4d3a2b"})
 	c.Assert(req.Form["Tag.1.Key"], DeepEquals, []string{"webserver"})
@@ -797,6 +899,105 @@ func (s *S) TestRebootInstances(c *C) {
 	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
 }
 
+func (s *S) TestInstanceAttributeExample(c *C) {
+	testServer.Response(200, nil, DescribeInstanceAttributeExample)
+
+	resp, err := s.ec2.InstanceAttribute("i-1234567890abcdef0", "userData")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeInstanceAttribute"})
+	c.Assert(req.Form["InstanceId"], DeepEquals, []string{"i-1234567890abcdef0"})
+	c.Assert(req.Form["Attribute"], DeepEquals, []string{"userData"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.InstanceId, Equals, "i-1234567890abcdef0")
+	c.Assert(string(resp.UserData), Equals, "#!/bin/sh\necho hello\n")
+}
+
+func (s *S) TestModifyInstanceAttributeExample(c *C) {
+	testServer.Response(200, nil, ModifyInstanceAttributeExample)
+
+	check := false
+	resp, err := s.ec2.ModifyInstanceAttribute("i-1234567890abcdef0", &ec2.ModifyInstanceAttribute{
+		SourceDestCheck: &check,
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ModifyInstanceAttribute"})
+	c.Assert(req.Form["InstanceId"], DeepEquals, []string{"i-1234567890abcdef0"})
+	c.Assert(req.Form["SourceDestCheck.Value"], DeepEquals, []string{"false"})
+	c.Assert(req.Form["DisableApiTermination.Value"], IsNil)
+	c.Assert(req.Form["InstanceType.Value"], IsNil)
+	c.Assert(req.Form["UserData.Value"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+
+	testServer.Response(200, nil, ModifyInstanceAttributeExample)
+	_, err = s.ec2.ModifyInstanceAttribute("i-1234567890abcdef0", &ec2.ModifyInstanceAttribute{
+		UserData: []byte("#!/bin/sh\n"),
+	})
+	req = testServer.WaitRequest()
+	c.Assert(req.Form["UserData.Value"], DeepEquals, []string{"IyEvYmluL3NoCg=="})
+	c.Assert(req.Form["SourceDestCheck.Value"], IsNil)
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestConsoleOutputExample(c *C) {
+	testServer.Response(200, nil, GetConsoleOutputExample)
+
+	resp, err := s.ec2.ConsoleOutput("i-1234567890abcdef0")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"GetConsoleOutput"})
+	c.Assert(req.Form["InstanceId"], DeepEquals, []string{"i-1234567890abcdef0"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.InstanceId, Equals, "i-1234567890abcdef0")
+	c.Assert(resp.Timestamp, Equals, "2010-10-14T01:12:41.000Z")
+	c.Assert(string(resp.Output), Matches, "Linux version 2.6.16-xenU .*\n")
+}
+
+func (s *S) TestInstanceStatusExample(c *C) {
+	testServer.Response(200, nil, DescribeInstanceStatusExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("system-status.status", "impaired")
+	resp, err := s.ec2.InstanceStatus([]string{"i-1234567890abcdef0"}, true, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeInstanceStatus"})
+	c.Assert(req.Form["InstanceId.1"], DeepEquals, []string{"i-1234567890abcdef0"})
+	c.Assert(req.Form["IncludeAllInstances"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"system-status.status"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"impaired"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "3be1508e-c444-4fef-89cc-0b1223c4f02fEXAMPLE")
+	c.Assert(resp.Statuses, HasLen, 2)
+	st := resp.Statuses[0]
+	c.Assert(st.InstanceId, Equals, "i-1234567890abcdef0")
+	c.Assert(st.AvailZone, Equals, "us-east-1d")
+	c.Assert(st.State, Equals, ec2.InstanceState{Code: 16, Name: "running"})
+	c.Assert(st.SystemStatus.Status, Equals, "impaired")
+	c.Assert(st.SystemStatus.Details, DeepEquals, []ec2.InstanceStatusDetail{{
+		Name:          "reachability",
+		Status:        "failed",
+		ImpairedSince: "YYYY-MM-DDTHH:MM:SS.000Z",
+	}})
+	c.Assert(st.InstanceStatus.Status, Equals, "impaired")
+	c.Assert(st.Events, DeepEquals, []ec2.InstanceStatusEvent{{
+		Code:        "instance-retirement",
+		Description: "The instance is running on degraded hardware",
+		NotBefore:   "YYYY-MM-DDTHH:MM:SS+0000",
+		NotAfter:    "YYYY-MM-DDTHH:MM:SS+0000",
+	}})
+	c.Assert(resp.Statuses[1].SystemStatus.Status, Equals, "ok")
+	c.Assert(resp.Statuses[1].Events, HasLen, 0)
+}
+
 func (s *S) TestAvailabilityZonesExample1(c *C) {
 	testServer.Response(200, nil, DescribeAvailabilityZonesExample1)
 
@@ -18,6 +18,7 @@ import (
 	"gopkg.in/amz.v1/ec2"
 	"gopkg.in/amz.v1/ec2/ec2test"
 	"gopkg.in/amz.v1/testutil"
+	"gopkg.in/amz.v1/testutil/faults"
 )
 
 // LocalServer represents a local ec2test fake server.
@@ -38,6 +39,8 @@ func (s *LocalServer) SetUp(c *C) {
 		"default-vpc":         []string{"vpc-xxxxxxx"},
 	})
 
+	addTestImages(srv)
+
 	s.srv = srv
 	s.region = aws.Region{EC2Endpoint: srv.URL(), Sign: aws.SignV2}
 }
@@ -55,6 +58,29 @@ type LocalServerSuite struct {
 
 var _ = Suite(&LocalServerSuite{})
 
+// addTestImages adds to srv the public images the tests launch.
+func addTestImages(srv *ec2test.Server) {
+	for _, img := range []ec2.Image{{
+		Id:             imageId,
+		Name:           "ubuntu-maverick-i386-ebs",
+		RootDeviceType: "ebs",
+	}, {
+		Id:             "ami-a6f504cf",
+		Name:           "ubuntu-maverick-i386-instance-store",
+		RootDeviceType: "instance-store",
+	}, {
+		Id:             "ami-e358958a",
+		Name:           "ubuntu-natty-i386-ebs",
+		RootDeviceType: "ebs",
+	}} {
+		img.OwnerId = "099720109477"
+		img.Public = true
+		img.Architecture = "i386"
+		img.VirtualizationType = "paravirtual"
+		srv.AddImage(img)
+	}
+}
+
 func (s *LocalServerSuite) SetUpSuite(c *C) {
 	s.srv.SetUp(c)
 	s.ServerTests.ec2 = ec2.New(s.srv.auth, s.srv.region)
@@ -158,6 +184,376 @@ func validIPForSubnet(c *C, subnet ec2.Subnet, startFrom byte) string {
 	return ip.String()
 }
 
+func (s *LocalServerSuite) TestRetryFaults(c *C) {
+	ec2.SetAttemptStrategy(&aws.AttemptStrategy{Min: 3, Delay: 10 * time.Millisecond})
+	defer ec2.SetAttemptStrategy(nil)
+	inj := s.srv.srv.Faults()
+	defer inj.Reset()
+
+	inj.Script("DescribeAvailabilityZones", faults.RequestLimitExceeded, faults.Dropped, faults.Truncated)
+	resp, err := s.ec2.AvailabilityZones(nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Zones, Not(HasLen), 0)
+	c.Assert(inj.Pending("DescribeAvailabilityZones"), Equals, 0)
+
+	inj.Random(faults.Any, 1, faults.InternalError)
+	_, err = s.ec2.AvailabilityZones(nil)
+	c.Assert(errorCode(err), Equals, "InternalError")
+	c.Assert(err.(*ec2.Error).StatusCode, Equals, 500)
+
+	// Other errors are returned straight away.
+	inj.Reset()
+	inj.Script(faults.Any, faults.Fault{StatusCode: 400, Code: "Blocked", Message: "blocked"})
+	_, err = s.ec2.AvailabilityZones(nil)
+	c.Assert(errorCode(err), Equals, "Blocked")
+	_, err = s.ec2.AvailabilityZones(nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *LocalServerSuite) TestRetryOnlyIdempotent(c *C) {
+	ec2.SetAttemptStrategy(&aws.AttemptStrategy{Min: 3, Delay: 10 * time.Millisecond})
+	defer ec2.SetAttemptStrategy(nil)
+	inj := s.srv.srv.Faults()
+	defer inj.Reset()
+
+	// RunInstances carries a client token, so it is retried and
+	// the retry returns the reservation made the first time.
+	inj.Script("RunInstances", faults.Truncated)
+	resp, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "m1.retry",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(resp.Instances, HasLen, 1)
+	defer terminateInstances(c, s.ec2, []string{resp.Instances[0].InstanceId})
+	c.Assert(inj.Pending("RunInstances"), Equals, 0)
+	filter := ec2.NewFilter()
+	filter.Add("instance-type", "m1.retry")
+	insts, err := s.ec2.Instances(nil, filter)
+	c.Assert(err, IsNil)
+	c.Assert(insts.Reservations, HasLen, 1)
+	c.Assert(insts.Reservations[0].Instances, HasLen, 1)
+	c.Assert(insts.Reservations[0].ReservationId, Equals, resp.ReservationId)
+
+	// AllocateAddress cannot be repeated safely, so its error is
+	// returned.
+	before, err := s.ec2.Addresses(nil, nil, nil)
+	c.Assert(err, IsNil)
+	known := make(map[string]bool)
+	for _, addr := range before.Addresses {
+		known[addr.PublicIP] = true
+	}
+	inj.Script("AllocateAddress", faults.Truncated)
+	_, err = s.ec2.AllocateAddress("standard")
+	c.Assert(err, NotNil)
+	after, err := s.ec2.Addresses(nil, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(after.Addresses, HasLen, len(before.Addresses)+1)
+	for _, addr := range after.Addresses {
+		if !known[addr.PublicIP] {
+			_, err := s.ec2.ReleaseAddress(addr.PublicIP)
+			c.Check(err, IsNil)
+		}
+	}
+}
+
+func (s *LocalServerSuite) TestEventualConsistency(c *C) {
+	s.srv.srv.SetConsistency(faults.NewConsistency(0, 2))
+	defer s.srv.srv.SetConsistency(nil)
+
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+	})
+	c.Assert(err, IsNil)
+	id := inst.Instances[0].InstanceId
+	defer terminateInstances(c, s.ec2, []string{id})
+	for i := 0; i < 2; i++ {
+		_, err = s.ec2.Instances([]string{id}, nil)
+		c.Assert(errorCode(err), Equals, "InvalidInstanceID.NotFound")
+	}
+	resp, err := s.ec2.Instances([]string{id}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Reservations[0].Instances[0].InstanceId, Equals, id)
+
+	g, err := s.ec2.CreateSecurityGroup("eventual", "eventual group")
+	c.Assert(err, IsNil)
+	describe := func() error {
+		_, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{{Id: g.Id}}, nil)
+		return err
+	}
+	c.Assert(errorCode(describe()), Equals, "InvalidGroup.NotFound")
+	c.Assert(errorCode(describe()), Equals, "InvalidGroup.NotFound")
+	c.Assert(describe(), IsNil)
+
+	_, err = s.ec2.DeleteSecurityGroup(g.SecurityGroup)
+	c.Assert(err, IsNil)
+	c.Assert(describe(), IsNil)
+	c.Assert(describe(), IsNil)
+	c.Assert(errorCode(describe()), Equals, "InvalidGroup.NotFound")
+
+	// Changes settle with time too.
+	s.srv.srv.SetConsistency(faults.NewConsistency(50*time.Millisecond, 0))
+	g, err = s.ec2.CreateSecurityGroup("eventual", "eventual group")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSecurityGroup(g.SecurityGroup)
+	c.Assert(errorCode(describe()), Equals, "InvalidGroup.NotFound")
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(describe(), IsNil)
+}
+
+func (s *LocalServerSuite) TestStartStopReboot(c *C) {
+	ids := s.srv.srv.NewInstances(1, "t1.micro", imageId, ec2test.Running, nil)
+	id := ids[0]
+	defer terminateInstances(c, s.ec2, ids)
+	state := func() string {
+		resp, err := s.ec2.Instances(ids, nil)
+		c.Assert(err, IsNil)
+		return resp.Reservations[0].Instances[0].State.Name
+	}
+
+	_, err := s.ec2.RebootInstances(id)
+	c.Assert(err, IsNil)
+	c.Assert(state(), Equals, "running")
+
+	stopped, err := s.ec2.StopInstances(id)
+	c.Assert(err, IsNil)
+	c.Assert(stopped.StateChanges, DeepEquals, []ec2.InstanceStateChange{{
+		InstanceId:    id,
+		PreviousState: ec2test.Running,
+		CurrentState:  ec2test.Stopping,
+	}})
+	// Transitional states are reported once.
+	c.Assert(state(), Equals, "stopped")
+	c.Assert(state(), Equals, "stopped")
+
+	_, err = s.ec2.RebootInstances(id)
+	c.Assert(errorCode(err), Equals, "IncorrectInstanceState")
+
+	started, err := s.ec2.StartInstances(id)
+	c.Assert(err, IsNil)
+	c.Assert(started.StateChanges, DeepEquals, []ec2.InstanceStateChange{{
+		InstanceId:    id,
+		PreviousState: ec2test.Stopped,
+		CurrentState:  ec2test.Pending,
+	}})
+	c.Assert(state(), Equals, "running")
+
+	// Starting a running instance changes nothing.
+	started, err = s.ec2.StartInstances(id)
+	c.Assert(err, IsNil)
+	c.Assert(started.StateChanges[0].CurrentState, Equals, ec2test.Running)
+
+	_, err = s.ec2.StopInstances("i-999")
+	c.Assert(errorCode(err), Equals, "InvalidInstanceID.NotFound")
+
+	_, err = s.ec2.TerminateInstances(ids)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.StartInstances(id)
+	c.Assert(errorCode(err), Equals, "IncorrectInstanceState")
+	_, err = s.ec2.StopInstances(id)
+	c.Assert(errorCode(err), Equals, "IncorrectInstanceState")
+	_, err = s.ec2.RebootInstances(id)
+	c.Assert(err, IsNil)
+}
+
+func (s *LocalServerSuite) TestInstanceTransitions(c *C) {
+	clock := ec2test.NewClock(time.Now())
+	s.srv.srv.SetTransitions(clock, time.Minute)
+	defer s.srv.srv.SetTransitions(nil, 0)
+
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+	})
+	c.Assert(err, IsNil)
+	id := inst.Instances[0].InstanceId
+	defer terminateInstances(c, s.ec2, []string{id})
+	c.Assert(inst.Instances[0].State, Equals, ec2test.Pending)
+	state := func() string {
+		resp, err := s.ec2.Instances([]string{id}, nil)
+		c.Assert(err, IsNil)
+		return resp.Reservations[0].Instances[0].State.Name
+	}
+
+	// Transitional states last until the delay has passed on the clock.
+	c.Assert(state(), Equals, "pending")
+	_, err = s.ec2.StopInstances(id)
+	c.Assert(errorCode(err), Equals, "IncorrectInstanceState")
+	clock.Advance(59 * time.Second)
+	c.Assert(state(), Equals, "pending")
+	clock.Advance(time.Second)
+	c.Assert(state(), Equals, "running")
+
+	_, err = s.ec2.StopInstances(id)
+	c.Assert(err, IsNil)
+	c.Assert(state(), Equals, "stopping")
+	_, err = s.ec2.StartInstances(id)
+	c.Assert(errorCode(err), Equals, "IncorrectInstanceState")
+	clock.Advance(time.Minute)
+	c.Assert(state(), Equals, "stopped")
+
+	_, err = s.ec2.StartInstances(id)
+	c.Assert(err, IsNil)
+	c.Assert(state(), Equals, "pending")
+	clock.Advance(time.Minute)
+	c.Assert(state(), Equals, "running")
+
+	_, err = s.ec2.TerminateInstances([]string{id})
+	c.Assert(err, IsNil)
+	c.Assert(state(), Equals, "shutting-down")
+	f := ec2.NewFilter()
+	f.Add("instance-state-name", "terminated")
+	resp, err := s.ec2.Instances([]string{id}, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Reservations, HasLen, 0)
+	clock.Advance(time.Minute)
+	c.Assert(state(), Equals, "terminated")
+}
+
+func (s *LocalServerSuite) TestInstanceAttributes(c *C) {
+	// Let the instance reach the next state on the next request, so
+	// that it can be stopped.
+	s.srv.srv.SetTransitions(ec2test.NewClock(time.Now()), 0)
+	defer s.srv.srv.SetTransitions(nil, 0)
+
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:               imageId,
+		InstanceType:          "t1.micro",
+		UserData:              []byte("hello"),
+		DisableAPITermination: true,
+	})
+	c.Assert(err, IsNil)
+	id := inst.Instances[0].InstanceId
+	c.Assert(inst.Instances[0].SourceDestCheck, Equals, true)
+	attr := func(name string) *ec2.InstanceAttributeResp {
+		resp, err := s.ec2.InstanceAttribute(id, name)
+		c.Assert(err, IsNil)
+		c.Assert(resp.InstanceId, Equals, id)
+		return resp
+	}
+	c.Assert(attr("instanceType").InstanceType, Equals, "t1.micro")
+	c.Assert(string(attr("userData").UserData), Equals, "hello")
+	c.Assert(attr("sourceDestCheck").SourceDestCheck, Equals, true)
+	c.Assert(attr("disableApiTermination").DisableAPITermination, Equals, true)
+	c.Assert(attr("instanceInitiatedShutdownBehavior").ShutdownBehavior, Equals, "stop")
+
+	// Termination protection must be disabled first.
+	_, err = s.ec2.TerminateInstances([]string{id})
+	c.Assert(errorCode(err), Equals, "OperationNotPermitted")
+	disable := false
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{DisableAPITermination: &disable})
+	c.Assert(err, IsNil)
+	c.Assert(attr("disableApiTermination").DisableAPITermination, Equals, false)
+	defer terminateInstances(c, s.ec2, []string{id})
+
+	check := false
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{SourceDestCheck: &check})
+	c.Assert(err, IsNil)
+	c.Assert(attr("sourceDestCheck").SourceDestCheck, Equals, false)
+	resp, err := s.ec2.Instances([]string{id}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Reservations[0].Instances[0].SourceDestCheck, Equals, false)
+
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{ShutdownBehavior: "terminate"})
+	c.Assert(err, IsNil)
+	c.Assert(attr("instanceInitiatedShutdownBehavior").ShutdownBehavior, Equals, "terminate")
+
+	// The instance type and user data can only be changed while the
+	// instance is stopped.
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{InstanceType: "m3.medium"})
+	c.Assert(errorCode(err), Equals, "IncorrectInstanceState")
+	_, err = s.ec2.StopInstances(id)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{InstanceType: "m3.medium"})
+	c.Assert(err, IsNil)
+	c.Assert(attr("instanceType").InstanceType, Equals, "m3.medium")
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{UserData: []byte("goodbye")})
+	c.Assert(err, IsNil)
+	c.Assert(string(attr("userData").UserData), Equals, "goodbye")
+
+	// Only one attribute can be changed at a time.
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{
+		InstanceType:    "t1.micro",
+		SourceDestCheck: &check,
+	})
+	c.Assert(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{})
+	c.Assert(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.ModifyInstanceAttribute(id, &ec2.ModifyInstanceAttribute{ShutdownBehavior: "hibernate"})
+	c.Assert(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.InstanceAttribute(id, "kernel")
+	c.Assert(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.InstanceAttribute("i-999", "instanceType")
+	c.Assert(errorCode(err), Equals, "InvalidInstanceID.NotFound")
+}
+
+func (s *LocalServerSuite) TestConsoleOutputAndStatus(c *C) {
+	ids := s.srv.srv.NewInstances(2, "t1.micro", imageId, ec2test.Running, nil)
+	defer terminateInstances(c, s.ec2, ids)
+
+	out, err := s.ec2.ConsoleOutput(ids[0])
+	c.Assert(err, IsNil)
+	c.Assert(out.InstanceId, Equals, ids[0])
+	c.Assert(out.Output, HasLen, 0)
+	s.srv.srv.SetConsoleOutput(ids[0], []byte("Kernel panic - not syncing\n"))
+	out, err = s.ec2.ConsoleOutput(ids[0])
+	c.Assert(err, IsNil)
+	c.Assert(string(out.Output), Equals, "Kernel panic - not syncing\n")
+	c.Assert(out.Timestamp, Not(Equals), "")
+	_, err = s.ec2.ConsoleOutput("i-999")
+	c.Assert(errorCode(err), Equals, "InvalidInstanceID.NotFound")
+
+	// Running instances pass their checks by default.
+	resp, err := s.ec2.InstanceStatus(ids, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Statuses, HasLen, 2)
+	for i, st := range resp.Statuses {
+		c.Check(st.InstanceId, Equals, ids[i])
+		c.Check(st.State, Equals, ec2test.Running)
+		c.Check(st.SystemStatus, DeepEquals, ec2.InstanceStatusSummary{
+			Status:  "ok",
+			Details: []ec2.InstanceStatusDetail{{Name: "reachability", Status: "passed"}},
+		})
+		c.Check(st.InstanceStatus.Status, Equals, "ok")
+	}
+
+	event := ec2.InstanceStatusEvent{
+		Code:        "instance-retirement",
+		Description: "The instance is running on degraded hardware",
+		NotBefore:   "2016-10-10T00:00:00Z",
+	}
+	s.srv.srv.SetInstanceStatus(ids[1], "impaired", "insufficient-data", event)
+	filter := ec2.NewFilter()
+	filter.Add("system-status.reachability", "failed")
+	filter.Add("event.code", "instance-retirement")
+	resp, err = s.ec2.InstanceStatus(nil, false, filter)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Statuses, HasLen, 1)
+	st := resp.Statuses[0]
+	c.Check(st.InstanceId, Equals, ids[1])
+	c.Check(st.SystemStatus.Status, Equals, "impaired")
+	c.Check(st.InstanceStatus.Status, Equals, "insufficient-data")
+	c.Check(st.Events, DeepEquals, []ec2.InstanceStatusEvent{event})
+
+	// Stopped instances are only reported when all instances are
+	// requested.
+	_, err = s.ec2.StopInstances(ids[0])
+	c.Assert(err, IsNil)
+	resp, err = s.ec2.InstanceStatus(ids, false, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Statuses, HasLen, 1)
+	c.Check(resp.Statuses[0].InstanceId, Equals, ids[1])
+	resp, err = s.ec2.InstanceStatus(ids[:1], true, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Statuses, HasLen, 1)
+	c.Check(resp.Statuses[0].State, Equals, ec2test.Stopping)
+	c.Check(resp.Statuses[0].SystemStatus, DeepEquals, ec2.InstanceStatusSummary{Status: "not-applicable"})
+
+	_, err = s.ec2.InstanceStatus([]string{"i-999"}, false, nil)
+	c.Assert(errorCode(err), Equals, "InvalidInstanceID.NotFound")
+}
+
 func (s *LocalServerSuite) TestAvailabilityZones(c *C) {
 	s.srv.srv.SetAvailabilityZones([]ec2.AvailabilityZoneInfo{{
 		AvailabilityZone: ec2.AvailabilityZone{
@@ -585,6 +981,168 @@ func (s *ServerTests) TestDuplicateIPPerm(c *C) {
 	c.Assert(errorCode(err), Equals, "InvalidPermission.Duplicate")
 }
 
+func (s *ServerTests) TestEgressIPPerms(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.17.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	defer s.deleteVPCs(c, []string{vpcId})
+
+	g0 := s.makeTestGroupVPC(c, vpcId, "goamz-test-egress0", "ec2test egress group 0")
+	g1 := s.makeTestGroupVPC(c, vpcId, "goamz-test-egress1", "ec2test egress group 1")
+	defer s.deleteGroups(c, []ec2.SecurityGroup{g0, g1})
+
+	// VPC groups allow all outbound traffic by default.
+	allTraffic := ec2.IPPerm{
+		Protocol:  "-1",
+		SourceIPs: []string{"0.0.0.0/0"},
+	}
+	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{g0}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Groups, HasLen, 1)
+	c.Check(resp.Groups[0].VPCId, Equals, vpcId)
+	c.Check(resp.Groups[0].IPPerms, HasLen, 0)
+	c.Check(resp.Groups[0].IPPermsEgress, DeepEquals, []ec2.IPPerm{allTraffic})
+
+	_, err = s.ec2.AuthorizeSecurityGroupEgress(g0, []ec2.IPPerm{allTraffic})
+	c.Check(errorCode(err), Equals, "InvalidPermission.Duplicate")
+	_, err = s.ec2.RevokeSecurityGroupEgress(g0, []ec2.IPPerm{allTraffic})
+	c.Assert(err, IsNil)
+	_, err = s.ec2.RevokeSecurityGroupEgress(g0, []ec2.IPPerm{allTraffic})
+	c.Check(errorCode(err), Equals, "InvalidPermission.NotFound")
+
+	_, err = s.ec2.AuthorizeSecurityGroupEgress(g0, []ec2.IPPerm{{
+		Protocol:    "tcp",
+		FromPort:    443,
+		ToPort:      443,
+		SourceIPs:   []string{"10.17.0.0/16", "10.18.0.0/16"},
+		SourceIPv6s: []string{"2001:db8::/32"},
+		Descriptions: map[string]string{
+			"10.17.0.0/16":  "HTTPS out",
+			"2001:db8::/32": "HTTPS out over IPv6",
+		},
+	}, {
+		Protocol:     "tcp",
+		FromPort:     3128,
+		ToPort:       3128,
+		SourceGroups: []ec2.UserSecurityGroup{{Id: g1.Id, Description: "Proxies"}},
+	}})
+	c.Assert(err, IsNil)
+
+	f := ec2.NewFilter()
+	f.Add("egress.ip-permission.ipv6-cidr", "2001:db8::/32")
+	resp, err = s.ec2.SecurityGroups(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Groups, HasLen, 1)
+	c.Check(resp.Groups[0].Id, Equals, g0.Id)
+	c.Check(resp.Groups[0].IPPerms, HasLen, 0)
+
+	perms := resp.Groups[0].IPPermsEgress
+	c.Assert(perms, HasLen, 2)
+	if perms[0].FromPort != 443 {
+		perms[0], perms[1] = perms[1], perms[0]
+	}
+	sort.Strings(perms[0].SourceIPs)
+	c.Check(perms[0], DeepEquals, ec2.IPPerm{
+		Protocol:    "tcp",
+		FromPort:    443,
+		ToPort:      443,
+		SourceIPs:   []string{"10.17.0.0/16", "10.18.0.0/16"},
+		SourceIPv6s: []string{"2001:db8::/32"},
+		Descriptions: map[string]string{
+			"10.17.0.0/16":  "HTTPS out",
+			"2001:db8::/32": "HTTPS out over IPv6",
+		},
+	})
+
+	// A rule read back can be authorized again without changing
+	// the description of any of its ranges.
+	_, err = s.ec2.RevokeSecurityGroupEgress(g0, perms[:1])
+	c.Assert(err, IsNil)
+	_, err = s.ec2.AuthorizeSecurityGroupEgress(g0, perms[:1])
+	c.Assert(err, IsNil)
+	resp, err = s.ec2.SecurityGroups([]ec2.SecurityGroup{g0}, nil)
+	c.Assert(err, IsNil)
+	again := resp.Groups[0].IPPermsEgress
+	c.Assert(again, HasLen, 2)
+	if again[0].FromPort != 443 {
+		again[0], again[1] = again[1], again[0]
+	}
+	sort.Strings(again[0].SourceIPs)
+	c.Check(again[0], DeepEquals, perms[0])
+	c.Assert(perms[1].SourceGroups, HasLen, 1)
+	c.Check(perms[1].SourceGroups[0].Id, Equals, g1.Id)
+	c.Check(perms[1].SourceGroups[0].Description, Equals, "Proxies")
+
+	// Outbound rules referring to a group keep it in use.
+	_, err = s.ec2.DeleteSecurityGroup(g1)
+	c.Check(errorCode(err), Equals, "InvalidGroup.InUse")
+
+	// Revoking ignores descriptions.
+	_, err = s.ec2.RevokeSecurityGroupEgress(g0, []ec2.IPPerm{{
+		Protocol:     "tcp",
+		FromPort:     3128,
+		ToPort:       3128,
+		SourceGroups: []ec2.UserSecurityGroup{{Id: g1.Id}},
+	}})
+	c.Assert(err, IsNil)
+	_, err = s.ec2.DeleteSecurityGroup(g1)
+	c.Assert(err, IsNil)
+}
+
+func (s *LocalServerSuite) TestEgressIPPermsValidation(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.18.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcGroup, err := s.ec2.CreateSecurityGroupVPC(vpcResp.VPC.Id, "vpc-egress", "vpc egress")
+	c.Assert(err, IsNil)
+	classicGroup, err := s.ec2.CreateSecurityGroup("classic-egress", "classic egress")
+	c.Assert(err, IsNil)
+
+	perms := []ec2.IPPerm{{
+		Protocol:      "tcp",
+		FromPort:      443,
+		ToPort:        443,
+		PrefixListIds: []string{"pl-12c4e678"},
+	}}
+	_, err = s.ec2.AuthorizeSecurityGroupEgress(classicGroup.SecurityGroup, perms)
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.AuthorizeSecurityGroupEgress(ec2.SecurityGroup{Name: "vpc-egress"}, perms)
+	c.Check(errorCode(err), Equals, "MissingParameter")
+	_, err = s.ec2.AuthorizeSecurityGroupEgress(vpcGroup.SecurityGroup, perms)
+	c.Assert(err, IsNil)
+
+	_, err = s.ec2.AuthorizeSecurityGroupEgress(vpcGroup.SecurityGroup, []ec2.IPPerm{{
+		Protocol:      "tcp",
+		FromPort:      80,
+		ToPort:        80,
+		PrefixListIds: []string{"s3"},
+	}})
+	c.Check(errorCode(err), Equals, "InvalidPrefixListId.Malformed")
+	_, err = s.ec2.AuthorizeSecurityGroup(vpcGroup.SecurityGroup, []ec2.IPPerm{{
+		Protocol:    "tcp",
+		FromPort:    80,
+		ToPort:      80,
+		SourceIPv6s: []string{"10.0.0.0/8"},
+	}})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+
+	// Classic groups have no outbound rules.
+	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{classicGroup.SecurityGroup}, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.Groups[0].IPPermsEgress, HasLen, 0)
+
+	f := ec2.NewFilter()
+	f.Add("egress.ip-permission.prefix-list-id", "pl-12c4e678")
+	resp, err = s.ec2.SecurityGroups(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Groups, HasLen, 1)
+	c.Check(resp.Groups[0].Id, Equals, vpcGroup.Id)
+	f = ec2.NewFilter()
+	f.Add("ip-permission.prefix-list-id", "pl-12c4e678")
+	resp, err = s.ec2.SecurityGroups(nil, f)
+	c.Assert(err, IsNil)
+	c.Check(resp.Groups, HasLen, 0)
+}
+
 type filterSpec struct {
 	name   string
 	values []string
@@ -647,6 +1205,11 @@ func (s *ServerTests) TestInstanceFiltering(c *C) {
 
 	defer terminateInstances(c, s.ec2, ids(0, 1, 2))
 
+	nameTag := []ec2.Tag{{"Name", sessionName("filtered")}}
+	_, err = s.ec2.CreateTags(ids(1), nameTag)
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteTags(ids(1), []ec2.DeleteTag{{Key: "Name"}})
+
 	tests := []struct {
 		about       string
 		instanceIds []string     // instanceIds argument to Instances method.
@@ -735,6 +1298,65 @@ func (s *ServerTests) TestInstanceFiltering(c *C) {
 				{"subnet-id", []string{subId}},
 			},
 			resultIds: ids(2),
+		}, {
+			about: "check that filtering on instance type and state works",
+			filters: []filterSpec{
+				{"instance-type", []string{"t1.micro"}},
+				{"instance-state-name", []string{"pending", "running"}},
+			},
+			resultIds:  ids(0, 1, 2),
+			allowExtra: true,
+		}, {
+			about: "check that filtering on the private IP address works",
+			filters: []filterSpec{
+				{"instance-id", ids(0, 1, 2)},
+				{"private-ip-address", []string{insts[2].PrivateIPAddress}},
+			},
+			resultIds: ids(2),
+		}, {
+			about: "check that filtering on the launch time works",
+			filters: []filterSpec{
+				{"instance-id", ids(0, 1, 2)},
+				{"launch-time", []string{insts[2].LaunchTime[:len("2006-01-02")] + "*"}},
+			},
+			resultIds:  ids(2),
+			allowExtra: true,
+		}, {
+			about: "check that filtering on network interface attributes works",
+			filters: []filterSpec{
+				{"network-interface.subnet-id", []string{subId}},
+				{"network-interface.vpc-id", []string{vpcId}},
+			},
+			resultIds: ids(2),
+		}, {
+			about: "check that filtering on tags works",
+			filters: []filterSpec{
+				{"tag:Name", []string{sessionName("filtered")}},
+			},
+			resultIds: ids(1),
+		}, {
+			about: "check that wildcards match tag values",
+			filters: []filterSpec{
+				{"tag:Name", []string{sessionName("*ilt?red")}},
+			},
+			resultIds: ids(1),
+		}, {
+			about: "check that wildcards match group names",
+			filters: []filterSpec{
+				{"group-name", []string{sessionName("testgroup?")}},
+			},
+			resultIds: ids(0, 1, 2),
+		}, {
+			about: "check that an escaped wildcard matches literally",
+			filters: []filterSpec{
+				{"group-name", []string{sessionName(`testgroup\?`)}},
+			},
+		}, {
+			about: "check that an unknown filter gives an error",
+			filters: []filterSpec{
+				{"no-such-filter", []string{"value"}},
+			},
+			err: `.*\(InvalidParameterValue\)`,
 		},
 	}
 	for i, t := range tests {
@@ -925,6 +1547,8 @@ func (s *ServerTests) TestGroupFiltering(c *C) {
 		_, err := s.ec2.AuthorizeSecurityGroup(g[i+1], ps)
 		c.Assert(err, IsNil)
 	}
+	_, err = s.ec2.CreateTags([]string{g[4].Id}, []ec2.Tag{{"Purpose", "filtering"}})
+	c.Assert(err, IsNil)
 
 	groups := func(indices ...int) (gs []ec2.SecurityGroup) {
 		for _, index := range indices {
@@ -994,6 +1618,11 @@ func (s *ServerTests) TestGroupFiltering(c *C) {
 		filterCheck("ip-permission.from-port", "200", groups(2, 3)),
 		filterCheck("ip-permission.to-port", "200", groups(1)),
 		filterCheck("vpc-id", vpcId, groups(0)),
+		filterCheck("group-name", sessionName("testgroup?"), groups(1, 2, 3, 4)),
+		filterCheck("description", "testdescription* vpc", groups(0)),
+		filterCheck("ip-permission.cidr", "1.2.3.*", groups(1)),
+		filterCheck("tag:Purpose", "filter*", groups(4)),
+		filterCheck("tag-key", "Purpose", groups(4)),
 		// TODO owner-id
 	}
 	for i, t := range tests {
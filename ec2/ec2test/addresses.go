@@ -0,0 +1,364 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// address holds a simulated ec2 Elastic IP address.
+type address struct {
+	ec2.Address
+}
+
+func (a *address) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "domain":
+		return a.Domain == value, nil
+	case "public-ip":
+		return a.PublicIP == value, nil
+	case "allocation-id":
+		return a.AllocationId == value, nil
+	case "association-id":
+		return a.AssociationId == value, nil
+	case "instance-id":
+		return a.InstanceId == value, nil
+	case "network-interface-id":
+		return a.NetworkInterfaceId == value, nil
+	case "network-interface-owner-id":
+		return a.NetworkInterfaceOwnerId == value, nil
+	case "private-ip-address":
+		return a.PrivateIPAddress == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+func (a *address) associated() bool {
+	return a.InstanceId != "" || a.NetworkInterfaceId != ""
+}
+
+// addressByIP returns the address with the given public IP.
+// It must be called with srv.mu held.
+func (srv *Server) addressByIP(ip string) *address {
+	if ip == "" {
+		fatalf(400, "MissingParameter", "missing publicIp")
+	}
+	a := srv.addresses[ip]
+	if a == nil {
+		fatalf(400, "InvalidAddress.NotFound", "Address '%s' not found.", ip)
+	}
+	return a
+}
+
+// addressByAllocation returns the address with the given allocation
+// id. It must be called with srv.mu held.
+func (srv *Server) addressByAllocation(id string) *address {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing allocationId")
+	}
+	for _, a := range srv.addresses {
+		if a.AllocationId == id {
+			return a
+		}
+	}
+	fatalf(400, "InvalidAllocationID.NotFound", "The allocation ID '%s' does not exist", id)
+	return nil
+}
+
+// primaryIface returns the network interface of inst with device
+// index 0, or nil if it has none. It must be called with srv.mu held.
+func (srv *Server) primaryIface(inst *Instance) *iface {
+	for _, nic := range inst.ifaces {
+		if nic.Attachment.DeviceIndex == 0 {
+			return srv.ifaces[nic.Id]
+		}
+	}
+	return nil
+}
+
+// setIfaceAssociation records assoc as the association of the given
+// private IP address of nic, and updates the instance nic is attached
+// to, if any. An empty assoc removes the association. It must be
+// called with srv.mu held.
+func (srv *Server) setIfaceAssociation(nic *iface, privateIP string, assoc ec2.NetworkInterfaceAssociation) {
+	// The private IPs may be shared with other interfaces created
+	// by the same RunInstances request, so copy them first.
+	nic.PrivateIPs = append([]ec2.PrivateIP(nil), nic.PrivateIPs...)
+	for i := range nic.PrivateIPs {
+		if nic.PrivateIPs[i].Address == privateIP {
+			nic.PrivateIPs[i].Association = assoc
+		}
+	}
+	primary := privateIP == nic.PrivateIPAddress
+	if primary {
+		nic.Association = assoc
+	}
+	inst := srv.instances[nic.Attachment.InstanceId]
+	if inst == nil {
+		return
+	}
+	for i := range inst.ifaces {
+		if inst.ifaces[i].Id == nic.Id {
+			inst.ifaces[i] = nic.NetworkInterface
+		}
+	}
+	if primary && nic.Attachment.DeviceIndex == 0 {
+		inst.publicIP = assoc.PublicIP
+	}
+}
+
+// clearAddress removes any association of a.
+// It must be called with srv.mu held.
+func (srv *Server) clearAddress(a *address) {
+	if a.NetworkInterfaceId != "" {
+		if nic := srv.ifaces[a.NetworkInterfaceId]; nic != nil {
+			srv.setIfaceAssociation(nic, a.PrivateIPAddress, ec2.NetworkInterfaceAssociation{})
+		}
+	} else if inst := srv.instances[a.InstanceId]; inst != nil {
+		inst.publicIP = ""
+	}
+	a.InstanceId = ""
+	a.AssociationId = ""
+	a.NetworkInterfaceId = ""
+	a.NetworkInterfaceOwnerId = ""
+	a.PrivateIPAddress = ""
+}
+
+// associateIface associates a with the given private IP address of
+// nic, removing any previous association of a. It must be called
+// with srv.mu held.
+func (srv *Server) associateIface(a *address, nic *iface, privateIP string) {
+	srv.clearAddress(a)
+	a.AssociationId = fmt.Sprintf("eipassoc-%d", srv.associationId.next())
+	a.InstanceId = nic.Attachment.InstanceId
+	a.NetworkInterfaceId = nic.Id
+	a.NetworkInterfaceOwnerId = nic.OwnerId
+	a.PrivateIPAddress = privateIP
+	srv.setIfaceAssociation(nic, privateIP, ec2.NetworkInterfaceAssociation{
+		PublicIP:      a.PublicIP,
+		PublicDNSName: fmt.Sprintf("%s.testing.invalid", a.PublicIP),
+		IPOwnerId:     ownerId,
+		AllocationId:  a.AllocationId,
+		AssociationId: a.AssociationId,
+	})
+}
+
+// clearInstanceAddresses removes the associations of all
+// addresses with inst. It must be called with srv.mu held.
+func (srv *Server) clearInstanceAddresses(inst *Instance) {
+	for _, a := range srv.addresses {
+		if a.InstanceId == inst.id() {
+			srv.clearAddress(a)
+		}
+	}
+}
+
+func (srv *Server) allocateAddress(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	domain := req.Form.Get("Domain")
+	switch domain {
+	case "":
+		domain = "standard"
+	case "standard", "vpc":
+	default:
+		fatalf(400, "InvalidParameterValue", "Invalid value '%s' for domain.", domain)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	n := srv.addressId.next()
+	a := &address{ec2.Address{
+		PublicIP: fmt.Sprintf("54.0.%d.%d", n/256, n%256),
+		Domain:   domain,
+	}}
+	if domain == "vpc" {
+		a.AllocationId = fmt.Sprintf("eipalloc-%d", n)
+	}
+	srv.addresses[a.PublicIP] = a
+	return &ec2.AllocateAddressResp{
+		RequestId:    reqId,
+		PublicIP:     a.PublicIP,
+		Domain:       a.Domain,
+		AllocationId: a.AllocationId,
+	}
+}
+
+func (srv *Server) describeAddresses(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var addrs []*address
+	for ip := range parseIDs(req.Form, "PublicIp.") {
+		addrs = append(addrs, srv.addressByIP(ip))
+	}
+	for id := range parseIDs(req.Form, "AllocationId.") {
+		addrs = append(addrs, srv.addressByAllocation(id))
+	}
+	if len(addrs) == 0 {
+		for _, a := range srv.addresses {
+			addrs = append(addrs, a)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.AddressesResp
+	resp.RequestId = reqId
+	for _, a := range addrs {
+		ok, err := f.ok(a)
+		if ok {
+			resp.Addresses = append(resp.Addresses, a.Address)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe addresses: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) associateAddress(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	instId := req.Form.Get("InstanceId")
+	nicId := req.Form.Get("NetworkInterfaceId")
+	privateIP := req.Form.Get("PrivateIpAddress")
+	allowReassoc := false
+	if v := req.Form.Get("AllowReassociation"); v != "" {
+		var err error
+		allowReassoc, err = strconv.ParseBool(v)
+		if err != nil {
+			fatalf(400, "InvalidParameterValue", "bad flag AllowReassociation: %s", v)
+		}
+	}
+	if instId != "" && nicId != "" {
+		fatalf(400, "InvalidParameterCombination", "Only one of instanceId or networkInterfaceId may be specified")
+	}
+	if instId == "" && nicId == "" {
+		fatalf(400, "MissingParameter", "Either instanceId or networkInterfaceId must be specified")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var inst *Instance
+	if instId != "" {
+		inst = srv.instances[instId]
+		if inst == nil || srv.consistency.Hidden(instId) {
+			fatalf(400, "InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", instId)
+		}
+	}
+	allocId := req.Form.Get("AllocationId")
+	if allocId == "" {
+		// EC2-Classic addresses are moved silently from any
+		// instance they were associated with.
+		a := srv.addressByIP(req.Form.Get("PublicIp"))
+		if a.Domain != "standard" {
+			fatalf(400, "InvalidParameterValue", "You must specify an allocation id when mapping address '%s' to a VPC instance", a.PublicIP)
+		}
+		if inst == nil || inst.vpcId != "" {
+			fatalf(400, "InvalidParameterCombination", "You must specify an allocation id when mapping an address to a VPC instance")
+		}
+		srv.clearAddress(a)
+		srv.clearInstanceAddresses(inst)
+		a.InstanceId = inst.id()
+		inst.publicIP = a.PublicIP
+		return &ec2.AssociateAddressResp{
+			RequestId: reqId,
+			Return:    true,
+		}
+	}
+
+	a := srv.addressByAllocation(allocId)
+	var nic *iface
+	if inst != nil {
+		nic = srv.primaryIface(inst)
+		if nic == nil {
+			fatalf(400, "InvalidInstanceID", "The instance '%s' is not in a VPC", instId)
+		}
+	} else {
+		nic = srv.ifaces[nicId]
+		if nic == nil {
+			fatalf(400, "InvalidNetworkInterfaceID.NotFound", "The networkInterface ID '%s' does not exist", nicId)
+		}
+	}
+	if privateIP == "" {
+		privateIP = nic.PrivateIPAddress
+	}
+	var target *ec2.PrivateIP
+	for i := range nic.PrivateIPs {
+		if nic.PrivateIPs[i].Address == privateIP {
+			target = &nic.PrivateIPs[i]
+		}
+	}
+	if target == nil {
+		fatalf(400, "InvalidParameterValue", "The private IP address '%s' is not associated with the network interface '%s'", privateIP, nic.Id)
+	}
+	var previous *address
+	if ip := target.Association.PublicIP; ip != "" && ip != a.PublicIP {
+		previous = srv.addresses[ip]
+	}
+	if (a.associated() || previous != nil) && !allowReassoc {
+		fatalf(400, "Resource.AlreadyAssociated", "resource %s is already associated", a.AllocationId)
+	}
+	if previous != nil {
+		srv.clearAddress(previous)
+	}
+	srv.associateIface(a, nic, privateIP)
+	return &ec2.AssociateAddressResp{
+		RequestId:     reqId,
+		Return:        true,
+		AssociationId: a.AssociationId,
+	}
+}
+
+func (srv *Server) disassociateAddress(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if assocId := req.Form.Get("AssociationId"); assocId != "" {
+		var found *address
+		for _, a := range srv.addresses {
+			if a.AssociationId == assocId {
+				found = a
+			}
+		}
+		if found == nil {
+			fatalf(400, "InvalidAssociationID.NotFound", "The association ID '%s' does not exist", assocId)
+		}
+		srv.clearAddress(found)
+	} else {
+		srv.clearAddress(srv.addressByIP(req.Form.Get("PublicIp")))
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DisassociateAddressResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) releaseAddress(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var a *address
+	if allocId := req.Form.Get("AllocationId"); allocId != "" {
+		a = srv.addressByAllocation(allocId)
+		if a.associated() {
+			fatalf(400, "InvalidIPAddress.InUse", "Address '%s' is in use.", a.PublicIP)
+		}
+	} else {
+		// EC2-Classic addresses are disassociated automatically.
+		a = srv.addressByIP(req.Form.Get("PublicIp"))
+		if a.Domain != "standard" {
+			fatalf(400, "InvalidParameterValue", "You must specify an allocation id when releasing a VPC elastic IP address")
+		}
+		srv.clearAddress(a)
+	}
+	delete(srv.addresses, a.PublicIP)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "ReleaseAddressResponse"},
+		RequestId: reqId,
+	}
+}
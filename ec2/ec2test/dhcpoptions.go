@@ -0,0 +1,188 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// dhcpOptions holds a simulated ec2 set of DHCP options.
+type dhcpOptions struct {
+	ec2.DHCPOptions
+}
+
+func (d *dhcpOptions) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "dhcp-options-id":
+		return d.Id == value, nil
+	case "key":
+		for _, c := range d.Configurations {
+			if c.Key == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "value":
+		for _, c := range d.Configurations {
+			for _, v := range c.Values {
+				if v == value {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+var dhcpOptionKeys = map[string]bool{
+	"domain-name":          true,
+	"domain-name-servers":  true,
+	"ntp-servers":          true,
+	"netbios-name-servers": true,
+	"netbios-node-type":    true,
+}
+
+// defaultDHCPConfigurations holds the configurations of the DHCP
+// options created with the server, which are used by new VPCs.
+var defaultDHCPConfigurations = []ec2.DHCPConfiguration{
+	{Key: "domain-name", Values: []string{"ec2.internal"}},
+	{Key: "domain-name-servers", Values: []string{"AmazonProvidedDNS"}},
+}
+
+// newDHCPOptions creates a set of DHCP options with the given
+// configurations. It must be called with srv.mu held.
+func (srv *Server) newDHCPOptions(configs []ec2.DHCPConfiguration) *dhcpOptions {
+	d := &dhcpOptions{ec2.DHCPOptions{
+		Id:             fmt.Sprintf("dopt-%d", srv.dhcpOptsId.next()),
+		Configurations: configs,
+	}}
+	srv.dhcpOptions[d.Id] = d
+	return d
+}
+
+// dhcpOptionsById returns the DHCP options with the given id.
+// It must be called with srv.mu held.
+func (srv *Server) dhcpOptionsById(id string) *dhcpOptions {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing dhcpOptionsId")
+	}
+	d := srv.dhcpOptions[id]
+	if d == nil {
+		fatalf(400, "InvalidDhcpOptionID.NotFound", "The dhcpOption ID '%s' does not exist", id)
+	}
+	return d
+}
+
+// parseDHCPConfigurations returns the DHCP configurations specified
+// in form, in request order.
+func parseDHCPConfigurations(form url.Values) []ec2.DHCPConfiguration {
+	var configs []ec2.DHCPConfiguration
+	for i := 1; ; i++ {
+		prefix := "DhcpConfiguration." + strconv.Itoa(i)
+		key := form.Get(prefix + ".Key")
+		if key == "" {
+			break
+		}
+		if !dhcpOptionKeys[key] {
+			fatalf(400, "InvalidParameterValue", "Value (%s) for parameter name is invalid. Unknown DHCP option", key)
+		}
+		c := ec2.DHCPConfiguration{Key: key}
+		for j := 1; ; j++ {
+			value := form.Get(prefix + ".Value." + strconv.Itoa(j))
+			if value == "" {
+				break
+			}
+			c.Values = append(c.Values, value)
+		}
+		if len(c.Values) == 0 {
+			fatalf(400, "InvalidParameterValue", "Value for DHCP option %s must be specified", key)
+		}
+		configs = append(configs, c)
+	}
+	if len(configs) == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter dhcpConfiguration")
+	}
+	return configs
+}
+
+func (srv *Server) createDHCPOptions(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	configs := parseDHCPConfigurations(req.Form)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	d := srv.newDHCPOptions(configs)
+	return &ec2.CreateDHCPOptionsResp{
+		RequestId:   reqId,
+		DHCPOptions: d.DHCPOptions,
+	}
+}
+
+func (srv *Server) deleteDHCPOptions(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	d := srv.dhcpOptionsById(req.Form.Get("DhcpOptionsId"))
+	for _, v := range srv.vpcs {
+		if v.DHCPOptionsId == d.Id {
+			fatalf(400, "DependencyViolation", "The dhcpOptions '%s' has dependencies and cannot be deleted.", d.Id)
+		}
+	}
+	delete(srv.dhcpOptions, d.Id)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteDhcpOptionsResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describeDHCPOptions(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var opts []*dhcpOptions
+	for id := range parseIDs(req.Form, "DhcpOptionsId.") {
+		opts = append(opts, srv.dhcpOptionsById(id))
+	}
+	if len(opts) == 0 {
+		for _, d := range srv.dhcpOptions {
+			opts = append(opts, d)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.DHCPOptionsResp
+	resp.RequestId = reqId
+	for _, d := range opts {
+		ok, err := f.ok(d)
+		if ok {
+			resp.DHCPOptions = append(resp.DHCPOptions, d.DHCPOptions)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe DHCP options: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) associateDHCPOptions(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.vpc(req.Form.Get("VpcId"))
+	id := req.Form.Get("DhcpOptionsId")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if id != "default" {
+		srv.dhcpOptionsById(id)
+	}
+	v.DHCPOptionsId = id
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "AssociateDhcpOptionsResponse"},
+		RequestId: reqId,
+	}
+}
@@ -6,8 +6,11 @@
 package ec2test
 
 import (
+	"bytes"
 	"fmt"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -60,6 +63,60 @@ func newFilter(form url.Values) filter {
 	return f
 }
 
+// matchValue reports whether s matches the filter value pattern. As
+// in EC2, a "*" in pattern matches any sequence of characters, a "?"
+// matches any single character, and a backslash escapes the character
+// that follows it.
+func matchValue(pattern, s string) bool {
+	if !strings.ContainsAny(pattern, `*?\`) {
+		return pattern == s
+	}
+	var expr bytes.Buffer
+	expr.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			expr.WriteString("(?s:.*)")
+		case r == '?':
+			expr.WriteString("(?s:.)")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if escaped {
+		expr.WriteString(regexp.QuoteMeta(`\`))
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()).MatchString(s)
+}
+
+// matchAny reports whether any of the given values matches the
+// filter value pattern.
+func matchAny(pattern string, values ...string) bool {
+	for _, v := range values {
+		if matchValue(pattern, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBool reports whether the boolean filter attribute attr with
+// the given value matches b.
+func matchBool(attr, value string, b bool) (bool, error) {
+	val, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("bad flag %q: %s", attr, value)
+	}
+	return val == b, nil
+}
+
 func notDigit(r rune) bool {
 	return r < '0' || r > '9'
 }
@@ -77,7 +134,7 @@ func (f filter) ok(x filterable) (bool, error) {
 next:
 	for a, vs := range f {
 		for _, v := range vs {
-			if ok, err := x.matchAttr(a, v); ok {
+			if ok, err := matchAttr(x, a, v); ok {
 				continue next
 			} else if err != nil {
 				return false, fmt.Errorf("bad attribute or value %q=%q for type %T: %v", a, v, x, err)
@@ -87,3 +144,14 @@ next:
 	}
 	return true, nil
 }
+
+// matchAttr returns true if the given attribute of x matches value.
+// The tag attributes are matched here for all tagged objects.
+func matchAttr(x filterable, attr, value string) (bool, error) {
+	if t, ok := x.(tagged); ok {
+		if ok, isTag := matchTag(t.tagSet(), attr, value); isTag {
+			return ok, nil
+		}
+	}
+	return x.matchAttr(attr, value)
+}
@@ -0,0 +1,645 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// image holds a simulated ec2 image.
+type image struct {
+	ec2.Image
+
+	// launchPerms holds the accounts other than the owner that
+	// may launch the image.
+	launchPerms map[string]bool
+
+	// availableAt holds the time at which a pending image becomes
+	// available when the server has a clock.
+	availableAt time.Time
+
+	// clientToken holds the token of the CopyImage request that
+	// created the image, if any.
+	clientToken string
+}
+
+func (img *image) tagSet() []ec2.Tag { return img.Tags }
+
+func (img *image) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "architecture":
+		return img.Architecture == value, nil
+	case "description":
+		return img.Description == value, nil
+	case "hypervisor":
+		return img.Hypervisor == value, nil
+	case "image-id":
+		return img.Id == value, nil
+	case "image-type":
+		return img.Type == value, nil
+	case "is-public":
+		val, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("bad flag %q: %s", attr, value)
+		}
+		return img.Public == val, nil
+	case "kernel-id":
+		return img.KernelId == value, nil
+	case "manifest-location":
+		return img.Location == value, nil
+	case "name":
+		return img.Name == value, nil
+	case "owner-alias":
+		return img.OwnerAlias == value, nil
+	case "owner-id":
+		return img.OwnerId == value, nil
+	case "ramdisk-id":
+		return img.RamdiskId == value, nil
+	case "root-device-name":
+		return img.RootDeviceName == value, nil
+	case "root-device-type":
+		return img.RootDeviceType == value, nil
+	case "state":
+		return img.State == value, nil
+	case "virtualization-type":
+		return img.VirtualizationType == value, nil
+	case "block-device-mapping.device-name":
+		return img.hasBlockDevice(func(b ec2.BlockDeviceMapping) bool { return b.DeviceName == value }), nil
+	case "block-device-mapping.snapshot-id":
+		return img.hasBlockDevice(func(b ec2.BlockDeviceMapping) bool { return b.SnapshotId == value }), nil
+	case "block-device-mapping.volume-type":
+		return img.hasBlockDevice(func(b ec2.BlockDeviceMapping) bool { return b.VolumeType == value }), nil
+	case "block-device-mapping.volume-size":
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		return img.hasBlockDevice(func(b ec2.BlockDeviceMapping) bool { return b.VolumeSize == size }), nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+func (img *image) hasBlockDevice(test func(b ec2.BlockDeviceMapping) bool) bool {
+	for _, b := range img.BlockDevices {
+		if test(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// launchableBy reports whether the given account may launch img.
+func (img *image) launchableBy(account string) bool {
+	return img.OwnerId == account || img.Public || img.launchPerms[account]
+}
+
+// validImageName matches the names EC2 accepts for images.
+var validImageName = regexp.MustCompile(`^[a-zA-Z0-9()\[\] ./\-'@_]{3,128}$`)
+
+// checkImageName fails unless name is acceptable for a new image of
+// the account of srv. It must be called with srv.mu held.
+func (srv *Server) checkImageName(name string) {
+	if name == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter name")
+	}
+	if !validImageName.MatchString(name) {
+		fatalf(400, "InvalidAMIName.Malformed", "AMI names must be between 3 and 128 characters long, and may contain letters, numbers, '(', ')', '.', '-', '/' and '_'")
+	}
+	for _, img := range srv.images {
+		if img.OwnerId == ownerId && img.Name == name {
+			fatalf(400, "InvalidAMIName.Duplicate", "AMI name %s is already in use by AMI %s", name, img.Id)
+		}
+	}
+}
+
+// image returns the image with the given id that the account of
+// srv can see, failing if there is none.
+// It must be called with srv.mu held.
+func (srv *Server) image(id string) *image {
+	if id == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter ImageId")
+	}
+	if !strings.HasPrefix(id, "ami-") {
+		fatalf(400, "InvalidAMIID.Malformed", "Invalid id: %q (expecting \"ami-...\")", id)
+	}
+	img := srv.images[id]
+	if img == nil || !img.launchableBy(ownerId) {
+		fatalf(400, "InvalidAMIID.NotFound", "The image id '[%s]' does not exist", id)
+	}
+	return img
+}
+
+// ownImage is like image, but it fails unless the image belongs to
+// the account of srv.
+// It must be called with srv.mu held.
+func (srv *Server) ownImage(id string) *image {
+	img := srv.image(id)
+	if img.OwnerId != ownerId {
+		fatalf(400, "AuthFailure", "Not authorized for image:%s", id)
+	}
+	return img
+}
+
+// launchImage returns the image with the given id, failing unless
+// the account of srv can launch instances from it. Unless srv has
+// strict images, an image it has never issued is launched as one
+// with no block devices.
+// It must be called with srv.mu held.
+func (srv *Server) launchImage(id string) *image {
+	if srv.images[id] == nil && !srv.deregisteredImages[id] && !srv.strictImages {
+		return &image{}
+	}
+	img := srv.image(id)
+	srv.completeImage(img)
+	if img.State != "available" {
+		fatalf(400, "InvalidAMIID.Unavailable", "The image '%s' is not available (%s)", id, img.State)
+	}
+	return img
+}
+
+// newImage adds a pending image owned by the account of srv, and
+// returns it. The image becomes available once its snapshots are
+// complete, which takes the transition delay when the server has a
+// clock, and is reported as complete as soon as the image has been
+// described otherwise.
+// It must be called with srv.mu held.
+func (srv *Server) newImage(img ec2.Image) *image {
+	img.Id = fmt.Sprintf("ami-%d", srv.imageId.next())
+	img.Type = "machine"
+	img.State = "pending"
+	img.OwnerId = ownerId
+	img.Hypervisor = "xen"
+	if img.Location == "" {
+		img.Location = ownerId + "/" + img.Name
+	}
+	i := &image{
+		Image:       img,
+		launchPerms: make(map[string]bool),
+	}
+	if srv.clock != nil {
+		i.availableAt = srv.clock.Now().Add(srv.transitionDelay)
+	}
+	srv.images[i.Id] = i
+	return i
+}
+
+// completeImage makes img available if it is pending and its
+// snapshots are complete.
+// It must be called with srv.mu held.
+func (srv *Server) completeImage(img *image) {
+	if img.State != "pending" {
+		return
+	}
+	if srv.clock != nil && srv.clock.Now().Before(img.availableAt) {
+		return
+	}
+	for _, b := range img.BlockDevices {
+		if snap := srv.snapshots[b.SnapshotId]; snap != nil {
+			srv.completeSnapshot(snap)
+			if snap.Status != "completed" {
+				return
+			}
+		}
+	}
+	img.State = "available"
+}
+
+// AddImage adds the given image to srv, as if it had been registered
+// by its owner, and returns its id. When srv has strict images (see
+// SetStrictImages), RunInstances only launches images known to it,
+// so AddImage is useful to make the public images used by tests
+// available. If image.Id is empty, a new id is allocated;
+// if image.OwnerId is empty, the image belongs to the account of
+// srv; if image.State is empty, the image is available. The block
+// devices of the image must refer to snapshots known to srv.
+func (srv *Server) AddImage(image ec2.Image) string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	img := srv.newImage(image)
+	if image.Id != "" {
+		delete(srv.images, img.Id)
+		img.Id = image.Id
+		srv.images[img.Id] = img
+		delete(srv.deregisteredImages, img.Id)
+	}
+	if image.OwnerId != "" {
+		img.OwnerId = image.OwnerId
+	}
+	if image.Location != "" {
+		img.Location = image.Location
+	}
+	img.State = image.State
+	if img.State == "" {
+		img.State = "available"
+	}
+	return img.Id
+}
+
+// parseLaunchPermissions returns the accounts and whether the "all"
+// group are given as launch or create volume permissions in form
+// with the given prefix (e.g. "LaunchPermission.Add." or
+// "CreateVolumePermission.Add.").
+func parseLaunchPermissions(form url.Values, prefix string) (users []string, all bool) {
+	for i := 1; ; i++ {
+		p := prefix + strconv.Itoa(i)
+		user, group := form.Get(p+".UserId"), form.Get(p+".Group")
+		if user == "" && group == "" {
+			break
+		}
+		switch {
+		case group == "all":
+			all = true
+		case group != "":
+			fatalf(400, "InvalidParameterValue", "Value (%s) for parameter group is invalid. Expected 'all'.", group)
+		}
+		if user != "" {
+			users = append(users, user)
+		}
+	}
+	return users, all
+}
+
+// imageBlockDevices returns the block devices of an image created
+// from inst, snapshotting its volumes, and overridden by the given
+// mappings.
+// It must be called with srv.mu held.
+func (srv *Server) imageBlockDevices(inst *Instance, imageId string, mappings []ec2.BlockDeviceMapping) []ec2.BlockDeviceMapping {
+	var devices []ec2.BlockDeviceMapping
+	for _, v := range inst.volumes {
+		att := v.Attachments[0]
+		snap := srv.newSnapshot(v, fmt.Sprintf("Created by CreateImage(%s) for %s from %s", inst.id(), imageId, v.Id))
+		devices = append(devices, ec2.BlockDeviceMapping{
+			DeviceName:          att.Device,
+			SnapshotId:          snap.Id,
+			VolumeType:          v.VolumeType,
+			VolumeSize:          int64(v.Size),
+			IOPS:                v.IOPS,
+			DeleteOnTermination: att.DeleteOnTermination,
+			Encrypted:           v.Encrypted,
+		})
+	}
+	return mergeBlockDevices(devices, mappings)
+}
+
+// mergeBlockDevices returns the given block devices, with those of
+// overrides replacing the ones with the same device name.
+func mergeBlockDevices(devices, overrides []ec2.BlockDeviceMapping) []ec2.BlockDeviceMapping {
+	result := append([]ec2.BlockDeviceMapping(nil), devices...)
+next:
+	for _, o := range overrides {
+		for i, b := range result {
+			if b.DeviceName == o.DeviceName {
+				result[i] = o
+				continue next
+			}
+		}
+		result = append(result, o)
+	}
+	return result
+}
+
+// checkImageBlockDevices checks the EBS block devices of a new
+// image, filling in the size of the volumes from their snapshot.
+// It must be called with srv.mu held.
+func (srv *Server) checkImageBlockDevices(devices []ec2.BlockDeviceMapping) {
+	for i, b := range devices {
+		if b.VirtualName != "" {
+			continue
+		}
+		if b.SnapshotId == "" {
+			if b.VolumeSize == 0 {
+				fatalf(400, "InvalidBlockDeviceMapping", "The block device mapping for %s must give a snapshot or a volume size", b.DeviceName)
+			}
+			continue
+		}
+		snap := srv.snapshots[b.SnapshotId]
+		if snap == nil {
+			fatalf(400, "InvalidSnapshot.NotFound", "The snapshot '%s' does not exist.", b.SnapshotId)
+		}
+		if b.VolumeSize == 0 {
+			size, _ := strconv.Atoi(snap.VolumeSize)
+			devices[i].VolumeSize = int64(size)
+		}
+		devices[i].Encrypted = b.Encrypted || snap.Encrypted
+	}
+}
+
+func (srv *Server) createImage(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("Name")
+	mappings := parseBlockDeviceMappings(req.Form)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	instId := req.Form.Get("InstanceId")
+	if instId == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter InstanceId")
+	}
+	inst := srv.instances[instId]
+	if inst == nil || srv.consistency.Hidden(instId) {
+		fatalf(400, "InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", instId)
+	}
+	srv.completeTransition(inst)
+	if inst.state != Running && inst.state != Stopped {
+		fatalf(400, "IncorrectInstanceState", "The instance '%s' is not in a state from which it can be imaged.", instId)
+	}
+	srv.checkImageName(name)
+
+	// The new image inherits the properties of the image the
+	// instance was launched from, if it is known.
+	props := ec2.Image{
+		Architecture:       "x86_64",
+		VirtualizationType: "hvm",
+	}
+	if src := srv.images[inst.imageId]; src != nil {
+		props = src.Image
+	}
+	img := srv.newImage(ec2.Image{
+		Name:               name,
+		Description:        req.Form.Get("Description"),
+		Architecture:       props.Architecture,
+		KernelId:           props.KernelId,
+		RamdiskId:          props.RamdiskId,
+		VirtualizationType: props.VirtualizationType,
+		RootDeviceType:     "ebs",
+		RootDeviceName:     "/dev/sda1",
+	})
+	img.BlockDevices = srv.imageBlockDevices(inst, img.Id, mappings)
+	srv.checkImageBlockDevices(img.BlockDevices)
+	return &ec2.CreateImageResp{
+		RequestId: reqId,
+		ImageId:   img.Id,
+	}
+}
+
+func (srv *Server) registerImage(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("Name")
+	location := req.Form.Get("ImageLocation")
+	rootDevice := req.Form.Get("RootDeviceName")
+	mappings := parseBlockDeviceMappings(req.Form)
+	arch := req.Form.Get("Architecture")
+	switch arch {
+	case "":
+		arch = "i386"
+	case "i386", "x86_64":
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter architecture is invalid.", arch)
+	}
+	virtType := req.Form.Get("VirtualizationType")
+	switch virtType {
+	case "":
+		virtType = "paravirtual"
+	case "paravirtual", "hvm":
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter virtualizationType is invalid.", virtType)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.checkImageName(name)
+	rootDeviceType := "instance-store"
+	if location == "" {
+		// EBS-backed images need a snapshot for their root device.
+		if rootDevice == "" {
+			fatalf(400, "MissingParameter", "The request must contain the parameter rootDeviceName")
+		}
+		root := false
+		for _, b := range mappings {
+			root = root || b.DeviceName == rootDevice && b.SnapshotId != ""
+		}
+		if !root {
+			fatalf(400, "InvalidBlockDeviceMapping", "The root device name '%s' is not mapped to a snapshot", rootDevice)
+		}
+		rootDeviceType = "ebs"
+	}
+	srv.checkImageBlockDevices(mappings)
+	img := srv.newImage(ec2.Image{
+		Name:               name,
+		Description:        req.Form.Get("Description"),
+		Location:           location,
+		Architecture:       arch,
+		KernelId:           req.Form.Get("KernelId"),
+		RamdiskId:          req.Form.Get("RamdiskId"),
+		VirtualizationType: virtType,
+		RootDeviceType:     rootDeviceType,
+		RootDeviceName:     rootDevice,
+		BlockDevices:       mappings,
+	})
+	return &ec2.RegisterImageResp{
+		RequestId: reqId,
+		ImageId:   img.Id,
+	}
+}
+
+func (srv *Server) copyImage(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	if req.Form.Get("SourceRegion") == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter SourceRegion")
+	}
+	name := req.Form.Get("Name")
+	token := req.Form.Get("ClientToken")
+	encrypted := false
+	if val := req.Form.Get("Encrypted"); val != "" {
+		var err error
+		encrypted, err = strconv.ParseBool(val)
+		if err != nil {
+			fatalf(400, "InvalidParameterValue", "bad flag Encrypted: %s", val)
+		}
+	}
+	if req.Form.Get("KmsKeyId") != "" && !encrypted {
+		fatalf(400, "InvalidParameterDependency", "The parameter KmsKeyId requires the parameter Encrypted to be set.")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	// The server simulates a single region, which holds the
+	// images of all regions.
+	src := srv.image(req.Form.Get("SourceImageId"))
+	if token != "" {
+		for _, img := range srv.images {
+			if img.clientToken == token {
+				return &ec2.CopyImageResp{RequestId: reqId, ImageId: img.Id}
+			}
+		}
+	}
+	srv.checkImageName(name)
+	img := srv.newImage(ec2.Image{
+		Name:               name,
+		Description:        req.Form.Get("Description"),
+		Architecture:       src.Architecture,
+		KernelId:           src.KernelId,
+		RamdiskId:          src.RamdiskId,
+		VirtualizationType: src.VirtualizationType,
+		RootDeviceType:     src.RootDeviceType,
+		RootDeviceName:     src.RootDeviceName,
+	})
+	img.clientToken = token
+	for _, b := range src.BlockDevices {
+		if b.SnapshotId != "" {
+			srcSnap := srv.snapshots[b.SnapshotId]
+			if srcSnap == nil {
+				fatalf(400, "InvalidSnapshot.NotFound", "The snapshot '%s' does not exist.", b.SnapshotId)
+			}
+			snap := srv.newSnapshotCopy(srcSnap, fmt.Sprintf("Copied for DestinationAmi %s from SourceAmi %s for SourceSnapshot %s", img.Id, src.Id, srcSnap.Id), encrypted, req.Form.Get("KmsKeyId"))
+			b.SnapshotId = snap.Id
+			b.Encrypted = snap.Encrypted
+		}
+		img.BlockDevices = append(img.BlockDevices, b)
+	}
+	return &ec2.CopyImageResp{
+		RequestId: reqId,
+		ImageId:   img.Id,
+	}
+}
+
+func (srv *Server) deregisterImage(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	img := srv.ownImage(req.Form.Get("ImageId"))
+	delete(srv.images, img.Id)
+	srv.deregisteredImages[img.Id] = true
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeregisterImageResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describeImages(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var imgs []*image
+	for id := range parseIDs(req.Form, "ImageId.") {
+		imgs = append(imgs, srv.image(id))
+	}
+	if len(imgs) == 0 {
+		for _, img := range srv.images {
+			if img.launchableBy(ownerId) {
+				imgs = append(imgs, img)
+			}
+		}
+	}
+	owners := parseIDs(req.Form, "Owner.")
+	if owners["self"] {
+		owners[ownerId] = true
+	}
+	executableBy := parseIDs(req.Form, "ExecutableBy.")
+	if executableBy["self"] {
+		executableBy[ownerId] = true
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.ImagesResp
+	resp.RequestId = reqId
+	for _, img := range imgs {
+		if len(owners) > 0 && !owners[img.OwnerId] && !owners[img.OwnerAlias] {
+			continue
+		}
+		if len(executableBy) > 0 && !(executableBy["all"] && img.Public) && !img.executableBy(executableBy) {
+			continue
+		}
+		srv.completeImage(img)
+		ok, err := f.ok(img)
+		if ok {
+			resp.Images = append(resp.Images, img.Image)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe images: %v", err)
+		}
+	}
+	sort.Slice(resp.Images, func(i, j int) bool { return resp.Images[i].Id < resp.Images[j].Id })
+	start, end, nextToken := page(req.Form, len(resp.Images))
+	resp.Images, resp.NextToken = resp.Images[start:end], nextToken
+	return &resp
+}
+
+// executableBy reports whether any of the given accounts may launch
+// img, other than through the "all" group.
+func (img *image) executableBy(accounts map[string]bool) bool {
+	for account := range accounts {
+		if img.OwnerId == account || img.launchPerms[account] {
+			return true
+		}
+	}
+	return false
+}
+
+func (srv *Server) describeImageAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	img := srv.ownImage(req.Form.Get("ImageId"))
+
+	resp := &ec2.ImageAttributeResp{
+		RequestId: reqId,
+		ImageId:   img.Id,
+	}
+	switch attr := req.Form.Get("Attribute"); attr {
+	case "launchPermission":
+		if img.Public {
+			resp.LaunchPermissions = append(resp.LaunchPermissions, ec2.LaunchPermission{Group: "all"})
+		}
+		var users []string
+		for user := range img.launchPerms {
+			users = append(users, user)
+		}
+		sort.Strings(users)
+		for _, user := range users {
+			resp.LaunchPermissions = append(resp.LaunchPermissions, ec2.LaunchPermission{UserId: user})
+		}
+	case "description":
+		resp.Description = img.Description
+	case "kernel":
+		resp.KernelId = img.KernelId
+	case "ramdisk":
+		resp.RamdiskId = img.RamdiskId
+	case "blockDeviceMapping":
+		resp.BlockDevices = img.BlockDevices
+	case "":
+		fatalf(400, "MissingParameter", "The request must contain the parameter Attribute")
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter attribute is invalid. Unknown attribute.", attr)
+	}
+	return resp
+}
+
+func (srv *Server) modifyImageAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	add, addAll := parseLaunchPermissions(req.Form, "LaunchPermission.Add.")
+	remove, removeAll := parseLaunchPermissions(req.Form, "LaunchPermission.Remove.")
+	description, setDescription := req.Form["Description.Value"]
+	if len(add) == 0 && !addAll && len(remove) == 0 && !removeAll && !setDescription {
+		fatalf(400, "InvalidParameterCombination", "No attributes specified.")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	img := srv.ownImage(req.Form.Get("ImageId"))
+	for _, user := range add {
+		img.launchPerms[user] = true
+	}
+	for _, user := range remove {
+		delete(img.launchPerms, user)
+	}
+	if addAll {
+		img.Public = true
+	}
+	if removeAll {
+		img.Public = false
+	}
+	if setDescription {
+		img.Description = description[0]
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "ModifyImageAttributeResponse"},
+		RequestId: reqId,
+	}
+}
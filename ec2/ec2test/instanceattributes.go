@@ -0,0 +1,146 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// parseBoolParam returns the value of the boolean parameter with the
+// given name in form, which is false if it is not set.
+func parseBoolParam(form url.Values, name string) bool {
+	value := form.Get(name)
+	if value == "" {
+		return false
+	}
+	val, err := strconv.ParseBool(value)
+	if err != nil {
+		fatalf(400, "InvalidParameterValue", "Invalid value '%s' for %s", value, name)
+	}
+	return val
+}
+
+// parseShutdownBehavior returns the instance initiated shutdown
+// behavior given by the parameter with the given name in form, which
+// is "stop" if it is not set.
+func parseShutdownBehavior(form url.Values, name string) string {
+	value := form.Get(name)
+	switch value {
+	case "":
+		return "stop"
+	case "stop", "terminate":
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter %s is invalid. Valid values are 'stop' and 'terminate'.", value, name)
+	}
+	return value
+}
+
+func (srv *Server) describeInstanceAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	inst := srv.instance(req.Form.Get("InstanceId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	resp := &ec2.InstanceAttributeResp{
+		RequestId:  reqId,
+		InstanceId: inst.id(),
+	}
+	switch attr := req.Form.Get("Attribute"); attr {
+	case "instanceType":
+		resp.InstanceType = inst.instType
+	case "sourceDestCheck":
+		resp.SourceDestCheck = inst.sourceDestCheck
+	case "disableApiTermination":
+		resp.DisableAPITermination = inst.disableAPITermination
+	case "instanceInitiatedShutdownBehavior":
+		resp.ShutdownBehavior = inst.shutdownBehavior
+	case "userData":
+		// The user data is reported encoded, as on EC2.
+		if len(inst.UserData) > 0 {
+			resp.UserData = []byte(b64.EncodeToString(inst.UserData))
+		}
+	case "":
+		fatalf(400, "MissingParameter", "The request must contain the parameter Attribute")
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter attribute is invalid. Unknown attribute.", attr)
+	}
+	return resp
+}
+
+// instanceAttributeParams maps the parameters of a
+// ModifyInstanceAttribute request to the attributes they modify.
+var instanceAttributeParams = map[string]string{
+	"InstanceType.Value":                      "instanceType",
+	"SourceDestCheck.Value":                   "sourceDestCheck",
+	"DisableApiTermination.Value":             "disableApiTermination",
+	"InstanceInitiatedShutdownBehavior.Value": "instanceInitiatedShutdownBehavior",
+	"UserData.Value":                          "userData",
+}
+
+// singleAttribute returns the one attribute, out of those the given
+// parameters map to, set in the form of req, failing unless exactly
+// one is: EC2 only modifies one attribute at a time.
+func singleAttribute(req *http.Request, params map[string]string) string {
+	var attrs []string
+	for param, attr := range params {
+		if _, ok := req.Form[param]; ok {
+			attrs = append(attrs, attr)
+		}
+	}
+	sort.Strings(attrs)
+	switch len(attrs) {
+	case 0:
+		fatalf(400, "InvalidParameterCombination", "No attributes specified.")
+	case 1:
+	default:
+		fatalf(400, "InvalidParameterCombination", "Fields for multiple attribute types specified: %s", strings.Join(attrs, ", "))
+	}
+	return attrs[0]
+}
+
+func (srv *Server) modifyInstanceAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	attr := singleAttribute(req, instanceAttributeParams)
+	inst := srv.instance(req.Form.Get("InstanceId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	switch attr {
+	case "instanceType", "userData":
+		if inst.state != Stopped {
+			fatalf(400, "IncorrectInstanceState", "The instance '%s' is not in the 'stopped' state.", inst.id())
+		}
+	}
+	switch attr {
+	case "instanceType":
+		instType := req.Form.Get("InstanceType.Value")
+		if instType == "" {
+			fatalf(400, "InvalidParameterValue", "The instance type may not be empty")
+		}
+		inst.instType = instType
+	case "sourceDestCheck":
+		inst.sourceDestCheck = parseBoolParam(req.Form, "SourceDestCheck.Value")
+	case "disableApiTermination":
+		inst.disableAPITermination = parseBoolParam(req.Form, "DisableApiTermination.Value")
+	case "instanceInitiatedShutdownBehavior":
+		inst.shutdownBehavior = parseShutdownBehavior(req.Form, "InstanceInitiatedShutdownBehavior.Value")
+	case "userData":
+		data, err := b64.DecodeString(req.Form.Get("UserData.Value"))
+		if err != nil {
+			fatalf(400, "InvalidParameterValue", "bad UserData value: %v", err)
+		}
+		inst.UserData = data
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "ModifyInstanceAttributeResponse"},
+		RequestId: reqId,
+	}
+}
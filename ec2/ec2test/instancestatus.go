@@ -0,0 +1,191 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// SetConsoleOutput sets the console output reported for the instance
+// with the given id by GetConsoleOutput.
+func (srv *Server) SetConsoleOutput(instId string, output []byte) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	inst := srv.instances[instId]
+	if inst == nil {
+		panic(fmt.Errorf("instance %q not found", instId))
+	}
+	inst.consoleOutput = output
+	inst.consoleTime = srv.now()
+}
+
+// SetInstanceStatus sets the results of the system and instance
+// status checks reported for the instance with the given id by
+// DescribeInstanceStatus, each one of "ok", "impaired",
+// "insufficient-data" and "initializing", and the events scheduled
+// for the instance. An empty status is reported as "ok", which is the
+// default.
+func (srv *Server) SetInstanceStatus(instId, systemStatus, instanceStatus string, events ...ec2.InstanceStatusEvent) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	inst := srv.instances[instId]
+	if inst == nil {
+		panic(fmt.Errorf("instance %q not found", instId))
+	}
+	inst.systemStatus = systemStatus
+	inst.instanceStatus = instanceStatus
+	inst.events = events
+}
+
+// statusSummary returns the summary of a status check of an instance
+// in the given state with the given status.
+func statusSummary(state ec2.InstanceState, status string) ec2.InstanceStatusSummary {
+	if state != Running {
+		return ec2.InstanceStatusSummary{Status: "not-applicable"}
+	}
+	if status == "" {
+		status = "ok"
+	}
+	detail := ec2.InstanceStatusDetail{Name: "reachability"}
+	switch status {
+	case "ok":
+		detail.Status = "passed"
+	case "impaired":
+		detail.Status = "failed"
+	default:
+		detail.Status = status
+	}
+	return ec2.InstanceStatusSummary{
+		Status:  status,
+		Details: []ec2.InstanceStatusDetail{detail},
+	}
+}
+
+func (inst *Instance) ec2status() ec2.InstanceStatus {
+	return ec2.InstanceStatus{
+		InstanceId:     inst.id(),
+		AvailZone:      inst.availZone,
+		State:          inst.state,
+		SystemStatus:   statusSummary(inst.state, inst.systemStatus),
+		InstanceStatus: statusSummary(inst.state, inst.instanceStatus),
+		Events:         inst.events,
+	}
+}
+
+// instanceStatus implements the filters of DescribeInstanceStatus.
+type instanceStatus struct {
+	ec2.InstanceStatus
+}
+
+func (s instanceStatus) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "availability-zone":
+		return s.AvailZone == value, nil
+	case "instance-state-name":
+		return s.State.Name == value, nil
+	case "instance-state-code":
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return false, err
+		}
+		return code&0xff == s.State.Code, nil
+	case "system-status.status":
+		return s.SystemStatus.Status == value, nil
+	case "system-status.reachability":
+		return matchStatusDetail(s.SystemStatus, value), nil
+	case "instance-status.status":
+		return s.InstanceStatus.InstanceStatus.Status == value, nil
+	case "instance-status.reachability":
+		return matchStatusDetail(s.InstanceStatus.InstanceStatus, value), nil
+	case "event.code", "event.description", "event.not-after", "event.not-before":
+		for _, e := range s.Events {
+			switch attr {
+			case "event.code":
+				ok = e.Code == value
+			case "event.description":
+				ok = e.Description == value
+			case "event.not-after":
+				ok = e.NotAfter == value
+			case "event.not-before":
+				ok = e.NotBefore == value
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// matchStatusDetail reports whether the reachability check of s has
+// the given status.
+func matchStatusDetail(s ec2.InstanceStatusSummary, status string) bool {
+	for _, d := range s.Details {
+		if d.Name == "reachability" && d.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (srv *Server) getConsoleOutput(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	inst := srv.instance(req.Form.Get("InstanceId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	resp := &ec2.ConsoleOutputResp{
+		RequestId:  reqId,
+		InstanceId: inst.id(),
+	}
+	if len(inst.consoleOutput) > 0 {
+		// The output is reported encoded, as on EC2.
+		resp.Output = []byte(b64.EncodeToString(inst.consoleOutput))
+		resp.Timestamp = inst.consoleTime.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func (srv *Server) describeInstanceStatus(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	includeAll := parseBoolParam(req.Form, "IncludeAllInstances")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var insts []*Instance
+	if ids := parseIDs(req.Form, "InstanceId."); len(ids) > 0 {
+		insts = srv.instancesFromForm(req)
+	} else {
+		for id, inst := range srv.instances {
+			if !srv.consistency.Hidden(id) {
+				insts = append(insts, inst)
+			}
+		}
+		sort.Sort(instancesById(insts))
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.InstanceStatusResp
+	resp.RequestId = reqId
+	for _, inst := range insts {
+		if !includeAll && inst.state != Running {
+			continue
+		}
+		status := instanceStatus{inst.ec2status()}
+		ok, err := f.ok(status)
+		if ok {
+			resp.Statuses = append(resp.Statuses, status.InstanceStatus)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe instance status: %v", err)
+		}
+	}
+	return &resp
+}
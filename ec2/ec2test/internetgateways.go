@@ -0,0 +1,162 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// internetGateway holds a simulated ec2 Internet gateway.
+type internetGateway struct {
+	ec2.InternetGateway
+}
+
+func (g *internetGateway) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "internet-gateway-id":
+		return g.Id == value, nil
+	case "attachment.vpc-id":
+		return g.vpcId() == value, nil
+	case "attachment.state":
+		for _, a := range g.Attachments {
+			if a.State == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// vpcId returns the id of the VPC the gateway is attached to, if any.
+func (g *internetGateway) vpcId() string {
+	if len(g.Attachments) == 0 {
+		return ""
+	}
+	return g.Attachments[0].VPCId
+}
+
+// internetGateway returns the Internet gateway with the given id.
+// It must be called with srv.mu held.
+func (srv *Server) internetGateway(id string) *internetGateway {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing internetGatewayId")
+	}
+	g := srv.igws[id]
+	if g == nil {
+		fatalf(400, "InvalidInternetGatewayID.NotFound", "The internetGateway ID '%s' does not exist", id)
+	}
+	return g
+}
+
+// vpcInternetGateway returns the Internet gateway attached to the VPC
+// with the given id, or nil if there is none. It must be called with
+// srv.mu held.
+func (srv *Server) vpcInternetGateway(vpcId string) *internetGateway {
+	for _, g := range srv.igws {
+		if g.vpcId() == vpcId {
+			return g
+		}
+	}
+	return nil
+}
+
+func (srv *Server) createInternetGateway(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	g := &internetGateway{ec2.InternetGateway{
+		Id: fmt.Sprintf("igw-%d", srv.igwId.next()),
+	}}
+	srv.igws[g.Id] = g
+	return &ec2.CreateInternetGatewayResp{
+		RequestId:       reqId,
+		InternetGateway: g.InternetGateway,
+	}
+}
+
+func (srv *Server) deleteInternetGateway(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	g := srv.internetGateway(req.Form.Get("InternetGatewayId"))
+	if g.vpcId() != "" {
+		fatalf(400, "DependencyViolation", "The internetGateway '%s' has dependencies and cannot be deleted.", g.Id)
+	}
+	delete(srv.igws, g.Id)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteInternetGatewayResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describeInternetGateways(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var gateways []*internetGateway
+	for id := range parseIDs(req.Form, "InternetGatewayId.") {
+		gateways = append(gateways, srv.internetGateway(id))
+	}
+	if len(gateways) == 0 {
+		for _, g := range srv.igws {
+			gateways = append(gateways, g)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.InternetGatewaysResp
+	resp.RequestId = reqId
+	for _, g := range gateways {
+		ok, err := f.ok(g)
+		if ok {
+			resp.InternetGateways = append(resp.InternetGateways, g.InternetGateway)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe internet gateways: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) attachInternetGateway(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.vpc(req.Form.Get("VpcId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	g := srv.internetGateway(req.Form.Get("InternetGatewayId"))
+	if vpcId := g.vpcId(); vpcId != "" {
+		fatalf(400, "Resource.AlreadyAssociated", "resource %s is already attached to network %s", g.Id, vpcId)
+	}
+	if other := srv.vpcInternetGateway(v.Id); other != nil {
+		fatalf(400, "Resource.AlreadyAssociated", "network %s already has an internet gateway attached", v.Id)
+	}
+	g.Attachments = []ec2.InternetGatewayAttachment{{
+		VPCId: v.Id,
+		State: "available",
+	}}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "AttachInternetGatewayResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) detachInternetGateway(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.vpc(req.Form.Get("VpcId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	g := srv.internetGateway(req.Form.Get("InternetGatewayId"))
+	if g.vpcId() != v.Id {
+		fatalf(400, "Gateway.NotAttached", "resource %s is not attached to network %s", g.Id, v.Id)
+	}
+	g.Attachments = nil
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DetachInternetGatewayResponse"},
+		RequestId: reqId,
+	}
+}
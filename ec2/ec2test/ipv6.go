@@ -0,0 +1,303 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// amazonIPv6Pool holds the pool Amazon-provided /56 IPv6 CIDR blocks
+// of VPCs are allocated from.
+var amazonIPv6Pool = net.ParseIP("2600:1f18::")
+
+// newAmazonIPv6Block returns a new /56 IPv6 CIDR block from the
+// Amazon pool. It must be called with srv.mu held.
+func (srv *Server) newAmazonIPv6Block() string {
+	n := srv.ipv6BlockId.next()
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, amazonIPv6Pool)
+	// Bits 40 to 55 select the block, which leaves room for 65536
+	// VPC blocks.
+	ip[5] = byte(n >> 8)
+	ip[6] = byte(n)
+	return (&net.IPNet{IP: ip, Mask: net.CIDRMask(56, 128)}).String()
+}
+
+// matchIPv6CIDRBlocks reports whether any of the given IPv6 CIDR
+// block associations matches the ipv6-cidr-block-association filter
+// attribute attr with value.
+func matchIPv6CIDRBlocks(blocks []ec2.IPv6CIDRBlockAssociation, attr, value string) bool {
+	for _, b := range blocks {
+		switch attr {
+		case "ipv6-cidr-block-association.ipv6-cidr-block":
+			if b.IPv6CIDRBlock == value {
+				return true
+			}
+		case "ipv6-cidr-block-association.association-id":
+			if b.AssociationId == value {
+				return true
+			}
+		case "ipv6-cidr-block-association.state":
+			if b.State == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ipv6Block returns the associated IPv6 CIDR block of the subnet, or
+// nil if it has none.
+func (s *subnet) ipv6Block() *net.IPNet {
+	for _, b := range s.IPv6CIDRBlocks {
+		if b.State != "associated" {
+			continue
+		}
+		if _, ipnet, err := net.ParseCIDR(b.IPv6CIDRBlock); err == nil {
+			return ipnet
+		}
+	}
+	return nil
+}
+
+// ipv6AddressCount returns the number of IPv6 addresses to allocate
+// for a new network interface in the subnet, given the addresses and
+// count requested for it: a single address, if the subnet assigns
+// one on creation and none were requested.
+func (s *subnet) ipv6AddressCount(addrs []string, count int) int {
+	if len(addrs) == 0 && count == 0 && s.AssignIPv6AddressOnCreation {
+		return 1
+	}
+	return count
+}
+
+// usedIPv6Addresses returns the IPv6 addresses assigned to network
+// interfaces in the subnet subnetId. It must be called with srv.mu
+// held.
+func (srv *Server) usedIPv6Addresses(subnetId string) map[string]bool {
+	used := make(map[string]bool)
+	for _, i := range srv.ifaces {
+		if i.SubnetId != subnetId {
+			continue
+		}
+		for _, addr := range i.IPv6Addresses {
+			used[addr] = true
+		}
+	}
+	return used
+}
+
+// checkIPv6Addresses fails unless count IPv6 addresses, or else the
+// given ones, can be assigned to a network interface in the subnet
+// sub. It must be called with srv.mu held.
+func (srv *Server) checkIPv6Addresses(sub *subnet, addrs []string, count int) {
+	if len(addrs) == 0 && count == 0 {
+		return
+	}
+	if len(addrs) > 0 && count > 0 {
+		fatalf(400, "InvalidParameterCombination", "Only one of Ipv6Addresses and Ipv6AddressCount may be specified")
+	}
+	if count < 0 {
+		fatalf(400, "InvalidParameterValue", "Invalid value '%d' for Ipv6AddressCount", count)
+	}
+	block := sub.ipv6Block()
+	if block == nil {
+		fatalf(400, "InvalidParameterValue", "The subnet '%s' does not have an IPv6 CIDR block", sub.Id)
+	}
+	used := srv.usedIPv6Addresses(sub.Id)
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.To4() != nil {
+			fatalf(400, "InvalidParameterValue", "Invalid IPv6 address '%s'", addr)
+		}
+		if !block.Contains(ip) {
+			fatalf(400, "InvalidParameterValue", "Address '%s' is not in the IPv6 CIDR block of subnet '%s'", addr, sub.Id)
+		}
+		if used[ip.String()] {
+			fatalf(400, "InvalidIPAddress.InUse", "Address '%s' is in use.", addr)
+		}
+		used[ip.String()] = true
+	}
+}
+
+// allocateIPv6Addresses returns the given IPv6 addresses, in their
+// canonical form, followed by count free addresses allocated from the
+// IPv6 CIDR block of the subnet sub. As in IPv4 subnets, the first
+// four addresses of the block are reserved. The addresses must have
+// been checked with checkIPv6Addresses, and it must be called with
+// srv.mu held.
+func (srv *Server) allocateIPv6Addresses(sub *subnet, addrs []string, count int) []string {
+	var result []string
+	for _, addr := range addrs {
+		result = append(result, net.ParseIP(addr).String())
+	}
+	if count == 0 {
+		return result
+	}
+	block := sub.ipv6Block()
+	used := srv.usedIPv6Addresses(sub.Id)
+	for n := uint64(4); len(result) < len(addrs)+count; n++ {
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, block.IP)
+		binary.BigEndian.PutUint64(ip[8:], n)
+		if !used[ip.String()] {
+			result = append(result, ip.String())
+		}
+	}
+	return result
+}
+
+// parseIPv6Addresses returns the values of the parameters in form
+// named by format with consecutive indexes from 1, such as
+// "Ipv6Addresses.%d.Ipv6Address".
+func parseIPv6Addresses(form url.Values, format string) []string {
+	var addrs []string
+	for i := 1; ; i++ {
+		addr := form.Get(fmt.Sprintf(format, i))
+		if addr == "" {
+			return addrs
+		}
+		addrs = append(addrs, addr)
+	}
+}
+
+func (srv *Server) associateSubnetCidrBlock(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	s := srv.subnet(req.Form.Get("SubnetId"))
+	v := srv.vpc(s.VPCId)
+	cidrBlock := parseCidr(req.Form.Get("Ipv6CidrBlock"))
+	_, ipnet, _ := net.ParseCIDR(cidrBlock)
+	if ones, bits := ipnet.Mask.Size(); bits != 128 || ones != 64 {
+		fatalf(400, "InvalidParameterValue", "The IPv6 CIDR block '%s' must have a /64 prefix length", cidrBlock)
+	}
+	cidrBlock = ipnet.String()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if s.ipv6Block() != nil {
+		fatalf(400, "CidrLimitExceeded", "This subnet '%s' has met its maximum number of allowed CIDRs: 1", s.Id)
+	}
+	inVPC := false
+	for _, b := range v.IPv6CIDRBlocks {
+		if b.State == "associated" && cidrContains(b.IPv6CIDRBlock, cidrBlock) {
+			inVPC = true
+		}
+	}
+	if !inVPC {
+		fatalf(400, "InvalidSubnet.Range", "The CIDR '%s' is invalid.", cidrBlock)
+	}
+	for _, other := range srv.subnets {
+		if other.VPCId != v.Id {
+			continue
+		}
+		if block := other.ipv6Block(); block != nil && block.String() == cidrBlock {
+			fatalf(400, "InvalidSubnet.Conflict", "The CIDR '%s' conflicts with another subnet", cidrBlock)
+		}
+	}
+	assoc := ec2.IPv6CIDRBlockAssociation{
+		AssociationId: fmt.Sprintf("subnet-cidr-assoc-%d", srv.cidrAssocId.next()),
+		IPv6CIDRBlock: cidrBlock,
+		State:         "associated",
+	}
+	s.IPv6CIDRBlocks = append(s.IPv6CIDRBlocks, assoc)
+	return &ec2.AssociateSubnetCIDRBlockResp{
+		RequestId:                reqId,
+		SubnetId:                 s.Id,
+		IPv6CIDRBlockAssociation: assoc,
+	}
+}
+
+func (srv *Server) disassociateSubnetCidrBlock(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	id := req.Form.Get("AssociationId")
+	if id == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter AssociationId")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, s := range srv.subnets {
+		for i, b := range s.IPv6CIDRBlocks {
+			if b.AssociationId != id {
+				continue
+			}
+			if len(srv.usedIPv6Addresses(s.Id)) > 0 {
+				fatalf(400, "DependencyViolation", "The subnet '%s' has IPv6 addresses in use and its CIDR block cannot be disassociated.", s.Id)
+			}
+			s.IPv6CIDRBlocks = append(s.IPv6CIDRBlocks[:i], s.IPv6CIDRBlocks[i+1:]...)
+			s.AssignIPv6AddressOnCreation = false
+			b.State = "disassociating"
+			return &ec2.DisassociateSubnetCIDRBlockResp{
+				RequestId:                reqId,
+				SubnetId:                 s.Id,
+				IPv6CIDRBlockAssociation: b,
+			}
+		}
+	}
+	fatalf(400, "InvalidSubnetCidrBlockAssociationID.NotFound", "The association '%s' does not exist.", id)
+	return nil
+}
+
+func (srv *Server) assignIpv6Addresses(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	nic := srv.iface(req.Form.Get("NetworkInterfaceId"))
+	addrs := parseIPv6Addresses(req.Form, "Ipv6Addresses.%d")
+	count := 0
+	if n := req.Form.Get("Ipv6AddressCount"); n != "" {
+		count = atoi(n)
+	}
+	if len(addrs) == 0 && count == 0 {
+		fatalf(400, "MissingParameter", "Either Ipv6Addresses or Ipv6AddressCount must be specified")
+	}
+	s := srv.subnet(nic.SubnetId)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.checkIPv6Addresses(s, addrs, count)
+	assigned := srv.allocateIPv6Addresses(s, addrs, count)
+	nic.IPv6Addresses = append(nic.IPv6Addresses, assigned...)
+	return &ec2.AssignIPv6AddressesResp{
+		RequestId:          reqId,
+		NetworkInterfaceId: nic.Id,
+		AssignedAddresses:  assigned,
+	}
+}
+
+func (srv *Server) unassignIpv6Addresses(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	nic := srv.iface(req.Form.Get("NetworkInterfaceId"))
+	addrs := parseIPv6Addresses(req.Form, "Ipv6Addresses.%d")
+	if len(addrs) == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter Ipv6Addresses")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	var unassigned []string
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		found := false
+		for i, assigned := range nic.IPv6Addresses {
+			if ip != nil && assigned == ip.String() {
+				// Remove it, preserving order.
+				nic.IPv6Addresses = append(nic.IPv6Addresses[:i], nic.IPv6Addresses[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			fatalf(400, "InvalidParameterValue", "Address '%s' is not assigned to network interface '%s'", addr, nic.Id)
+		}
+		unassigned = append(unassigned, ip.String())
+	}
+	return &ec2.UnassignIPv6AddressesResp{
+		RequestId:           reqId,
+		NetworkInterfaceId:  nic.Id,
+		UnassignedAddresses: unassigned,
+	}
+}
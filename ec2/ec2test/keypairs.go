@@ -0,0 +1,172 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// keyPair holds a simulated ec2 key pair.
+type keyPair struct {
+	ec2.KeyPair
+}
+
+func (k *keyPair) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "fingerprint":
+		return k.Fingerprint == value, nil
+	case "key-name":
+		return k.Name == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// checkKeyName fails unless name is acceptable for a new key pair.
+// It must be called with srv.mu held.
+func (srv *Server) checkKeyName(name string) {
+	if name == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter KeyName")
+	}
+	if len(name) > 255 {
+		fatalf(400, "InvalidParameterValue", "Value for parameter KeyName is too long")
+	}
+	if srv.keyPairs[name] != nil {
+		fatalf(400, "InvalidKeyPair.Duplicate", "The keypair '%s' already exists.", name)
+	}
+}
+
+// checkKeyPair fails if name is not empty and there is no key pair
+// with that name. It must be called with srv.mu held.
+func (srv *Server) checkKeyPair(name string) {
+	if name != "" && srv.keyPairs[name] == nil {
+		fatalf(400, "InvalidKeyPair.NotFound", "The key pair '%s' does not exist", name)
+	}
+}
+
+func (srv *Server) createKeyPair(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("KeyName")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.checkKeyName(name)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		fatalf(500, "InternalError", "cannot generate key: %v", err)
+	}
+	// The fingerprint of key pairs created by EC2 is the SHA-1 sum
+	// of the DER encoded private key in PKCS#8 format.
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		fatalf(500, "InternalError", "cannot encode key: %v", err)
+	}
+	k := &keyPair{ec2.KeyPair{
+		Name:        name,
+		Fingerprint: colonHex(sha1.Sum(der)),
+	}}
+	srv.keyPairs[name] = k
+	material := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	return &ec2.CreateKeyPairResp{
+		RequestId: reqId,
+		KeyPair:   k.KeyPair,
+		Material:  string(material),
+	}
+}
+
+func (srv *Server) importKeyPair(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("KeyName")
+	material, err := b64.DecodeString(req.Form.Get("PublicKeyMaterial"))
+	if err != nil {
+		fatalf(400, "InvalidParameterValue", "Value for parameter PublicKeyMaterial is invalid: %v", err)
+	}
+	fingerprint, err := ec2.PublicKeyFingerprint(material)
+	if err != nil {
+		fatalf(400, "InvalidKey.Format", "Key is not in valid OpenSSH public key format")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.checkKeyName(name)
+	k := &keyPair{ec2.KeyPair{
+		Name:        name,
+		Fingerprint: fingerprint,
+	}}
+	srv.keyPairs[name] = k
+	return &ec2.ImportKeyPairResp{
+		RequestId: reqId,
+		KeyPair:   k.KeyPair,
+	}
+}
+
+func (srv *Server) describeKeyPairs(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var keys []*keyPair
+	for name := range parseIDs(req.Form, "KeyName.") {
+		k := srv.keyPairs[name]
+		if k == nil {
+			fatalf(400, "InvalidKeyPair.NotFound", "The key pair '%s' does not exist", name)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		for _, k := range srv.keyPairs {
+			keys = append(keys, k)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.KeyPairsResp
+	resp.RequestId = reqId
+	for _, k := range keys {
+		ok, err := f.ok(k)
+		if ok {
+			resp.KeyPairs = append(resp.KeyPairs, k.KeyPair)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe key pairs: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) deleteKeyPair(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("KeyName")
+	if name == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter KeyName")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	// EC2 does not fail when deleting a key pair that does not exist.
+	delete(srv.keyPairs, name)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteKeyPairResponse"},
+		RequestId: reqId,
+	}
+}
+
+// colonHex formats sum in colon separated hexadecimal, as used
+// for key fingerprints.
+func colonHex(sum [sha1.Size]byte) string {
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, ":")
+}
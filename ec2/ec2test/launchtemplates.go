@@ -0,0 +1,439 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// launchTemplate holds a simulated ec2 launch template.
+type launchTemplate struct {
+	ec2.LaunchTemplate
+
+	// versions holds the versions of the template, in version
+	// number order.
+	versions []*launchTemplateVersion
+}
+
+func (t *launchTemplate) tagSet() []ec2.Tag { return t.Tags }
+
+func (t *launchTemplate) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "launch-template-name":
+		return t.Name == value, nil
+	case "create-time":
+		return t.CreateTime == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// launchTemplateVersion holds a version of a simulated ec2 launch
+// template.
+type launchTemplateVersion struct {
+	ec2.LaunchTemplateVersion
+
+	// data holds the launch parameters of the version, named as
+	// in RunInstances requests.
+	data url.Values
+}
+
+// launchTemplateVersionFilter implements the filters of
+// DescribeLaunchTemplateVersions.
+type launchTemplateVersionFilter struct {
+	*launchTemplateVersion
+}
+
+func (v launchTemplateVersionFilter) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "image-id":
+		return v.Data.ImageId == value, nil
+	case "instance-type":
+		return v.Data.InstanceType == value, nil
+	case "is-default-version":
+		return strconv.FormatBool(v.DefaultVersion) == value, nil
+	case "kernel-id":
+		return v.Data.KernelId == value, nil
+	case "ram-disk-id":
+		return v.Data.RamdiskId == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// version returns the version of t selected by the given version
+// number, "$Latest" or "$Default", which is used when it is empty.
+func (t *launchTemplate) version(version string) *launchTemplateVersion {
+	var n int64
+	switch version {
+	case "", "$Default":
+		n = t.DefaultVersionNumber
+	case "$Latest":
+		n = t.LatestVersionNumber
+	default:
+		var err error
+		n, err = strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			fatalf(400, "InvalidLaunchTemplateId.VersionNotFound", "Could not find launch template version %s for template %s", version, t.Id)
+		}
+	}
+	if n < 1 || n > int64(len(t.versions)) {
+		fatalf(400, "InvalidLaunchTemplateId.VersionNotFound", "Could not find launch template version %s for template %s", version, t.Id)
+	}
+	return t.versions[n-1]
+}
+
+// launchTemplateFromForm returns the launch template selected by the
+// LaunchTemplateId or LaunchTemplateName parameter in form, with the
+// given prefix.
+// It must be called with srv.mu held.
+func (srv *Server) launchTemplateFromForm(form url.Values, prefix string) *launchTemplate {
+	if id := form.Get(prefix + "LaunchTemplateId"); id != "" {
+		t := srv.launchTemplates[id]
+		if t == nil {
+			fatalf(400, "InvalidLaunchTemplateId.NotFound", "The specified launch template, with template ID %s, does not exist.", id)
+		}
+		return t
+	}
+	name := form.Get(prefix + "LaunchTemplateName")
+	if name == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter %sLaunchTemplateName or %sLaunchTemplateId", prefix, prefix)
+	}
+	for _, t := range srv.launchTemplates {
+		if t.Name == name {
+			return t
+		}
+	}
+	fatalf(400, "InvalidLaunchTemplateName.NotFoundException", "The specified launch template, with template name %s, does not exist.", name)
+	return nil
+}
+
+// parseLaunchTemplateData returns the launch parameters given by the
+// LaunchTemplateData parameters in form, named as in RunInstances
+// requests.
+func parseLaunchTemplateData(form url.Values) url.Values {
+	const prefix = "LaunchTemplateData."
+	data := make(url.Values)
+	for name, values := range form {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		name = name[len(prefix):]
+		if name == "RamDiskId" {
+			name = "RamdiskId"
+		}
+		data[name] = values
+	}
+	return data
+}
+
+// launchTemplateData returns the launch template data reported for
+// the launch parameters data, failing if they are not valid.
+// It must be called with srv.mu held.
+func (srv *Server) launchTemplateData(data url.Values) ec2.LaunchTemplateData {
+	if userData := data.Get("UserData"); userData != "" {
+		if _, err := b64.DecodeString(userData); err != nil {
+			fatalf(400, "InvalidParameterValue", "bad UserData value: %v", err)
+		}
+	}
+	if data.Get("InstanceInitiatedShutdownBehavior") != "" {
+		parseShutdownBehavior(data, "InstanceInitiatedShutdownBehavior")
+	}
+	srv.checkKeyPair(data.Get("KeyName"))
+	srv.formToGroups(data)
+	ifaces, _ := srv.parseRunNetworkInterfaces(data)
+	tagSpecs := parseTagSpecs(data, "instance", "volume", "network-interface")
+	d := ec2.LaunchTemplateData{
+		ImageId:               data.Get("ImageId"),
+		InstanceType:          data.Get("InstanceType"),
+		KeyName:               data.Get("KeyName"),
+		KernelId:              data.Get("KernelId"),
+		RamdiskId:             data.Get("RamdiskId"),
+		AvailZone:             data.Get("Placement.AvailabilityZone"),
+		PlacementGroupName:    data.Get("Placement.GroupName"),
+		Tenancy:               data.Get("Placement.Tenancy"),
+		Monitoring:            parseBoolParam(data, "Monitoring.Enabled"),
+		DisableAPITermination: parseBoolParam(data, "DisableApiTermination"),
+		ShutdownBehavior:      data.Get("InstanceInitiatedShutdownBehavior"),
+		SecurityGroupIds:      parseIndexed(data, "SecurityGroupId."),
+		SecurityGroups:        parseIndexed(data, "SecurityGroup."),
+		BlockDeviceMappings:   parseBlockDeviceMappings(data),
+		UserData:              []byte(data.Get("UserData")),
+	}
+	if len(ifaces) > 0 {
+		d.NetworkInterfaces = ifaces
+	}
+	for _, resourceType := range []string{"instance", "volume", "network-interface"} {
+		if tags, ok := tagSpecs[resourceType]; ok {
+			d.TagSpecifications = append(d.TagSpecifications, ec2.TagSpecification{
+				ResourceType: resourceType,
+				Tags:         tags,
+			})
+		}
+	}
+	return d
+}
+
+// parseIndexed returns the values of the form fields with the given
+// prefix followed by an index, in index order.
+func parseIndexed(form url.Values, prefix string) []string {
+	indexes := make(map[int]string)
+	var order []int
+	for name, values := range form {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		i, err := strconv.Atoi(name[len(prefix):])
+		if err != nil {
+			continue
+		}
+		indexes[i] = values[0]
+		order = append(order, i)
+	}
+	sort.Ints(order)
+	var result []string
+	for _, i := range order {
+		result = append(result, indexes[i])
+	}
+	return result
+}
+
+// launchParamGroup returns the name of the group of launch
+// parameters that the parameter with the given name belongs to.
+// Parameters given when launching from a template replace all the
+// parameters of the template in the same group, so that lists are
+// replaced as a whole.
+func launchParamGroup(name string) string {
+	switch {
+	case strings.HasPrefix(name, "SecurityGroupId."), strings.HasPrefix(name, "SecurityGroup."):
+		return "SecurityGroup"
+	case strings.HasPrefix(name, "NetworkInterface."), name == "SubnetId":
+		// An instance-level subnet replaces the network
+		// interfaces of the template.
+		return "NetworkInterface"
+	case strings.HasPrefix(name, "BlockDeviceMapping."):
+		return "BlockDeviceMapping"
+	case strings.HasPrefix(name, "TagSpecification."):
+		return "TagSpecification"
+	}
+	return name
+}
+
+// applyLaunchTemplate returns the RunInstances parameters in form
+// merged with those of the launch template version they select, if
+// any, and that version.
+// It must be called with srv.mu held.
+func (srv *Server) applyLaunchTemplate(form url.Values) (url.Values, *launchTemplateVersion) {
+	if form.Get("LaunchTemplate.LaunchTemplateId") == "" && form.Get("LaunchTemplate.LaunchTemplateName") == "" {
+		return form, nil
+	}
+	t := srv.launchTemplateFromForm(form, "LaunchTemplate.")
+	v := t.version(form.Get("LaunchTemplate.Version"))
+
+	overridden := make(map[string]bool)
+	for name := range form {
+		overridden[launchParamGroup(name)] = true
+	}
+	merged := make(url.Values)
+	for name, values := range v.data {
+		if !overridden[launchParamGroup(name)] {
+			merged[name] = values
+		}
+	}
+	for name, values := range form {
+		if !strings.HasPrefix(name, "LaunchTemplate.") {
+			merged[name] = values
+		}
+	}
+	return merged, v
+}
+
+// instanceTags returns the tags that EC2 adds to the instances
+// launched from the launch template version v.
+func (v *launchTemplateVersion) instanceTags() []ec2.Tag {
+	return []ec2.Tag{
+		{Key: "aws:ec2launchtemplate:id", Value: v.TemplateId},
+		{Key: "aws:ec2launchtemplate:version", Value: strconv.FormatInt(v.VersionNumber, 10)},
+	}
+}
+
+// addLaunchTemplateVersion adds a new version to t, with the given
+// description and launch parameters, and returns it.
+// It must be called with srv.mu held.
+func (srv *Server) addLaunchTemplateVersion(t *launchTemplate, description string, data url.Values) *launchTemplateVersion {
+	v := &launchTemplateVersion{
+		LaunchTemplateVersion: ec2.LaunchTemplateVersion{
+			TemplateId:         t.Id,
+			TemplateName:       t.Name,
+			VersionNumber:      int64(len(t.versions) + 1),
+			VersionDescription: description,
+			CreateTime:         srv.now().Format(time.RFC3339),
+			CreatedBy:          t.CreatedBy,
+			DefaultVersion:     int64(len(t.versions)+1) == t.DefaultVersionNumber,
+			Data:               srv.launchTemplateData(data),
+		},
+		data: data,
+	}
+	t.versions = append(t.versions, v)
+	t.LatestVersionNumber = v.VersionNumber
+	return v
+}
+
+func (srv *Server) createLaunchTemplate(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("LaunchTemplateName")
+	if name == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter LaunchTemplateName")
+	}
+	data := parseLaunchTemplateData(req.Form)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, t := range srv.launchTemplates {
+		if t.Name == name {
+			fatalf(400, "InvalidLaunchTemplateName.AlreadyExistsException", "Launch template name already in use.")
+		}
+	}
+	t := &launchTemplate{
+		LaunchTemplate: ec2.LaunchTemplate{
+			Id:                   fmt.Sprintf("lt-%d", srv.launchTemplateId.next()),
+			Name:                 name,
+			CreateTime:           srv.now().Format(time.RFC3339),
+			CreatedBy:            "arn:aws:iam::" + ownerId + ":root",
+			DefaultVersionNumber: 1,
+		},
+	}
+	srv.addLaunchTemplateVersion(t, req.Form.Get("VersionDescription"), data)
+	srv.launchTemplates[t.Id] = t
+	return &ec2.CreateLaunchTemplateResp{
+		RequestId:      reqId,
+		LaunchTemplate: t.LaunchTemplate,
+	}
+}
+
+func (srv *Server) createLaunchTemplateVersion(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	data := parseLaunchTemplateData(req.Form)
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.launchTemplateFromForm(req.Form, "")
+	if source := req.Form.Get("SourceVersion"); source != "" {
+		// The new version holds the parameters of the source
+		// version, overridden as when launching instances.
+		overridden := make(map[string]bool)
+		for name := range data {
+			overridden[launchParamGroup(name)] = true
+		}
+		for name, values := range t.version(source).data {
+			if !overridden[launchParamGroup(name)] {
+				data[name] = values
+			}
+		}
+	}
+	v := srv.addLaunchTemplateVersion(t, req.Form.Get("VersionDescription"), data)
+	return &ec2.CreateLaunchTemplateVersionResp{
+		RequestId: reqId,
+		Version:   v.LaunchTemplateVersion,
+	}
+}
+
+func (srv *Server) describeLaunchTemplates(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var templates []*launchTemplate
+	ids := parseIDs(req.Form, "LaunchTemplateId.")
+	names := parseIDs(req.Form, "LaunchTemplateName.")
+	for id := range ids {
+		if srv.launchTemplates[id] == nil {
+			fatalf(400, "InvalidLaunchTemplateId.NotFound", "The specified launch template, with template ID %s, does not exist.", id)
+		}
+	}
+	for name := range names {
+		found := false
+		for _, t := range srv.launchTemplates {
+			found = found || t.Name == name
+		}
+		if !found {
+			fatalf(400, "InvalidLaunchTemplateName.NotFoundException", "The specified launch template, with template name %s, does not exist.", name)
+		}
+	}
+	for id, t := range srv.launchTemplates {
+		if len(ids) == 0 && len(names) == 0 || ids[id] || names[t.Name] {
+			templates = append(templates, t)
+		}
+	}
+	sort.Sort(launchTemplatesById(templates))
+
+	f := newFilter(req.Form)
+	var resp ec2.LaunchTemplatesResp
+	resp.RequestId = reqId
+	for _, t := range templates {
+		ok, err := f.ok(t)
+		if ok {
+			resp.LaunchTemplates = append(resp.LaunchTemplates, t.LaunchTemplate)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe launch templates: %v", err)
+		}
+	}
+	return &resp
+}
+
+type launchTemplatesById []*launchTemplate
+
+func (s launchTemplatesById) Len() int      { return len(s) }
+func (s launchTemplatesById) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s launchTemplatesById) Less(i, j int) bool {
+	return atoi(s[i].Id[len("lt-"):]) < atoi(s[j].Id[len("lt-"):])
+}
+
+func (srv *Server) describeLaunchTemplateVersions(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.launchTemplateFromForm(req.Form, "")
+
+	var versions []*launchTemplateVersion
+	if selected := parseIndexed(req.Form, "LaunchTemplateVersion."); len(selected) > 0 {
+		seen := make(map[*launchTemplateVersion]bool)
+		for _, version := range selected {
+			if v := t.version(version); !seen[v] {
+				seen[v] = true
+				versions = append(versions, v)
+			}
+		}
+	} else {
+		versions = t.versions
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.LaunchTemplateVersionsResp
+	resp.RequestId = reqId
+	for _, v := range versions {
+		ok, err := f.ok(launchTemplateVersionFilter{v})
+		if ok {
+			resp.Versions = append(resp.Versions, v.LaunchTemplateVersion)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe launch template versions: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) deleteLaunchTemplate(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.launchTemplateFromForm(req.Form, "")
+	delete(srv.launchTemplates, t.Id)
+	return &ec2.DeleteLaunchTemplateResp{
+		RequestId:      reqId,
+		LaunchTemplate: t.LaunchTemplate,
+	}
+}
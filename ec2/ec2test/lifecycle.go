@@ -0,0 +1,215 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// Clock is a virtual clock that drives the simulated instance state
+// transitions of a server. Time only passes on it when Advance is
+// called.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a new clock showing the given time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the time shown by the clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// SetTransitions makes instances go through their transitional states
+// ("pending", "stopping" and "shutting-down") for the given delay on
+// clock, before they reach the next state ("running", "stopped" and
+// "terminated" respectively). New instances start in the "pending"
+// state unless SetInitialInstanceState says otherwise. Likewise, new
+// images are "pending" for the delay before they are "available",
+// and new snapshots are "pending", with increasing progress, for the
+// delay before they are "completed".
+//
+// If clock is nil, which is the default, a transitional state is
+// only reported once: the instance, image or snapshot reaches the
+// next state as soon as it has been described. Instances started
+// with the initial state set by SetInitialInstanceState stay in it.
+func (srv *Server) SetTransitions(clock *Clock, delay time.Duration) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.clock = clock
+	srv.transitionDelay = delay
+}
+
+// transition moves inst to the given state, which it leaves for next
+// once the transition completes, and returns the resulting state
+// change. If next is the zero state, state is final.
+// It must be called with srv.mu held.
+func (srv *Server) transition(inst *Instance, state, next ec2.InstanceState) ec2.InstanceStateChange {
+	d := ec2.InstanceStateChange{
+		InstanceId:    inst.id(),
+		PreviousState: inst.state,
+		CurrentState:  state,
+	}
+	inst.state = state
+	inst.next = next
+	if srv.clock != nil {
+		inst.nextAt = srv.clock.Now().Add(srv.transitionDelay)
+	}
+	return d
+}
+
+// completeTransition moves inst to its next state if its current
+// transition is complete: when a clock is set, once the transition
+// delay has passed on it; otherwise, whenever it is called.
+// It must be called with srv.mu held.
+func (srv *Server) completeTransition(inst *Instance) {
+	if inst.next == (ec2.InstanceState{}) {
+		return
+	}
+	if srv.clock != nil && srv.clock.Now().Before(inst.nextAt) {
+		return
+	}
+	inst.state, inst.next = inst.next, ec2.InstanceState{}
+}
+
+// completeTransitions completes the instance transitions, and
+// completes the pending snapshots and images, that are due on the
+// clock. It does nothing if no clock is set.
+func (srv *Server) completeTransitions() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.clock == nil {
+		return
+	}
+	for _, inst := range srv.instances {
+		srv.completeTransition(inst)
+	}
+	for _, snap := range srv.snapshots {
+		srv.completeSnapshot(snap)
+	}
+	for _, img := range srv.images {
+		srv.completeImage(img)
+	}
+}
+
+// instancesFromForm returns the instances with the ids given in the
+// form, sorted by id.
+// It must be called with srv.mu held.
+func (srv *Server) instancesFromForm(req *http.Request) []*Instance {
+	ids := parseIDs(req.Form, "InstanceId.")
+	if len(ids) == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter InstanceId")
+	}
+	var insts []*Instance
+	for id := range ids {
+		inst := srv.instances[id]
+		if inst == nil || srv.consistency.Hidden(id) {
+			fatalf(400, "InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+		}
+		insts = append(insts, inst)
+	}
+	sort.Sort(instancesById(insts))
+	return insts
+}
+
+type instancesById []*Instance
+
+func (s instancesById) Len() int           { return len(s) }
+func (s instancesById) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s instancesById) Less(i, j int) bool { return s[i].seq < s[j].seq }
+
+func (srv *Server) startInstances(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	insts := srv.instancesFromForm(req)
+	for _, inst := range insts {
+		switch inst.state {
+		case Pending, Running, Stopped:
+		default:
+			fatalf(400, "IncorrectInstanceState", "The instance '%s' is not in a state from which it can be started.", inst.id())
+		}
+	}
+	var resp ec2.StartInstanceResp
+	resp.RequestId = reqId
+	for _, inst := range insts {
+		if inst.state == Stopped {
+			resp.StateChanges = append(resp.StateChanges, srv.transition(inst, Pending, Running))
+		} else {
+			resp.StateChanges = append(resp.StateChanges, ec2.InstanceStateChange{
+				InstanceId:    inst.id(),
+				PreviousState: inst.state,
+				CurrentState:  inst.state,
+			})
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) stopInstances(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	insts := srv.instancesFromForm(req)
+	for _, inst := range insts {
+		switch inst.state {
+		case Running, Stopping, Stopped:
+		default:
+			fatalf(400, "IncorrectInstanceState", "The instance '%s' is not in a state from which it can be stopped.", inst.id())
+		}
+	}
+	var resp ec2.StopInstanceResp
+	resp.RequestId = reqId
+	for _, inst := range insts {
+		if inst.state == Running {
+			resp.StateChanges = append(resp.StateChanges, srv.transition(inst, Stopping, Stopped))
+		} else {
+			resp.StateChanges = append(resp.StateChanges, ec2.InstanceStateChange{
+				InstanceId:    inst.id(),
+				PreviousState: inst.state,
+				CurrentState:  inst.state,
+			})
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) rebootInstances(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	insts := srv.instancesFromForm(req)
+	for _, inst := range insts {
+		switch inst.state {
+		case Running, ShuttingDown, Terminated:
+			// Requests to reboot terminated instances are ignored.
+		default:
+			fatalf(400, "IncorrectInstanceState", "The instance '%s' is not in a state from which it can be rebooted.", inst.id())
+		}
+	}
+	// A reboot leaves the instance running, so there is nothing
+	// else to simulate.
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "RebootInstancesResponse"},
+		RequestId: reqId,
+	}
+}
@@ -0,0 +1,153 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// natGateway holds a simulated ec2 NAT gateway. Gateways become
+// available as soon as they are created, and linger in the "deleted"
+// state once deleted, as on EC2.
+type natGateway struct {
+	ec2.NatGateway
+}
+
+func (g *natGateway) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "nat-gateway-id":
+		return g.Id == value, nil
+	case "state":
+		return g.State == value, nil
+	case "subnet-id":
+		return g.SubnetId == value, nil
+	case "vpc-id":
+		return g.VPCId == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// natGateway returns the NAT gateway with the given id.
+// It must be called with srv.mu held.
+func (srv *Server) natGateway(id string) *natGateway {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing natGatewayId")
+	}
+	g := srv.natGateways[id]
+	if g == nil {
+		fatalf(400, "NatGatewayNotFound", "NAT gateway %s was not found", id)
+	}
+	return g
+}
+
+func (srv *Server) createNatGateway(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	s := srv.subnet(req.Form.Get("SubnetId"))
+	ip, _, err := net.ParseCIDR(s.CIDRBlock)
+	if err != nil {
+		panic(fmt.Sprintf("subnet %q has invalid CIDR: %v", s.Id, err))
+	}
+	// Just pick a valid subnet IP, as addDefaultNIC does.
+	ip = ip.To4()
+	ip[len(ip)-1] = 6
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	a := srv.addressByAllocation(req.Form.Get("AllocationId"))
+	if a.Domain != "vpc" {
+		fatalf(400, "InvalidAllocationID.NotFound", "The allocation ID '%s' does not exist", a.AllocationId)
+	}
+	if a.associated() {
+		fatalf(400, "Resource.AlreadyAssociated", "Elastic IP address [%s] is already associated", a.AllocationId)
+	}
+	id := fmt.Sprintf("nat-%d", srv.natGatewayId.next())
+	nic := &iface{ec2.NetworkInterface{
+		Id:               fmt.Sprintf("eni-%d", srv.ifaceId.next()),
+		SubnetId:         s.Id,
+		VPCId:            s.VPCId,
+		AvailZone:        s.AvailZone,
+		Description:      "Interface for NAT Gateway " + id,
+		OwnerId:          ownerId,
+		Status:           "in-use",
+		MACAddress:       fmt.Sprintf("20:%02x:60:cb:27:37", srv.ifaceId),
+		PrivateIPAddress: ip.String(),
+		PrivateIPs:       []ec2.PrivateIP{{Address: ip.String(), IsPrimary: true}},
+	}}
+	srv.ifaces[nic.Id] = nic
+	srv.associateIface(a, nic, ip.String())
+	g := &natGateway{ec2.NatGateway{
+		Id:       id,
+		SubnetId: s.Id,
+		VPCId:    s.VPCId,
+		State:    "available",
+		Addresses: []ec2.NatGatewayAddress{{
+			AllocationId:       a.AllocationId,
+			NetworkInterfaceId: nic.Id,
+			PrivateIP:          ip.String(),
+			PublicIP:           a.PublicIP,
+		}},
+		CreateTime: time.Now().Format(time.RFC3339),
+	}}
+	srv.natGateways[g.Id] = g
+	return &ec2.CreateNatGatewayResp{
+		RequestId:  reqId,
+		NatGateway: g.NatGateway,
+	}
+}
+
+func (srv *Server) deleteNatGateway(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	g := srv.natGateway(req.Form.Get("NatGatewayId"))
+	if g.State != "deleted" {
+		for _, na := range g.Addresses {
+			for _, a := range srv.addresses {
+				if a.AllocationId == na.AllocationId {
+					srv.clearAddress(a)
+				}
+			}
+			delete(srv.ifaces, na.NetworkInterfaceId)
+		}
+		g.State = "deleted"
+		g.DeleteTime = time.Now().Format(time.RFC3339)
+	}
+	return &ec2.DeleteNatGatewayResp{
+		RequestId:    reqId,
+		NatGatewayId: g.Id,
+	}
+}
+
+func (srv *Server) describeNatGateways(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var gateways []*natGateway
+	for id := range parseIDs(req.Form, "NatGatewayId.") {
+		gateways = append(gateways, srv.natGateway(id))
+	}
+	if len(gateways) == 0 {
+		for _, g := range srv.natGateways {
+			gateways = append(gateways, g)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.NatGatewaysResp
+	resp.RequestId = reqId
+	for _, g := range gateways {
+		ok, err := f.ok(g)
+		if ok {
+			resp.NatGateways = append(resp.NatGateways, g.NatGateway)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe NAT gateways: %v", err)
+		}
+	}
+	return &resp
+}
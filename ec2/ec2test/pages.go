@@ -0,0 +1,66 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	minPageSize = 5
+	maxPageSize = 1000
+)
+
+// page returns the bounds of the page of a result with n items
+// selected by the MaxResults and NextToken parameters in form, and
+// the token of the following page, which is empty for the last page.
+// Without MaxResults, the whole result is returned in one page. The
+// items of the result must be sorted, so that pages are stable
+// across requests.
+func page(form url.Values, n int) (start, end int, nextToken string) {
+	if token := form.Get("NextToken"); token != "" {
+		start = parseNextToken(form.Get("Action"), token)
+		if start > n {
+			start = n
+		}
+	}
+	end = n
+	if max := form.Get("MaxResults"); max != "" {
+		size, err := strconv.Atoi(max)
+		if err != nil || size < minPageSize || size > maxPageSize {
+			fatalf(400, "InvalidParameterValue", "Value (%s) for parameter maxResults is invalid. Expecting a value between %d and %d.", max, minPageSize, maxPageSize)
+		}
+		if start+size < n {
+			end = start + size
+			nextToken = makeNextToken(form.Get("Action"), end)
+		}
+	}
+	return start, end, nextToken
+}
+
+// makeNextToken returns the token of the page of the results of the
+// given action starting at offset.
+func makeNextToken(action string, offset int) string {
+	return b64.EncodeToString([]byte(fmt.Sprintf("%s:%d", action, offset)))
+}
+
+// parseNextToken returns the offset of the page of the results of
+// the given action encoded in token.
+func parseNextToken(action, token string) int {
+	data, err := b64.DecodeString(token)
+	prefix := action + ":"
+	if err != nil || !strings.HasPrefix(string(data), prefix) {
+		fatalf(400, "InvalidPaginationToken", "Invalid pagination token: %s", token)
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), prefix))
+	if err != nil || offset < 0 {
+		fatalf(400, "InvalidPaginationToken", "Invalid pagination token: %s", token)
+	}
+	return offset
+}
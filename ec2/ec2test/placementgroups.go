@@ -0,0 +1,224 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// maxPartitions holds the maximum number of partitions of a
+// partition placement group.
+const maxPartitions = 7
+
+// placementGroup holds a simulated ec2 placement group.
+type placementGroup struct {
+	ec2.PlacementGroup
+
+	// nextPartition holds the partition, counting from zero, of the
+	// next instance launched in the group without a partition
+	// number.
+	nextPartition int
+}
+
+func (g *placementGroup) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "group-name":
+		return g.Name == value, nil
+	case "state":
+		return g.State == value, nil
+	case "strategy":
+		return g.Strategy == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// placement holds the placement of instances launched by a
+// RunInstances request.
+type placement struct {
+	group           *placementGroup
+	partitionNumber int
+	tenancy         string
+	hostId          string
+	affinity        string
+}
+
+// parsePlacement returns the placement given by the Placement
+// parameters in form, failing if its placement group does not exist.
+// It must be called with srv.mu held.
+func (srv *Server) parsePlacement(form url.Values) *placement {
+	p := &placement{
+		tenancy:  form.Get("Placement.Tenancy"),
+		hostId:   form.Get("Placement.HostId"),
+		affinity: form.Get("Placement.Affinity"),
+	}
+	if p.tenancy == "" && p.hostId != "" {
+		// Launching on a given host implies host tenancy.
+		p.tenancy = "host"
+	}
+	switch p.tenancy {
+	case "":
+		p.tenancy = "default"
+	case "default", "dedicated", "host":
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter tenancy is invalid. Valid values are 'default', 'dedicated' and 'host'.", p.tenancy)
+	}
+	switch p.affinity {
+	case "", "default", "host":
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter affinity is invalid. Valid values are 'default' and 'host'.", p.affinity)
+	}
+	if (p.hostId != "" || p.affinity != "") && p.tenancy != "host" {
+		fatalf(400, "InvalidParameterCombination", "Host ID and affinity may only be specified with host tenancy")
+	}
+	if name := form.Get("Placement.GroupName"); name != "" {
+		p.group = srv.placementGroups[name]
+		if p.group == nil {
+			fatalf(400, "InvalidPlacementGroup.Unknown", "The Placement Group '%s' is unknown.", name)
+		}
+	}
+	if n := form.Get("Placement.PartitionNumber"); n != "" {
+		if p.group == nil || p.group.Strategy != "partition" {
+			fatalf(400, "InvalidParameterCombination", "A partition number may only be specified with a partition placement group")
+		}
+		var err error
+		p.partitionNumber, err = strconv.Atoi(n)
+		if err != nil || p.partitionNumber < 1 || p.partitionNumber > p.group.PartitionCount {
+			fatalf(400, "InvalidParameterValue", "Value (%s) for parameter partitionNumber is invalid. The placement group has %d partitions.", n, p.group.PartitionCount)
+		}
+	}
+	return p
+}
+
+// place records the placement p on the new instance inst. Instances
+// launched in a partition placement group without a partition number
+// are spread evenly across its partitions.
+func (p *placement) place(inst *Instance) {
+	inst.tenancy = p.tenancy
+	inst.hostId = p.hostId
+	inst.affinity = p.affinity
+	if p.group == nil {
+		return
+	}
+	inst.placementGroup = p.group.Name
+	if p.group.Strategy == "partition" {
+		inst.partitionNumber = p.partitionNumber
+		if inst.partitionNumber == 0 {
+			inst.partitionNumber = p.group.nextPartition%p.group.PartitionCount + 1
+			p.group.nextPartition++
+		}
+	}
+}
+
+func (srv *Server) createPlacementGroup(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("GroupName")
+	if name == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter GroupName")
+	}
+	strategy := req.Form.Get("Strategy")
+	switch strategy {
+	case "":
+		fatalf(400, "MissingParameter", "The request must contain the parameter Strategy")
+	case "cluster", "spread", "partition":
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter strategy is invalid. Valid values are 'cluster', 'spread' and 'partition'.", strategy)
+	}
+	partitionCount := 0
+	if strategy == "partition" {
+		partitionCount = 2
+	}
+	if n := req.Form.Get("PartitionCount"); n != "" {
+		if strategy != "partition" {
+			fatalf(400, "InvalidParameterCombination", "The partition count may only be specified for the partition strategy")
+		}
+		var err error
+		partitionCount, err = strconv.Atoi(n)
+		if err != nil || partitionCount < 1 || partitionCount > maxPartitions {
+			fatalf(400, "InvalidParameterValue", "Value (%s) for parameter partitionCount is invalid. It must be between 1 and %d.", n, maxPartitions)
+		}
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.placementGroups[name] != nil {
+		fatalf(400, "InvalidPlacementGroup.Duplicate", "The Placement Group '%s' already exists.", name)
+	}
+	srv.placementGroups[name] = &placementGroup{
+		PlacementGroup: ec2.PlacementGroup{
+			Name:           name,
+			Strategy:       strategy,
+			PartitionCount: partitionCount,
+			State:          "available",
+		},
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "CreatePlacementGroupResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describePlacementGroups(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var names []string
+	if ids := parseIDs(req.Form, "GroupName."); len(ids) > 0 {
+		for name := range ids {
+			if srv.placementGroups[name] == nil {
+				fatalf(400, "InvalidPlacementGroup.Unknown", "The Placement Group '%s' is unknown.", name)
+			}
+			names = append(names, name)
+		}
+	} else {
+		for name := range srv.placementGroups {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	f := newFilter(req.Form)
+	var resp ec2.PlacementGroupsResp
+	resp.RequestId = reqId
+	for _, name := range names {
+		g := srv.placementGroups[name]
+		ok, err := f.ok(g)
+		if ok {
+			resp.PlacementGroups = append(resp.PlacementGroups, g.PlacementGroup)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe placement groups: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) deletePlacementGroup(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	name := req.Form.Get("GroupName")
+	if name == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter GroupName")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.placementGroups[name] == nil {
+		fatalf(400, "InvalidPlacementGroup.Unknown", "The Placement Group '%s' is unknown.", name)
+	}
+	for _, inst := range srv.instances {
+		if inst.placementGroup == name && inst.state != Terminated {
+			fatalf(400, "InvalidPlacementGroup.InUse", "The Placement Group '%s' is in use and may not be deleted.", name)
+		}
+	}
+	delete(srv.placementGroups, name)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeletePlacementGroupResponse"},
+		RequestId: reqId,
+	}
+}
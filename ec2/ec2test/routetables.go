@@ -0,0 +1,463 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// routeTable holds a simulated ec2 route table. The state of its
+// routes is computed when the table is described.
+type routeTable struct {
+	ec2.RouteTable
+}
+
+func (t *routeTable) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "route-table-id":
+		return t.Id == value, nil
+	case "vpc-id":
+		return t.VPCId == value, nil
+	case "association.route-table-association-id":
+		for _, a := range t.Associations {
+			if a.Id == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "association.subnet-id":
+		for _, a := range t.Associations {
+			if a.SubnetId == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "association.main":
+		val, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("bad flag %q: %s", attr, value)
+		}
+		return t.main() == val, nil
+	case "route.destination-cidr-block", "route.gateway-id", "route.instance-id",
+		"route.nat-gateway-id", "route.vpc-peering-connection-id", "route.origin", "route.state":
+		for _, r := range t.Routes {
+			var field string
+			switch attr {
+			case "route.destination-cidr-block":
+				field = r.DestinationCIDRBlock
+			case "route.gateway-id":
+				field = r.GatewayId
+			case "route.instance-id":
+				field = r.InstanceId
+			case "route.nat-gateway-id":
+				field = r.NatGatewayId
+			case "route.vpc-peering-connection-id":
+				field = r.VPCPeeringConnectionId
+			case "route.origin":
+				field = r.Origin
+			case "route.state":
+				field = r.State
+			}
+			if field == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// main reports whether t is the main route table of its VPC.
+func (t *routeTable) main() bool {
+	for _, a := range t.Associations {
+		if a.Main {
+			return true
+		}
+	}
+	return false
+}
+
+// routeIndex returns the index of the route of t with the given
+// destination, or -1 if there is none.
+func (t *routeTable) routeIndex(dest string) int {
+	for i, r := range t.Routes {
+		if r.DestinationCIDRBlock == dest {
+			return i
+		}
+	}
+	return -1
+}
+
+// newRouteTable creates a route table for v holding its local route.
+// If main is true, the table becomes the main route table of v.
+// It must be called with srv.mu held.
+func (srv *Server) newRouteTable(v *vpc, main bool) *routeTable {
+	t := &routeTable{ec2.RouteTable{
+		Id:    fmt.Sprintf("rtb-%d", srv.routeTableId.next()),
+		VPCId: v.Id,
+		Routes: []ec2.Route{{
+			DestinationCIDRBlock: v.CIDRBlock,
+			GatewayId:            "local",
+			State:                "active",
+			Origin:               "CreateRouteTable",
+		}},
+	}}
+	if main {
+		t.Associations = []ec2.RouteTableAssociation{{
+			Id:           fmt.Sprintf("rtbassoc-%d", srv.routeTableAssocId.next()),
+			RouteTableId: t.Id,
+			Main:         true,
+		}}
+	}
+	srv.routeTables[t.Id] = t
+	return t
+}
+
+// routeTable returns the route table with the given id.
+// It must be called with srv.mu held.
+func (srv *Server) routeTable(id string) *routeTable {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing routeTableId")
+	}
+	t := srv.routeTables[id]
+	if t == nil {
+		fatalf(400, "InvalidRouteTableID.NotFound", "The routeTable ID '%s' does not exist", id)
+	}
+	return t
+}
+
+// routeTableAssociation returns the route table and the index of the
+// association with the given id. It must be called with srv.mu held.
+func (srv *Server) routeTableAssociation(id string) (*routeTable, int) {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing associationId")
+	}
+	for _, t := range srv.routeTables {
+		for i, a := range t.Associations {
+			if a.Id == id {
+				return t, i
+			}
+		}
+	}
+	fatalf(400, "InvalidAssociationID.NotFound", "The association ID '%s' does not exist", id)
+	return nil, 0
+}
+
+// removeSubnetAssociation removes any explicit association of the
+// subnet with the given id. It must be called with srv.mu held.
+func (srv *Server) removeSubnetAssociation(subnetId string) {
+	for _, t := range srv.routeTables {
+		for i, a := range t.Associations {
+			if a.SubnetId == subnetId {
+				t.Associations = append(t.Associations[:i], t.Associations[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// ec2RouteTable returns t as described by EC2: routes whose target
+// is gone are reported as blackholes. It must be called with srv.mu
+// held.
+func (srv *Server) ec2RouteTable(t *routeTable) ec2.RouteTable {
+	rt := t.RouteTable
+	rt.Routes = make([]ec2.Route, len(t.Routes))
+	for i, r := range t.Routes {
+		r.State = "blackhole"
+		if srv.routeTargetActive(t, r) {
+			r.State = "active"
+		}
+		rt.Routes[i] = r
+	}
+	return rt
+}
+
+// routeTargetActive reports whether the target of r can still route
+// traffic for t. It must be called with srv.mu held.
+func (srv *Server) routeTargetActive(t *routeTable, r ec2.Route) bool {
+	switch {
+	case r.GatewayId == "local":
+		return true
+	case r.GatewayId != "":
+		g := srv.igws[r.GatewayId]
+		return g != nil && g.vpcId() == t.VPCId
+	case r.NatGatewayId != "":
+		g := srv.natGateways[r.NatGatewayId]
+		return g != nil && (g.State == "pending" || g.State == "available")
+	case r.VPCPeeringConnectionId != "":
+		p := srv.peerings[r.VPCPeeringConnectionId]
+		return p != nil && p.Status.Code == "active"
+	case r.InstanceId != "":
+		inst := srv.instances[r.InstanceId]
+		return inst != nil && (inst.state == Pending || inst.state == Running)
+	case r.NetworkInterfaceId != "":
+		return srv.ifaces[r.NetworkInterfaceId] != nil
+	}
+	return false
+}
+
+// parseRoute returns the route specified in form for t, checking
+// that it has exactly one target, which must be in the same VPC as
+// t. It must be called with srv.mu held.
+func (srv *Server) parseRoute(form url.Values, t *routeTable) ec2.Route {
+	r := ec2.Route{
+		DestinationCIDRBlock: parseCidr(form.Get("DestinationCidrBlock")),
+		Origin:               "CreateRoute",
+	}
+	targets := 0
+	for _, name := range []string{"GatewayId", "InstanceId", "NetworkInterfaceId", "NatGatewayId", "VpcPeeringConnectionId"} {
+		if form.Get(name) != "" {
+			targets++
+		}
+	}
+	if targets == 0 {
+		fatalf(400, "MissingParameter", "The request must contain exactly one of gatewayId, natGatewayId, networkInterfaceId, vpcPeeringConnectionId or instanceId")
+	}
+	if targets > 1 {
+		fatalf(400, "InvalidParameterCombination", "Only one of gatewayId, natGatewayId, networkInterfaceId, vpcPeeringConnectionId or instanceId may be specified")
+	}
+	differentNetworks := func(target string) {
+		fatalf(400, "InvalidParameterValue", "route table %s and network %s belong to different networks", t.Id, target)
+	}
+	switch {
+	case form.Get("GatewayId") != "":
+		g := srv.igws[form.Get("GatewayId")]
+		if g == nil {
+			fatalf(400, "InvalidGatewayID.NotFound", "The gateway ID '%s' does not exist", form.Get("GatewayId"))
+		}
+		if g.vpcId() != t.VPCId {
+			differentNetworks(g.Id)
+		}
+		r.GatewayId = g.Id
+	case form.Get("InstanceId") != "":
+		id := form.Get("InstanceId")
+		inst := srv.instances[id]
+		if inst == nil || srv.consistency.Hidden(id) {
+			fatalf(400, "InvalidInstanceID.NotFound", "The instance ID '%s' does not exist", id)
+		}
+		if inst.vpcId != t.VPCId {
+			differentNetworks(id)
+		}
+		if len(inst.ifaces) > 1 {
+			fatalf(400, "InvalidInstanceID", "There are multiple interfaces attached to instance '%s'. Please specify an interface ID for the operation instead.", id)
+		}
+		r.InstanceId = id
+		r.InstanceOwnerId = ownerId
+		if nic := srv.primaryIface(inst); nic != nil {
+			r.NetworkInterfaceId = nic.Id
+		}
+	case form.Get("NetworkInterfaceId") != "":
+		id := form.Get("NetworkInterfaceId")
+		nic := srv.ifaces[id]
+		if nic == nil {
+			fatalf(400, "InvalidNetworkInterfaceID.NotFound", "The networkInterface ID '%s' does not exist", id)
+		}
+		if nic.VPCId != t.VPCId {
+			differentNetworks(id)
+		}
+		r.NetworkInterfaceId = id
+		if instId := nic.Attachment.InstanceId; instId != "" {
+			r.InstanceId = instId
+			r.InstanceOwnerId = ownerId
+		}
+	case form.Get("NatGatewayId") != "":
+		id := form.Get("NatGatewayId")
+		g := srv.natGateways[id]
+		if g == nil || g.State == "deleted" {
+			fatalf(400, "InvalidNatGatewayID.NotFound", "The natGateway ID '%s' does not exist", id)
+		}
+		if g.VPCId != t.VPCId {
+			differentNetworks(id)
+		}
+		r.NatGatewayId = id
+	case form.Get("VpcPeeringConnectionId") != "":
+		p := srv.peering(form.Get("VpcPeeringConnectionId"))
+		if p.Status.Code != "active" {
+			fatalf(400, "InvalidParameterValue", "VPC peering connection %s is not active", p.Id)
+		}
+		if p.RequesterVPC.VPCId != t.VPCId && p.AccepterVPC.VPCId != t.VPCId {
+			differentNetworks(p.Id)
+		}
+		r.VPCPeeringConnectionId = p.Id
+	}
+	return r
+}
+
+func (srv *Server) createRouteTable(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.vpc(req.Form.Get("VpcId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.newRouteTable(v, false)
+	return &ec2.CreateRouteTableResp{
+		RequestId:  reqId,
+		RouteTable: srv.ec2RouteTable(t),
+	}
+}
+
+func (srv *Server) deleteRouteTable(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.routeTable(req.Form.Get("RouteTableId"))
+	if len(t.Associations) > 0 {
+		fatalf(400, "DependencyViolation", "The routeTable '%s' has dependencies and cannot be deleted.", t.Id)
+	}
+	delete(srv.routeTables, t.Id)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteRouteTableResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describeRouteTables(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var tables []*routeTable
+	for id := range parseIDs(req.Form, "RouteTableId.") {
+		tables = append(tables, srv.routeTable(id))
+	}
+	if len(tables) == 0 {
+		for _, t := range srv.routeTables {
+			tables = append(tables, t)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.RouteTablesResp
+	resp.RequestId = reqId
+	for _, t := range tables {
+		rt := &routeTable{srv.ec2RouteTable(t)}
+		ok, err := f.ok(rt)
+		if ok {
+			resp.RouteTables = append(resp.RouteTables, rt.RouteTable)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe route tables: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) associateRouteTable(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	s := srv.subnet(req.Form.Get("SubnetId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.routeTable(req.Form.Get("RouteTableId"))
+	if s.VPCId != t.VPCId {
+		fatalf(400, "InvalidParameterValue", "route table %s and subnet %s belong to different networks", t.Id, s.Id)
+	}
+	for _, other := range srv.routeTables {
+		for _, a := range other.Associations {
+			if a.SubnetId == s.Id {
+				fatalf(400, "Resource.AlreadyAssociated", "the specified association for route table %s conflicts with an existing association", t.Id)
+			}
+		}
+	}
+	a := ec2.RouteTableAssociation{
+		Id:           fmt.Sprintf("rtbassoc-%d", srv.routeTableAssocId.next()),
+		RouteTableId: t.Id,
+		SubnetId:     s.Id,
+	}
+	t.Associations = append(t.Associations, a)
+	return &ec2.AssociateRouteTableResp{
+		RequestId:     reqId,
+		AssociationId: a.Id,
+	}
+}
+
+func (srv *Server) disassociateRouteTable(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t, i := srv.routeTableAssociation(req.Form.Get("AssociationId"))
+	if t.Associations[i].Main {
+		fatalf(400, "InvalidParameterValue", "cannot disassociate the main route table association %s", t.Associations[i].Id)
+	}
+	t.Associations = append(t.Associations[:i], t.Associations[i+1:]...)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DisassociateRouteTableResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) replaceRouteTableAssociation(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	old, i := srv.routeTableAssociation(req.Form.Get("AssociationId"))
+	t := srv.routeTable(req.Form.Get("RouteTableId"))
+	if t.VPCId != old.VPCId {
+		fatalf(400, "InvalidParameterValue", "route table %s and association %s belong to different networks", t.Id, old.Associations[i].Id)
+	}
+	a := old.Associations[i]
+	old.Associations = append(old.Associations[:i], old.Associations[i+1:]...)
+	a.Id = fmt.Sprintf("rtbassoc-%d", srv.routeTableAssocId.next())
+	a.RouteTableId = t.Id
+	t.Associations = append(t.Associations, a)
+	return &ec2.ReplaceRouteTableAssociationResp{
+		RequestId:        reqId,
+		NewAssociationId: a.Id,
+	}
+}
+
+func (srv *Server) createRoute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.routeTable(req.Form.Get("RouteTableId"))
+	r := srv.parseRoute(req.Form, t)
+	if t.routeIndex(r.DestinationCIDRBlock) >= 0 {
+		fatalf(400, "RouteAlreadyExists", "The route identified by %s already exists.", r.DestinationCIDRBlock)
+	}
+	t.Routes = append(t.Routes, r)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "CreateRouteResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) replaceRoute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.routeTable(req.Form.Get("RouteTableId"))
+	r := srv.parseRoute(req.Form, t)
+	i := t.routeIndex(r.DestinationCIDRBlock)
+	if i < 0 {
+		fatalf(400, "InvalidRoute.NotFound", "no route with destination-cidr-block %s in route table %s", r.DestinationCIDRBlock, t.Id)
+	}
+	if t.Routes[i].GatewayId == "local" {
+		fatalf(400, "InvalidParameterValue", "cannot replace local route %s in route table %s", r.DestinationCIDRBlock, t.Id)
+	}
+	t.Routes[i] = r
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "ReplaceRouteResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) deleteRoute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	dest := parseCidr(req.Form.Get("DestinationCidrBlock"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	t := srv.routeTable(req.Form.Get("RouteTableId"))
+	i := t.routeIndex(dest)
+	if i < 0 {
+		fatalf(400, "InvalidRoute.NotFound", "no route with destination-cidr-block %s in route table %s", dest, t.Id)
+	}
+	if t.Routes[i].GatewayId == "local" {
+		fatalf(400, "InvalidParameterValue", "cannot remove local route %s in route table %s", dest, t.Id)
+	}
+	t.Routes = append(t.Routes[:i], t.Routes[i+1:]...)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteRouteResponse"},
+		RequestId: reqId,
+	}
+}
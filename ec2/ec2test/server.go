@@ -7,6 +7,12 @@
 // the capability of inducing errors on any given operation,
 // and retrospectively determining what operations have been
 // carried out.
+//
+// Instances may be launched from any image id the server has not
+// issued itself unless SetStrictImages is called, after which
+// RunInstances only launches the images known to the server, such
+// as those added with AddImage. Deregistered images cannot be
+// launched in either case.
 package ec2test
 
 import (
@@ -18,12 +24,14 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/testutil/faults"
 )
 
 var b64 = base64.StdEncoding
@@ -53,16 +61,40 @@ type Server struct {
 	listener net.Listener
 	mu       sync.Mutex
 	reqs     []*Action
+	faults   *faults.Injector
+
+	// consistency is nil unless eventual consistency is emulated.
+	// Instances and groups are keyed by id; deleted groups linger
+	// as *securityGroup.
+	consistency *faults.Consistency
 
 	attributes           map[string][]string       // attr name -> values
 	instances            map[string]*Instance      // id -> instance
 	reservations         map[string]*reservation   // id -> reservation
 	groups               map[string]*securityGroup // id -> group
 	zones                []availabilityZone
-	vpcs                 map[string]*vpc        // id -> vpc
-	subnets              map[string]*subnet     // id -> subnet
-	ifaces               map[string]*iface      // id -> iface
-	attachments          map[string]*attachment // id -> attachment
+	vpcs                 map[string]*vpc             // id -> vpc
+	subnets              map[string]*subnet          // id -> subnet
+	ifaces               map[string]*iface           // id -> iface
+	attachments          map[string]*attachment      // id -> attachment
+	volumes              map[string]*volume          // id -> volume
+	snapshots            map[string]*snapshot        // id -> snapshot
+	keyPairs             map[string]*keyPair         // name -> key pair
+	addresses            map[string]*address         // public ip -> address
+	igws                 map[string]*internetGateway // id -> gateway
+	routeTables          map[string]*routeTable      // id -> route table
+	natGateways          map[string]*natGateway      // id -> gateway
+	dhcpOptions          map[string]*dhcpOptions     // id -> DHCP options
+	peerings             map[string]*vpcPeering      // id -> peering connection
+	images               map[string]*image           // id -> image
+	deregisteredImages   map[string]bool             // id -> deregistered
+	spotRequests         map[string]*spotRequest     // id -> spot request
+	placementGroups      map[string]*placementGroup  // name -> placement group
+	launchTemplates      map[string]*launchTemplate  // id -> launch template
+	spotPrices           map[string]string           // instance type -> spot price
+	spotPriceHistory     []*spotPrice                // in time order
+	spotCapacity         bool
+	defaultDHCPOptsId    string
 	maxId                counter
 	reqId                counter
 	reservationId        counter
@@ -72,7 +104,30 @@ type Server struct {
 	subnetId             counter
 	ifaceId              counter
 	attachId             counter
+	volumeId             counter
+	snapshotId           counter
+	addressId            counter
+	associationId        counter
+	igwId                counter
+	routeTableId         counter
+	routeTableAssocId    counter
+	natGatewayId         counter
+	peeringId            counter
+	imageId              counter
+	spotRequestId        counter
+	launchTemplateId     counter
+	ipv6BlockId          counter
+	cidrAssocId          counter
 	initialInstanceState ec2.InstanceState
+
+	// strictImages causes instances to be launched only from known
+	// images.
+	strictImages bool
+
+	// clock, if set, drives the instance state transitions,
+	// which take transitionDelay on it.
+	clock           *Clock
+	transitionDelay time.Duration
 }
 
 // reservation holds a simulated ec2 reservation.
@@ -80,6 +135,10 @@ type reservation struct {
 	id        string
 	instances map[string]*Instance
 	groups    []*securityGroup
+
+	// clientToken holds the token of the RunInstances request that
+	// made the reservation, if any.
+	clientToken string
 }
 
 // instance holds a simulated ec2 instance
@@ -92,26 +151,74 @@ type Instance struct {
 	imageId     string
 	reservation *reservation
 	instType    string
+	keyName     string
+	publicIP    string
 	availZone   string
 	state       ec2.InstanceState
 	subnetId    string
 	vpcId       string
+	launchTime  time.Time
 	ifaces      []ec2.NetworkInterface
-}
-
-// permKey represents permission for a given security
-// group or IP address (but not both) to access a given range of
-// ports. Equality of permKeys is used in the implementation of
+	volumes     []*volume
+	tags        []ec2.Tag
+
+	// spotRequestId holds the id of the spot request the instance
+	// was launched for, if any.
+	spotRequestId string
+
+	// placementGroup and partitionNumber hold the placement group
+	// the instance was launched in, if any, and its partition.
+	placementGroup  string
+	partitionNumber int
+	tenancy         string
+	hostId          string
+	affinity        string
+
+	// noPublicIP is set for instances launched in a subnet that
+	// does not map public IP addresses on launch.
+	noPublicIP bool
+
+	sourceDestCheck       bool
+	disableAPITermination bool
+	shutdownBehavior      string
+
+	// consoleOutput holds the console output set by
+	// SetConsoleOutput at consoleTime.
+	consoleOutput []byte
+	consoleTime   time.Time
+
+	// systemStatus, instanceStatus and events hold the status set
+	// by SetInstanceStatus. The statuses of running instances are
+	// "ok" when not set.
+	systemStatus   string
+	instanceStatus string
+	events         []ec2.InstanceStatusEvent
+
+	// next holds the state the instance reaches when its current
+	// transition completes, at nextAt if the server has a clock.
+	next   ec2.InstanceState
+	nextAt time.Time
+}
+
+// permKey represents permission for a single security group,
+// IPv4 range, IPv6 range or prefix list to access a given range
+// of ports. Equality of permKeys is used in the implementation of
 // permission sets, relying on the uniqueness of securityGroup
 // instances.
 type permKey struct {
-	protocol string
-	fromPort int
-	toPort   int
-	group    *securityGroup
-	ipAddr   string
+	protocol     string
+	fromPort     int
+	toPort       int
+	group        *securityGroup
+	ipAddr       string
+	ipv6Addr     string
+	prefixListId string
 }
 
+// permSet holds a set of permissions, mapping each
+// permission to its description.
+type permSet map[permKey]string
+
 // securityGroup holds a simulated ec2 security group.
 // Instances of securityGroup should only be created through
 // Server.createSecurityGroup to ensure that groups can be
@@ -122,7 +229,9 @@ type securityGroup struct {
 	description string
 	vpcId       string
 
-	perms map[permKey]bool
+	perms       permSet
+	egressPerms permSet
+	tags        []ec2.Tag
 }
 
 func (g *securityGroup) ec2SecurityGroup() ec2.SecurityGroup {
@@ -133,43 +242,60 @@ func (g *securityGroup) ec2SecurityGroup() ec2.SecurityGroup {
 }
 
 func (g *securityGroup) matchAttr(attr, value string) (ok bool, err error) {
+	perms := g.perms
+	if strings.HasPrefix(attr, "egress.") {
+		perms = g.egressPerms
+		attr = strings.TrimPrefix(attr, "egress.")
+	}
 	switch attr {
 	case "description":
-		return g.description == value, nil
+		return matchValue(value, g.description), nil
 	case "group-id":
-		return g.id == value, nil
+		return matchValue(value, g.id), nil
 	case "group-name":
-		return g.name == value, nil
+		return matchValue(value, g.name), nil
 	case "ip-permission.cidr":
-		return g.hasPerm(func(k permKey) bool { return k.ipAddr == value }), nil
+		return perms.has(func(k permKey) bool { return matchValue(value, k.ipAddr) }), nil
+	case "ip-permission.ipv6-cidr":
+		return perms.has(func(k permKey) bool { return matchValue(value, k.ipv6Addr) }), nil
+	case "ip-permission.prefix-list-id":
+		return perms.has(func(k permKey) bool { return matchValue(value, k.prefixListId) }), nil
+	case "ip-permission.group-id":
+		return perms.has(func(k permKey) bool {
+			return k.group != nil && matchValue(value, k.group.id)
+		}), nil
 	case "ip-permission.group-name":
-		return g.hasPerm(func(k permKey) bool {
-			return k.group != nil && k.group.name == value
+		return perms.has(func(k permKey) bool {
+			return k.group != nil && matchValue(value, k.group.name)
 		}), nil
 	case "ip-permission.from-port":
 		port, err := strconv.Atoi(value)
 		if err != nil {
 			return false, err
 		}
-		return g.hasPerm(func(k permKey) bool { return k.fromPort == port }), nil
+		return perms.has(func(k permKey) bool { return k.fromPort == port }), nil
 	case "ip-permission.to-port":
 		port, err := strconv.Atoi(value)
 		if err != nil {
 			return false, err
 		}
-		return g.hasPerm(func(k permKey) bool { return k.toPort == port }), nil
+		return perms.has(func(k permKey) bool { return k.toPort == port }), nil
 	case "ip-permission.protocol":
-		return g.hasPerm(func(k permKey) bool { return k.protocol == value }), nil
+		return perms.has(func(k permKey) bool { return matchValue(value, k.protocol) }), nil
+	case "ip-permission.user-id":
+		return perms.has(func(k permKey) bool {
+			return k.group != nil && matchValue(value, ownerId)
+		}), nil
 	case "owner-id":
-		return value == ownerId, nil
+		return matchValue(value, ownerId), nil
 	case "vpc-id":
-		return g.vpcId == value, nil
+		return matchValue(value, g.vpcId), nil
 	}
 	return false, fmt.Errorf("unknown attribute %q", attr)
 }
 
-func (g *securityGroup) hasPerm(test func(k permKey) bool) bool {
-	for k := range g.perms {
+func (perms permSet) has(test func(k permKey) bool) bool {
+	for k := range perms {
 		if test(k) {
 			return true
 		}
@@ -177,47 +303,70 @@ func (g *securityGroup) hasPerm(test func(k permKey) bool) bool {
 	return false
 }
 
-// ec2Perms returns the list of EC2 permissions granted
-// to g. It groups permissions by port range and protocol.
-func (g *securityGroup) ec2Perms() (perms []ec2.IPPerm) {
-	// The grouping is held in result. We use permKey for convenience,
-	// (ensuring that the group and ipAddr of each key is zero). For
+// ec2Perms returns the list of EC2 permissions in perms.
+// It groups permissions by port range and protocol.
+func (perms permSet) ec2Perms() (result []ec2.IPPerm) {
+	// The grouping is held in grouped. We use permKey for convenience,
+	// (ensuring that the source fields of each key are zero). For
 	// each protocol/port range combination, we build up the permission
 	// set in the associated value.
-	result := make(map[permKey]*ec2.IPPerm)
-	for k := range g.perms {
-		groupKey := k
-		groupKey.group = nil
-		groupKey.ipAddr = ""
-
-		ec2p := result[groupKey]
+	grouped := make(map[permKey]*ec2.IPPerm)
+	for k, desc := range perms {
+		gk := permKey{
+			protocol: k.protocol,
+			fromPort: k.fromPort,
+			toPort:   k.toPort,
+		}
+		ec2p := grouped[gk]
 		if ec2p == nil {
 			ec2p = &ec2.IPPerm{
 				Protocol: k.protocol,
 				FromPort: k.fromPort,
 				ToPort:   k.toPort,
 			}
-			result[groupKey] = ec2p
+			grouped[gk] = ec2p
 		}
-		if k.group != nil {
+		var source string
+		switch {
+		case k.group != nil:
 			ec2p.SourceGroups = append(ec2p.SourceGroups,
 				ec2.UserSecurityGroup{
-					Id:      k.group.id,
-					Name:    k.group.name,
-					OwnerId: ownerId,
+					Id:          k.group.id,
+					Name:        k.group.name,
+					OwnerId:     ownerId,
+					Description: desc,
 				})
-		} else {
-			ec2p.SourceIPs = append(ec2p.SourceIPs, k.ipAddr)
+			continue
+		case k.ipv6Addr != "":
+			source = k.ipv6Addr
+			ec2p.SourceIPv6s = append(ec2p.SourceIPv6s, source)
+		case k.prefixListId != "":
+			source = k.prefixListId
+			ec2p.PrefixListIds = append(ec2p.PrefixListIds, source)
+		default:
+			source = k.ipAddr
+			ec2p.SourceIPs = append(ec2p.SourceIPs, source)
+		}
+		if desc != "" {
+			if ec2p.Descriptions == nil {
+				ec2p.Descriptions = make(map[string]string)
+			}
+			ec2p.Descriptions[source] = desc
 		}
 	}
-	for _, ec2p := range result {
-		perms = append(perms, *ec2p)
+	for _, ec2p := range grouped {
+		result = append(result, *ec2p)
 	}
 	return
 }
 
 type vpc struct {
 	ec2.VPC
+
+	// dnsSupport and dnsHostnames hold the enableDnsSupport and
+	// enableDnsHostnames attributes of the VPC.
+	dnsSupport   bool
+	dnsHostnames bool
 }
 
 func (v *vpc) matchAttr(attr, value string) (ok bool, err error) {
@@ -228,7 +377,27 @@ func (v *vpc) matchAttr(attr, value string) (ok bool, err error) {
 		return v.State == value, nil
 	case "vpc-id":
 		return v.Id == value, nil
-	case "tag", "tag-key", "tag-value", "dhcp-options-id", "isDefault":
+	case "dhcp-options-id":
+		return v.DHCPOptionsId == value, nil
+	case "cidr-block-association.cidr-block":
+		for _, b := range v.CIDRBlocks {
+			if b.CIDRBlock == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "cidr-block-association.association-id":
+		for _, b := range v.CIDRBlocks {
+			if b.AssociationId == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "ipv6-cidr-block-association.ipv6-cidr-block",
+		"ipv6-cidr-block-association.association-id",
+		"ipv6-cidr-block-association.state":
+		return matchIPv6CIDRBlocks(v.IPv6CIDRBlocks, attr, value), nil
+	case "tag", "isDefault":
 		return false, fmt.Errorf("%q filter is not implemented", attr)
 	}
 	return false, fmt.Errorf("unknown attribute %q", attr)
@@ -256,7 +425,11 @@ func (s *subnet) matchAttr(attr, value string) (ok bool, err error) {
 			return false, fmt.Errorf("bad flag %q: %s", attr, value)
 		}
 		return s.DefaultForAZ == val, nil
-	case "tag", "tag-key", "tag-value", "available-ip-address-count":
+	case "ipv6-cidr-block-association.ipv6-cidr-block",
+		"ipv6-cidr-block-association.association-id",
+		"ipv6-cidr-block-association.state":
+		return matchIPv6CIDRBlocks(s.IPv6CIDRBlocks, attr, value), nil
+	case "tag", "available-ip-address-count":
 		return false, fmt.Errorf("%q filter not implemented", attr)
 	}
 	return false, fmt.Errorf("unknown attribute %q", attr)
@@ -267,28 +440,100 @@ type iface struct {
 }
 
 func (i *iface) matchAttr(attr, value string) (ok bool, err error) {
-	notImplemented := []string{
-		"addresses.", "association.", "tag", "requester-",
-		"attachment.", "source-dest-check", "mac-address",
-		"group-", "description", "private-", "owner-id",
-	}
 	switch attr {
 	case "availability-zone":
-		return i.AvailZone == value, nil
+		return matchValue(value, i.AvailZone), nil
+	case "description":
+		return matchValue(value, i.Description), nil
 	case "network-interface-id":
-		return i.Id == value, nil
+		return matchValue(value, i.Id), nil
+	case "owner-id":
+		return matchValue(value, i.OwnerId), nil
+	case "requester-id":
+		return matchValue(value, i.RequesterId), nil
+	case "requester-managed":
+		return matchBool(attr, value, i.RequesterManaged)
 	case "status":
-		return i.Status == value, nil
+		return matchValue(value, i.Status), nil
 	case "subnet-id":
-		return i.SubnetId == value, nil
+		return matchValue(value, i.SubnetId), nil
 	case "vpc-id":
-		return i.VPCId == value, nil
-	default:
-		for _, item := range notImplemented {
-			if strings.HasPrefix(attr, item) {
-				return false, fmt.Errorf("%q filter not implemented", attr)
+		return matchValue(value, i.VPCId), nil
+	case "mac-address":
+		return matchValue(value, i.MACAddress), nil
+	case "source-dest-check":
+		return matchBool(attr, value, i.SourceDestCheck)
+	case "private-ip-address":
+		return matchValue(value, i.PrivateIPAddress), nil
+	case "private-dns-name":
+		return matchValue(value, i.PrivateDNSName), nil
+	case "group-id", "group-name":
+		for _, g := range i.Groups {
+			if attr == "group-id" && matchValue(value, g.Id) ||
+				attr == "group-name" && matchValue(value, g.Name) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "attachment.attachment-id":
+		return matchValue(value, i.Attachment.Id), nil
+	case "attachment.instance-id":
+		return matchValue(value, i.Attachment.InstanceId), nil
+	case "attachment.instance-owner-id":
+		return matchValue(value, i.Attachment.InstanceOwnerId), nil
+	case "attachment.device-index":
+		if i.Attachment.Id == "" {
+			return false, nil
+		}
+		return matchValue(value, strconv.Itoa(i.Attachment.DeviceIndex)), nil
+	case "attachment.status":
+		return matchValue(value, i.Attachment.Status), nil
+	case "attachment.attach-time":
+		return matchValue(value, i.Attachment.AttachTime), nil
+	case "attachment.delete-on-termination":
+		if i.Attachment.Id == "" {
+			return false, nil
+		}
+		return matchBool(attr, value, i.Attachment.DeleteOnTermination)
+	case "addresses.private-ip-address", "addresses.primary",
+		"addresses.association.public-ip", "addresses.association.owner-id":
+		for _, ip := range i.PrivateIPs {
+			var ok bool
+			switch attr {
+			case "addresses.private-ip-address":
+				ok = matchValue(value, ip.Address)
+			case "addresses.primary":
+				if ok, err = matchBool(attr, value, ip.IsPrimary); err != nil {
+					return false, err
+				}
+			case "addresses.association.public-ip":
+				ok = matchValue(value, ip.Association.PublicIP)
+			case "addresses.association.owner-id":
+				ok = ip.Association.PublicIP != "" && matchValue(value, ip.Association.IPOwnerId)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "association.public-ip", "association.public-dns-name", "association.ip-owner-id",
+		"association.allocation-id", "association.association-id":
+		for _, ip := range i.PrivateIPs {
+			assoc := ip.Association
+			if assoc.PublicIP == "" {
+				continue
+			}
+			if attr == "association.public-ip" && matchValue(value, assoc.PublicIP) ||
+				attr == "association.public-dns-name" && matchValue(value, assoc.PublicDNSName) ||
+				attr == "association.ip-owner-id" && matchValue(value, assoc.IPOwnerId) ||
+				attr == "association.allocation-id" && matchValue(value, assoc.AllocationId) ||
+				attr == "association.association-id" && matchValue(value, assoc.AssociationId) {
+				return true, nil
 			}
 		}
+		return false, nil
+	case "ipv6-addresses.ipv6-address":
+		return matchAny(value, i.IPv6Addresses...), nil
 	}
 	return false, fmt.Errorf("unknown attribute %q", attr)
 }
@@ -346,29 +591,117 @@ type attachment struct {
 }
 
 var actions = map[string]func(*Server, http.ResponseWriter, *http.Request, string) interface{}{
-	"RunInstances":                  (*Server).runInstances,
-	"TerminateInstances":            (*Server).terminateInstances,
-	"DescribeInstances":             (*Server).describeInstances,
-	"CreateSecurityGroup":           (*Server).createSecurityGroup,
-	"DescribeAvailabilityZones":     (*Server).describeAvailabilityZones,
-	"DescribeSecurityGroups":        (*Server).describeSecurityGroups,
-	"DeleteSecurityGroup":           (*Server).deleteSecurityGroup,
-	"AuthorizeSecurityGroupIngress": (*Server).authorizeSecurityGroupIngress,
-	"RevokeSecurityGroupIngress":    (*Server).revokeSecurityGroupIngress,
-	"CreateVpc":                     (*Server).createVpc,
-	"DeleteVpc":                     (*Server).deleteVpc,
-	"DescribeVpcs":                  (*Server).describeVpcs,
-	"CreateSubnet":                  (*Server).createSubnet,
-	"DeleteSubnet":                  (*Server).deleteSubnet,
-	"DescribeSubnets":               (*Server).describeSubnets,
-	"CreateNetworkInterface":        (*Server).createIFace,
-	"DeleteNetworkInterface":        (*Server).deleteIFace,
-	"DescribeNetworkInterfaces":     (*Server).describeIFaces,
-	"AttachNetworkInterface":        (*Server).attachIFace,
-	"DetachNetworkInterface":        (*Server).detachIFace,
-	"DescribeAccountAttributes":     (*Server).accountAttributes,
-	"AssignPrivateIpAddresses":      (*Server).assignPrivateIP,
-	"UnassignPrivateIpAddresses":    (*Server).unassignPrivateIP,
+	"RunInstances":                   (*Server).runInstances,
+	"TerminateInstances":             (*Server).terminateInstances,
+	"StartInstances":                 (*Server).startInstances,
+	"StopInstances":                  (*Server).stopInstances,
+	"RebootInstances":                (*Server).rebootInstances,
+	"DescribeInstances":              (*Server).describeInstances,
+	"CreateSecurityGroup":            (*Server).createSecurityGroup,
+	"DescribeAvailabilityZones":      (*Server).describeAvailabilityZones,
+	"DescribeSecurityGroups":         (*Server).describeSecurityGroups,
+	"DeleteSecurityGroup":            (*Server).deleteSecurityGroup,
+	"AuthorizeSecurityGroupIngress":  (*Server).authorizeSecurityGroupIngress,
+	"RevokeSecurityGroupIngress":     (*Server).revokeSecurityGroupIngress,
+	"AuthorizeSecurityGroupEgress":   (*Server).authorizeSecurityGroupEgress,
+	"RevokeSecurityGroupEgress":      (*Server).revokeSecurityGroupEgress,
+	"CreateVpc":                      (*Server).createVpc,
+	"DeleteVpc":                      (*Server).deleteVpc,
+	"DescribeVpcs":                   (*Server).describeVpcs,
+	"AssociateVpcCidrBlock":          (*Server).associateVpcCidrBlock,
+	"DisassociateVpcCidrBlock":       (*Server).disassociateVpcCidrBlock,
+	"DescribeVpcAttribute":           (*Server).describeVpcAttribute,
+	"ModifyVpcAttribute":             (*Server).modifyVpcAttribute,
+	"CreateSubnet":                   (*Server).createSubnet,
+	"DeleteSubnet":                   (*Server).deleteSubnet,
+	"DescribeSubnets":                (*Server).describeSubnets,
+	"AssociateSubnetCidrBlock":       (*Server).associateSubnetCidrBlock,
+	"DisassociateSubnetCidrBlock":    (*Server).disassociateSubnetCidrBlock,
+	"ModifySubnetAttribute":          (*Server).modifySubnetAttribute,
+	"CreateNetworkInterface":         (*Server).createIFace,
+	"DeleteNetworkInterface":         (*Server).deleteIFace,
+	"DescribeNetworkInterfaces":      (*Server).describeIFaces,
+	"AttachNetworkInterface":         (*Server).attachIFace,
+	"DetachNetworkInterface":         (*Server).detachIFace,
+	"DescribeAccountAttributes":      (*Server).accountAttributes,
+	"AssignPrivateIpAddresses":       (*Server).assignPrivateIP,
+	"UnassignPrivateIpAddresses":     (*Server).unassignPrivateIP,
+	"AssignIpv6Addresses":            (*Server).assignIpv6Addresses,
+	"UnassignIpv6Addresses":          (*Server).unassignIpv6Addresses,
+	"CreateVolume":                   (*Server).createVolume,
+	"DeleteVolume":                   (*Server).deleteVolume,
+	"DescribeVolumes":                (*Server).describeVolumes,
+	"DescribeVolumeStatus":           (*Server).describeVolumeStatus,
+	"AttachVolume":                   (*Server).attachVolume,
+	"DetachVolume":                   (*Server).detachVolume,
+	"ModifyVolume":                   (*Server).modifyVolume,
+	"CreateSnapshot":                 (*Server).createSnapshot,
+	"DeleteSnapshot":                 (*Server).deleteSnapshot,
+	"DescribeSnapshots":              (*Server).describeSnapshots,
+	"CopySnapshot":                   (*Server).copySnapshot,
+	"DescribeSnapshotAttribute":      (*Server).describeSnapshotAttribute,
+	"ModifySnapshotAttribute":        (*Server).modifySnapshotAttribute,
+	"CreateKeyPair":                  (*Server).createKeyPair,
+	"ImportKeyPair":                  (*Server).importKeyPair,
+	"DescribeKeyPairs":               (*Server).describeKeyPairs,
+	"DeleteKeyPair":                  (*Server).deleteKeyPair,
+	"AllocateAddress":                (*Server).allocateAddress,
+	"DescribeAddresses":              (*Server).describeAddresses,
+	"AssociateAddress":               (*Server).associateAddress,
+	"DisassociateAddress":            (*Server).disassociateAddress,
+	"ReleaseAddress":                 (*Server).releaseAddress,
+	"CreateInternetGateway":          (*Server).createInternetGateway,
+	"DeleteInternetGateway":          (*Server).deleteInternetGateway,
+	"DescribeInternetGateways":       (*Server).describeInternetGateways,
+	"AttachInternetGateway":          (*Server).attachInternetGateway,
+	"DetachInternetGateway":          (*Server).detachInternetGateway,
+	"CreateRouteTable":               (*Server).createRouteTable,
+	"DeleteRouteTable":               (*Server).deleteRouteTable,
+	"DescribeRouteTables":            (*Server).describeRouteTables,
+	"AssociateRouteTable":            (*Server).associateRouteTable,
+	"DisassociateRouteTable":         (*Server).disassociateRouteTable,
+	"ReplaceRouteTableAssociation":   (*Server).replaceRouteTableAssociation,
+	"CreateRoute":                    (*Server).createRoute,
+	"ReplaceRoute":                   (*Server).replaceRoute,
+	"DeleteRoute":                    (*Server).deleteRoute,
+	"CreateNatGateway":               (*Server).createNatGateway,
+	"DeleteNatGateway":               (*Server).deleteNatGateway,
+	"DescribeNatGateways":            (*Server).describeNatGateways,
+	"CreateDhcpOptions":              (*Server).createDHCPOptions,
+	"DeleteDhcpOptions":              (*Server).deleteDHCPOptions,
+	"DescribeDhcpOptions":            (*Server).describeDHCPOptions,
+	"AssociateDhcpOptions":           (*Server).associateDHCPOptions,
+	"CreateVpcPeeringConnection":     (*Server).createVpcPeeringConnection,
+	"AcceptVpcPeeringConnection":     (*Server).acceptVpcPeeringConnection,
+	"RejectVpcPeeringConnection":     (*Server).rejectVpcPeeringConnection,
+	"DeleteVpcPeeringConnection":     (*Server).deleteVpcPeeringConnection,
+	"DescribeVpcPeeringConnections":  (*Server).describeVpcPeeringConnections,
+	"CreateTags":                     (*Server).createTags,
+	"DeleteTags":                     (*Server).deleteTags,
+	"DescribeTags":                   (*Server).describeTags,
+	"CreateImage":                    (*Server).createImage,
+	"RegisterImage":                  (*Server).registerImage,
+	"CopyImage":                      (*Server).copyImage,
+	"DeregisterImage":                (*Server).deregisterImage,
+	"DescribeImages":                 (*Server).describeImages,
+	"DescribeImageAttribute":         (*Server).describeImageAttribute,
+	"ModifyImageAttribute":           (*Server).modifyImageAttribute,
+	"DescribeInstanceAttribute":      (*Server).describeInstanceAttribute,
+	"GetConsoleOutput":               (*Server).getConsoleOutput,
+	"DescribeInstanceStatus":         (*Server).describeInstanceStatus,
+	"ModifyInstanceAttribute":        (*Server).modifyInstanceAttribute,
+	"RequestSpotInstances":           (*Server).requestSpotInstances,
+	"DescribeSpotInstanceRequests":   (*Server).describeSpotInstanceRequests,
+	"CancelSpotInstanceRequests":     (*Server).cancelSpotInstanceRequests,
+	"DescribeSpotPriceHistory":       (*Server).describeSpotPriceHistory,
+	"CreatePlacementGroup":           (*Server).createPlacementGroup,
+	"DescribePlacementGroups":        (*Server).describePlacementGroups,
+	"DeletePlacementGroup":           (*Server).deletePlacementGroup,
+	"CreateLaunchTemplate":           (*Server).createLaunchTemplate,
+	"CreateLaunchTemplateVersion":    (*Server).createLaunchTemplateVersion,
+	"DescribeLaunchTemplates":        (*Server).describeLaunchTemplates,
+	"DescribeLaunchTemplateVersions": (*Server).describeLaunchTemplateVersions,
+	"DeleteLaunchTemplate":           (*Server).deleteLaunchTemplate,
 }
 
 const (
@@ -397,8 +730,25 @@ func NewServer() (*Server, error) {
 		subnets:              make(map[string]*subnet),
 		ifaces:               make(map[string]*iface),
 		attachments:          make(map[string]*attachment),
+		volumes:              make(map[string]*volume),
+		snapshots:            make(map[string]*snapshot),
+		keyPairs:             make(map[string]*keyPair),
+		addresses:            make(map[string]*address),
+		igws:                 make(map[string]*internetGateway),
+		routeTables:          make(map[string]*routeTable),
+		natGateways:          make(map[string]*natGateway),
+		dhcpOptions:          make(map[string]*dhcpOptions),
+		peerings:             make(map[string]*vpcPeering),
+		images:               make(map[string]*image),
+		deregisteredImages:   make(map[string]bool),
+		spotRequests:         make(map[string]*spotRequest),
+		placementGroups:      make(map[string]*placementGroup),
+		launchTemplates:      make(map[string]*launchTemplate),
+		spotPrices:           make(map[string]string),
+		spotCapacity:         true,
 		reservations:         make(map[string]*reservation),
 		initialInstanceState: Pending,
+		faults:               faults.NewInjector(),
 	}
 
 	// Add default security group.
@@ -407,26 +757,27 @@ func NewServer() (*Server, error) {
 		description: "default group",
 		id:          fmt.Sprintf("sg-%d", srv.groupId.next()),
 	}
-	g.perms = map[permKey]bool{
+	g.perms = permSet{
 		permKey{
 			protocol: "icmp",
 			fromPort: -1,
 			toPort:   -1,
 			group:    g,
-		}: true,
+		}: "",
 		permKey{
 			protocol: "tcp",
 			fromPort: 0,
 			toPort:   65535,
 			group:    g,
-		}: true,
+		}: "",
 		permKey{
 			protocol: "udp",
 			fromPort: 0,
 			toPort:   65535,
 			group:    g,
-		}: true,
+		}: "",
 	}
+	g.egressPerms = make(permSet)
 	srv.groups[g.id] = g
 
 	// Add a default availability zone.
@@ -436,6 +787,9 @@ func NewServer() (*Server, error) {
 	z.State = "available"
 	srv.zones = []availabilityZone{z}
 
+	// Add the default DHCP options used by new VPCs.
+	srv.defaultDHCPOptsId = srv.newDHCPOptions(defaultDHCPConfigurations).Id
+
 	l, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
 		return nil, fmt.Errorf("cannot listen on localhost: %v", err)
@@ -464,6 +818,16 @@ func (srv *Server) SetInitialInstanceState(state ec2.InstanceState) {
 	srv.mu.Unlock()
 }
 
+// SetStrictImages sets whether instances may only be launched from
+// images known to srv, failing with InvalidAMIID.NotFound otherwise.
+// By default, any image id is accepted unless srv issued it and the
+// image has since been deregistered.
+func (srv *Server) SetStrictImages(strict bool) {
+	srv.mu.Lock()
+	srv.strictImages = strict
+	srv.mu.Unlock()
+}
+
 func (srv *Server) SetAvailabilityZones(zones []ec2.AvailabilityZoneInfo) {
 	srv.mu.Lock()
 	srv.zones = make([]availabilityZone, len(zones))
@@ -481,14 +845,26 @@ func (srv *Server) SetInitialAttributes(attrs map[string][]string) {
 			// The default-vpc attribute was provided, so create the
 			// respective VPCs and their subnets.
 			for _, vpcId := range values {
-				srv.vpcs[vpcId] = &vpc{ec2.VPC{
-					Id:              vpcId,
-					State:           "available",
-					CIDRBlock:       "10.0.0.0/16",
-					DHCPOptionsId:   fmt.Sprintf("dopt-%d", srv.dhcpOptsId.next()),
-					InstanceTenancy: "default",
-					IsDefault:       true,
-				}}
+				v := &vpc{
+					VPC: ec2.VPC{
+						Id:              vpcId,
+						State:           "available",
+						CIDRBlock:       "10.0.0.0/16",
+						DHCPOptionsId:   srv.defaultDHCPOptsId,
+						InstanceTenancy: "default",
+						IsDefault:       true,
+						CIDRBlocks: []ec2.CIDRBlockAssociation{{
+							AssociationId: fmt.Sprintf("vpc-cidr-assoc-%d", srv.cidrAssocId.next()),
+							CIDRBlock:     "10.0.0.0/16",
+							State:         "associated",
+						}},
+					},
+					// Default VPCs have DNS hostnames enabled.
+					dnsSupport:   true,
+					dnsHostnames: true,
+				}
+				srv.vpcs[vpcId] = v
+				srv.newRouteTable(v, true)
 				subnetId := fmt.Sprintf("subnet-%d", srv.subnetId.next())
 				cidrBlock := "10.10.0.0/20"
 				availIPs, _ := srv.calcSubnetAvailIPs(cidrBlock)
@@ -500,6 +876,9 @@ func (srv *Server) SetInitialAttributes(attrs map[string][]string) {
 					AvailZone:        "us-east-1b",
 					AvailableIPCount: availIPs,
 					DefaultForAZ:     true,
+					// Instances launched in default subnets
+					// get a public IP address.
+					MapPublicIPOnLaunch: true,
 				}}
 			}
 		}
@@ -511,10 +890,39 @@ func (srv *Server) URL() string {
 	return srv.url
 }
 
+// SetConsistency makes the server emulate eventual consistency as
+// decided by c: instances and security groups are not described
+// until their creation settles, and deleted security groups are
+// described until their deletion does. If c is nil, the server is
+// strongly consistent.
+func (srv *Server) SetConsistency(c *faults.Consistency) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.consistency = c
+}
+
+// Faults returns the injector deciding the faults injected into
+// requests, which are chosen by EC2 action name.
+func (srv *Server) Faults() *faults.Injector {
+	return srv.faults
+}
+
 // serveHTTP serves the EC2 protocol.
 func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 	req.ParseForm()
 
+	fault := srv.faults.Next(req.Form.Get("Action"))
+	time.Sleep(fault.Delay)
+	if fault.Drop {
+		faults.Drop(w)
+		return
+	}
+	if fault.Truncate {
+		tw := faults.NewTruncatingWriter(w)
+		defer tw.Close()
+		w = tw
+	}
+
 	a := srv.newAction()
 	a.RequestId = fmt.Sprintf("req%d", srv.reqId.next())
 	a.Request = req.Form
@@ -536,10 +944,15 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
+	if fault.IsError() {
+		fatalf(fault.StatusCode, fault.Code, "%s", fault.Message)
+	}
+
 	f := actions[req.Form.Get("Action")]
 	if f == nil {
 		fatalf(400, "InvalidParameterValue", "Unrecognized Action")
 	}
+	srv.completeTransitions()
 
 	response := f(srv, w, req, a.RequestId)
 	a.Response = response
@@ -629,10 +1042,10 @@ func (srv *Server) formToGroups(form url.Values) []*securityGroup {
 // limitToOneInstance flag. The flag is set only when an existing
 // network interface id is specified, which according to the API
 // limits the number of instances to 1.
-func (srv *Server) parseRunNetworkInterfaces(req *http.Request) ([]ec2.RunNetworkInterface, bool) {
+func (srv *Server) parseRunNetworkInterfaces(form url.Values) ([]ec2.RunNetworkInterface, bool) {
 	ifaces := []ec2.RunNetworkInterface{}
 	limitToOneInstance := false
-	for attr, vals := range req.Form {
+	for attr, vals := range form {
 		if !strings.HasPrefix(attr, "NetworkInterface.") {
 			// Only process network interface params.
 			continue
@@ -684,6 +1097,15 @@ func (srv *Server) parseRunNetworkInterfaces(req *http.Request) ([]ec2.RunNetwor
 			limitToOneInstance = true
 		case "SecondaryPrivateIpAddressCount":
 			iface.SecondaryPrivateIPCount = atoi(vals[0])
+		case "Ipv6Addresses":
+			// ...Ipv6Addresses.<#>.Ipv6Address: <address>
+			format := "NetworkInterface." + fields[1] + ".Ipv6Addresses.%d.Ipv6Address"
+			iface.IPv6Addresses = parseIPv6Addresses(form, format)
+			// Like private IP addresses, explicit IPv6 addresses
+			// can be given to one instance only.
+			limitToOneInstance = true
+		case "Ipv6AddressCount":
+			iface.IPv6AddressCount = atoi(vals[0])
 		case "PrivateIpAddresses":
 			// ...PrivateIpAddress.<ipIndex>.<subFieldName>: vals[0]
 			if len(fields) < 4 {
@@ -817,11 +1239,12 @@ func (srv *Server) createNICsOnRun(instId string, instSubnet *subnet, ifacesToCr
 			InstanceId:          instId,
 			InstanceOwnerId:     ownerId,
 			DeviceIndex:         ifaceToCreate.DeviceIndex,
-			Status:              "in-use",
+			Status:              "attached",
 			AttachTime:          time.Now().Format(time.RFC3339),
 			DeleteOnTermination: true,
 		}
 		srv.attachments[attach.Id] = &attachment{attach}
+		ipv6Addrs := srv.allocateIPv6Addresses(instSubnet, ifaceToCreate.IPv6Addresses, ifaceToCreate.IPv6AddressCount)
 		nic := ec2.NetworkInterface{
 			Id:               nicId,
 			SubnetId:         instSubnet.Id,
@@ -837,6 +1260,7 @@ func (srv *Server) createNICsOnRun(instId string, instSubnet *subnet, ifacesToCr
 			Groups:           groups,
 			PrivateIPs:       ifaceToCreate.PrivateIPs,
 			Attachment:       attach,
+			IPv6Addresses:    ipv6Addrs,
 		}
 		srv.ifaces[nicId] = &iface{nic}
 		createdNICs = append(createdNICs, nic)
@@ -854,51 +1278,94 @@ func (srv *Server) runInstances(w http.ResponseWriter, req *http.Request, reqId
 	if min > max {
 		fatalf(400, "InvalidParameterCombination", "MinCount is greater than MaxCount")
 	}
-	var userData []byte
-	if data := req.Form.Get("UserData"); data != "" {
-		var err error
-		userData, err = b64.DecodeString(data)
-		if err != nil {
-			fatalf(400, "InvalidParameterValue", "bad UserData value: %v", err)
-		}
-	}
 
 	// TODO attributes still to consider:
-	//    ImageId:                  accept anything, we can verify later
-	//    KeyName                   ?
 	//    InstanceType              ?
 	//    KernelId                  ?
 	//    RamdiskId                 ?
 	//    AvailZone                 ?
 	//    GroupName                 tag
 	//    Monitoring                ignore?
-	//    DisableAPITermination     bool
-	//    ShutdownBehavior          string
 	//    PrivateIPAddress          string
 
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
+	// A repeated request returns the reservation it made the first
+	// time, as EC2 does.
+	token := req.Form.Get("ClientToken")
+	if token != "" {
+		for _, r := range srv.reservations {
+			if r.clientToken == token {
+				resp := r.runInstancesResp()
+				resp.RequestId = reqId
+				return resp
+			}
+		}
+	}
+	resp := srv.launchInstances(req.Form, max)
+	srv.reservations[resp.ReservationId].clientToken = token
+	resp.RequestId = reqId
+	return resp
+}
+
+// runInstancesResp returns the response to the RunInstances request
+// that made the reservation r.
+func (r *reservation) runInstancesResp() *ec2.RunInstancesResp {
+	resp := &ec2.RunInstancesResp{
+		ReservationId: r.id,
+		OwnerId:       ownerId,
+	}
+	var insts []*Instance
+	for _, inst := range r.instances {
+		insts = append(insts, inst)
+	}
+	sort.Slice(insts, func(i, j int) bool { return insts[i].seq < insts[j].seq })
+	for _, inst := range insts {
+		resp.Instances = append(resp.Instances, inst.ec2instance())
+	}
+	return resp
+}
+
+// launchInstances launches max instances in a new reservation, as
+// described by the RunInstances parameters in form and the launch
+// template they select, if any, and returns the reservation. Spot
+// requests launch their instances with it too.
+// It must be called with srv.mu held.
+func (srv *Server) launchInstances(form url.Values, max int) *ec2.RunInstancesResp {
+	form, tmplVersion := srv.applyLaunchTemplate(form)
+	var userData []byte
+	if data := form.Get("UserData"); data != "" {
+		var err error
+		userData, err = b64.DecodeString(data)
+		if err != nil {
+			fatalf(400, "InvalidParameterValue", "bad UserData value: %v", err)
+		}
+	}
+	disableAPITermination := parseBoolParam(form, "DisableApiTermination")
+	shutdownBehavior := parseShutdownBehavior(form, "InstanceInitiatedShutdownBehavior")
 
 	// make sure that form fields are correct before creating the reservation.
-	instType := req.Form.Get("InstanceType")
-	imageId := req.Form.Get("ImageId")
-	availZone := req.Form.Get("Placement.AvailabilityZone")
+	instType := form.Get("InstanceType")
+	imageId := form.Get("ImageId")
+	availZone := form.Get("Placement.AvailabilityZone")
+	keyName := form.Get("KeyName")
+	srv.checkKeyPair(keyName)
+	img := srv.launchImage(imageId)
+	place := srv.parsePlacement(form)
 
-	r := srv.newReservation(srv.formToGroups(req.Form))
+	r := srv.newReservation(srv.formToGroups(form))
 
 	// If the user specifies an explicit subnet id, use it.
-	// Otherwise, get a subnet from the default VPC.
-	userSubnetId := req.Form.Get("SubnetId")
+	// Otherwise, use the subnet of the new network interfaces,
+	// if any, or get a subnet from the default VPC.
+	userSubnetId := form.Get("SubnetId")
 	instSubnet := srv.subnets[userSubnetId]
 	if instSubnet == nil && userSubnetId != "" {
 		fatalf(400, "InvalidSubnetID.NotFound", "subnet %s not found", userSubnetId)
 	}
-	if userSubnetId == "" {
-		instSubnet = srv.getDefaultSubnet()
-	}
 
 	// Handle network interfaces parsing.
-	ifacesToCreate, limitToOneInstance := srv.parseRunNetworkInterfaces(req)
+	ifacesToCreate, limitToOneInstance := srv.parseRunNetworkInterfaces(form)
 	if len(ifacesToCreate) > 0 && userSubnetId != "" {
 		// Since we have an instance-level subnet id
 		// specified, we cannot add network interfaces
@@ -908,6 +1375,32 @@ func (srv *Server) runInstances(w http.ResponseWriter, req *http.Request, reqId
 	if limitToOneInstance {
 		max = 1
 	}
+	if userSubnetId == "" {
+		for _, ni := range ifacesToCreate {
+			if ni.Id == "" && ni.SubnetId != "" {
+				instSubnet = srv.subnets[ni.SubnetId]
+				break
+			}
+		}
+	}
+	if instSubnet == nil && userSubnetId == "" {
+		instSubnet = srv.getDefaultSubnet()
+	}
+	if instSubnet != nil && place.tenancy == "default" {
+		// Instances launched in a dedicated VPC run on dedicated
+		// hardware.
+		if v := srv.vpcs[instSubnet.VPCId]; v != nil && v.InstanceTenancy == "dedicated" {
+			place.tenancy = "dedicated"
+		}
+	}
+	for i, ni := range ifacesToCreate {
+		if ni.Id == "" && instSubnet != nil {
+			ifacesToCreate[i].IPv6AddressCount = instSubnet.ipv6AddressCount(ni.IPv6Addresses, ni.IPv6AddressCount)
+			srv.checkIPv6Addresses(instSubnet, ni.IPv6Addresses, ifacesToCreate[i].IPv6AddressCount)
+		}
+	}
+	blockDevices := mergeBlockDevices(img.BlockDevices, parseBlockDeviceMappings(form))
+	tagSpecs := parseTagSpecs(form, "instance", "volume", "network-interface")
 	if len(ifacesToCreate) == 0 {
 		// No NICs specified, so create a default one to simulate what
 		// EC2 does.
@@ -915,7 +1408,6 @@ func (srv *Server) runInstances(w http.ResponseWriter, req *http.Request, reqId
 	}
 
 	var resp ec2.RunInstancesResp
-	resp.RequestId = reqId
 	resp.ReservationId = r.id
 	resp.OwnerId = ownerId
 
@@ -927,8 +1419,26 @@ func (srv *Server) runInstances(w http.ResponseWriter, req *http.Request, reqId
 		if instSubnet != nil {
 			inst.subnetId = instSubnet.Id
 			inst.vpcId = instSubnet.VPCId
+			inst.noPublicIP = !instSubnet.MapPublicIPOnLaunch
 		}
 		inst.UserData = userData
+		inst.keyName = keyName
+		inst.disableAPITermination = disableAPITermination
+		inst.shutdownBehavior = shutdownBehavior
+		place.place(inst)
+		srv.createBlockDevices(inst, blockDevices)
+		inst.tags = tagSpecs["instance"]
+		if tmplVersion != nil {
+			inst.tags = setTags(inst.tags, tmplVersion.instanceTags())
+		}
+		for _, v := range inst.volumes {
+			v.Tags = tagSpecs["volume"]
+		}
+		for j := range inst.ifaces {
+			inst.ifaces[j].Tags = tagSpecs["network-interface"]
+			srv.ifaces[inst.ifaces[j].Id].Tags = tagSpecs["network-interface"]
+		}
+		srv.consistency.Created(inst.id())
 		resp.Instances = append(resp.Instances, inst.ec2instance())
 	}
 	return &resp
@@ -946,6 +1456,35 @@ func (srv *Server) group(group ec2.SecurityGroup) *securityGroup {
 	return nil
 }
 
+// visibleGroup is like group, but it returns the group as seen by
+// readers when eventual consistency is emulated.
+func (srv *Server) visibleGroup(group ec2.SecurityGroup) *securityGroup {
+	if g := srv.group(group); g != nil {
+		if srv.consistency.Hidden(g.id) {
+			return nil
+		}
+		return g
+	}
+	for _, g := range srv.lingeringGroups() {
+		if group.Id != "" && g.id == group.Id || group.Id == "" && g.name == group.Name {
+			return g
+		}
+	}
+	return nil
+}
+
+// lingeringGroups returns the deleted security groups that
+// readers can still see.
+func (srv *Server) lingeringGroups() []*securityGroup {
+	var groups []*securityGroup
+	for _, id := range srv.consistency.Deletions("sg-") {
+		if v, ok := srv.consistency.Lingering(id); ok {
+			groups = append(groups, v.(*securityGroup))
+		}
+	}
+	return groups
+}
+
 // NewInstancesVPC creates n new VPC instances in srv with the given
 // instance type, image ID, initial state, and security groups,
 // belonging to the given vpcId and subnetId. If any group does not
@@ -994,6 +1533,14 @@ func (srv *Server) newInstance(r *reservation, instType string, imageId string,
 		availZone:   availZone,
 		state:       state,
 		reservation: r,
+		launchTime:  srv.now(),
+
+		sourceDestCheck:  true,
+		shutdownBehavior: "stop",
+		tenancy:          "default",
+	}
+	if srv.clock != nil && state == Pending {
+		srv.transition(inst, Pending, Running)
 	}
 	id := inst.id()
 	srv.instances[id] = inst
@@ -1022,15 +1569,32 @@ func (srv *Server) terminateInstances(w http.ResponseWriter, req *http.Request,
 		if strings.HasPrefix(attr, "InstanceId.") {
 			id := vals[0]
 			inst := srv.instances[id]
-			if inst == nil {
+			if inst == nil || srv.consistency.Hidden(id) {
 				fatalf(400, "InvalidInstanceID.NotFound", "no such instance id %q", id)
 			}
 			insts = append(insts, inst)
 		}
 	}
 	for _, inst := range insts {
-		resp.StateChanges = append(resp.StateChanges, inst.terminate())
+		if inst.disableAPITermination {
+			fatalf(400, "OperationNotPermitted", "The instance '%s' may not be terminated. Modify its 'disableApiTermination' instance attribute and try again.", inst.id())
+		}
+	}
+	for _, inst := range insts {
+		if inst.state == Terminated {
+			resp.StateChanges = append(resp.StateChanges, ec2.InstanceStateChange{
+				InstanceId:    inst.id(),
+				PreviousState: Terminated,
+				CurrentState:  Terminated,
+			})
+			continue
+		}
+		resp.StateChanges = append(resp.StateChanges, srv.transition(inst, ShuttingDown, Terminated))
+		srv.releaseVolumes(inst)
+		srv.clearInstanceAddresses(inst)
+		srv.spotInstanceTerminated(inst, "instance-terminated-by-user")
 	}
+	srv.fulfilSpotRequests()
 	return &resp
 }
 
@@ -1038,14 +1602,6 @@ func (inst *Instance) id() string {
 	return fmt.Sprintf("i-%d", inst.seq)
 }
 
-func (inst *Instance) terminate() (d ec2.InstanceStateChange) {
-	d.PreviousState = inst.state
-	inst.state = ShuttingDown
-	d.CurrentState = inst.state
-	d.InstanceId = inst.id()
-	return d
-}
-
 func (inst *Instance) ec2instance() ec2.Instance {
 	id := inst.id()
 	// The first time the instance is returned, its DNSName
@@ -1057,50 +1613,126 @@ func (inst *Instance) ec2instance() ec2.Instance {
 		inst.dnsNameSet = true
 	}
 	return ec2.Instance{
-		InstanceId:        id,
-		InstanceType:      inst.instType,
-		ImageId:           inst.imageId,
-		DNSName:           dnsName,
-		PrivateDNSName:    fmt.Sprintf("%s.internal.invalid", id),
-		IPAddress:         fmt.Sprintf("8.0.0.%d", inst.seq%256),
-		PrivateIPAddress:  fmt.Sprintf("127.0.0.%d", inst.seq%256),
-		State:             inst.state,
-		AvailZone:         inst.availZone,
-		VPCId:             inst.vpcId,
-		SubnetId:          inst.subnetId,
-		NetworkInterfaces: inst.ifaces,
+		InstanceId:            id,
+		InstanceType:          inst.instType,
+		ImageId:               inst.imageId,
+		KeyName:               inst.keyName,
+		DNSName:               dnsName,
+		PrivateDNSName:        inst.privateDNSName(),
+		IPAddress:             inst.ipAddress(),
+		PrivateIPAddress:      inst.privateIPAddress(),
+		LaunchTime:            inst.launchTime.UTC().Format(time.RFC3339),
+		SourceDestCheck:       inst.sourceDestCheck,
+		State:                 inst.state,
+		AvailZone:             inst.availZone,
+		PlacementGroupName:    inst.placementGroup,
+		PartitionNumber:       inst.partitionNumber,
+		Tenancy:               inst.tenancy,
+		HostId:                inst.hostId,
+		Affinity:              inst.affinity,
+		VPCId:                 inst.vpcId,
+		SubnetId:              inst.subnetId,
+		NetworkInterfaces:     inst.ifaces,
+		BlockDeviceMappings:   inst.blockDeviceMappings(),
+		Tags:                  inst.tags,
+		InstanceLifecycle:     inst.lifecycle(),
+		SpotInstanceRequestId: inst.spotRequestId,
 		// TODO the rest
 	}
 }
 
+// lifecycle returns "spot" for spot instances, and the empty string
+// for on-demand instances.
+func (inst *Instance) lifecycle() string {
+	if inst.spotRequestId != "" {
+		return "spot"
+	}
+	return ""
+}
+
+// privateIPAddress returns the private IP address of the instance.
+func (inst *Instance) privateIPAddress() string {
+	return fmt.Sprintf("127.0.0.%d", inst.seq%256)
+}
+
+// privateDNSName returns the private DNS name of the instance.
+func (inst *Instance) privateDNSName() string {
+	return fmt.Sprintf("%s.internal.invalid", inst.id())
+}
+
+// ipAddress returns the public IP address of the instance: the
+// Elastic IP address associated with it, if any, or else the one it
+// got on launch, if any.
+func (inst *Instance) ipAddress() string {
+	if inst.publicIP != "" {
+		return inst.publicIP
+	}
+	if inst.noPublicIP {
+		return ""
+	}
+	return fmt.Sprintf("8.0.0.%d", inst.seq%256)
+}
+
 func (inst *Instance) matchAttr(attr, value string) (ok bool, err error) {
+	if strings.HasPrefix(attr, "network-interface.") {
+		attr = strings.TrimPrefix(attr, "network-interface.")
+		for _, nic := range inst.ifaces {
+			if ok, err := (&iface{nic}).matchAttr(attr, value); ok || err != nil {
+				return ok, err
+			}
+		}
+		if len(inst.ifaces) == 0 {
+			// Check the attribute is known anyway.
+			_, err := (&iface{}).matchAttr(attr, value)
+			return false, err
+		}
+		return false, nil
+	}
 	switch attr {
 	case "architecture":
-		return value == "i386", nil
+		return matchValue(value, "i386"), nil
 	case "availability-zone":
-		return value == inst.availZone, nil
+		return matchValue(value, inst.availZone), nil
 	case "instance-id":
-		return inst.id() == value, nil
+		return matchValue(value, inst.id()), nil
+	case "instance-type":
+		return matchValue(value, inst.instType), nil
+	case "reservation-id":
+		return matchValue(value, inst.reservation.id), nil
+	case "owner-id":
+		return matchValue(value, ownerId), nil
 	case "subnet-id":
-		return inst.subnetId == value, nil
+		return matchValue(value, inst.subnetId), nil
 	case "vpc-id":
-		return inst.vpcId == value, nil
+		return matchValue(value, inst.vpcId), nil
 	case "instance.group-id", "group-id":
 		for _, g := range inst.reservation.groups {
-			if g.id == value {
+			if matchValue(value, g.id) {
 				return true, nil
 			}
 		}
 		return false, nil
 	case "instance.group-name", "group-name":
 		for _, g := range inst.reservation.groups {
-			if g.name == value {
+			if matchValue(value, g.name) {
 				return true, nil
 			}
 		}
 		return false, nil
 	case "image-id":
-		return value == inst.imageId, nil
+		return matchValue(value, inst.imageId), nil
+	case "key-name":
+		return matchValue(value, inst.keyName), nil
+	case "ip-address":
+		return matchValue(value, inst.ipAddress()), nil
+	case "private-ip-address":
+		return matchValue(value, inst.privateIPAddress()), nil
+	case "private-dns-name":
+		return matchValue(value, inst.privateDNSName()), nil
+	case "launch-time":
+		return matchValue(value, inst.launchTime.UTC().Format(time.RFC3339)), nil
+	case "source-dest-check":
+		return matchBool(attr, value, inst.sourceDestCheck)
 	case "instance-state-code":
 		code, err := strconv.Atoi(value)
 		if err != nil {
@@ -1108,7 +1740,21 @@ func (inst *Instance) matchAttr(attr, value string) (ok bool, err error) {
 		}
 		return code&0xff == inst.state.Code, nil
 	case "instance-state-name":
-		return value == inst.state.Name, nil
+		return matchValue(value, inst.state.Name), nil
+	case "instance-lifecycle":
+		return matchValue(value, inst.lifecycle()), nil
+	case "spot-instance-request-id":
+		return matchValue(value, inst.spotRequestId), nil
+	case "placement-group-name":
+		return matchValue(value, inst.placementGroup), nil
+	case "placement-partition-number":
+		return matchValue(value, strconv.Itoa(inst.partitionNumber)), nil
+	case "tenancy":
+		return matchValue(value, inst.tenancy), nil
+	case "host-id":
+		return matchValue(value, inst.hostId), nil
+	case "affinity":
+		return matchValue(value, inst.affinity), nil
 	}
 	return false, fmt.Errorf("unknown attribute %q", attr)
 }
@@ -1117,8 +1763,9 @@ var (
 	Pending      = ec2.InstanceState{0, "pending"}
 	Running      = ec2.InstanceState{16, "running"}
 	ShuttingDown = ec2.InstanceState{32, "shutting-down"}
-	Terminated   = ec2.InstanceState{16, "terminated"}
-	Stopped      = ec2.InstanceState{16, "stopped"}
+	Terminated   = ec2.InstanceState{48, "terminated"}
+	Stopping     = ec2.InstanceState{64, "stopping"}
+	Stopped      = ec2.InstanceState{80, "stopped"}
 )
 
 func (srv *Server) createSecurityGroup(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
@@ -1135,13 +1782,17 @@ func (srv *Server) createSecurityGroup(w http.ResponseWriter, req *http.Request,
 		name:        name,
 		description: req.Form.Get("GroupDescription"),
 		id:          fmt.Sprintf("sg-%d", srv.groupId.next()),
-		perms:       make(map[permKey]bool),
+		perms:       make(permSet),
+		egressPerms: make(permSet),
 	}
 	vpcId := req.Form.Get("VpcId")
 	if vpcId != "" {
 		g.vpcId = vpcId
+		// VPC security groups allow all outbound traffic by default.
+		g.egressPerms[permKey{protocol: "-1", ipAddr: "0.0.0.0/0"}] = ""
 	}
 	srv.groups[g.id] = g
+	srv.consistency.Created(g.id)
 	// we define a local type for this because ec2.CreateSecurityGroupResp
 	// contains SecurityGroup, but the response to this request
 	// should not contain the security group name.
@@ -1172,51 +1823,64 @@ func (srv *Server) describeInstances(w http.ResponseWriter, req *http.Request, r
 			continue
 		}
 		inst := srv.instances[vals[0]]
-		if inst == nil {
+		if inst == nil || srv.consistency.Hidden(vals[0]) {
 			fatalf(400, "InvalidInstanceID.NotFound", "instance %q not found", vals[0])
 		}
 		insts[inst] = true
 	}
 
+	if len(insts) > 0 && req.Form.Get("MaxResults") != "" {
+		fatalf(400, "InvalidParameterCombination", "The parameter instancesSet cannot be used with the parameter maxResults")
+	}
+
 	f := newFilter(req.Form)
+	var matched []*Instance
+	for _, inst := range srv.instances {
+		if len(insts) > 0 && !insts[inst] {
+			continue
+		}
+		if len(insts) == 0 && srv.consistency.Hidden(inst.id()) {
+			continue
+		}
+		// Complete any transition first, so we can simulate,
+		// for example: shutdown, subsequent refresh of the
+		// state with Instances(), terminated.
+		srv.completeTransition(inst)
+
+		ok, err := f.ok(inst)
+		if ok {
+			matched = append(matched, inst)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe instances: %v", err)
+		}
+	}
+	// Instances are paginated, and then grouped by reservation.
+	sort.Sort(instancesById(matched))
+	start, end, nextToken := page(req.Form, len(matched))
 
 	var resp ec2.InstancesResp
 	resp.RequestId = reqId
-	for _, r := range srv.reservations {
-		var instances []ec2.Instance
+	resp.NextToken = nextToken
+	reservations := make(map[*reservation]int)
+	for _, inst := range matched[start:end] {
+		r := inst.reservation
 		var groups []ec2.SecurityGroup
 		for _, g := range r.groups {
 			groups = append(groups, g.ec2SecurityGroup())
 		}
-		for _, inst := range r.instances {
-			if len(insts) > 0 && !insts[inst] {
-				continue
-			}
-			// make instances in state "shutting-down" to transition
-			// to "terminated" first, so we can simulate: shutdown,
-			// subsequent refresh of the state with Instances(),
-			// terminated.
-			if inst.state == ShuttingDown {
-				inst.state = Terminated
-			}
-
-			ok, err := f.ok(inst)
-			if ok {
-				instance := inst.ec2instance()
-				instance.SecurityGroups = groups
-				instances = append(instances, instance)
-			} else if err != nil {
-				fatalf(400, "InvalidParameterValue", "describe instances: %v", err)
-			}
-		}
-		if len(instances) > 0 {
+		i, ok := reservations[r]
+		if !ok {
+			i = len(resp.Reservations)
+			reservations[r] = i
 			resp.Reservations = append(resp.Reservations, ec2.Reservation{
 				ReservationId:  r.id,
 				OwnerId:        ownerId,
-				Instances:      instances,
 				SecurityGroups: groups,
 			})
 		}
+		instance := inst.ec2instance()
+		instance.SecurityGroups = groups
+		resp.Reservations[i].Instances = append(resp.Reservations[i].Instances, instance)
 	}
 	return &resp
 }
@@ -1237,7 +1901,7 @@ func (srv *Server) describeSecurityGroups(w http.ResponseWriter, req *http.Reque
 		default:
 			continue
 		}
-		sg := srv.group(g)
+		sg := srv.visibleGroup(g)
 		if sg == nil {
 			fatalf(400, "InvalidGroup.NotFound", "no such group %v", g)
 		}
@@ -1245,8 +1909,11 @@ func (srv *Server) describeSecurityGroups(w http.ResponseWriter, req *http.Reque
 	}
 	if len(groups) == 0 {
 		for _, g := range srv.groups {
-			groups = append(groups, g)
+			if !srv.consistency.Hidden(g.id) {
+				groups = append(groups, g)
+			}
 		}
+		groups = append(groups, srv.lingeringGroups()...)
 	}
 
 	f := newFilter(req.Form)
@@ -1258,13 +1925,19 @@ func (srv *Server) describeSecurityGroups(w http.ResponseWriter, req *http.Reque
 			resp.Groups = append(resp.Groups, ec2.SecurityGroupInfo{
 				OwnerId:       ownerId,
 				SecurityGroup: group.ec2SecurityGroup(),
+				VPCId:         group.vpcId,
 				Description:   group.description,
-				IPPerms:       group.ec2Perms(),
+				IPPerms:       group.perms.ec2Perms(),
+				IPPermsEgress: group.egressPerms.ec2Perms(),
+				Tags:          group.tags,
 			})
 		} else if err != nil {
 			fatalf(400, "InvalidParameterValue", "describe security groups: %v", err)
 		}
 	}
+	sort.Slice(resp.Groups, func(i, j int) bool { return resp.Groups[i].Id < resp.Groups[j].Id })
+	start, end, nextToken := page(req.Form, len(resp.Groups))
+	resp.Groups, resp.NextToken = resp.Groups[start:end], nextToken
 	return &resp
 }
 
@@ -1279,15 +1952,7 @@ func (srv *Server) authorizeSecurityGroupIngress(w http.ResponseWriter, req *htt
 		fatalf(400, "InvalidGroup.NotFound", "group not found")
 	}
 	perms := srv.parsePerms(req)
-
-	for _, p := range perms {
-		if g.perms[p] {
-			fatalf(400, "InvalidPermission.Duplicate", "Permission has already been authorized on the specified group")
-		}
-	}
-	for _, p := range perms {
-		g.perms[p] = true
-	}
+	g.perms.authorize(perms)
 	return &ec2.SimpleResp{
 		XMLName:   xml.Name{"", "AuthorizeSecurityGroupIngressResponse"},
 		RequestId: reqId,
@@ -1308,7 +1973,7 @@ func (srv *Server) revokeSecurityGroupIngress(w http.ResponseWriter, req *http.R
 
 	// Note EC2 does not give an error if asked to revoke an authorization
 	// that does not exist.
-	for _, p := range perms {
+	for p := range perms {
 		delete(g.perms, p)
 	}
 	return &ec2.SimpleResp{
@@ -1317,15 +1982,82 @@ func (srv *Server) revokeSecurityGroupIngress(w http.ResponseWriter, req *http.R
 	}
 }
 
+// egressGroup returns the VPC security group named by the GroupId
+// parameter of an egress request.
+// It must be called with srv.mu held.
+func (srv *Server) egressGroup(req *http.Request) *securityGroup {
+	id := req.Form.Get("GroupId")
+	if id == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter groupId")
+	}
+	g := srv.group(ec2.SecurityGroup{Id: id})
+	if g == nil {
+		fatalf(400, "InvalidGroup.NotFound", "group not found")
+	}
+	if g.vpcId == "" {
+		fatalf(400, "InvalidParameterValue", "outbound rules are only supported by VPC security groups")
+	}
+	return g
+}
+
+func (srv *Server) authorizeSecurityGroupEgress(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	g := srv.egressGroup(req)
+	perms := srv.parsePerms(req)
+	g.egressPerms.authorize(perms)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "AuthorizeSecurityGroupEgressResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) revokeSecurityGroupEgress(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	g := srv.egressGroup(req)
+	perms := srv.parsePerms(req)
+
+	// Unlike for inbound rules, EC2 reports outbound rules that
+	// do not exist.
+	for p := range perms {
+		if _, ok := g.egressPerms[p]; !ok {
+			fatalf(400, "InvalidPermission.NotFound", "The specified rule does not exist in this security group.")
+		}
+	}
+	for p := range perms {
+		delete(g.egressPerms, p)
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "RevokeSecurityGroupEgressResponse"},
+		RequestId: reqId,
+	}
+}
+
+// authorize adds the given permissions to perms, failing
+// if any of them is already present.
+func (perms permSet) authorize(add permSet) {
+	for p := range add {
+		if _, ok := perms[p]; ok {
+			fatalf(400, "InvalidPermission.Duplicate", "Permission has already been authorized on the specified group")
+		}
+	}
+	for p, desc := range add {
+		perms[p] = desc
+	}
+}
+
 var (
 	secGroupPat = regexp.MustCompile(`^sg-[a-z0-9]+$`)
 	cidrIpPat   = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+\.[0-9]+/([0-9]+)$`)
 	ownerIdPat  = regexp.MustCompile(`^[0-9]+$`)
+
+	prefixListPat = regexp.MustCompile(`^pl-[a-z0-9]+$`)
 )
 
-// parsePerms returns a slice of permKey values extracted
-// from the permission fields in req.
-func (srv *Server) parsePerms(req *http.Request) []permKey {
+// parsePerms returns the set of permissions extracted
+// from the permission fields in req, with their descriptions.
+func (srv *Server) parsePerms(req *http.Request) permSet {
 	// perms maps an index found in the form to its associated
 	// IPPerm. For instance, the form value with key
 	// "IpPermissions.3.FromPort" will be stored in perms[3].FromPort
@@ -1343,6 +2075,18 @@ func (srv *Server) parsePerms(req *http.Request) []permKey {
 	// will be stored in sourceGroups[subgroupKey{3, 2}].Name.
 	sourceGroups := make(map[subgroupKey]ec2.UserSecurityGroup)
 
+	// IP ranges and prefix lists are indexed in the same way as
+	// source groups, and each may have a description. The key holds
+	// the IPv4 range, IPv6 range or prefix list id, as appropriate.
+	type source struct {
+		key         permKey
+		description string
+	}
+	sources := make(map[string]map[subgroupKey]source)
+	for _, kind := range []string{"IpRanges", "Ipv6Ranges", "PrefixListIds"} {
+		sources[kind] = make(map[subgroupKey]source)
+	}
+
 	// For each value in the form we store its associated information in the
 	// above maps. The maps are necessary because the form keys may
 	// arrive in any order, and the indices are not
@@ -1355,6 +2099,10 @@ func (srv *Server) parsePerms(req *http.Request) []permKey {
 			continue
 		}
 		ec2p := perms[id1]
+		kind := rest
+		if i := strings.Index(rest, "."); i >= 0 {
+			kind = rest[:i]
+		}
 		switch {
 		case rest == "FromPort":
 			ec2p.FromPort = atoi(val)
@@ -1394,70 +2142,92 @@ func (srv *Server) parsePerms(req *http.Request) []permKey {
 					fatalf(400, "InvalidGroupId.Malformed", "Invalid group ID: %q", val)
 				}
 				g.Id = val
+			case "Description":
+				g.Description = val
 			default:
 				fatalf(400, "UnknownParameter", "unknown parameter %q", name)
 			}
 			sourceGroups[k] = g
-		case strings.HasPrefix(rest, "IpRanges."):
-			var id2 int
-			if x, _ := fmt.Sscanf(rest[len("IpRanges."):], "%d.%s", &id2, &rest); x != 2 {
+		case sources[kind] != nil:
+			k := subgroupKey{id1: id1}
+			if x, _ := fmt.Sscanf(rest[len(kind)+1:], "%d.%s", &k.id2, &rest); x != 2 {
 				continue
 			}
-			switch rest {
-			case "CidrIp":
+			src := sources[kind][k]
+			switch {
+			case kind == "IpRanges" && rest == "CidrIp":
 				if !cidrIpPat.MatchString(val) {
 					fatalf(400, "InvalidPermission.Malformed", "Invalid IP range: %q", val)
 				}
-				ec2p.SourceIPs = append(ec2p.SourceIPs, val)
+				src.key.ipAddr = val
+			case kind == "Ipv6Ranges" && rest == "CidrIpv6":
+				ip, _, err := net.ParseCIDR(val)
+				if err != nil || ip.To4() != nil {
+					fatalf(400, "InvalidParameterValue", "CIDR block %s is malformed", val)
+				}
+				src.key.ipv6Addr = val
+			case kind == "PrefixListIds" && rest == "PrefixListId":
+				if !prefixListPat.MatchString(val) {
+					fatalf(400, "InvalidPrefixListId.Malformed", "Invalid prefix list ID: %q", val)
+				}
+				src.key.prefixListId = val
+			case rest == "Description":
+				src.description = val
 			default:
 				fatalf(400, "UnknownParameter", "unknown parameter %q", name)
 			}
+			sources[kind][k] = src
 		default:
 			fatalf(400, "UnknownParameter", "unknown parameter %q", name)
 		}
 		perms[id1] = ec2p
 	}
-	// Associate each set of source groups with its IPPerm.
-	for k, g := range sourceGroups {
-		p := perms[k.id1]
-		p.SourceGroups = append(p.SourceGroups, g)
-		perms[k.id1] = p
-	}
 
 	// Now that we have built up the IPPerms we need, we check for
 	// parameter errors and build up a permKey for each permission,
 	// looking up security groups from srv as we do so.
-	var result []permKey
 	for _, p := range perms {
 		if p.FromPort > p.ToPort {
 			fatalf(400, "InvalidParameterValue", "invalid port range")
 		}
-		k := permKey{
+	}
+	baseKey := func(id int) permKey {
+		p := perms[id]
+		return permKey{
 			protocol: p.Protocol,
 			fromPort: p.FromPort,
 			toPort:   p.ToPort,
 		}
-		for _, g := range p.SourceGroups {
-			if g.OwnerId != "" && g.OwnerId != ownerId {
-				fatalf(400, "InvalidGroup.NotFound", "group %q not found", g.Name)
-			}
-			var ec2g ec2.SecurityGroup
-			switch {
-			case g.Id != "":
-				ec2g.Id = g.Id
-			case g.Name != "":
-				ec2g.Name = g.Name
-			}
-			k.group = srv.group(ec2g)
-			if k.group == nil {
-				fatalf(400, "InvalidGroup.NotFound", "group %v not found", g)
-			}
-			result = append(result, k)
+	}
+	result := make(permSet)
+	for sk, g := range sourceGroups {
+		if g.OwnerId != "" && g.OwnerId != ownerId {
+			fatalf(400, "InvalidGroup.NotFound", "group %q not found", g.Name)
 		}
-		k.group = nil
-		for _, ip := range p.SourceIPs {
-			k.ipAddr = ip
-			result = append(result, k)
+		var ec2g ec2.SecurityGroup
+		switch {
+		case g.Id != "":
+			ec2g.Id = g.Id
+		case g.Name != "":
+			ec2g.Name = g.Name
+		}
+		k := baseKey(sk.id1)
+		k.group = srv.group(ec2g)
+		if k.group == nil {
+			fatalf(400, "InvalidGroup.NotFound", "group %v not found", g)
+		}
+		result[k] = g.Description
+	}
+	for kind, m := range sources {
+		for sk, src := range m {
+			if src.key == (permKey{}) {
+				fatalf(400, "MissingParameter", "missing value for %s.%d", kind, sk.id2)
+			}
+			k := baseKey(sk.id1)
+			k.ipAddr = src.key.ipAddr
+			k.ipv6Addr = src.key.ipv6Addr
+			k.prefixListId = src.key.prefixListId
+			result[k] = src.description
 		}
 	}
 	return result
@@ -1485,14 +2255,14 @@ func (srv *Server) deleteSecurityGroup(w http.ResponseWriter, req *http.Request,
 		if sg == g {
 			continue
 		}
-		for k := range sg.perms {
-			if k.group == g {
-				fatalf(500, "InvalidGroup.InUse", "group is currently in use by group %q", sg.id)
-			}
+		if sg.perms.has(func(k permKey) bool { return k.group == g }) ||
+			sg.egressPerms.has(func(k permKey) bool { return k.group == g }) {
+			fatalf(500, "InvalidGroup.InUse", "group is currently in use by group %q", sg.id)
 		}
 	}
 
 	delete(srv.groups, g.id)
+	srv.consistency.Deleted(g.id, g)
 	return &ec2.SimpleResp{
 		XMLName:   xml.Name{"", "DeleteSecurityGroupResponse"},
 		RequestId: reqId,
@@ -1553,14 +2323,23 @@ func (srv *Server) createVpc(w http.ResponseWriter, req *http.Request, reqId str
 
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
-	v := &vpc{ec2.VPC{
-		Id:              fmt.Sprintf("vpc-%d", srv.vpcId.next()),
-		State:           "available",
-		CIDRBlock:       cidrBlock,
-		DHCPOptionsId:   fmt.Sprintf("dopt-%d", srv.dhcpOptsId.next()),
-		InstanceTenancy: tenancy,
-	}}
+	v := &vpc{
+		VPC: ec2.VPC{
+			Id:              fmt.Sprintf("vpc-%d", srv.vpcId.next()),
+			State:           "available",
+			CIDRBlock:       cidrBlock,
+			DHCPOptionsId:   srv.defaultDHCPOptsId,
+			InstanceTenancy: tenancy,
+			CIDRBlocks: []ec2.CIDRBlockAssociation{{
+				AssociationId: fmt.Sprintf("vpc-cidr-assoc-%d", srv.cidrAssocId.next()),
+				CIDRBlock:     cidrBlock,
+				State:         "associated",
+			}},
+		},
+		dnsSupport: true,
+	}
 	srv.vpcs[v.Id] = v
+	srv.newRouteTable(v, true)
 	r := &ec2.CreateVPCResp{
 		RequestId: reqId,
 		VPC:       v.VPC,
@@ -1573,6 +2352,19 @@ func (srv *Server) deleteVpc(w http.ResponseWriter, req *http.Request, reqId str
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
 
+	if srv.vpcInternetGateway(v.Id) != nil {
+		fatalf(400, "DependencyViolation", "The vpc '%s' has dependencies and cannot be deleted.", v.Id)
+	}
+	for _, t := range srv.routeTables {
+		if t.VPCId == v.Id && !t.main() {
+			fatalf(400, "DependencyViolation", "The vpc '%s' has dependencies and cannot be deleted.", v.Id)
+		}
+	}
+	for _, t := range srv.routeTables {
+		if t.VPCId == v.Id {
+			delete(srv.routeTables, t.Id)
+		}
+	}
 	delete(srv.vpcs, v.Id)
 	return &ec2.SimpleResp{
 		XMLName:   xml.Name{"", "DeleteVpcResponse"},
@@ -1597,6 +2389,9 @@ func (srv *Server) describeVpcs(w http.ResponseWriter, req *http.Request, reqId
 			fatalf(400, "InvalidParameterValue", "describe VPCs: %v", err)
 		}
 	}
+	sort.Slice(resp.VPCs, func(i, j int) bool { return resp.VPCs[i].Id < resp.VPCs[j].Id })
+	start, end, nextToken := page(req.Form, len(resp.VPCs))
+	resp.VPCs, resp.NextToken = resp.VPCs[start:end], nextToken
 	return &resp
 }
 
@@ -1605,6 +2400,11 @@ func (srv *Server) calcSubnetAvailIPs(cidrBlock string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	if ipnet.IP.To4() == nil {
+		// The IPv6 CIDR blocks of subnets are associated later,
+		// and their addresses are not counted.
+		return 0, fmt.Errorf("%q is not an IPv4 CIDR block", cidrBlock)
+	}
 	// calculate the available IP addresses, removing the first 4 and
 	// the last, which are reserved by AWS.
 	maskOnes, maskBits := ipnet.Mask.Size()
@@ -1626,6 +2426,7 @@ func (srv *Server) createSubnet(w http.ResponseWriter, req *http.Request, reqId
 
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
+	srv.checkSubnetCIDRBlock(v, cidrBlock)
 	s := &subnet{ec2.Subnet{
 		Id:               fmt.Sprintf("subnet-%d", srv.subnetId.next()),
 		VPCId:            v.Id,
@@ -1647,6 +2448,7 @@ func (srv *Server) deleteSubnet(w http.ResponseWriter, req *http.Request, reqId
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
 
+	srv.removeSubnetAssociation(s.Id)
 	delete(srv.subnets, s.Id)
 	return &ec2.SimpleResp{
 		XMLName:   xml.Name{"", "DeleteSubnetResponse"},
@@ -1671,6 +2473,9 @@ func (srv *Server) describeSubnets(w http.ResponseWriter, req *http.Request, req
 			fatalf(400, "InvalidParameterValue", "describe subnets: %v", err)
 		}
 	}
+	sort.Slice(resp.Subnets, func(i, j int) bool { return resp.Subnets[i].Id < resp.Subnets[j].Id })
+	start, end, nextToken := page(req.Form, len(resp.Subnets))
+	resp.Subnets, resp.NextToken = resp.Subnets[start:end], nextToken
 	return &resp
 }
 
@@ -1682,6 +2487,11 @@ func (srv *Server) createIFace(w http.ResponseWriter, req *http.Request, reqId s
 		ipMap[0] = ec2.PrivateIP{Address: primaryIP, IsPrimary: true}
 	}
 	desc := req.Form.Get("Description")
+	ipv6Addrs := parseIPv6Addresses(req.Form, "Ipv6Addresses.%d.Ipv6Address")
+	ipv6Count := 0
+	if n := req.Form.Get("Ipv6AddressCount"); n != "" {
+		ipv6Count = atoi(n)
+	}
 
 	var groups []ec2.SecurityGroup
 	for name, vals := range req.Form {
@@ -1726,6 +2536,8 @@ func (srv *Server) createIFace(w http.ResponseWriter, req *http.Request, reqId s
 
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
+	ipv6Count = s.ipv6AddressCount(ipv6Addrs, ipv6Count)
+	srv.checkIPv6Addresses(s, ipv6Addrs, ipv6Count)
 	i := &iface{ec2.NetworkInterface{
 		Id:               fmt.Sprintf("eni-%d", srv.ifaceId.next()),
 		SubnetId:         s.Id,
@@ -1739,6 +2551,7 @@ func (srv *Server) createIFace(w http.ResponseWriter, req *http.Request, reqId s
 		SourceDestCheck:  true,
 		Groups:           groups,
 		PrivateIPs:       privateIPs,
+		IPv6Addresses:    srv.allocateIPv6Addresses(s, ipv6Addrs, ipv6Count),
 	}}
 	srv.ifaces[i.Id] = i
 	r := &ec2.CreateNetworkInterfaceResp{
@@ -1778,6 +2591,9 @@ func (srv *Server) describeIFaces(w http.ResponseWriter, req *http.Request, reqI
 			fatalf(400, "InvalidParameterValue", "describe ifaces: %v", err)
 		}
 	}
+	sort.Slice(resp.Interfaces, func(i, j int) bool { return resp.Interfaces[i].Id < resp.Interfaces[j].Id })
+	start, end, nextToken := page(req.Form, len(resp.Interfaces))
+	resp.Interfaces, resp.NextToken = resp.Interfaces[start:end], nextToken
 	return &resp
 }
 
@@ -1793,13 +2609,15 @@ func (srv *Server) attachIFace(w http.ResponseWriter, req *http.Request, reqId s
 		InstanceId:          inst.id(),
 		InstanceOwnerId:     ownerId,
 		DeviceIndex:         devIndex,
-		Status:              "in-use",
+		Status:              "attached",
 		AttachTime:          time.Now().Format(time.RFC3339),
 		DeleteOnTermination: true,
 	}}
 	srv.attachments[a.Id] = a
 	i.Attachment = a.NetworkInterfaceAttachment
+	i.Status = "in-use"
 	srv.ifaces[i.Id] = i
+	inst.ifaces = append(inst.ifaces, i.NetworkInterface)
 	r := &ec2.AttachNetworkInterfaceResp{
 		RequestId:    reqId,
 		AttachmentId: a.Id,
@@ -1816,7 +2634,16 @@ func (srv *Server) detachIFace(w http.ResponseWriter, req *http.Request, reqId s
 	for _, i := range srv.ifaces {
 		if i.Attachment.Id == att.Id {
 			i.Attachment = ec2.NetworkInterfaceAttachment{}
+			i.Status = "available"
 			srv.ifaces[i.Id] = i
+			if inst := srv.instances[att.InstanceId]; inst != nil {
+				for j, nic := range inst.ifaces {
+					if nic.Id == i.Id {
+						inst.ifaces = append(inst.ifaces[:j], inst.ifaces[j+1:]...)
+						break
+					}
+				}
+			}
 			break
 		}
 	}
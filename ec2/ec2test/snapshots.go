@@ -0,0 +1,134 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// snapshot returns the snapshot with the given id, failing if there
+// is none.
+// It must be called with srv.mu held.
+func (srv *Server) snapshot(id string) *snapshot {
+	if id == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter snapshotId")
+	}
+	s := srv.snapshots[id]
+	if s == nil {
+		fatalf(400, "InvalidSnapshot.NotFound", "The snapshot '%s' does not exist.", id)
+	}
+	return s
+}
+
+func (srv *Server) copySnapshot(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	region := req.Form.Get("SourceRegion")
+	if region == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter SourceRegion")
+	}
+	encrypted := false
+	if val := req.Form.Get("Encrypted"); val != "" {
+		var err error
+		encrypted, err = strconv.ParseBool(val)
+		if err != nil {
+			fatalf(400, "InvalidParameterValue", "bad flag Encrypted: %s", val)
+		}
+	}
+	kmsKeyId := req.Form.Get("KmsKeyId")
+	if kmsKeyId != "" && !encrypted {
+		fatalf(400, "InvalidParameterDependency", "The parameter KmsKeyId requires the parameter Encrypted to be set.")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	// The server simulates a single region, which holds the
+	// snapshots of all regions.
+	src := srv.snapshot(req.Form.Get("SourceSnapshotId"))
+	srv.completeSnapshot(src)
+	if src.Status != "completed" {
+		fatalf(400, "IncorrectState", "The snapshot '%s' is not in a 'completed' state.", src.Id)
+	}
+	description := req.Form.Get("Description")
+	if description == "" {
+		description = fmt.Sprintf("[Copied %s from %s]", src.Id, region)
+	}
+	s := srv.newSnapshotCopy(src, description, encrypted, kmsKeyId)
+	return &ec2.CopySnapshotResp{
+		RequestId:  reqId,
+		SnapshotId: s.Id,
+	}
+}
+
+func (srv *Server) describeSnapshotAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	s := srv.snapshot(req.Form.Get("SnapshotId"))
+
+	resp := &ec2.SnapshotAttributeResp{
+		RequestId:  reqId,
+		SnapshotId: s.Id,
+	}
+	switch attr := req.Form.Get("Attribute"); attr {
+	case "createVolumePermission":
+		if s.public {
+			resp.CreateVolumePermissions = append(resp.CreateVolumePermissions, ec2.CreateVolumePermission{Group: "all"})
+		}
+		var users []string
+		for user := range s.createVolumePerms {
+			users = append(users, user)
+		}
+		sort.Strings(users)
+		for _, user := range users {
+			resp.CreateVolumePermissions = append(resp.CreateVolumePermissions, ec2.CreateVolumePermission{UserId: user})
+		}
+	case "productCodes":
+		// Snapshots of the server have no product codes.
+	case "":
+		fatalf(400, "MissingParameter", "The request must contain the parameter Attribute")
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter attribute is invalid. Unknown attribute.", attr)
+	}
+	return resp
+}
+
+func (srv *Server) modifySnapshotAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	add, addAll := parseLaunchPermissions(req.Form, "CreateVolumePermission.Add.")
+	remove, removeAll := parseLaunchPermissions(req.Form, "CreateVolumePermission.Remove.")
+	if len(add) == 0 && !addAll && len(remove) == 0 && !removeAll {
+		fatalf(400, "InvalidParameterCombination", "No attributes specified.")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	s := srv.snapshot(req.Form.Get("SnapshotId"))
+	if addAll && s.Encrypted {
+		fatalf(400, "OperationNotPermitted", "Encrypted snapshots cannot be made public.")
+	}
+	for _, user := range add {
+		if user == ownerId {
+			fatalf(400, "InvalidParameterValue", "The snapshot '%s' already belongs to account %s.", s.Id, user)
+		}
+		s.createVolumePerms[user] = true
+	}
+	for _, user := range remove {
+		delete(s.createVolumePerms, user)
+	}
+	if addAll {
+		s.public = true
+	}
+	if removeAll {
+		s.public = false
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "ModifySnapshotAttributeResponse"},
+		RequestId: reqId,
+	}
+}
@@ -0,0 +1,576 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+const (
+	// defaultSpotPrice holds the spot price of the instance types
+	// whose price has not been set with SetSpotPrice.
+	defaultSpotPrice = "0.0100"
+
+	// spotProduct holds the product description of the spot
+	// instances launched by the server.
+	spotProduct = "Linux/UNIX"
+)
+
+// spotStatusMessages holds the messages of the spot request status
+// codes reported by the server.
+var spotStatusMessages = map[string]string{
+	"pending-evaluation":                    "Your Spot request has been submitted for review, and is pending evaluation.",
+	"not-scheduled-yet":                     "Your Spot request will not be evaluated until the scheduled date.",
+	"schedule-expired":                      "Your Spot request has expired because it was not fulfilled before the specified date.",
+	"capacity-not-available":                "There is no capacity available for the instance type in the requested Availability Zone.",
+	"price-too-low":                         "Your Spot request price is lower than the minimum required Spot request fulfillment price.",
+	"bad-parameters":                        "Your Spot request is not valid.",
+	"fulfilled":                             "Your Spot request is fulfilled.",
+	"canceled-before-fulfillment":           "You canceled your Spot request before it was fulfilled.",
+	"request-canceled-and-instance-running": "You canceled your Spot request while the Spot instance was still running.",
+	"instance-terminated-by-user":           "You terminated a Spot instance that had been fulfilled.",
+	"instance-terminated-by-price":          "The Spot price rose above your maximum price.",
+	"instance-terminated-no-capacity":       "There is no longer enough Spot capacity available for the instance.",
+}
+
+// spotRequest holds a simulated ec2 spot instance request. A request
+// for several instances is simulated as several requests, as on EC2.
+type spotRequest struct {
+	ec2.SpotInstanceRequest
+	seq int
+
+	// spec holds the RunInstances parameters that launch the
+	// instance of the request.
+	spec url.Values
+
+	bid                   float64
+	validFrom, validUntil time.Time
+}
+
+func (r *spotRequest) tagSet() []ec2.Tag { return r.Tags }
+
+func (r *spotRequest) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "spot-instance-request-id":
+		return r.Id == value, nil
+	case "state":
+		return r.State == value, nil
+	case "status-code":
+		return r.Status.Code == value, nil
+	case "type":
+		return r.Type == value, nil
+	case "spot-price":
+		return r.SpotPrice == value, nil
+	case "instance-id":
+		return r.InstanceId == value, nil
+	case "launched-availability-zone":
+		return r.LaunchedAvailZone == value, nil
+	case "launch-group":
+		return r.LaunchGroup == value, nil
+	case "availability-zone-group":
+		return r.AvailZoneGroup == value, nil
+	case "product-description":
+		return r.ProductDescription == value, nil
+	case "launch.image-id":
+		return r.LaunchSpecification.ImageId == value, nil
+	case "launch.instance-type":
+		return r.LaunchSpecification.InstanceType == value, nil
+	case "launch.key-name":
+		return r.LaunchSpecification.KeyName == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// setStatus sets the status of r to the given code, at the given
+// time.
+func (r *spotRequest) setStatus(code string, now time.Time) {
+	if r.Status.Code == code {
+		return
+	}
+	r.Status = ec2.SpotInstanceStatus{
+		Code:       code,
+		Message:    spotStatusMessages[code],
+		UpdateTime: now.Format(time.RFC3339),
+	}
+}
+
+// spotPrice holds a simulated ec2 spot price history entry.
+type spotPrice struct {
+	ec2.SpotPrice
+	time time.Time
+}
+
+func (p *spotPrice) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "instance-type":
+		return p.InstanceType == value, nil
+	case "product-description":
+		return p.ProductDescription == value, nil
+	case "availability-zone":
+		return p.AvailZone == value, nil
+	case "spot-price":
+		return p.SpotPrice.SpotPrice == value, nil
+	case "timestamp":
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return false, err
+		}
+		return p.time.Equal(t), nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// SetSpotPrice sets the spot price of the given instance type, such
+// as "0.05", in every availability zone, and records it in the spot
+// price history. The price of the types that have not been set is
+// 0.01.
+//
+// Open spot requests with a maximum price not below the new price are
+// fulfilled, and the instances of active requests with a lower
+// maximum price are interrupted, unless the requests have a block
+// duration.
+func (srv *Server) SetSpotPrice(instType, price string) {
+	if _, err := strconv.ParseFloat(price, 64); err != nil {
+		panic(fmt.Errorf("invalid spot price %q: %v", price, err))
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.spotPrices[instType] = price
+	now := srv.now()
+	for _, z := range srv.zones {
+		srv.spotPriceHistory = append(srv.spotPriceHistory, &spotPrice{
+			SpotPrice: ec2.SpotPrice{
+				InstanceType:       instType,
+				ProductDescription: spotProduct,
+				SpotPrice:          price,
+				Timestamp:          now.Format(time.RFC3339),
+				AvailZone:          z.Name,
+			},
+			time: now,
+		})
+	}
+	srv.fulfilSpotRequests()
+}
+
+// SetSpotCapacity sets whether spare capacity is available to fulfil
+// spot requests, which is the case by default. Without capacity, open
+// requests are held with the "capacity-not-available" status; running
+// spot instances are not affected, but see InterruptSpotInstance.
+func (srv *Server) SetSpotCapacity(available bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.spotCapacity = available
+	srv.fulfilSpotRequests()
+}
+
+// InterruptSpotInstance terminates the spot instance with the given
+// id, as EC2 does when it needs the capacity back. The request of the
+// instance is closed if it is a one-time request; a persistent request
+// is fulfilled again with a new instance if capacity is available.
+func (srv *Server) InterruptSpotInstance(instId string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	inst := srv.instances[instId]
+	if inst == nil || inst.spotRequestId == "" {
+		panic(fmt.Errorf("spot instance %q not found", instId))
+	}
+	if inst.state == ShuttingDown || inst.state == Terminated {
+		panic(fmt.Errorf("spot instance %q is already terminated", instId))
+	}
+	srv.interruptSpotInstance(inst, "instance-terminated-no-capacity")
+	srv.fulfilSpotRequests()
+}
+
+// now returns the time on the clock of srv if it has one, and the
+// current time otherwise.
+// It must be called with srv.mu held.
+func (srv *Server) now() time.Time {
+	if srv.clock != nil {
+		return srv.clock.Now()
+	}
+	return time.Now()
+}
+
+// spotPrice returns the current spot price of the given instance
+// type.
+// It must be called with srv.mu held.
+func (srv *Server) spotPrice(instType string) float64 {
+	price, ok := srv.spotPrices[instType]
+	if !ok {
+		price = defaultSpotPrice
+	}
+	p, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		panic(fmt.Errorf("invalid spot price %q: %v", price, err))
+	}
+	return p
+}
+
+// fulfilSpotRequests evaluates the open spot requests in id order,
+// launching their instances when their maximum price and the spare
+// capacity allow it, and interrupts the spot instances whose request
+// has been outbid.
+// It must be called with srv.mu held.
+func (srv *Server) fulfilSpotRequests() {
+	now := srv.now()
+	for _, r := range srv.sortedSpotRequests() {
+		price := srv.spotPrice(r.LaunchSpecification.InstanceType)
+		switch r.State {
+		case "open":
+			switch {
+			case !r.validUntil.IsZero() && now.After(r.validUntil):
+				r.State = "closed"
+				r.setStatus("schedule-expired", now)
+			case !r.validFrom.IsZero() && now.Before(r.validFrom):
+				r.setStatus("not-scheduled-yet", now)
+			case !srv.spotCapacity:
+				r.setStatus("capacity-not-available", now)
+			case r.bid < price:
+				r.setStatus("price-too-low", now)
+			default:
+				srv.launchSpotInstance(r, now)
+			}
+		case "active":
+			if r.bid < price && r.BlockDurationMinutes == 0 {
+				srv.interruptSpotInstance(srv.instances[r.InstanceId], "instance-terminated-by-price")
+			}
+		}
+	}
+}
+
+// launchSpotInstance launches the instance of r. If the launch
+// fails, because a resource of the launch specification has been
+// deleted since the request was made, r fails.
+// It must be called with srv.mu held.
+func (srv *Server) launchSpotInstance(r *spotRequest, now time.Time) {
+	defer func() {
+		switch err := recover().(type) {
+		case *ec2.Error:
+			r.State = "failed"
+			r.setStatus("bad-parameters", now)
+			r.FaultCode = err.Code
+			r.FaultMessage = err.Message
+		case nil:
+		default:
+			panic(err)
+		}
+	}()
+	resp := srv.launchInstances(r.spec, 1)
+	inst := srv.instances[resp.Instances[0].InstanceId]
+	inst.spotRequestId = r.Id
+	r.State = "active"
+	r.setStatus("fulfilled", now)
+	r.InstanceId = inst.id()
+	r.LaunchedAvailZone = inst.availZone
+}
+
+// interruptSpotInstance terminates the spot instance inst, and
+// records why in the status of its request.
+// It must be called with srv.mu held.
+func (srv *Server) interruptSpotInstance(inst *Instance, code string) {
+	srv.transition(inst, ShuttingDown, Terminated)
+	srv.releaseVolumes(inst)
+	srv.clearInstanceAddresses(inst)
+	srv.spotInstanceTerminated(inst, code)
+}
+
+// spotInstanceTerminated updates the request of inst, if it is an
+// active spot instance, now that inst has been terminated for the
+// reason given by the status code: a one-time request is closed,
+// while a persistent request opens again.
+// It must be called with srv.mu held.
+func (srv *Server) spotInstanceTerminated(inst *Instance, code string) {
+	r := srv.spotRequests[inst.spotRequestId]
+	if r == nil || r.State != "active" || r.InstanceId != inst.id() {
+		return
+	}
+	if r.Type == "persistent" {
+		r.State = "open"
+	} else {
+		r.State = "closed"
+	}
+	r.setStatus(code, srv.now())
+}
+
+// sortedSpotRequests returns the spot requests of srv, sorted by id.
+// It must be called with srv.mu held.
+func (srv *Server) sortedSpotRequests() []*spotRequest {
+	requests := make([]*spotRequest, 0, len(srv.spotRequests))
+	for _, r := range srv.spotRequests {
+		requests = append(requests, r)
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].seq < requests[j].seq })
+	return requests
+}
+
+// spotRequest returns the spot request with the given id.
+// It must be called with srv.mu held.
+func (srv *Server) spotRequest(id string) *spotRequest {
+	r := srv.spotRequests[id]
+	if r == nil {
+		fatalf(400, "InvalidSpotInstanceRequestID.NotFound", "The spot instance request ID '%s' does not exist", id)
+	}
+	return r
+}
+
+// parseSpotTime parses the time of the given spot request parameter,
+// if it is set.
+func parseSpotTime(form url.Values, name string) time.Time {
+	value := form.Get(name)
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		fatalf(400, "InvalidParameterValue", "Invalid value '%s' for %s", value, name)
+	}
+	return t
+}
+
+// spotLaunchSpec returns the RunInstances parameters given with the
+// LaunchSpecification prefix in form, after checking that they
+// describe instances that can be launched.
+// It must be called with srv.mu held.
+func (srv *Server) spotLaunchSpec(form url.Values) url.Values {
+	const prefix = "LaunchSpecification."
+	spec := make(url.Values)
+	for name, values := range form {
+		if strings.HasPrefix(name, prefix) {
+			spec[name[len(prefix):]] = values
+		}
+	}
+	if spec.Get("ImageId") == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter LaunchSpecification.ImageId")
+	}
+	srv.checkKeyPair(spec.Get("KeyName"))
+	srv.launchImage(spec.Get("ImageId"))
+	srv.formToGroups(spec)
+	if id := spec.Get("SubnetId"); id != "" && srv.subnets[id] == nil {
+		fatalf(400, "InvalidSubnetID.NotFound", "subnet %s not found", id)
+	}
+	srv.parseRunNetworkInterfaces(spec)
+	parseBlockDeviceMappings(spec)
+	srv.parsePlacement(spec)
+	return spec
+}
+
+// spotLaunchSpecification returns the launch specification reported
+// for spot requests launching instances with spec.
+// It must be called with srv.mu held.
+func (srv *Server) spotLaunchSpecification(spec url.Values) ec2.SpotLaunchSpecification {
+	ls := ec2.SpotLaunchSpecification{
+		ImageId:             spec.Get("ImageId"),
+		InstanceType:        spec.Get("InstanceType"),
+		KeyName:             spec.Get("KeyName"),
+		KernelId:            spec.Get("KernelId"),
+		RamdiskId:           spec.Get("RamdiskId"),
+		SubnetId:            spec.Get("SubnetId"),
+		AvailZone:           spec.Get("Placement.AvailabilityZone"),
+		PlacementGroupName:  spec.Get("Placement.GroupName"),
+		Tenancy:             spec.Get("Placement.Tenancy"),
+		Monitoring:          spec.Get("Monitoring.Enabled") == "true",
+		BlockDeviceMappings: parseBlockDeviceMappings(spec),
+	}
+	for _, g := range srv.formToGroups(spec) {
+		ls.SecurityGroups = append(ls.SecurityGroups, g.ec2SecurityGroup())
+	}
+	return ls
+}
+
+func (srv *Server) requestSpotInstances(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	price := req.Form.Get("SpotPrice")
+	if price == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter SpotPrice")
+	}
+	bid, err := strconv.ParseFloat(price, 64)
+	if err != nil || bid <= 0 {
+		fatalf(400, "InvalidParameterValue", "Invalid value '%s' for SpotPrice", price)
+	}
+	count := 1
+	if c := req.Form.Get("InstanceCount"); c != "" {
+		count, err = strconv.Atoi(c)
+		if err != nil || count < 1 {
+			fatalf(400, "InvalidParameterValue", "Invalid value '%s' for InstanceCount", c)
+		}
+	}
+	reqType := req.Form.Get("Type")
+	switch reqType {
+	case "":
+		reqType = "one-time"
+	case "one-time", "persistent":
+	default:
+		fatalf(400, "InvalidParameterValue", "Invalid value '%s' for Type", reqType)
+	}
+	validFrom := parseSpotTime(req.Form, "ValidFrom")
+	validUntil := parseSpotTime(req.Form, "ValidUntil")
+	if !validFrom.IsZero() && !validUntil.IsZero() && !validUntil.After(validFrom) {
+		fatalf(400, "InvalidParameterCombination", "ValidUntil must be later than ValidFrom")
+	}
+	duration := 0
+	if d := req.Form.Get("BlockDurationMinutes"); d != "" {
+		duration, err = strconv.Atoi(d)
+		if err != nil || duration < 60 || duration > 360 || duration%60 != 0 {
+			fatalf(400, "InvalidParameterValue", "Invalid value '%s' for BlockDurationMinutes", d)
+		}
+		if reqType == "persistent" {
+			fatalf(400, "InvalidParameterCombination", "Persistent Spot requests cannot have a block duration")
+		}
+	}
+	tagSpecs := parseTagSpecs(req.Form, "spot-instances-request")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	spec := srv.spotLaunchSpec(req.Form)
+	now := srv.now()
+
+	var resp ec2.RequestSpotInstancesResp
+	resp.RequestId = reqId
+	for i := 0; i < count; i++ {
+		r := &spotRequest{
+			SpotInstanceRequest: ec2.SpotInstanceRequest{
+				SpotPrice:            price,
+				Type:                 reqType,
+				State:                "open",
+				ValidFrom:            req.Form.Get("ValidFrom"),
+				ValidUntil:           req.Form.Get("ValidUntil"),
+				LaunchGroup:          req.Form.Get("LaunchGroup"),
+				AvailZoneGroup:       req.Form.Get("AvailabilityZoneGroup"),
+				BlockDurationMinutes: duration,
+				LaunchSpecification:  srv.spotLaunchSpecification(spec),
+				CreateTime:           now.Format(time.RFC3339),
+				ProductDescription:   spotProduct,
+				Tags:                 tagSpecs["spot-instances-request"],
+			},
+			seq:        srv.spotRequestId.next(),
+			spec:       spec,
+			bid:        bid,
+			validFrom:  validFrom,
+			validUntil: validUntil,
+		}
+		r.Id = fmt.Sprintf("sir-%d", r.seq)
+		r.setStatus("pending-evaluation", now)
+		srv.spotRequests[r.Id] = r
+		resp.SpotRequests = append(resp.SpotRequests, r.SpotInstanceRequest)
+	}
+	// The requests are reported as pending evaluation, but they are
+	// evaluated straight away, so that they are fulfilled or held
+	// by the time they are described.
+	srv.fulfilSpotRequests()
+	return &resp
+}
+
+func (srv *Server) describeSpotInstanceRequests(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.fulfilSpotRequests()
+
+	var requests []*spotRequest
+	if ids := parseIDs(req.Form, "SpotInstanceRequestId."); len(ids) > 0 {
+		for id := range ids {
+			requests = append(requests, srv.spotRequest(id))
+		}
+		sort.Slice(requests, func(i, j int) bool { return requests[i].seq < requests[j].seq })
+	} else {
+		requests = srv.sortedSpotRequests()
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.SpotInstanceRequestsResp
+	resp.RequestId = reqId
+	for _, r := range requests {
+		ok, err := f.ok(r)
+		if ok {
+			resp.SpotRequests = append(resp.SpotRequests, r.SpotInstanceRequest)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe spot instance requests: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) cancelSpotInstanceRequests(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	ids := parseIDs(req.Form, "SpotInstanceRequestId.")
+	if len(ids) == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter SpotInstanceRequestId")
+	}
+	var requests []*spotRequest
+	for id := range ids {
+		requests = append(requests, srv.spotRequest(id))
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].seq < requests[j].seq })
+
+	now := srv.now()
+	var resp ec2.CancelSpotInstanceRequestsResp
+	resp.RequestId = reqId
+	for _, r := range requests {
+		switch r.State {
+		case "open":
+			r.State = "cancelled"
+			r.setStatus("canceled-before-fulfillment", now)
+		case "active":
+			// The instance keeps running.
+			r.State = "cancelled"
+			r.setStatus("request-canceled-and-instance-running", now)
+		}
+		resp.SpotRequests = append(resp.SpotRequests, ec2.CancelledSpotInstanceRequest{
+			Id:    r.Id,
+			State: r.State,
+		})
+	}
+	return &resp
+}
+
+func (srv *Server) describeSpotPriceHistory(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	startTime := parseSpotTime(req.Form, "StartTime")
+	endTime := parseSpotTime(req.Form, "EndTime")
+	instTypes := parseIDs(req.Form, "InstanceType.")
+	products := parseIDs(req.Form, "ProductDescription.")
+	availZone := req.Form.Get("AvailabilityZone")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	f := newFilter(req.Form)
+	var history []*spotPrice
+	for _, p := range srv.spotPriceHistory {
+		switch {
+		case !startTime.IsZero() && p.time.Before(startTime):
+		case !endTime.IsZero() && p.time.After(endTime):
+		case len(instTypes) > 0 && !instTypes[p.InstanceType]:
+		case len(products) > 0 && !products[p.ProductDescription]:
+		case availZone != "" && p.AvailZone != availZone:
+		default:
+			ok, err := f.ok(p)
+			if ok {
+				history = append(history, p)
+			} else if err != nil {
+				fatalf(400, "InvalidParameterValue", "describe spot price history: %v", err)
+			}
+		}
+	}
+	// The most recent prices come first. The history is recorded in
+	// time order, so a stable sort keeps the prices set at the same
+	// time in a consistent order across pages.
+	sort.SliceStable(history, func(i, j int) bool { return history[i].time.After(history[j].time) })
+
+	start, end, nextToken := page(req.Form, len(history))
+	var resp ec2.SpotPriceHistoryResp
+	resp.RequestId = reqId
+	resp.NextToken = nextToken
+	for _, p := range history[start:end] {
+		resp.History = append(resp.History, p.SpotPrice)
+	}
+	return &resp
+}
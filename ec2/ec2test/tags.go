@@ -0,0 +1,423 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// maxTags holds the maximum number of tags a resource can have.
+const maxTags = 50
+
+// tagged represents an object with tags. Tagged objects are
+// matched against the "tag:<key>", "tag-key" and "tag-value"
+// filters by the filter itself.
+type tagged interface {
+	tagSet() []ec2.Tag
+}
+
+func (inst *Instance) tagSet() []ec2.Tag     { return inst.tags }
+func (g *securityGroup) tagSet() []ec2.Tag   { return g.tags }
+func (v *vpc) tagSet() []ec2.Tag             { return v.Tags }
+func (s *subnet) tagSet() []ec2.Tag          { return s.Tags }
+func (i *iface) tagSet() []ec2.Tag           { return i.Tags }
+func (v *volume) tagSet() []ec2.Tag          { return v.Tags }
+func (s *snapshot) tagSet() []ec2.Tag        { return s.Tags }
+func (a *address) tagSet() []ec2.Tag         { return a.Tags }
+func (g *internetGateway) tagSet() []ec2.Tag { return g.Tags }
+func (t *routeTable) tagSet() []ec2.Tag      { return t.Tags }
+func (g *natGateway) tagSet() []ec2.Tag      { return g.Tags }
+func (d *dhcpOptions) tagSet() []ec2.Tag     { return d.Tags }
+func (p *vpcPeering) tagSet() []ec2.Tag      { return p.Tags }
+
+// matchTag reports whether the tag filter attr=value matches
+// tags, and whether attr is a tag filter at all.
+func matchTag(tags []ec2.Tag, attr, value string) (ok, isTag bool) {
+	var match func(t ec2.Tag) bool
+	switch {
+	case attr == "tag-key":
+		match = func(t ec2.Tag) bool { return matchValue(value, t.Key) }
+	case attr == "tag-value":
+		match = func(t ec2.Tag) bool { return matchValue(value, t.Value) }
+	case strings.HasPrefix(attr, "tag:"):
+		key := attr[len("tag:"):]
+		match = func(t ec2.Tag) bool { return t.Key == key && matchValue(value, t.Value) }
+	default:
+		return false, false
+	}
+	for _, t := range tags {
+		if match(t) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// resourceTag holds a tag of a resource, as described by DescribeTags.
+type resourceTag struct {
+	ec2.ResourceTag
+}
+
+func (t *resourceTag) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "key":
+		return t.Key == value, nil
+	case "value":
+		return t.Value == value, nil
+	case "resource-id":
+		return t.ResourceId == value, nil
+	case "resource-type":
+		return t.ResourceType == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// resourceTags returns the type of the resource with the given id,
+// and a pointer to its tags.
+// It must be called with srv.mu held.
+func (srv *Server) resourceTags(id string) (resourceType string, tags *[]ec2.Tag) {
+	var code string
+	switch {
+	case strings.HasPrefix(id, "i-"):
+		resourceType, code = "instance", "InvalidInstanceID.NotFound"
+		if inst := srv.instances[id]; inst != nil && !srv.consistency.Hidden(id) {
+			tags = &inst.tags
+		}
+	case strings.HasPrefix(id, "sg-"):
+		resourceType, code = "security-group", "InvalidGroup.NotFound"
+		if g := srv.groups[id]; g != nil && !srv.consistency.Hidden(id) {
+			tags = &g.tags
+		}
+	case strings.HasPrefix(id, "vpc-"):
+		resourceType, code = "vpc", "InvalidVpcID.NotFound"
+		if v := srv.vpcs[id]; v != nil {
+			tags = &v.Tags
+		}
+	case strings.HasPrefix(id, "subnet-"):
+		resourceType, code = "subnet", "InvalidSubnetID.NotFound"
+		if s := srv.subnets[id]; s != nil {
+			tags = &s.Tags
+		}
+	case strings.HasPrefix(id, "eni-"):
+		resourceType, code = "network-interface", "InvalidNetworkInterfaceID.NotFound"
+		if i := srv.ifaces[id]; i != nil {
+			tags = &i.Tags
+		}
+	case strings.HasPrefix(id, "vol-"):
+		resourceType, code = "volume", "InvalidVolume.NotFound"
+		if v := srv.volumes[id]; v != nil {
+			tags = &v.Tags
+		}
+	case strings.HasPrefix(id, "snap-"):
+		resourceType, code = "snapshot", "InvalidSnapshot.NotFound"
+		if s := srv.snapshots[id]; s != nil {
+			tags = &s.Tags
+		}
+	case strings.HasPrefix(id, "eipalloc-"):
+		resourceType, code = "elastic-ip", "InvalidAllocationID.NotFound"
+		for _, a := range srv.addresses {
+			if a.AllocationId == id {
+				tags = &a.Tags
+			}
+		}
+	case strings.HasPrefix(id, "igw-"):
+		resourceType, code = "internet-gateway", "InvalidInternetGatewayID.NotFound"
+		if g := srv.igws[id]; g != nil {
+			tags = &g.Tags
+		}
+	case strings.HasPrefix(id, "rtb-"):
+		resourceType, code = "route-table", "InvalidRouteTableID.NotFound"
+		if t := srv.routeTables[id]; t != nil {
+			tags = &t.Tags
+		}
+	case strings.HasPrefix(id, "nat-"):
+		resourceType, code = "natgateway", "NatGatewayNotFound"
+		if g := srv.natGateways[id]; g != nil {
+			tags = &g.Tags
+		}
+	case strings.HasPrefix(id, "dopt-"):
+		resourceType, code = "dhcp-options", "InvalidDhcpOptionID.NotFound"
+		if d := srv.dhcpOptions[id]; d != nil {
+			tags = &d.Tags
+		}
+	case strings.HasPrefix(id, "pcx-"):
+		resourceType, code = "vpc-peering-connection", "InvalidVpcPeeringConnectionID.NotFound"
+		if p := srv.peerings[id]; p != nil {
+			tags = &p.Tags
+		}
+	case strings.HasPrefix(id, "ami-"):
+		resourceType, code = "image", "InvalidAMIID.NotFound"
+		if img := srv.images[id]; img != nil && img.launchableBy(ownerId) {
+			tags = &img.Tags
+		}
+	case strings.HasPrefix(id, "sir-"):
+		resourceType, code = "spot-instances-request", "InvalidSpotInstanceRequestID.NotFound"
+		if r := srv.spotRequests[id]; r != nil {
+			tags = &r.Tags
+		}
+	case strings.HasPrefix(id, "lt-"):
+		resourceType, code = "launch-template", "InvalidLaunchTemplateId.NotFound"
+		if t := srv.launchTemplates[id]; t != nil {
+			tags = &t.Tags
+		}
+	default:
+		fatalf(400, "InvalidID", "The ID '%s' is not valid", id)
+	}
+	if tags == nil {
+		fatalf(400, code, "The %s ID '%s' does not exist", resourceType, id)
+	}
+	return resourceType, tags
+}
+
+// allResourceTags returns the tags of every resource in srv.
+// It must be called with srv.mu held.
+func (srv *Server) allResourceTags() []*resourceTag {
+	var all []*resourceTag
+	add := func(id, resourceType string, tags []ec2.Tag) {
+		for _, t := range tags {
+			all = append(all, &resourceTag{ec2.ResourceTag{
+				Tag:          t,
+				ResourceId:   id,
+				ResourceType: resourceType,
+			}})
+		}
+	}
+	for id, inst := range srv.instances {
+		if !srv.consistency.Hidden(id) {
+			add(id, "instance", inst.tags)
+		}
+	}
+	for id, g := range srv.groups {
+		if !srv.consistency.Hidden(id) {
+			add(id, "security-group", g.tags)
+		}
+	}
+	for id, v := range srv.vpcs {
+		add(id, "vpc", v.Tags)
+	}
+	for id, s := range srv.subnets {
+		add(id, "subnet", s.Tags)
+	}
+	for id, i := range srv.ifaces {
+		add(id, "network-interface", i.Tags)
+	}
+	for id, v := range srv.volumes {
+		add(id, "volume", v.Tags)
+	}
+	for id, s := range srv.snapshots {
+		add(id, "snapshot", s.Tags)
+	}
+	for _, a := range srv.addresses {
+		if a.AllocationId != "" {
+			add(a.AllocationId, "elastic-ip", a.Tags)
+		}
+	}
+	for id, g := range srv.igws {
+		add(id, "internet-gateway", g.Tags)
+	}
+	for id, t := range srv.routeTables {
+		add(id, "route-table", t.Tags)
+	}
+	for id, g := range srv.natGateways {
+		add(id, "natgateway", g.Tags)
+	}
+	for id, d := range srv.dhcpOptions {
+		add(id, "dhcp-options", d.Tags)
+	}
+	for id, p := range srv.peerings {
+		add(id, "vpc-peering-connection", p.Tags)
+	}
+	for id, img := range srv.images {
+		if img.launchableBy(ownerId) {
+			add(id, "image", img.Tags)
+		}
+	}
+	for id, r := range srv.spotRequests {
+		add(id, "spot-instances-request", r.Tags)
+	}
+	for id, t := range srv.launchTemplates {
+		add(id, "launch-template", t.Tags)
+	}
+	sort.Sort(resourceTagsByKey(all))
+	return all
+}
+
+type resourceTagsByKey []*resourceTag
+
+func (s resourceTagsByKey) Len() int      { return len(s) }
+func (s resourceTagsByKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s resourceTagsByKey) Less(i, j int) bool {
+	if s[i].ResourceId != s[j].ResourceId {
+		return s[i].ResourceId < s[j].ResourceId
+	}
+	return s[i].Key < s[j].Key
+}
+
+// parseTags returns the tags specified in form with the given
+// prefix (e.g. "Tag."), in request order.
+func parseTags(form url.Values, prefix string) []ec2.Tag {
+	var tags []ec2.Tag
+	for i := 1; ; i++ {
+		p := prefix + strconv.Itoa(i)
+		keys, ok := form[p+".Key"]
+		if !ok {
+			break
+		}
+		t := ec2.Tag{Key: keys[0], Value: form.Get(p + ".Value")}
+		if t.Key == "" {
+			fatalf(400, "InvalidParameterValue", "Tag key cannot be empty")
+		}
+		if strings.HasPrefix(t.Key, "aws:") {
+			fatalf(400, "InvalidParameterValue", "Tag keys starting with 'aws:' are reserved for internal use")
+		}
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// parseTagSpecs returns the tags to apply to new resources, by
+// resource type, specified with TagSpecification parameters in form.
+// Only the given resource types are allowed.
+func parseTagSpecs(form url.Values, resourceTypes ...string) map[string][]ec2.Tag {
+	specs := make(map[string][]ec2.Tag)
+	for i := 1; ; i++ {
+		prefix := "TagSpecification." + strconv.Itoa(i)
+		resourceType := form.Get(prefix + ".ResourceType")
+		if resourceType == "" {
+			break
+		}
+		allowed := false
+		for _, t := range resourceTypes {
+			allowed = allowed || t == resourceType
+		}
+		if !allowed {
+			fatalf(400, "InvalidParameterValue", "'%s' is not a valid taggable resource type for this operation.", resourceType)
+		}
+		if _, ok := specs[resourceType]; ok {
+			fatalf(400, "InvalidParameterValue", "The same resource type may not be specified more than once in tag specifications")
+		}
+		specs[resourceType] = setTags(nil, parseTags(form, prefix+".Tag."))
+	}
+	return specs
+}
+
+// setTags returns tags with the given tags added, replacing any
+// existing tags with the same keys. The tags slice is not modified,
+// as it may be shared.
+func setTags(tags []ec2.Tag, add []ec2.Tag) []ec2.Tag {
+	result := append([]ec2.Tag(nil), tags...)
+next:
+	for _, t := range add {
+		for i := range result {
+			if result[i].Key == t.Key {
+				result[i].Value = t.Value
+				continue next
+			}
+		}
+		result = append(result, t)
+	}
+	if len(result) > maxTags {
+		fatalf(400, "TagLimitExceeded", "The maximum number of Tags for a resource has been reached.")
+	}
+	return result
+}
+
+// resourcesFromForm returns pointers to the tags of the resources
+// with the ids given in the form. All the resources are checked
+// before any of them is changed.
+// It must be called with srv.mu held.
+func (srv *Server) resourcesFromForm(form url.Values) []*[]ec2.Tag {
+	ids := parseIDs(form, "ResourceId.")
+	if len(ids) == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter resourceIdSet")
+	}
+	var resources []*[]ec2.Tag
+	for id := range ids {
+		_, tags := srv.resourceTags(id)
+		resources = append(resources, tags)
+	}
+	return resources
+}
+
+func (srv *Server) createTags(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	tags := parseTags(req.Form, "Tag.")
+	if len(tags) == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter tag")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	resources := srv.resourcesFromForm(req.Form)
+	for _, r := range resources {
+		*r = setTags(*r, tags)
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "CreateTagsResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) deleteTags(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	resources := srv.resourcesFromForm(req.Form)
+
+	// A tag matches if its key matches and, when a value is
+	// given, its value matches too. With no tags given, all the
+	// tags are deleted.
+	tags := parseTags(req.Form, "Tag.")
+	matches := func(t ec2.Tag) bool {
+		if len(tags) == 0 {
+			return true
+		}
+		for i, d := range tags {
+			_, hasValue := req.Form["Tag."+strconv.Itoa(i+1)+".Value"]
+			if d.Key == t.Key && (!hasValue || d.Value == t.Value) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range resources {
+		var kept []ec2.Tag
+		for _, t := range *r {
+			if !matches(t) {
+				kept = append(kept, t)
+			}
+		}
+		*r = kept
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteTagsResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describeTags(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	f := newFilter(req.Form)
+	var resp ec2.TagsResp
+	resp.RequestId = reqId
+	for _, t := range srv.allResourceTags() {
+		ok, err := f.ok(t)
+		if ok {
+			resp.Tags = append(resp.Tags, t.ResourceTag)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe tags: %v", err)
+		}
+	}
+	return &resp
+}
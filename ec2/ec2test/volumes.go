@@ -0,0 +1,778 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// volume holds a simulated EBS volume. While the volume is attached,
+// Attachments holds a single item describing the attachment, and the
+// volume is also listed in the volumes of the instance.
+type volume struct {
+	ec2.Volume
+	ioStatus string
+}
+
+func (v *volume) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "availability-zone":
+		return v.AvailZone == value, nil
+	case "create-time":
+		return v.CreateTime == value, nil
+	case "encrypted":
+		val, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("bad flag %q: %s", attr, value)
+		}
+		return v.Encrypted == val, nil
+	case "size":
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return false, err
+		}
+		return v.Size == size, nil
+	case "snapshot-id":
+		return v.SnapshotId == value, nil
+	case "status":
+		return v.Status == value, nil
+	case "volume-id":
+		return v.Id == value, nil
+	case "volume-type":
+		return v.VolumeType == value, nil
+	case "attachment.instance-id":
+		return v.attached() && v.Attachments[0].InstanceId == value, nil
+	case "attachment.device":
+		return v.attached() && v.Attachments[0].Device == value, nil
+	case "attachment.status":
+		return v.attached() && v.Attachments[0].Status == value, nil
+	case "attachment.delete-on-termination":
+		val, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("bad flag %q: %s", attr, value)
+		}
+		return v.attached() && v.Attachments[0].DeleteOnTermination == val, nil
+	case "attachment.attach-time", "tag":
+		return false, fmt.Errorf("%q filter is not implemented", attr)
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+func (v *volume) attached() bool {
+	return len(v.Attachments) > 0
+}
+
+// volumeStatus allows filtering the status of a volume, which
+// uses different attribute names from the volume itself.
+type volumeStatus struct {
+	*volume
+}
+
+func (v volumeStatus) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "availability-zone":
+		return v.AvailZone == value, nil
+	case "volume-status.status":
+		return v.ioStatus == value, nil
+	case "volume-status.details-name":
+		return value == "io-enabled", nil
+	case "volume-status.details-status":
+		return value == v.ioDetail(), nil
+	case "event.description", "event.event-id", "event.event-type",
+		"event.not-after", "event.not-before", "action.code",
+		"action.description", "action.event-id":
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// ioDetail returns the status of the io-enabled check of
+// the volume.
+func (v *volume) ioDetail() string {
+	switch v.ioStatus {
+	case "ok":
+		return "passed"
+	case "impaired":
+		return "failed"
+	}
+	return "insufficient-data"
+}
+
+func (v *volume) ec2status() ec2.VolumeStatus {
+	return ec2.VolumeStatus{
+		VolumeId:  v.Id,
+		AvailZone: v.AvailZone,
+		Status:    v.ioStatus,
+		Details: []ec2.VolumeStatusDetail{{
+			Name:   "io-enabled",
+			Status: v.ioDetail(),
+		}},
+	}
+}
+
+// snapshot holds a simulated EBS snapshot.
+type snapshot struct {
+	ec2.Snapshot
+
+	// createVolumePerms holds the accounts other than the owner
+	// that may create volumes from the snapshot, and public
+	// whether all accounts may.
+	createVolumePerms map[string]bool
+	public            bool
+
+	// completeAt holds the time at which a pending snapshot is
+	// completed when the server has a clock.
+	completeAt time.Time
+}
+
+func (s *snapshot) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "description":
+		return s.Description == value, nil
+	case "owner-id":
+		return s.OwnerId == value, nil
+	case "progress":
+		return s.Progress == value, nil
+	case "snapshot-id":
+		return s.Id == value, nil
+	case "start-time":
+		return s.StartTime == value, nil
+	case "status":
+		return s.Status == value, nil
+	case "volume-id":
+		return s.VolumeId == value, nil
+	case "volume-size":
+		return s.VolumeSize == value, nil
+	case "encrypted":
+		return matchBool(attr, value, s.Encrypted)
+	case "kms-key-id":
+		return s.KMSKeyId == value, nil
+	case "owner-alias", "tag":
+		return false, fmt.Errorf("%q filter is not implemented", attr)
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// volumeTypes holds the IOPS that can be provisioned for each
+// volume type; zero means the type does not take IOPS.
+var volumeTypes = map[string]int64{
+	"standard": 0,
+	"gp2":      0,
+	"io1":      20000,
+	"st1":      0,
+	"sc1":      0,
+}
+
+// checkVolumeType checks the type and IOPS requested for a volume,
+// and returns the type to use.
+func checkVolumeType(volType string, iops int64) string {
+	if volType == "" {
+		volType = "standard"
+	}
+	maxIOPS, ok := volumeTypes[volType]
+	if !ok {
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter volumeType is invalid.", volType)
+	}
+	switch {
+	case maxIOPS == 0 && iops != 0:
+		fatalf(400, "InvalidParameterCombination", "The parameter iops is not supported for %s volumes.", volType)
+	case maxIOPS != 0 && iops == 0:
+		fatalf(400, "MissingParameter", "The request must contain the parameter iops")
+	case iops > maxIOPS:
+		fatalf(400, "InvalidParameterValue", "Volume iops of %d is too high; maximum is %d.", iops, maxIOPS)
+	}
+	return volType
+}
+
+// parseIOPS returns the value of the given IOPS form field,
+// or zero if it is not present.
+func parseIOPS(form url.Values, field string) int64 {
+	val := form.Get(field)
+	if val == "" {
+		return 0
+	}
+	iops, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || iops <= 0 {
+		fatalf(400, "InvalidParameterValue", "bad iops value %q", val)
+	}
+	return iops
+}
+
+// newVolume creates a volume in the given availability zone,
+// optionally from a snapshot, and adds it to the server. It
+// must be called with srv.mu held.
+func (srv *Server) newVolume(availZone string, size int, snapshotId, volType string, iops int64, encrypted bool, kmsKeyId string) *volume {
+	if snapshotId != "" {
+		snap := srv.snapshots[snapshotId]
+		if snap == nil {
+			fatalf(400, "InvalidSnapshot.NotFound", "The snapshot '%s' does not exist.", snapshotId)
+		}
+		srv.completeSnapshot(snap)
+		if snap.Status != "completed" {
+			fatalf(400, "IncorrectState", "Snapshot is in invalid state - %s", snap.Status)
+		}
+		snapSize, _ := strconv.Atoi(snap.VolumeSize)
+		if size == 0 {
+			size = snapSize
+		} else if size < snapSize {
+			fatalf(400, "InvalidParameterValue", "Volume of %dGiB is smaller than snapshot '%s', expect size >= %dGiB", size, snapshotId, snapSize)
+		}
+		// Volumes created from encrypted snapshots are encrypted.
+		encrypted = encrypted || snap.Encrypted
+	} else if size == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter size/snapshot")
+	}
+	if size < 1 || size > 16384 {
+		fatalf(400, "InvalidParameterValue", "Volume size %d is out of range", size)
+	}
+	if kmsKeyId != "" && !encrypted {
+		fatalf(400, "InvalidParameterDependency", "The parameter KmsKeyId requires the parameter Encrypted to be set.")
+	}
+	v := &volume{
+		Volume: ec2.Volume{
+			Id:         fmt.Sprintf("vol-%d", srv.volumeId.next()),
+			Size:       size,
+			SnapshotId: snapshotId,
+			AvailZone:  availZone,
+			Status:     "available",
+			CreateTime: time.Now().Format(time.RFC3339),
+			VolumeType: volType,
+			IOPS:       iops,
+			Encrypted:  encrypted,
+			KMSKeyId:   kmsKeyId,
+		},
+		ioStatus: "ok",
+	}
+	srv.volumes[v.Id] = v
+	return v
+}
+
+// attachVolume attaches v to inst as the given device. It must
+// be called with srv.mu held.
+func (inst *Instance) attachVolume(v *volume, device string, deleteOnTermination bool) {
+	for _, other := range inst.volumes {
+		if other.Attachments[0].Device == device {
+			fatalf(400, "InvalidParameterValue", "Attachment point %s is already in use", device)
+		}
+	}
+	v.Status = "in-use"
+	v.Attachments = []ec2.VolumeAttachment{{
+		VolumeId:            v.Id,
+		InstanceId:          inst.id(),
+		Device:              device,
+		Status:              "attached",
+		AttachTime:          time.Now().Format(time.RFC3339),
+		DeleteOnTermination: deleteOnTermination,
+	}}
+	inst.volumes = append(inst.volumes, v)
+}
+
+// detachVolume detaches v from inst, and returns the attachment.
+// It must be called with srv.mu held.
+func (inst *Instance) detachVolume(v *volume) ec2.VolumeAttachment {
+	att := v.Attachments[0]
+	for i, other := range inst.volumes {
+		if other == v {
+			inst.volumes = append(inst.volumes[:i], inst.volumes[i+1:]...)
+			break
+		}
+	}
+	v.Status = "available"
+	v.Attachments = nil
+	return att
+}
+
+// releaseVolumes detaches the volumes of an instance being
+// terminated, deleting those that are marked to be deleted on
+// termination. It must be called with srv.mu held.
+func (srv *Server) releaseVolumes(inst *Instance) {
+	for len(inst.volumes) > 0 {
+		v := inst.volumes[0]
+		if att := inst.detachVolume(v); att.DeleteOnTermination {
+			delete(srv.volumes, v.Id)
+		}
+	}
+}
+
+// parseBlockDeviceMappings parses the EBS block device mappings
+// passed to RunInstances.
+func parseBlockDeviceMappings(form url.Values) []ec2.BlockDeviceMapping {
+	mappings := make(map[int]ec2.BlockDeviceMapping)
+	for name, vals := range form {
+		var index int
+		var rest string
+		if x, _ := fmt.Sscanf(name, "BlockDeviceMapping.%d.%s", &index, &rest); x != 2 {
+			continue
+		}
+		b := mappings[index]
+		val := vals[0]
+		switch rest {
+		case "DeviceName":
+			b.DeviceName = val
+		case "VirtualName":
+			b.VirtualName = val
+		case "Ebs.SnapshotId":
+			b.SnapshotId = val
+		case "Ebs.VolumeType":
+			b.VolumeType = val
+		case "Ebs.VolumeSize":
+			b.VolumeSize = int64(atoi(val))
+		case "Ebs.Iops":
+			b.IOPS = parseIOPS(form, name)
+		case "Ebs.DeleteOnTermination", "Ebs.Encrypted":
+			flag, err := strconv.ParseBool(val)
+			if err != nil {
+				fatalf(400, "InvalidParameterValue", "bad flag %s: %s", name, val)
+			}
+			if rest == "Ebs.Encrypted" {
+				b.Encrypted = flag
+			} else {
+				b.DeleteOnTermination = flag
+			}
+		default:
+			fatalf(400, "UnknownParameter", "unknown parameter %q", name)
+		}
+		mappings[index] = b
+	}
+	var result []ec2.BlockDeviceMapping
+	for i := 1; len(result) < len(mappings); i++ {
+		b, ok := mappings[i]
+		if !ok {
+			fatalf(400, "InvalidParameterValue", "missing block device mapping %d", i)
+		}
+		if b.DeviceName == "" {
+			fatalf(400, "MissingParameter", "The request must contain the parameter BlockDeviceMapping.%d.DeviceName", i)
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// createBlockDevices creates and attaches to inst a volume for each
+// of the given EBS block device mappings. It must be called with
+// srv.mu held.
+func (srv *Server) createBlockDevices(inst *Instance, mappings []ec2.BlockDeviceMapping) {
+	for _, b := range mappings {
+		if b.VirtualName != "" {
+			// Instance store volumes are not modelled.
+			continue
+		}
+		volType := checkVolumeType(b.VolumeType, b.IOPS)
+		v := srv.newVolume(inst.availZone, int(b.VolumeSize), b.SnapshotId, volType, b.IOPS, b.Encrypted, "")
+		inst.attachVolume(v, b.DeviceName, b.DeleteOnTermination)
+	}
+}
+
+// SetVolumeStatus sets the status reported for the volume with
+// the given id by DescribeVolumeStatus, which is one of "ok",
+// "impaired" or "insufficient-data".
+func (srv *Server) SetVolumeStatus(id, status string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	v := srv.volumes[id]
+	if v == nil {
+		panic(fmt.Errorf("volume %q not found", id))
+	}
+	v.ioStatus = status
+}
+
+func (srv *Server) createVolume(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	availZone := req.Form.Get("AvailabilityZone")
+	if availZone == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter AvailabilityZone")
+	}
+	size := 0
+	if val := req.Form.Get("Size"); val != "" {
+		size = atoi(val)
+	}
+	iops := parseIOPS(req.Form, "Iops")
+	volType := checkVolumeType(req.Form.Get("VolumeType"), iops)
+	encrypted := false
+	if val := req.Form.Get("Encrypted"); val != "" {
+		var err error
+		encrypted, err = strconv.ParseBool(val)
+		if err != nil {
+			fatalf(400, "InvalidParameterValue", "bad flag Encrypted: %s", val)
+		}
+	}
+	tagSpecs := parseTagSpecs(req.Form, "volume")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	found := false
+	for _, z := range srv.zones {
+		found = found || z.Name == availZone
+	}
+	if !found {
+		fatalf(400, "InvalidZone.NotFound", "The zone '%s' does not exist.", availZone)
+	}
+	v := srv.newVolume(availZone, size, req.Form.Get("SnapshotId"), volType, iops, encrypted, req.Form.Get("KmsKeyId"))
+	v.Tags = tagSpecs["volume"]
+	return &ec2.CreateVolumeResp{
+		RequestId: reqId,
+		Volume:    v.Volume,
+	}
+}
+
+func (srv *Server) deleteVolume(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.volume(req.Form.Get("VolumeId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if v.attached() {
+		fatalf(400, "VolumeInUse", "Volume %s is currently attached to %s", v.Id, v.Attachments[0].InstanceId)
+	}
+	delete(srv.volumes, v.Id)
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteVolumeResponse"},
+		RequestId: reqId,
+	}
+}
+
+// volumesFromForm returns the volumes with the ids given in the
+// form, or all volumes if there are none.
+// It must be called with srv.mu held.
+func (srv *Server) volumesFromForm(form url.Values) []*volume {
+	var vols []*volume
+	for id := range parseIDs(form, "VolumeId.") {
+		v := srv.volumes[id]
+		if v == nil {
+			fatalf(400, "InvalidVolume.NotFound", "The volume '%s' does not exist.", id)
+		}
+		vols = append(vols, v)
+	}
+	if len(vols) > 0 {
+		return vols
+	}
+	for _, v := range srv.volumes {
+		vols = append(vols, v)
+	}
+	return vols
+}
+
+func (srv *Server) describeVolumes(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	f := newFilter(req.Form)
+	var resp ec2.VolumesResp
+	resp.RequestId = reqId
+	for _, v := range srv.volumesFromForm(req.Form) {
+		ok, err := f.ok(v)
+		if ok {
+			resp.Volumes = append(resp.Volumes, v.Volume)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe volumes: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) describeVolumeStatus(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	f := newFilter(req.Form)
+	var resp ec2.VolumeStatusResp
+	resp.RequestId = reqId
+	for _, v := range srv.volumesFromForm(req.Form) {
+		ok, err := f.ok(volumeStatus{v})
+		if ok {
+			resp.Statuses = append(resp.Statuses, v.ec2status())
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe volume status: %v", err)
+		}
+	}
+	return &resp
+}
+
+func (srv *Server) attachVolume(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.volume(req.Form.Get("VolumeId"))
+	inst := srv.instance(req.Form.Get("InstanceId"))
+	device := req.Form.Get("Device")
+	if device == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter device")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if v.attached() {
+		fatalf(400, "VolumeInUse", "%s is already attached to an instance", v.Id)
+	}
+	if inst.state == ShuttingDown || inst.state == Terminated {
+		fatalf(400, "IncorrectState", "Instance '%s' is not 'running'.", inst.id())
+	}
+	if inst.availZone != "" && inst.availZone != v.AvailZone {
+		fatalf(400, "InvalidVolume.ZoneMismatch", "The volume '%s' is not in the same availability zone as instance '%s'", v.Id, inst.id())
+	}
+	inst.attachVolume(v, device, false)
+	resp := &ec2.VolumeAttachmentResp{
+		RequestId:        reqId,
+		VolumeAttachment: v.Attachments[0],
+	}
+	resp.Status = "attaching"
+	return resp
+}
+
+func (srv *Server) detachVolume(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.volume(req.Form.Get("VolumeId"))
+	instId := req.Form.Get("InstanceId")
+	device := req.Form.Get("Device")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !v.attached() {
+		fatalf(400, "IncorrectState", "Volume '%s' is in the 'available' state.", v.Id)
+	}
+	att := v.Attachments[0]
+	if instId != "" && instId != att.InstanceId || device != "" && device != att.Device {
+		fatalf(400, "InvalidAttachment.NotFound", "The volume '%s' is not attached to the specified instance or device", v.Id)
+	}
+	srv.instances[att.InstanceId].detachVolume(v)
+	att.Status = "detaching"
+	return &ec2.VolumeAttachmentResp{
+		RequestId:        reqId,
+		VolumeAttachment: att,
+	}
+}
+
+func (srv *Server) modifyVolume(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.volume(req.Form.Get("VolumeId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	mod := ec2.VolumeModification{
+		VolumeId:           v.Id,
+		OriginalSize:       v.Size,
+		OriginalIOPS:       v.IOPS,
+		OriginalVolumeType: v.VolumeType,
+		TargetSize:         v.Size,
+		TargetIOPS:         v.IOPS,
+		TargetVolumeType:   v.VolumeType,
+	}
+	if val := req.Form.Get("Size"); val != "" {
+		mod.TargetSize = atoi(val)
+		if mod.TargetSize < v.Size {
+			fatalf(400, "InvalidParameterValue", "New size cannot be smaller than existing size")
+		}
+	}
+	if volType := req.Form.Get("VolumeType"); volType != "" {
+		mod.TargetVolumeType = volType
+		if volumeTypes[volType] == 0 {
+			// IOPS are not retained when moving to a type that
+			// does not provision them.
+			mod.TargetIOPS = 0
+		}
+	}
+	if iops := parseIOPS(req.Form, "Iops"); iops != 0 {
+		mod.TargetIOPS = iops
+	}
+	checkVolumeType(mod.TargetVolumeType, mod.TargetIOPS)
+	if mod.TargetSize == v.Size && mod.TargetIOPS == v.IOPS && mod.TargetVolumeType == v.VolumeType {
+		fatalf(400, "InvalidParameterCombination", "New configuration is the same as the existing one")
+	}
+
+	// Modifications take effect immediately.
+	now := time.Now().Format(time.RFC3339)
+	mod.ModificationState = "completed"
+	mod.Progress = 100
+	mod.StartTime = now
+	mod.EndTime = now
+	v.Size = mod.TargetSize
+	v.IOPS = mod.TargetIOPS
+	v.VolumeType = mod.TargetVolumeType
+	return &ec2.ModifyVolumeResp{
+		RequestId:    reqId,
+		Modification: mod,
+	}
+}
+
+// newSnapshot creates a pending snapshot of v with the given
+// description, and adds it to the server.
+// It must be called with srv.mu held.
+func (srv *Server) newSnapshot(v *volume, description string) *snapshot {
+	return srv.addSnapshot(ec2.Snapshot{
+		VolumeId:    v.Id,
+		VolumeSize:  strconv.Itoa(v.Size),
+		Description: description,
+		Encrypted:   v.Encrypted,
+		KMSKeyId:    v.KMSKeyId,
+	})
+}
+
+// newSnapshotCopy creates a pending copy of src with the given
+// description, and adds it to the server. The copy is encrypted if
+// src is or encrypted is set, with the given KMS key if any.
+// It must be called with srv.mu held.
+func (srv *Server) newSnapshotCopy(src *snapshot, description string, encrypted bool, kmsKeyId string) *snapshot {
+	if kmsKeyId == "" {
+		kmsKeyId = src.KMSKeyId
+	}
+	return srv.addSnapshot(ec2.Snapshot{
+		VolumeId:    src.VolumeId,
+		VolumeSize:  src.VolumeSize,
+		Description: description,
+		Encrypted:   src.Encrypted || encrypted,
+		KMSKeyId:    kmsKeyId,
+	})
+}
+
+// addSnapshot adds a new pending snapshot with the details of snap
+// to the server, and returns it.
+// It must be called with srv.mu held.
+func (srv *Server) addSnapshot(snap ec2.Snapshot) *snapshot {
+	snap.Id = fmt.Sprintf("snap-%d", srv.snapshotId.next())
+	snap.Status = "pending"
+	snap.Progress = "0%"
+	snap.StartTime = srv.now().Format(time.RFC3339)
+	snap.OwnerId = ownerId
+	s := &snapshot{
+		Snapshot:          snap,
+		createVolumePerms: make(map[string]bool),
+	}
+	if srv.clock != nil {
+		s.completeAt = srv.clock.Now().Add(srv.transitionDelay)
+	}
+	srv.snapshots[s.Id] = s
+	return s
+}
+
+// completeSnapshot completes snap if it is pending and its transition
+// delay has passed on the clock of the server, or if the server has
+// no clock. Otherwise, it updates the progress of snap.
+// It must be called with srv.mu held.
+func (srv *Server) completeSnapshot(snap *snapshot) {
+	if snap.Status != "pending" {
+		return
+	}
+	if srv.clock != nil {
+		if remaining := snap.completeAt.Sub(srv.clock.Now()); remaining > 0 {
+			progress := 0
+			if srv.transitionDelay > remaining {
+				progress = 100 - int(100*remaining/srv.transitionDelay)
+			}
+			snap.Progress = fmt.Sprintf("%d%%", progress)
+			return
+		}
+	}
+	snap.Status = "completed"
+	snap.Progress = "100%"
+}
+
+func (srv *Server) createSnapshot(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.volume(req.Form.Get("VolumeId"))
+	tagSpecs := parseTagSpecs(req.Form, "snapshot")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	s := srv.newSnapshot(v, req.Form.Get("Description"))
+	s.Tags = tagSpecs["snapshot"]
+	return &ec2.CreateSnapshotResp{
+		RequestId: reqId,
+		Snapshot:  s.Snapshot,
+	}
+}
+
+func (srv *Server) deleteSnapshot(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	ids := parseIDs(req.Form, "SnapshotId.")
+	if len(ids) == 0 {
+		fatalf(400, "MissingParameter", "The request must contain the parameter snapshotId")
+	}
+	for id := range ids {
+		if srv.snapshots[id] == nil {
+			fatalf(400, "InvalidSnapshot.NotFound", "The snapshot '%s' does not exist.", id)
+		}
+		for _, img := range srv.images {
+			if img.hasBlockDevice(func(b ec2.BlockDeviceMapping) bool { return b.SnapshotId == id }) {
+				fatalf(400, "InvalidSnapshot.InUse", "The snapshot %s is currently in use by %s", id, img.Id)
+			}
+		}
+	}
+	for id := range ids {
+		delete(srv.snapshots, id)
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteSnapshotResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describeSnapshots(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var snaps []*snapshot
+	for id := range parseIDs(req.Form, "SnapshotId.") {
+		s := srv.snapshots[id]
+		if s == nil {
+			fatalf(400, "InvalidSnapshot.NotFound", "The snapshot '%s' does not exist.", id)
+		}
+		snaps = append(snaps, s)
+	}
+	if len(snaps) == 0 {
+		for _, s := range srv.snapshots {
+			snaps = append(snaps, s)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.SnapshotsResp
+	resp.RequestId = reqId
+	for _, s := range snaps {
+		srv.completeSnapshot(s)
+		ok, err := f.ok(s)
+		if ok {
+			resp.Snapshots = append(resp.Snapshots, s.Snapshot)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe snapshots: %v", err)
+		}
+	}
+	sort.Slice(resp.Snapshots, func(i, j int) bool { return resp.Snapshots[i].Id < resp.Snapshots[j].Id })
+	start, end, nextToken := page(req.Form, len(resp.Snapshots))
+	resp.Snapshots, resp.NextToken = resp.Snapshots[start:end], nextToken
+	return &resp
+}
+
+func (srv *Server) volume(id string) *volume {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing volumeId")
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	v, found := srv.volumes[id]
+	if !found {
+		fatalf(400, "InvalidVolume.NotFound", "The volume '%s' does not exist.", id)
+	}
+	return v
+}
+
+// blockDeviceMappings returns the block device mappings describing the
+// volumes attached to inst.
+func (inst *Instance) blockDeviceMappings() []ec2.InstanceBlockDeviceMapping {
+	var mappings []ec2.InstanceBlockDeviceMapping
+	for _, v := range inst.volumes {
+		att := v.Attachments[0]
+		mappings = append(mappings, ec2.InstanceBlockDeviceMapping{
+			DeviceName:          att.Device,
+			VolumeId:            v.Id,
+			Status:              att.Status,
+			AttachTime:          att.AttachTime,
+			DeleteOnTermination: att.DeleteOnTermination,
+		})
+	}
+	return mappings
+}
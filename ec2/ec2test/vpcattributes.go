@@ -0,0 +1,252 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// maxVPCCIDRBlocks holds the maximum number of IPv4 CIDR blocks
+// associated with a VPC.
+const maxVPCCIDRBlocks = 5
+
+// cidrContains reports whether the CIDR block inner lies within the
+// CIDR block outer.
+func cidrContains(outer, inner string) bool {
+	_, outerNet, errOuter := net.ParseCIDR(outer)
+	_, innerNet, errInner := net.ParseCIDR(inner)
+	if errOuter != nil || errInner != nil {
+		return false
+	}
+	outerOnes, outerBits := outerNet.Mask.Size()
+	innerOnes, innerBits := innerNet.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outerNet.Contains(innerNet.IP)
+}
+
+// checkSubnetCIDRBlock fails unless the IPv4 CIDR block cidrBlock
+// lies within a CIDR block of the VPC v and does not overlap the
+// CIDR block of any of its subnets. It must be called with srv.mu
+// held.
+func (srv *Server) checkSubnetCIDRBlock(v *vpc, cidrBlock string) {
+	inVPC := false
+	for _, b := range v.CIDRBlocks {
+		if b.State == "associated" && cidrContains(b.CIDRBlock, cidrBlock) {
+			inVPC = true
+		}
+	}
+	if !inVPC {
+		fatalf(400, "InvalidSubnet.Range", "The CIDR '%s' is invalid.", cidrBlock)
+	}
+	for _, s := range srv.subnets {
+		if s.VPCId == v.Id && cidrsOverlap(s.CIDRBlock, cidrBlock) {
+			fatalf(400, "InvalidSubnet.Conflict", "The CIDR '%s' conflicts with another subnet", cidrBlock)
+		}
+	}
+}
+
+// checkVPCCIDRBlock fails unless the IPv4 CIDR block cidrBlock can
+// be associated with the VPC v. It must be called with srv.mu held.
+func checkVPCCIDRBlock(v *vpc, cidrBlock string) {
+	_, ipnet, _ := net.ParseCIDR(cidrBlock)
+	if ones, _ := ipnet.Mask.Size(); ipnet.IP.To4() == nil || ones < 16 || ones > 28 {
+		fatalf(400, "InvalidVpc.Range", "The CIDR '%s' is invalid.", cidrBlock)
+	}
+	associated := 0
+	for _, b := range v.CIDRBlocks {
+		if b.State != "associated" {
+			continue
+		}
+		if cidrsOverlap(b.CIDRBlock, cidrBlock) {
+			fatalf(400, "CidrConflict", "The CIDR '%s' conflicts with CIDR '%s' of the VPC '%s'.", cidrBlock, b.CIDRBlock, v.Id)
+		}
+		associated++
+	}
+	if associated >= maxVPCCIDRBlocks {
+		fatalf(400, "CidrLimitExceeded", "This network '%s' has met its maximum number of allowed CIDRs: %d", v.Id, maxVPCCIDRBlocks)
+	}
+}
+
+func (srv *Server) associateVpcCidrBlock(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.vpc(req.Form.Get("VpcId"))
+	cidrBlock := req.Form.Get("CidrBlock")
+	amazonIPv6 := parseBoolParam(req.Form, "AmazonProvidedIpv6CidrBlock")
+	switch {
+	case cidrBlock == "" && !amazonIPv6:
+		fatalf(400, "MissingParameter", "Either the parameter CidrBlock or AmazonProvidedIpv6CidrBlock must be specified")
+	case cidrBlock != "" && amazonIPv6:
+		fatalf(400, "InvalidParameterCombination", "Only one of CidrBlock and AmazonProvidedIpv6CidrBlock may be specified")
+	case cidrBlock != "":
+		cidrBlock = parseCidr(cidrBlock)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	resp := &ec2.AssociateVPCCIDRBlockResp{
+		RequestId: reqId,
+		VPCId:     v.Id,
+	}
+	if cidrBlock != "" {
+		checkVPCCIDRBlock(v, cidrBlock)
+		assoc := ec2.CIDRBlockAssociation{
+			AssociationId: fmt.Sprintf("vpc-cidr-assoc-%d", srv.cidrAssocId.next()),
+			CIDRBlock:     cidrBlock,
+			State:         "associated",
+		}
+		v.CIDRBlocks = append(v.CIDRBlocks, assoc)
+		resp.CIDRBlockAssociation = assoc
+		return resp
+	}
+	for _, b := range v.IPv6CIDRBlocks {
+		if b.State == "associated" {
+			fatalf(400, "CidrLimitExceeded", "This network '%s' has met its maximum number of allowed CIDRs: 1", v.Id)
+		}
+	}
+	assoc := ec2.IPv6CIDRBlockAssociation{
+		AssociationId: fmt.Sprintf("vpc-cidr-assoc-%d", srv.cidrAssocId.next()),
+		IPv6CIDRBlock: srv.newAmazonIPv6Block(),
+		State:         "associated",
+	}
+	v.IPv6CIDRBlocks = append(v.IPv6CIDRBlocks, assoc)
+	resp.IPv6CIDRBlockAssociation = assoc
+	return resp
+}
+
+func (srv *Server) disassociateVpcCidrBlock(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	id := req.Form.Get("AssociationId")
+	if id == "" {
+		fatalf(400, "MissingParameter", "The request must contain the parameter AssociationId")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, v := range srv.vpcs {
+		for i, b := range v.CIDRBlocks {
+			if b.AssociationId != id {
+				continue
+			}
+			if b.CIDRBlock == v.CIDRBlock {
+				fatalf(400, "OperationNotPermitted", "The vpc '%s' has a primary CIDR '%s' which cannot be disassociated.", v.Id, b.CIDRBlock)
+			}
+			for _, s := range srv.subnets {
+				if s.VPCId == v.Id && cidrContains(b.CIDRBlock, s.CIDRBlock) {
+					fatalf(400, "DependencyViolation", "The CIDR '%s' of the vpc '%s' is in use by the subnet '%s'.", b.CIDRBlock, v.Id, s.Id)
+				}
+			}
+			v.CIDRBlocks = append(v.CIDRBlocks[:i], v.CIDRBlocks[i+1:]...)
+			b.State = "disassociating"
+			return &ec2.DisassociateVPCCIDRBlockResp{
+				RequestId:            reqId,
+				VPCId:                v.Id,
+				CIDRBlockAssociation: b,
+			}
+		}
+		for i, b := range v.IPv6CIDRBlocks {
+			if b.AssociationId != id {
+				continue
+			}
+			for _, s := range srv.subnets {
+				if block := s.ipv6Block(); s.VPCId == v.Id && block != nil && cidrContains(b.IPv6CIDRBlock, block.String()) {
+					fatalf(400, "DependencyViolation", "The CIDR '%s' of the vpc '%s' is in use by the subnet '%s'.", b.IPv6CIDRBlock, v.Id, s.Id)
+				}
+			}
+			v.IPv6CIDRBlocks = append(v.IPv6CIDRBlocks[:i], v.IPv6CIDRBlocks[i+1:]...)
+			b.State = "disassociating"
+			return &ec2.DisassociateVPCCIDRBlockResp{
+				RequestId:                reqId,
+				VPCId:                    v.Id,
+				IPv6CIDRBlockAssociation: b,
+			}
+		}
+	}
+	fatalf(400, "InvalidVpcCidrBlockAssociationID.NotFound", "The association '%s' does not exist.", id)
+	return nil
+}
+
+// vpcAttributeParams maps the parameters of ModifyVpcAttribute to
+// the attributes they modify.
+var vpcAttributeParams = map[string]string{
+	"EnableDnsSupport.Value":   "enableDnsSupport",
+	"EnableDnsHostnames.Value": "enableDnsHostnames",
+}
+
+func (srv *Server) describeVpcAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.vpc(req.Form.Get("VpcId"))
+	attr := req.Form.Get("Attribute")
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	resp := &ec2.VPCAttributeResp{
+		RequestId: reqId,
+		VPCId:     v.Id,
+	}
+	switch attr {
+	case "enableDnsSupport":
+		resp.EnableDNSSupport = v.dnsSupport
+	case "enableDnsHostnames":
+		resp.EnableDNSHostnames = v.dnsHostnames
+	case "":
+		fatalf(400, "MissingParameter", "The request must contain the parameter Attribute")
+	default:
+		fatalf(400, "InvalidParameterValue", "Value (%s) for parameter attribute is invalid. Unknown attribute.", attr)
+	}
+	return resp
+}
+
+func (srv *Server) modifyVpcAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	attr := singleAttribute(req, vpcAttributeParams)
+	v := srv.vpc(req.Form.Get("VpcId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	switch attr {
+	case "enableDnsSupport":
+		v.dnsSupport = parseBoolParam(req.Form, "EnableDnsSupport.Value")
+	case "enableDnsHostnames":
+		hostnames := parseBoolParam(req.Form, "EnableDnsHostnames.Value")
+		if hostnames && !v.dnsSupport {
+			fatalf(400, "InvalidParameterValue", "DNS hostnames cannot be enabled for the vpc '%s' without DNS support.", v.Id)
+		}
+		v.dnsHostnames = hostnames
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "ModifyVpcAttributeResponse"},
+		RequestId: reqId,
+	}
+}
+
+// subnetAttributeParams maps the parameters of ModifySubnetAttribute
+// to the attributes they modify.
+var subnetAttributeParams = map[string]string{
+	"MapPublicIpOnLaunch.Value":         "mapPublicIpOnLaunch",
+	"AssignIpv6AddressOnCreation.Value": "assignIpv6AddressOnCreation",
+}
+
+func (srv *Server) modifySubnetAttribute(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	attr := singleAttribute(req, subnetAttributeParams)
+	s := srv.subnet(req.Form.Get("SubnetId"))
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	switch attr {
+	case "mapPublicIpOnLaunch":
+		s.MapPublicIPOnLaunch = parseBoolParam(req.Form, "MapPublicIpOnLaunch.Value")
+	case "assignIpv6AddressOnCreation":
+		assign := parseBoolParam(req.Form, "AssignIpv6AddressOnCreation.Value")
+		if assign && s.ipv6Block() == nil {
+			fatalf(400, "InvalidParameterValue", "The subnet '%s' does not have an IPv6 CIDR block", s.Id)
+		}
+		s.AssignIPv6AddressOnCreation = assign
+	}
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "ModifySubnetAttributeResponse"},
+		RequestId: reqId,
+	}
+}
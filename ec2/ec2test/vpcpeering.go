@@ -0,0 +1,199 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package ec2test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// vpcPeering holds a simulated ec2 VPC peering connection.
+type vpcPeering struct {
+	ec2.VPCPeeringConnection
+}
+
+func (p *vpcPeering) matchAttr(attr, value string) (ok bool, err error) {
+	switch attr {
+	case "vpc-peering-connection-id":
+		return p.Id == value, nil
+	case "status-code":
+		return p.Status.Code == value, nil
+	case "requester-vpc-info.vpc-id":
+		return p.RequesterVPC.VPCId == value, nil
+	case "requester-vpc-info.owner-id":
+		return p.RequesterVPC.OwnerId == value, nil
+	case "requester-vpc-info.cidr-block":
+		return p.RequesterVPC.CIDRBlock == value, nil
+	case "accepter-vpc-info.vpc-id":
+		return p.AccepterVPC.VPCId == value, nil
+	case "accepter-vpc-info.owner-id":
+		return p.AccepterVPC.OwnerId == value, nil
+	case "accepter-vpc-info.cidr-block":
+		return p.AccepterVPC.CIDRBlock == value, nil
+	}
+	return false, fmt.Errorf("unknown attribute %q", attr)
+}
+
+// setStatus changes the status of p, failing unless it is currently
+// in one of the given states.
+func (p *vpcPeering) setStatus(code, message string, from ...string) {
+	for _, f := range from {
+		if p.Status.Code == f {
+			p.Status = ec2.VPCPeeringConnectionStatus{
+				Code:    code,
+				Message: message,
+			}
+			return
+		}
+	}
+	fatalf(400, "InvalidStateTransition", "Invalid state transition for pcx %s, attempted to transition from %s to %s", p.Id, p.Status.Code, code)
+}
+
+// peering returns the VPC peering connection with the given id.
+// It must be called with srv.mu held.
+func (srv *Server) peering(id string) *vpcPeering {
+	if id == "" {
+		fatalf(400, "MissingParameter", "missing vpcPeeringConnectionId")
+	}
+	p := srv.peerings[id]
+	if p == nil {
+		fatalf(400, "InvalidVpcPeeringConnectionID.NotFound", "The vpcPeeringConnection ID '%s' does not exist", id)
+	}
+	return p
+}
+
+// cidrsOverlap reports whether the given CIDR blocks overlap.
+func cidrsOverlap(a, b string) bool {
+	_, netA, errA := net.ParseCIDR(a)
+	_, netB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP)
+}
+
+func (srv *Server) createVpcPeeringConnection(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	v := srv.vpc(req.Form.Get("VpcId"))
+	peerVPCId := req.Form.Get("PeerVpcId")
+	if peerVPCId == "" {
+		fatalf(400, "MissingParameter", "missing peerVpcId")
+	}
+	peerOwnerId := req.Form.Get("PeerOwnerId")
+	if peerOwnerId == "" {
+		peerOwnerId = ownerId
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	p := &vpcPeering{ec2.VPCPeeringConnection{
+		Id: fmt.Sprintf("pcx-%d", srv.peeringId.next()),
+		RequesterVPC: ec2.VPCPeeringConnectionVPC{
+			VPCId:     v.Id,
+			OwnerId:   ownerId,
+			CIDRBlock: v.CIDRBlock,
+		},
+		AccepterVPC: ec2.VPCPeeringConnectionVPC{
+			VPCId:   peerVPCId,
+			OwnerId: peerOwnerId,
+		},
+		Status: ec2.VPCPeeringConnectionStatus{
+			Code:    "pending-acceptance",
+			Message: "Pending Acceptance by " + peerOwnerId,
+		},
+		ExpirationTime: time.Now().Add(7 * 24 * time.Hour).Format(time.RFC3339),
+	}}
+	// Only VPCs of the same account are known to the server.
+	if peerOwnerId == ownerId {
+		peer := srv.vpcs[peerVPCId]
+		if peer == nil {
+			fatalf(400, "InvalidVpcID.NotFound", "The vpc ID '%s' does not exist", peerVPCId)
+		}
+		p.AccepterVPC.CIDRBlock = peer.CIDRBlock
+		if cidrsOverlap(v.CIDRBlock, peer.CIDRBlock) {
+			p.Status = ec2.VPCPeeringConnectionStatus{
+				Code:    "failed",
+				Message: "Overlapping CIDR range",
+			}
+		}
+	}
+	srv.peerings[p.Id] = p
+	return &ec2.CreateVPCPeeringConnectionResp{
+		RequestId:            reqId,
+		VPCPeeringConnection: p.VPCPeeringConnection,
+	}
+}
+
+func (srv *Server) acceptVpcPeeringConnection(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	p := srv.peering(req.Form.Get("VpcPeeringConnectionId"))
+	if p.AccepterVPC.OwnerId != ownerId {
+		fatalf(400, "OperationNotPermitted", "User %s cannot accept peering %s", ownerId, p.Id)
+	}
+	p.setStatus("active", "Active", "pending-acceptance")
+	return &ec2.AcceptVPCPeeringConnectionResp{
+		RequestId:            reqId,
+		VPCPeeringConnection: p.VPCPeeringConnection,
+	}
+}
+
+func (srv *Server) rejectVpcPeeringConnection(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	p := srv.peering(req.Form.Get("VpcPeeringConnectionId"))
+	if p.AccepterVPC.OwnerId != ownerId {
+		fatalf(400, "OperationNotPermitted", "User %s cannot reject peering %s", ownerId, p.Id)
+	}
+	p.setStatus("rejected", "Rejected by "+ownerId, "pending-acceptance")
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "RejectVpcPeeringConnectionResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) deleteVpcPeeringConnection(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	p := srv.peering(req.Form.Get("VpcPeeringConnectionId"))
+	p.setStatus("deleted", "Deleted by "+ownerId, "active", "pending-acceptance")
+	return &ec2.SimpleResp{
+		XMLName:   xml.Name{"", "DeleteVpcPeeringConnectionResponse"},
+		RequestId: reqId,
+	}
+}
+
+func (srv *Server) describeVpcPeeringConnections(w http.ResponseWriter, req *http.Request, reqId string) interface{} {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var peerings []*vpcPeering
+	for id := range parseIDs(req.Form, "VpcPeeringConnectionId.") {
+		peerings = append(peerings, srv.peering(id))
+	}
+	if len(peerings) == 0 {
+		for _, p := range srv.peerings {
+			peerings = append(peerings, p)
+		}
+	}
+
+	f := newFilter(req.Form)
+	var resp ec2.VPCPeeringConnectionsResp
+	resp.RequestId = reqId
+	for _, p := range peerings {
+		ok, err := f.ok(p)
+		if ok {
+			resp.VPCPeeringConnections = append(resp.VPCPeeringConnections, p.VPCPeeringConnection)
+		} else if err != nil {
+			fatalf(400, "InvalidParameterValue", "describe VPC peering connections: %v", err)
+		}
+	}
+	return &resp
+}
@@ -7,6 +7,8 @@ package ec2
 
 import (
 	"time"
+
+	"gopkg.in/amz.v1/aws"
 )
 
 func fixedTime() time.Time {
@@ -22,3 +24,13 @@ func FakeTime(fakeIt bool) {
 }
 
 var PrepareRunParams = prepareRunParams
+
+var originalStrategy = attempts
+
+func SetAttemptStrategy(s *aws.AttemptStrategy) {
+	if s == nil {
+		attempts = originalStrategy
+	} else {
+		attempts = *s
+	}
+}
@@ -0,0 +1,294 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// CreateImage holds the options for a CreateImage request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateImage.html for more details.
+type CreateImage struct {
+	InstanceId  string
+	Name        string
+	Description string
+
+	// NoReboot prevents EC2 from shutting down the instance before
+	// creating the image, at the cost of file system integrity.
+	NoReboot bool
+
+	// BlockDeviceMappings holds the block devices to add to, or
+	// override in, the image, in addition to the EBS volumes
+	// attached to the instance.
+	BlockDeviceMappings []BlockDeviceMapping
+}
+
+// CreateImageResp is the response to a CreateImage request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateImage.html for more details.
+type CreateImageResp struct {
+	RequestId string `xml:"requestId"`
+	ImageId   string `xml:"imageId"`
+}
+
+// CreateImage creates an EBS-backed image from an instance that is
+// either running or stopped. The image is "pending" until the
+// snapshots of its volumes are completed; see WaitImageAvailable.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateImage.html for more details.
+func (ec2 *EC2) CreateImage(options *CreateImage) (resp *CreateImageResp, err error) {
+	params := makeParamsCurrent("CreateImage")
+	params["InstanceId"] = options.InstanceId
+	params["Name"] = options.Name
+	if options.Description != "" {
+		params["Description"] = options.Description
+	}
+	if options.NoReboot {
+		params["NoReboot"] = "true"
+	}
+	prepareBlockDevices(params, "", options.BlockDeviceMappings)
+
+	resp = &CreateImageResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RegisterImage holds the options for a RegisterImage request.
+// An EBS-backed image is registered from snapshots, by giving a
+// block device mapping for RootDeviceName; an instance store backed
+// image is registered from the manifest at ImageLocation.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RegisterImage.html for more details.
+type RegisterImage struct {
+	Name                string
+	Description         string
+	ImageLocation       string
+	Architecture        string // "i386" or "x86_64".
+	KernelId            string
+	RamdiskId           string
+	RootDeviceName      string
+	VirtualizationType  string // "paravirtual" or "hvm".
+	SriovNetSupport     string
+	EnaSupport          bool
+	BlockDeviceMappings []BlockDeviceMapping
+}
+
+// RegisterImageResp is the response to a RegisterImage request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RegisterImage.html for more details.
+type RegisterImageResp struct {
+	RequestId string `xml:"requestId"`
+	ImageId   string `xml:"imageId"`
+}
+
+// RegisterImage registers a new image.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RegisterImage.html for more details.
+func (ec2 *EC2) RegisterImage(options *RegisterImage) (resp *RegisterImageResp, err error) {
+	params := makeParamsCurrent("RegisterImage")
+	params["Name"] = options.Name
+	if options.Description != "" {
+		params["Description"] = options.Description
+	}
+	if options.ImageLocation != "" {
+		params["ImageLocation"] = options.ImageLocation
+	}
+	if options.Architecture != "" {
+		params["Architecture"] = options.Architecture
+	}
+	if options.KernelId != "" {
+		params["KernelId"] = options.KernelId
+	}
+	if options.RamdiskId != "" {
+		params["RamdiskId"] = options.RamdiskId
+	}
+	if options.RootDeviceName != "" {
+		params["RootDeviceName"] = options.RootDeviceName
+	}
+	if options.VirtualizationType != "" {
+		params["VirtualizationType"] = options.VirtualizationType
+	}
+	if options.SriovNetSupport != "" {
+		params["SriovNetSupport"] = options.SriovNetSupport
+	}
+	if options.EnaSupport {
+		params["EnaSupport"] = "true"
+	}
+	prepareBlockDevices(params, "", options.BlockDeviceMappings)
+
+	resp = &RegisterImageResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CopyImage holds the options for a CopyImage request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopyImage.html for more details.
+type CopyImage struct {
+	SourceRegion  string
+	SourceImageId string
+	Name          string
+	Description   string
+
+	// Encrypted requests the snapshots of the copy to be
+	// encrypted, with the KMSKeyId key if it is set, or with the
+	// default key for EBS otherwise.
+	Encrypted bool
+	KMSKeyId  string
+
+	// ClientToken, if set, makes the request idempotent.
+	ClientToken string
+}
+
+// CopyImageResp is the response to a CopyImage request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopyImage.html for more details.
+type CopyImageResp struct {
+	RequestId string `xml:"requestId"`
+	ImageId   string `xml:"imageId"`
+}
+
+// CopyImage copies an image from the source region to the region
+// of ec2. The copy is "pending" until its snapshots are copied; see
+// WaitImageAvailable.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopyImage.html for more details.
+func (ec2 *EC2) CopyImage(options *CopyImage) (resp *CopyImageResp, err error) {
+	params := makeParamsCurrent("CopyImage")
+	params["SourceRegion"] = options.SourceRegion
+	params["SourceImageId"] = options.SourceImageId
+	params["Name"] = options.Name
+	if options.Description != "" {
+		params["Description"] = options.Description
+	}
+	if options.Encrypted {
+		params["Encrypted"] = "true"
+	}
+	if options.KMSKeyId != "" {
+		params["KmsKeyId"] = options.KMSKeyId
+	}
+	if options.ClientToken != "" {
+		params["ClientToken"] = options.ClientToken
+	}
+
+	resp = &CopyImageResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeregisterImage deregisters the image with the given id, which can
+// no longer be launched. The snapshots of the image are not deleted.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeregisterImage.html for more details.
+func (ec2 *EC2) DeregisterImage(id string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("DeregisterImage")
+	params["ImageId"] = id
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LaunchPermission allows an AWS account, identified by UserId, or
+// all accounts, when Group is "all", to launch an image.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_LaunchPermission.html for more details.
+type LaunchPermission struct {
+	UserId string `xml:"userId"`
+	Group  string `xml:"group"`
+}
+
+// ImageAttributeResp is the response to an ImageAttribute request.
+// Only the field of the requested attribute is set.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeImageAttribute.html for more details.
+type ImageAttributeResp struct {
+	RequestId         string               `xml:"requestId"`
+	ImageId           string               `xml:"imageId"`
+	LaunchPermissions []LaunchPermission   `xml:"launchPermission>item"`
+	Description       string               `xml:"description>value"`
+	KernelId          string               `xml:"kernel>value"`
+	RamdiskId         string               `xml:"ramdisk>value"`
+	BlockDevices      []BlockDeviceMapping `xml:"blockDeviceMapping>item"`
+}
+
+// ImageAttribute describes an attribute of the image with the given
+// id: one of "launchPermission", "description", "kernel", "ramdisk"
+// and "blockDeviceMapping".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeImageAttribute.html for more details.
+func (ec2 *EC2) ImageAttribute(id, attribute string) (resp *ImageAttributeResp, err error) {
+	params := makeParamsCurrent("DescribeImageAttribute")
+	params["ImageId"] = id
+	params["Attribute"] = attribute
+
+	resp = &ImageAttributeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ModifyImageAttribute holds the changes of a ModifyImageAttribute
+// request. Adding a launch permission for the "all" group makes the
+// image public.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyImageAttribute.html for more details.
+type ModifyImageAttribute struct {
+	AddLaunchPermissions    []LaunchPermission
+	RemoveLaunchPermissions []LaunchPermission
+	Description             string // Left unchanged if empty.
+}
+
+// ModifyImageAttribute modifies the attributes of the image with the
+// given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyImageAttribute.html for more details.
+func (ec2 *EC2) ModifyImageAttribute(id string, options *ModifyImageAttribute) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("ModifyImageAttribute")
+	params["ImageId"] = id
+	addLaunchPermissionParams(params, "LaunchPermission.Add.", options.AddLaunchPermissions)
+	addLaunchPermissionParams(params, "LaunchPermission.Remove.", options.RemoveLaunchPermissions)
+	if options.Description != "" {
+		params["Description.Value"] = options.Description
+	}
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func addLaunchPermissionParams(params map[string]string, prefix string, perms []LaunchPermission) {
+	for i, perm := range perms {
+		n := prefix + strconv.Itoa(i+1)
+		if perm.UserId != "" {
+			params[n+".UserId"] = perm.UserId
+		}
+		if perm.Group != "" {
+			params[n+".Group"] = perm.Group
+		}
+	}
+}
@@ -0,0 +1,414 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/ec2/ec2test"
+)
+
+// Image tests with example responses
+
+func (s *S) TestCreateImageExample(c *C) {
+	testServer.Response(200, nil, CreateImageExample)
+
+	resp, err := s.ec2.CreateImage(&ec2.CreateImage{
+		InstanceId:  "i-10a64379",
+		Name:        "standard-web-server-v1.0",
+		Description: "web server",
+		NoReboot:    true,
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+			DeviceName: "/dev/sdf",
+			VolumeSize: 100,
+		}},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateImage"})
+	c.Assert(req.Form["InstanceId"], DeepEquals, []string{"i-10a64379"})
+	c.Assert(req.Form["Name"], DeepEquals, []string{"standard-web-server-v1.0"})
+	c.Assert(req.Form["Description"], DeepEquals, []string{"web server"})
+	c.Assert(req.Form["NoReboot"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["BlockDeviceMapping.1.DeviceName"], DeepEquals, []string{"/dev/sdf"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.VolumeSize"], DeepEquals, []string{"100"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.ImageId, Equals, "ami-4fa54026")
+}
+
+func (s *S) TestRegisterImageExample(c *C) {
+	testServer.Response(200, nil, RegisterImageExample)
+
+	resp, err := s.ec2.RegisterImage(&ec2.RegisterImage{
+		Name:               "my-image",
+		Architecture:       "x86_64",
+		RootDeviceName:     "/dev/sda1",
+		VirtualizationType: "hvm",
+		EnaSupport:         true,
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+			DeviceName: "/dev/sda1",
+			SnapshotId: "snap-1234567890abcdef0",
+			Encrypted:  true,
+		}},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RegisterImage"})
+	c.Assert(req.Form["Name"], DeepEquals, []string{"my-image"})
+	c.Assert(req.Form["Architecture"], DeepEquals, []string{"x86_64"})
+	c.Assert(req.Form["RootDeviceName"], DeepEquals, []string{"/dev/sda1"})
+	c.Assert(req.Form["VirtualizationType"], DeepEquals, []string{"hvm"})
+	c.Assert(req.Form["EnaSupport"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["ImageLocation"], IsNil)
+	c.Assert(req.Form["BlockDeviceMapping.1.DeviceName"], DeepEquals, []string{"/dev/sda1"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.SnapshotId"], DeepEquals, []string{"snap-1234567890abcdef0"})
+	c.Assert(req.Form["BlockDeviceMapping.1.Ebs.Encrypted"], DeepEquals, []string{"true"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.ImageId, Equals, "ami-1a2b3c4d")
+}
+
+func (s *S) TestCopyImageExample(c *C) {
+	testServer.Response(200, nil, CopyImageExample)
+
+	resp, err := s.ec2.CopyImage(&ec2.CopyImage{
+		SourceRegion:  "us-west-2",
+		SourceImageId: "ami-1a2b3c4d",
+		Name:          "my-copy",
+		Encrypted:     true,
+		KMSKeyId:      "alias/my-key",
+		ClientToken:   "token",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CopyImage"})
+	c.Assert(req.Form["SourceRegion"], DeepEquals, []string{"us-west-2"})
+	c.Assert(req.Form["SourceImageId"], DeepEquals, []string{"ami-1a2b3c4d"})
+	c.Assert(req.Form["Name"], DeepEquals, []string{"my-copy"})
+	c.Assert(req.Form["Encrypted"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["KmsKeyId"], DeepEquals, []string{"alias/my-key"})
+	c.Assert(req.Form["ClientToken"], DeepEquals, []string{"token"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "60bc441d-fa2c-494d-b155-5d6a3EXAMPLE")
+	c.Assert(resp.ImageId, Equals, "ami-4d3c2b1a")
+}
+
+func (s *S) TestDeregisterImageExample(c *C) {
+	testServer.Response(200, nil, DeregisterImageExample)
+
+	resp, err := s.ec2.DeregisterImage("ami-4fa54026")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeregisterImage"})
+	c.Assert(req.Form["ImageId"], DeepEquals, []string{"ami-4fa54026"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestImageAttributeExample(c *C) {
+	testServer.Response(200, nil, DescribeImageAttributeExample)
+
+	resp, err := s.ec2.ImageAttribute("ami-61a54008", "launchPermission")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeImageAttribute"})
+	c.Assert(req.Form["ImageId"], DeepEquals, []string{"ami-61a54008"})
+	c.Assert(req.Form["Attribute"], DeepEquals, []string{"launchPermission"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.ImageId, Equals, "ami-61a54008")
+	c.Assert(resp.LaunchPermissions, DeepEquals, []ec2.LaunchPermission{
+		{Group: "all"},
+		{UserId: "495219933132"},
+	})
+}
+
+func (s *S) TestModifyImageAttributeExample(c *C) {
+	testServer.Response(200, nil, ModifyImageAttributeExample)
+
+	resp, err := s.ec2.ModifyImageAttribute("ami-61a54008", &ec2.ModifyImageAttribute{
+		AddLaunchPermissions:    []ec2.LaunchPermission{{UserId: "495219933132"}, {Group: "all"}},
+		RemoveLaunchPermissions: []ec2.LaunchPermission{{UserId: "111122223333"}},
+		Description:             "new description",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ModifyImageAttribute"})
+	c.Assert(req.Form["ImageId"], DeepEquals, []string{"ami-61a54008"})
+	c.Assert(req.Form["LaunchPermission.Add.1.UserId"], DeepEquals, []string{"495219933132"})
+	c.Assert(req.Form["LaunchPermission.Add.1.Group"], IsNil)
+	c.Assert(req.Form["LaunchPermission.Add.2.Group"], DeepEquals, []string{"all"})
+	c.Assert(req.Form["LaunchPermission.Remove.1.UserId"], DeepEquals, []string{"111122223333"})
+	c.Assert(req.Form["Description.Value"], DeepEquals, []string{"new description"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+// Image tests run only against the local test server, as images
+// take long to create on EC2.
+
+func (s *LocalServerSuite) TestImageLifecycle(c *C) {
+	clock := ec2test.NewClock(time.Now())
+	s.srv.srv.SetTransitions(clock, time.Minute)
+	defer s.srv.srv.SetTransitions(nil, 0)
+
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		AvailZone:    "us-east-1a",
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+			DeviceName:          "/dev/sda1",
+			VolumeSize:          8,
+			DeleteOnTermination: true,
+		}},
+	})
+	c.Assert(err, IsNil)
+	instId := inst.Instances[0].InstanceId
+	defer terminateInstances(c, s.ec2, []string{instId})
+	// Stop the clock before terminating the instance, so that
+	// termination does not wait for it.
+	defer s.srv.srv.SetTransitions(nil, 0)
+
+	// Pending instances cannot be imaged.
+	_, err = s.ec2.CreateImage(&ec2.CreateImage{InstanceId: instId, Name: "goamz-image"})
+	c.Assert(errorCode(err), Equals, "IncorrectInstanceState")
+	clock.Advance(time.Minute)
+
+	created, err := s.ec2.CreateImage(&ec2.CreateImage{
+		InstanceId:  instId,
+		Name:        "goamz-image",
+		Description: "created by goamz",
+	})
+	c.Assert(err, IsNil)
+	imgId := created.ImageId
+	_, err = s.ec2.CreateImage(&ec2.CreateImage{InstanceId: instId, Name: "goamz-image"})
+	c.Assert(errorCode(err), Equals, "InvalidAMIName.Duplicate")
+
+	// The image cannot be launched until it is available.
+	_, err = s.ec2.RunInstances(&ec2.RunInstances{ImageId: imgId, InstanceType: "t1.micro"})
+	c.Assert(errorCode(err), Equals, "InvalidAMIID.Unavailable")
+	err = s.ec2.WaitImageAvailable(imgId, shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: imgId, Want: "available", State: "pending"})
+	clock.Advance(time.Minute)
+	c.Assert(s.ec2.WaitImageAvailable(imgId, shortWait), IsNil)
+	s.srv.srv.SetTransitions(nil, 0)
+
+	resp, err := s.ec2.Images([]string{imgId}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Images, HasLen, 1)
+	img := resp.Images[0]
+	c.Check(img.Name, Equals, "goamz-image")
+	c.Check(img.Description, Equals, "created by goamz")
+	c.Check(img.Architecture, Equals, "i386")
+	c.Check(img.RootDeviceType, Equals, "ebs")
+	c.Check(img.Public, Equals, false)
+	c.Assert(img.BlockDevices, HasLen, 1)
+	c.Check(img.BlockDevices[0].DeviceName, Equals, "/dev/sda1")
+	c.Check(img.BlockDevices[0].VolumeSize, Equals, int64(8))
+	snapId := img.BlockDevices[0].SnapshotId
+	c.Check(snapId, Matches, "snap-.+")
+
+	// Instances launched from the image get volumes created from
+	// its snapshots.
+	launched, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imgId,
+		InstanceType: "t1.micro",
+		AvailZone:    "us-east-1a",
+	})
+	c.Assert(err, IsNil)
+	launchedId := launched.Instances[0].InstanceId
+	defer terminateInstances(c, s.ec2, []string{launchedId})
+	f := ec2.NewFilter()
+	f.Add("snapshot-id", snapId)
+	vols, err := s.ec2.Volumes(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(vols.Volumes, HasLen, 1)
+	c.Check(vols.Volumes[0].Attachments[0].InstanceId, Equals, launchedId)
+
+	// Copies get their own, optionally encrypted, snapshots.
+	copied, err := s.ec2.CopyImage(&ec2.CopyImage{
+		SourceRegion:  "us-east-1",
+		SourceImageId: imgId,
+		Name:          "goamz-image-copy",
+		Encrypted:     true,
+		ClientToken:   "goamz-copy",
+	})
+	c.Assert(err, IsNil)
+	again, err := s.ec2.CopyImage(&ec2.CopyImage{
+		SourceRegion:  "us-east-1",
+		SourceImageId: imgId,
+		Name:          "goamz-image-copy",
+		Encrypted:     true,
+		ClientToken:   "goamz-copy",
+	})
+	c.Assert(err, IsNil)
+	c.Check(again.ImageId, Equals, copied.ImageId)
+	resp, err = s.ec2.Images([]string{copied.ImageId}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Images[0].BlockDevices, HasLen, 1)
+	copySnapId := resp.Images[0].BlockDevices[0].SnapshotId
+	c.Check(copySnapId, Not(Equals), snapId)
+	c.Check(resp.Images[0].BlockDevices[0].Encrypted, Equals, true)
+
+	// Images can be registered from snapshots.
+	registered, err := s.ec2.RegisterImage(&ec2.RegisterImage{
+		Name:           "goamz-image-registered",
+		Architecture:   "x86_64",
+		RootDeviceName: "/dev/xvda",
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+			DeviceName: "/dev/xvda",
+			SnapshotId: snapId,
+		}},
+	})
+	c.Assert(err, IsNil)
+
+	f = ec2.NewFilter()
+	f.Add("block-device-mapping.snapshot-id", snapId)
+	resp, err = s.ec2.Images(nil, f)
+	c.Assert(err, IsNil)
+	var ids []string
+	for _, img := range resp.Images {
+		ids = append(ids, img.Id)
+	}
+	c.Check(ids, DeepEquals, []string{imgId, registered.ImageId})
+
+	// Snapshots cannot be deleted while images use them.
+	_, err = s.ec2.DeleteSnapshots([]string{snapId})
+	c.Assert(errorCode(err), Equals, "InvalidSnapshot.InUse")
+	for _, id := range []string{imgId, copied.ImageId, registered.ImageId} {
+		_, err = s.ec2.DeregisterImage(id)
+		c.Assert(err, IsNil)
+	}
+	_, err = s.ec2.DeleteSnapshots([]string{snapId, copySnapId})
+	c.Assert(err, IsNil)
+
+	_, err = s.ec2.Images([]string{imgId}, nil)
+	c.Assert(errorCode(err), Equals, "InvalidAMIID.NotFound")
+	_, err = s.ec2.RunInstances(&ec2.RunInstances{ImageId: imgId, InstanceType: "t1.micro"})
+	c.Assert(errorCode(err), Equals, "InvalidAMIID.NotFound")
+}
+
+func (s *LocalServerSuite) TestImageLaunchPermissions(c *C) {
+	// Unknown images are launched unless the server has strict
+	// images.
+	run, err := s.ec2.RunInstances(&ec2.RunInstances{ImageId: "ami-unknown", InstanceType: "t1.micro"})
+	c.Assert(err, IsNil)
+	terminateInstances(c, s.ec2, []string{run.Instances[0].InstanceId})
+	s.srv.srv.SetStrictImages(true)
+	defer s.srv.srv.SetStrictImages(false)
+
+	// Private images of other accounts cannot be seen.
+	private := s.srv.srv.AddImage(ec2.Image{Name: "other", OwnerId: "111122223333"})
+	_, err = s.ec2.Images([]string{private}, nil)
+	c.Assert(errorCode(err), Equals, "InvalidAMIID.NotFound")
+	_, err = s.ec2.RunInstances(&ec2.RunInstances{ImageId: private, InstanceType: "t1.micro"})
+	c.Assert(errorCode(err), Equals, "InvalidAMIID.NotFound")
+	_, err = s.ec2.RunInstances(&ec2.RunInstances{ImageId: "bad-id", InstanceType: "t1.micro"})
+	c.Assert(errorCode(err), Equals, "InvalidAMIID.Malformed")
+
+	imgId := s.srv.srv.AddImage(ec2.Image{Name: "goamz-shared", Description: "shared"})
+	defer s.ec2.DeregisterImage(imgId)
+
+	attr, err := s.ec2.ImageAttribute(imgId, "launchPermission")
+	c.Assert(err, IsNil)
+	c.Check(attr.LaunchPermissions, HasLen, 0)
+
+	_, err = s.ec2.ModifyImageAttribute(imgId, &ec2.ModifyImageAttribute{
+		AddLaunchPermissions: []ec2.LaunchPermission{{UserId: "111122223333"}, {UserId: "444455556666"}, {Group: "all"}},
+		Description:          "public",
+	})
+	c.Assert(err, IsNil)
+	attr, err = s.ec2.ImageAttribute(imgId, "launchPermission")
+	c.Assert(err, IsNil)
+	c.Check(attr.LaunchPermissions, DeepEquals, []ec2.LaunchPermission{
+		{Group: "all"},
+		{UserId: "111122223333"},
+		{UserId: "444455556666"},
+	})
+	attr, err = s.ec2.ImageAttribute(imgId, "description")
+	c.Assert(err, IsNil)
+	c.Check(attr.Description, Equals, "public")
+
+	f := ec2.NewFilter()
+	f.Add("is-public", "true")
+	f.Add("image-id", imgId)
+	resp, err := s.ec2.Images(nil, f)
+	c.Assert(err, IsNil)
+	c.Check(resp.Images, HasLen, 1)
+
+	_, err = s.ec2.ModifyImageAttribute(imgId, &ec2.ModifyImageAttribute{
+		RemoveLaunchPermissions: []ec2.LaunchPermission{{UserId: "111122223333"}, {Group: "all"}},
+	})
+	c.Assert(err, IsNil)
+	attr, err = s.ec2.ImageAttribute(imgId, "launchPermission")
+	c.Assert(err, IsNil)
+	c.Check(attr.LaunchPermissions, DeepEquals, []ec2.LaunchPermission{{UserId: "444455556666"}})
+
+	_, err = s.ec2.ModifyImageAttribute(imgId, &ec2.ModifyImageAttribute{})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.ModifyImageAttribute(imageId, &ec2.ModifyImageAttribute{Description: "not mine"})
+	c.Check(errorCode(err), Equals, "AuthFailure")
+	_, err = s.ec2.DeregisterImage(imageId)
+	c.Check(errorCode(err), Equals, "AuthFailure")
+	_, err = s.ec2.ImageAttribute(imgId, "unknown")
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+}
+
+func (s *LocalServerSuite) TestRegisterImageErrors(c *C) {
+	for i, test := range []struct {
+		image *ec2.RegisterImage
+		code  string
+	}{{
+		image: &ec2.RegisterImage{Name: "x", ImageLocation: "bucket/manifest.xml"},
+		code:  "InvalidAMIName.Malformed",
+	}, {
+		image: &ec2.RegisterImage{Name: "goamz-no-root"},
+		code:  "MissingParameter",
+	}, {
+		image: &ec2.RegisterImage{Name: "goamz-no-snapshot", RootDeviceName: "/dev/sda1"},
+		code:  "InvalidBlockDeviceMapping",
+	}, {
+		image: &ec2.RegisterImage{
+			Name:           "goamz-bad-snapshot",
+			RootDeviceName: "/dev/sda1",
+			BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+				DeviceName: "/dev/sda1",
+				SnapshotId: "snap-999",
+			}},
+		},
+		code: "InvalidSnapshot.NotFound",
+	}, {
+		image: &ec2.RegisterImage{Name: "goamz-bad-arch", Architecture: "arm", ImageLocation: "bucket/manifest.xml"},
+		code:  "InvalidParameterValue",
+	}} {
+		c.Logf("test %d: %s", i, test.image.Name)
+		_, err := s.ec2.RegisterImage(test.image)
+		c.Check(errorCode(err), Equals, test.code)
+	}
+
+	// Instance store backed images are registered from a manifest.
+	resp, err := s.ec2.RegisterImage(&ec2.RegisterImage{
+		Name:          "goamz-instance-store",
+		ImageLocation: "bucket/manifest.xml",
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeregisterImage(resp.ImageId)
+	images, err := s.ec2.Images([]string{resp.ImageId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(images.Images[0].RootDeviceType, Equals, "instance-store")
+	c.Check(images.Images[0].Location, Equals, "bucket/manifest.xml")
+}
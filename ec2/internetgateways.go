@@ -0,0 +1,130 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// InternetGateway describes an Internet gateway, which connects a VPC
+// to the Internet.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InternetGateway.html for more details.
+type InternetGateway struct {
+	Id          string                      `xml:"internetGatewayId"`
+	Attachments []InternetGatewayAttachment `xml:"attachmentSet>item"`
+	Tags        []Tag                       `xml:"tagSet>item"`
+}
+
+// InternetGatewayAttachment describes the attachment of an Internet
+// gateway to a VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InternetGatewayAttachment.html for more details.
+type InternetGatewayAttachment struct {
+	VPCId string `xml:"vpcId"`
+	State string `xml:"state"`
+}
+
+// CreateInternetGatewayResp is the response to a
+// CreateInternetGateway request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateInternetGateway.html for more details.
+type CreateInternetGatewayResp struct {
+	RequestId       string          `xml:"requestId"`
+	InternetGateway InternetGateway `xml:"internetGateway"`
+}
+
+// CreateInternetGateway creates an Internet gateway, which must then
+// be attached to a VPC with AttachInternetGateway.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateInternetGateway.html for more details.
+func (ec2 *EC2) CreateInternetGateway() (resp *CreateInternetGatewayResp, err error) {
+	params := makeParamsVPC("CreateInternetGateway")
+	resp = &CreateInternetGatewayResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteInternetGateway deletes the Internet gateway with the given
+// id. The gateway must have been detached from its VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteInternetGateway.html for more details.
+func (ec2 *EC2) DeleteInternetGateway(id string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("DeleteInternetGateway")
+	params["InternetGatewayId"] = id
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// InternetGatewaysResp is the response to an InternetGateways request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInternetGateways.html for more details.
+type InternetGatewaysResp struct {
+	RequestId        string            `xml:"requestId"`
+	InternetGateways []InternetGateway `xml:"internetGatewaySet>item"`
+}
+
+// InternetGateways describes one or more Internet gateways. Both
+// parameters are optional, and if specified will limit the returned
+// gateways to the matching ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInternetGateways.html for more details.
+func (ec2 *EC2) InternetGateways(ids []string, filter *Filter) (resp *InternetGatewaysResp, err error) {
+	params := makeParamsVPC("DescribeInternetGateways")
+	for i, id := range ids {
+		params["InternetGatewayId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &InternetGatewaysResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AttachInternetGateway attaches the Internet gateway with the given
+// id to a VPC. A VPC can have at most one Internet gateway.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AttachInternetGateway.html for more details.
+func (ec2 *EC2) AttachInternetGateway(id, vpcId string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("AttachInternetGateway")
+	params["InternetGatewayId"] = id
+	params["VpcId"] = vpcId
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DetachInternetGateway detaches the Internet gateway with the given
+// id from a VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DetachInternetGateway.html for more details.
+func (ec2 *EC2) DetachInternetGateway(id, vpcId string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("DetachInternetGateway")
+	params["InternetGatewayId"] = id
+	params["VpcId"] = vpcId
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
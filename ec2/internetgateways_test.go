@@ -0,0 +1,135 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// Internet gateway tests with example responses
+
+func (s *S) TestCreateInternetGatewayExample(c *C) {
+	testServer.Response(200, nil, CreateInternetGatewayExample)
+
+	resp, err := s.ec2.CreateInternetGateway()
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateInternetGateway"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.InternetGateway.Id, Equals, "igw-eaad4883")
+	c.Assert(resp.InternetGateway.Attachments, HasLen, 0)
+}
+
+func (s *S) TestInternetGatewaysExample(c *C) {
+	testServer.Response(200, nil, DescribeInternetGatewaysExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("attachment.vpc-id", "vpc-11ad4878")
+	resp, err := s.ec2.InternetGateways([]string{"igw-eaad4883EXAMPLE"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeInternetGateways"})
+	c.Assert(req.Form["InternetGatewayId.1"], DeepEquals, []string{"igw-eaad4883EXAMPLE"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"attachment.vpc-id"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"vpc-11ad4878"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.InternetGateways, DeepEquals, []ec2.InternetGateway{{
+		Id: "igw-eaad4883EXAMPLE",
+		Attachments: []ec2.InternetGatewayAttachment{{
+			VPCId: "vpc-11ad4878",
+			State: "available",
+		}},
+	}})
+}
+
+func (s *S) TestAttachInternetGatewayExample(c *C) {
+	testServer.Response(200, nil, AttachInternetGatewayExample)
+
+	resp, err := s.ec2.AttachInternetGateway("igw-eaad4883", "vpc-11ad4878")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AttachInternetGateway"})
+	c.Assert(req.Form["InternetGatewayId"], DeepEquals, []string{"igw-eaad4883"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-11ad4878"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestDetachInternetGatewayExample(c *C) {
+	testServer.Response(200, nil, AttachInternetGatewayExample)
+
+	_, err := s.ec2.DetachInternetGateway("igw-eaad4883", "vpc-11ad4878")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DetachInternetGateway"})
+	c.Assert(req.Form["InternetGatewayId"], DeepEquals, []string{"igw-eaad4883"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-11ad4878"})
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestDeleteInternetGatewayExample(c *C) {
+	testServer.Response(200, nil, AttachInternetGatewayExample)
+
+	_, err := s.ec2.DeleteInternetGateway("igw-eaad4883")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteInternetGateway"})
+	c.Assert(req.Form["InternetGatewayId"], DeepEquals, []string{"igw-eaad4883"})
+	c.Assert(err, IsNil)
+}
+
+// Internet gateway tests run against either a local test server or
+// live on EC2.
+
+func (s *ServerTests) TestInternetGateways(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.6.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	defer s.deleteVPCs(c, []string{vpcId})
+
+	created, err := s.ec2.CreateInternetGateway()
+	c.Assert(err, IsNil)
+	igwId := created.InternetGateway.Id
+	defer s.ec2.DeleteInternetGateway(igwId)
+	c.Check(igwId, Matches, "igw-.+")
+
+	_, err = s.ec2.AttachInternetGateway(igwId, vpcId)
+	c.Assert(err, IsNil)
+
+	_, err = s.ec2.DeleteInternetGateway(igwId)
+	c.Check(errorCode(err), Equals, "DependencyViolation")
+
+	f := ec2.NewFilter()
+	f.Add("attachment.vpc-id", vpcId)
+	resp, err := s.ec2.InternetGateways(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.InternetGateways, HasLen, 1)
+	c.Check(resp.InternetGateways[0].Id, Equals, igwId)
+	c.Check(resp.InternetGateways[0].Attachments, DeepEquals, []ec2.InternetGatewayAttachment{{
+		VPCId: vpcId,
+		State: "available",
+	}})
+
+	_, err = s.ec2.DetachInternetGateway(igwId, vpcId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.DetachInternetGateway(igwId, vpcId)
+	c.Check(errorCode(err), Equals, "Gateway.NotAttached")
+
+	_, err = s.ec2.DeleteInternetGateway(igwId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.InternetGateways([]string{igwId}, nil)
+	c.Check(errorCode(err), Equals, "InvalidInternetGatewayID.NotFound")
+}
@@ -0,0 +1,292 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"net"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// IPv6 tests with example responses
+
+func (s *S) TestAssociateVPCCIDRBlockExample(c *C) {
+	testServer.Response(200, nil, AssociateVpcCidrBlockExample)
+
+	resp, err := s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{
+		VPCId:                       "vpc-a034d6c4",
+		AmazonProvidedIPv6CIDRBlock: true,
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AssociateVpcCidrBlock"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-a034d6c4"})
+	c.Assert(req.Form["AmazonProvidedIpv6CidrBlock"], DeepEquals, []string{"true"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "33af6c54-1139-4d50-b4f7-15a8example")
+	c.Check(resp.VPCId, Equals, "vpc-a034d6c4")
+	c.Check(resp.IPv6CIDRBlockAssociation, DeepEquals, ec2.IPv6CIDRBlockAssociation{
+		AssociationId: "vpc-cidr-assoc-e2a5408b",
+		State:         "associating",
+	})
+}
+
+func (s *S) TestAssociateSubnetCIDRBlockExample(c *C) {
+	testServer.Response(200, nil, AssociateSubnetCidrBlockExample)
+
+	resp, err := s.ec2.AssociateSubnetCIDRBlock("subnet-b61f49f0", "2001:db8:1234:1a00::/64")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AssociateSubnetCidrBlock"})
+	c.Assert(req.Form["SubnetId"], DeepEquals, []string{"subnet-b61f49f0"})
+	c.Assert(req.Form["Ipv6CidrBlock"], DeepEquals, []string{"2001:db8:1234:1a00::/64"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "c8d3a1b3-6b22-4a1c-a7e8-4bfbexample")
+	c.Check(resp.SubnetId, Equals, "subnet-b61f49f0")
+	c.Check(resp.IPv6CIDRBlockAssociation, DeepEquals, ec2.IPv6CIDRBlockAssociation{
+		AssociationId: "subnet-cidr-assoc-3aa54053",
+		IPv6CIDRBlock: "2001:db8:1234:1a00::/64",
+		State:         "associating",
+	})
+}
+
+func (s *S) TestAssignIPv6AddressesExample(c *C) {
+	testServer.Response(200, nil, AssignIpv6AddressesExample)
+
+	resp, err := s.ec2.AssignIPv6Addresses("eni-38664473", nil, 2)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AssignIpv6Addresses"})
+	c.Assert(req.Form["NetworkInterfaceId"], DeepEquals, []string{"eni-38664473"})
+	c.Assert(req.Form["Ipv6AddressCount"], DeepEquals, []string{"2"})
+	c.Assert(req.Form["Ipv6Addresses.1"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "c36d17eb-a0ba-4d38-8727-example")
+	c.Check(resp.NetworkInterfaceId, Equals, "eni-38664473")
+	c.Check(resp.AssignedAddresses, DeepEquals, []string{
+		"2001:db8:1234:1a00:3304:8879:34cf:4071",
+		"2001:db8:1234:1a00:9691:9503:25ad:1761",
+	})
+}
+
+func (s *S) TestUnassignIPv6AddressesExample(c *C) {
+	testServer.Response(200, nil, UnassignIpv6AddressesExample)
+
+	resp, err := s.ec2.UnassignIPv6Addresses("eni-0b42e9fa", []string{"2001:db8:1234:1a00:3304:8879:34cf:4071"})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"UnassignIpv6Addresses"})
+	c.Assert(req.Form["NetworkInterfaceId"], DeepEquals, []string{"eni-0b42e9fa"})
+	c.Assert(req.Form["Ipv6Addresses.1"], DeepEquals, []string{"2001:db8:1234:1a00:3304:8879:34cf:4071"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "94d446d7-fc8e-4918-94f9-example")
+	c.Check(resp.UnassignedAddresses, DeepEquals, []string{"2001:db8:1234:1a00:3304:8879:34cf:4071"})
+}
+
+func (s *S) TestCreateNetworkInterfaceIPv6Example(c *C) {
+	testServer.Response(200, nil, CreateNetworkInterfaceExample)
+
+	_, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+		SubnetId:      "subnet-b2a249da",
+		IPv6Addresses: []string{"2001:db8:1234:1a00::123", "2001:db8:1234:1a00::456"},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateNetworkInterface"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["Ipv6Addresses.1.Ipv6Address"], DeepEquals, []string{"2001:db8:1234:1a00::123"})
+	c.Assert(req.Form["Ipv6Addresses.2.Ipv6Address"], DeepEquals, []string{"2001:db8:1234:1a00::456"})
+	c.Assert(req.Form["Ipv6AddressCount"], IsNil)
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestRunInstancesIPv6Example(c *C) {
+	testServer.Response(200, nil, RunInstancesExample)
+
+	_, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		NetworkInterfaces: []ec2.RunNetworkInterface{{
+			DeviceIndex:      0,
+			SubnetId:         "subnet-id",
+			IPv6AddressCount: 2,
+		}, {
+			DeviceIndex:   1,
+			SubnetId:      "subnet-id",
+			IPv6Addresses: []string{"2001:db8:1234:1a00::123"},
+		}},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RunInstances"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["NetworkInterface.0.Ipv6AddressCount"], DeepEquals, []string{"2"})
+	c.Assert(req.Form["NetworkInterface.1.Ipv6Addresses.1.Ipv6Address"], DeepEquals, []string{"2001:db8:1234:1a00::123"})
+	c.Assert(err, IsNil)
+}
+
+// IPv6 tests run only against the local test server.
+
+// subnetIPv6Block returns the /64 block with the given index within
+// the /56 IPv6 CIDR block vpcBlock.
+func subnetIPv6Block(c *C, vpcBlock string, index byte) string {
+	_, ipnet, err := net.ParseCIDR(vpcBlock)
+	c.Assert(err, IsNil)
+	ipnet.IP[7] = index
+	ipnet.Mask = net.CIDRMask(64, 128)
+	return ipnet.String()
+}
+
+func (s *LocalServerSuite) TestIPv6(c *C) {
+	vpc, err := s.ec2.CreateVPC("10.11.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpc.VPC.Id
+	defer s.ec2.DeleteVPC(vpcId)
+	sub, err := s.ec2.CreateSubnet(vpcId, "10.11.1.0/24", "")
+	c.Assert(err, IsNil)
+	subId := sub.Subnet.Id
+	defer s.ec2.DeleteSubnet(subId)
+
+	// Without an IPv6 CIDR block, there are no IPv6 addresses.
+	_, err = s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+		SubnetId:         subId,
+		IPv6AddressCount: 1,
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{VPCId: vpcId})
+	c.Check(errorCode(err), Equals, "MissingParameter")
+
+	assocResp, err := s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{
+		VPCId:                       vpcId,
+		AmazonProvidedIPv6CIDRBlock: true,
+	})
+	c.Assert(err, IsNil)
+	vpcAssoc := assocResp.IPv6CIDRBlockAssociation
+	c.Check(assocResp.VPCId, Equals, vpcId)
+	c.Check(vpcAssoc.AssociationId, Matches, "vpc-cidr-assoc-.+")
+	c.Check(vpcAssoc.IPv6CIDRBlock, Matches, ".*::/56")
+	c.Check(vpcAssoc.State, Equals, "associated")
+	_, err = s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{
+		VPCId:                       vpcId,
+		AmazonProvidedIPv6CIDRBlock: true,
+	})
+	c.Check(errorCode(err), Equals, "CidrLimitExceeded")
+
+	f := ec2.NewFilter()
+	f.Add("ipv6-cidr-block-association.ipv6-cidr-block", vpcAssoc.IPv6CIDRBlock)
+	vpcs, err := s.ec2.VPCs(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(vpcs.VPCs, HasLen, 1)
+	c.Check(vpcs.VPCs[0].Id, Equals, vpcId)
+	c.Check(vpcs.VPCs[0].IPv6CIDRBlocks, DeepEquals, []ec2.IPv6CIDRBlockAssociation{vpcAssoc})
+
+	subBlock := subnetIPv6Block(c, vpcAssoc.IPv6CIDRBlock, 1)
+	for i, t := range []struct {
+		block string
+		code  string
+	}{
+		{"2001:db8::/64", "InvalidSubnet.Range"},
+		{"10.11.1.0/24", "InvalidParameterValue"},
+		{subBlock[:len(subBlock)-3] + "/80", "InvalidParameterValue"},
+	} {
+		_, err := s.ec2.AssociateSubnetCIDRBlock(subId, t.block)
+		c.Check(errorCode(err), Equals, t.code, Commentf("test %d", i))
+	}
+	subResp, err := s.ec2.AssociateSubnetCIDRBlock(subId, subBlock)
+	c.Assert(err, IsNil)
+	c.Check(subResp.SubnetId, Equals, subId)
+	c.Check(subResp.IPv6CIDRBlockAssociation.AssociationId, Matches, "subnet-cidr-assoc-.+")
+	c.Check(subResp.IPv6CIDRBlockAssociation.IPv6CIDRBlock, Equals, subBlock)
+	_, err = s.ec2.AssociateSubnetCIDRBlock(subId, subnetIPv6Block(c, vpcAssoc.IPv6CIDRBlock, 2))
+	c.Check(errorCode(err), Equals, "CidrLimitExceeded")
+
+	f = ec2.NewFilter()
+	f.Add("ipv6-cidr-block-association.association-id", subResp.IPv6CIDRBlockAssociation.AssociationId)
+	subs, err := s.ec2.Subnets(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(subs.Subnets, HasLen, 1)
+	c.Check(subs.Subnets[0].IPv6CIDRBlocks, DeepEquals, []ec2.IPv6CIDRBlockAssociation{subResp.IPv6CIDRBlockAssociation})
+
+	// Addresses are allocated after the four reserved ones.
+	prefix := subBlock[:len(subBlock)-len("/64")]
+	nic, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+		SubnetId:         subId,
+		IPv6AddressCount: 2,
+	})
+	c.Assert(err, IsNil)
+	nicId := nic.NetworkInterface.Id
+	defer s.ec2.DeleteNetworkInterface(nicId)
+	c.Check(nic.NetworkInterface.IPv6Addresses, DeepEquals, []string{prefix + "4", prefix + "5"})
+
+	for i, addrs := range [][]string{
+		{prefix + "5"},
+		{"2001:db8::5"},
+		{"10.11.1.5"},
+	} {
+		_, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+			SubnetId:      subId,
+			IPv6Addresses: addrs,
+		})
+		c.Check(err, NotNil, Commentf("test %d", i))
+	}
+	_, err = s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+		SubnetId:         subId,
+		IPv6Addresses:    []string{prefix + "10"},
+		IPv6AddressCount: 1,
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+
+	assigned, err := s.ec2.AssignIPv6Addresses(nicId, []string{prefix + "10"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(assigned.AssignedAddresses, DeepEquals, []string{prefix + "10"})
+	assigned, err = s.ec2.AssignIPv6Addresses(nicId, nil, 1)
+	c.Assert(err, IsNil)
+	c.Check(assigned.AssignedAddresses, DeepEquals, []string{prefix + "6"})
+	_, err = s.ec2.AssignIPv6Addresses(nicId, []string{prefix + "6"}, 0)
+	c.Check(errorCode(err), Equals, "InvalidIPAddress.InUse")
+
+	unassigned, err := s.ec2.UnassignIPv6Addresses(nicId, []string{prefix + "5"})
+	c.Assert(err, IsNil)
+	c.Check(unassigned.UnassignedAddresses, DeepEquals, []string{prefix + "5"})
+	_, err = s.ec2.UnassignIPv6Addresses(nicId, []string{prefix + "5"})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+
+	f = ec2.NewFilter()
+	f.Add("ipv6-addresses.ipv6-address", prefix+"10")
+	nics, err := s.ec2.NetworkInterfaces(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(nics.Interfaces, HasLen, 1)
+	c.Check(nics.Interfaces[0].IPv6Addresses, DeepEquals, []string{prefix + "4", prefix + "10", prefix + "6"})
+
+	// Network interfaces created on launch get their addresses
+	// from the subnet they are created in.
+	resp, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "m1.ipv6",
+		NetworkInterfaces: []ec2.RunNetworkInterface{{
+			DeviceIndex:         0,
+			SubnetId:            subId,
+			DeleteOnTermination: true,
+			IPv6AddressCount:    2,
+		}},
+	})
+	c.Assert(err, IsNil)
+	inst := resp.Instances[0]
+	defer terminateInstances(c, s.ec2, []string{inst.InstanceId})
+	c.Check(inst.SubnetId, Equals, subId)
+	c.Assert(inst.NetworkInterfaces, HasLen, 1)
+	c.Check(inst.NetworkInterfaces[0].IPv6Addresses, DeepEquals, []string{prefix + "5", prefix + "7"})
+}
@@ -0,0 +1,188 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// KeyPair describes a key pair known to EC2.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_KeyPairInfo.html for more details.
+type KeyPair struct {
+	Name        string `xml:"keyName"`
+	Fingerprint string `xml:"keyFingerprint"`
+}
+
+// CreateKeyPairResp is the response to a CreateKeyPair request.
+// Material holds the unencrypted PEM encoded RSA private key,
+// which EC2 does not keep.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateKeyPair.html for more details.
+type CreateKeyPairResp struct {
+	RequestId string `xml:"requestId"`
+	KeyPair
+	Material string `xml:"keyMaterial"`
+}
+
+// CreateKeyPair creates a new 2048-bit RSA key pair with the given
+// name. EC2 keeps the public key, and returns the private key.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateKeyPair.html for more details.
+func (ec2 *EC2) CreateKeyPair(name string) (resp *CreateKeyPairResp, err error) {
+	params := makeParamsVPC("CreateKeyPair")
+	params["KeyName"] = name
+	resp = &CreateKeyPairResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ImportKeyPairResp is the response to an ImportKeyPair request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ImportKeyPair.html for more details.
+type ImportKeyPairResp struct {
+	RequestId string `xml:"requestId"`
+	KeyPair
+}
+
+// ImportKeyPair imports the given RSA public key, in the format
+// used by OpenSSH authorized_keys files, as a key pair with the
+// given name.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ImportKeyPair.html for more details.
+func (ec2 *EC2) ImportKeyPair(name string, publicKey []byte) (resp *ImportKeyPairResp, err error) {
+	if _, err := ParsePublicKey(publicKey); err != nil {
+		return nil, err
+	}
+	params := makeParamsVPC("ImportKeyPair")
+	params["KeyName"] = name
+	params["PublicKeyMaterial"] = base64.StdEncoding.EncodeToString(publicKey)
+	resp = &ImportKeyPairResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// KeyPairsResp is the response to a KeyPairs request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeKeyPairs.html for more details.
+type KeyPairsResp struct {
+	RequestId string    `xml:"requestId"`
+	KeyPairs  []KeyPair `xml:"keySet>item"`
+}
+
+// KeyPairs returns one or more key pairs. Both parameters are
+// optional, and if specified will limit the returned key pairs to
+// the matching names or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeKeyPairs.html for more details.
+func (ec2 *EC2) KeyPairs(names []string, filter *Filter) (resp *KeyPairsResp, err error) {
+	params := makeParamsVPC("DescribeKeyPairs")
+	for i, name := range names {
+		params["KeyName."+strconv.Itoa(i+1)] = name
+	}
+	filter.addParams(params)
+
+	resp = &KeyPairsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteKeyPair deletes the key pair with the given name. It does
+// not fail if there is no such key pair.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteKeyPair.html for more details.
+func (ec2 *EC2) DeleteKeyPair(name string) (resp *SimpleResp, err error) {
+	params := makeParamsVPC("DeleteKeyPair")
+	params["KeyName"] = name
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ParsePublicKey parses an RSA public key in the format used by
+// OpenSSH authorized_keys files, such as "ssh-rsa AAAA... comment".
+func ParsePublicKey(data []byte) (*rsa.PublicKey, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return nil, errors.New("invalid public key: expected key type and data")
+	}
+	if fields[0] != "ssh-rsa" {
+		return nil, fmt.Errorf("unsupported public key type %q", fields[0])
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key data: %v", err)
+	}
+	// The key data holds the key type, the public exponent and the
+	// modulus, each prefixed by its length.
+	var parts [3][]byte
+	r := bytes.NewReader(blob)
+	for i := range parts {
+		var n uint32
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil || int(n) > r.Len() {
+			return nil, errors.New("invalid public key data: truncated")
+		}
+		parts[i] = make([]byte, n)
+		r.Read(parts[i])
+	}
+	if string(parts[0]) != "ssh-rsa" || r.Len() != 0 {
+		return nil, errors.New("invalid public key data: not an RSA key")
+	}
+	e := new(big.Int).SetBytes(parts[1])
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid public key data: exponent too large")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(parts[2]),
+		E: int(e.Int64()),
+	}, nil
+}
+
+// PublicKeyFingerprint returns the fingerprint that EC2 reports
+// for a key pair imported with the given public key, in the format
+// accepted by ParsePublicKey: the MD5 sum of its DER encoding, in
+// colon separated hexadecimal.
+func PublicKeyFingerprint(publicKey []byte) (string, error) {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(der)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, ":"), nil
+}
@@ -0,0 +1,188 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"encoding/base64"
+	"encoding/pem"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// testPublicKey was generated with ssh-keygen. Its fingerprint was
+// computed as EC2 does for imported keys, with:
+//
+//   ssh-keygen -f key.pub -e -m PKCS8 |
+//       openssl pkey -pubin -outform DER | openssl md5 -c
+//
+const (
+	testPublicKey            = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDD9TUCX//Ips7urDFKI+EoQJJPZV4Ci734R7KiAxGtwwNyYO8RWIpL2yOru/RVMs/pynHr8NN0JIws+r94HKA/HdapxgFuxVy6o0V2taASVrYgHl0T2NcNzHl2H0au7ryjRE5WD7CvGxQAnMQziCQAtHmIR+SIr9NgmSDdeGSUiw== test@example\n"
+	testPublicKeyFingerprint = "25:f7:88:82:45:0c:6f:3b:dd:5f:b8:1c:46:ec:6a:07"
+)
+
+// Key pair tests with example responses
+
+func (s *S) TestCreateKeyPairExample(c *C) {
+	testServer.Response(200, nil, CreateKeyPairExample)
+
+	resp, err := s.ec2.CreateKeyPair("my-key-pair")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateKeyPair"})
+	c.Assert(req.Form["KeyName"], DeepEquals, []string{"my-key-pair"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Check(resp.Name, Equals, "my-key-pair")
+	c.Check(resp.Fingerprint, Equals, "1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f")
+	c.Check(resp.Material, Matches, "(?s)---- BEGIN RSA PRIVATE KEY ----.*-----END RSA PRIVATE KEY-----")
+}
+
+func (s *S) TestImportKeyPairExample(c *C) {
+	testServer.Response(200, nil, ImportKeyPairExample)
+
+	resp, err := s.ec2.ImportKeyPair("my-key-pair", []byte(testPublicKey))
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ImportKeyPair"})
+	c.Assert(req.Form["KeyName"], DeepEquals, []string{"my-key-pair"})
+	c.Assert(req.Form["PublicKeyMaterial"], DeepEquals, []string{base64.StdEncoding.EncodeToString([]byte(testPublicKey))})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Check(resp.Name, Equals, "my-key-pair")
+	c.Check(resp.Fingerprint, Equals, "1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca")
+}
+
+func (s *S) TestImportKeyPairBadKey(c *C) {
+	_, err := s.ec2.ImportKeyPair("my-key-pair", []byte("ssh-dss AAAA"))
+	c.Assert(err, ErrorMatches, `unsupported public key type "ssh-dss"`)
+}
+
+func (s *S) TestKeyPairsExample(c *C) {
+	testServer.Response(200, nil, DescribeKeyPairsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("key-name", "my-*")
+	resp, err := s.ec2.KeyPairs([]string{"my-key-pair", "other"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeKeyPairs"})
+	c.Assert(req.Form["KeyName.1"], DeepEquals, []string{"my-key-pair"})
+	c.Assert(req.Form["KeyName.2"], DeepEquals, []string{"other"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"key-name"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"my-*"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.KeyPairs, DeepEquals, []ec2.KeyPair{{
+		Name:        "my-key-pair",
+		Fingerprint: "1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f",
+	}})
+}
+
+func (s *S) TestDeleteKeyPairExample(c *C) {
+	testServer.Response(200, nil, DeleteKeyPairExample)
+
+	resp, err := s.ec2.DeleteKeyPair("my-key-pair")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteKeyPair"})
+	c.Assert(req.Form["KeyName"], DeepEquals, []string{"my-key-pair"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestPublicKeyFingerprint(c *C) {
+	fingerprint, err := ec2.PublicKeyFingerprint([]byte(testPublicKey))
+	c.Assert(err, IsNil)
+	c.Assert(fingerprint, Equals, testPublicKeyFingerprint)
+
+	for _, bad := range []string{
+		"",
+		"ssh-rsa",
+		"ssh-rsa !!!",
+		"ssh-rsa AAAAB3NzaC1yc2E=",
+		"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIHv5",
+	} {
+		_, err := ec2.PublicKeyFingerprint([]byte(bad))
+		c.Check(err, NotNil, Commentf("%q", bad))
+	}
+}
+
+// Key pair tests run against either a local test server or live on EC2.
+
+func (s *ServerTests) TestKeyPairs(c *C) {
+	created, err := s.ec2.CreateKeyPair("goamz-test-created")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteKeyPair("goamz-test-created")
+	c.Check(created.Name, Equals, "goamz-test-created")
+	c.Check(created.Fingerprint, Matches, "([0-9a-f]{2}:){19}[0-9a-f]{2}")
+	block, _ := pem.Decode([]byte(created.Material))
+	c.Assert(block, NotNil)
+	c.Check(block.Type, Equals, "RSA PRIVATE KEY")
+
+	imported, err := s.ec2.ImportKeyPair("goamz-test-imported", []byte(testPublicKey))
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteKeyPair("goamz-test-imported")
+	c.Check(imported.Name, Equals, "goamz-test-imported")
+	c.Check(imported.Fingerprint, Equals, testPublicKeyFingerprint)
+
+	_, err = s.ec2.ImportKeyPair("goamz-test-imported", []byte(testPublicKey))
+	c.Check(errorCode(err), Equals, "InvalidKeyPair.Duplicate")
+
+	resp, err := s.ec2.KeyPairs([]string{"goamz-test-created", "goamz-test-imported"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.KeyPairs, HasLen, 2)
+
+	f := ec2.NewFilter()
+	f.Add("fingerprint", testPublicKeyFingerprint)
+	resp, err = s.ec2.KeyPairs(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.KeyPairs, DeepEquals, []ec2.KeyPair{imported.KeyPair})
+
+	_, err = s.ec2.DeleteKeyPair("goamz-test-imported")
+	c.Assert(err, IsNil)
+	_, err = s.ec2.KeyPairs([]string{"goamz-test-imported"}, nil)
+	c.Check(errorCode(err), Equals, "InvalidKeyPair.NotFound")
+}
+
+func (s *ServerTests) TestRunInstancesUnknownKeyPair(c *C) {
+	_, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		KeyName:      "goamz-test-no-such-key",
+	})
+	c.Assert(errorCode(err), Equals, "InvalidKeyPair.NotFound")
+}
+
+func (s *LocalServerSuite) TestRunInstancesKeyPair(c *C) {
+	_, err := s.ec2.ImportKeyPair("goamz-test-run", []byte(testPublicKey))
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteKeyPair("goamz-test-run")
+
+	resp, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		KeyName:      "goamz-test-run",
+	})
+	c.Assert(err, IsNil)
+	id := resp.Instances[0].InstanceId
+	defer s.ec2.TerminateInstances([]string{id})
+	c.Check(resp.Instances[0].KeyName, Equals, "goamz-test-run")
+
+	f := ec2.NewFilter()
+	f.Add("key-name", "goamz-test-run")
+	insts, err := s.ec2.Instances([]string{id}, f)
+	c.Assert(err, IsNil)
+	c.Assert(insts.Reservations, HasLen, 1)
+}
@@ -0,0 +1,327 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+)
+
+// LaunchTemplateSpecification selects a launch template, by id or by
+// name, and one of its versions: a version number, "$Latest" or
+// "$Default", which is used when Version is empty.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_LaunchTemplateSpecification.html for more details.
+type LaunchTemplateSpecification struct {
+	Id      string
+	Name    string
+	Version string
+}
+
+// LaunchTemplate describes a launch template.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_LaunchTemplate.html for more details.
+type LaunchTemplate struct {
+	Id                   string `xml:"launchTemplateId"`
+	Name                 string `xml:"launchTemplateName"`
+	CreateTime           string `xml:"createTime"`
+	CreatedBy            string `xml:"createdBy"`
+	DefaultVersionNumber int64  `xml:"defaultVersionNumber"`
+	LatestVersionNumber  int64  `xml:"latestVersionNumber"`
+	Tags                 []Tag  `xml:"tagSet>item"`
+}
+
+// LaunchTemplateData holds the launch parameters of a launch
+// template version.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ResponseLaunchTemplateData.html for more details.
+type LaunchTemplateData struct {
+	ImageId               string                `xml:"imageId"`
+	InstanceType          string                `xml:"instanceType"`
+	KeyName               string                `xml:"keyName"`
+	KernelId              string                `xml:"kernelId"`
+	RamdiskId             string                `xml:"ramDiskId"`
+	AvailZone             string                `xml:"placement>availabilityZone"`
+	PlacementGroupName    string                `xml:"placement>groupName"`
+	Tenancy               string                `xml:"placement>tenancy"`
+	Monitoring            bool                  `xml:"monitoring>enabled"`
+	DisableAPITermination bool                  `xml:"disableApiTermination"`
+	ShutdownBehavior      string                `xml:"instanceInitiatedShutdownBehavior"`
+	SecurityGroupIds      []string              `xml:"securityGroupIdSet>item"`
+	SecurityGroups        []string              `xml:"securityGroupSet>item"`
+	BlockDeviceMappings   []BlockDeviceMapping  `xml:"blockDeviceMappingSet>item"`
+	NetworkInterfaces     []RunNetworkInterface `xml:"networkInterfaceSet>item"`
+	TagSpecifications     []TagSpecification    `xml:"tagSpecificationSet>item"`
+
+	// UserData holds the user data of the instances, decoded.
+	UserData []byte `xml:"userData"`
+}
+
+// LaunchTemplateVersion describes a version of a launch template.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_LaunchTemplateVersion.html for more details.
+type LaunchTemplateVersion struct {
+	TemplateId         string             `xml:"launchTemplateId"`
+	TemplateName       string             `xml:"launchTemplateName"`
+	VersionNumber      int64              `xml:"versionNumber"`
+	VersionDescription string             `xml:"versionDescription"`
+	CreateTime         string             `xml:"createTime"`
+	CreatedBy          string             `xml:"createdBy"`
+	DefaultVersion     bool               `xml:"defaultVersion"`
+	Data               LaunchTemplateData `xml:"launchTemplateData"`
+}
+
+// decodeUserData decodes the user data of the version, which EC2
+// reports encoded.
+func (v *LaunchTemplateVersion) decodeUserData() error {
+	if len(v.Data.UserData) == 0 {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(string(v.Data.UserData))
+	if err != nil {
+		return fmt.Errorf("cannot decode user data: %v", err)
+	}
+	v.Data.UserData = data
+	return nil
+}
+
+// addLaunchTemplateDataParams adds the launch template data given by
+// options to params. The fields of RunInstances that launch templates
+// do not support are not used; see CreateLaunchTemplate.
+func addLaunchTemplateDataParams(params map[string]string, options *RunInstances) {
+	const prefix = "LaunchTemplateData."
+	addLaunchParams(params, prefix, options)
+	// Launch templates spell the ramdisk id differently, and have no
+	// instance-level subnet.
+	if id, ok := params[prefix+"RamdiskId"]; ok {
+		delete(params, prefix+"RamdiskId")
+		params[prefix+"RamDiskId"] = id
+	}
+	delete(params, prefix+"SubnetId")
+	if options.DisableAPITermination {
+		params[prefix+"DisableApiTermination"] = "true"
+	}
+	if options.ShutdownBehavior != "" {
+		params[prefix+"InstanceInitiatedShutdownBehavior"] = options.ShutdownBehavior
+	}
+	for i, spec := range options.TagSpecifications {
+		specPrefix := prefix + "TagSpecification." + strconv.Itoa(i+1)
+		params[specPrefix+".ResourceType"] = spec.ResourceType
+		addTagParams(params, specPrefix+".Tag.", spec.Tags)
+	}
+}
+
+// addLaunchTemplateParams adds the parameters selecting the launch
+// template spec to params, under the given prefix.
+func addLaunchTemplateParams(params map[string]string, prefix string, spec *LaunchTemplateSpecification) {
+	if spec.Id != "" {
+		params[prefix+"LaunchTemplateId"] = spec.Id
+	}
+	if spec.Name != "" {
+		params[prefix+"LaunchTemplateName"] = spec.Name
+	}
+}
+
+// CreateLaunchTemplate holds the options for a CreateLaunchTemplate
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplate.html for more details.
+type CreateLaunchTemplate struct {
+	Name               string
+	VersionDescription string
+
+	// Data holds the launch parameters of the first version of the
+	// template. Its MinCount, MaxCount, SubnetId, PrivateIPAddress,
+	// PartitionNumber, HostId, Affinity and LaunchTemplate fields are
+	// not used; the subnet may be given in NetworkInterfaces.
+	Data RunInstances
+}
+
+// CreateLaunchTemplateResp is the response to a CreateLaunchTemplate
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplate.html for more details.
+type CreateLaunchTemplateResp struct {
+	RequestId      string         `xml:"requestId"`
+	LaunchTemplate LaunchTemplate `xml:"launchTemplate"`
+}
+
+// CreateLaunchTemplate creates a launch template, which holds
+// launch parameters that RunInstances can use instead of repeating
+// them; see RunInstances.LaunchTemplate.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplate.html for more details.
+func (ec2 *EC2) CreateLaunchTemplate(options *CreateLaunchTemplate) (resp *CreateLaunchTemplateResp, err error) {
+	params := makeParamsCurrent("CreateLaunchTemplate")
+	params["LaunchTemplateName"] = options.Name
+	if options.VersionDescription != "" {
+		params["VersionDescription"] = options.VersionDescription
+	}
+	addLaunchTemplateDataParams(params, &options.Data)
+	token, err := clientToken()
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	resp = &CreateLaunchTemplateResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateLaunchTemplateVersion holds the options for a
+// CreateLaunchTemplateVersion request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplateVersion.html for more details.
+type CreateLaunchTemplateVersion struct {
+	// Template selects the launch template by id or name; its
+	// Version is not used.
+	Template LaunchTemplateSpecification
+
+	// SourceVersion, if set, holds the version number the new
+	// version is based on. Data then only holds the parameters that
+	// differ from those of the source version.
+	SourceVersion      string
+	VersionDescription string
+
+	// Data holds the launch parameters of the version, as in
+	// CreateLaunchTemplate.
+	Data RunInstances
+}
+
+// CreateLaunchTemplateVersionResp is the response to a
+// CreateLaunchTemplateVersion request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplateVersion.html for more details.
+type CreateLaunchTemplateVersionResp struct {
+	RequestId string                `xml:"requestId"`
+	Version   LaunchTemplateVersion `xml:"launchTemplateVersion"`
+}
+
+// CreateLaunchTemplateVersion adds a new version to a launch
+// template. The default version of the template does not change.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplateVersion.html for more details.
+func (ec2 *EC2) CreateLaunchTemplateVersion(options *CreateLaunchTemplateVersion) (resp *CreateLaunchTemplateVersionResp, err error) {
+	params := makeParamsCurrent("CreateLaunchTemplateVersion")
+	addLaunchTemplateParams(params, "", &options.Template)
+	if options.SourceVersion != "" {
+		params["SourceVersion"] = options.SourceVersion
+	}
+	if options.VersionDescription != "" {
+		params["VersionDescription"] = options.VersionDescription
+	}
+	addLaunchTemplateDataParams(params, &options.Data)
+	token, err := clientToken()
+	if err != nil {
+		return nil, err
+	}
+	params["ClientToken"] = token
+
+	resp = &CreateLaunchTemplateVersionResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.Version.decodeUserData(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LaunchTemplatesResp is the response to a LaunchTemplates request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeLaunchTemplates.html for more details.
+type LaunchTemplatesResp struct {
+	RequestId       string           `xml:"requestId"`
+	LaunchTemplates []LaunchTemplate `xml:"launchTemplates>item"`
+}
+
+// LaunchTemplates returns the launch templates with the given ids or
+// names, or all of them if both are empty, that match the given
+// filter.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeLaunchTemplates.html for more details.
+func (ec2 *EC2) LaunchTemplates(ids, names []string, filter *Filter) (resp *LaunchTemplatesResp, err error) {
+	params := makeParamsCurrent("DescribeLaunchTemplates")
+	addParamsList(params, "LaunchTemplateId", ids)
+	addParamsList(params, "LaunchTemplateName", names)
+	filter.addParams(params)
+
+	resp = &LaunchTemplatesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LaunchTemplateVersionsResp is the response to a
+// LaunchTemplateVersions request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeLaunchTemplateVersions.html for more details.
+type LaunchTemplateVersionsResp struct {
+	RequestId string                  `xml:"requestId"`
+	Versions  []LaunchTemplateVersion `xml:"launchTemplateVersionSet>item"`
+}
+
+// LaunchTemplateVersions returns the given versions of the launch
+// template selected by tmpl, or all of them if versions is empty,
+// that match the given filter. The versions may be version numbers,
+// "$Latest" or "$Default".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeLaunchTemplateVersions.html for more details.
+func (ec2 *EC2) LaunchTemplateVersions(tmpl LaunchTemplateSpecification, versions []string, filter *Filter) (resp *LaunchTemplateVersionsResp, err error) {
+	params := makeParamsCurrent("DescribeLaunchTemplateVersions")
+	addLaunchTemplateParams(params, "", &tmpl)
+	addParamsList(params, "LaunchTemplateVersion", versions)
+	filter.addParams(params)
+
+	resp = &LaunchTemplateVersionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	for i := range resp.Versions {
+		if err := resp.Versions[i].decodeUserData(); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// DeleteLaunchTemplateResp is the response to a DeleteLaunchTemplate
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteLaunchTemplate.html for more details.
+type DeleteLaunchTemplateResp struct {
+	RequestId      string         `xml:"requestId"`
+	LaunchTemplate LaunchTemplate `xml:"launchTemplate"`
+}
+
+// DeleteLaunchTemplate deletes the launch template selected by tmpl,
+// with all its versions. Instances launched from it are not
+// affected.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteLaunchTemplate.html for more details.
+func (ec2 *EC2) DeleteLaunchTemplate(tmpl LaunchTemplateSpecification) (resp *DeleteLaunchTemplateResp, err error) {
+	params := makeParamsCurrent("DeleteLaunchTemplate")
+	addLaunchTemplateParams(params, "", &tmpl)
+
+	resp = &DeleteLaunchTemplateResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
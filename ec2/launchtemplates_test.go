@@ -0,0 +1,347 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"encoding/base64"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// Launch template tests with example responses
+
+func (s *S) TestCreateLaunchTemplateExample(c *C) {
+	testServer.Response(200, nil, CreateLaunchTemplateExample)
+
+	resp, err := s.ec2.CreateLaunchTemplate(&ec2.CreateLaunchTemplate{
+		Name:               "MyLaunchTemplate",
+		VersionDescription: "WebVersion1",
+		Data: ec2.RunInstances{
+			ImageId:          "ami-8c1be5f6",
+			InstanceType:     "t2.small",
+			RamdiskId:        "ari-1",
+			SubnetId:         "subnet-7b16de0c",
+			SecurityGroups:   []ec2.SecurityGroup{{Id: "sg-7c227019"}},
+			UserData:         []byte("hello"),
+			ShutdownBehavior: "terminate",
+			NetworkInterfaces: []ec2.RunNetworkInterface{{
+				DeviceIndex:         0,
+				SubnetId:            "subnet-7b16de0c",
+				DeleteOnTermination: true,
+			}},
+			TagSpecifications: []ec2.TagSpecification{{
+				ResourceType: "instance",
+				Tags:         []ec2.Tag{{"purpose", "webserver"}},
+			}},
+		},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplateName"], DeepEquals, []string{"MyLaunchTemplate"})
+	c.Assert(req.Form["VersionDescription"], DeepEquals, []string{"WebVersion1"})
+	c.Assert(req.Form["LaunchTemplateData.ImageId"], DeepEquals, []string{"ami-8c1be5f6"})
+	c.Assert(req.Form["LaunchTemplateData.InstanceType"], DeepEquals, []string{"t2.small"})
+	c.Assert(req.Form["LaunchTemplateData.RamDiskId"], DeepEquals, []string{"ari-1"})
+	c.Assert(req.Form["LaunchTemplateData.RamdiskId"], IsNil)
+	c.Assert(req.Form["LaunchTemplateData.SubnetId"], IsNil)
+	c.Assert(req.Form["LaunchTemplateData.SecurityGroupId.1"], DeepEquals, []string{"sg-7c227019"})
+	c.Assert(req.Form["LaunchTemplateData.UserData"], DeepEquals, []string{base64.StdEncoding.EncodeToString([]byte("hello"))})
+	c.Assert(req.Form["LaunchTemplateData.InstanceInitiatedShutdownBehavior"], DeepEquals, []string{"terminate"})
+	c.Assert(req.Form["LaunchTemplateData.NetworkInterface.0.DeviceIndex"], DeepEquals, []string{"0"})
+	c.Assert(req.Form["LaunchTemplateData.NetworkInterface.0.SubnetId"], DeepEquals, []string{"subnet-7b16de0c"})
+	c.Assert(req.Form["LaunchTemplateData.NetworkInterface.0.DeleteOnTermination"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["LaunchTemplateData.TagSpecification.1.ResourceType"], DeepEquals, []string{"instance"})
+	c.Assert(req.Form["LaunchTemplateData.TagSpecification.1.Tag.1.Key"], DeepEquals, []string{"purpose"})
+	c.Assert(req.Form["LaunchTemplateData.TagSpecification.1.Tag.1.Value"], DeepEquals, []string{"webserver"})
+	c.Assert(req.Form["ClientToken"], HasLen, 1)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "39f77e1b-e1ed-4f43-a1ad-d9b4cEXAMPLE")
+	c.Assert(resp.LaunchTemplate, DeepEquals, ec2.LaunchTemplate{
+		Id:                   "lt-01238c059e3466abc",
+		Name:                 "MyLaunchTemplate",
+		CreateTime:           "2017-10-31T11:38:52.000Z",
+		CreatedBy:            "arn:aws:iam::123456789012:root",
+		DefaultVersionNumber: 1,
+		LatestVersionNumber:  1,
+	})
+}
+
+func (s *S) TestCreateLaunchTemplateVersionExample(c *C) {
+	testServer.Response(200, nil, CreateLaunchTemplateVersionExample)
+
+	resp, err := s.ec2.CreateLaunchTemplateVersion(&ec2.CreateLaunchTemplateVersion{
+		Template:           ec2.LaunchTemplateSpecification{Id: "lt-0abcd290751193123"},
+		SourceVersion:      "1",
+		VersionDescription: "Version2",
+		Data:               ec2.RunInstances{ImageId: "ami-8c1be5f6"},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateLaunchTemplateVersion"})
+	c.Assert(req.Form["LaunchTemplateId"], DeepEquals, []string{"lt-0abcd290751193123"})
+	c.Assert(req.Form["LaunchTemplateName"], IsNil)
+	c.Assert(req.Form["SourceVersion"], DeepEquals, []string{"1"})
+	c.Assert(req.Form["VersionDescription"], DeepEquals, []string{"Version2"})
+	c.Assert(req.Form["LaunchTemplateData.ImageId"], DeepEquals, []string{"ami-8c1be5f6"})
+	c.Assert(req.Form["LaunchTemplateData.InstanceType"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "6657423a-2616-461a-9ce5-3c65EXAMPLE")
+	v := resp.Version
+	c.Check(v.TemplateId, Equals, "lt-0abcd290751193123")
+	c.Check(v.TemplateName, Equals, "ExampleLaunchTemplate")
+	c.Check(v.VersionNumber, Equals, int64(2))
+	c.Check(v.VersionDescription, Equals, "Version2")
+	c.Check(v.DefaultVersion, Equals, false)
+	c.Check(v.Data.ImageId, Equals, "ami-8c1be5f6")
+	c.Check(v.Data.InstanceType, Equals, "t2.micro")
+	c.Check(string(v.Data.UserData), Equals, "hello")
+	c.Check(v.Data.NetworkInterfaces, DeepEquals, []ec2.RunNetworkInterface{{
+		DeviceIndex:         0,
+		SubnetId:            "subnet-7b16de0c",
+		SecurityGroupIds:    []string{"sg-7c227019"},
+		DeleteOnTermination: true,
+	}})
+}
+
+func (s *S) TestLaunchTemplatesExample(c *C) {
+	testServer.Response(200, nil, DescribeLaunchTemplatesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("tag:purpose", "production")
+	resp, err := s.ec2.LaunchTemplates([]string{"lt-01238c059e3466abc"}, []string{"my-template"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeLaunchTemplates"})
+	c.Assert(req.Form["LaunchTemplateId.1"], DeepEquals, []string{"lt-01238c059e3466abc"})
+	c.Assert(req.Form["LaunchTemplateName.1"], DeepEquals, []string{"my-template"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"tag:purpose"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"production"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "1afa6e44-eb38-4229-8db6-d5eaEXAMPLE")
+	c.Assert(resp.LaunchTemplates, DeepEquals, []ec2.LaunchTemplate{{
+		Id:                   "lt-01238c059e3466abc",
+		Name:                 "my-template",
+		CreateTime:           "2017-10-31T11:38:52.000Z",
+		CreatedBy:            "arn:aws:iam::123456789012:root",
+		DefaultVersionNumber: 1,
+		LatestVersionNumber:  2,
+		Tags:                 []ec2.Tag{{"purpose", "production"}},
+	}})
+}
+
+func (s *S) TestLaunchTemplateVersionsExample(c *C) {
+	testServer.Response(200, nil, DescribeLaunchTemplateVersionsExample)
+
+	resp, err := s.ec2.LaunchTemplateVersions(ec2.LaunchTemplateSpecification{Name: "Webservers"}, []string{"1", "$Latest"}, nil)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeLaunchTemplateVersions"})
+	c.Assert(req.Form["LaunchTemplateName"], DeepEquals, []string{"Webservers"})
+	c.Assert(req.Form["LaunchTemplateVersion.1"], DeepEquals, []string{"1"})
+	c.Assert(req.Form["LaunchTemplateVersion.2"], DeepEquals, []string{"$Latest"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "65cadec1-b364-4354-8ca8-4176dEXAMPLE")
+	c.Assert(resp.Versions, HasLen, 1)
+	v := resp.Versions[0]
+	c.Check(v.TemplateId, Equals, "lt-068f72b72934aff71")
+	c.Check(v.VersionNumber, Equals, int64(1))
+	c.Check(v.DefaultVersion, Equals, true)
+	c.Check(v.Data, DeepEquals, ec2.LaunchTemplateData{
+		ImageId:          "ami-6057e21a",
+		InstanceType:     "t2.micro",
+		KeyName:          "user-key-pair",
+		AvailZone:        "us-east-1a",
+		Tenancy:          "default",
+		SecurityGroupIds: []string{"sg-7c227019"},
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+			DeviceName:          "/dev/xvda",
+			VolumeSize:          20,
+			VolumeType:          "gp2",
+			DeleteOnTermination: true,
+		}},
+		TagSpecifications: []ec2.TagSpecification{{
+			ResourceType: "instance",
+			Tags:         []ec2.Tag{{"Name", "webserver"}},
+		}},
+		DisableAPITermination: true,
+		ShutdownBehavior:      "terminate",
+	})
+}
+
+func (s *S) TestDeleteLaunchTemplateExample(c *C) {
+	testServer.Response(200, nil, DeleteLaunchTemplateExample)
+
+	resp, err := s.ec2.DeleteLaunchTemplate(ec2.LaunchTemplateSpecification{Id: "lt-0a20c965061f64abc"})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteLaunchTemplate"})
+	c.Assert(req.Form["LaunchTemplateId"], DeepEquals, []string{"lt-0a20c965061f64abc"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "a8d4bc6d-4f8e-4a14-a5ac-95b6EXAMPLE")
+	c.Assert(resp.LaunchTemplate.Id, Equals, "lt-0a20c965061f64abc")
+	c.Assert(resp.LaunchTemplate.Name, Equals, "my-template")
+}
+
+func (s *S) TestRunInstancesLaunchTemplateExample(c *C) {
+	testServer.Response(200, nil, RunInstancesExample)
+
+	_, err := s.ec2.RunInstances(&ec2.RunInstances{
+		InstanceType: "t2.large",
+		LaunchTemplate: &ec2.LaunchTemplateSpecification{
+			Name:    "my-template",
+			Version: "$Latest",
+		},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RunInstances"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["LaunchTemplate.LaunchTemplateName"], DeepEquals, []string{"my-template"})
+	c.Assert(req.Form["LaunchTemplate.Version"], DeepEquals, []string{"$Latest"})
+	c.Assert(req.Form["InstanceType"], DeepEquals, []string{"t2.large"})
+	c.Assert(req.Form["ImageId"], IsNil)
+	c.Assert(err, IsNil)
+}
+
+// Launch template tests run only against the local test server.
+
+func (s *LocalServerSuite) TestLaunchTemplates(c *C) {
+	created, err := s.ec2.CreateLaunchTemplate(&ec2.CreateLaunchTemplate{
+		Name:               "goamz-test-template",
+		VersionDescription: "first",
+		Data: ec2.RunInstances{
+			ImageId:          imageId,
+			InstanceType:     "m1.template",
+			UserData:         []byte("hello"),
+			ShutdownBehavior: "terminate",
+			TagSpecifications: []ec2.TagSpecification{{
+				ResourceType: "instance",
+				Tags:         []ec2.Tag{{"Name", "templated"}},
+			}},
+		},
+	})
+	c.Assert(err, IsNil)
+	tmpl := created.LaunchTemplate
+	defer s.ec2.DeleteLaunchTemplate(ec2.LaunchTemplateSpecification{Id: tmpl.Id})
+	c.Check(tmpl.Id, Matches, "lt-.+")
+	c.Check(tmpl.Name, Equals, "goamz-test-template")
+	c.Check(tmpl.DefaultVersionNumber, Equals, int64(1))
+	c.Check(tmpl.LatestVersionNumber, Equals, int64(1))
+
+	_, err = s.ec2.CreateLaunchTemplate(&ec2.CreateLaunchTemplate{Name: "goamz-test-template"})
+	c.Check(errorCode(err), Equals, "InvalidLaunchTemplateName.AlreadyExistsException")
+
+	// The second version is based on the first one.
+	version, err := s.ec2.CreateLaunchTemplateVersion(&ec2.CreateLaunchTemplateVersion{
+		Template:           ec2.LaunchTemplateSpecification{Name: "goamz-test-template"},
+		SourceVersion:      "1",
+		VersionDescription: "second",
+		Data:               ec2.RunInstances{InstanceType: "m1.template2"},
+	})
+	c.Assert(err, IsNil)
+	c.Check(version.Version.VersionNumber, Equals, int64(2))
+	c.Check(version.Version.DefaultVersion, Equals, false)
+	c.Check(version.Version.Data.ImageId, Equals, imageId)
+	c.Check(version.Version.Data.InstanceType, Equals, "m1.template2")
+	c.Check(string(version.Version.Data.UserData), Equals, "hello")
+
+	templates, err := s.ec2.LaunchTemplates(nil, []string{"goamz-test-template"}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(templates.LaunchTemplates, HasLen, 1)
+	c.Check(templates.LaunchTemplates[0].LatestVersionNumber, Equals, int64(2))
+
+	versions, err := s.ec2.LaunchTemplateVersions(ec2.LaunchTemplateSpecification{Id: tmpl.Id}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(versions.Versions, HasLen, 2)
+	c.Check(versions.Versions[0].VersionDescription, Equals, "first")
+	c.Check(versions.Versions[0].DefaultVersion, Equals, true)
+	c.Check(versions.Versions[0].Data.ShutdownBehavior, Equals, "terminate")
+	c.Check(versions.Versions[0].Data.TagSpecifications, DeepEquals, []ec2.TagSpecification{{
+		ResourceType: "instance",
+		Tags:         []ec2.Tag{{"Name", "templated"}},
+	}})
+	c.Check(versions.Versions[1].VersionDescription, Equals, "second")
+
+	f := ec2.NewFilter()
+	f.Add("instance-type", "m1.template2")
+	versions, err = s.ec2.LaunchTemplateVersions(ec2.LaunchTemplateSpecification{Id: tmpl.Id}, []string{"$Default", "$Latest"}, f)
+	c.Assert(err, IsNil)
+	c.Assert(versions.Versions, HasLen, 1)
+	c.Check(versions.Versions[0].VersionNumber, Equals, int64(2))
+
+	// Instances are launched from the default version unless
+	// another one is selected, and the request parameters override
+	// those of the template.
+	resp, err := s.ec2.RunInstances(&ec2.RunInstances{
+		LaunchTemplate: &ec2.LaunchTemplateSpecification{Id: tmpl.Id},
+	})
+	c.Assert(err, IsNil)
+	ids := []string{resp.Instances[0].InstanceId}
+	defer func() {
+		// Remove the tags, which terminated instances keep.
+		_, err := s.ec2.DeleteTags(ids, nil)
+		c.Check(err, IsNil)
+		terminateInstances(c, s.ec2, ids)
+	}()
+	inst := resp.Instances[0]
+	c.Check(inst.ImageId, Equals, imageId)
+	c.Check(inst.InstanceType, Equals, "m1.template")
+	c.Check(inst.Tags, DeepEquals, []ec2.Tag{
+		{"Name", "templated"},
+		{"aws:ec2launchtemplate:id", tmpl.Id},
+		{"aws:ec2launchtemplate:version", "1"},
+	})
+	attr, err := s.ec2.InstanceAttribute(inst.InstanceId, "instanceInitiatedShutdownBehavior")
+	c.Assert(err, IsNil)
+	c.Check(attr.ShutdownBehavior, Equals, "terminate")
+
+	resp, err = s.ec2.RunInstances(&ec2.RunInstances{
+		InstanceType: "m1.override",
+		TagSpecifications: []ec2.TagSpecification{{
+			ResourceType: "instance",
+			Tags:         []ec2.Tag{{"Name", "overridden"}},
+		}},
+		LaunchTemplate: &ec2.LaunchTemplateSpecification{
+			Name:    "goamz-test-template",
+			Version: "$Latest",
+		},
+	})
+	c.Assert(err, IsNil)
+	ids = append(ids, resp.Instances[0].InstanceId)
+	inst = resp.Instances[0]
+	c.Check(inst.ImageId, Equals, imageId)
+	c.Check(inst.InstanceType, Equals, "m1.override")
+	c.Check(inst.Tags, DeepEquals, []ec2.Tag{
+		{"Name", "overridden"},
+		{"aws:ec2launchtemplate:id", tmpl.Id},
+		{"aws:ec2launchtemplate:version", "2"},
+	})
+
+	_, err = s.ec2.RunInstances(&ec2.RunInstances{
+		LaunchTemplate: &ec2.LaunchTemplateSpecification{Id: tmpl.Id, Version: "3"},
+	})
+	c.Check(errorCode(err), Equals, "InvalidLaunchTemplateId.VersionNotFound")
+	_, err = s.ec2.RunInstances(&ec2.RunInstances{
+		LaunchTemplate: &ec2.LaunchTemplateSpecification{Name: "goamz-test-no-such-template"},
+	})
+	c.Check(errorCode(err), Equals, "InvalidLaunchTemplateName.NotFoundException")
+
+	_, err = s.ec2.DeleteLaunchTemplate(ec2.LaunchTemplateSpecification{Name: "goamz-test-template"})
+	c.Assert(err, IsNil)
+	_, err = s.ec2.LaunchTemplates([]string{tmpl.Id}, nil, nil)
+	c.Check(errorCode(err), Equals, "InvalidLaunchTemplateId.NotFound")
+}
@@ -0,0 +1,119 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// NatGateway describes a NAT gateway, which lets instances in
+// private subnets reach the Internet. State is one of "pending",
+// "failed", "available", "deleting" or "deleted".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_NatGateway.html for more details.
+type NatGateway struct {
+	Id             string              `xml:"natGatewayId"`
+	SubnetId       string              `xml:"subnetId"`
+	VPCId          string              `xml:"vpcId"`
+	State          string              `xml:"state"`
+	FailureCode    string              `xml:"failureCode"`
+	FailureMessage string              `xml:"failureMessage"`
+	Addresses      []NatGatewayAddress `xml:"natGatewayAddressSet>item"`
+	CreateTime     string              `xml:"createTime"`
+	DeleteTime     string              `xml:"deleteTime"`
+	Tags           []Tag               `xml:"tagSet>item"`
+}
+
+// NatGatewayAddress describes the Elastic IP address and the network
+// interface of a NAT gateway.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_NatGatewayAddress.html for more details.
+type NatGatewayAddress struct {
+	AllocationId       string `xml:"allocationId"`
+	NetworkInterfaceId string `xml:"networkInterfaceId"`
+	PrivateIP          string `xml:"privateIp"`
+	PublicIP           string `xml:"publicIp"`
+}
+
+// CreateNatGatewayResp is the response to a CreateNatGateway request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateNatGateway.html for more details.
+type CreateNatGatewayResp struct {
+	RequestId  string     `xml:"requestId"`
+	NatGateway NatGateway `xml:"natGateway"`
+}
+
+// CreateNatGateway creates a NAT gateway in the subnet with the given
+// id, using the Elastic IP address in the "vpc" domain with the given
+// allocation id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateNatGateway.html for more details.
+func (ec2 *EC2) CreateNatGateway(subnetId, allocationId string) (resp *CreateNatGatewayResp, err error) {
+	params := makeParamsCurrent("CreateNatGateway")
+	params["SubnetId"] = subnetId
+	params["AllocationId"] = allocationId
+	resp = &CreateNatGatewayResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteNatGatewayResp is the response to a DeleteNatGateway request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteNatGateway.html for more details.
+type DeleteNatGatewayResp struct {
+	RequestId    string `xml:"requestId"`
+	NatGatewayId string `xml:"natGatewayId"`
+}
+
+// DeleteNatGateway deletes the NAT gateway with the given id,
+// disassociating its Elastic IP address and deleting its network
+// interface.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteNatGateway.html for more details.
+func (ec2 *EC2) DeleteNatGateway(id string) (resp *DeleteNatGatewayResp, err error) {
+	params := makeParamsCurrent("DeleteNatGateway")
+	params["NatGatewayId"] = id
+	resp = &DeleteNatGatewayResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// NatGatewaysResp is the response to a NatGateways request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeNatGateways.html for more details.
+type NatGatewaysResp struct {
+	RequestId   string       `xml:"requestId"`
+	NatGateways []NatGateway `xml:"natGatewaySet>item"`
+}
+
+// NatGateways describes one or more NAT gateways. Both parameters
+// are optional, and if specified will limit the returned gateways to
+// the matching ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeNatGateways.html for more details.
+func (ec2 *EC2) NatGateways(ids []string, filter *Filter) (resp *NatGatewaysResp, err error) {
+	params := makeParamsCurrent("DescribeNatGateways")
+	for i, id := range ids {
+		params["NatGatewayId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &NatGatewaysResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
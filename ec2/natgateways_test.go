@@ -0,0 +1,156 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// NAT gateway tests with example responses
+
+func (s *S) TestCreateNatGatewayExample(c *C) {
+	testServer.Response(200, nil, CreateNatGatewayExample)
+
+	resp, err := s.ec2.CreateNatGateway("subnet-1a2b3c4d", "eipalloc-37fc1a52")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateNatGateway"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["SubnetId"], DeepEquals, []string{"subnet-1a2b3c4d"})
+	c.Assert(req.Form["AllocationId"], DeepEquals, []string{"eipalloc-37fc1a52"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.NatGateway, DeepEquals, ec2.NatGateway{
+		Id:       "nat-04e77a5e9c34432f9",
+		SubnetId: "subnet-1a2b3c4d",
+		VPCId:    "vpc-4e20d42b",
+		State:    "pending",
+		Addresses: []ec2.NatGatewayAddress{{
+			AllocationId: "eipalloc-37fc1a52",
+		}},
+		CreateTime: "2015-11-25T14:00:55.416Z",
+	})
+}
+
+func (s *S) TestDeleteNatGatewayExample(c *C) {
+	testServer.Response(200, nil, DeleteNatGatewayExample)
+
+	resp, err := s.ec2.DeleteNatGateway("nat-04ae55e711cec5680")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteNatGateway"})
+	c.Assert(req.Form["NatGatewayId"], DeepEquals, []string{"nat-04ae55e711cec5680"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "741fc8ab-6ebe-452b-b92b-example")
+	c.Assert(resp.NatGatewayId, Equals, "nat-04ae55e711cec5680")
+}
+
+func (s *S) TestNatGatewaysExample(c *C) {
+	testServer.Response(200, nil, DescribeNatGatewaysExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("state", "available")
+	resp, err := s.ec2.NatGateways([]string{"nat-04e77a5e9c34432f9"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeNatGateways"})
+	c.Assert(req.Form["NatGatewayId.1"], DeepEquals, []string{"nat-04e77a5e9c34432f9"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"state"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"available"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.NatGateways, HasLen, 1)
+	c.Assert(resp.NatGateways[0].Addresses, DeepEquals, []ec2.NatGatewayAddress{{
+		AllocationId:       "eipalloc-37fc1a52",
+		NetworkInterfaceId: "eni-00e37850",
+		PrivateIP:          "10.0.2.147",
+		PublicIP:           "198.18.125.129",
+	}})
+	c.Assert(resp.NatGateways[0].State, Equals, "available")
+}
+
+// NAT gateway tests run against a local test server, as NAT gateways
+// take minutes to become available on EC2.
+
+func (s *LocalServerSuite) TestNatGateways(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.11.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	subResp := s.createSubnet(c, vpcId, "10.11.1.0/24", "")
+	subId := subResp.Subnet.Id
+
+	_, err = s.ec2.CreateNatGateway(subId, "eipalloc-999")
+	c.Check(errorCode(err), Equals, "InvalidAllocationID.NotFound")
+
+	alloc, err := s.ec2.AllocateAddress("vpc")
+	c.Assert(err, IsNil)
+
+	created, err := s.ec2.CreateNatGateway(subId, alloc.AllocationId)
+	c.Assert(err, IsNil)
+	g := created.NatGateway
+	c.Check(g.Id, Matches, "nat-.+")
+	c.Check(g.SubnetId, Equals, subId)
+	c.Check(g.VPCId, Equals, vpcId)
+	c.Check(g.State, Equals, "available")
+	c.Assert(g.Addresses, HasLen, 1)
+	c.Check(g.Addresses[0].AllocationId, Equals, alloc.AllocationId)
+	c.Check(g.Addresses[0].PublicIP, Equals, alloc.PublicIP)
+	c.Check(g.Addresses[0].PrivateIP, Equals, "10.11.1.6")
+
+	// The address is associated with the gateway's interface.
+	addrs, err := s.ec2.Addresses(nil, []string{alloc.AllocationId}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(addrs.Addresses, HasLen, 1)
+	c.Check(addrs.Addresses[0].NetworkInterfaceId, Equals, g.Addresses[0].NetworkInterfaceId)
+	c.Check(addrs.Addresses[0].PrivateIPAddress, Equals, "10.11.1.6")
+
+	_, err = s.ec2.CreateNatGateway(subId, alloc.AllocationId)
+	c.Check(errorCode(err), Equals, "Resource.AlreadyAssociated")
+
+	// Routes may target the gateway.
+	rtResp, err := s.ec2.CreateRouteTable(vpcId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.CreateRoute(rtResp.RouteTable.Id, ec2.Route{
+		DestinationCIDRBlock: "0.0.0.0/0",
+		NatGatewayId:         g.Id,
+	})
+	c.Assert(err, IsNil)
+
+	f := ec2.NewFilter()
+	f.Add("vpc-id", vpcId)
+	resp, err := s.ec2.NatGateways(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.NatGateways, HasLen, 1)
+	c.Check(resp.NatGateways[0].Id, Equals, g.Id)
+
+	deleted, err := s.ec2.DeleteNatGateway(g.Id)
+	c.Assert(err, IsNil)
+	c.Check(deleted.NatGatewayId, Equals, g.Id)
+
+	// Deleted gateways are still reported, and their address is
+	// released.
+	resp, err = s.ec2.NatGateways([]string{g.Id}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.NatGateways, HasLen, 1)
+	c.Check(resp.NatGateways[0].State, Equals, "deleted")
+	c.Check(resp.NatGateways[0].DeleteTime, Not(Equals), "")
+	addrs, err = s.ec2.Addresses(nil, []string{alloc.AllocationId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(addrs.Addresses[0].NetworkInterfaceId, Equals, "")
+
+	rts, err := s.ec2.RouteTables([]string{rtResp.RouteTable.Id}, nil)
+	c.Assert(err, IsNil)
+	c.Check(rts.RouteTables[0].Routes[1].State, Equals, "blackhole")
+
+	_, err = s.ec2.NatGateways([]string{"nat-999"}, nil)
+	c.Check(errorCode(err), Equals, "NatGatewayNotFound")
+}
@@ -27,13 +27,27 @@ type NetworkInterfaceAttachment struct {
 	DeleteOnTermination bool   `xml:"deleteOnTermination"`
 }
 
+// NetworkInterfaceAssociation describes the association of an
+// Elastic IP address with a network interface or one of its private
+// IP addresses.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_NetworkInterfaceAssociation.html for more details.
+type NetworkInterfaceAssociation struct {
+	PublicIP      string `xml:"publicIp"`
+	PublicDNSName string `xml:"publicDnsName"`
+	IPOwnerId     string `xml:"ipOwnerId"`
+	AllocationId  string `xml:"allocationId"`
+	AssociationId string `xml:"associationId"`
+}
+
 // PrivateIP describes a private IP address of a network interface.
 //
 // See http://goo.gl/jtuQEJ for more details.
 type PrivateIP struct {
-	Address   string `xml:"privateIpAddress"`
-	DNSName   string `xml:"privateDnsName"`
-	IsPrimary bool   `xml:"primary"`
+	Address     string                      `xml:"privateIpAddress"`
+	DNSName     string                      `xml:"privateDnsName"`
+	IsPrimary   bool                        `xml:"primary"`
+	Association NetworkInterfaceAssociation `xml:"association"`
 }
 
 // NetworkInterface describes a network interface for VPC.
@@ -57,6 +71,14 @@ type NetworkInterface struct {
 	Attachment       NetworkInterfaceAttachment `xml:"attachment"`
 	Tags             []Tag                      `xml:"tagSet>item"`
 	PrivateIPs       []PrivateIP                `xml:"privateIpAddressesSet>item"`
+
+	// Association describes the Elastic IP address associated
+	// with the primary private IP address, if any.
+	Association NetworkInterfaceAssociation `xml:"association"`
+
+	// IPv6Addresses holds the IPv6 addresses assigned to the
+	// network interface.
+	IPv6Addresses []string `xml:"ipv6AddressesSet>item>ipv6Address"`
 }
 
 // CreateNetworkInterface encapsulates options for the
@@ -75,12 +97,18 @@ type NetworkInterface struct {
 // number of IP addresses from within the subnet range.  The number of
 // IP addresses you can assign to a network interface varies by
 // instance type.
+//
+// IPv6 addresses from the subnet's IPv6 CIDR block can be assigned
+// either explicitly with IPv6Addresses or by setting IPv6AddressCount
+// to the number of addresses EC2 should allocate.
 type CreateNetworkInterface struct {
 	SubnetId                string
 	PrivateIPs              []PrivateIP
 	SecondaryPrivateIPCount int
 	Description             string
 	SecurityGroupIds        []string
+	IPv6Addresses           []string
+	IPv6AddressCount        int
 }
 
 // CreateNetworkInterfaceResp is the response to a
@@ -97,7 +125,13 @@ type CreateNetworkInterfaceResp struct {
 //
 // See http://goo.gl/ze3VhA for more details.
 func (ec2 *EC2) CreateNetworkInterface(opts CreateNetworkInterface) (resp *CreateNetworkInterfaceResp, err error) {
-	params := makeParamsVPC("CreateNetworkInterface")
+	var params map[string]string
+	if len(opts.IPv6Addresses) > 0 || opts.IPv6AddressCount > 0 {
+		// IPv6 addresses need the current API version.
+		params = makeParamsCurrent("CreateNetworkInterface")
+	} else {
+		params = makeParamsVPC("CreateNetworkInterface")
+	}
 	params["SubnetId"] = opts.SubnetId
 	for i, ip := range opts.PrivateIPs {
 		prefix := fmt.Sprintf("PrivateIpAddresses.%d.", i+1)
@@ -114,6 +148,12 @@ func (ec2 *EC2) CreateNetworkInterface(opts CreateNetworkInterface) (resp *Creat
 	for i, groupId := range opts.SecurityGroupIds {
 		params["SecurityGroupId."+strconv.Itoa(i+1)] = groupId
 	}
+	for i, ip := range opts.IPv6Addresses {
+		params["Ipv6Addresses."+strconv.Itoa(i+1)+".Ipv6Address"] = ip
+	}
+	if opts.IPv6AddressCount > 0 {
+		params["Ipv6AddressCount"] = strconv.Itoa(opts.IPv6AddressCount)
+	}
 	resp = &CreateNetworkInterfaceResp{}
 	err = ec2.query(params, resp)
 	if err != nil {
@@ -144,6 +184,7 @@ func (ec2 *EC2) DeleteNetworkInterface(id string) (resp *SimpleResp, err error)
 type NetworkInterfacesResp struct {
 	RequestId  string             `xml:"requestId"`
 	Interfaces []NetworkInterface `xml:"networkInterfaceSet>item"`
+	NextToken  string             `xml:"nextToken"`
 }
 
 // NetworkInterfaces returns a list of network interfaces.
@@ -153,11 +194,19 @@ type NetworkInterfacesResp struct {
 //
 // See http://goo.gl/2LcXtM for more details.
 func (ec2 *EC2) NetworkInterfaces(ids []string, filter *Filter) (resp *NetworkInterfacesResp, err error) {
+	return ec2.NetworkInterfacesPage(ids, filter, 0, "")
+}
+
+// NetworkInterfacesPage is like NetworkInterfaces, but it returns a
+// single page of results. See InstancesPage for how pages are
+// selected.
+func (ec2 *EC2) NetworkInterfacesPage(ids []string, filter *Filter, maxResults int, nextToken string) (resp *NetworkInterfacesResp, err error) {
 	params := makeParamsVPC("DescribeNetworkInterfaces")
 	for i, id := range ids {
 		params["NetworkInterfaceId."+strconv.Itoa(i+1)] = id
 	}
 	filter.addParams(params)
+	addPageParams(params, maxResults, nextToken)
 
 	resp = &NetworkInterfacesResp{}
 	err = ec2.query(params, resp)
@@ -9,6 +9,7 @@
 package ec2_test
 
 import (
+	"sort"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -320,7 +321,7 @@ func (s *ServerTests) TestNetworkInterfaces(c *C) {
 	c.Check(att.Id, Equals, attResp.AttachmentId)
 	c.Check(att.InstanceId, Equals, instId)
 	c.Check(att.DeviceIndex, Equals, 1)
-	c.Check(att.Status, Matches, "(attaching|in-use)")
+	c.Check(att.Status, Matches, "(attaching|attached)")
 
 	_, err = s.ec2.DetachNetworkInterface(att.Id, true)
 	c.Check(err, IsNil)
@@ -360,3 +361,133 @@ func assertNetworkInterface(c *C, obtained ec2.NetworkInterface, expectId, expec
 		c.Check(obtained.PrivateIPAddress, DeepEquals, expectIPs[0].Address)
 	}
 }
+
+// Network interface tests run only against the local test server.
+
+func (s *LocalServerSuite) TestNetworkInterfaceFiltering(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.25.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	defer s.ec2.DeleteVPC(vpcId)
+	subResp, err := s.ec2.CreateSubnet(vpcId, "10.25.1.0/24", "")
+	c.Assert(err, IsNil)
+	subId := subResp.Subnet.Id
+	defer s.ec2.DeleteSubnet(subId)
+	groupResp, err := s.ec2.CreateSecurityGroupVPC(vpcId, "nicfilter", "nic filtering group")
+	c.Assert(err, IsNil)
+	group := groupResp.SecurityGroup
+	defer s.ec2.DeleteSecurityGroup(group)
+
+	instResp, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "m1.nicfilter",
+		SubnetId:     subId,
+	})
+	c.Assert(err, IsNil)
+	instId := instResp.Instances[0].InstanceId
+	defer terminateInstances(c, s.ec2, []string{instId})
+
+	resp1, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+		SubnetId:    subId,
+		PrivateIPs:  []ec2.PrivateIP{{Address: "10.25.1.10", IsPrimary: true}},
+		Description: "first filtered iface",
+	})
+	c.Assert(err, IsNil)
+	id1 := resp1.NetworkInterface.Id
+	defer s.ec2.DeleteNetworkInterface(id1)
+	resp2, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{
+		SubnetId: subId,
+		PrivateIPs: []ec2.PrivateIP{
+			{Address: "10.25.1.20", IsPrimary: true},
+			{Address: "10.25.1.21"},
+		},
+		SecurityGroupIds: []string{group.Id},
+		Description:      "second filtered iface",
+	})
+	c.Assert(err, IsNil)
+	id2 := resp2.NetworkInterface.Id
+	defer s.ec2.DeleteNetworkInterface(id2)
+	_, err = s.ec2.CreateTags([]string{id2}, []ec2.Tag{{"Role", "frontend"}})
+	c.Assert(err, IsNil)
+	attResp, err := s.ec2.AttachNetworkInterface(id2, instId, 1)
+	c.Assert(err, IsNil)
+	defer s.ec2.DetachNetworkInterface(attResp.AttachmentId, true)
+
+	for i, t := range []struct {
+		filters []filterSpec
+		ids     []string
+		err     string
+	}{{
+		filters: []filterSpec{{"description", []string{"* filtered iface"}}},
+		ids:     []string{id1, id2},
+	}, {
+		filters: []filterSpec{{"description", []string{"?irst*"}}},
+		ids:     []string{id1},
+	}, {
+		filters: []filterSpec{{"private-ip-address", []string{"10.25.1.20"}}},
+		ids:     []string{id2},
+	}, {
+		filters: []filterSpec{{"addresses.private-ip-address", []string{"10.25.1.21"}}},
+		ids:     []string{id2},
+	}, {
+		filters: []filterSpec{
+			{"addresses.private-ip-address", []string{"10.25.1.21"}},
+			{"addresses.primary", []string{"false"}},
+		},
+		ids: []string{id2},
+	}, {
+		filters: []filterSpec{
+			{"attachment.instance-id", []string{instId}},
+			{"attachment.device-index", []string{"1"}},
+		},
+		ids: []string{id2},
+	}, {
+		filters: []filterSpec{
+			{"subnet-id", []string{subId}},
+			{"status", []string{"available"}},
+		},
+		ids: []string{id1},
+	}, {
+		filters: []filterSpec{{"group-name", []string{"nicfil*"}}},
+		ids:     []string{id2},
+	}, {
+		filters: []filterSpec{{"tag:Role", []string{"front*"}}},
+		ids:     []string{id2},
+	}, {
+		filters: []filterSpec{{"addresses.primary", []string{"maybe"}}},
+		err:     `.*\(InvalidParameterValue\)`,
+	}, {
+		filters: []filterSpec{{"no-such-filter", []string{"value"}}},
+		err:     `.*\(InvalidParameterValue\)`,
+	}} {
+		c.Logf("test %d: %v", i, t.filters)
+		f := ec2.NewFilter()
+		for _, spec := range t.filters {
+			f.Add(spec.name, spec.values...)
+		}
+		list, err := s.ec2.NetworkInterfaces(nil, f)
+		if t.err != "" {
+			c.Check(err, ErrorMatches, t.err)
+			continue
+		}
+		c.Assert(err, IsNil)
+		var ids []string
+		for _, iface := range list.Interfaces {
+			ids = append(ids, iface.Id)
+		}
+		sort.Strings(ids)
+		sort.Strings(t.ids)
+		c.Check(ids, DeepEquals, t.ids)
+	}
+
+	// Instances can be filtered on the attributes of their network
+	// interfaces.
+	f := ec2.NewFilter()
+	f.Add("network-interface.addresses.private-ip-address", "10.25.1.2?")
+	f.Add("network-interface.network-interface-id", id2)
+	list, err := s.ec2.Instances(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(list.Reservations, HasLen, 1)
+	c.Assert(list.Reservations[0].Instances, HasLen, 1)
+	c.Check(list.Reservations[0].Instances[0].InstanceId, Equals, instId)
+}
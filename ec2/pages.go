@@ -0,0 +1,237 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// addPageParams adds the pagination parameters to params. As
+// pagination needs a recent version of the API, the version of
+// paginated requests is upgraded.
+func addPageParams(params map[string]string, maxResults int, nextToken string) {
+	if maxResults == 0 && nextToken == "" {
+		return
+	}
+	params["Version"] = currentAPIVersion
+	if maxResults > 0 {
+		params["MaxResults"] = strconv.Itoa(maxResults)
+	}
+	if nextToken != "" {
+		params["NextToken"] = nextToken
+	}
+}
+
+// page is implemented by the responses of paginated requests.
+type page interface {
+	nextToken() string
+}
+
+func (resp *InstancesResp) nextToken() string         { return resp.NextToken }
+func (resp *ImagesResp) nextToken() string            { return resp.NextToken }
+func (resp *SnapshotsResp) nextToken() string         { return resp.NextToken }
+func (resp *SecurityGroupsResp) nextToken() string    { return resp.NextToken }
+func (resp *NetworkInterfacesResp) nextToken() string { return resp.NextToken }
+func (resp *SubnetsResp) nextToken() string           { return resp.NextToken }
+func (resp *VPCsResp) nextToken() string              { return resp.NextToken }
+
+// pager holds the state shared by the iterators over the pages of
+// describe results. Each iterator only adds a Page method returning
+// the current page with its own type.
+type pager struct {
+	page page
+	done bool
+	err  error
+
+	// fetch requests the page identified by nextToken.
+	fetch func(nextToken string) (page, error)
+}
+
+// Next requests the next page of results, and reports whether it
+// succeeded. It returns false after the last page, or if a request
+// fails; Err returns the error in the latter case.
+func (p *pager) Next() bool {
+	if p.done || p.err != nil {
+		return false
+	}
+	nextToken := ""
+	if p.page != nil {
+		nextToken = p.page.nextToken()
+	}
+	resp, err := p.fetch(nextToken)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	p.page = resp
+	p.done = resp.nextToken() == ""
+	return true
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *pager) Err() error {
+	return p.err
+}
+
+// InstancesIter iterates over the pages of the instances returned
+// by InstancesPage. For example:
+//
+//	iter := e.InstancesIter(nil, filter, 100)
+//	for iter.Next() {
+//		for _, r := range iter.Page().Reservations {
+//			...
+//		}
+//	}
+//	if err := iter.Err(); err != nil {
+//		...
+//	}
+type InstancesIter struct {
+	pager
+}
+
+// InstancesIter returns an iterator over the pages of at most
+// maxResults instances matching the given ids and filter. See
+// InstancesPage.
+func (ec2 *EC2) InstancesIter(instIds []string, filter *Filter, maxResults int) *InstancesIter {
+	return &InstancesIter{pager{fetch: func(nextToken string) (page, error) {
+		return ec2.InstancesPage(instIds, filter, maxResults, nextToken)
+	}}}
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iter *InstancesIter) Page() *InstancesResp {
+	resp, _ := iter.page.(*InstancesResp)
+	return resp
+}
+
+// ImagesIter iterates over the pages of the images returned by
+// ImagesPage. See InstancesIter for an example.
+type ImagesIter struct {
+	pager
+}
+
+// ImagesIter returns an iterator over the pages of at most
+// maxResults images matching the given ids and filter. See
+// ImagesPage.
+func (ec2 *EC2) ImagesIter(ids []string, filter *Filter, maxResults int) *ImagesIter {
+	return &ImagesIter{pager{fetch: func(nextToken string) (page, error) {
+		return ec2.ImagesPage(ids, filter, maxResults, nextToken)
+	}}}
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iter *ImagesIter) Page() *ImagesResp {
+	resp, _ := iter.page.(*ImagesResp)
+	return resp
+}
+
+// SnapshotsIter iterates over the pages of the snapshots returned
+// by SnapshotsPage. See InstancesIter for an example.
+type SnapshotsIter struct {
+	pager
+}
+
+// SnapshotsIter returns an iterator over the pages of at most
+// maxResults snapshots matching the given ids and filter. See
+// SnapshotsPage.
+func (ec2 *EC2) SnapshotsIter(ids []string, filter *Filter, maxResults int) *SnapshotsIter {
+	return &SnapshotsIter{pager{fetch: func(nextToken string) (page, error) {
+		return ec2.SnapshotsPage(ids, filter, maxResults, nextToken)
+	}}}
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iter *SnapshotsIter) Page() *SnapshotsResp {
+	resp, _ := iter.page.(*SnapshotsResp)
+	return resp
+}
+
+// SecurityGroupsIter iterates over the pages of the security groups
+// returned by SecurityGroupsPage. See InstancesIter for an example.
+type SecurityGroupsIter struct {
+	pager
+}
+
+// SecurityGroupsIter returns an iterator over the pages of at most
+// maxResults security groups matching the given groups and filter.
+// See SecurityGroupsPage.
+func (ec2 *EC2) SecurityGroupsIter(groups []SecurityGroup, filter *Filter, maxResults int) *SecurityGroupsIter {
+	return &SecurityGroupsIter{pager{fetch: func(nextToken string) (page, error) {
+		return ec2.SecurityGroupsPage(groups, filter, maxResults, nextToken)
+	}}}
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iter *SecurityGroupsIter) Page() *SecurityGroupsResp {
+	resp, _ := iter.page.(*SecurityGroupsResp)
+	return resp
+}
+
+// NetworkInterfacesIter iterates over the pages of the network
+// interfaces returned by NetworkInterfacesPage. See InstancesIter for
+// an example.
+type NetworkInterfacesIter struct {
+	pager
+}
+
+// NetworkInterfacesIter returns an iterator over the pages of at
+// most maxResults network interfaces matching the given ids and
+// filter. See NetworkInterfacesPage.
+func (ec2 *EC2) NetworkInterfacesIter(ids []string, filter *Filter, maxResults int) *NetworkInterfacesIter {
+	return &NetworkInterfacesIter{pager{fetch: func(nextToken string) (page, error) {
+		return ec2.NetworkInterfacesPage(ids, filter, maxResults, nextToken)
+	}}}
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iter *NetworkInterfacesIter) Page() *NetworkInterfacesResp {
+	resp, _ := iter.page.(*NetworkInterfacesResp)
+	return resp
+}
+
+// SubnetsIter iterates over the pages of the subnets returned by
+// SubnetsPage. See InstancesIter for an example.
+type SubnetsIter struct {
+	pager
+}
+
+// SubnetsIter returns an iterator over the pages of at most
+// maxResults subnets matching the given ids and filter. See
+// SubnetsPage.
+func (ec2 *EC2) SubnetsIter(ids []string, filter *Filter, maxResults int) *SubnetsIter {
+	return &SubnetsIter{pager{fetch: func(nextToken string) (page, error) {
+		return ec2.SubnetsPage(ids, filter, maxResults, nextToken)
+	}}}
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iter *SubnetsIter) Page() *SubnetsResp {
+	resp, _ := iter.page.(*SubnetsResp)
+	return resp
+}
+
+// VPCsIter iterates over the pages of the VPCs returned by
+// VPCsPage. See InstancesIter for an example.
+type VPCsIter struct {
+	pager
+}
+
+// VPCsIter returns an iterator over the pages of at most maxResults
+// VPCs matching the given ids and filter. See VPCsPage.
+func (ec2 *EC2) VPCsIter(ids []string, filter *Filter, maxResults int) *VPCsIter {
+	return &VPCsIter{pager{fetch: func(nextToken string) (page, error) {
+		return ec2.VPCsPage(ids, filter, maxResults, nextToken)
+	}}}
+}
+
+// Page returns the page fetched by the last call to Next.
+func (iter *VPCsIter) Page() *VPCsResp {
+	resp, _ := iter.page.(*VPCsResp)
+	return resp
+}
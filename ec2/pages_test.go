@@ -0,0 +1,216 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/ec2/ec2test"
+)
+
+// Pagination tests with example responses
+
+func (s *S) TestInstancesPageExample(c *C) {
+	page := strings.Replace(DescribeInstancesExample2, "</reservationSet>", "</reservationSet>\n  <nextToken>token-2</nextToken>", 1)
+	testServer.Response(200, nil, page)
+
+	resp, err := s.ec2.InstancesPage(nil, nil, 5, "token-1")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["MaxResults"], DeepEquals, []string{"5"})
+	c.Assert(req.Form["NextToken"], DeepEquals, []string{"token-1"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.Reservations, HasLen, 1)
+	c.Assert(resp.NextToken, Equals, "token-2")
+}
+
+func (s *S) TestInstancesWithoutPageExample(c *C) {
+	testServer.Response(200, nil, DescribeInstancesExample2)
+
+	resp, err := s.ec2.Instances(nil, nil)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeInstances"})
+	c.Assert(req.Form["MaxResults"], IsNil)
+	c.Assert(req.Form["NextToken"], IsNil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.NextToken, Equals, "")
+}
+
+func (s *S) TestVPCsIterExample(c *C) {
+	first := strings.Replace(DescribeVpcsExample, "</vpcSet>", "</vpcSet>\n  <nextToken>token-1</nextToken>", 1)
+	testServer.Response(200, nil, first)
+	testServer.Response(200, nil, DescribeVpcsExample)
+
+	iter := s.ec2.VPCsIter([]string{"vpc-1a2b3c4d"}, nil, 5)
+	var tokens []string
+	for iter.Next() {
+		req := testServer.WaitRequest()
+		c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeVpcs"})
+		c.Assert(req.Form["VpcId.1"], DeepEquals, []string{"vpc-1a2b3c4d"})
+		c.Assert(req.Form["MaxResults"], DeepEquals, []string{"5"})
+		tokens = append(tokens, req.Form.Get("NextToken"))
+		c.Assert(iter.Page().VPCs, HasLen, 1)
+	}
+	c.Assert(iter.Err(), IsNil)
+	c.Assert(tokens, DeepEquals, []string{"", "token-1"})
+}
+
+func (s *S) TestSubnetsIterErrorExample(c *C) {
+	testServer.Response(400, nil, ErrorDump)
+
+	iter := s.ec2.SubnetsIter(nil, nil, 5)
+	c.Assert(iter.Next(), Equals, false)
+	testServer.WaitRequest()
+	c.Assert(iter.Err(), ErrorMatches, ".*UnsupportedOperation.*")
+	c.Assert(iter.Next(), Equals, false)
+}
+
+// Pagination tests run only against the local test server.
+
+func (s *LocalServerSuite) TestInstancesPagination(c *C) {
+	ids := s.srv.srv.NewInstances(7, "t1.micro", "ami-paginate", ec2test.Running, nil)
+	defer terminateInstances(c, s.ec2, ids)
+	f := ec2.NewFilter()
+	f.Add("image-id", "ami-paginate")
+
+	var pages [][]string
+	iter := s.ec2.InstancesIter(nil, f, 5)
+	for iter.Next() {
+		var page []string
+		for _, r := range iter.Page().Reservations {
+			// All the instances are in one reservation.
+			c.Assert(r.ReservationId, Matches, "r-.+")
+			for _, inst := range r.Instances {
+				page = append(page, inst.InstanceId)
+			}
+		}
+		pages = append(pages, page)
+	}
+	c.Assert(iter.Err(), IsNil)
+	c.Assert(pages, HasLen, 2)
+	c.Assert(pages[0], HasLen, 5)
+	c.Assert(pages[1], HasLen, 2)
+	c.Assert(append(pages[0], pages[1]...), DeepEquals, ids)
+
+	_, err := s.ec2.InstancesPage(ids, nil, 5, "")
+	c.Assert(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.InstancesPage(nil, f, 4, "")
+	c.Assert(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.InstancesPage(nil, f, 5, "bad-token")
+	c.Assert(errorCode(err), Equals, "InvalidPaginationToken")
+
+	// Tokens are only valid for the action that issued them.
+	resp, err := s.ec2.InstancesPage(nil, f, 5, "")
+	c.Assert(err, IsNil)
+	c.Assert(resp.NextToken, Not(Equals), "")
+	_, err = s.ec2.VPCsPage(nil, nil, 5, resp.NextToken)
+	c.Assert(errorCode(err), Equals, "InvalidPaginationToken")
+}
+
+func (s *LocalServerSuite) TestDescribePagination(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.13.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	vpcIds := []string{vpcId}
+	defer func() { s.deleteVPCs(c, vpcIds) }()
+	var subIds []string
+	defer func() { s.deleteSubnets(c, subIds) }()
+	var groups []ec2.SecurityGroup
+	defer func() { s.deleteGroups(c, groups) }()
+	for i := 0; i < 6; i++ {
+		subId := s.createSubnet(c, vpcId, fmt.Sprintf("10.13.%d.0/24", i), "").Subnet.Id
+		subIds = append(subIds, subId)
+		iface, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{SubnetId: subId})
+		c.Assert(err, IsNil)
+		defer s.ec2.DeleteNetworkInterface(iface.NetworkInterface.Id)
+		groups = append(groups, s.makeTestGroupVPC(c, vpcId, fmt.Sprintf("goamz-page-%d", i), "pagination test"))
+		other, err := s.ec2.CreateVPC(fmt.Sprintf("10.%d.0.0/16", 100+i), "")
+		c.Assert(err, IsNil)
+		vpcIds = append(vpcIds, other.VPC.Id)
+	}
+	vols, err := s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", Size: 1})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVolume(vols.Id)
+	for i := 0; i < 6; i++ {
+		snap, err := s.ec2.CreateSnapshot(vols.Id, "pagination test")
+		c.Assert(err, IsNil)
+		defer s.ec2.DeleteSnapshots([]string{snap.Id})
+	}
+
+	// Each iterator returns the same items as a single request, in
+	// pages of at most 5 items.
+	f := ec2.NewFilter()
+	f.Add("vpc-id", vpcId)
+	collect := func(next func() bool, err func() error, page func() []string) []string {
+		var all []string
+		for next() {
+			p := page()
+			c.Assert(len(p) <= 5, Equals, true)
+			all = append(all, p...)
+		}
+		c.Assert(err(), IsNil)
+		return all
+	}
+
+	allVPCs, err := s.ec2.VPCs(nil, nil)
+	c.Assert(err, IsNil)
+	var want []string
+	for _, v := range allVPCs.VPCs {
+		want = append(want, v.Id)
+	}
+	vpcIter := s.ec2.VPCsIter(nil, nil, 5)
+	c.Check(collect(vpcIter.Next, vpcIter.Err, func() (ids []string) {
+		for _, v := range vpcIter.Page().VPCs {
+			ids = append(ids, v.Id)
+		}
+		return
+	}), DeepEquals, want)
+
+	subIter := s.ec2.SubnetsIter(nil, f, 5)
+	c.Check(collect(subIter.Next, subIter.Err, func() (ids []string) {
+		for _, sub := range subIter.Page().Subnets {
+			ids = append(ids, sub.Id)
+		}
+		return
+	}), HasLen, 6)
+
+	ifaceIter := s.ec2.NetworkInterfacesIter(nil, f, 5)
+	c.Check(collect(ifaceIter.Next, ifaceIter.Err, func() (ids []string) {
+		for _, iface := range ifaceIter.Page().Interfaces {
+			ids = append(ids, iface.Id)
+		}
+		return
+	}), HasLen, 6)
+
+	groupIter := s.ec2.SecurityGroupsIter(nil, f, 5)
+	c.Check(collect(groupIter.Next, groupIter.Err, func() (ids []string) {
+		for _, g := range groupIter.Page().Groups {
+			ids = append(ids, g.Id)
+		}
+		return
+	}), HasLen, 6)
+
+	snapFilter := ec2.NewFilter()
+	snapFilter.Add("volume-id", vols.Id)
+	snapIter := s.ec2.SnapshotsIter(nil, snapFilter, 5)
+	c.Check(collect(snapIter.Next, snapIter.Err, func() (ids []string) {
+		for _, snap := range snapIter.Page().Snapshots {
+			ids = append(ids, snap.Id)
+		}
+		return
+	}), HasLen, 6)
+}
@@ -0,0 +1,93 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// PlacementGroup describes a placement group, which influences the
+// placement of the instances launched in it.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_PlacementGroup.html for more details.
+type PlacementGroup struct {
+	Name string `xml:"groupName"`
+
+	// Strategy is "cluster", which packs the instances close
+	// together, "spread", which places each instance on distinct
+	// hardware, or "partition", which spreads the instances across
+	// PartitionCount logical partitions.
+	Strategy       string `xml:"strategy"`
+	PartitionCount int    `xml:"partitionCount"`
+
+	State string `xml:"state"` // "pending", "available", "deleting" or "deleted".
+}
+
+// CreatePlacementGroup creates a placement group with the given
+// name and strategy. The partition count is only used by the
+// "partition" strategy, and defaults to 2 when zero.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreatePlacementGroup.html for more details.
+func (ec2 *EC2) CreatePlacementGroup(name, strategy string, partitionCount int) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("CreatePlacementGroup")
+	params["GroupName"] = name
+	params["Strategy"] = strategy
+	if partitionCount != 0 {
+		params["PartitionCount"] = strconv.Itoa(partitionCount)
+	}
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// PlacementGroupsResp is the response to a PlacementGroups request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribePlacementGroups.html for more details.
+type PlacementGroupsResp struct {
+	RequestId       string           `xml:"requestId"`
+	PlacementGroups []PlacementGroup `xml:"placementGroupSet>item"`
+}
+
+// PlacementGroups returns the placement groups with the given
+// names, or all of them if names is empty, that match the given
+// filter.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribePlacementGroups.html for more details.
+func (ec2 *EC2) PlacementGroups(names []string, filter *Filter) (resp *PlacementGroupsResp, err error) {
+	params := makeParamsCurrent("DescribePlacementGroups")
+	for i, name := range names {
+		params["GroupName."+strconv.Itoa(i+1)] = name
+	}
+	filter.addParams(params)
+
+	resp = &PlacementGroupsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeletePlacementGroup deletes the placement group with the given
+// name. All the instances in the group must be terminated first.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeletePlacementGroup.html for more details.
+func (ec2 *EC2) DeletePlacementGroup(name string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("DeletePlacementGroup")
+	params["GroupName"] = name
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,291 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// Placement group tests with example responses
+
+func (s *S) TestCreatePlacementGroupExample(c *C) {
+	testServer.Response(200, nil, CreatePlacementGroupExample)
+
+	resp, err := s.ec2.CreatePlacementGroup("ABC-partition", "partition", 5)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreatePlacementGroup"})
+	c.Assert(req.Form["GroupName"], DeepEquals, []string{"ABC-partition"})
+	c.Assert(req.Form["Strategy"], DeepEquals, []string{"partition"})
+	c.Assert(req.Form["PartitionCount"], DeepEquals, []string{"5"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE")
+}
+
+func (s *S) TestPlacementGroupsExample(c *C) {
+	testServer.Response(200, nil, DescribePlacementGroupsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("state", "available")
+	resp, err := s.ec2.PlacementGroups([]string{"XYZ-cluster", "ABC-partition"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribePlacementGroups"})
+	c.Assert(req.Form["GroupName.1"], DeepEquals, []string{"XYZ-cluster"})
+	c.Assert(req.Form["GroupName.2"], DeepEquals, []string{"ABC-partition"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"state"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"available"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE")
+	c.Assert(resp.PlacementGroups, DeepEquals, []ec2.PlacementGroup{{
+		Name:     "XYZ-cluster",
+		Strategy: "cluster",
+		State:    "available",
+	}, {
+		Name:           "ABC-partition",
+		Strategy:       "partition",
+		PartitionCount: 5,
+		State:          "available",
+	}})
+}
+
+func (s *S) TestDeletePlacementGroupExample(c *C) {
+	testServer.Response(200, nil, DeletePlacementGroupExample)
+
+	resp, err := s.ec2.DeletePlacementGroup("XYZ-cluster")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeletePlacementGroup"})
+	c.Assert(req.Form["GroupName"], DeepEquals, []string{"XYZ-cluster"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE")
+}
+
+func (s *S) TestRunInstancesPlacementExample(c *C) {
+	testServer.Response(200, nil, RunInstancesExample)
+
+	_, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:            "image-id",
+		InstanceType:       "inst-type",
+		PlacementGroupName: "ABC-partition",
+		PartitionNumber:    3,
+		Tenancy:            "host",
+		HostId:             "h-0123456789abcdef0",
+		Affinity:           "host",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RunInstances"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["Placement.GroupName"], DeepEquals, []string{"ABC-partition"})
+	c.Assert(req.Form["Placement.PartitionNumber"], DeepEquals, []string{"3"})
+	c.Assert(req.Form["Placement.Tenancy"], DeepEquals, []string{"host"})
+	c.Assert(req.Form["Placement.HostId"], DeepEquals, []string{"h-0123456789abcdef0"})
+	c.Assert(req.Form["Placement.Affinity"], DeepEquals, []string{"host"})
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestRunInstancesHostTenancyExample(c *C) {
+	testServer.Response(200, nil, RunInstancesExample)
+
+	_, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      "image-id",
+		InstanceType: "inst-type",
+		Tenancy:      "host",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RunInstances"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["Placement.Tenancy"], DeepEquals, []string{"host"})
+	c.Assert(err, IsNil)
+}
+
+// Placement group tests run against either a local test server or live on EC2.
+
+func (s *ServerTests) TestPlacementGroups(c *C) {
+	_, err := s.ec2.CreatePlacementGroup("goamz-test-cluster", "cluster", 0)
+	c.Assert(err, IsNil)
+	defer s.ec2.DeletePlacementGroup("goamz-test-cluster")
+	_, err = s.ec2.CreatePlacementGroup("goamz-test-partition", "partition", 3)
+	c.Assert(err, IsNil)
+	defer s.ec2.DeletePlacementGroup("goamz-test-partition")
+
+	_, err = s.ec2.CreatePlacementGroup("goamz-test-cluster", "spread", 0)
+	c.Check(errorCode(err), Equals, "InvalidPlacementGroup.Duplicate")
+
+	resp, err := s.ec2.PlacementGroups([]string{"goamz-test-cluster", "goamz-test-partition"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.PlacementGroups, DeepEquals, []ec2.PlacementGroup{{
+		Name:     "goamz-test-cluster",
+		Strategy: "cluster",
+		State:    "available",
+	}, {
+		Name:           "goamz-test-partition",
+		Strategy:       "partition",
+		PartitionCount: 3,
+		State:          "available",
+	}})
+
+	f := ec2.NewFilter()
+	f.Add("strategy", "partition")
+	resp, err = s.ec2.PlacementGroups([]string{"goamz-test-cluster", "goamz-test-partition"}, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.PlacementGroups, HasLen, 1)
+	c.Check(resp.PlacementGroups[0].Name, Equals, "goamz-test-partition")
+
+	_, err = s.ec2.DeletePlacementGroup("goamz-test-cluster")
+	c.Assert(err, IsNil)
+	_, err = s.ec2.PlacementGroups([]string{"goamz-test-cluster"}, nil)
+	c.Check(errorCode(err), Equals, "InvalidPlacementGroup.Unknown")
+}
+
+func (s *ServerTests) TestRunInstancesUnknownPlacementGroup(c *C) {
+	_, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:            imageId,
+		InstanceType:       "t1.micro",
+		PlacementGroupName: "goamz-test-no-such-group",
+	})
+	c.Assert(errorCode(err), Equals, "InvalidPlacementGroup.Unknown")
+}
+
+// Placement tests that launch instances run only against the local test server.
+
+func (s *LocalServerSuite) TestCreatePlacementGroupErrors(c *C) {
+	for i, t := range []struct {
+		strategy       string
+		partitionCount int
+		code           string
+	}{
+		{"", 0, "MissingParameter"},
+		{"scattered", 0, "InvalidParameterValue"},
+		{"cluster", 2, "InvalidParameterCombination"},
+		{"partition", 8, "InvalidParameterValue"},
+	} {
+		_, err := s.ec2.CreatePlacementGroup("goamz-test-bad", t.strategy, t.partitionCount)
+		c.Check(errorCode(err), Equals, t.code, Commentf("test %d", i))
+	}
+	_, err := s.ec2.DeletePlacementGroup("goamz-test-bad")
+	c.Check(errorCode(err), Equals, "InvalidPlacementGroup.Unknown")
+}
+
+func (s *LocalServerSuite) TestRunInstancesPlacement(c *C) {
+	_, err := s.ec2.CreatePlacementGroup("goamz-test-partition", "partition", 2)
+	c.Assert(err, IsNil)
+	defer s.ec2.DeletePlacementGroup("goamz-test-partition")
+
+	// Instances launched without a partition number are spread
+	// across the partitions.
+	resp, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:            imageId,
+		InstanceType:       "m1.placement",
+		MinCount:           3,
+		PlacementGroupName: "goamz-test-partition",
+	})
+	c.Assert(err, IsNil)
+	var ids []string
+	for _, inst := range resp.Instances {
+		ids = append(ids, inst.InstanceId)
+	}
+	defer func() {
+		terminateInstances(c, s.ec2, ids)
+	}()
+	c.Assert(resp.Instances, HasLen, 3)
+	for i, inst := range resp.Instances {
+		c.Check(inst.PlacementGroupName, Equals, "goamz-test-partition")
+		c.Check(inst.PartitionNumber, Equals, i%2+1)
+		c.Check(inst.Tenancy, Equals, "default")
+	}
+
+	resp, err = s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:            imageId,
+		InstanceType:       "m1.placement",
+		PlacementGroupName: "goamz-test-partition",
+		PartitionNumber:    2,
+		Tenancy:            "dedicated",
+	})
+	c.Assert(err, IsNil)
+	ids = append(ids, resp.Instances[0].InstanceId)
+	c.Check(resp.Instances[0].PartitionNumber, Equals, 2)
+	c.Check(resp.Instances[0].Tenancy, Equals, "dedicated")
+
+	f := ec2.NewFilter()
+	f.Add("placement-group-name", "goamz-test-partition")
+	f.Add("placement-partition-number", "2")
+	insts, err := s.ec2.Instances(nil, f)
+	c.Assert(err, IsNil)
+	var got []string
+	for _, r := range insts.Reservations {
+		for _, inst := range r.Instances {
+			got = append(got, inst.InstanceId)
+		}
+	}
+	c.Check(got, DeepEquals, []string{ids[1], ids[3]})
+
+	// The group cannot be deleted while it has instances.
+	_, err = s.ec2.DeletePlacementGroup("goamz-test-partition")
+	c.Check(errorCode(err), Equals, "InvalidPlacementGroup.InUse")
+
+	for i, t := range []struct {
+		options ec2.RunInstances
+		code    string
+	}{{
+		ec2.RunInstances{PlacementGroupName: "goamz-test-partition", PartitionNumber: 3},
+		"InvalidParameterValue",
+	}, {
+		ec2.RunInstances{PartitionNumber: 1},
+		"InvalidParameterCombination",
+	}, {
+		ec2.RunInstances{Tenancy: "shared"},
+		"InvalidParameterValue",
+	}, {
+		ec2.RunInstances{Tenancy: "dedicated", HostId: "h-0123456789abcdef0"},
+		"InvalidParameterCombination",
+	}} {
+		t.options.ImageId = imageId
+		t.options.InstanceType = "m1.placement"
+		_, err := s.ec2.RunInstances(&t.options)
+		c.Check(errorCode(err), Equals, t.code, Commentf("test %d", i))
+	}
+
+	resp, err = s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "m1.placement",
+		HostId:       "h-0123456789abcdef0",
+		Affinity:     "host",
+	})
+	c.Assert(err, IsNil)
+	ids = append(ids, resp.Instances[0].InstanceId)
+	c.Check(resp.Instances[0].Tenancy, Equals, "host")
+	c.Check(resp.Instances[0].HostId, Equals, "h-0123456789abcdef0")
+	c.Check(resp.Instances[0].Affinity, Equals, "host")
+}
+
+func (s *LocalServerSuite) TestRunInstancesDedicatedVPC(c *C) {
+	vpc, err := s.ec2.CreateVPC("10.9.0.0/16", "dedicated")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVPC(vpc.VPC.Id)
+	sub, err := s.ec2.CreateSubnet(vpc.VPC.Id, "10.9.1.0/24", "")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSubnet(sub.Subnet.Id)
+
+	resp, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "m1.placement",
+		SubnetId:     sub.Subnet.Id,
+	})
+	c.Assert(err, IsNil)
+	defer terminateInstances(c, s.ec2, []string{resp.Instances[0].InstanceId})
+	c.Check(resp.Instances[0].Tenancy, Equals, "dedicated")
+}
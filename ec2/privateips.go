@@ -65,3 +65,62 @@ func (ec2 *EC2) UnassignPrivateIPAddresses(interfaceId string, ipAddresses []str
 	}
 	return resp, nil
 }
+
+// AssignIPv6AddressesResp is the response to an AssignIPv6Addresses
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssignIpv6Addresses.html for more details.
+type AssignIPv6AddressesResp struct {
+	RequestId          string   `xml:"requestId"`
+	NetworkInterfaceId string   `xml:"networkInterfaceId"`
+	AssignedAddresses  []string `xml:"assignedIpv6Addresses>item"`
+}
+
+// AssignIPv6Addresses assigns IPv6 addresses to the network interface
+// interfaceId, whose subnet must have an IPv6 CIDR block.
+//
+// Either ipAddresses holds the addresses to assign, or ipCount is
+// non-zero and that number of addresses will be allocated within the
+// subnet's IPv6 CIDR block.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssignIpv6Addresses.html for more details.
+func (ec2 *EC2) AssignIPv6Addresses(interfaceId string, ipAddresses []string, ipCount int) (resp *AssignIPv6AddressesResp, err error) {
+	params := makeParamsCurrent("AssignIpv6Addresses")
+	params["NetworkInterfaceId"] = interfaceId
+	if ipCount > 0 {
+		params["Ipv6AddressCount"] = strconv.Itoa(ipCount)
+	}
+	addParamsList(params, "Ipv6Addresses", ipAddresses)
+	resp = &AssignIPv6AddressesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UnassignIPv6AddressesResp is the response to an
+// UnassignIPv6Addresses request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_UnassignIpv6Addresses.html for more details.
+type UnassignIPv6AddressesResp struct {
+	RequestId           string   `xml:"requestId"`
+	NetworkInterfaceId  string   `xml:"networkInterfaceId"`
+	UnassignedAddresses []string `xml:"unassignedIpv6Addresses>item"`
+}
+
+// UnassignIPv6Addresses unassigns one or more IPv6 addresses from a
+// network interface.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_UnassignIpv6Addresses.html for more details.
+func (ec2 *EC2) UnassignIPv6Addresses(interfaceId string, ipAddresses []string) (resp *UnassignIPv6AddressesResp, err error) {
+	params := makeParamsCurrent("UnassignIpv6Addresses")
+	params["NetworkInterfaceId"] = interfaceId
+	addParamsList(params, "Ipv6Addresses", ipAddresses)
+	resp = &UnassignIPv6AddressesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,772 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NetworkSpec describes the desired state of a VPC, along with its
+// subnets and security groups. See ReconcileNetwork.
+type NetworkSpec struct {
+	// Name identifies the VPC through its "Name" tag. It is
+	// required.
+	Name string
+
+	// CIDRBlock holds the primary IPv4 CIDR block of the VPC. It
+	// is required, and cannot be changed once the VPC exists.
+	CIDRBlock string
+
+	// Tags holds the tags of the VPC, other than its Name tag.
+	Tags []Tag
+
+	Subnets        []SubnetSpec
+	SecurityGroups []SecurityGroupSpec
+}
+
+// SubnetSpec describes the desired state of a subnet, which is
+// identified by its CIDR block within the VPC.
+type SubnetSpec struct {
+	CIDRBlock string
+
+	// AvailZone holds the availability zone of the subnet. If it
+	// is empty, any zone will do. A subnet in another zone is
+	// replaced.
+	AvailZone string
+
+	MapPublicIPOnLaunch bool
+	Tags                []Tag
+}
+
+// SecurityGroupSpec describes the desired state of a security group,
+// which is identified by its name within the VPC.
+type SecurityGroupSpec struct {
+	Name string
+
+	// Description holds the description of the group. As it
+	// cannot be changed, a group with another description is
+	// replaced.
+	Description string
+
+	// Ingress and Egress hold the inbound and outbound rules of
+	// the group. A source group with a Name but no Id refers to
+	// another group of the spec. A nil Egress stands for the
+	// default rule, which allows all outbound traffic; an empty
+	// one allows none. Descriptions are set on new rules but are
+	// not compared.
+	Ingress []IPPerm
+	Egress  []IPPerm
+
+	Tags []Tag
+}
+
+// NetworkOp identifies the kind of change a network action makes.
+type NetworkOp string
+
+const (
+	// NetworkCreate creates a resource or authorizes rules.
+	NetworkCreate NetworkOp = "create"
+	// NetworkUpdate changes the attributes or tags of a resource.
+	NetworkUpdate NetworkOp = "update"
+	// NetworkDelete deletes a resource or revokes rules.
+	NetworkDelete NetworkOp = "delete"
+)
+
+// NetworkResource identifies the kind of resource a network action
+// changes.
+type NetworkResource string
+
+const (
+	NetworkVPC           NetworkResource = "vpc"
+	NetworkSubnet        NetworkResource = "subnet"
+	NetworkSecurityGroup NetworkResource = "security-group"
+	NetworkIngress       NetworkResource = "ingress"
+	NetworkEgress        NetworkResource = "egress"
+)
+
+// NetworkAction describes a single change made by a network
+// reconciliation.
+type NetworkAction struct {
+	Op       NetworkOp
+	Resource NetworkResource
+	Id       string // id of the resource or group, if it exists yet.
+	Name     string // name of the VPC or group, or CIDR block of the subnet.
+	Reason   string // why the action is needed ("new", "tags", ...).
+
+	// Perms holds the rules authorized or revoked by ingress and
+	// egress actions, with a single source each. Source groups
+	// that are yet to be created have a Name but no Id.
+	Perms []IPPerm
+
+	tags   []Tag       // tags to create.
+	untags []DeleteTag // tags to delete.
+	subnet *SubnetSpec
+	group  *SecurityGroupSpec
+}
+
+// NetworkPlan holds the set of actions required to bring a VPC in
+// line with a NetworkSpec. A plan may be inspected before applying
+// it, which allows for dry runs.
+type NetworkPlan struct {
+	Actions []NetworkAction
+
+	ec2       *EC2
+	spec      NetworkSpec
+	vpcId     string
+	subnetIds map[string]string // by CIDR block.
+	groupIds  map[string]string // by name.
+}
+
+// defaultEgress holds the outbound rule of new VPC security groups.
+var defaultEgress = []IPPerm{{Protocol: "-1", SourceIPs: []string{"0.0.0.0/0"}}}
+
+// PlanNetwork returns the plan for bringing the VPC named by spec,
+// its subnets and its security groups in line with spec, without
+// applying it. See ReconcileNetwork.
+func (ec2 *EC2) PlanNetwork(spec *NetworkSpec) (*NetworkPlan, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	plan := &NetworkPlan{
+		ec2:       ec2,
+		spec:      *spec,
+		subnetIds: make(map[string]string),
+		groupIds:  make(map[string]string),
+	}
+	vpc, err := ec2.networkVPC(spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	vpcTags := append([]Tag{{"Name", spec.Name}}, spec.Tags...)
+	if vpc == nil {
+		plan.Actions = append(plan.Actions, NetworkAction{
+			Op:       NetworkCreate,
+			Resource: NetworkVPC,
+			Name:     spec.Name,
+			Reason:   "new",
+			tags:     vpcTags,
+		})
+		plan.planSubnets(nil)
+		plan.planGroups(nil)
+		return plan, nil
+	}
+	if vpc.CIDRBlock != spec.CIDRBlock {
+		return nil, fmt.Errorf("VPC %q has CIDR block %s, not %s", spec.Name, vpc.CIDRBlock, spec.CIDRBlock)
+	}
+	plan.vpcId = vpc.Id
+	if a, ok := tagAction(NetworkVPC, vpc.Id, spec.Name, vpc.Tags, vpcTags); ok {
+		plan.Actions = append(plan.Actions, a)
+	}
+
+	filter := NewFilter()
+	filter.Add("vpc-id", vpc.Id)
+	subnets, err := ec2.Subnets(nil, filter)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := ec2.SecurityGroups(nil, filter)
+	if err != nil {
+		return nil, err
+	}
+	plan.planSubnets(subnets.Subnets)
+	plan.planGroups(groups.Groups)
+	return plan, nil
+}
+
+// ReconcileNetwork makes the VPC named by spec, its subnets and its
+// security groups match spec, creating, updating and deleting
+// resources as necessary, and returns the applied plan. Subnets and
+// security groups of the VPC that are not in spec are deleted, except
+// for the VPC's default security group, as are the tags of the
+// resources that are not in spec, except for those reserved by AWS.
+// The VPC itself is never deleted.
+//
+// Reconciling an up to date VPC does nothing, so ReconcileNetwork may
+// be called repeatedly with the same spec. The ids of the resources
+// are available from the plan once it has been applied.
+func (ec2 *EC2) ReconcileNetwork(spec *NetworkSpec) (*NetworkPlan, error) {
+	plan, err := ec2.PlanNetwork(spec)
+	if err != nil {
+		return nil, err
+	}
+	return plan, plan.Apply()
+}
+
+// VPCId returns the id of the VPC, which is empty if the VPC is new
+// and the plan has not been applied.
+func (p *NetworkPlan) VPCId() string {
+	return p.vpcId
+}
+
+// SubnetId returns the id of the subnet with the given CIDR block,
+// which is empty if the subnet is new and the plan has not been
+// applied.
+func (p *NetworkPlan) SubnetId(cidrBlock string) string {
+	return p.subnetIds[cidrBlock]
+}
+
+// GroupId returns the id of the security group with the given name,
+// which is empty if the group is new and the plan has not been
+// applied.
+func (p *NetworkPlan) GroupId(name string) string {
+	return p.groupIds[name]
+}
+
+// Apply performs the actions in the plan in order, stopping at the
+// first error. A plan that failed to apply should not be applied
+// again; a new plan should be made instead.
+func (p *NetworkPlan) Apply() error {
+	for _, a := range p.Actions {
+		if err := p.apply(a); err != nil {
+			return fmt.Errorf("cannot %s %s %q: %v", a.Op, a.Resource, a.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p *NetworkPlan) apply(a NetworkAction) error {
+	switch a.Resource {
+	case NetworkVPC:
+		if a.Op != NetworkCreate {
+			return p.applyTags(a)
+		}
+		resp, err := p.ec2.CreateVPC(p.spec.CIDRBlock, "")
+		if err != nil {
+			return err
+		}
+		a.Id = resp.VPC.Id
+		if err := p.applyTags(a); err != nil {
+			// The VPC is found by its name, so an unnamed one
+			// would be left behind by the next reconciliation.
+			if _, derr := p.ec2.DeleteVPC(a.Id); derr != nil {
+				return fmt.Errorf("%v (cannot delete unnamed VPC %s: %v)", err, a.Id, derr)
+			}
+			return err
+		}
+		p.vpcId = a.Id
+		return nil
+	case NetworkSubnet:
+		switch a.Op {
+		case NetworkCreate:
+			resp, err := p.ec2.CreateSubnet(p.vpcId, a.subnet.CIDRBlock, a.subnet.AvailZone)
+			if err != nil {
+				return err
+			}
+			a.Id = resp.Subnet.Id
+			p.subnetIds[a.Name] = a.Id
+		case NetworkDelete:
+			_, err := p.ec2.DeleteSubnet(a.Id)
+			return err
+		}
+		// New subnets do not map public IP addresses on launch.
+		if a.subnet != nil && (a.Op == NetworkUpdate || a.subnet.MapPublicIPOnLaunch) {
+			_, err := p.ec2.ModifySubnetAttribute(a.Id, &ModifySubnetAttribute{
+				MapPublicIPOnLaunch: &a.subnet.MapPublicIPOnLaunch,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return p.applyTags(a)
+	case NetworkSecurityGroup:
+		switch a.Op {
+		case NetworkCreate:
+			resp, err := p.ec2.CreateSecurityGroupVPC(p.vpcId, a.Name, a.group.Description)
+			if err != nil {
+				return err
+			}
+			a.Id = resp.Id
+			p.groupIds[a.Name] = a.Id
+		case NetworkDelete:
+			_, err := p.ec2.DeleteSecurityGroup(SecurityGroup{Id: a.Id})
+			return err
+		}
+		return p.applyTags(a)
+	case NetworkIngress, NetworkEgress:
+		group := SecurityGroup{Id: a.Id}
+		if group.Id == "" {
+			group.Id = p.groupIds[a.Name]
+		}
+		perms := make([]IPPerm, len(a.Perms))
+		for i, perm := range a.Perms {
+			perms[i] = perm
+			perms[i].SourceGroups = nil
+			for _, g := range perm.SourceGroups {
+				if g.Id == "" {
+					g.Id = p.groupIds[g.Name]
+				}
+				perms[i].SourceGroups = append(perms[i].SourceGroups, g)
+			}
+		}
+		var err error
+		switch {
+		case a.Resource == NetworkIngress && a.Op == NetworkCreate:
+			_, err = p.ec2.AuthorizeSecurityGroup(group, perms)
+		case a.Resource == NetworkIngress:
+			_, err = p.ec2.RevokeSecurityGroup(group, perms)
+		case a.Op == NetworkCreate:
+			_, err = p.ec2.AuthorizeSecurityGroupEgress(group, perms)
+		default:
+			_, err = p.ec2.RevokeSecurityGroupEgress(group, perms)
+		}
+		return err
+	}
+	return fmt.Errorf("unknown resource")
+}
+
+// applyTags creates and deletes the tags of the resource changed by
+// the action a.
+func (p *NetworkPlan) applyTags(a NetworkAction) error {
+	if len(a.tags) > 0 {
+		if _, err := p.ec2.CreateTags([]string{a.Id}, a.tags); err != nil {
+			return err
+		}
+	}
+	if len(a.untags) > 0 {
+		if _, err := p.ec2.DeleteTags([]string{a.Id}, a.untags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planSubnets adds the actions needed to turn the existing subnets
+// of the VPC into those of the spec. Subnets are deleted before any
+// are created, so that a replaced subnet can reuse its CIDR block.
+func (p *NetworkPlan) planSubnets(existing []Subnet) {
+	sort.Slice(existing, func(i, j int) bool { return existing[i].CIDRBlock < existing[j].CIDRBlock })
+	byCIDR := make(map[string]*Subnet)
+	for i := range existing {
+		byCIDR[existing[i].CIDRBlock] = &existing[i]
+	}
+	var deletes, creates, updates []NetworkAction
+	wanted := make(map[string]bool)
+	for i := range p.spec.Subnets {
+		spec := &p.spec.Subnets[i]
+		wanted[spec.CIDRBlock] = true
+		create := NetworkAction{
+			Op:       NetworkCreate,
+			Resource: NetworkSubnet,
+			Name:     spec.CIDRBlock,
+			Reason:   "new",
+			subnet:   spec,
+			tags:     spec.Tags,
+		}
+		have := byCIDR[spec.CIDRBlock]
+		switch {
+		case have == nil:
+			creates = append(creates, create)
+		case spec.AvailZone != "" && have.AvailZone != spec.AvailZone:
+			deletes = append(deletes, NetworkAction{
+				Op:       NetworkDelete,
+				Resource: NetworkSubnet,
+				Id:       have.Id,
+				Name:     have.CIDRBlock,
+				Reason:   "availability zone",
+			})
+			create.Reason = "availability zone"
+			creates = append(creates, create)
+		default:
+			p.subnetIds[spec.CIDRBlock] = have.Id
+			if have.MapPublicIPOnLaunch != spec.MapPublicIPOnLaunch {
+				updates = append(updates, NetworkAction{
+					Op:       NetworkUpdate,
+					Resource: NetworkSubnet,
+					Id:       have.Id,
+					Name:     have.CIDRBlock,
+					Reason:   "attributes",
+					subnet:   spec,
+				})
+			}
+			if a, ok := tagAction(NetworkSubnet, have.Id, have.CIDRBlock, have.Tags, spec.Tags); ok {
+				updates = append(updates, a)
+			}
+		}
+	}
+	var extraneous []NetworkAction
+	for _, s := range existing {
+		if !wanted[s.CIDRBlock] {
+			extraneous = append(extraneous, NetworkAction{
+				Op:       NetworkDelete,
+				Resource: NetworkSubnet,
+				Id:       s.Id,
+				Name:     s.CIDRBlock,
+				Reason:   "extraneous",
+			})
+		}
+	}
+	p.Actions = append(p.Actions, extraneous...)
+	p.Actions = append(p.Actions, deletes...)
+	p.Actions = append(p.Actions, updates...)
+	p.Actions = append(p.Actions, creates...)
+}
+
+// planGroups adds the actions needed to turn the existing security
+// groups of the VPC into those of the spec. Rules of existing groups
+// are revoked first, as a group cannot be deleted while other groups
+// refer to it; groups are then deleted, created and updated, and the
+// rules they lack are authorized last, once every group they refer to
+// exists.
+func (p *NetworkPlan) planGroups(existing []SecurityGroupInfo) {
+	specs := make(map[string]*SecurityGroupSpec)
+	for i := range p.spec.SecurityGroups {
+		specs[p.spec.SecurityGroups[i].Name] = &p.spec.SecurityGroups[i]
+	}
+	sort.Slice(existing, func(i, j int) bool { return existing[i].Name < existing[j].Name })
+	kept := make(map[string]*SecurityGroupInfo)
+	var revokes, deletes []NetworkAction
+	for i := range existing {
+		g := &existing[i]
+		spec := specs[g.Name]
+		if g.Name == "default" || spec != nil && spec.Description == g.Description {
+			kept[g.Name] = g
+			p.groupIds[g.Name] = g.Id
+			continue
+		}
+		reason := "extraneous"
+		if spec != nil {
+			reason = "description"
+		}
+		for _, rules := range []struct {
+			resource NetworkResource
+			perms    []IPPerm
+		}{{NetworkIngress, g.IPPerms}, {NetworkEgress, g.IPPermsEgress}} {
+			var refs []IPPerm
+			for _, perm := range rules.perms {
+				if len(perm.SourceGroups) > 0 {
+					refs = append(refs, IPPerm{
+						Protocol:     perm.Protocol,
+						FromPort:     perm.FromPort,
+						ToPort:       perm.ToPort,
+						SourceGroups: perm.SourceGroups,
+					})
+				}
+			}
+			if len(refs) > 0 {
+				revokes = append(revokes, NetworkAction{
+					Op:       NetworkDelete,
+					Resource: rules.resource,
+					Id:       g.Id,
+					Name:     g.Name,
+					Reason:   reason,
+					Perms:    refs,
+				})
+			}
+		}
+		deletes = append(deletes, NetworkAction{
+			Op:       NetworkDelete,
+			Resource: NetworkSecurityGroup,
+			Id:       g.Id,
+			Name:     g.Name,
+			Reason:   reason,
+		})
+	}
+
+	var creates, updates, authorizes, lateRevokes []NetworkAction
+	for _, spec := range p.spec.SecurityGroups {
+		spec := spec
+		have := kept[spec.Name]
+		haveIngress, haveEgress := []IPPerm(nil), defaultEgress
+		id := ""
+		if have == nil {
+			reason := "new"
+			for _, g := range existing {
+				if g.Name == spec.Name {
+					reason = "description"
+				}
+			}
+			creates = append(creates, NetworkAction{
+				Op:       NetworkCreate,
+				Resource: NetworkSecurityGroup,
+				Name:     spec.Name,
+				Reason:   reason,
+				group:    &spec,
+				tags:     spec.Tags,
+			})
+		} else {
+			id = have.Id
+			haveIngress, haveEgress = have.IPPerms, have.IPPermsEgress
+			if a, ok := tagAction(NetworkSecurityGroup, id, spec.Name, have.Tags, spec.Tags); ok {
+				updates = append(updates, a)
+			}
+		}
+		wantEgress := spec.Egress
+		if wantEgress == nil {
+			wantEgress = defaultEgress
+		}
+		for _, rules := range []struct {
+			resource   NetworkResource
+			have, want []IPPerm
+		}{{NetworkIngress, haveIngress, spec.Ingress}, {NetworkEgress, haveEgress, wantEgress}} {
+			add, del := ruleChanges(p.ruleKeys(rules.have), p.ruleKeys(rules.want))
+			if len(del) > 0 {
+				a := NetworkAction{
+					Op:       NetworkDelete,
+					Resource: rules.resource,
+					Id:       id,
+					Name:     spec.Name,
+					Reason:   "extraneous",
+					Perms:    del,
+				}
+				if have == nil {
+					lateRevokes = append(lateRevokes, a)
+				} else {
+					revokes = append(revokes, a)
+				}
+			}
+			if len(add) > 0 {
+				authorizes = append(authorizes, NetworkAction{
+					Op:       NetworkCreate,
+					Resource: rules.resource,
+					Id:       id,
+					Name:     spec.Name,
+					Reason:   "new",
+					Perms:    add,
+				})
+			}
+		}
+	}
+	p.Actions = append(p.Actions, revokes...)
+	p.Actions = append(p.Actions, deletes...)
+	p.Actions = append(p.Actions, creates...)
+	p.Actions = append(p.Actions, updates...)
+	p.Actions = append(p.Actions, authorizes...)
+	p.Actions = append(p.Actions, lateRevokes...)
+}
+
+// ruleKey identifies a single security group rule: a protocol and
+// port range with a single source or destination.
+type ruleKey struct {
+	protocol     string
+	fromPort     int
+	toPort       int
+	ipAddr       string
+	ipv6Addr     string
+	prefixListId string
+	groupId      string
+	groupName    string // group of the spec that is yet to be created.
+}
+
+// protocolNames maps the numbers of the protocols EC2 reports by
+// name to their names.
+var protocolNames = map[string]string{
+	"1":  "icmp",
+	"6":  "tcp",
+	"17": "udp",
+}
+
+// ruleKeys splits perms into single rules, mapping each rule to its
+// description. Source groups given by name are identified by their
+// id when they already exist.
+func (p *NetworkPlan) ruleKeys(perms []IPPerm) map[ruleKey]string {
+	keys := make(map[ruleKey]string)
+	for _, perm := range perms {
+		base := ruleKey{
+			protocol: strings.ToLower(perm.Protocol),
+			fromPort: perm.FromPort,
+			toPort:   perm.ToPort,
+		}
+		if name, ok := protocolNames[base.protocol]; ok {
+			base.protocol = name
+		}
+		if base.protocol == "-1" {
+			// All traffic rules have no port range.
+			base.fromPort, base.toPort = 0, 0
+		}
+		for _, ip := range perm.SourceIPs {
+			k := base
+			k.ipAddr = ip
+			keys[k] = perm.Descriptions[ip]
+		}
+		for _, ip := range perm.SourceIPv6s {
+			k := base
+			k.ipv6Addr = ip
+			keys[k] = perm.Descriptions[ip]
+		}
+		for _, id := range perm.PrefixListIds {
+			k := base
+			k.prefixListId = id
+			keys[k] = perm.Descriptions[id]
+		}
+		for _, g := range perm.SourceGroups {
+			k := base
+			switch {
+			case g.Id != "":
+				k.groupId = g.Id
+			case p.groupIds[g.Name] != "":
+				k.groupId = p.groupIds[g.Name]
+			default:
+				k.groupName = g.Name
+			}
+			keys[k] = g.Description
+		}
+	}
+	return keys
+}
+
+// perm returns the permission holding the single rule k.
+func (k ruleKey) perm(description string) IPPerm {
+	perm := IPPerm{
+		Protocol: k.protocol,
+		FromPort: k.fromPort,
+		ToPort:   k.toPort,
+	}
+	var source string
+	switch {
+	case k.ipAddr != "":
+		source = k.ipAddr
+		perm.SourceIPs = []string{source}
+	case k.ipv6Addr != "":
+		source = k.ipv6Addr
+		perm.SourceIPv6s = []string{source}
+	case k.prefixListId != "":
+		source = k.prefixListId
+		perm.PrefixListIds = []string{source}
+	default:
+		perm.SourceGroups = []UserSecurityGroup{{
+			Id:          k.groupId,
+			Name:        k.groupName,
+			Description: description,
+		}}
+		return perm
+	}
+	if description != "" {
+		perm.Descriptions = map[string]string{source: description}
+	}
+	return perm
+}
+
+// ruleChanges returns the rules to authorize and revoke to turn the
+// rules have into want, in a stable order.
+func ruleChanges(have, want map[ruleKey]string) (add, del []IPPerm) {
+	for k, desc := range want {
+		if _, ok := have[k]; !ok {
+			add = append(add, k.perm(desc))
+		}
+	}
+	for k := range have {
+		if _, ok := want[k]; !ok {
+			del = append(del, k.perm(""))
+		}
+	}
+	sortPerms(add)
+	sortPerms(del)
+	return add, del
+}
+
+func sortPerms(perms []IPPerm) {
+	sort.Slice(perms, func(i, j int) bool {
+		return fmt.Sprint(perms[i]) < fmt.Sprint(perms[j])
+	})
+}
+
+// tagAction returns the action that turns the tags have of the
+// resource with the given id into want, and whether any change is
+// needed. Tags reserved by AWS, with the "aws:" prefix, are kept.
+func tagAction(resource NetworkResource, id, name string, have, want []Tag) (NetworkAction, bool) {
+	a := NetworkAction{
+		Op:       NetworkUpdate,
+		Resource: resource,
+		Id:       id,
+		Name:     name,
+		Reason:   "tags",
+	}
+	values := make(map[string]string)
+	for _, t := range have {
+		values[t.Key] = t.Value
+	}
+	wanted := make(map[string]bool)
+	for _, t := range want {
+		wanted[t.Key] = true
+		if v, ok := values[t.Key]; !ok || v != t.Value {
+			a.tags = append(a.tags, t)
+		}
+	}
+	for _, t := range have {
+		if !wanted[t.Key] && !strings.HasPrefix(t.Key, "aws:") {
+			a.untags = append(a.untags, DeleteTag{Key: t.Key})
+		}
+	}
+	return a, len(a.tags) > 0 || len(a.untags) > 0
+}
+
+// filterValueEscaper escapes the characters EC2 treats as wildcards
+// in filter values.
+var filterValueEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`)
+
+// networkVPC returns the VPC with the given Name tag, or nil if there
+// is none.
+func (ec2 *EC2) networkVPC(name string) (*VPC, error) {
+	filter := NewFilter()
+	filter.Add("tag:Name", filterValueEscaper.Replace(name))
+	resp, err := ec2.VPCs(nil, filter)
+	if err != nil {
+		return nil, err
+	}
+	var vpcs []*VPC
+	for i := range resp.VPCs {
+		for _, t := range resp.VPCs[i].Tags {
+			if t.Key == "Name" && t.Value == name {
+				vpcs = append(vpcs, &resp.VPCs[i])
+				break
+			}
+		}
+	}
+	switch len(vpcs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return vpcs[0], nil
+	}
+	return nil, fmt.Errorf("%d VPCs are named %q", len(vpcs), name)
+}
+
+// validate checks that the spec is complete and consistent.
+func (spec *NetworkSpec) validate() error {
+	if spec.Name == "" {
+		return fmt.Errorf("network spec has no name")
+	}
+	if spec.CIDRBlock == "" {
+		return fmt.Errorf("network %q has no CIDR block", spec.Name)
+	}
+	subnets := make(map[string]bool)
+	for _, s := range spec.Subnets {
+		if s.CIDRBlock == "" {
+			return fmt.Errorf("subnet of network %q has no CIDR block", spec.Name)
+		}
+		if subnets[s.CIDRBlock] {
+			return fmt.Errorf("duplicate subnet %s in network %q", s.CIDRBlock, spec.Name)
+		}
+		subnets[s.CIDRBlock] = true
+	}
+	groups := make(map[string]bool)
+	for _, g := range spec.SecurityGroups {
+		if g.Name == "" {
+			return fmt.Errorf("security group of network %q has no name", spec.Name)
+		}
+		if groups[g.Name] {
+			return fmt.Errorf("duplicate security group %q in network %q", g.Name, spec.Name)
+		}
+		groups[g.Name] = true
+	}
+	for _, g := range spec.SecurityGroups {
+		for _, perms := range [][]IPPerm{g.Ingress, g.Egress} {
+			for _, perm := range perms {
+				for _, src := range perm.SourceGroups {
+					if src.Id == "" && !groups[src.Name] {
+						return fmt.Errorf("security group %q refers to unknown group %q", g.Name, src.Name)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,357 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"fmt"
+	"sort"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/testutil/faults"
+)
+
+// Network reconciliation tests run only against the local test server.
+
+// networkSummary returns the actions of plan in a compact form.
+func networkSummary(plan *ec2.NetworkPlan) []string {
+	var ops []string
+	for _, a := range plan.Actions {
+		op := fmt.Sprintf("%s %s %s (%s)", a.Op, a.Resource, a.Name, a.Reason)
+		for _, perm := range a.Perms {
+			op += fmt.Sprintf(" %s/%d-%d", perm.Protocol, perm.FromPort, perm.ToPort)
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// groupRules returns the rules of the security group with the given
+// id, one per source, with source groups identified by name.
+func (s *LocalServerSuite) groupRules(c *C, id string) (ingress, egress []string) {
+	resp, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{{Id: id}}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Groups, HasLen, 1)
+	rules := func(perms []ec2.IPPerm) []string {
+		var rules []string
+		for _, perm := range perms {
+			sources := append([]string(nil), perm.SourceIPs...)
+			for _, g := range perm.SourceGroups {
+				sources = append(sources, g.Name)
+			}
+			for _, src := range sources {
+				rules = append(rules, fmt.Sprintf("%s/%d-%d %s", perm.Protocol, perm.FromPort, perm.ToPort, src))
+			}
+		}
+		sort.Strings(rules)
+		return rules
+	}
+	return rules(resp.Groups[0].IPPerms), rules(resp.Groups[0].IPPermsEgress)
+}
+
+func (s *LocalServerSuite) TestReconcileNetwork(c *C) {
+	name := sessionName("reconcile")
+	web, bastion := sessionName("reconcile-web"), sessionName("reconcile-bastion")
+	spec := &ec2.NetworkSpec{
+		Name:      name,
+		CIDRBlock: "10.26.0.0/16",
+		Tags:      []ec2.Tag{{"Env", "test"}},
+		Subnets: []ec2.SubnetSpec{{
+			CIDRBlock:           "10.26.1.0/24",
+			MapPublicIPOnLaunch: true,
+			Tags:                []ec2.Tag{{"Tier", "public"}},
+		}, {
+			CIDRBlock: "10.26.2.0/24",
+		}},
+		SecurityGroups: []ec2.SecurityGroupSpec{{
+			Name:        web,
+			Description: "web servers",
+			Ingress: []ec2.IPPerm{{
+				Protocol:  "tcp",
+				FromPort:  80,
+				ToPort:    80,
+				SourceIPs: []string{"0.0.0.0/0"},
+			}, {
+				Protocol:     "tcp",
+				FromPort:     22,
+				ToPort:       22,
+				SourceGroups: []ec2.UserSecurityGroup{{Name: bastion}},
+			}},
+		}, {
+			Name:        bastion,
+			Description: "bastion hosts",
+			Ingress: []ec2.IPPerm{{
+				Protocol:  "tcp",
+				FromPort:  22,
+				ToPort:    22,
+				SourceIPs: []string{"203.0.113.0/24"},
+			}},
+			Egress: []ec2.IPPerm{{
+				Protocol:  "6",
+				FromPort:  22,
+				ToPort:    22,
+				SourceIPs: []string{"10.26.0.0/16"},
+			}},
+			Tags: []ec2.Tag{{"Role", "bastion"}},
+		}},
+	}
+
+	// Nothing exists yet, so everything is created.
+	plan, err := s.ec2.PlanNetwork(spec)
+	c.Assert(err, IsNil)
+	c.Assert(networkSummary(plan), DeepEquals, []string{
+		"create vpc " + name + " (new)",
+		"create subnet 10.26.1.0/24 (new)",
+		"create subnet 10.26.2.0/24 (new)",
+		"create security-group " + web + " (new)",
+		"create security-group " + bastion + " (new)",
+		"create ingress " + web + " (new) tcp/22-22 tcp/80-80",
+		"create ingress " + bastion + " (new) tcp/22-22",
+		"create egress " + bastion + " (new) tcp/22-22",
+		"delete egress " + bastion + " (extraneous) -1/0-0",
+	})
+	c.Assert(plan.VPCId(), Equals, "")
+	plan, err = s.ec2.ReconcileNetwork(spec)
+	c.Assert(err, IsNil)
+	vpcId := plan.VPCId()
+	c.Assert(vpcId, Not(Equals), "")
+	defer s.ec2.DeleteVPC(vpcId)
+	defer func() {
+		// Reconciling an empty spec deletes the subnets and groups.
+		_, err := s.ec2.ReconcileNetwork(&ec2.NetworkSpec{Name: name, CIDRBlock: "10.26.0.0/16"})
+		c.Check(err, IsNil)
+	}()
+
+	vpcs, err := s.ec2.VPCs([]string{vpcId}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(vpcs.VPCs, HasLen, 1)
+	c.Check(vpcs.VPCs[0].CIDRBlock, Equals, "10.26.0.0/16")
+	c.Check(vpcs.VPCs[0].Tags, DeepEquals, []ec2.Tag{{"Name", name}, {"Env", "test"}})
+
+	subnets, err := s.ec2.Subnets([]string{plan.SubnetId("10.26.1.0/24")}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(subnets.Subnets, HasLen, 1)
+	c.Check(subnets.Subnets[0].VPCId, Equals, vpcId)
+	c.Check(subnets.Subnets[0].MapPublicIPOnLaunch, Equals, true)
+	c.Check(subnets.Subnets[0].Tags, DeepEquals, []ec2.Tag{{"Tier", "public"}})
+	c.Check(plan.SubnetId("10.26.2.0/24"), Not(Equals), "")
+
+	ingress, egress := s.groupRules(c, plan.GroupId(web))
+	c.Check(ingress, DeepEquals, []string{"tcp/22-22 " + bastion, "tcp/80-80 0.0.0.0/0"})
+	c.Check(egress, DeepEquals, []string{"-1/0-0 0.0.0.0/0"})
+	ingress, egress = s.groupRules(c, plan.GroupId(bastion))
+	c.Check(ingress, DeepEquals, []string{"tcp/22-22 203.0.113.0/24"})
+	// Protocol numbers are authorized by name.
+	c.Check(egress, DeepEquals, []string{"tcp/22-22 10.26.0.0/16"})
+
+	// Reconciling again does nothing.
+	plan, err = s.ec2.PlanNetwork(spec)
+	c.Assert(err, IsNil)
+	c.Check(plan.Actions, HasLen, 0)
+	c.Check(plan.VPCId(), Equals, vpcId)
+	webId := plan.GroupId(web)
+
+	// Change the spec.
+	spec.Tags = nil
+	spec.Subnets = []ec2.SubnetSpec{{
+		CIDRBlock: "10.26.1.0/24",
+	}, {
+		CIDRBlock: "10.26.3.0/24",
+		AvailZone: "us-east-1a",
+	}}
+	spec.SecurityGroups[0].Ingress[0].FromPort = 443
+	spec.SecurityGroups[0].Ingress[0].ToPort = 443
+	spec.SecurityGroups[0].Tags = []ec2.Tag{{"Role", "web"}}
+	spec.SecurityGroups[1].Description = "bastion hosts, replaced"
+	plan, err = s.ec2.PlanNetwork(spec)
+	c.Assert(err, IsNil)
+	c.Assert(networkSummary(plan), DeepEquals, []string{
+		"update vpc " + name + " (tags)",
+		"delete subnet 10.26.2.0/24 (extraneous)",
+		"update subnet 10.26.1.0/24 (attributes)",
+		"update subnet 10.26.1.0/24 (tags)",
+		"create subnet 10.26.3.0/24 (new)",
+		"delete ingress " + web + " (extraneous) tcp/22-22 tcp/80-80",
+		"delete security-group " + bastion + " (description)",
+		"create security-group " + bastion + " (description)",
+		"update security-group " + web + " (tags)",
+		"create ingress " + web + " (new) tcp/22-22 tcp/443-443",
+		"create ingress " + bastion + " (new) tcp/22-22",
+		"create egress " + bastion + " (new) tcp/22-22",
+		"delete egress " + bastion + " (extraneous) -1/0-0",
+	})
+	err = plan.Apply()
+	c.Assert(err, IsNil)
+	c.Check(plan.GroupId(web), Equals, webId)
+
+	vpcs, err = s.ec2.VPCs([]string{vpcId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(vpcs.VPCs[0].Tags, DeepEquals, []ec2.Tag{{"Name", name}})
+	filter := ec2.NewFilter()
+	filter.Add("vpc-id", vpcId)
+	subnets, err = s.ec2.Subnets(nil, filter)
+	c.Assert(err, IsNil)
+	var cidrs []string
+	for _, sub := range subnets.Subnets {
+		cidrs = append(cidrs, sub.CIDRBlock+" "+sub.AvailZone)
+		if sub.CIDRBlock == "10.26.1.0/24" {
+			c.Check(sub.MapPublicIPOnLaunch, Equals, false)
+			c.Check(sub.Tags, HasLen, 0)
+		}
+	}
+	sort.Strings(cidrs)
+	c.Check(cidrs, DeepEquals, []string{"10.26.1.0/24 us-east-1b", "10.26.3.0/24 us-east-1a"})
+	ingress, _ = s.groupRules(c, webId)
+	c.Check(ingress, DeepEquals, []string{"tcp/22-22 " + bastion, "tcp/443-443 0.0.0.0/0"})
+	groups, err := s.ec2.SecurityGroups(nil, filter)
+	c.Assert(err, IsNil)
+	c.Check(groups.Groups, HasLen, 2)
+	for _, g := range groups.Groups {
+		if g.Name == bastion {
+			c.Check(g.Id, Equals, plan.GroupId(bastion))
+			c.Check(g.Description, Equals, "bastion hosts, replaced")
+			c.Check(g.Tags, DeepEquals, []ec2.Tag{{"Role", "bastion"}})
+		}
+	}
+
+	// A subnet in the wrong zone is replaced.
+	spec.Subnets[0].AvailZone = "us-east-1a"
+	plan, err = s.ec2.ReconcileNetwork(spec)
+	c.Assert(err, IsNil)
+	c.Assert(networkSummary(plan), DeepEquals, []string{
+		"delete subnet 10.26.1.0/24 (availability zone)",
+		"create subnet 10.26.1.0/24 (availability zone)",
+	})
+	plan, err = s.ec2.PlanNetwork(spec)
+	c.Assert(err, IsNil)
+	c.Check(plan.Actions, HasLen, 0)
+}
+
+func (s *LocalServerSuite) TestReconcileNetworkErrors(c *C) {
+	name := sessionName("reconcile-errors")
+	for i, test := range []struct {
+		spec ec2.NetworkSpec
+		err  string
+	}{{
+		spec: ec2.NetworkSpec{CIDRBlock: "10.27.0.0/16"},
+		err:  "network spec has no name",
+	}, {
+		spec: ec2.NetworkSpec{Name: name},
+		err:  `network ".*" has no CIDR block`,
+	}, {
+		spec: ec2.NetworkSpec{
+			Name:      name,
+			CIDRBlock: "10.27.0.0/16",
+			Subnets:   []ec2.SubnetSpec{{CIDRBlock: "10.27.1.0/24"}, {CIDRBlock: "10.27.1.0/24"}},
+		},
+		err: `duplicate subnet 10.27.1.0/24 in network ".*"`,
+	}, {
+		spec: ec2.NetworkSpec{
+			Name:           name,
+			CIDRBlock:      "10.27.0.0/16",
+			SecurityGroups: []ec2.SecurityGroupSpec{{Name: "a"}, {Name: "a"}},
+		},
+		err: `duplicate security group "a" in network ".*"`,
+	}, {
+		spec: ec2.NetworkSpec{
+			Name:      name,
+			CIDRBlock: "10.27.0.0/16",
+			SecurityGroups: []ec2.SecurityGroupSpec{{
+				Name: "a",
+				Ingress: []ec2.IPPerm{{
+					Protocol:     "tcp",
+					SourceGroups: []ec2.UserSecurityGroup{{Name: "b"}},
+				}},
+			}},
+		},
+		err: `security group "a" refers to unknown group "b"`,
+	}} {
+		c.Logf("test %d: %s", i, test.err)
+		_, err := s.ec2.PlanNetwork(&test.spec)
+		c.Check(err, ErrorMatches, test.err)
+	}
+
+	// The CIDR block of an existing VPC cannot change.
+	spec := &ec2.NetworkSpec{Name: name, CIDRBlock: "10.27.0.0/16"}
+	plan, err := s.ec2.ReconcileNetwork(spec)
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVPC(plan.VPCId())
+	spec.CIDRBlock = "10.28.0.0/16"
+	_, err = s.ec2.PlanNetwork(spec)
+	c.Check(err, ErrorMatches, `VPC ".*" has CIDR block 10.27.0.0/16, not 10.28.0.0/16`)
+
+	// Errors name the failing action.
+	spec = &ec2.NetworkSpec{
+		Name:      name,
+		CIDRBlock: "10.27.0.0/16",
+		Subnets:   []ec2.SubnetSpec{{CIDRBlock: "10.99.0.0/24"}},
+	}
+	_, err = s.ec2.ReconcileNetwork(spec)
+	c.Check(err, ErrorMatches, `cannot create subnet "10.99.0.0/24": .*`)
+}
+
+func (s *LocalServerSuite) TestReconcileNetworkUnnamedVPC(c *C) {
+	inj := s.srv.srv.Faults()
+	defer inj.Reset()
+	countVPCs := func() int {
+		filter := ec2.NewFilter()
+		filter.Add("cidr", "10.29.0.0/16")
+		resp, err := s.ec2.VPCs(nil, filter)
+		c.Assert(err, IsNil)
+		return len(resp.VPCs)
+	}
+
+	// A new VPC that cannot be named is not left behind.
+	spec := &ec2.NetworkSpec{Name: sessionName("reconcile-unnamed"), CIDRBlock: "10.29.0.0/16"}
+	inj.Script("CreateTags", faults.Fault{StatusCode: 400, Code: "Blocked", Message: "blocked"})
+	_, err := s.ec2.ReconcileNetwork(spec)
+	c.Assert(err, ErrorMatches, `cannot create vpc ".*": blocked \(Blocked\)`)
+	c.Assert(countVPCs(), Equals, 0)
+
+	plan, err := s.ec2.ReconcileNetwork(spec)
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVPC(plan.VPCId())
+	c.Assert(countVPCs(), Equals, 1)
+	plan, err = s.ec2.PlanNetwork(spec)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Actions, HasLen, 0)
+}
+
+func (s *LocalServerSuite) TestReconcileNetworkWildcardName(c *C) {
+	// Another VPC whose name matches the spec's as a pattern.
+	name := sessionName("reconcile-wild")
+	resp, err := s.ec2.CreateVPC("10.30.0.0/16", "")
+	c.Assert(err, IsNil)
+	otherId := resp.VPC.Id
+	defer s.ec2.DeleteVPC(otherId)
+	_, err = s.ec2.CreateTags([]string{otherId}, []ec2.Tag{{"Name", name + "-1"}})
+	c.Assert(err, IsNil)
+	_, err = s.ec2.CreateSubnet(otherId, "10.30.1.0/24", "")
+	c.Assert(err, IsNil)
+
+	for _, pattern := range []string{name + "-?", name + "*", name + `\-1`} {
+		c.Logf("name %q", pattern)
+		plan, err := s.ec2.PlanNetwork(&ec2.NetworkSpec{Name: pattern, CIDRBlock: "10.30.0.0/16"})
+		c.Assert(err, IsNil)
+		c.Check(networkSummary(plan), DeepEquals, []string{"create vpc " + pattern + " (new)"})
+	}
+
+	// The VPC is still found by its exact name.
+	plan, err := s.ec2.PlanNetwork(&ec2.NetworkSpec{
+		Name:      name + "-1",
+		CIDRBlock: "10.30.0.0/16",
+		Subnets:   []ec2.SubnetSpec{{CIDRBlock: "10.30.1.0/24"}},
+	})
+	c.Assert(err, IsNil)
+	c.Check(plan.Actions, HasLen, 0)
+	c.Check(plan.VPCId(), Equals, otherId)
+	_, err = s.ec2.DeleteSubnet(plan.SubnetId("10.30.1.0/24"))
+	c.Check(err, IsNil)
+}
@@ -611,6 +611,87 @@ var RevokeSecurityGroupIngressExample = `
 </RevokeSecurityGroupIngressResponse>
 `
 
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AuthorizeSecurityGroupEgress.html
+var AuthorizeSecurityGroupEgressExample = `
+<AuthorizeSecurityGroupEgressResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</AuthorizeSecurityGroupEgressResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSecurityGroups.html
+var DescribeSecurityGroupsVPCExample = `
+<DescribeSecurityGroupsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <securityGroupInfo>
+    <item>
+      <ownerId>123456789012</ownerId>
+      <groupId>sg-1a2b3c4d</groupId>
+      <groupName>MySecurityGroup</groupName>
+      <groupDescription>MySecurityGroup</groupDescription>
+      <vpcId>vpc-81326ae4</vpcId>
+      <ipPermissions>
+        <item>
+          <ipProtocol>tcp</ipProtocol>
+          <fromPort>22</fromPort>
+          <toPort>22</toPort>
+          <groups>
+            <item>
+              <userId>123456789012</userId>
+              <groupId>sg-2a2b3c4d</groupId>
+              <description>Access from bastion</description>
+            </item>
+          </groups>
+          <ipRanges>
+            <item>
+              <cidrIp>203.0.113.0/24</cidrIp>
+              <description>Office network</description>
+            </item>
+            <item>
+              <cidrIp>198.51.100.0/24</cidrIp>
+            </item>
+          </ipRanges>
+          <ipv6Ranges>
+            <item>
+              <cidrIpv6>2001:db8:1234:1a00::/64</cidrIpv6>
+              <description>Office IPv6 network</description>
+            </item>
+          </ipv6Ranges>
+          <prefixListIds/>
+        </item>
+      </ipPermissions>
+      <ipPermissionsEgress>
+        <item>
+          <ipProtocol>-1</ipProtocol>
+          <groups/>
+          <ipRanges>
+            <item>
+              <cidrIp>0.0.0.0/0</cidrIp>
+            </item>
+          </ipRanges>
+          <ipv6Ranges/>
+          <prefixListIds/>
+        </item>
+        <item>
+          <ipProtocol>tcp</ipProtocol>
+          <fromPort>443</fromPort>
+          <toPort>443</toPort>
+          <groups/>
+          <ipRanges/>
+          <ipv6Ranges/>
+          <prefixListIds>
+            <item>
+              <prefixListId>pl-12c4e678</prefixListId>
+              <description>S3 endpoint</description>
+            </item>
+          </prefixListIds>
+        </item>
+      </ipPermissionsEgress>
+    </item>
+  </securityGroupInfo>
+</DescribeSecurityGroupsResponse>
+`
+
 // http://goo.gl/Vmkqc
 var CreateTagsExample = `
 <CreateTagsResponse xmlns="http://ec2.amazonaws.com/doc/2011-12-15/">
@@ -619,6 +700,35 @@ var CreateTagsExample = `
 </CreateTagsResponse>
 `
 
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteTags.html
+var DeleteTagsExample = `
+<DeleteTagsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+   <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+   <return>true</return>
+</DeleteTagsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeTags.html
+var DescribeTagsExample = `
+<DescribeTagsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+   <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+   <tagSet>
+      <item>
+         <resourceId>ami-1a2b3c4d</resourceId>
+         <resourceType>image</resourceType>
+         <key>webserver</key>
+         <value/>
+      </item>
+      <item>
+         <resourceId>i-1234567890abcdef0</resourceId>
+         <resourceType>instance</resourceType>
+         <key>stack</key>
+         <value>Production</value>
+      </item>
+   </tagSet>
+</DescribeTagsResponse>
+`
+
 // http://goo.gl/awKeF
 var StartInstancesExample = `
 <StartInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2011-12-15/">
@@ -1033,3 +1143,1189 @@ var DescribeAccountAttributesExample3 = `
   </accountAttributeSet>
 </DescribeAccountAttributesResponse>
 `
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVolume.html
+var CreateVolumeExample = `
+<CreateVolumeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <volumeId>vol-1234567890abcdef0</volumeId>
+  <size>80</size>
+  <snapshotId>snap-1234567890abcdef0</snapshotId>
+  <availabilityZone>us-east-1a</availabilityZone>
+  <status>creating</status>
+  <createTime>2016-08-29T18:52:32.724Z</createTime>
+  <volumeType>io1</volumeType>
+  <iops>1000</iops>
+  <encrypted>true</encrypted>
+  <kmsKeyId>arn:aws:kms:us-east-1:012345678910:key/abcd1234-a123-456a-a12b-a123b4cd56ef</kmsKeyId>
+</CreateVolumeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteVolume.html
+var DeleteVolumeExample = `
+<DeleteVolumeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</DeleteVolumeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumes.html
+var DescribeVolumesExample = `
+<DescribeVolumesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <volumeSet>
+    <item>
+      <volumeId>vol-1234567890abcdef0</volumeId>
+      <size>80</size>
+      <snapshotId/>
+      <availabilityZone>us-east-1a</availabilityZone>
+      <status>in-use</status>
+      <createTime>YYYY-MM-DDTHH:MM:SS.SSSZ</createTime>
+      <attachmentSet>
+        <item>
+          <volumeId>vol-1234567890abcdef0</volumeId>
+          <instanceId>i-1234567890abcdef0</instanceId>
+          <device>/dev/sdh</device>
+          <status>attached</status>
+          <attachTime>YYYY-MM-DDTHH:MM:SS.SSSZ</attachTime>
+          <deleteOnTermination>false</deleteOnTermination>
+        </item>
+      </attachmentSet>
+      <volumeType>standard</volumeType>
+      <encrypted>true</encrypted>
+      <tagSet>
+        <item>
+          <key>Name</key>
+          <value>data</value>
+        </item>
+      </tagSet>
+    </item>
+  </volumeSet>
+</DescribeVolumesResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AttachVolume.html
+var AttachVolumeExample = `
+<AttachVolumeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <volumeId>vol-1234567890abcdef0</volumeId>
+  <instanceId>i-1234567890abcdef0</instanceId>
+  <device>/dev/sdh</device>
+  <status>attaching</status>
+  <attachTime>YYYY-MM-DDTHH:MM:SS.000Z</attachTime>
+</AttachVolumeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DetachVolume.html
+var DetachVolumeExample = `
+<DetachVolumeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <volumeId>vol-1234567890abcdef0</volumeId>
+  <instanceId>i-1234567890abcdef0</instanceId>
+  <device>/dev/sdh</device>
+  <status>detaching</status>
+  <attachTime>YYYY-MM-DDTHH:MM:SS.000Z</attachTime>
+</DetachVolumeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVolume.html
+var ModifyVolumeExample = `
+<ModifyVolumeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>5jkdf074-37ed-4004-8671-a78ee82bf1cbEXAMPLE</requestId>
+  <volumeModification>
+    <targetIops>10000</targetIops>
+    <originalIops>300</originalIops>
+    <modificationState>modifying</modificationState>
+    <targetSize>200</targetSize>
+    <targetVolumeType>io1</targetVolumeType>
+    <volumeId>vol-0123456789EXAMPLE</volumeId>
+    <progress>0</progress>
+    <startTime>2017-01-19T22:21:02.959Z</startTime>
+    <originalSize>100</originalSize>
+    <originalVolumeType>gp2</originalVolumeType>
+  </volumeModification>
+</ModifyVolumeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumeStatus.html
+var DescribeVolumeStatusExample = `
+<DescribeVolumeStatusResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>5jkdf074-37ed-4004-8671-a78ee82bf1cbEXAMPLE</requestId>
+  <volumeStatusSet>
+    <item>
+      <volumeId>vol-1234567890abcdef0</volumeId>
+      <availabilityZone>us-east-1d</availabilityZone>
+      <volumeStatus>
+        <status>impaired</status>
+        <details>
+          <item>
+            <name>io-enabled</name>
+            <status>failed</status>
+          </item>
+        </details>
+      </volumeStatus>
+      <eventsSet>
+        <item>
+          <eventId>evol-61a54008</eventId>
+          <eventType>potential-data-inconsistency</eventType>
+          <description>THIS IS AN EXAMPLE</description>
+          <notBefore>2011-12-01T14:00:00.000Z</notBefore>
+          <notAfter>2011-12-01T15:00:00.000Z</notAfter>
+        </item>
+      </eventsSet>
+      <actionsSet>
+        <item>
+          <code>enable-volume-io</code>
+          <eventId>evol-61a54008</eventId>
+          <eventType>potential-data-inconsistency</eventType>
+          <description>THIS IS AN EXAMPLE</description>
+        </item>
+      </actionsSet>
+    </item>
+  </volumeStatusSet>
+</DescribeVolumeStatusResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateKeyPair.html
+var CreateKeyPairExample = `
+<CreateKeyPairResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <keyName>my-key-pair</keyName>
+  <keyFingerprint>1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f</keyFingerprint>
+  <keyMaterial>---- BEGIN RSA PRIVATE KEY ----
+MIICiTCCAfICCQD6m7oRw0uXOjANBgkqhkiG9w0BAQUFADCBiDELMAkGA1UEBhMC
+-----END RSA PRIVATE KEY-----</keyMaterial>
+</CreateKeyPairResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ImportKeyPair.html
+var ImportKeyPairExample = `
+<ImportKeyPairResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+  <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+  <keyName>my-key-pair</keyName>
+  <keyFingerprint>1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca</keyFingerprint>
+</ImportKeyPairResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeKeyPairs.html
+var DescribeKeyPairsExample = `
+<DescribeKeyPairsResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <keySet>
+    <item>
+      <keyName>my-key-pair</keyName>
+      <keyFingerprint>1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f</keyFingerprint>
+    </item>
+  </keySet>
+</DescribeKeyPairsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteKeyPair.html
+var DeleteKeyPairExample = `
+<DeleteKeyPairResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</DeleteKeyPairResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AllocateAddress.html
+var AllocateAddressExample = `
+<AllocateAddressResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <publicIp>198.51.100.1</publicIp>
+   <domain>vpc</domain>
+   <allocationId>eipalloc-5723d13e</allocationId>
+</AllocateAddressResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeAddresses.html
+var DescribeAddressesExample = `
+<DescribeAddressesResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>f7de5e98-491a-4c19-a92d-908d6EXAMPLE</requestId>
+   <addressesSet>
+      <item>
+         <publicIp>203.0.113.41</publicIp>
+         <allocationId>eipalloc-08229861</allocationId>
+         <domain>vpc</domain>
+         <instanceId>i-64600030</instanceId>
+         <associationId>eipassoc-f0229899</associationId>
+         <networkInterfaceId>eni-ef229886</networkInterfaceId>
+         <networkInterfaceOwnerId>053230519467</networkInterfaceOwnerId>
+         <privateIpAddress>10.0.0.228</privateIpAddress>
+      </item>
+      <item>
+         <publicIp>198.51.100.2</publicIp>
+         <domain>standard</domain>
+         <instanceId/>
+      </item>
+   </addressesSet>
+</DescribeAddressesResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateAddress.html
+var AssociateAddressExample = `
+<AssociateAddressResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <return>true</return>
+   <associationId>eipassoc-fc5ca095</associationId>
+</AssociateAddressResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateAddress.html
+var DisassociateAddressExample = `
+<DisassociateAddressResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <return>true</return>
+</DisassociateAddressResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReleaseAddress.html
+var ReleaseAddressExample = `
+<ReleaseAddressResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <return>true</return>
+</ReleaseAddressResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateInternetGateway.html
+var CreateInternetGatewayExample = `
+<CreateInternetGatewayResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <internetGateway>
+      <internetGatewayId>igw-eaad4883</internetGatewayId>
+      <attachmentSet/>
+      <tagSet/>
+   </internetGateway>
+</CreateInternetGatewayResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInternetGateways.html
+var DescribeInternetGatewaysExample = `
+<DescribeInternetGatewaysResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <internetGatewaySet>
+      <item>
+         <internetGatewayId>igw-eaad4883EXAMPLE</internetGatewayId>
+         <attachmentSet>
+            <item>
+               <vpcId>vpc-11ad4878</vpcId>
+               <state>available</state>
+            </item>
+         </attachmentSet>
+         <tagSet/>
+      </item>
+   </internetGatewaySet>
+</DescribeInternetGatewaysResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AttachInternetGateway.html
+var AttachInternetGatewayExample = `
+<AttachInternetGatewayResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <return>true</return>
+</AttachInternetGatewayResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateRouteTable.html
+var CreateRouteTableExample = `
+<CreateRouteTableResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <routeTable>
+      <routeTableId>rtb-f9ad4890</routeTableId>
+      <vpcId>vpc-11ad4878</vpcId>
+      <routeSet>
+         <item>
+            <destinationCidrBlock>10.0.0.0/22</destinationCidrBlock>
+            <gatewayId>local</gatewayId>
+            <state>active</state>
+            <origin>CreateRouteTable</origin>
+         </item>
+      </routeSet>
+      <associationSet/>
+      <tagSet/>
+   </routeTable>
+</CreateRouteTableResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeRouteTables.html
+var DescribeRouteTablesExample = `
+<DescribeRouteTablesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+   <requestId>6f570b0b-9c18-4b07-bdec-73740dcf861a</requestId>
+   <routeTableSet>
+      <item>
+         <routeTableId>rtb-13ad487a</routeTableId>
+         <vpcId>vpc-11ad4878</vpcId>
+         <routeSet>
+            <item>
+               <destinationCidrBlock>10.0.0.0/22</destinationCidrBlock>
+               <gatewayId>local</gatewayId>
+               <state>active</state>
+               <origin>CreateRouteTable</origin>
+            </item>
+         </routeSet>
+         <associationSet>
+            <item>
+               <routeTableAssociationId>rtbassoc-12ad487b</routeTableAssociationId>
+               <routeTableId>rtb-13ad487a</routeTableId>
+               <main>true</main>
+            </item>
+         </associationSet>
+         <tagSet/>
+      </item>
+      <item>
+         <routeTableId>rtb-f9ad4890</routeTableId>
+         <vpcId>vpc-11ad4878</vpcId>
+         <routeSet>
+            <item>
+               <destinationCidrBlock>10.0.0.0/22</destinationCidrBlock>
+               <gatewayId>local</gatewayId>
+               <state>active</state>
+               <origin>CreateRouteTable</origin>
+            </item>
+            <item>
+               <destinationCidrBlock>0.0.0.0/0</destinationCidrBlock>
+               <gatewayId>igw-eaad4883</gatewayId>
+               <state>active</state>
+               <origin>CreateRoute</origin>
+            </item>
+         </routeSet>
+         <associationSet>
+            <item>
+               <routeTableAssociationId>rtbassoc-faad4893</routeTableAssociationId>
+               <routeTableId>rtb-f9ad4890</routeTableId>
+               <subnetId>subnet-15ad487c</subnetId>
+               <main>false</main>
+            </item>
+         </associationSet>
+         <tagSet/>
+      </item>
+   </routeTableSet>
+</DescribeRouteTablesResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateRouteTable.html
+var AssociateRouteTableExample = `
+<AssociateRouteTableResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <associationId>rtbassoc-f8ad4891</associationId>
+</AssociateRouteTableResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReplaceRouteTableAssociation.html
+var ReplaceRouteTableAssociationExample = `
+<ReplaceRouteTableAssociationResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <newAssociationId>rtbassoc-faad2958</newAssociationId>
+</ReplaceRouteTableAssociationResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateRoute.html
+var CreateRouteExample = `
+<CreateRouteResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+   <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+   <return>true</return>
+</CreateRouteResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateNatGateway.html
+var CreateNatGatewayExample = `
+<CreateNatGatewayResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+    <requestId>1b74dc5c-bcda-403f-867d-example</requestId>
+    <natGateway>
+        <subnetId>subnet-1a2b3c4d</subnetId>
+        <natGatewayAddressSet>
+            <item>
+                <allocationId>eipalloc-37fc1a52</allocationId>
+            </item>
+        </natGatewayAddressSet>
+        <createTime>2015-11-25T14:00:55.416Z</createTime>
+        <vpcId>vpc-4e20d42b</vpcId>
+        <natGatewayId>nat-04e77a5e9c34432f9</natGatewayId>
+        <state>pending</state>
+    </natGateway>
+</CreateNatGatewayResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteNatGateway.html
+var DeleteNatGatewayExample = `
+<DeleteNatGatewayResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+    <requestId>741fc8ab-6ebe-452b-b92b-example</requestId>
+    <natGatewayId>nat-04ae55e711cec5680</natGatewayId>
+</DeleteNatGatewayResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeNatGateways.html
+var DescribeNatGatewaysExample = `
+<DescribeNatGatewaysResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+    <requestId>bfed02c6-dae9-47c0-86a2-example</requestId>
+    <natGatewaySet>
+         <item>
+            <subnetId>subnet-1a2a3a4a</subnetId>
+            <natGatewayAddressSet>
+                <item>
+                    <networkInterfaceId>eni-00e37850</networkInterfaceId>
+                    <publicIp>198.18.125.129</publicIp>
+                    <allocationId>eipalloc-37fc1a52</allocationId>
+                    <privateIp>10.0.2.147</privateIp>
+                </item>
+            </natGatewayAddressSet>
+            <createTime>2015-11-25T14:00:55.416Z</createTime>
+            <vpcId>vpc-4e20d42b</vpcId>
+            <natGatewayId>nat-04e77a5e9c34432f9</natGatewayId>
+            <state>available</state>
+        </item>
+    </natGatewaySet>
+</DescribeNatGatewaysResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateDhcpOptions.html
+var CreateDhcpOptionsExample = `
+<CreateDhcpOptionsResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <dhcpOptions>
+      <dhcpOptionsId>dopt-7a8b9c2d</dhcpOptionsId>
+      <dhcpConfigurationSet>
+        <item>
+          <key>domain-name</key>
+          <valueSet>
+            <item>
+              <value>example.com</value>
+            </item>
+          </valueSet>
+        </item>
+        <item>
+          <key>domain-name-servers</key>
+          <valueSet>
+            <item>
+              <value>10.2.5.1</value>
+            </item>
+            <item>
+              <value>10.2.5.2</value>
+            </item>
+          </valueSet>
+        </item>
+      </dhcpConfigurationSet>
+      <tagSet/>
+  </dhcpOptions>
+</CreateDhcpOptionsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeDhcpOptions.html
+var DescribeDhcpOptionsExample = `
+<DescribeDhcpOptionsResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <dhcpOptionsSet>
+    <item>
+      <dhcpOptionsId>dopt-7a8b9c2d</dhcpOptionsId>
+      <dhcpConfigurationSet>
+        <item>
+          <key>domain-name</key>
+          <valueSet>
+            <item>
+              <value>example.com</value>
+            </item>
+          </valueSet>
+        </item>
+      </dhcpConfigurationSet>
+      <tagSet/>
+    </item>
+  </dhcpOptionsSet>
+</DescribeDhcpOptionsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateDhcpOptions.html
+var AssociateDhcpOptionsExample = `
+<AssociateDhcpOptionsResponse xmlns="http://ec2.amazonaws.com/doc/2013-10-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</AssociateDhcpOptionsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVpcPeeringConnection.html
+var CreateVpcPeeringConnectionExample = `
+<CreateVpcPeeringConnectionResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+  <vpcPeeringConnection>
+    <vpcPeeringConnectionId>pcx-73a5401a</vpcPeeringConnectionId>
+    <requesterVpcInfo>
+      <ownerId>777788889999</ownerId>
+      <vpcId>vpc-1a2b3c4d</vpcId>
+      <cidrBlock>10.0.0.0/28</cidrBlock>
+    </requesterVpcInfo>
+    <accepterVpcInfo>
+      <ownerId>123456789012</ownerId>
+      <vpcId>vpc-a1b2c3d4</vpcId>
+    </accepterVpcInfo>
+    <status>
+      <code>initiating-request</code>
+      <message>Initiating Request to 123456789012</message>
+    </status>
+    <expirationTime>2014-02-18T14:37:25.000Z</expirationTime>
+    <tagSet/>
+  </vpcPeeringConnection>
+</CreateVpcPeeringConnectionResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AcceptVpcPeeringConnection.html
+var AcceptVpcPeeringConnectionExample = `
+<AcceptVpcPeeringConnectionResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+  <vpcPeeringConnection>
+    <vpcPeeringConnectionId>pcx-1a2b3c4d</vpcPeeringConnectionId>
+    <requesterVpcInfo>
+      <ownerId>123456789012</ownerId>
+      <vpcId>vpc-1a2b3c4d</vpcId>
+      <cidrBlock>10.0.0.0/28</cidrBlock>
+    </requesterVpcInfo>
+    <accepterVpcInfo>
+      <ownerId>777788889999</ownerId>
+      <vpcId>vpc-111aaa22</vpcId>
+      <cidrBlock>10.0.1.0/28</cidrBlock>
+    </accepterVpcInfo>
+    <status>
+      <code>active</code>
+      <message>Active</message>
+    </status>
+    <tagSet/>
+  </vpcPeeringConnection>
+</AcceptVpcPeeringConnectionResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcPeeringConnections.html
+var DescribeVpcPeeringConnectionsExample = `
+<DescribeVpcPeeringConnectionsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+  <vpcPeeringConnectionSet>
+    <item>
+      <vpcPeeringConnectionId>pcx-111aaa22</vpcPeeringConnectionId>
+      <requesterVpcInfo>
+        <ownerId>777788889999</ownerId>
+        <vpcId>vpc-1a2b3c4d</vpcId>
+        <cidrBlock>172.31.0.0/16</cidrBlock>
+      </requesterVpcInfo>
+      <accepterVpcInfo>
+        <ownerId>111122223333</ownerId>
+        <vpcId>vpc-aa22cc33</vpcId>
+      </accepterVpcInfo>
+      <status>
+        <code>pending-acceptance</code>
+        <message>Pending Acceptance by 111122223333</message>
+      </status>
+      <expirationTime>2014-02-17T16:00:50.000Z</expirationTime>
+      <tagSet/>
+    </item>
+  </vpcPeeringConnectionSet>
+</DescribeVpcPeeringConnectionsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateImage.html
+var CreateImageExample = `
+<CreateImageResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <imageId>ami-4fa54026</imageId>
+</CreateImageResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RegisterImage.html
+var RegisterImageExample = `
+<RegisterImageResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <imageId>ami-1a2b3c4d</imageId>
+</RegisterImageResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopyImage.html
+var CopyImageExample = `
+<CopyImageResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>60bc441d-fa2c-494d-b155-5d6a3EXAMPLE</requestId>
+  <imageId>ami-4d3c2b1a</imageId>
+</CopyImageResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeregisterImage.html
+var DeregisterImageExample = `
+<DeregisterImageResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</DeregisterImageResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeImageAttribute.html
+var DescribeImageAttributeExample = `
+<DescribeImageAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <imageId>ami-61a54008</imageId>
+  <launchPermission>
+    <item>
+      <group>all</group>
+    </item>
+    <item>
+      <userId>495219933132</userId>
+    </item>
+  </launchPermission>
+</DescribeImageAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyImageAttribute.html
+var ModifyImageAttributeExample = `
+<ModifyImageAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</ModifyImageAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RequestSpotInstances.html
+var RequestSpotInstancesExample = `
+<RequestSpotInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <spotInstanceRequestSet>
+    <item>
+      <spotInstanceRequestId>sir-1a2b3c4d</spotInstanceRequestId>
+      <spotPrice>0.5</spotPrice>
+      <type>one-time</type>
+      <state>open</state>
+      <status>
+        <code>pending-evaluation</code>
+        <updateTime>2016-10-10T23:28:58.000Z</updateTime>
+        <message>Your Spot request has been submitted for review, and is pending evaluation.</message>
+      </status>
+      <availabilityZoneGroup>MyAzGroup</availabilityZoneGroup>
+      <launchSpecification>
+        <imageId>ami-1a2b3c4d</imageId>
+        <keyName>my-key-pair</keyName>
+        <groupSet>
+          <item>
+            <groupId>sg-1a2b3c4d</groupId>
+            <groupName>websrv</groupName>
+          </item>
+        </groupSet>
+        <instanceType>m3.medium</instanceType>
+        <blockDeviceMapping/>
+        <monitoring>
+          <enabled>false</enabled>
+        </monitoring>
+        <ebsOptimized>false</ebsOptimized>
+      </launchSpecification>
+      <createTime>2016-10-10T23:28:58.000Z</createTime>
+      <productDescription>Linux/UNIX</productDescription>
+    </item>
+  </spotInstanceRequestSet>
+</RequestSpotInstancesResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotInstanceRequests.html
+var DescribeSpotInstanceRequestsExample = `
+<DescribeSpotInstanceRequestsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>b1719f2a-5334-4479-b2f1-26926EXAMPLE</requestId>
+  <spotInstanceRequestSet>
+    <item>
+      <spotInstanceRequestId>sir-1a2b3c4d</spotInstanceRequestId>
+      <spotPrice>0.09</spotPrice>
+      <type>one-time</type>
+      <state>active</state>
+      <status>
+        <code>fulfilled</code>
+        <updateTime>2016-10-10T23:31:04.000Z</updateTime>
+        <message>Your Spot request is fulfilled.</message>
+      </status>
+      <launchSpecification>
+        <imageId>ami-7aba833f</imageId>
+        <keyName>my-key-pair</keyName>
+        <groupSet>
+          <item>
+            <groupId>sg-e38f24a7</groupId>
+            <groupName>websrv</groupName>
+          </item>
+        </groupSet>
+        <instanceType>m1.small</instanceType>
+        <placement>
+          <availabilityZone>us-west-1b</availabilityZone>
+        </placement>
+        <blockDeviceMapping/>
+        <monitoring>
+          <enabled>false</enabled>
+        </monitoring>
+        <ebsOptimized>false</ebsOptimized>
+      </launchSpecification>
+      <instanceId>i-1234567890abcdef0</instanceId>
+      <createTime>2016-10-10T23:28:58.000Z</createTime>
+      <productDescription>Linux/UNIX</productDescription>
+      <tagSet>
+        <item>
+          <key>my-tag-key</key>
+          <value>my-tag-value</value>
+        </item>
+      </tagSet>
+      <launchedAvailabilityZone>us-west-1b</launchedAvailabilityZone>
+    </item>
+  </spotInstanceRequestSet>
+</DescribeSpotInstanceRequestsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CancelSpotInstanceRequests.html
+var CancelSpotInstanceRequestsExample = `
+<CancelSpotInstanceRequestsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <spotInstanceRequestSet>
+    <item>
+      <spotInstanceRequestId>sir-1a2b3c4d</spotInstanceRequestId>
+      <state>cancelled</state>
+    </item>
+  </spotInstanceRequestSet>
+</CancelSpotInstanceRequestsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotPriceHistory.html
+var DescribeSpotPriceHistoryExample = `
+<DescribeSpotPriceHistoryResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <spotPriceHistorySet>
+    <item>
+      <instanceType>m1.xlarge</instanceType>
+      <productDescription>Linux/UNIX (Amazon VPC)</productDescription>
+      <spotPrice>0.080000</spotPrice>
+      <timestamp>2014-01-06T04:32:53.000Z</timestamp>
+      <availabilityZone>us-west-2a</availabilityZone>
+    </item>
+    <item>
+      <instanceType>m1.xlarge</instanceType>
+      <productDescription>Linux/UNIX (Amazon VPC)</productDescription>
+      <spotPrice>0.080000</spotPrice>
+      <timestamp>2014-01-05T11:28:26.000Z</timestamp>
+      <availabilityZone>us-west-2c</availabilityZone>
+    </item>
+  </spotPriceHistorySet>
+  <nextToken/>
+</DescribeSpotPriceHistoryResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceAttribute.html
+var DescribeInstanceAttributeExample = `
+<DescribeInstanceAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <instanceId>i-1234567890abcdef0</instanceId>
+  <userData>
+    <value>IyEvYmluL3NoCmVjaG8gaGVsbG8K</value>
+  </userData>
+</DescribeInstanceAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyInstanceAttribute.html
+var ModifyInstanceAttributeExample = `
+<ModifyInstanceAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</ModifyInstanceAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_GetConsoleOutput.html
+var GetConsoleOutputExample = `
+<GetConsoleOutputResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <instanceId>i-1234567890abcdef0</instanceId>
+  <timestamp>2010-10-14T01:12:41.000Z</timestamp>
+  <output>TGludXggdmVyc2lvbiAyLjYuMTYteGVuVSAoYnVpbGRlckBwYXRjaGJhdC5hbWF6b25zYSkgKGdj
+YyB2ZXJzaW9uIDQuMC4xIDIwMDUwNzI3IChSZWQgSGF0IDQuMC4xLTUpKSAjMSBTTVAgVGh1IE9j
+dCAyNiAwODo0MToyNiBTQVNUIDIwMDYK</output>
+</GetConsoleOutputResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeInstanceStatus.html
+var DescribeInstanceStatusExample = `
+<DescribeInstanceStatusResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>3be1508e-c444-4fef-89cc-0b1223c4f02fEXAMPLE</requestId>
+  <instanceStatusSet>
+    <item>
+      <instanceId>i-1234567890abcdef0</instanceId>
+      <availabilityZone>us-east-1d</availabilityZone>
+      <instanceState>
+        <code>16</code>
+        <name>running</name>
+      </instanceState>
+      <systemStatus>
+        <status>impaired</status>
+        <details>
+          <item>
+            <name>reachability</name>
+            <status>failed</status>
+            <impairedSince>YYYY-MM-DDTHH:MM:SS.000Z</impairedSince>
+          </item>
+        </details>
+      </systemStatus>
+      <instanceStatus>
+        <status>impaired</status>
+        <details>
+          <item>
+            <name>reachability</name>
+            <status>failed</status>
+            <impairedSince>YYYY-MM-DDTHH:MM:SS.000Z</impairedSince>
+          </item>
+        </details>
+      </instanceStatus>
+      <eventsSet>
+        <item>
+          <code>instance-retirement</code>
+          <description>The instance is running on degraded hardware</description>
+          <notBefore>YYYY-MM-DDTHH:MM:SS+0000</notBefore>
+          <notAfter>YYYY-MM-DDTHH:MM:SS+0000</notAfter>
+        </item>
+      </eventsSet>
+    </item>
+    <item>
+      <instanceId>i-0598c7d356eba48d7</instanceId>
+      <availabilityZone>us-east-1d</availabilityZone>
+      <instanceState>
+        <code>16</code>
+        <name>running</name>
+      </instanceState>
+      <systemStatus>
+        <status>ok</status>
+        <details>
+          <item>
+            <name>reachability</name>
+            <status>passed</status>
+          </item>
+        </details>
+      </systemStatus>
+      <instanceStatus>
+        <status>ok</status>
+        <details>
+          <item>
+            <name>reachability</name>
+            <status>passed</status>
+          </item>
+        </details>
+      </instanceStatus>
+    </item>
+  </instanceStatusSet>
+</DescribeInstanceStatusResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreatePlacementGroup.html
+var CreatePlacementGroupExample = `
+<CreatePlacementGroupResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE</requestId>
+  <return>true</return>
+</CreatePlacementGroupResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribePlacementGroups.html
+var DescribePlacementGroupsExample = `
+<DescribePlacementGroupsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE</requestId>
+  <placementGroupSet>
+    <item>
+      <groupName>XYZ-cluster</groupName>
+      <strategy>cluster</strategy>
+      <state>available</state>
+    </item>
+    <item>
+      <groupName>ABC-partition</groupName>
+      <strategy>partition</strategy>
+      <partitionCount>5</partitionCount>
+      <state>available</state>
+    </item>
+  </placementGroupSet>
+</DescribePlacementGroupsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeletePlacementGroup.html
+var DeletePlacementGroupExample = `
+<DeletePlacementGroupResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>d4904fd9-82c2-4ea5-adfe-a9cc3EXAMPLE</requestId>
+  <return>true</return>
+</DeletePlacementGroupResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplate.html
+var CreateLaunchTemplateExample = `
+<CreateLaunchTemplateResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>39f77e1b-e1ed-4f43-a1ad-d9b4cEXAMPLE</requestId>
+  <launchTemplate>
+    <createTime>2017-10-31T11:38:52.000Z</createTime>
+    <createdBy>arn:aws:iam::123456789012:root</createdBy>
+    <defaultVersionNumber>1</defaultVersionNumber>
+    <latestVersionNumber>1</latestVersionNumber>
+    <launchTemplateId>lt-01238c059e3466abc</launchTemplateId>
+    <launchTemplateName>MyLaunchTemplate</launchTemplateName>
+  </launchTemplate>
+</CreateLaunchTemplateResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateLaunchTemplateVersion.html
+var CreateLaunchTemplateVersionExample = `
+<CreateLaunchTemplateVersionResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>6657423a-2616-461a-9ce5-3c65EXAMPLE</requestId>
+  <launchTemplateVersion>
+    <createTime>2017-10-31T11:56:00.000Z</createTime>
+    <createdBy>arn:aws:iam::123456789012:root</createdBy>
+    <defaultVersion>false</defaultVersion>
+    <launchTemplateData>
+      <imageId>ami-8c1be5f6</imageId>
+      <instanceType>t2.micro</instanceType>
+      <networkInterfaceSet>
+        <item>
+          <deviceIndex>0</deviceIndex>
+          <subnetId>subnet-7b16de0c</subnetId>
+          <groupSet>
+            <item>sg-7c227019</item>
+          </groupSet>
+          <deleteOnTermination>true</deleteOnTermination>
+        </item>
+      </networkInterfaceSet>
+      <userData>aGVsbG8=</userData>
+    </launchTemplateData>
+    <launchTemplateId>lt-0abcd290751193123</launchTemplateId>
+    <launchTemplateName>ExampleLaunchTemplate</launchTemplateName>
+    <versionDescription>Version2</versionDescription>
+    <versionNumber>2</versionNumber>
+  </launchTemplateVersion>
+</CreateLaunchTemplateVersionResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeLaunchTemplates.html
+var DescribeLaunchTemplatesExample = `
+<DescribeLaunchTemplatesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>1afa6e44-eb38-4229-8db6-d5eaEXAMPLE</requestId>
+  <launchTemplates>
+    <item>
+      <createTime>2017-10-31T11:38:52.000Z</createTime>
+      <createdBy>arn:aws:iam::123456789012:root</createdBy>
+      <defaultVersionNumber>1</defaultVersionNumber>
+      <latestVersionNumber>2</latestVersionNumber>
+      <launchTemplateId>lt-01238c059e3466abc</launchTemplateId>
+      <launchTemplateName>my-template</launchTemplateName>
+      <tagSet>
+        <item>
+          <key>purpose</key>
+          <value>production</value>
+        </item>
+      </tagSet>
+    </item>
+  </launchTemplates>
+</DescribeLaunchTemplatesResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeLaunchTemplateVersions.html
+var DescribeLaunchTemplateVersionsExample = `
+<DescribeLaunchTemplateVersionsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>65cadec1-b364-4354-8ca8-4176dEXAMPLE</requestId>
+  <launchTemplateVersionSet>
+    <item>
+      <createTime>2017-10-31T11:38:52.000Z</createTime>
+      <createdBy>arn:aws:iam::123456789012:root</createdBy>
+      <defaultVersion>true</defaultVersion>
+      <launchTemplateData>
+        <imageId>ami-6057e21a</imageId>
+        <instanceType>t2.micro</instanceType>
+        <keyName>user-key-pair</keyName>
+        <placement>
+          <availabilityZone>us-east-1a</availabilityZone>
+          <tenancy>default</tenancy>
+        </placement>
+        <securityGroupIdSet>
+          <item>sg-7c227019</item>
+        </securityGroupIdSet>
+        <blockDeviceMappingSet>
+          <item>
+            <deviceName>/dev/xvda</deviceName>
+            <ebs>
+              <volumeSize>20</volumeSize>
+              <volumeType>gp2</volumeType>
+              <deleteOnTermination>true</deleteOnTermination>
+            </ebs>
+          </item>
+        </blockDeviceMappingSet>
+        <tagSpecificationSet>
+          <item>
+            <resourceType>instance</resourceType>
+            <tagSet>
+              <item>
+                <key>Name</key>
+                <value>webserver</value>
+              </item>
+            </tagSet>
+          </item>
+        </tagSpecificationSet>
+        <disableApiTermination>true</disableApiTermination>
+        <instanceInitiatedShutdownBehavior>terminate</instanceInitiatedShutdownBehavior>
+      </launchTemplateData>
+      <launchTemplateId>lt-068f72b72934aff71</launchTemplateId>
+      <launchTemplateName>Webservers</launchTemplateName>
+      <versionNumber>1</versionNumber>
+    </item>
+  </launchTemplateVersionSet>
+</DescribeLaunchTemplateVersionsResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteLaunchTemplate.html
+var DeleteLaunchTemplateExample = `
+<DeleteLaunchTemplateResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>a8d4bc6d-4f8e-4a14-a5ac-95b6EXAMPLE</requestId>
+  <launchTemplate>
+    <createTime>2017-10-31T11:38:52.000Z</createTime>
+    <createdBy>arn:aws:iam::123456789012:root</createdBy>
+    <defaultVersionNumber>2</defaultVersionNumber>
+    <latestVersionNumber>2</latestVersionNumber>
+    <launchTemplateId>lt-0a20c965061f64abc</launchTemplateId>
+    <launchTemplateName>my-template</launchTemplateName>
+  </launchTemplate>
+</DeleteLaunchTemplateResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateVpcCidrBlock.html
+var AssociateVpcCidrBlockExample = `
+<AssociateVpcCidrBlockResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>33af6c54-1139-4d50-b4f7-15a8example</requestId>
+  <ipv6CidrBlockAssociation>
+    <associationId>vpc-cidr-assoc-e2a5408b</associationId>
+    <ipv6CidrBlock/>
+    <ipv6CidrBlockState>
+      <state>associating</state>
+    </ipv6CidrBlockState>
+  </ipv6CidrBlockAssociation>
+  <vpcId>vpc-a034d6c4</vpcId>
+</AssociateVpcCidrBlockResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateSubnetCidrBlock.html
+var AssociateSubnetCidrBlockExample = `
+<AssociateSubnetCidrBlockResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>c8d3a1b3-6b22-4a1c-a7e8-4bfbexample</requestId>
+  <subnetId>subnet-b61f49f0</subnetId>
+  <ipv6CidrBlockAssociation>
+    <ipv6CidrBlock>2001:db8:1234:1a00::/64</ipv6CidrBlock>
+    <associationId>subnet-cidr-assoc-3aa54053</associationId>
+    <ipv6CidrBlockState>
+      <state>associating</state>
+    </ipv6CidrBlockState>
+  </ipv6CidrBlockAssociation>
+</AssociateSubnetCidrBlockResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssignIpv6Addresses.html
+var AssignIpv6AddressesExample = `
+<AssignIpv6AddressesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>c36d17eb-a0ba-4d38-8727-example</requestId>
+  <networkInterfaceId>eni-38664473</networkInterfaceId>
+  <assignedIpv6Addresses>
+    <item>2001:db8:1234:1a00:3304:8879:34cf:4071</item>
+    <item>2001:db8:1234:1a00:9691:9503:25ad:1761</item>
+  </assignedIpv6Addresses>
+</AssignIpv6AddressesResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_UnassignIpv6Addresses.html
+var UnassignIpv6AddressesExample = `
+<UnassignIpv6AddressesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>94d446d7-fc8e-4918-94f9-example</requestId>
+  <networkInterfaceId>eni-0b42e9fa</networkInterfaceId>
+  <unassignedIpv6Addresses>
+    <item>2001:db8:1234:1a00:3304:8879:34cf:4071</item>
+  </unassignedIpv6Addresses>
+</UnassignIpv6AddressesResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateVpcCidrBlock.html
+var AssociateVpcCidrBlockIPv4Example = `
+<AssociateVpcCidrBlockResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>8e4fb0ea-4fd1-4e46-a3c6-a8e9example</requestId>
+  <cidrBlockAssociation>
+    <associationId>vpc-cidr-assoc-0280ab6b</associationId>
+    <cidrBlock>10.2.0.0/16</cidrBlock>
+    <cidrBlockState>
+      <state>associating</state>
+    </cidrBlockState>
+  </cidrBlockAssociation>
+  <vpcId>vpc-a034d6c4</vpcId>
+</AssociateVpcCidrBlockResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateVpcCidrBlock.html
+var DisassociateVpcCidrBlockExample = `
+<DisassociateVpcCidrBlockResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>1e7a6fc6-0a02-4e5c-bd28-5f68example</requestId>
+  <cidrBlockAssociation>
+    <associationId>vpc-cidr-assoc-0280ab6b</associationId>
+    <cidrBlock>10.2.0.0/16</cidrBlock>
+    <cidrBlockState>
+      <state>disassociating</state>
+    </cidrBlockState>
+  </cidrBlockAssociation>
+  <vpcId>vpc-a034d6c4</vpcId>
+</DisassociateVpcCidrBlockResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateSubnetCidrBlock.html
+var DisassociateSubnetCidrBlockExample = `
+<DisassociateSubnetCidrBlockResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>5e2ad7f4-4a7a-4b2e-8dd9-6b4eexample</requestId>
+  <subnetId>subnet-b61f49f0</subnetId>
+  <ipv6CidrBlockAssociation>
+    <ipv6CidrBlock>2001:db8:1234:1a00::/64</ipv6CidrBlock>
+    <associationId>subnet-cidr-assoc-3aa54053</associationId>
+    <ipv6CidrBlockState>
+      <state>disassociating</state>
+    </ipv6CidrBlockState>
+  </ipv6CidrBlockAssociation>
+</DisassociateSubnetCidrBlockResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcAttribute.html
+var DescribeVpcAttributeExample = `
+<DescribeVpcAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+  <vpcId>vpc-1a2b3c4d</vpcId>
+  <enableDnsHostnames>
+    <value>true</value>
+  </enableDnsHostnames>
+</DescribeVpcAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVpcAttribute.html
+var ModifyVpcAttributeExample = `
+<ModifyVpcAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+  <return>true</return>
+</ModifyVpcAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifySubnetAttribute.html
+var ModifySubnetAttributeExample = `
+<ModifySubnetAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>7a62c49f-347e-4fc4-9331-6e8eEXAMPLE</requestId>
+  <return>true</return>
+</ModifySubnetAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopySnapshot.html
+var CopySnapshotExample = `
+<CopySnapshotResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>60bc441d-fa2c-494d-b155-5d6a3EXAMPLE</requestId>
+  <snapshotId>snap-1234567890abcdef1</snapshotId>
+</CopySnapshotResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSnapshotAttribute.html
+var DescribeSnapshotAttributeExample = `
+<DescribeSnapshotAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <snapshotId>snap-1234567890abcdef0</snapshotId>
+  <createVolumePermission>
+    <item>
+      <group>all</group>
+    </item>
+    <item>
+      <userId>111122223333</userId>
+    </item>
+  </createVolumePermission>
+</DescribeSnapshotAttributeResponse>
+`
+
+// http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifySnapshotAttribute.html
+var ModifySnapshotAttributeExample = `
+<ModifySnapshotAttributeResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>59dbff89-35bd-4eac-99ed-be587EXAMPLE</requestId>
+  <return>true</return>
+</ModifySnapshotAttributeResponse>
+`
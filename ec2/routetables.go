@@ -0,0 +1,262 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// RouteTable describes a route table of a VPC. Each VPC has a main
+// route table, which is used by all subnets not explicitly
+// associated with another route table.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RouteTable.html for more details.
+type RouteTable struct {
+	Id           string                  `xml:"routeTableId"`
+	VPCId        string                  `xml:"vpcId"`
+	Routes       []Route                 `xml:"routeSet>item"`
+	Associations []RouteTableAssociation `xml:"associationSet>item"`
+	Tags         []Tag                   `xml:"tagSet>item"`
+}
+
+// Route describes a route in a route table. Exactly one of the
+// target fields (GatewayId, InstanceId, NetworkInterfaceId,
+// NatGatewayId or VPCPeeringConnectionId) is set when creating a
+// route; EC2 may report more than one, for instance both the
+// instance and its network interface.
+//
+// State is either "active" or "blackhole", when the target of the
+// route is no longer available. Origin tells how the route was
+// created: "CreateRouteTable" for the local route of the VPC, or
+// "CreateRoute". Both are ignored by CreateRoute and ReplaceRoute.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_Route.html for more details.
+type Route struct {
+	DestinationCIDRBlock   string `xml:"destinationCidrBlock"`
+	GatewayId              string `xml:"gatewayId"`
+	InstanceId             string `xml:"instanceId"`
+	InstanceOwnerId        string `xml:"instanceOwnerId"`
+	NetworkInterfaceId     string `xml:"networkInterfaceId"`
+	NatGatewayId           string `xml:"natGatewayId"`
+	VPCPeeringConnectionId string `xml:"vpcPeeringConnectionId"`
+	State                  string `xml:"state"`
+	Origin                 string `xml:"origin"`
+}
+
+// RouteTableAssociation describes the association between a route
+// table and a subnet. Main is true for the implicit association of
+// the main route table of a VPC, which has no subnet.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RouteTableAssociation.html for more details.
+type RouteTableAssociation struct {
+	Id           string `xml:"routeTableAssociationId"`
+	RouteTableId string `xml:"routeTableId"`
+	SubnetId     string `xml:"subnetId"`
+	Main         bool   `xml:"main"`
+}
+
+// CreateRouteTableResp is the response to a CreateRouteTable request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateRouteTable.html for more details.
+type CreateRouteTableResp struct {
+	RequestId  string     `xml:"requestId"`
+	RouteTable RouteTable `xml:"routeTable"`
+}
+
+// CreateRouteTable creates a route table in the VPC with the given
+// id. The new table holds only the local route of the VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateRouteTable.html for more details.
+func (ec2 *EC2) CreateRouteTable(vpcId string) (resp *CreateRouteTableResp, err error) {
+	params := makeParamsCurrent("CreateRouteTable")
+	params["VpcId"] = vpcId
+	resp = &CreateRouteTableResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteRouteTable deletes the route table with the given id. The
+// main route table of a VPC, and tables associated with subnets,
+// cannot be deleted.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteRouteTable.html for more details.
+func (ec2 *EC2) DeleteRouteTable(id string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("DeleteRouteTable")
+	params["RouteTableId"] = id
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RouteTablesResp is the response to a RouteTables request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeRouteTables.html for more details.
+type RouteTablesResp struct {
+	RequestId   string       `xml:"requestId"`
+	RouteTables []RouteTable `xml:"routeTableSet>item"`
+}
+
+// RouteTables describes one or more route tables. Both parameters
+// are optional, and if specified will limit the returned tables to
+// the matching ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeRouteTables.html for more details.
+func (ec2 *EC2) RouteTables(ids []string, filter *Filter) (resp *RouteTablesResp, err error) {
+	params := makeParamsCurrent("DescribeRouteTables")
+	for i, id := range ids {
+		params["RouteTableId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &RouteTablesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AssociateRouteTableResp is the response to an AssociateRouteTable
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateRouteTable.html for more details.
+type AssociateRouteTableResp struct {
+	RequestId     string `xml:"requestId"`
+	AssociationId string `xml:"associationId"`
+}
+
+// AssociateRouteTable associates the route table with the given id
+// with a subnet in the same VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateRouteTable.html for more details.
+func (ec2 *EC2) AssociateRouteTable(id, subnetId string) (resp *AssociateRouteTableResp, err error) {
+	params := makeParamsCurrent("AssociateRouteTable")
+	params["RouteTableId"] = id
+	params["SubnetId"] = subnetId
+	resp = &AssociateRouteTableResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DisassociateRouteTable removes the association with the given id
+// between a route table and a subnet. The subnet then uses the main
+// route table of its VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateRouteTable.html for more details.
+func (ec2 *EC2) DisassociateRouteTable(associationId string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("DisassociateRouteTable")
+	params["AssociationId"] = associationId
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReplaceRouteTableAssociationResp is the response to a
+// ReplaceRouteTableAssociation request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReplaceRouteTableAssociation.html for more details.
+type ReplaceRouteTableAssociationResp struct {
+	RequestId        string `xml:"requestId"`
+	NewAssociationId string `xml:"newAssociationId"`
+}
+
+// ReplaceRouteTableAssociation changes the route table used by the
+// association with the given id. If it is the main association of a
+// VPC, the route table with the given id becomes its main route
+// table.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReplaceRouteTableAssociation.html for more details.
+func (ec2 *EC2) ReplaceRouteTableAssociation(associationId, routeTableId string) (resp *ReplaceRouteTableAssociationResp, err error) {
+	params := makeParamsCurrent("ReplaceRouteTableAssociation")
+	params["AssociationId"] = associationId
+	params["RouteTableId"] = routeTableId
+	resp = &ReplaceRouteTableAssociationResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func addRouteParams(params map[string]string, routeTableId string, route Route) {
+	params["RouteTableId"] = routeTableId
+	params["DestinationCidrBlock"] = route.DestinationCIDRBlock
+	if route.GatewayId != "" {
+		params["GatewayId"] = route.GatewayId
+	}
+	if route.InstanceId != "" {
+		params["InstanceId"] = route.InstanceId
+	}
+	if route.NetworkInterfaceId != "" {
+		params["NetworkInterfaceId"] = route.NetworkInterfaceId
+	}
+	if route.NatGatewayId != "" {
+		params["NatGatewayId"] = route.NatGatewayId
+	}
+	if route.VPCPeeringConnectionId != "" {
+		params["VpcPeeringConnectionId"] = route.VPCPeeringConnectionId
+	}
+}
+
+// CreateRoute adds a route to the route table with the given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateRoute.html for more details.
+func (ec2 *EC2) CreateRoute(routeTableId string, route Route) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("CreateRoute")
+	addRouteParams(params, routeTableId, route)
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReplaceRoute changes the target of the route with the same
+// destination in the route table with the given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ReplaceRoute.html for more details.
+func (ec2 *EC2) ReplaceRoute(routeTableId string, route Route) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("ReplaceRoute")
+	addRouteParams(params, routeTableId, route)
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteRoute deletes the route with the given destination from the
+// route table with the given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteRoute.html for more details.
+func (ec2 *EC2) DeleteRoute(routeTableId, destinationCIDRBlock string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("DeleteRoute")
+	params["RouteTableId"] = routeTableId
+	params["DestinationCidrBlock"] = destinationCIDRBlock
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
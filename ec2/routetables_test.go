@@ -0,0 +1,379 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// Route table tests with example responses
+
+func (s *S) TestCreateRouteTableExample(c *C) {
+	testServer.Response(200, nil, CreateRouteTableExample)
+
+	resp, err := s.ec2.CreateRouteTable("vpc-11ad4878")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateRouteTable"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-11ad4878"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.RouteTable, DeepEquals, ec2.RouteTable{
+		Id:    "rtb-f9ad4890",
+		VPCId: "vpc-11ad4878",
+		Routes: []ec2.Route{{
+			DestinationCIDRBlock: "10.0.0.0/22",
+			GatewayId:            "local",
+			State:                "active",
+			Origin:               "CreateRouteTable",
+		}},
+	})
+}
+
+func (s *S) TestRouteTablesExample(c *C) {
+	testServer.Response(200, nil, DescribeRouteTablesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("vpc-id", "vpc-11ad4878")
+	resp, err := s.ec2.RouteTables([]string{"rtb-13ad487a", "rtb-f9ad4890"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeRouteTables"})
+	c.Assert(req.Form["RouteTableId.1"], DeepEquals, []string{"rtb-13ad487a"})
+	c.Assert(req.Form["RouteTableId.2"], DeepEquals, []string{"rtb-f9ad4890"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"vpc-id"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"vpc-11ad4878"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "6f570b0b-9c18-4b07-bdec-73740dcf861a")
+	c.Assert(resp.RouteTables, HasLen, 2)
+	c.Check(resp.RouteTables[0].Associations, DeepEquals, []ec2.RouteTableAssociation{{
+		Id:           "rtbassoc-12ad487b",
+		RouteTableId: "rtb-13ad487a",
+		Main:         true,
+	}})
+	c.Check(resp.RouteTables[1].Routes[1], DeepEquals, ec2.Route{
+		DestinationCIDRBlock: "0.0.0.0/0",
+		GatewayId:            "igw-eaad4883",
+		State:                "active",
+		Origin:               "CreateRoute",
+	})
+	c.Check(resp.RouteTables[1].Associations, DeepEquals, []ec2.RouteTableAssociation{{
+		Id:           "rtbassoc-faad4893",
+		RouteTableId: "rtb-f9ad4890",
+		SubnetId:     "subnet-15ad487c",
+	}})
+}
+
+func (s *S) TestAssociateRouteTableExample(c *C) {
+	testServer.Response(200, nil, AssociateRouteTableExample)
+
+	resp, err := s.ec2.AssociateRouteTable("rtb-e4ad488d", "subnet-15ad487c")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AssociateRouteTable"})
+	c.Assert(req.Form["RouteTableId"], DeepEquals, []string{"rtb-e4ad488d"})
+	c.Assert(req.Form["SubnetId"], DeepEquals, []string{"subnet-15ad487c"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.AssociationId, Equals, "rtbassoc-f8ad4891")
+}
+
+func (s *S) TestReplaceRouteTableAssociationExample(c *C) {
+	testServer.Response(200, nil, ReplaceRouteTableAssociationExample)
+
+	resp, err := s.ec2.ReplaceRouteTableAssociation("rtbassoc-f8ad4891", "rtb-f9ad4890")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ReplaceRouteTableAssociation"})
+	c.Assert(req.Form["AssociationId"], DeepEquals, []string{"rtbassoc-f8ad4891"})
+	c.Assert(req.Form["RouteTableId"], DeepEquals, []string{"rtb-f9ad4890"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.NewAssociationId, Equals, "rtbassoc-faad2958")
+}
+
+func (s *S) TestCreateRouteExample(c *C) {
+	testServer.Response(200, nil, CreateRouteExample)
+
+	_, err := s.ec2.CreateRoute("rtb-e4ad488d", ec2.Route{
+		DestinationCIDRBlock: "0.0.0.0/0",
+		NatGatewayId:         "nat-1a2b3c4d",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateRoute"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["RouteTableId"], DeepEquals, []string{"rtb-e4ad488d"})
+	c.Assert(req.Form["DestinationCidrBlock"], DeepEquals, []string{"0.0.0.0/0"})
+	c.Assert(req.Form["NatGatewayId"], DeepEquals, []string{"nat-1a2b3c4d"})
+	c.Assert(req.Form["GatewayId"], IsNil)
+	c.Assert(req.Form["InstanceId"], IsNil)
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestReplaceRouteExample(c *C) {
+	testServer.Response(200, nil, CreateRouteExample)
+
+	_, err := s.ec2.ReplaceRoute("rtb-e4ad488d", ec2.Route{
+		DestinationCIDRBlock:   "10.1.0.0/16",
+		VPCPeeringConnectionId: "pcx-111aaa22",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ReplaceRoute"})
+	c.Assert(req.Form["RouteTableId"], DeepEquals, []string{"rtb-e4ad488d"})
+	c.Assert(req.Form["DestinationCidrBlock"], DeepEquals, []string{"10.1.0.0/16"})
+	c.Assert(req.Form["VpcPeeringConnectionId"], DeepEquals, []string{"pcx-111aaa22"})
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestDeleteRouteExample(c *C) {
+	testServer.Response(200, nil, CreateRouteExample)
+
+	_, err := s.ec2.DeleteRoute("rtb-e4ad488d", "0.0.0.0/0")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteRoute"})
+	c.Assert(req.Form["RouteTableId"], DeepEquals, []string{"rtb-e4ad488d"})
+	c.Assert(req.Form["DestinationCidrBlock"], DeepEquals, []string{"0.0.0.0/0"})
+	c.Assert(err, IsNil)
+}
+
+// Route table tests run against either a local test server or live
+// on EC2.
+
+func (s *ServerTests) TestRouteTables(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.7.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	defer s.deleteVPCs(c, []string{vpcId})
+
+	subResp := s.createSubnet(c, vpcId, "10.7.1.0/24", "")
+	subId := subResp.Subnet.Id
+	defer s.deleteSubnets(c, []string{subId})
+
+	igwResp, err := s.ec2.CreateInternetGateway()
+	c.Assert(err, IsNil)
+	igwId := igwResp.InternetGateway.Id
+	defer s.ec2.DeleteInternetGateway(igwId)
+
+	// Every VPC has a main route table.
+	f := ec2.NewFilter()
+	f.Add("vpc-id", vpcId)
+	f.Add("association.main", "true")
+	resp, err := s.ec2.RouteTables(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.RouteTables, HasLen, 1)
+	main := resp.RouteTables[0]
+	c.Assert(main.Associations, HasLen, 1)
+	c.Check(main.Associations[0].Main, Equals, true)
+	c.Check(main.Routes, DeepEquals, []ec2.Route{{
+		DestinationCIDRBlock: "10.7.0.0/16",
+		GatewayId:            "local",
+		State:                "active",
+		Origin:               "CreateRouteTable",
+	}})
+
+	created, err := s.ec2.CreateRouteTable(vpcId)
+	c.Assert(err, IsNil)
+	rtbId := created.RouteTable.Id
+	defer s.ec2.DeleteRouteTable(rtbId)
+	c.Check(created.RouteTable.VPCId, Equals, vpcId)
+
+	// Routes to a gateway need it to be attached to the VPC.
+	defaultRoute := ec2.Route{
+		DestinationCIDRBlock: "0.0.0.0/0",
+		GatewayId:            igwId,
+	}
+	_, err = s.ec2.CreateRoute(rtbId, defaultRoute)
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.AttachInternetGateway(igwId, vpcId)
+	c.Assert(err, IsNil)
+	defer s.ec2.DetachInternetGateway(igwId, vpcId)
+	_, err = s.ec2.CreateRoute(rtbId, defaultRoute)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.CreateRoute(rtbId, defaultRoute)
+	c.Check(errorCode(err), Equals, "RouteAlreadyExists")
+	_, err = s.ec2.DeleteRoute(rtbId, "10.7.0.0/16")
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+
+	assoc, err := s.ec2.AssociateRouteTable(rtbId, subId)
+	c.Assert(err, IsNil)
+	c.Check(assoc.AssociationId, Matches, "rtbassoc-.+")
+
+	_, err = s.ec2.DeleteRouteTable(rtbId)
+	c.Check(errorCode(err), Equals, "DependencyViolation")
+
+	f = ec2.NewFilter()
+	f.Add("association.subnet-id", subId)
+	resp, err = s.ec2.RouteTables(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.RouteTables, HasLen, 1)
+	table := resp.RouteTables[0]
+	c.Check(table.Id, Equals, rtbId)
+	c.Check(table.Associations, DeepEquals, []ec2.RouteTableAssociation{{
+		Id:           assoc.AssociationId,
+		RouteTableId: rtbId,
+		SubnetId:     subId,
+	}})
+	c.Assert(table.Routes, HasLen, 2)
+	c.Check(table.Routes[1], DeepEquals, ec2.Route{
+		DestinationCIDRBlock: "0.0.0.0/0",
+		GatewayId:            igwId,
+		State:                "active",
+		Origin:               "CreateRoute",
+	})
+
+	// The route becomes a blackhole once the gateway is detached.
+	_, err = s.ec2.DetachInternetGateway(igwId, vpcId)
+	c.Assert(err, IsNil)
+	resp, err = s.ec2.RouteTables([]string{rtbId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.RouteTables[0].Routes[1].State, Equals, "blackhole")
+
+	_, err = s.ec2.DisassociateRouteTable(main.Associations[0].Id)
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.DisassociateRouteTable(assoc.AssociationId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.DeleteRouteTable(rtbId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.RouteTables([]string{rtbId}, nil)
+	c.Check(errorCode(err), Equals, "InvalidRouteTableID.NotFound")
+}
+
+func (s *LocalServerSuite) TestMainRouteTableReplacement(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.8.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+
+	f := ec2.NewFilter()
+	f.Add("vpc-id", vpcId)
+	resp, err := s.ec2.RouteTables(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.RouteTables, HasLen, 1)
+	oldMain := resp.RouteTables[0]
+
+	created, err := s.ec2.CreateRouteTable(vpcId)
+	c.Assert(err, IsNil)
+	newMain := created.RouteTable.Id
+
+	replaced, err := s.ec2.ReplaceRouteTableAssociation(oldMain.Associations[0].Id, newMain)
+	c.Assert(err, IsNil)
+
+	f.Add("association.main", "true")
+	resp, err = s.ec2.RouteTables(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.RouteTables, HasLen, 1)
+	c.Check(resp.RouteTables[0].Id, Equals, newMain)
+	c.Check(resp.RouteTables[0].Associations, DeepEquals, []ec2.RouteTableAssociation{{
+		Id:           replaced.NewAssociationId,
+		RouteTableId: newMain,
+		Main:         true,
+	}})
+
+	// The former main table has no dependencies left, but the VPC
+	// cannot be deleted while it exists.
+	_, err = s.ec2.DeleteVPC(vpcId)
+	c.Check(errorCode(err), Equals, "DependencyViolation")
+	_, err = s.ec2.DeleteRouteTable(oldMain.Id)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.DeleteVPC(vpcId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.RouteTables([]string{newMain}, nil)
+	c.Check(errorCode(err), Equals, "InvalidRouteTableID.NotFound")
+}
+
+func (s *LocalServerSuite) TestRouteTargets(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.9.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	subResp := s.createSubnet(c, vpcId, "10.9.1.0/24", "")
+	otherVPC, err := s.ec2.CreateVPC("10.10.0.0/16", "")
+	c.Assert(err, IsNil)
+
+	created, err := s.ec2.CreateRouteTable(vpcId)
+	c.Assert(err, IsNil)
+	rtbId := created.RouteTable.Id
+
+	_, err = s.ec2.CreateRoute(rtbId, ec2.Route{DestinationCIDRBlock: "0.0.0.0/0"})
+	c.Check(errorCode(err), Equals, "MissingParameter")
+	_, err = s.ec2.CreateRoute(rtbId, ec2.Route{
+		DestinationCIDRBlock: "0.0.0.0/0",
+		GatewayId:            "igw-1",
+		NatGatewayId:         "nat-1",
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.CreateRoute(rtbId, ec2.Route{
+		DestinationCIDRBlock: "0.0.0.0/0",
+		GatewayId:            "igw-999",
+	})
+	c.Check(errorCode(err), Equals, "InvalidGatewayID.NotFound")
+
+	list, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		SubnetId:     subResp.Subnet.Id,
+	})
+	c.Assert(err, IsNil)
+	inst := list.Instances[0]
+	_, err = s.ec2.CreateRoute(rtbId, ec2.Route{
+		DestinationCIDRBlock: "192.168.0.0/16",
+		InstanceId:           inst.InstanceId,
+	})
+	c.Assert(err, IsNil)
+
+	// Peering connections must be active.
+	peering, err := s.ec2.CreateVPCPeeringConnection(vpcId, otherVPC.VPC.Id, "")
+	c.Assert(err, IsNil)
+	pcxId := peering.VPCPeeringConnection.Id
+	peerRoute := ec2.Route{
+		DestinationCIDRBlock:   "10.10.0.0/16",
+		VPCPeeringConnectionId: pcxId,
+	}
+	_, err = s.ec2.CreateRoute(rtbId, peerRoute)
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.AcceptVPCPeeringConnection(pcxId)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.CreateRoute(rtbId, peerRoute)
+	c.Assert(err, IsNil)
+
+	resp, err := s.ec2.RouteTables([]string{rtbId}, nil)
+	c.Assert(err, IsNil)
+	routes := resp.RouteTables[0].Routes
+	c.Assert(routes, HasLen, 3)
+	c.Check(routes[1].InstanceId, Equals, inst.InstanceId)
+	c.Check(routes[1].NetworkInterfaceId, Equals, inst.NetworkInterfaces[0].Id)
+	c.Check(routes[1].State, Equals, "active")
+	c.Check(routes[2].State, Equals, "active")
+
+	// Routes to terminated instances and deleted peering connections
+	// become blackholes.
+	_, err = s.ec2.TerminateInstances([]string{inst.InstanceId})
+	c.Assert(err, IsNil)
+	_, err = s.ec2.DeleteVPCPeeringConnection(pcxId)
+	c.Assert(err, IsNil)
+	f := ec2.NewFilter()
+	f.Add("route.state", "blackhole")
+	resp, err = s.ec2.RouteTables([]string{rtbId}, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.RouteTables, HasLen, 1)
+	routes = resp.RouteTables[0].Routes
+	c.Check(routes[1].State, Equals, "blackhole")
+	c.Check(routes[2].State, Equals, "blackhole")
+
+	_, err = s.ec2.ReplaceRoute(rtbId, ec2.Route{
+		DestinationCIDRBlock: "172.16.0.0/12",
+		InstanceId:           inst.InstanceId,
+	})
+	c.Check(errorCode(err), Equals, "InvalidRoute.NotFound")
+}
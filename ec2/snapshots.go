@@ -0,0 +1,141 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// CopySnapshot holds the options of a CopySnapshot request.
+// SourceRegion and SourceSnapshotId are required.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopySnapshot.html for more details.
+type CopySnapshot struct {
+	SourceRegion     string
+	SourceSnapshotId string
+	Description      string
+
+	// Encrypted makes the copy encrypted, with the KMS key
+	// identified by KMSKeyId, or the default EBS key if KMSKeyId
+	// is empty. Copies of encrypted snapshots are always encrypted.
+	Encrypted bool
+	KMSKeyId  string
+}
+
+// CopySnapshotResp is the response to a CopySnapshot request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopySnapshot.html for more details.
+type CopySnapshotResp struct {
+	RequestId  string `xml:"requestId"`
+	SnapshotId string `xml:"snapshotId"`
+}
+
+// CopySnapshot copies a snapshot from the source region to the
+// region of ec2. The copy is "pending" until it is complete; see
+// WaitSnapshotCompleted.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CopySnapshot.html for more details.
+func (ec2 *EC2) CopySnapshot(options *CopySnapshot) (resp *CopySnapshotResp, err error) {
+	params := makeParamsCurrent("CopySnapshot")
+	params["SourceRegion"] = options.SourceRegion
+	params["SourceSnapshotId"] = options.SourceSnapshotId
+	if options.Description != "" {
+		params["Description"] = options.Description
+	}
+	if options.Encrypted {
+		params["Encrypted"] = "true"
+	}
+	if options.KMSKeyId != "" {
+		params["KmsKeyId"] = options.KMSKeyId
+	}
+
+	resp = &CopySnapshotResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateVolumePermission allows an AWS account, identified by UserId,
+// or all accounts, when Group is "all", to create volumes from a
+// snapshot.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVolumePermission.html for more details.
+type CreateVolumePermission struct {
+	UserId string `xml:"userId"`
+	Group  string `xml:"group"`
+}
+
+// SnapshotAttributeResp is the response to a SnapshotAttribute
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSnapshotAttribute.html for more details.
+type SnapshotAttributeResp struct {
+	RequestId               string                   `xml:"requestId"`
+	SnapshotId              string                   `xml:"snapshotId"`
+	CreateVolumePermissions []CreateVolumePermission `xml:"createVolumePermission>item"`
+}
+
+// SnapshotAttribute describes an attribute of the snapshot with the
+// given id. Only the "createVolumePermission" attribute is supported.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSnapshotAttribute.html for more details.
+func (ec2 *EC2) SnapshotAttribute(id, attribute string) (resp *SnapshotAttributeResp, err error) {
+	params := makeParamsCurrent("DescribeSnapshotAttribute")
+	params["SnapshotId"] = id
+	params["Attribute"] = attribute
+
+	resp = &SnapshotAttributeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ModifySnapshotAttribute holds the changes of a
+// ModifySnapshotAttribute request. Adding a create volume permission
+// for the "all" group makes the snapshot public.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifySnapshotAttribute.html for more details.
+type ModifySnapshotAttribute struct {
+	AddCreateVolumePermissions    []CreateVolumePermission
+	RemoveCreateVolumePermissions []CreateVolumePermission
+}
+
+// ModifySnapshotAttribute modifies the attributes of the snapshot
+// with the given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifySnapshotAttribute.html for more details.
+func (ec2 *EC2) ModifySnapshotAttribute(id string, options *ModifySnapshotAttribute) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("ModifySnapshotAttribute")
+	params["SnapshotId"] = id
+	addCreateVolumePermissionParams(params, "CreateVolumePermission.Add.", options.AddCreateVolumePermissions)
+	addCreateVolumePermissionParams(params, "CreateVolumePermission.Remove.", options.RemoveCreateVolumePermissions)
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func addCreateVolumePermissionParams(params map[string]string, prefix string, perms []CreateVolumePermission) {
+	for i, perm := range perms {
+		n := prefix + strconv.Itoa(i+1)
+		if perm.UserId != "" {
+			params[n+".UserId"] = perm.UserId
+		}
+		if perm.Group != "" {
+			params[n+".Group"] = perm.Group
+		}
+	}
+}
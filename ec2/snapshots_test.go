@@ -0,0 +1,297 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/ec2/ec2test"
+)
+
+// Snapshot tests with example responses
+
+func (s *S) TestCopySnapshotExample(c *C) {
+	testServer.Response(200, nil, CopySnapshotExample)
+
+	resp, err := s.ec2.CopySnapshot(&ec2.CopySnapshot{
+		SourceRegion:     "us-west-1",
+		SourceSnapshotId: "snap-1234567890abcdef0",
+		Description:      "My snapshot",
+		Encrypted:        true,
+		KMSKeyId:         "arn:aws:kms:us-east-1:111122223333:key/1234abcd",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CopySnapshot"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["SourceRegion"], DeepEquals, []string{"us-west-1"})
+	c.Assert(req.Form["SourceSnapshotId"], DeepEquals, []string{"snap-1234567890abcdef0"})
+	c.Assert(req.Form["Description"], DeepEquals, []string{"My snapshot"})
+	c.Assert(req.Form["Encrypted"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["KmsKeyId"], DeepEquals, []string{"arn:aws:kms:us-east-1:111122223333:key/1234abcd"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "60bc441d-fa2c-494d-b155-5d6a3EXAMPLE")
+	c.Check(resp.SnapshotId, Equals, "snap-1234567890abcdef1")
+}
+
+func (s *S) TestCopySnapshotExampleDefaults(c *C) {
+	testServer.Response(200, nil, CopySnapshotExample)
+
+	_, err := s.ec2.CopySnapshot(&ec2.CopySnapshot{
+		SourceRegion:     "us-west-1",
+		SourceSnapshotId: "snap-1234567890abcdef0",
+	})
+	req := testServer.WaitRequest()
+	c.Assert(err, IsNil)
+
+	for _, name := range []string{"Description", "Encrypted", "KmsKeyId"} {
+		c.Check(req.Form[name], IsNil, Commentf("%s", name))
+	}
+}
+
+func (s *S) TestSnapshotAttributeExample(c *C) {
+	testServer.Response(200, nil, DescribeSnapshotAttributeExample)
+
+	resp, err := s.ec2.SnapshotAttribute("snap-1234567890abcdef0", "createVolumePermission")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeSnapshotAttribute"})
+	c.Assert(req.Form["SnapshotId"], DeepEquals, []string{"snap-1234567890abcdef0"})
+	c.Assert(req.Form["Attribute"], DeepEquals, []string{"createVolumePermission"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Check(resp.SnapshotId, Equals, "snap-1234567890abcdef0")
+	c.Check(resp.CreateVolumePermissions, DeepEquals, []ec2.CreateVolumePermission{
+		{Group: "all"},
+		{UserId: "111122223333"},
+	})
+}
+
+func (s *S) TestModifySnapshotAttributeExample(c *C) {
+	testServer.Response(200, nil, ModifySnapshotAttributeExample)
+
+	resp, err := s.ec2.ModifySnapshotAttribute("snap-1234567890abcdef0", &ec2.ModifySnapshotAttribute{
+		AddCreateVolumePermissions:    []ec2.CreateVolumePermission{{UserId: "111122223333"}},
+		RemoveCreateVolumePermissions: []ec2.CreateVolumePermission{{Group: "all"}},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ModifySnapshotAttribute"})
+	c.Assert(req.Form["SnapshotId"], DeepEquals, []string{"snap-1234567890abcdef0"})
+	c.Assert(req.Form["CreateVolumePermission.Add.1.UserId"], DeepEquals, []string{"111122223333"})
+	c.Assert(req.Form["CreateVolumePermission.Add.1.Group"], IsNil)
+	c.Assert(req.Form["CreateVolumePermission.Remove.1.Group"], DeepEquals, []string{"all"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+// Snapshot tests run only against the local test server, as
+// snapshots take long to complete on EC2.
+
+func (s *LocalServerSuite) TestSnapshotProgress(c *C) {
+	clock := ec2test.NewClock(time.Now())
+	s.srv.srv.SetTransitions(clock, time.Minute)
+	defer s.srv.srv.SetTransitions(nil, 0)
+
+	vol, err := s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", Size: 4})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVolume(vol.Id)
+	snap, err := s.ec2.CreateSnapshot(vol.Id, "progress")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{snap.Id})
+	c.Check(snap.Status, Equals, "pending")
+	c.Check(snap.Progress, Equals, "0%")
+
+	checkSnapshot := func(status, progress string) {
+		resp, err := s.ec2.Snapshots([]string{snap.Id}, nil)
+		c.Assert(err, IsNil)
+		c.Assert(resp.Snapshots, HasLen, 1)
+		c.Check(resp.Snapshots[0].Status, Equals, status)
+		c.Check(resp.Snapshots[0].Progress, Equals, progress)
+	}
+	clock.Advance(15 * time.Second)
+	checkSnapshot("pending", "25%")
+
+	// Volumes cannot be created from pending snapshots, which
+	// cannot be copied either.
+	_, err = s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", SnapshotId: snap.Id})
+	c.Check(errorCode(err), Equals, "IncorrectState")
+	_, err = s.ec2.CopySnapshot(&ec2.CopySnapshot{SourceRegion: "us-east-1", SourceSnapshotId: snap.Id})
+	c.Check(errorCode(err), Equals, "IncorrectState")
+	err = s.ec2.WaitSnapshotCompleted(snap.Id, shortWait)
+	c.Check(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: snap.Id, Want: "completed", State: "pending"})
+
+	clock.Advance(30 * time.Second)
+	f := ec2.NewFilter()
+	f.Add("status", "pending")
+	f.Add("progress", "75%")
+	resp, err := s.ec2.Snapshots([]string{snap.Id}, f)
+	c.Assert(err, IsNil)
+	c.Check(resp.Snapshots, HasLen, 1)
+
+	clock.Advance(15 * time.Second)
+	c.Assert(s.ec2.WaitSnapshotCompleted(snap.Id, shortWait), IsNil)
+	checkSnapshot("completed", "100%")
+	restored, err := s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", SnapshotId: snap.Id})
+	c.Assert(err, IsNil)
+	s.ec2.DeleteVolume(restored.Id)
+}
+
+func (s *LocalServerSuite) TestCopySnapshot(c *C) {
+	vol, err := s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", Size: 4})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVolume(vol.Id)
+	snap, err := s.ec2.CreateSnapshot(vol.Id, "original")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{snap.Id})
+
+	for i, t := range []struct {
+		options ec2.CopySnapshot
+		code    string
+	}{
+		{ec2.CopySnapshot{SourceSnapshotId: snap.Id}, "MissingParameter"},
+		{ec2.CopySnapshot{SourceRegion: "us-west-1"}, "MissingParameter"},
+		{ec2.CopySnapshot{SourceRegion: "us-west-1", SourceSnapshotId: "snap-999"}, "InvalidSnapshot.NotFound"},
+		{ec2.CopySnapshot{SourceRegion: "us-west-1", SourceSnapshotId: snap.Id, KMSKeyId: "alias/dr"}, "InvalidParameterDependency"},
+	} {
+		_, err := s.ec2.CopySnapshot(&t.options)
+		c.Check(errorCode(err), Equals, t.code, Commentf("test %d", i))
+	}
+
+	plain, err := s.ec2.CopySnapshot(&ec2.CopySnapshot{
+		SourceRegion:     "us-west-1",
+		SourceSnapshotId: snap.Id,
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{plain.SnapshotId})
+	encrypted, err := s.ec2.CopySnapshot(&ec2.CopySnapshot{
+		SourceRegion:     "us-west-1",
+		SourceSnapshotId: plain.SnapshotId,
+		Description:      "for DR",
+		Encrypted:        true,
+		KMSKeyId:         "alias/dr",
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{encrypted.SnapshotId})
+	// Copies of encrypted snapshots stay encrypted with their key.
+	copied, err := s.ec2.CopySnapshot(&ec2.CopySnapshot{
+		SourceRegion:     "us-west-1",
+		SourceSnapshotId: encrypted.SnapshotId,
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{copied.SnapshotId})
+
+	resp, err := s.ec2.Snapshots([]string{plain.SnapshotId, encrypted.SnapshotId, copied.SnapshotId}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Snapshots, HasLen, 3)
+	for i, expect := range []ec2.Snapshot{{
+		Id:          plain.SnapshotId,
+		Description: "[Copied " + snap.Id + " from us-west-1]",
+	}, {
+		Id:          encrypted.SnapshotId,
+		Description: "for DR",
+		Encrypted:   true,
+		KMSKeyId:    "alias/dr",
+	}, {
+		Id:          copied.SnapshotId,
+		Description: "[Copied " + encrypted.SnapshotId + " from us-west-1]",
+		Encrypted:   true,
+		KMSKeyId:    "alias/dr",
+	}} {
+		got := resp.Snapshots[i]
+		c.Check(got.Id, Equals, expect.Id)
+		c.Check(got.VolumeId, Equals, vol.Id)
+		c.Check(got.VolumeSize, Equals, "4")
+		c.Check(got.Status, Equals, "completed")
+		c.Check(got.Description, Equals, expect.Description)
+		c.Check(got.Encrypted, Equals, expect.Encrypted)
+		c.Check(got.KMSKeyId, Equals, expect.KMSKeyId)
+	}
+
+	f := ec2.NewFilter()
+	f.Add("encrypted", "true")
+	f.Add("kms-key-id", "alias/dr")
+	resp, err = s.ec2.Snapshots(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Snapshots, HasLen, 2)
+	c.Check(resp.Snapshots[0].Id, Equals, encrypted.SnapshotId)
+	c.Check(resp.Snapshots[1].Id, Equals, copied.SnapshotId)
+}
+
+func (s *LocalServerSuite) TestSnapshotAttribute(c *C) {
+	vol, err := s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", Size: 4})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVolume(vol.Id)
+	snap, err := s.ec2.CreateSnapshot(vol.Id, "shared")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{snap.Id})
+
+	checkPerms := func(expect []ec2.CreateVolumePermission) {
+		resp, err := s.ec2.SnapshotAttribute(snap.Id, "createVolumePermission")
+		c.Assert(err, IsNil)
+		c.Check(resp.SnapshotId, Equals, snap.Id)
+		c.Check(resp.CreateVolumePermissions, DeepEquals, expect)
+	}
+	checkPerms(nil)
+
+	_, err = s.ec2.ModifySnapshotAttribute(snap.Id, &ec2.ModifySnapshotAttribute{
+		AddCreateVolumePermissions: []ec2.CreateVolumePermission{
+			{UserId: "222233334444"},
+			{UserId: "111122223333"},
+			{Group: "all"},
+		},
+	})
+	c.Assert(err, IsNil)
+	checkPerms([]ec2.CreateVolumePermission{
+		{Group: "all"},
+		{UserId: "111122223333"},
+		{UserId: "222233334444"},
+	})
+
+	_, err = s.ec2.ModifySnapshotAttribute(snap.Id, &ec2.ModifySnapshotAttribute{
+		RemoveCreateVolumePermissions: []ec2.CreateVolumePermission{
+			{UserId: "222233334444"},
+			{Group: "all"},
+		},
+	})
+	c.Assert(err, IsNil)
+	checkPerms([]ec2.CreateVolumePermission{{UserId: "111122223333"}})
+
+	_, err = s.ec2.ModifySnapshotAttribute(snap.Id, &ec2.ModifySnapshotAttribute{})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.ModifySnapshotAttribute(snap.Id, &ec2.ModifySnapshotAttribute{
+		AddCreateVolumePermissions: []ec2.CreateVolumePermission{{Group: "everyone"}},
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.ModifySnapshotAttribute("snap-999", &ec2.ModifySnapshotAttribute{
+		AddCreateVolumePermissions: []ec2.CreateVolumePermission{{Group: "all"}},
+	})
+	c.Check(errorCode(err), Equals, "InvalidSnapshot.NotFound")
+	_, err = s.ec2.SnapshotAttribute(snap.Id, "kernel")
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+
+	// Encrypted snapshots cannot be made public.
+	copied, err := s.ec2.CopySnapshot(&ec2.CopySnapshot{
+		SourceRegion:     "us-east-1",
+		SourceSnapshotId: snap.Id,
+		Encrypted:        true,
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{copied.SnapshotId})
+	_, err = s.ec2.ModifySnapshotAttribute(copied.SnapshotId, &ec2.ModifySnapshotAttribute{
+		AddCreateVolumePermissions: []ec2.CreateVolumePermission{{Group: "all"}},
+	})
+	c.Check(errorCode(err), Equals, "OperationNotPermitted")
+}
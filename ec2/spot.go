@@ -0,0 +1,289 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+	"time"
+)
+
+// RequestSpotInstances holds the options for a RequestSpotInstances
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RequestSpotInstances.html for more details.
+type RequestSpotInstances struct {
+	// SpotPrice holds the maximum hourly price to pay for each
+	// instance, such as "0.05".
+	SpotPrice string
+
+	// InstanceCount holds the number of instances to launch; a
+	// spot request is made for each of them. It defaults to 1.
+	InstanceCount int
+
+	// Type is either "one-time", the default, or "persistent". A
+	// persistent request launches a new instance whenever its
+	// instance is interrupted, until it is cancelled.
+	Type string
+
+	// ValidFrom and ValidUntil, if not zero, limit the period
+	// during which the request can be fulfilled.
+	ValidFrom  time.Time
+	ValidUntil time.Time
+
+	// LaunchGroup and AvailZoneGroup group requests that must be
+	// fulfilled together, or in the same availability zone.
+	LaunchGroup    string
+	AvailZoneGroup string
+
+	// BlockDurationMinutes, if not zero, requests instances that
+	// run uninterrupted for that duration, a multiple of 60.
+	BlockDurationMinutes int
+
+	// LaunchSpecification describes the instances to launch. Its
+	// MinCount, MaxCount, DisableAPITermination, ShutdownBehavior,
+	// PrivateIPAddress, PartitionNumber, HostId, Affinity and
+	// TagSpecifications fields are not used.
+	LaunchSpecification RunInstances
+}
+
+// SpotLaunchSpecification describes the instances launched by a
+// spot request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_LaunchSpecification.html for more details.
+type SpotLaunchSpecification struct {
+	ImageId             string               `xml:"imageId"`
+	InstanceType        string               `xml:"instanceType"`
+	KeyName             string               `xml:"keyName"`
+	KernelId            string               `xml:"kernelId"`
+	RamdiskId           string               `xml:"ramdiskId"`
+	SubnetId            string               `xml:"subnetId"`
+	AvailZone           string               `xml:"placement>availabilityZone"`
+	PlacementGroupName  string               `xml:"placement>groupName"`
+	Tenancy             string               `xml:"placement>tenancy"`
+	Monitoring          bool                 `xml:"monitoring>enabled"`
+	SecurityGroups      []SecurityGroup      `xml:"groupSet>item"`
+	BlockDeviceMappings []BlockDeviceMapping `xml:"blockDeviceMapping>item"`
+}
+
+// SpotInstanceStatus describes the progress of a spot request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_SpotInstanceStatus.html for more details.
+type SpotInstanceStatus struct {
+	// Code holds the status code, such as "fulfilled" or
+	// "price-too-low". See http://docs.aws.amazon.com/AWSEC2/latest/UserGuide/spot-bid-status.html
+	// for the possible codes.
+	Code       string `xml:"code"`
+	Message    string `xml:"message"`
+	UpdateTime string `xml:"updateTime"`
+}
+
+// SpotInstanceRequest describes a spot request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_SpotInstanceRequest.html for more details.
+type SpotInstanceRequest struct {
+	Id                   string                  `xml:"spotInstanceRequestId"`
+	SpotPrice            string                  `xml:"spotPrice"`
+	Type                 string                  `xml:"type"`
+	State                string                  `xml:"state"` // "open", "active", "closed", "cancelled" or "failed".
+	Status               SpotInstanceStatus      `xml:"status"`
+	FaultCode            string                  `xml:"fault>code"`
+	FaultMessage         string                  `xml:"fault>message"`
+	ValidFrom            string                  `xml:"validFrom"`
+	ValidUntil           string                  `xml:"validUntil"`
+	LaunchGroup          string                  `xml:"launchGroup"`
+	AvailZoneGroup       string                  `xml:"availabilityZoneGroup"`
+	BlockDurationMinutes int                     `xml:"blockDurationMinutes"`
+	LaunchSpecification  SpotLaunchSpecification `xml:"launchSpecification"`
+	InstanceId           string                  `xml:"instanceId"`
+	CreateTime           string                  `xml:"createTime"`
+	ProductDescription   string                  `xml:"productDescription"`
+	LaunchedAvailZone    string                  `xml:"launchedAvailabilityZone"`
+	Tags                 []Tag                   `xml:"tagSet>item"`
+}
+
+// RequestSpotInstancesResp is the response to a RequestSpotInstances
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RequestSpotInstances.html for more details.
+type RequestSpotInstancesResp struct {
+	RequestId    string                `xml:"requestId"`
+	SpotRequests []SpotInstanceRequest `xml:"spotInstanceRequestSet>item"`
+}
+
+// RequestSpotInstances requests spot instances, which are launched
+// when spare capacity is available at a price not above the given
+// maximum price. The requests are "open" until they are fulfilled;
+// see SpotInstanceRequests.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RequestSpotInstances.html for more details.
+func (ec2 *EC2) RequestSpotInstances(options *RequestSpotInstances) (resp *RequestSpotInstancesResp, err error) {
+	params := makeParamsCurrent("RequestSpotInstances")
+	params["SpotPrice"] = options.SpotPrice
+	if options.InstanceCount != 0 {
+		params["InstanceCount"] = strconv.Itoa(options.InstanceCount)
+	}
+	if options.Type != "" {
+		params["Type"] = options.Type
+	}
+	if !options.ValidFrom.IsZero() {
+		params["ValidFrom"] = options.ValidFrom.UTC().Format(time.RFC3339)
+	}
+	if !options.ValidUntil.IsZero() {
+		params["ValidUntil"] = options.ValidUntil.UTC().Format(time.RFC3339)
+	}
+	if options.LaunchGroup != "" {
+		params["LaunchGroup"] = options.LaunchGroup
+	}
+	if options.AvailZoneGroup != "" {
+		params["AvailabilityZoneGroup"] = options.AvailZoneGroup
+	}
+	if options.BlockDurationMinutes != 0 {
+		params["BlockDurationMinutes"] = strconv.Itoa(options.BlockDurationMinutes)
+	}
+	addLaunchParams(params, "LaunchSpecification.", &options.LaunchSpecification)
+
+	resp = &RequestSpotInstancesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SpotInstanceRequestsResp is the response to a SpotInstanceRequests
+// request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotInstanceRequests.html for more details.
+type SpotInstanceRequestsResp struct {
+	RequestId    string                `xml:"requestId"`
+	SpotRequests []SpotInstanceRequest `xml:"spotInstanceRequestSet>item"`
+}
+
+// SpotInstanceRequests returns the spot requests with the given ids,
+// or all of them if ids is empty, that match the given filter.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotInstanceRequests.html for more details.
+func (ec2 *EC2) SpotInstanceRequests(ids []string, filter *Filter) (resp *SpotInstanceRequestsResp, err error) {
+	params := makeParamsCurrent("DescribeSpotInstanceRequests")
+	for i, id := range ids {
+		params["SpotInstanceRequestId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &SpotInstanceRequestsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CancelledSpotInstanceRequest describes a cancelled spot request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CancelledSpotInstanceRequest.html for more details.
+type CancelledSpotInstanceRequest struct {
+	Id    string `xml:"spotInstanceRequestId"`
+	State string `xml:"state"`
+}
+
+// CancelSpotInstanceRequestsResp is the response to a
+// CancelSpotInstanceRequests request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CancelSpotInstanceRequests.html for more details.
+type CancelSpotInstanceRequestsResp struct {
+	RequestId    string                         `xml:"requestId"`
+	SpotRequests []CancelledSpotInstanceRequest `xml:"spotInstanceRequestSet>item"`
+}
+
+// CancelSpotInstanceRequests cancels the spot requests with the
+// given ids. The instances launched for them keep running.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CancelSpotInstanceRequests.html for more details.
+func (ec2 *EC2) CancelSpotInstanceRequests(ids []string) (resp *CancelSpotInstanceRequestsResp, err error) {
+	params := makeParamsCurrent("CancelSpotInstanceRequests")
+	for i, id := range ids {
+		params["SpotInstanceRequestId."+strconv.Itoa(i+1)] = id
+	}
+
+	resp = &CancelSpotInstanceRequestsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SpotPriceHistory holds the options for a SpotPriceHistory request.
+// All of them are optional.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotPriceHistory.html for more details.
+type SpotPriceHistory struct {
+	StartTime           time.Time
+	EndTime             time.Time
+	InstanceTypes       []string
+	ProductDescriptions []string // Such as "Linux/UNIX".
+	AvailZone           string
+	Filter              *Filter
+	MaxResults          int
+	NextToken           string
+}
+
+// SpotPrice holds the spot price of an instance type at a point in
+// time.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_SpotPrice.html for more details.
+type SpotPrice struct {
+	InstanceType       string `xml:"instanceType"`
+	ProductDescription string `xml:"productDescription"`
+	SpotPrice          string `xml:"spotPrice"`
+	Timestamp          string `xml:"timestamp"`
+	AvailZone          string `xml:"availabilityZone"`
+}
+
+// SpotPriceHistoryResp is the response to a SpotPriceHistory request.
+// NextToken identifies the following page of results, if any.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotPriceHistory.html for more details.
+type SpotPriceHistoryResp struct {
+	RequestId string      `xml:"requestId"`
+	History   []SpotPrice `xml:"spotPriceHistorySet>item"`
+	NextToken string      `xml:"nextToken"`
+}
+
+// SpotPriceHistory returns the history of spot prices, most recent
+// first.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeSpotPriceHistory.html for more details.
+func (ec2 *EC2) SpotPriceHistory(options *SpotPriceHistory) (resp *SpotPriceHistoryResp, err error) {
+	params := makeParamsCurrent("DescribeSpotPriceHistory")
+	if !options.StartTime.IsZero() {
+		params["StartTime"] = options.StartTime.UTC().Format(time.RFC3339)
+	}
+	if !options.EndTime.IsZero() {
+		params["EndTime"] = options.EndTime.UTC().Format(time.RFC3339)
+	}
+	for i, t := range options.InstanceTypes {
+		params["InstanceType."+strconv.Itoa(i+1)] = t
+	}
+	for i, d := range options.ProductDescriptions {
+		params["ProductDescription."+strconv.Itoa(i+1)] = d
+	}
+	if options.AvailZone != "" {
+		params["AvailabilityZone"] = options.AvailZone
+	}
+	options.Filter.addParams(params)
+	addPageParams(params, options.MaxResults, options.NextToken)
+
+	resp = &SpotPriceHistoryResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,494 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/ec2/ec2test"
+)
+
+// Spot instance tests with example responses
+
+func (s *S) TestRequestSpotInstancesExample(c *C) {
+	testServer.Response(200, nil, RequestSpotInstancesExample)
+
+	resp, err := s.ec2.RequestSpotInstances(&ec2.RequestSpotInstances{
+		SpotPrice:      "0.5",
+		InstanceCount:  2,
+		Type:           "persistent",
+		ValidUntil:     time.Date(2016, 10, 11, 0, 0, 0, 0, time.UTC),
+		AvailZoneGroup: "MyAzGroup",
+		LaunchSpecification: ec2.RunInstances{
+			ImageId:        "ami-1a2b3c4d",
+			KeyName:        "my-key-pair",
+			InstanceType:   "m3.medium",
+			SecurityGroups: []ec2.SecurityGroup{{Id: "sg-1a2b3c4d"}},
+			UserData:       []byte("1234"),
+			BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+				DeviceName: "/dev/sdb",
+				VolumeSize: 10,
+			}},
+		},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RequestSpotInstances"})
+	c.Assert(req.Form["SpotPrice"], DeepEquals, []string{"0.5"})
+	c.Assert(req.Form["InstanceCount"], DeepEquals, []string{"2"})
+	c.Assert(req.Form["Type"], DeepEquals, []string{"persistent"})
+	c.Assert(req.Form["ValidUntil"], DeepEquals, []string{"2016-10-11T00:00:00Z"})
+	c.Assert(req.Form["ValidFrom"], IsNil)
+	c.Assert(req.Form["AvailabilityZoneGroup"], DeepEquals, []string{"MyAzGroup"})
+	c.Assert(req.Form["LaunchSpecification.ImageId"], DeepEquals, []string{"ami-1a2b3c4d"})
+	c.Assert(req.Form["LaunchSpecification.KeyName"], DeepEquals, []string{"my-key-pair"})
+	c.Assert(req.Form["LaunchSpecification.InstanceType"], DeepEquals, []string{"m3.medium"})
+	c.Assert(req.Form["LaunchSpecification.SecurityGroupId.1"], DeepEquals, []string{"sg-1a2b3c4d"})
+	c.Assert(req.Form["LaunchSpecification.UserData"], DeepEquals, []string{"MTIzNA=="})
+	c.Assert(req.Form["LaunchSpecification.BlockDeviceMapping.1.DeviceName"], DeepEquals, []string{"/dev/sdb"})
+	c.Assert(req.Form["LaunchSpecification.BlockDeviceMapping.1.Ebs.VolumeSize"], DeepEquals, []string{"10"})
+	c.Assert(req.Form["ImageId"], IsNil)
+	c.Assert(req.Form["MinCount"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.SpotRequests, HasLen, 1)
+	r := resp.SpotRequests[0]
+	c.Assert(r.Id, Equals, "sir-1a2b3c4d")
+	c.Assert(r.SpotPrice, Equals, "0.5")
+	c.Assert(r.Type, Equals, "one-time")
+	c.Assert(r.State, Equals, "open")
+	c.Assert(r.Status.Code, Equals, "pending-evaluation")
+	c.Assert(r.Status.UpdateTime, Equals, "2016-10-10T23:28:58.000Z")
+	c.Assert(r.AvailZoneGroup, Equals, "MyAzGroup")
+	c.Assert(r.LaunchSpecification.ImageId, Equals, "ami-1a2b3c4d")
+	c.Assert(r.LaunchSpecification.InstanceType, Equals, "m3.medium")
+	c.Assert(r.LaunchSpecification.SecurityGroups, DeepEquals, []ec2.SecurityGroup{{Id: "sg-1a2b3c4d", Name: "websrv"}})
+	c.Assert(r.ProductDescription, Equals, "Linux/UNIX")
+}
+
+func (s *S) TestSpotInstanceRequestsExample(c *C) {
+	testServer.Response(200, nil, DescribeSpotInstanceRequestsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("state", "active")
+	resp, err := s.ec2.SpotInstanceRequests([]string{"sir-1a2b3c4d"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeSpotInstanceRequests"})
+	c.Assert(req.Form["SpotInstanceRequestId.1"], DeepEquals, []string{"sir-1a2b3c4d"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"state"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"active"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "b1719f2a-5334-4479-b2f1-26926EXAMPLE")
+	c.Assert(resp.SpotRequests, HasLen, 1)
+	r := resp.SpotRequests[0]
+	c.Assert(r.Id, Equals, "sir-1a2b3c4d")
+	c.Assert(r.State, Equals, "active")
+	c.Assert(r.Status.Code, Equals, "fulfilled")
+	c.Assert(r.Status.Message, Equals, "Your Spot request is fulfilled.")
+	c.Assert(r.LaunchSpecification.AvailZone, Equals, "us-west-1b")
+	c.Assert(r.InstanceId, Equals, "i-1234567890abcdef0")
+	c.Assert(r.LaunchedAvailZone, Equals, "us-west-1b")
+	c.Assert(r.Tags, DeepEquals, []ec2.Tag{{"my-tag-key", "my-tag-value"}})
+}
+
+func (s *S) TestCancelSpotInstanceRequestsExample(c *C) {
+	testServer.Response(200, nil, CancelSpotInstanceRequestsExample)
+
+	resp, err := s.ec2.CancelSpotInstanceRequests([]string{"sir-1a2b3c4d"})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CancelSpotInstanceRequests"})
+	c.Assert(req.Form["SpotInstanceRequestId.1"], DeepEquals, []string{"sir-1a2b3c4d"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.SpotRequests, DeepEquals, []ec2.CancelledSpotInstanceRequest{{
+		Id:    "sir-1a2b3c4d",
+		State: "cancelled",
+	}})
+}
+
+func (s *S) TestSpotPriceHistoryExample(c *C) {
+	testServer.Response(200, nil, DescribeSpotPriceHistoryExample)
+
+	resp, err := s.ec2.SpotPriceHistory(&ec2.SpotPriceHistory{
+		StartTime:           time.Date(2014, 1, 6, 7, 8, 9, 0, time.UTC),
+		InstanceTypes:       []string{"m1.xlarge"},
+		ProductDescriptions: []string{"Linux/UNIX (Amazon VPC)"},
+		MaxResults:          5,
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeSpotPriceHistory"})
+	c.Assert(req.Form["StartTime"], DeepEquals, []string{"2014-01-06T07:08:09Z"})
+	c.Assert(req.Form["EndTime"], IsNil)
+	c.Assert(req.Form["InstanceType.1"], DeepEquals, []string{"m1.xlarge"})
+	c.Assert(req.Form["ProductDescription.1"], DeepEquals, []string{"Linux/UNIX (Amazon VPC)"})
+	c.Assert(req.Form["MaxResults"], DeepEquals, []string{"5"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.History, DeepEquals, []ec2.SpotPrice{{
+		InstanceType:       "m1.xlarge",
+		ProductDescription: "Linux/UNIX (Amazon VPC)",
+		SpotPrice:          "0.080000",
+		Timestamp:          "2014-01-06T04:32:53.000Z",
+		AvailZone:          "us-west-2a",
+	}, {
+		InstanceType:       "m1.xlarge",
+		ProductDescription: "Linux/UNIX (Amazon VPC)",
+		SpotPrice:          "0.080000",
+		Timestamp:          "2014-01-05T11:28:26.000Z",
+		AvailZone:          "us-west-2c",
+	}})
+	c.Assert(resp.NextToken, Equals, "")
+}
+
+// Spot instance tests run only against the local test server, which
+// controls the spot price and capacity.
+
+// spotRequest returns the spot request with the given id.
+func spotRequest(c *C, e *ec2.EC2, id string) ec2.SpotInstanceRequest {
+	resp, err := e.SpotInstanceRequests([]string{id}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(resp.SpotRequests, HasLen, 1)
+	return resp.SpotRequests[0]
+}
+
+func (s *LocalServerSuite) TestSpotRequestOneTime(c *C) {
+	const instType = "m3.medium"
+	defer s.srv.srv.SetSpotPrice(instType, "0.01")
+
+	resp, err := s.ec2.RequestSpotInstances(&ec2.RequestSpotInstances{
+		SpotPrice:     "0.05",
+		InstanceCount: 2,
+		LaunchSpecification: ec2.RunInstances{
+			ImageId:      imageId,
+			InstanceType: instType,
+			AvailZone:    "us-east-1a",
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(resp.SpotRequests, HasLen, 2)
+	var ids []string
+	for _, r := range resp.SpotRequests {
+		c.Check(r.State, Equals, "open")
+		c.Check(r.Status.Code, Equals, "pending-evaluation")
+		c.Check(r.Type, Equals, "one-time")
+		c.Check(r.LaunchSpecification.ImageId, Equals, imageId)
+		ids = append(ids, r.Id)
+	}
+
+	// The requests are fulfilled, as the price is below their
+	// maximum price.
+	var instIds []string
+	for _, id := range ids {
+		r := spotRequest(c, s.ec2, id)
+		c.Check(r.State, Equals, "active")
+		c.Check(r.Status.Code, Equals, "fulfilled")
+		c.Check(r.LaunchedAvailZone, Equals, "us-east-1a")
+		c.Assert(r.InstanceId, Not(Equals), "")
+		instIds = append(instIds, r.InstanceId)
+	}
+	defer terminateInstances(c, s.ec2, instIds)
+
+	filter := ec2.NewFilter()
+	filter.Add("instance-lifecycle", "spot")
+	insts, err := s.ec2.Instances(instIds, filter)
+	c.Assert(err, IsNil)
+	c.Assert(insts.Reservations, HasLen, 2)
+	for _, r := range insts.Reservations {
+		inst := r.Instances[0]
+		c.Check(inst.InstanceLifecycle, Equals, "spot")
+		c.Check(inst.InstanceType, Equals, instType)
+		c.Check(inst.SpotInstanceRequestId == ids[0] || inst.SpotInstanceRequestId == ids[1], Equals, true)
+	}
+
+	// Terminating an instance closes its request.
+	_, err = s.ec2.TerminateInstances(instIds[:1])
+	c.Assert(err, IsNil)
+	r := spotRequest(c, s.ec2, ids[0])
+	c.Check(r.State, Equals, "closed")
+	c.Check(r.Status.Code, Equals, "instance-terminated-by-user")
+
+	// Raising the price above the maximum price interrupts the
+	// other instance.
+	s.srv.srv.SetSpotPrice(instType, "0.06")
+	r = spotRequest(c, s.ec2, ids[1])
+	c.Check(r.State, Equals, "closed")
+	c.Check(r.Status.Code, Equals, "instance-terminated-by-price")
+	insts, err = s.ec2.Instances(instIds[1:], nil)
+	c.Assert(err, IsNil)
+	c.Assert(insts.Reservations, HasLen, 1)
+	c.Check(insts.Reservations[0].Instances[0].State.Name, Matches, "shutting-down|terminated")
+
+	// Closed requests cannot be cancelled.
+	cancelled, err := s.ec2.CancelSpotInstanceRequests(ids)
+	c.Assert(err, IsNil)
+	c.Assert(cancelled.SpotRequests, HasLen, 2)
+	c.Check(cancelled.SpotRequests[0].State, Equals, "closed")
+	c.Check(cancelled.SpotRequests[1].State, Equals, "closed")
+}
+
+func (s *LocalServerSuite) TestSpotRequestPersistent(c *C) {
+	const instType = "c4.large"
+	s.srv.srv.SetSpotCapacity(false)
+	defer s.srv.srv.SetSpotCapacity(true)
+
+	resp, err := s.ec2.RequestSpotInstances(&ec2.RequestSpotInstances{
+		SpotPrice: "0.05",
+		Type:      "persistent",
+		LaunchSpecification: ec2.RunInstances{
+			ImageId:      imageId,
+			InstanceType: instType,
+		},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(resp.SpotRequests, HasLen, 1)
+	id := resp.SpotRequests[0].Id
+
+	// The request is held until capacity is available.
+	r := spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "open")
+	c.Check(r.Status.Code, Equals, "capacity-not-available")
+	c.Check(r.InstanceId, Equals, "")
+
+	s.srv.srv.SetSpotCapacity(true)
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "active")
+	c.Check(r.Status.Code, Equals, "fulfilled")
+	instId := r.InstanceId
+	c.Assert(instId, Not(Equals), "")
+
+	// An interrupted instance is replaced when capacity is
+	// available again.
+	s.srv.srv.SetSpotCapacity(false)
+	s.srv.srv.InterruptSpotInstance(instId)
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "open")
+	c.Check(r.Status.Code, Equals, "capacity-not-available")
+
+	s.srv.srv.SetSpotCapacity(true)
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "active")
+	c.Assert(r.InstanceId, Not(Equals), instId)
+	newInstId := r.InstanceId
+	defer terminateInstances(c, s.ec2, []string{instId, newInstId})
+
+	filter := ec2.NewFilter()
+	filter.Add("status-code", "fulfilled")
+	filter.Add("type", "persistent")
+	requests, err := s.ec2.SpotInstanceRequests(nil, filter)
+	c.Assert(err, IsNil)
+	c.Assert(requests.SpotRequests, HasLen, 1)
+	c.Check(requests.SpotRequests[0].Id, Equals, id)
+
+	// Cancelling the request leaves its instance running, and
+	// terminating the instance then does not replace it.
+	cancelled, err := s.ec2.CancelSpotInstanceRequests([]string{id})
+	c.Assert(err, IsNil)
+	c.Check(cancelled.SpotRequests, DeepEquals, []ec2.CancelledSpotInstanceRequest{{Id: id, State: "cancelled"}})
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "cancelled")
+	c.Check(r.Status.Code, Equals, "request-canceled-and-instance-running")
+	c.Check(r.InstanceId, Equals, newInstId)
+
+	terminateInstances(c, s.ec2, []string{newInstId})
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "cancelled")
+	c.Check(r.InstanceId, Equals, newInstId)
+}
+
+func (s *LocalServerSuite) TestSpotRequestHeld(c *C) {
+	const instType = "r4.large"
+	s.srv.srv.SetSpotPrice(instType, "0.10")
+	defer s.srv.srv.SetSpotPrice(instType, "0.01")
+
+	resp, err := s.ec2.RequestSpotInstances(&ec2.RequestSpotInstances{
+		SpotPrice:            "0.05",
+		BlockDurationMinutes: 120,
+		LaunchSpecification: ec2.RunInstances{
+			ImageId:      imageId,
+			InstanceType: instType,
+		},
+	})
+	c.Assert(err, IsNil)
+	id := resp.SpotRequests[0].Id
+	r := spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "open")
+	c.Check(r.Status.Code, Equals, "price-too-low")
+
+	// Requests with a block duration are not interrupted when the
+	// price rises again.
+	s.srv.srv.SetSpotPrice(instType, "0.05")
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "active")
+	c.Check(r.BlockDurationMinutes, Equals, 120)
+	defer terminateInstances(c, s.ec2, []string{r.InstanceId})
+	s.srv.srv.SetSpotPrice(instType, "0.10")
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "active")
+
+	// Requests that were not fulfilled before they expire are
+	// closed.
+	resp, err = s.ec2.RequestSpotInstances(&ec2.RequestSpotInstances{
+		SpotPrice:  "0.05",
+		ValidUntil: time.Now().Add(-time.Minute),
+		LaunchSpecification: ec2.RunInstances{
+			ImageId:      imageId,
+			InstanceType: instType,
+		},
+	})
+	c.Assert(err, IsNil)
+	r = spotRequest(c, s.ec2, resp.SpotRequests[0].Id)
+	c.Check(r.State, Equals, "closed")
+	c.Check(r.Status.Code, Equals, "schedule-expired")
+
+	// Pending requests can be cancelled.
+	resp, err = s.ec2.RequestSpotInstances(&ec2.RequestSpotInstances{
+		SpotPrice: "0.05",
+		ValidFrom: time.Now().Add(time.Hour),
+		LaunchSpecification: ec2.RunInstances{
+			ImageId:      imageId,
+			InstanceType: instType,
+		},
+	})
+	c.Assert(err, IsNil)
+	id = resp.SpotRequests[0].Id
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.Status.Code, Equals, "not-scheduled-yet")
+	_, err = s.ec2.CancelSpotInstanceRequests([]string{id})
+	c.Assert(err, IsNil)
+	r = spotRequest(c, s.ec2, id)
+	c.Check(r.State, Equals, "cancelled")
+	c.Check(r.Status.Code, Equals, "canceled-before-fulfillment")
+}
+
+func (s *LocalServerSuite) TestSpotRequestErrors(c *C) {
+	s.srv.srv.SetStrictImages(true)
+	defer s.srv.srv.SetStrictImages(false)
+	spec := ec2.RunInstances{ImageId: imageId, InstanceType: "m3.medium"}
+	for i, test := range []struct {
+		options ec2.RequestSpotInstances
+		code    string
+	}{{
+		options: ec2.RequestSpotInstances{LaunchSpecification: spec},
+		code:    "MissingParameter",
+	}, {
+		options: ec2.RequestSpotInstances{SpotPrice: "cheap", LaunchSpecification: spec},
+		code:    "InvalidParameterValue",
+	}, {
+		options: ec2.RequestSpotInstances{SpotPrice: "0.05", Type: "sometimes", LaunchSpecification: spec},
+		code:    "InvalidParameterValue",
+	}, {
+		options: ec2.RequestSpotInstances{SpotPrice: "0.05", BlockDurationMinutes: 90, LaunchSpecification: spec},
+		code:    "InvalidParameterValue",
+	}, {
+		options: ec2.RequestSpotInstances{SpotPrice: "0.05", Type: "persistent", BlockDurationMinutes: 60, LaunchSpecification: spec},
+		code:    "InvalidParameterCombination",
+	}, {
+		options: ec2.RequestSpotInstances{SpotPrice: "0.05"},
+		code:    "MissingParameter",
+	}, {
+		options: ec2.RequestSpotInstances{
+			SpotPrice:           "0.05",
+			LaunchSpecification: ec2.RunInstances{ImageId: "ami-01234567"},
+		},
+		code: "InvalidAMIID.NotFound",
+	}, {
+		options: ec2.RequestSpotInstances{
+			SpotPrice:           "0.05",
+			LaunchSpecification: ec2.RunInstances{ImageId: imageId, KeyName: "no-such-key"},
+		},
+		code: "InvalidKeyPair.NotFound",
+	}} {
+		c.Logf("test %d: %s", i, test.code)
+		_, err := s.ec2.RequestSpotInstances(&test.options)
+		c.Check(errorCode(err), Equals, test.code)
+	}
+
+	_, err := s.ec2.SpotInstanceRequests([]string{"sir-0"}, nil)
+	c.Check(errorCode(err), Equals, "InvalidSpotInstanceRequestID.NotFound")
+	_, err = s.ec2.CancelSpotInstanceRequests([]string{"sir-0"})
+	c.Check(errorCode(err), Equals, "InvalidSpotInstanceRequestID.NotFound")
+	_, err = s.ec2.CancelSpotInstanceRequests(nil)
+	c.Check(errorCode(err), Equals, "MissingParameter")
+}
+
+func (s *LocalServerSuite) TestSpotPriceHistory(c *C) {
+	start := time.Date(2016, 10, 10, 0, 0, 0, 0, time.UTC)
+	clock := ec2test.NewClock(start)
+	s.srv.srv.SetTransitions(clock, 0)
+	defer s.srv.srv.SetTransitions(nil, 0)
+
+	for _, price := range []string{"0.02", "0.03", "0.04"} {
+		s.srv.srv.SetSpotPrice("x1.32xlarge", price)
+		s.srv.srv.SetSpotPrice("x1.16xlarge", price)
+		clock.Advance(time.Hour)
+	}
+	s.srv.srv.SetSpotPrice("x1.32xlarge", "0.01")
+	s.srv.srv.SetSpotPrice("x1.16xlarge", "0.01")
+
+	resp, err := s.ec2.SpotPriceHistory(&ec2.SpotPriceHistory{
+		InstanceTypes: []string{"x1.32xlarge"},
+		AvailZone:     "us-east-1a",
+		StartTime:     start.Add(time.Hour),
+		EndTime:       start.Add(2 * time.Hour),
+	})
+	c.Assert(err, IsNil)
+	c.Assert(resp.History, DeepEquals, []ec2.SpotPrice{{
+		InstanceType:       "x1.32xlarge",
+		ProductDescription: "Linux/UNIX",
+		SpotPrice:          "0.04",
+		Timestamp:          "2016-10-10T02:00:00Z",
+		AvailZone:          "us-east-1a",
+	}, {
+		InstanceType:       "x1.32xlarge",
+		ProductDescription: "Linux/UNIX",
+		SpotPrice:          "0.03",
+		Timestamp:          "2016-10-10T01:00:00Z",
+		AvailZone:          "us-east-1a",
+	}})
+
+	filter := ec2.NewFilter()
+	filter.Add("spot-price", "0.02")
+	resp, err = s.ec2.SpotPriceHistory(&ec2.SpotPriceHistory{
+		ProductDescriptions: []string{"Linux/UNIX"},
+		AvailZone:           "us-east-1a",
+		Filter:              filter,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(resp.History, HasLen, 2)
+	c.Check(resp.History[0].InstanceType, Equals, "x1.32xlarge")
+	c.Check(resp.History[1].InstanceType, Equals, "x1.16xlarge")
+
+	// The history is paginated, most recent prices first.
+	var prices []string
+	options := &ec2.SpotPriceHistory{
+		InstanceTypes: []string{"x1.32xlarge", "x1.16xlarge"},
+		AvailZone:     "us-east-1a",
+		MaxResults:    5,
+	}
+	for {
+		resp, err := s.ec2.SpotPriceHistory(options)
+		c.Assert(err, IsNil)
+		for _, p := range resp.History {
+			prices = append(prices, p.SpotPrice)
+		}
+		if resp.NextToken == "" {
+			break
+		}
+		options.NextToken = resp.NextToken
+	}
+	c.Assert(prices, DeepEquals, []string{"0.01", "0.01", "0.04", "0.04", "0.03", "0.03", "0.02", "0.02"})
+}
@@ -25,6 +25,14 @@ type Subnet struct {
 	DefaultForAZ        bool   `xml:"defaultForAz"`
 	MapPublicIPOnLaunch bool   `xml:"mapPublicIpOnLaunch"`
 	Tags                []Tag  `xml:"tagSet>item"`
+
+	// IPv6CIDRBlocks holds the IPv6 CIDR block associated with the
+	// subnet, if any.
+	IPv6CIDRBlocks []IPv6CIDRBlockAssociation `xml:"ipv6CidrBlockAssociationSet>item"`
+
+	// AssignIPv6AddressOnCreation reports whether network
+	// interfaces created in the subnet get an IPv6 address.
+	AssignIPv6AddressOnCreation bool `xml:"assignIpv6AddressOnCreation"`
 }
 
 // CreateSubnetResp is the response to a CreateSubnet request.
@@ -86,6 +94,7 @@ func (ec2 *EC2) DeleteSubnet(id string) (resp *SimpleResp, err error) {
 type SubnetsResp struct {
 	RequestId string   `xml:"requestId"`
 	Subnets   []Subnet `xml:"subnetSet>item"`
+	NextToken string   `xml:"nextToken"`
 }
 
 // Subnets returns one or more subnets. Both parameters are optional,
@@ -94,11 +103,18 @@ type SubnetsResp struct {
 //
 // See http://goo.gl/NTKQVI for more details.
 func (ec2 *EC2) Subnets(ids []string, filter *Filter) (resp *SubnetsResp, err error) {
+	return ec2.SubnetsPage(ids, filter, 0, "")
+}
+
+// SubnetsPage is like Subnets, but it returns a single page of
+// results. See InstancesPage for how pages are selected.
+func (ec2 *EC2) SubnetsPage(ids []string, filter *Filter, maxResults int, nextToken string) (resp *SubnetsResp, err error) {
 	params := makeParamsVPC("DescribeSubnets")
 	for i, id := range ids {
 		params["SubnetId."+strconv.Itoa(i+1)] = id
 	}
 	filter.addParams(params)
+	addPageParams(params, maxResults, nextToken)
 
 	resp = &SubnetsResp{}
 	err = ec2.query(params, resp)
@@ -107,3 +123,93 @@ func (ec2 *EC2) Subnets(ids []string, filter *Filter) (resp *SubnetsResp, err er
 	}
 	return resp, nil
 }
+
+// AssociateSubnetCIDRBlockResp is the response to an
+// AssociateSubnetCIDRBlock request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateSubnetCidrBlock.html for more details.
+type AssociateSubnetCIDRBlockResp struct {
+	RequestId                string                   `xml:"requestId"`
+	SubnetId                 string                   `xml:"subnetId"`
+	IPv6CIDRBlockAssociation IPv6CIDRBlockAssociation `xml:"ipv6CidrBlockAssociation"`
+}
+
+// AssociateSubnetCIDRBlock associates the IPv6 CIDR block
+// ipv6CIDRBlock with the subnet subnetId. The block must have a /64
+// prefix length and lie within an IPv6 CIDR block of the subnet's
+// VPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateSubnetCidrBlock.html for more details.
+func (ec2 *EC2) AssociateSubnetCIDRBlock(subnetId, ipv6CIDRBlock string) (resp *AssociateSubnetCIDRBlockResp, err error) {
+	params := makeParamsCurrent("AssociateSubnetCidrBlock")
+	params["SubnetId"] = subnetId
+	params["Ipv6CidrBlock"] = ipv6CIDRBlock
+	resp = &AssociateSubnetCIDRBlockResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DisassociateSubnetCIDRBlockResp is the response to a
+// DisassociateSubnetCIDRBlock request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateSubnetCidrBlock.html for more details.
+type DisassociateSubnetCIDRBlockResp struct {
+	RequestId                string                   `xml:"requestId"`
+	SubnetId                 string                   `xml:"subnetId"`
+	IPv6CIDRBlockAssociation IPv6CIDRBlockAssociation `xml:"ipv6CidrBlockAssociation"`
+}
+
+// DisassociateSubnetCIDRBlock disassociates an IPv6 CIDR block from a
+// subnet, given the id of its association. No IPv6 addresses of the
+// block may be in use.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateSubnetCidrBlock.html for more details.
+func (ec2 *EC2) DisassociateSubnetCIDRBlock(associationId string) (resp *DisassociateSubnetCIDRBlockResp, err error) {
+	params := makeParamsCurrent("DisassociateSubnetCidrBlock")
+	params["AssociationId"] = associationId
+	resp = &DisassociateSubnetCIDRBlockResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ModifySubnetAttribute holds the changes of a ModifySubnetAttribute
+// request. Fields left nil are not changed. EC2 only changes one
+// attribute per request.
+//
+// MapPublicIPOnLaunch makes instances launched in the subnet get a
+// public IP address, and AssignIPv6AddressOnCreation makes network
+// interfaces created in the subnet get an IPv6 address, which needs
+// an IPv6 CIDR block.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifySubnetAttribute.html for more details.
+type ModifySubnetAttribute struct {
+	MapPublicIPOnLaunch         *bool
+	AssignIPv6AddressOnCreation *bool
+}
+
+// ModifySubnetAttribute modifies an attribute of the subnet with the
+// given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifySubnetAttribute.html for more details.
+func (ec2 *EC2) ModifySubnetAttribute(subnetId string, options *ModifySubnetAttribute) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("ModifySubnetAttribute")
+	params["SubnetId"] = subnetId
+	if options.MapPublicIPOnLaunch != nil {
+		params["MapPublicIpOnLaunch.Value"] = strconv.FormatBool(*options.MapPublicIPOnLaunch)
+	}
+	if options.AssignIPv6AddressOnCreation != nil {
+		params["AssignIpv6AddressOnCreation.Value"] = strconv.FormatBool(*options.AssignIPv6AddressOnCreation)
+	}
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
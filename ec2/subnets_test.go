@@ -91,6 +91,43 @@ func (s *S) TestSubnetsExample(c *C) {
 	c.Check(subnet.Tags, HasLen, 0)
 }
 
+func (s *S) TestDisassociateSubnetCIDRBlockExample(c *C) {
+	testServer.Response(200, nil, DisassociateSubnetCidrBlockExample)
+
+	resp, err := s.ec2.DisassociateSubnetCIDRBlock("subnet-cidr-assoc-3aa54053")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DisassociateSubnetCidrBlock"})
+	c.Assert(req.Form["AssociationId"], DeepEquals, []string{"subnet-cidr-assoc-3aa54053"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "5e2ad7f4-4a7a-4b2e-8dd9-6b4eexample")
+	c.Check(resp.SubnetId, Equals, "subnet-b61f49f0")
+	c.Check(resp.IPv6CIDRBlockAssociation, DeepEquals, ec2.IPv6CIDRBlockAssociation{
+		AssociationId: "subnet-cidr-assoc-3aa54053",
+		IPv6CIDRBlock: "2001:db8:1234:1a00::/64",
+		State:         "disassociating",
+	})
+}
+
+func (s *S) TestModifySubnetAttributeExample(c *C) {
+	testServer.Response(200, nil, ModifySubnetAttributeExample)
+
+	enable := true
+	resp, err := s.ec2.ModifySubnetAttribute("subnet-1a2b3c4d", &ec2.ModifySubnetAttribute{
+		MapPublicIPOnLaunch: &enable,
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ModifySubnetAttribute"})
+	c.Assert(req.Form["SubnetId"], DeepEquals, []string{"subnet-1a2b3c4d"})
+	c.Assert(req.Form["MapPublicIpOnLaunch.Value"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["AssignIpv6AddressOnCreation.Value"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+}
+
 // Subnet tests run against either a local test server or live on EC2.
 
 func (s *ServerTests) TestSubnets(c *C) {
@@ -247,3 +284,88 @@ func assertSubnet(c *C, obtained ec2.Subnet, expectId, expectVpcId, expectCidr s
 	c.Check(obtained.DefaultForAZ, Equals, false)
 	c.Check(obtained.MapPublicIPOnLaunch, Equals, false)
 }
+
+// Subnet tests run only against the local test server.
+
+func (s *LocalServerSuite) TestSubnetAttributes(c *C) {
+	vpc, err := s.ec2.CreateVPC("10.24.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpc.VPC.Id
+	defer s.ec2.DeleteVPC(vpcId)
+	sub, err := s.ec2.CreateSubnet(vpcId, "10.24.1.0/24", "")
+	c.Assert(err, IsNil)
+	subId := sub.Subnet.Id
+	defer s.ec2.DeleteSubnet(subId)
+
+	yes := true
+	_, err = s.ec2.ModifySubnetAttribute(subId, &ec2.ModifySubnetAttribute{
+		MapPublicIPOnLaunch:         &yes,
+		AssignIPv6AddressOnCreation: &yes,
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+
+	// Instances only get a public IP address when the subnet maps
+	// one on launch.
+	var ids []string
+	defer func() {
+		terminateInstances(c, s.ec2, ids)
+	}()
+	run := func() ec2.Instance {
+		resp, err := s.ec2.RunInstances(&ec2.RunInstances{
+			ImageId:      imageId,
+			InstanceType: "m1.subnetattr",
+			SubnetId:     subId,
+		})
+		c.Assert(err, IsNil)
+		ids = append(ids, resp.Instances[0].InstanceId)
+		return resp.Instances[0]
+	}
+	c.Check(run().IPAddress, Equals, "")
+	_, err = s.ec2.ModifySubnetAttribute(subId, &ec2.ModifySubnetAttribute{MapPublicIPOnLaunch: &yes})
+	c.Assert(err, IsNil)
+	c.Check(run().IPAddress, Not(Equals), "")
+	list, err := s.ec2.Subnets([]string{subId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(list.Subnets[0].MapPublicIPOnLaunch, Equals, true)
+	insts, err := s.ec2.Instances([]string{ids[0]}, nil)
+	c.Assert(err, IsNil)
+	c.Check(insts.Reservations[0].Instances[0].IPAddress, Equals, "")
+
+	// Assigning IPv6 addresses on creation needs an IPv6 CIDR block.
+	_, err = s.ec2.ModifySubnetAttribute(subId, &ec2.ModifySubnetAttribute{AssignIPv6AddressOnCreation: &yes})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	ipv6Resp, err := s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{
+		VPCId:                       vpcId,
+		AmazonProvidedIPv6CIDRBlock: true,
+	})
+	c.Assert(err, IsNil)
+	subResp, err := s.ec2.AssociateSubnetCIDRBlock(subId, subnetIPv6Block(c, ipv6Resp.IPv6CIDRBlockAssociation.IPv6CIDRBlock, 0))
+	c.Assert(err, IsNil)
+	_, err = s.ec2.ModifySubnetAttribute(subId, &ec2.ModifySubnetAttribute{AssignIPv6AddressOnCreation: &yes})
+	c.Assert(err, IsNil)
+
+	nic, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{SubnetId: subId})
+	c.Assert(err, IsNil)
+	c.Check(nic.NetworkInterface.IPv6Addresses, HasLen, 1)
+
+	// The IPv6 CIDR block cannot be disassociated while its
+	// addresses are in use.
+	assocId := subResp.IPv6CIDRBlockAssociation.AssociationId
+	_, err = s.ec2.DisassociateSubnetCIDRBlock(assocId)
+	c.Check(errorCode(err), Equals, "DependencyViolation")
+	_, err = s.ec2.DeleteNetworkInterface(nic.NetworkInterface.Id)
+	c.Assert(err, IsNil)
+	disResp, err := s.ec2.DisassociateSubnetCIDRBlock(assocId)
+	c.Assert(err, IsNil)
+	c.Check(disResp.SubnetId, Equals, subId)
+	c.Check(disResp.IPv6CIDRBlockAssociation.State, Equals, "disassociating")
+	_, err = s.ec2.DisassociateSubnetCIDRBlock(assocId)
+	c.Check(errorCode(err), Equals, "InvalidSubnetCidrBlockAssociationID.NotFound")
+
+	list, err = s.ec2.Subnets([]string{subId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(list.Subnets[0].IPv6CIDRBlocks, HasLen, 0)
+	c.Check(list.Subnets[0].AssignIPv6AddressOnCreation, Equals, false)
+	_, err = s.ec2.DisassociateVPCCIDRBlock(ipv6Resp.IPv6CIDRBlockAssociation.AssociationId)
+	c.Assert(err, IsNil)
+}
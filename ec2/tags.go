@@ -0,0 +1,137 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// Tag represents key-value metadata used to classify and organize
+// EC2 resources.
+//
+// See http://goo.gl/bncl3 for more details
+type Tag struct {
+	Key   string `xml:"key"`
+	Value string `xml:"value"`
+}
+
+// ResourceTag describes a tag of an EC2 resource, as reported by
+// DescribeTags. ResourceType is one of "instance", "security-group",
+// "vpc", "subnet", "network-interface", "volume", "snapshot" and so
+// on.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_TagDescription.html
+// for more details.
+type ResourceTag struct {
+	Tag
+	ResourceId   string `xml:"resourceId"`
+	ResourceType string `xml:"resourceType"`
+}
+
+// TagSpecification holds the tags to apply to the resources of
+// the given type when they are created.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_TagSpecification.html
+// for more details.
+type TagSpecification struct {
+	ResourceType string `xml:"resourceType"`
+	Tags         []Tag  `xml:"tagSet>item"`
+}
+
+// addTagParams adds the given tags to params under the given prefix.
+func addTagParams(params map[string]string, prefix string, tags []Tag) {
+	for j, tag := range tags {
+		params[prefix+strconv.Itoa(j+1)+".Key"] = tag.Key
+		params[prefix+strconv.Itoa(j+1)+".Value"] = tag.Value
+	}
+}
+
+// addTagSpecParams adds the given tag specifications to params.
+func addTagSpecParams(params map[string]string, specs []TagSpecification) {
+	for i, spec := range specs {
+		prefix := "TagSpecification." + strconv.Itoa(i+1)
+		params[prefix+".ResourceType"] = spec.ResourceType
+		addTagParams(params, prefix+".Tag.", spec.Tags)
+	}
+}
+
+// CreateTags adds or overwrites one or more tags for the specified
+// resources, which may be of any type that supports tagging.
+//
+// See http://goo.gl/Vmkqc for more details
+func (ec2 *EC2) CreateTags(resourceIds []string, tags []Tag) (resp *SimpleResp, err error) {
+	params := makeParams("CreateTags")
+	addParamsList(params, "ResourceId", resourceIds)
+	addTagParams(params, "Tag.", tags)
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteTag identifies a tag to delete. If Value is nil, the tag is
+// deleted whatever its value; otherwise it is only deleted if its
+// value equals *Value, which may be empty.
+type DeleteTag struct {
+	Key   string
+	Value *string
+}
+
+// DeleteTags deletes the given tags from the specified resources.
+// If tags is empty, all the tags of the resources are deleted.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteTags.html
+// for more details.
+func (ec2 *EC2) DeleteTags(resourceIds []string, tags []DeleteTag) (resp *SimpleResp, err error) {
+	params := makeParams("DeleteTags")
+	addParamsList(params, "ResourceId", resourceIds)
+	for j, tag := range tags {
+		params["Tag."+strconv.Itoa(j+1)+".Key"] = tag.Key
+		if tag.Value != nil {
+			params["Tag."+strconv.Itoa(j+1)+".Value"] = *tag.Value
+		}
+	}
+
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// TagsResp is the response to a DescribeTags request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeTags.html
+// for more details.
+type TagsResp struct {
+	RequestId string        `xml:"requestId"`
+	Tags      []ResourceTag `xml:"tagSet>item"`
+}
+
+// Tags returns the tags of the resources matching the given
+// filter, which may use the "key", "value", "resource-id" and
+// "resource-type" attributes.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeTags.html
+// for more details.
+func (ec2 *EC2) Tags(filter *Filter) (resp *TagsResp, err error) {
+	params := makeParamsCurrent("DescribeTags")
+	filter.addParams(params)
+
+	resp = &TagsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
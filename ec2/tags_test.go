@@ -0,0 +1,380 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// Tag tests with example responses
+
+func (s *S) TestDeleteTagsExample(c *C) {
+	testServer.Response(200, nil, DeleteTagsExample)
+
+	production, empty := "Production", ""
+	resp, err := s.ec2.DeleteTags([]string{"ami-1a2b3c4d", "i-7f4d3a2b"}, []ec2.DeleteTag{
+		{Key: "webserver"},
+		{Key: "stack", Value: &production},
+		{Key: "role", Value: &empty},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteTags"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2011-12-15"})
+	c.Assert(req.Form["ResourceId.1"], DeepEquals, []string{"ami-1a2b3c4d"})
+	c.Assert(req.Form["ResourceId.2"], DeepEquals, []string{"i-7f4d3a2b"})
+	c.Assert(req.Form["Tag.1.Key"], DeepEquals, []string{"webserver"})
+	c.Assert(req.Form["Tag.1.Value"], IsNil)
+	c.Assert(req.Form["Tag.2.Key"], DeepEquals, []string{"stack"})
+	c.Assert(req.Form["Tag.2.Value"], DeepEquals, []string{"Production"})
+	c.Assert(req.Form["Tag.3.Key"], DeepEquals, []string{"role"})
+	c.Assert(req.Form["Tag.3.Value"], DeepEquals, []string{""})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+}
+
+func (s *S) TestTagsExample(c *C) {
+	testServer.Response(200, nil, DescribeTagsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("resource-type", "image", "instance")
+	resp, err := s.ec2.Tags(filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeTags"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"resource-type"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"image"})
+	c.Assert(req.Form["Filter.1.Value.2"], DeepEquals, []string{"instance"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Assert(resp.Tags, DeepEquals, []ec2.ResourceTag{{
+		Tag:          ec2.Tag{Key: "webserver"},
+		ResourceId:   "ami-1a2b3c4d",
+		ResourceType: "image",
+	}, {
+		Tag:          ec2.Tag{Key: "stack", Value: "Production"},
+		ResourceId:   "i-1234567890abcdef0",
+		ResourceType: "instance",
+	}})
+}
+
+func (s *S) TestRunInstancesTagSpecificationsExample(c *C) {
+	testServer.Response(200, nil, RunInstancesExample)
+
+	_, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId: "image-id",
+		TagSpecifications: []ec2.TagSpecification{{
+			ResourceType: "instance",
+			Tags:         []ec2.Tag{{Key: "Name", Value: "web"}, {Key: "stack", Value: "Production"}},
+		}, {
+			ResourceType: "volume",
+			Tags:         []ec2.Tag{{Key: "cost-center", Value: "cc123"}},
+		}},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"RunInstances"})
+	c.Assert(req.Form["TagSpecification.1.ResourceType"], DeepEquals, []string{"instance"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Key"], DeepEquals, []string{"Name"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Value"], DeepEquals, []string{"web"})
+	c.Assert(req.Form["TagSpecification.1.Tag.2.Key"], DeepEquals, []string{"stack"})
+	c.Assert(req.Form["TagSpecification.1.Tag.2.Value"], DeepEquals, []string{"Production"})
+	c.Assert(req.Form["TagSpecification.2.ResourceType"], DeepEquals, []string{"volume"})
+	c.Assert(req.Form["TagSpecification.2.Tag.1.Key"], DeepEquals, []string{"cost-center"})
+	c.Assert(req.Form["TagSpecification.2.Tag.1.Value"], DeepEquals, []string{"cc123"})
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestCreateVolumeTagsExample(c *C) {
+	testServer.Response(200, nil, CreateVolumeExample)
+
+	_, err := s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone: "us-east-1a",
+		Size:      80,
+		Tags:      []ec2.Tag{{Key: "Name", Value: "data"}},
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateVolume"})
+	c.Assert(req.Form["TagSpecification.1.ResourceType"], DeepEquals, []string{"volume"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Key"], DeepEquals, []string{"Name"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Value"], DeepEquals, []string{"data"})
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestCreateSnapshotWithTagsExample(c *C) {
+	testServer.Response(200, nil, CreateSnapshotExample)
+
+	_, err := s.ec2.CreateSnapshotWithTags("vol-4d826724", "Daily Backup", []ec2.Tag{{Key: "Name", Value: "daily"}})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateSnapshot"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["VolumeId"], DeepEquals, []string{"vol-4d826724"})
+	c.Assert(req.Form["Description"], DeepEquals, []string{"Daily Backup"})
+	c.Assert(req.Form["TagSpecification.1.ResourceType"], DeepEquals, []string{"snapshot"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Key"], DeepEquals, []string{"Name"})
+	c.Assert(req.Form["TagSpecification.1.Tag.1.Value"], DeepEquals, []string{"daily"})
+	c.Assert(err, IsNil)
+}
+
+// Tag tests run against either a local test server or live on EC2.
+
+func (s *ServerTests) TestTags(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.7.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	defer s.deleteVPCs(c, []string{vpcId})
+	subId := s.createSubnet(c, vpcId, "10.7.1.0/24", "").Subnet.Id
+	defer s.deleteSubnets(c, []string{subId})
+	group := s.makeTestGroupVPC(c, vpcId, "goamz-tags-test", "tags test group")
+	defer s.deleteGroups(c, []ec2.SecurityGroup{group})
+
+	ids := []string{vpcId, subId, group.Id}
+	_, err = s.ec2.CreateTags(ids, []ec2.Tag{
+		{Key: "Name", Value: "goamz-tags-test"},
+		{Key: "stack", Value: "test"},
+	})
+	c.Assert(err, IsNil)
+
+	// Creating a tag again overwrites its value.
+	_, err = s.ec2.CreateTags([]string{subId}, []ec2.Tag{{Key: "stack", Value: "other"}})
+	c.Assert(err, IsNil)
+
+	f := ec2.NewFilter()
+	f.Add("tag:Name", "goamz-tags-test")
+	vpcs, err := s.ec2.VPCs(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(vpcs.VPCs, HasLen, 1)
+	c.Check(vpcs.VPCs[0].Id, Equals, vpcId)
+	c.Check(vpcs.VPCs[0].Tags, DeepEquals, []ec2.Tag{
+		{Key: "Name", Value: "goamz-tags-test"},
+		{Key: "stack", Value: "test"},
+	})
+
+	f = ec2.NewFilter()
+	f.Add("tag:stack", "other")
+	f.Add("vpc-id", vpcId)
+	subnets, err := s.ec2.Subnets(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(subnets.Subnets, HasLen, 1)
+	c.Check(subnets.Subnets[0].Id, Equals, subId)
+
+	f = ec2.NewFilter()
+	f.Add("tag-key", "stack")
+	groups, err := s.ec2.SecurityGroups([]ec2.SecurityGroup{group}, f)
+	c.Assert(err, IsNil)
+	c.Assert(groups.Groups, HasLen, 1)
+	c.Check(groups.Groups[0].Tags, HasLen, 2)
+
+	f = ec2.NewFilter()
+	f.Add("resource-id", ids...)
+	f.Add("key", "stack")
+	tags, err := s.ec2.Tags(f)
+	c.Assert(err, IsNil)
+	c.Check(tags.Tags, HasLen, 3)
+	for _, t := range tags.Tags {
+		switch t.ResourceId {
+		case vpcId:
+			c.Check(t.ResourceType, Equals, "vpc")
+			c.Check(t.Value, Equals, "test")
+		case subId:
+			c.Check(t.ResourceType, Equals, "subnet")
+			c.Check(t.Value, Equals, "other")
+		case group.Id:
+			c.Check(t.ResourceType, Equals, "security-group")
+			c.Check(t.Value, Equals, "test")
+		default:
+			c.Errorf("unexpected tag %+v", t)
+		}
+	}
+
+	// A tag given with a value is only deleted where the value
+	// matches, even when it is empty; without a value it is
+	// deleted whatever its value.
+	test := "test"
+	_, err = s.ec2.DeleteTags(ids, []ec2.DeleteTag{{Key: "stack", Value: &test}})
+	c.Assert(err, IsNil)
+	f = ec2.NewFilter()
+	f.Add("resource-id", ids...)
+	f.Add("key", "stack")
+	tags, err = s.ec2.Tags(f)
+	c.Assert(err, IsNil)
+	c.Assert(tags.Tags, HasLen, 1)
+	c.Check(tags.Tags[0].ResourceId, Equals, subId)
+
+	_, err = s.ec2.CreateTags([]string{vpcId}, []ec2.Tag{{Key: "role", Value: ""}})
+	c.Assert(err, IsNil)
+	_, err = s.ec2.CreateTags([]string{subId}, []ec2.Tag{{Key: "role", Value: "web"}})
+	c.Assert(err, IsNil)
+	empty := ""
+	_, err = s.ec2.DeleteTags(ids, []ec2.DeleteTag{{Key: "role", Value: &empty}})
+	c.Assert(err, IsNil)
+	f = ec2.NewFilter()
+	f.Add("resource-id", ids...)
+	f.Add("key", "role")
+	tags, err = s.ec2.Tags(f)
+	c.Assert(err, IsNil)
+	c.Assert(tags.Tags, HasLen, 1)
+	c.Check(tags.Tags[0].ResourceId, Equals, subId)
+
+	_, err = s.ec2.DeleteTags(ids, []ec2.DeleteTag{{Key: "stack"}, {Key: "Name"}, {Key: "role"}})
+	c.Assert(err, IsNil)
+	f = ec2.NewFilter()
+	f.Add("resource-id", ids...)
+	tags, err = s.ec2.Tags(f)
+	c.Assert(err, IsNil)
+	c.Check(tags.Tags, HasLen, 0)
+}
+
+func (s *ServerTests) TestTagsOnCreate(c *C) {
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		TagSpecifications: []ec2.TagSpecification{{
+			ResourceType: "instance",
+			Tags:         []ec2.Tag{{Key: "Name", Value: "goamz-tags-test"}},
+		}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(inst.Instances, HasLen, 1)
+	instId := inst.Instances[0].InstanceId
+	defer terminateInstances(c, s.ec2, []string{instId})
+	c.Check(inst.Instances[0].Tags, DeepEquals, []ec2.Tag{{Key: "Name", Value: "goamz-tags-test"}})
+
+	f := ec2.NewFilter()
+	f.Add("tag:Name", "goamz-tags-test")
+	resp, err := s.ec2.Instances([]string{instId}, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Reservations, HasLen, 1)
+	c.Assert(resp.Reservations[0].Instances, HasLen, 1)
+	c.Check(resp.Reservations[0].Instances[0].InstanceId, Equals, instId)
+
+	vol, err := s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone: "us-east-1a",
+		Size:      1,
+		Tags:      []ec2.Tag{{Key: "Name", Value: "goamz-tags-test"}},
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVolume(vol.Id)
+	c.Check(vol.Tags, DeepEquals, []ec2.Tag{{Key: "Name", Value: "goamz-tags-test"}})
+
+	f = ec2.NewFilter()
+	f.Add("tag-value", "goamz-tags-test")
+	vols, err := s.ec2.Volumes([]string{vol.Id}, f)
+	c.Assert(err, IsNil)
+	c.Assert(vols.Volumes, HasLen, 1)
+}
+
+// Tag tests run only against the local test server.
+
+func (s *LocalServerSuite) TestTagsOnCreateSnapshotAndInterfaces(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.8.0.0/16", "")
+	c.Assert(err, IsNil)
+	subId := s.createSubnet(c, vpcResp.VPC.Id, "10.8.1.0/24", "").Subnet.Id
+
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		NetworkInterfaces: []ec2.RunNetworkInterface{{
+			DeviceIndex: 0,
+			SubnetId:    subId,
+		}},
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+			DeviceName: "/dev/sdb",
+			VolumeSize: 8,
+		}},
+		TagSpecifications: []ec2.TagSpecification{{
+			ResourceType: "volume",
+			Tags:         []ec2.Tag{{Key: "kind", Value: "volume"}},
+		}, {
+			ResourceType: "network-interface",
+			Tags:         []ec2.Tag{{Key: "kind", Value: "nic"}},
+		}},
+	})
+	c.Assert(err, IsNil)
+	instId := inst.Instances[0].InstanceId
+	c.Check(inst.Instances[0].Tags, HasLen, 0)
+
+	f := ec2.NewFilter()
+	f.Add("resource-type", "volume", "network-interface")
+	tags, err := s.ec2.Tags(f)
+	c.Assert(err, IsNil)
+	c.Assert(tags.Tags, HasLen, 2)
+	for _, t := range tags.Tags {
+		switch t.ResourceType {
+		case "volume":
+			c.Check(t.ResourceId, Matches, "vol-.+")
+			c.Check(t.Tag, Equals, ec2.Tag{Key: "kind", Value: "volume"})
+		case "network-interface":
+			c.Check(t.ResourceId, Matches, "eni-.+")
+			c.Check(t.Tag, Equals, ec2.Tag{Key: "kind", Value: "nic"})
+		}
+	}
+
+	volId := ""
+	for _, t := range tags.Tags {
+		if t.ResourceType == "volume" {
+			volId = t.ResourceId
+		}
+	}
+	snap, err := s.ec2.CreateSnapshotWithTags(volId, "", []ec2.Tag{{Key: "Name", Value: "backup"}})
+	c.Assert(err, IsNil)
+	c.Check(snap.Tags, DeepEquals, []ec2.Tag{{Key: "Name", Value: "backup"}})
+
+	f = ec2.NewFilter()
+	f.Add("tag:Name", "backup")
+	snaps, err := s.ec2.Snapshots(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(snaps.Snapshots, HasLen, 1)
+	c.Check(snaps.Snapshots[0].Id, Equals, snap.Id)
+
+	_, err = s.ec2.TerminateInstances([]string{instId})
+	c.Assert(err, IsNil)
+}
+
+func (s *LocalServerSuite) TestTagsErrors(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.9.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+
+	_, err = s.ec2.CreateTags([]string{vpcId, "subnet-999"}, []ec2.Tag{{Key: "Name", Value: "x"}})
+	c.Check(errorCode(err), Equals, "InvalidSubnetID.NotFound")
+	_, err = s.ec2.CreateTags([]string{"foo-1"}, []ec2.Tag{{Key: "Name", Value: "x"}})
+	c.Check(errorCode(err), Equals, "InvalidID")
+	_, err = s.ec2.CreateTags([]string{vpcId}, []ec2.Tag{{Key: "aws:reserved", Value: "x"}})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	_, err = s.ec2.DeleteTags([]string{"i-999"}, nil)
+	c.Check(errorCode(err), Equals, "InvalidInstanceID.NotFound")
+
+	// Nothing is tagged when any resource is unknown.
+	tags, err := s.ec2.Tags(nil)
+	c.Assert(err, IsNil)
+	c.Check(tags.Tags, HasLen, 0)
+
+	many := make([]ec2.Tag, 51)
+	for i := range many {
+		many[i] = ec2.Tag{Key: fmt.Sprint("key", i)}
+	}
+	_, err = s.ec2.CreateTags([]string{vpcId}, many)
+	c.Check(errorCode(err), Equals, "TagLimitExceeded")
+
+	_, err = s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone: "us-east-1a",
+		Size:      1,
+		Tags:      []ec2.Tag{{Key: "aws:reserved"}},
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+}
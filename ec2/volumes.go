@@ -0,0 +1,338 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// Volume describes an Amazon EBS volume.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_Volume.html for more details.
+type Volume struct {
+	Id          string             `xml:"volumeId"`
+	Size        int                `xml:"size"` // Size in GiB.
+	SnapshotId  string             `xml:"snapshotId"`
+	AvailZone   string             `xml:"availabilityZone"`
+	Status      string             `xml:"status"`
+	CreateTime  string             `xml:"createTime"`
+	VolumeType  string             `xml:"volumeType"`
+	IOPS        int64              `xml:"iops"`
+	Encrypted   bool               `xml:"encrypted"`
+	KMSKeyId    string             `xml:"kmsKeyId"`
+	Attachments []VolumeAttachment `xml:"attachmentSet>item"`
+	Tags        []Tag              `xml:"tagSet>item"`
+}
+
+// VolumeAttachment describes the attachment of an EBS volume to an
+// instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AttachVolume.html for more details.
+type VolumeAttachment struct {
+	VolumeId            string `xml:"volumeId"`
+	InstanceId          string `xml:"instanceId"`
+	Device              string `xml:"device"`
+	Status              string `xml:"status"`
+	AttachTime          string `xml:"attachTime"`
+	DeleteOnTermination bool   `xml:"deleteOnTermination"`
+}
+
+// InstanceBlockDeviceMapping describes an EBS volume attached to an
+// instance, as reported along with the instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_InstanceBlockDeviceMapping.html for more details.
+type InstanceBlockDeviceMapping struct {
+	DeviceName          string `xml:"deviceName"`
+	VolumeId            string `xml:"ebs>volumeId"`
+	Status              string `xml:"ebs>status"`
+	AttachTime          string `xml:"ebs>attachTime"`
+	DeleteOnTermination bool   `xml:"ebs>deleteOnTermination"`
+}
+
+// CreateVolume holds the options for a CreateVolume request.
+// Either Size or SnapshotId must be given; when both are, Size
+// must not be smaller than the snapshot.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVolume.html for more details.
+type CreateVolume struct {
+	AvailZone  string
+	Size       int // Size in GiB.
+	SnapshotId string
+	VolumeType string // "standard", "gp2", "io1", "st1" or "sc1".
+	IOPS       int64  // Required for "io1" volumes only.
+	Encrypted  bool
+	KMSKeyId   string
+	Tags       []Tag // Applied to the volume on creation.
+}
+
+// CreateVolumeResp is the response to a CreateVolume request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVolume.html for more details.
+type CreateVolumeResp struct {
+	RequestId string `xml:"requestId"`
+	Volume
+}
+
+// CreateVolume creates an EBS volume that can be attached to any
+// instance in the same availability zone.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVolume.html for more details.
+func (ec2 *EC2) CreateVolume(options *CreateVolume) (resp *CreateVolumeResp, err error) {
+	params := makeParamsCurrent("CreateVolume")
+	params["AvailabilityZone"] = options.AvailZone
+	if options.Size != 0 {
+		params["Size"] = strconv.Itoa(options.Size)
+	}
+	if options.SnapshotId != "" {
+		params["SnapshotId"] = options.SnapshotId
+	}
+	if options.VolumeType != "" {
+		params["VolumeType"] = options.VolumeType
+	}
+	if options.IOPS != 0 {
+		params["Iops"] = strconv.FormatInt(options.IOPS, 10)
+	}
+	if options.Encrypted {
+		params["Encrypted"] = "true"
+	}
+	if options.KMSKeyId != "" {
+		params["KmsKeyId"] = options.KMSKeyId
+	}
+	if len(options.Tags) > 0 {
+		addTagSpecParams(params, []TagSpecification{{ResourceType: "volume", Tags: options.Tags}})
+	}
+	resp = &CreateVolumeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteVolume deletes the specified volume, which must not be
+// attached to an instance.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteVolume.html for more details.
+func (ec2 *EC2) DeleteVolume(id string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("DeleteVolume")
+	params["VolumeId"] = id
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VolumesResp is the response to a Volumes request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumes.html for more details.
+type VolumesResp struct {
+	RequestId string   `xml:"requestId"`
+	Volumes   []Volume `xml:"volumeSet>item"`
+}
+
+// Volumes returns one or more volumes. Both parameters are optional,
+// and if specified will limit the returned volumes to the matching
+// ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumes.html for more details.
+func (ec2 *EC2) Volumes(ids []string, filter *Filter) (resp *VolumesResp, err error) {
+	params := makeParamsCurrent("DescribeVolumes")
+	for i, id := range ids {
+		params["VolumeId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &VolumesResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VolumeAttachmentResp is the response to an AttachVolume or
+// DetachVolume request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AttachVolume.html for more details.
+type VolumeAttachmentResp struct {
+	RequestId string `xml:"requestId"`
+	VolumeAttachment
+}
+
+// AttachVolume attaches the specified volume to a running or
+// stopped instance, exposing it with the given device name.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AttachVolume.html for more details.
+func (ec2 *EC2) AttachVolume(volumeId, instanceId, device string) (resp *VolumeAttachmentResp, err error) {
+	params := makeParamsCurrent("AttachVolume")
+	params["VolumeId"] = volumeId
+	params["InstanceId"] = instanceId
+	params["Device"] = device
+	resp = &VolumeAttachmentResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DetachVolume detaches the specified volume from the instance it
+// is attached to. The instanceId and device parameters are optional,
+// and if specified must match the attachment. If force is true, the
+// volume is detached even if the instance does not release it, which
+// may lead to data loss.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DetachVolume.html for more details.
+func (ec2 *EC2) DetachVolume(volumeId, instanceId, device string, force bool) (resp *VolumeAttachmentResp, err error) {
+	params := makeParamsCurrent("DetachVolume")
+	params["VolumeId"] = volumeId
+	if instanceId != "" {
+		params["InstanceId"] = instanceId
+	}
+	if device != "" {
+		params["Device"] = device
+	}
+	if force {
+		params["Force"] = "true"
+	}
+	resp = &VolumeAttachmentResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ModifyVolume holds the changes to make in a ModifyVolume request.
+// Zero values are left unchanged.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVolume.html for more details.
+type ModifyVolume struct {
+	Size       int
+	VolumeType string
+	IOPS       int64
+}
+
+// VolumeModification describes the progress of a modification
+// to a volume.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVolume.html for more details.
+type VolumeModification struct {
+	VolumeId           string `xml:"volumeId"`
+	ModificationState  string `xml:"modificationState"`
+	StatusMessage      string `xml:"statusMessage"`
+	TargetSize         int    `xml:"targetSize"`
+	TargetIOPS         int64  `xml:"targetIops"`
+	TargetVolumeType   string `xml:"targetVolumeType"`
+	OriginalSize       int    `xml:"originalSize"`
+	OriginalIOPS       int64  `xml:"originalIops"`
+	OriginalVolumeType string `xml:"originalVolumeType"`
+	Progress           int    `xml:"progress"`
+	StartTime          string `xml:"startTime"`
+	EndTime            string `xml:"endTime"`
+}
+
+// ModifyVolumeResp is the response to a ModifyVolume request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVolume.html for more details.
+type ModifyVolumeResp struct {
+	RequestId    string             `xml:"requestId"`
+	Modification VolumeModification `xml:"volumeModification"`
+}
+
+// ModifyVolume changes the size, type or provisioned IOPS of the
+// specified volume. Volumes can grow but never shrink.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVolume.html for more details.
+func (ec2 *EC2) ModifyVolume(volumeId string, options *ModifyVolume) (resp *ModifyVolumeResp, err error) {
+	params := makeParamsCurrent("ModifyVolume")
+	params["VolumeId"] = volumeId
+	if options.Size != 0 {
+		params["Size"] = strconv.Itoa(options.Size)
+	}
+	if options.VolumeType != "" {
+		params["VolumeType"] = options.VolumeType
+	}
+	if options.IOPS != 0 {
+		params["Iops"] = strconv.FormatInt(options.IOPS, 10)
+	}
+	resp = &ModifyVolumeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VolumeStatusDetail holds the result of one of the checks made on
+// a volume.
+type VolumeStatusDetail struct {
+	Name   string `xml:"name"`
+	Status string `xml:"status"`
+}
+
+// VolumeStatusEvent describes an event affecting a volume.
+type VolumeStatusEvent struct {
+	Id          string `xml:"eventId"`
+	Type        string `xml:"eventType"`
+	Description string `xml:"description"`
+	NotBefore   string `xml:"notBefore"`
+	NotAfter    string `xml:"notAfter"`
+}
+
+// VolumeStatusAction describes an action that may be taken in
+// response to a volume event.
+type VolumeStatusAction struct {
+	Code        string `xml:"code"`
+	Description string `xml:"description"`
+	EventId     string `xml:"eventId"`
+	EventType   string `xml:"eventType"`
+}
+
+// VolumeStatus holds the status of a volume.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumeStatus.html for more details.
+type VolumeStatus struct {
+	VolumeId  string               `xml:"volumeId"`
+	AvailZone string               `xml:"availabilityZone"`
+	Status    string               `xml:"volumeStatus>status"`
+	Details   []VolumeStatusDetail `xml:"volumeStatus>details>item"`
+	Events    []VolumeStatusEvent  `xml:"eventsSet>item"`
+	Actions   []VolumeStatusAction `xml:"actionsSet>item"`
+}
+
+// VolumeStatusResp is the response to a VolumeStatus request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumeStatus.html for more details.
+type VolumeStatusResp struct {
+	RequestId string         `xml:"requestId"`
+	Statuses  []VolumeStatus `xml:"volumeStatusSet>item"`
+}
+
+// VolumeStatus returns the status of one or more volumes. Both
+// parameters are optional, and if specified will limit the returned
+// statuses to the matching volume ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVolumeStatus.html for more details.
+func (ec2 *EC2) VolumeStatus(ids []string, filter *Filter) (resp *VolumeStatusResp, err error) {
+	params := makeParamsCurrent("DescribeVolumeStatus")
+	for i, id := range ids {
+		params["VolumeId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &VolumeStatusResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,433 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// Volume tests with example responses
+
+func (s *S) TestCreateVolumeExample(c *C) {
+	testServer.Response(200, nil, CreateVolumeExample)
+
+	resp, err := s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone:  "us-east-1a",
+		Size:       80,
+		SnapshotId: "snap-1234567890abcdef0",
+		VolumeType: "io1",
+		IOPS:       1000,
+		Encrypted:  true,
+		KMSKeyId:   "key-id",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateVolume"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["AvailabilityZone"], DeepEquals, []string{"us-east-1a"})
+	c.Assert(req.Form["Size"], DeepEquals, []string{"80"})
+	c.Assert(req.Form["SnapshotId"], DeepEquals, []string{"snap-1234567890abcdef0"})
+	c.Assert(req.Form["VolumeType"], DeepEquals, []string{"io1"})
+	c.Assert(req.Form["Iops"], DeepEquals, []string{"1000"})
+	c.Assert(req.Form["Encrypted"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["KmsKeyId"], DeepEquals, []string{"key-id"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Check(resp.Id, Equals, "vol-1234567890abcdef0")
+	c.Check(resp.Size, Equals, 80)
+	c.Check(resp.SnapshotId, Equals, "snap-1234567890abcdef0")
+	c.Check(resp.AvailZone, Equals, "us-east-1a")
+	c.Check(resp.Status, Equals, "creating")
+	c.Check(resp.CreateTime, Equals, "2016-08-29T18:52:32.724Z")
+	c.Check(resp.VolumeType, Equals, "io1")
+	c.Check(resp.IOPS, Equals, int64(1000))
+	c.Check(resp.Encrypted, Equals, true)
+	c.Check(resp.KMSKeyId, Matches, "arn:aws:kms:.*")
+}
+
+func (s *S) TestCreateVolumeMinimal(c *C) {
+	testServer.Response(200, nil, CreateVolumeExample)
+
+	_, err := s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone: "us-east-1a",
+		Size:      10,
+	})
+	req := testServer.WaitRequest()
+	c.Assert(err, IsNil)
+
+	c.Assert(req.Form["Size"], DeepEquals, []string{"10"})
+	for _, name := range []string{"SnapshotId", "VolumeType", "Iops", "Encrypted", "KmsKeyId"} {
+		c.Check(req.Form[name], IsNil, Commentf("%s", name))
+	}
+}
+
+func (s *S) TestDeleteVolumeExample(c *C) {
+	testServer.Response(200, nil, DeleteVolumeExample)
+
+	resp, err := s.ec2.DeleteVolume("vol-1234567890abcdef0")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DeleteVolume"})
+	c.Assert(req.Form["VolumeId"], DeepEquals, []string{"vol-1234567890abcdef0"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+}
+
+func (s *S) TestVolumesExample(c *C) {
+	testServer.Response(200, nil, DescribeVolumesExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("status", "in-use")
+	resp, err := s.ec2.Volumes([]string{"vol-1", "vol-2"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeVolumes"})
+	c.Assert(req.Form["VolumeId.1"], DeepEquals, []string{"vol-1"})
+	c.Assert(req.Form["VolumeId.2"], DeepEquals, []string{"vol-2"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"status"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"in-use"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Assert(resp.Volumes, HasLen, 1)
+	v := resp.Volumes[0]
+	c.Check(v.Id, Equals, "vol-1234567890abcdef0")
+	c.Check(v.Size, Equals, 80)
+	c.Check(v.SnapshotId, Equals, "")
+	c.Check(v.AvailZone, Equals, "us-east-1a")
+	c.Check(v.Status, Equals, "in-use")
+	c.Check(v.VolumeType, Equals, "standard")
+	c.Check(v.Encrypted, Equals, true)
+	c.Check(v.Attachments, DeepEquals, []ec2.VolumeAttachment{{
+		VolumeId:            "vol-1234567890abcdef0",
+		InstanceId:          "i-1234567890abcdef0",
+		Device:              "/dev/sdh",
+		Status:              "attached",
+		AttachTime:          "YYYY-MM-DDTHH:MM:SS.SSSZ",
+		DeleteOnTermination: false,
+	}})
+	c.Check(v.Tags, DeepEquals, []ec2.Tag{{"Name", "data"}})
+}
+
+func (s *S) TestAttachVolumeExample(c *C) {
+	testServer.Response(200, nil, AttachVolumeExample)
+
+	resp, err := s.ec2.AttachVolume("vol-1234567890abcdef0", "i-1234567890abcdef0", "/dev/sdh")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AttachVolume"})
+	c.Assert(req.Form["VolumeId"], DeepEquals, []string{"vol-1234567890abcdef0"})
+	c.Assert(req.Form["InstanceId"], DeepEquals, []string{"i-1234567890abcdef0"})
+	c.Assert(req.Form["Device"], DeepEquals, []string{"/dev/sdh"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Check(resp.VolumeId, Equals, "vol-1234567890abcdef0")
+	c.Check(resp.InstanceId, Equals, "i-1234567890abcdef0")
+	c.Check(resp.Device, Equals, "/dev/sdh")
+	c.Check(resp.Status, Equals, "attaching")
+}
+
+func (s *S) TestDetachVolumeExample(c *C) {
+	testServer.Response(200, nil, DetachVolumeExample)
+
+	resp, err := s.ec2.DetachVolume("vol-1234567890abcdef0", "", "", true)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DetachVolume"})
+	c.Assert(req.Form["VolumeId"], DeepEquals, []string{"vol-1234567890abcdef0"})
+	c.Assert(req.Form["InstanceId"], IsNil)
+	c.Assert(req.Form["Device"], IsNil)
+	c.Assert(req.Form["Force"], DeepEquals, []string{"true"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "59dbff89-35bd-4eac-99ed-be587EXAMPLE")
+	c.Check(resp.VolumeId, Equals, "vol-1234567890abcdef0")
+	c.Check(resp.InstanceId, Equals, "i-1234567890abcdef0")
+	c.Check(resp.Status, Equals, "detaching")
+}
+
+func (s *S) TestModifyVolumeExample(c *C) {
+	testServer.Response(200, nil, ModifyVolumeExample)
+
+	resp, err := s.ec2.ModifyVolume("vol-0123456789EXAMPLE", &ec2.ModifyVolume{
+		Size:       200,
+		VolumeType: "io1",
+		IOPS:       10000,
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ModifyVolume"})
+	c.Assert(req.Form["VolumeId"], DeepEquals, []string{"vol-0123456789EXAMPLE"})
+	c.Assert(req.Form["Size"], DeepEquals, []string{"200"})
+	c.Assert(req.Form["VolumeType"], DeepEquals, []string{"io1"})
+	c.Assert(req.Form["Iops"], DeepEquals, []string{"10000"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "5jkdf074-37ed-4004-8671-a78ee82bf1cbEXAMPLE")
+	c.Check(resp.Modification, DeepEquals, ec2.VolumeModification{
+		VolumeId:           "vol-0123456789EXAMPLE",
+		ModificationState:  "modifying",
+		TargetSize:         200,
+		TargetIOPS:         10000,
+		TargetVolumeType:   "io1",
+		OriginalSize:       100,
+		OriginalIOPS:       300,
+		OriginalVolumeType: "gp2",
+		StartTime:          "2017-01-19T22:21:02.959Z",
+	})
+}
+
+func (s *S) TestVolumeStatusExample(c *C) {
+	testServer.Response(200, nil, DescribeVolumeStatusExample)
+
+	resp, err := s.ec2.VolumeStatus([]string{"vol-1234567890abcdef0"}, nil)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeVolumeStatus"})
+	c.Assert(req.Form["VolumeId.1"], DeepEquals, []string{"vol-1234567890abcdef0"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.Statuses, HasLen, 1)
+	st := resp.Statuses[0]
+	c.Check(st.VolumeId, Equals, "vol-1234567890abcdef0")
+	c.Check(st.AvailZone, Equals, "us-east-1d")
+	c.Check(st.Status, Equals, "impaired")
+	c.Check(st.Details, DeepEquals, []ec2.VolumeStatusDetail{{"io-enabled", "failed"}})
+	c.Check(st.Events, DeepEquals, []ec2.VolumeStatusEvent{{
+		Id:          "evol-61a54008",
+		Type:        "potential-data-inconsistency",
+		Description: "THIS IS AN EXAMPLE",
+		NotBefore:   "2011-12-01T14:00:00.000Z",
+		NotAfter:    "2011-12-01T15:00:00.000Z",
+	}})
+	c.Check(st.Actions, DeepEquals, []ec2.VolumeStatusAction{{
+		Code:        "enable-volume-io",
+		Description: "THIS IS AN EXAMPLE",
+		EventId:     "evol-61a54008",
+		EventType:   "potential-data-inconsistency",
+	}})
+}
+
+// Volume tests that run against the local test server.
+
+func (s *LocalServerSuite) TestVolumes(c *C) {
+	resp, err := s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone:  "us-east-1a",
+		Size:       10,
+		VolumeType: "gp2",
+	})
+	c.Assert(err, IsNil)
+	id := resp.Id
+	c.Check(id, Matches, "vol-[0-9]+")
+	c.Check(resp.Size, Equals, 10)
+	c.Check(resp.Status, Equals, "available")
+	c.Check(resp.VolumeType, Equals, "gp2")
+
+	list, err := s.ec2.Volumes([]string{id}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(list.Volumes, HasLen, 1)
+	c.Check(list.Volumes[0], DeepEquals, resp.Volume)
+
+	f := ec2.NewFilter()
+	f.Add("volume-type", "io1")
+	list, err = s.ec2.Volumes([]string{id}, f)
+	c.Assert(err, IsNil)
+	c.Check(list.Volumes, HasLen, 0)
+
+	mod, err := s.ec2.ModifyVolume(id, &ec2.ModifyVolume{Size: 5})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	mod, err = s.ec2.ModifyVolume(id, &ec2.ModifyVolume{Size: 20, VolumeType: "io1", IOPS: 500})
+	c.Assert(err, IsNil)
+	c.Check(mod.Modification.OriginalSize, Equals, 10)
+	c.Check(mod.Modification.TargetSize, Equals, 20)
+	c.Check(mod.Modification.ModificationState, Equals, "completed")
+
+	list, err = s.ec2.Volumes(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(list.Volumes, HasLen, 1)
+	c.Check(list.Volumes[0].Size, Equals, 20)
+	c.Check(list.Volumes[0].IOPS, Equals, int64(500))
+
+	status, err := s.ec2.VolumeStatus([]string{id}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(status.Statuses, HasLen, 1)
+	c.Check(status.Statuses[0].Status, Equals, "ok")
+	s.srv.srv.SetVolumeStatus(id, "impaired")
+	f = ec2.NewFilter()
+	f.Add("volume-status.status", "impaired")
+	status, err = s.ec2.VolumeStatus(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(status.Statuses, HasLen, 1)
+	c.Check(status.Statuses[0].Details, DeepEquals, []ec2.VolumeStatusDetail{{"io-enabled", "failed"}})
+
+	_, err = s.ec2.DeleteVolume(id)
+	c.Assert(err, IsNil)
+	_, err = s.ec2.Volumes([]string{id}, nil)
+	c.Check(errorCode(err), Equals, "InvalidVolume.NotFound")
+}
+
+func (s *LocalServerSuite) TestCreateVolumeErrors(c *C) {
+	for i, t := range []struct {
+		options ec2.CreateVolume
+		code    string
+	}{{
+		options: ec2.CreateVolume{Size: 1},
+		code:    "MissingParameter",
+	}, {
+		options: ec2.CreateVolume{AvailZone: "us-east-1a"},
+		code:    "MissingParameter",
+	}, {
+		options: ec2.CreateVolume{AvailZone: "nowhere", Size: 1},
+		code:    "InvalidZone.NotFound",
+	}, {
+		options: ec2.CreateVolume{AvailZone: "us-east-1a", Size: 1, VolumeType: "floppy"},
+		code:    "InvalidParameterValue",
+	}, {
+		options: ec2.CreateVolume{AvailZone: "us-east-1a", Size: 1, VolumeType: "io1"},
+		code:    "MissingParameter",
+	}, {
+		options: ec2.CreateVolume{AvailZone: "us-east-1a", Size: 1, VolumeType: "gp2", IOPS: 100},
+		code:    "InvalidParameterCombination",
+	}, {
+		options: ec2.CreateVolume{AvailZone: "us-east-1a", Size: 1, KMSKeyId: "key"},
+		code:    "InvalidParameterDependency",
+	}, {
+		options: ec2.CreateVolume{AvailZone: "us-east-1a", SnapshotId: "snap-missing"},
+		code:    "InvalidSnapshot.NotFound",
+	}} {
+		c.Logf("test %d: %+v", i, t.options)
+		_, err := s.ec2.CreateVolume(&t.options)
+		c.Check(errorCode(err), Equals, t.code)
+	}
+}
+
+func (s *LocalServerSuite) TestVolumeFromSnapshot(c *C) {
+	resp, err := s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone: "us-east-1a",
+		Size:      8,
+		Encrypted: true,
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVolume(resp.Id)
+
+	snap, err := s.ec2.CreateSnapshot(resp.Id, "backup")
+	c.Assert(err, IsNil)
+	c.Check(snap.VolumeId, Equals, resp.Id)
+	c.Check(snap.VolumeSize, Equals, "8")
+	defer s.ec2.DeleteSnapshots([]string{snap.Id})
+
+	f := ec2.NewFilter()
+	f.Add("volume-id", resp.Id)
+	snaps, err := s.ec2.Snapshots(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(snaps.Snapshots, HasLen, 1)
+	c.Check(snaps.Snapshots[0].Id, Equals, snap.Id)
+
+	_, err = s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone:  "us-east-1a",
+		Size:       4,
+		SnapshotId: snap.Id,
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+
+	restored, err := s.ec2.CreateVolume(&ec2.CreateVolume{
+		AvailZone:  "us-east-1a",
+		SnapshotId: snap.Id,
+	})
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteVolume(restored.Id)
+	c.Check(restored.Size, Equals, 8)
+	c.Check(restored.SnapshotId, Equals, snap.Id)
+	c.Check(restored.Encrypted, Equals, true)
+
+	f = ec2.NewFilter()
+	f.Add("snapshot-id", snap.Id)
+	list, err := s.ec2.Volumes(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(list.Volumes, HasLen, 1)
+	c.Check(list.Volumes[0].Id, Equals, restored.Id)
+}
+
+func (s *LocalServerSuite) TestVolumeAttachment(c *C) {
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+		AvailZone:    "us-east-1a",
+		BlockDeviceMappings: []ec2.BlockDeviceMapping{{
+			DeviceName:          "/dev/sda1",
+			VolumeSize:          8,
+			DeleteOnTermination: true,
+		}},
+	})
+	c.Assert(err, IsNil)
+	instId := inst.Instances[0].InstanceId
+	rootDevs := inst.Instances[0].BlockDeviceMappings
+	c.Assert(rootDevs, HasLen, 1)
+	c.Check(rootDevs[0].DeviceName, Equals, "/dev/sda1")
+	c.Check(rootDevs[0].DeleteOnTermination, Equals, true)
+
+	resp, err := s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", Size: 1})
+	c.Assert(err, IsNil)
+	volId := resp.Id
+
+	_, err = s.ec2.AttachVolume(volId, instId, "/dev/sda1")
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	att, err := s.ec2.AttachVolume(volId, instId, "/dev/sdh")
+	c.Assert(err, IsNil)
+	c.Check(att.VolumeId, Equals, volId)
+	c.Check(att.InstanceId, Equals, instId)
+	c.Check(att.Status, Equals, "attaching")
+
+	f := ec2.NewFilter()
+	f.Add("attachment.instance-id", instId)
+	list, err := s.ec2.Volumes(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(list.Volumes, HasLen, 2)
+
+	insts, err := s.ec2.Instances([]string{instId}, nil)
+	c.Assert(err, IsNil)
+	devs := insts.Reservations[0].Instances[0].BlockDeviceMappings
+	c.Assert(devs, HasLen, 2)
+	c.Check(devs[1].DeviceName, Equals, "/dev/sdh")
+	c.Check(devs[1].VolumeId, Equals, volId)
+	c.Check(devs[1].Status, Equals, "attached")
+
+	_, err = s.ec2.AttachVolume(volId, instId, "/dev/sdi")
+	c.Check(errorCode(err), Equals, "VolumeInUse")
+	_, err = s.ec2.DeleteVolume(volId)
+	c.Check(errorCode(err), Equals, "VolumeInUse")
+	_, err = s.ec2.DetachVolume(volId, "i-other", "", false)
+	c.Check(errorCode(err), Equals, "InvalidAttachment.NotFound")
+
+	det, err := s.ec2.DetachVolume(volId, instId, "/dev/sdh", false)
+	c.Assert(err, IsNil)
+	c.Check(det.Status, Equals, "detaching")
+	_, err = s.ec2.DetachVolume(volId, "", "", false)
+	c.Check(errorCode(err), Equals, "IncorrectState")
+
+	// Terminating the instance deletes the root volume, while
+	// volumes not marked for deletion are left available.
+	_, err = s.ec2.AttachVolume(volId, instId, "/dev/sdh")
+	c.Assert(err, IsNil)
+	_, err = s.ec2.TerminateInstances([]string{instId})
+	c.Assert(err, IsNil)
+	list, err = s.ec2.Volumes([]string{volId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(list.Volumes[0].Status, Equals, "available")
+	_, err = s.ec2.Volumes([]string{rootDevs[0].VolumeId}, nil)
+	c.Check(errorCode(err), Equals, "InvalidVolume.NotFound")
+
+	_, err = s.ec2.DeleteVolume(volId)
+	c.Assert(err, IsNil)
+}
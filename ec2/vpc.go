@@ -23,6 +23,39 @@ type VPC struct {
 	Tags            []Tag  `xml:"tagSet>item"`
 	InstanceTenancy string `xml:"instanceTenancy"`
 	IsDefault       bool   `xml:"isDefault"`
+
+	// CIDRBlocks holds the IPv4 CIDR blocks associated with the
+	// VPC: its primary CIDRBlock and any secondary ones.
+	CIDRBlocks []CIDRBlockAssociation `xml:"cidrBlockAssociationSet>item"`
+
+	// IPv6CIDRBlocks holds the IPv6 CIDR blocks associated with
+	// the VPC.
+	IPv6CIDRBlocks []IPv6CIDRBlockAssociation `xml:"ipv6CidrBlockAssociationSet>item"`
+}
+
+// CIDRBlockAssociation describes the association of an IPv4 CIDR
+// block with a VPC. State is one of "associating", "associated",
+// "disassociating", "disassociated", "failing" or "failed".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VpcCidrBlockAssociation.html for more details.
+type CIDRBlockAssociation struct {
+	AssociationId string `xml:"associationId"`
+	CIDRBlock     string `xml:"cidrBlock"`
+	State         string `xml:"cidrBlockState>state"`
+	StatusMessage string `xml:"cidrBlockState>statusMessage"`
+}
+
+// IPv6CIDRBlockAssociation describes the association of an IPv6 CIDR
+// block with a VPC or a subnet. State is one of "associating",
+// "associated", "disassociating", "disassociated", "failing" or
+// "failed".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VpcIpv6CidrBlockAssociation.html for more details.
+type IPv6CIDRBlockAssociation struct {
+	AssociationId string `xml:"associationId"`
+	IPv6CIDRBlock string `xml:"ipv6CidrBlock"`
+	State         string `xml:"ipv6CidrBlockState>state"`
+	StatusMessage string `xml:"ipv6CidrBlockState>statusMessage"`
 }
 
 // CreateVPCResp is the response to a CreateVPC request.
@@ -82,6 +115,7 @@ func (ec2 *EC2) DeleteVPC(id string) (resp *SimpleResp, err error) {
 type VPCsResp struct {
 	RequestId string `xml:"requestId"`
 	VPCs      []VPC  `xml:"vpcSet>item"`
+	NextToken string `xml:"nextToken"`
 }
 
 // VPCs describes one or more VPCs. Both parameters are optional, and
@@ -90,11 +124,18 @@ type VPCsResp struct {
 //
 // See http://goo.gl/Y5kHqG for more details.
 func (ec2 *EC2) VPCs(ids []string, filter *Filter) (resp *VPCsResp, err error) {
+	return ec2.VPCsPage(ids, filter, 0, "")
+}
+
+// VPCsPage is like VPCs, but it returns a single page of results.
+// See InstancesPage for how pages are selected.
+func (ec2 *EC2) VPCsPage(ids []string, filter *Filter, maxResults int, nextToken string) (resp *VPCsResp, err error) {
 	params := makeParamsVPC("DescribeVpcs")
 	for i, id := range ids {
 		params["VpcId."+strconv.Itoa(i+1)] = id
 	}
 	filter.addParams(params)
+	addPageParams(params, maxResults, nextToken)
 
 	resp = &VPCsResp{}
 	err = ec2.query(params, resp)
@@ -103,3 +144,144 @@ func (ec2 *EC2) VPCs(ids []string, filter *Filter) (resp *VPCsResp, err error) {
 	}
 	return resp, nil
 }
+
+// AssociateVPCCIDRBlock encapsulates options for the
+// AssociateVPCCIDRBlock call.
+type AssociateVPCCIDRBlock struct {
+	// VPCId identifies the VPC. It is required.
+	VPCId string
+
+	// CIDRBlock holds a secondary IPv4 CIDR block to associate.
+	// It must not overlap the VPC's other CIDR blocks.
+	CIDRBlock string
+
+	// AmazonProvidedIPv6CIDRBlock requests an IPv6 CIDR block with
+	// a /56 prefix length from Amazon's pool of IPv6 addresses.
+	AmazonProvidedIPv6CIDRBlock bool
+}
+
+// AssociateVPCCIDRBlockResp is the response to an
+// AssociateVPCCIDRBlock request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateVpcCidrBlock.html for more details.
+type AssociateVPCCIDRBlockResp struct {
+	RequestId                string                   `xml:"requestId"`
+	VPCId                    string                   `xml:"vpcId"`
+	CIDRBlockAssociation     CIDRBlockAssociation     `xml:"cidrBlockAssociation"`
+	IPv6CIDRBlockAssociation IPv6CIDRBlockAssociation `xml:"ipv6CidrBlockAssociation"`
+}
+
+// AssociateVPCCIDRBlock associates a CIDR block with a VPC: either a
+// secondary IPv4 CIDR block, whose addresses can then be used by new
+// subnets of the VPC, or an IPv6 CIDR block. The IPv6 CIDR block can be used by the VPC's subnets, once they have
+// themselves been associated with a /64 part of it with
+// AssociateSubnetCIDRBlock.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AssociateVpcCidrBlock.html for more details.
+func (ec2 *EC2) AssociateVPCCIDRBlock(opts AssociateVPCCIDRBlock) (resp *AssociateVPCCIDRBlockResp, err error) {
+	params := makeParamsCurrent("AssociateVpcCidrBlock")
+	params["VpcId"] = opts.VPCId
+	if opts.CIDRBlock != "" {
+		params["CidrBlock"] = opts.CIDRBlock
+	}
+	if opts.AmazonProvidedIPv6CIDRBlock {
+		params["AmazonProvidedIpv6CidrBlock"] = "true"
+	}
+	resp = &AssociateVPCCIDRBlockResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DisassociateVPCCIDRBlockResp is the response to a
+// DisassociateVPCCIDRBlock request. Only the association of the
+// disassociated kind of CIDR block is set.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateVpcCidrBlock.html for more details.
+type DisassociateVPCCIDRBlockResp struct {
+	RequestId                string                   `xml:"requestId"`
+	VPCId                    string                   `xml:"vpcId"`
+	CIDRBlockAssociation     CIDRBlockAssociation     `xml:"cidrBlockAssociation"`
+	IPv6CIDRBlockAssociation IPv6CIDRBlockAssociation `xml:"ipv6CidrBlockAssociation"`
+}
+
+// DisassociateVPCCIDRBlock disassociates a secondary IPv4 CIDR block
+// or an IPv6 CIDR block from a VPC, given the id of its association.
+// The primary CIDR block of a VPC cannot be disassociated, and no
+// subnet may be using the disassociated block.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DisassociateVpcCidrBlock.html for more details.
+func (ec2 *EC2) DisassociateVPCCIDRBlock(associationId string) (resp *DisassociateVPCCIDRBlockResp, err error) {
+	params := makeParamsCurrent("DisassociateVpcCidrBlock")
+	params["AssociationId"] = associationId
+	resp = &DisassociateVPCCIDRBlockResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VPCAttributeResp is the response to a VPCAttribute request. Only
+// the field of the requested attribute is set.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcAttribute.html for more details.
+type VPCAttributeResp struct {
+	RequestId          string `xml:"requestId"`
+	VPCId              string `xml:"vpcId"`
+	EnableDNSSupport   bool   `xml:"enableDnsSupport>value"`
+	EnableDNSHostnames bool   `xml:"enableDnsHostnames>value"`
+}
+
+// VPCAttribute describes an attribute of the VPC with the given id:
+// either "enableDnsSupport" or "enableDnsHostnames".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcAttribute.html for more details.
+func (ec2 *EC2) VPCAttribute(vpcId, attribute string) (resp *VPCAttributeResp, err error) {
+	params := makeParamsCurrent("DescribeVpcAttribute")
+	params["VpcId"] = vpcId
+	params["Attribute"] = attribute
+	resp = &VPCAttributeResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ModifyVPCAttribute holds the changes of a ModifyVPCAttribute
+// request. Fields left nil are not changed. EC2 only changes one
+// attribute per request.
+//
+// EnableDNSSupport enables the Amazon provided DNS server for the
+// VPC, and EnableDNSHostnames makes instances launched in the VPC get
+// public DNS hostnames, which needs DNS support.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVpcAttribute.html for more details.
+type ModifyVPCAttribute struct {
+	EnableDNSSupport   *bool
+	EnableDNSHostnames *bool
+}
+
+// ModifyVPCAttribute modifies an attribute of the VPC with the given
+// id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_ModifyVpcAttribute.html for more details.
+func (ec2 *EC2) ModifyVPCAttribute(vpcId string, options *ModifyVPCAttribute) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("ModifyVpcAttribute")
+	params["VpcId"] = vpcId
+	if options.EnableDNSSupport != nil {
+		params["EnableDnsSupport.Value"] = strconv.FormatBool(*options.EnableDNSSupport)
+	}
+	if options.EnableDNSHostnames != nil {
+		params["EnableDnsHostnames.Value"] = strconv.FormatBool(*options.EnableDNSHostnames)
+	}
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -76,6 +76,86 @@ func (s *S) TestVPCsExample(c *C) {
 	c.Check(vpc.InstanceTenancy, Equals, "default")
 }
 
+func (s *S) TestAssociateVPCCIDRBlockIPv4Example(c *C) {
+	testServer.Response(200, nil, AssociateVpcCidrBlockIPv4Example)
+
+	resp, err := s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{
+		VPCId:     "vpc-a034d6c4",
+		CIDRBlock: "10.2.0.0/16",
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AssociateVpcCidrBlock"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-a034d6c4"})
+	c.Assert(req.Form["CidrBlock"], DeepEquals, []string{"10.2.0.0/16"})
+	c.Assert(req.Form["AmazonProvidedIpv6CidrBlock"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "8e4fb0ea-4fd1-4e46-a3c6-a8e9example")
+	c.Check(resp.VPCId, Equals, "vpc-a034d6c4")
+	c.Check(resp.CIDRBlockAssociation, DeepEquals, ec2.CIDRBlockAssociation{
+		AssociationId: "vpc-cidr-assoc-0280ab6b",
+		CIDRBlock:     "10.2.0.0/16",
+		State:         "associating",
+	})
+}
+
+func (s *S) TestDisassociateVPCCIDRBlockExample(c *C) {
+	testServer.Response(200, nil, DisassociateVpcCidrBlockExample)
+
+	resp, err := s.ec2.DisassociateVPCCIDRBlock("vpc-cidr-assoc-0280ab6b")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DisassociateVpcCidrBlock"})
+	c.Assert(req.Form["Version"], DeepEquals, []string{"2016-11-15"})
+	c.Assert(req.Form["AssociationId"], DeepEquals, []string{"vpc-cidr-assoc-0280ab6b"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "1e7a6fc6-0a02-4e5c-bd28-5f68example")
+	c.Check(resp.VPCId, Equals, "vpc-a034d6c4")
+	c.Check(resp.CIDRBlockAssociation, DeepEquals, ec2.CIDRBlockAssociation{
+		AssociationId: "vpc-cidr-assoc-0280ab6b",
+		CIDRBlock:     "10.2.0.0/16",
+		State:         "disassociating",
+	})
+	c.Check(resp.IPv6CIDRBlockAssociation, DeepEquals, ec2.IPv6CIDRBlockAssociation{})
+}
+
+func (s *S) TestVPCAttributeExample(c *C) {
+	testServer.Response(200, nil, DescribeVpcAttributeExample)
+
+	resp, err := s.ec2.VPCAttribute("vpc-1a2b3c4d", "enableDnsHostnames")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeVpcAttribute"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(req.Form["Attribute"], DeepEquals, []string{"enableDnsHostnames"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+	c.Check(resp.VPCId, Equals, "vpc-1a2b3c4d")
+	c.Check(resp.EnableDNSHostnames, Equals, true)
+	c.Check(resp.EnableDNSSupport, Equals, false)
+}
+
+func (s *S) TestModifyVPCAttributeExample(c *C) {
+	testServer.Response(200, nil, ModifyVpcAttributeExample)
+
+	enable := true
+	resp, err := s.ec2.ModifyVPCAttribute("vpc-1a2b3c4d", &ec2.ModifyVPCAttribute{
+		EnableDNSHostnames: &enable,
+	})
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"ModifyVpcAttribute"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(req.Form["EnableDnsHostnames.Value"], DeepEquals, []string{"true"})
+	c.Assert(req.Form["EnableDnsSupport.Value"], IsNil)
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.RequestId, Equals, "7a62c49f-347e-4fc4-9331-6e8eEXAMPLE")
+}
+
 // VPC tests to run against either a local test server or live on EC2.
 
 func (s *ServerTests) TestVPCs(c *C) {
@@ -196,3 +276,138 @@ func assertVPC(c *C, obtained ec2.VPC, expectId, expectCidr string) {
 	c.Check(obtained.Tags, HasLen, 0)
 	c.Check(obtained.InstanceTenancy, Matches, "(default|dedicated)")
 }
+
+// VPC tests run only against the local test server.
+
+func (s *LocalServerSuite) TestVPCCIDRBlocks(c *C) {
+	resp, err := s.ec2.CreateVPC("10.20.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := resp.VPC.Id
+	defer s.ec2.DeleteVPC(vpcId)
+	c.Assert(resp.VPC.CIDRBlocks, HasLen, 1)
+	primary := resp.VPC.CIDRBlocks[0]
+	c.Check(primary.AssociationId, Matches, "vpc-cidr-assoc-.+")
+	c.Check(primary.CIDRBlock, Equals, "10.20.0.0/16")
+	c.Check(primary.State, Equals, "associated")
+
+	for i, t := range []struct {
+		options ec2.AssociateVPCCIDRBlock
+		code    string
+	}{
+		{ec2.AssociateVPCCIDRBlock{CIDRBlock: "10.20.128.0/17"}, "CidrConflict"},
+		{ec2.AssociateVPCCIDRBlock{CIDRBlock: "10.0.0.0/8"}, "InvalidVpc.Range"},
+		{ec2.AssociateVPCCIDRBlock{CIDRBlock: "10.21.0.0/29"}, "InvalidVpc.Range"},
+		{ec2.AssociateVPCCIDRBlock{CIDRBlock: "10.21.0.0/16", AmazonProvidedIPv6CIDRBlock: true}, "InvalidParameterCombination"},
+	} {
+		t.options.VPCId = vpcId
+		_, err := s.ec2.AssociateVPCCIDRBlock(t.options)
+		c.Check(errorCode(err), Equals, t.code, Commentf("test %d", i))
+	}
+
+	assocResp, err := s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{
+		VPCId:     vpcId,
+		CIDRBlock: "10.21.0.0/16",
+	})
+	c.Assert(err, IsNil)
+	secondary := assocResp.CIDRBlockAssociation
+	c.Check(secondary.CIDRBlock, Equals, "10.21.0.0/16")
+	c.Check(secondary.State, Equals, "associated")
+
+	f := ec2.NewFilter()
+	f.Add("cidr-block-association.cidr-block", "10.21.0.0/16")
+	list, err := s.ec2.VPCs(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(list.VPCs, HasLen, 1)
+	c.Check(list.VPCs[0].CIDRBlock, Equals, "10.20.0.0/16")
+	c.Check(list.VPCs[0].CIDRBlocks, DeepEquals, []ec2.CIDRBlockAssociation{primary, secondary})
+
+	// Subnets may use any of the CIDR blocks, without overlapping.
+	sub, err := s.ec2.CreateSubnet(vpcId, "10.21.1.0/24", "")
+	c.Assert(err, IsNil)
+	_, err = s.ec2.CreateSubnet(vpcId, "10.21.1.128/25", "")
+	c.Check(errorCode(err), Equals, "InvalidSubnet.Conflict")
+	_, err = s.ec2.CreateSubnet(vpcId, "10.22.1.0/24", "")
+	c.Check(errorCode(err), Equals, "InvalidSubnet.Range")
+
+	_, err = s.ec2.DisassociateVPCCIDRBlock(secondary.AssociationId)
+	c.Check(errorCode(err), Equals, "DependencyViolation")
+	_, err = s.ec2.DeleteSubnet(sub.Subnet.Id)
+	c.Assert(err, IsNil)
+	disResp, err := s.ec2.DisassociateVPCCIDRBlock(secondary.AssociationId)
+	c.Assert(err, IsNil)
+	c.Check(disResp.VPCId, Equals, vpcId)
+	c.Check(disResp.CIDRBlockAssociation.CIDRBlock, Equals, "10.21.0.0/16")
+	c.Check(disResp.CIDRBlockAssociation.State, Equals, "disassociating")
+
+	_, err = s.ec2.DisassociateVPCCIDRBlock(secondary.AssociationId)
+	c.Check(errorCode(err), Equals, "InvalidVpcCidrBlockAssociationID.NotFound")
+	_, err = s.ec2.DisassociateVPCCIDRBlock(primary.AssociationId)
+	c.Check(errorCode(err), Equals, "OperationNotPermitted")
+
+	// The IPv6 CIDR block cannot be disassociated while a subnet
+	// uses it.
+	ipv6Resp, err := s.ec2.AssociateVPCCIDRBlock(ec2.AssociateVPCCIDRBlock{
+		VPCId:                       vpcId,
+		AmazonProvidedIPv6CIDRBlock: true,
+	})
+	c.Assert(err, IsNil)
+	ipv6Assoc := ipv6Resp.IPv6CIDRBlockAssociation
+	sub, err = s.ec2.CreateSubnet(vpcId, "10.20.1.0/24", "")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSubnet(sub.Subnet.Id)
+	subResp, err := s.ec2.AssociateSubnetCIDRBlock(sub.Subnet.Id, subnetIPv6Block(c, ipv6Assoc.IPv6CIDRBlock, 0))
+	c.Assert(err, IsNil)
+	_, err = s.ec2.DisassociateVPCCIDRBlock(ipv6Assoc.AssociationId)
+	c.Check(errorCode(err), Equals, "DependencyViolation")
+	_, err = s.ec2.DisassociateSubnetCIDRBlock(subResp.IPv6CIDRBlockAssociation.AssociationId)
+	c.Assert(err, IsNil)
+	disResp, err = s.ec2.DisassociateVPCCIDRBlock(ipv6Assoc.AssociationId)
+	c.Assert(err, IsNil)
+	c.Check(disResp.IPv6CIDRBlockAssociation.IPv6CIDRBlock, Equals, ipv6Assoc.IPv6CIDRBlock)
+	list, err = s.ec2.VPCs([]string{vpcId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(list.VPCs[0].IPv6CIDRBlocks, HasLen, 0)
+}
+
+func (s *LocalServerSuite) TestVPCAttributes(c *C) {
+	resp, err := s.ec2.CreateVPC("10.23.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := resp.VPC.Id
+	defer s.ec2.DeleteVPC(vpcId)
+
+	checkAttrs := func(support, hostnames bool) {
+		attr, err := s.ec2.VPCAttribute(vpcId, "enableDnsSupport")
+		c.Assert(err, IsNil)
+		c.Check(attr.VPCId, Equals, vpcId)
+		c.Check(attr.EnableDNSSupport, Equals, support)
+		attr, err = s.ec2.VPCAttribute(vpcId, "enableDnsHostnames")
+		c.Assert(err, IsNil)
+		c.Check(attr.EnableDNSHostnames, Equals, hostnames)
+	}
+	checkAttrs(true, false)
+	_, err = s.ec2.VPCAttribute(vpcId, "enableMagic")
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+
+	yes, no := true, false
+	_, err = s.ec2.ModifyVPCAttribute(vpcId, &ec2.ModifyVPCAttribute{})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+	_, err = s.ec2.ModifyVPCAttribute(vpcId, &ec2.ModifyVPCAttribute{
+		EnableDNSSupport:   &yes,
+		EnableDNSHostnames: &yes,
+	})
+	c.Check(errorCode(err), Equals, "InvalidParameterCombination")
+
+	_, err = s.ec2.ModifyVPCAttribute(vpcId, &ec2.ModifyVPCAttribute{EnableDNSHostnames: &yes})
+	c.Assert(err, IsNil)
+	checkAttrs(true, true)
+
+	// DNS hostnames need DNS support.
+	_, err = s.ec2.ModifyVPCAttribute(vpcId, &ec2.ModifyVPCAttribute{EnableDNSHostnames: &no})
+	c.Assert(err, IsNil)
+	_, err = s.ec2.ModifyVPCAttribute(vpcId, &ec2.ModifyVPCAttribute{EnableDNSSupport: &no})
+	c.Assert(err, IsNil)
+	checkAttrs(false, false)
+	_, err = s.ec2.ModifyVPCAttribute(vpcId, &ec2.ModifyVPCAttribute{EnableDNSHostnames: &yes})
+	c.Check(errorCode(err), Equals, "InvalidParameterValue")
+	checkAttrs(false, false)
+}
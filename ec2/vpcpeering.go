@@ -0,0 +1,163 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"strconv"
+)
+
+// VPCPeeringConnection describes a peering connection between two
+// VPCs. The connection is requested by the owner of RequesterVPC and
+// must be accepted by the owner of AccepterVPC.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VpcPeeringConnection.html for more details.
+type VPCPeeringConnection struct {
+	Id             string                     `xml:"vpcPeeringConnectionId"`
+	RequesterVPC   VPCPeeringConnectionVPC    `xml:"requesterVpcInfo"`
+	AccepterVPC    VPCPeeringConnectionVPC    `xml:"accepterVpcInfo"`
+	Status         VPCPeeringConnectionStatus `xml:"status"`
+	ExpirationTime string                     `xml:"expirationTime"`
+	Tags           []Tag                      `xml:"tagSet>item"`
+}
+
+// VPCPeeringConnectionVPC describes one of the VPCs of a peering
+// connection.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VpcPeeringConnectionVpcInfo.html for more details.
+type VPCPeeringConnectionVPC struct {
+	VPCId     string `xml:"vpcId"`
+	OwnerId   string `xml:"ownerId"`
+	CIDRBlock string `xml:"cidrBlock"`
+	Region    string `xml:"region"`
+}
+
+// VPCPeeringConnectionStatus describes the status of a peering
+// connection. Code is one of "initiating-request",
+// "pending-acceptance", "active", "deleted", "rejected", "failed",
+// "expired", "provisioning" or "deleting".
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_VpcPeeringConnectionStateReason.html for more details.
+type VPCPeeringConnectionStatus struct {
+	Code    string `xml:"code"`
+	Message string `xml:"message"`
+}
+
+// CreateVPCPeeringConnectionResp is the response to a
+// CreateVPCPeeringConnection request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVpcPeeringConnection.html for more details.
+type CreateVPCPeeringConnectionResp struct {
+	RequestId            string               `xml:"requestId"`
+	VPCPeeringConnection VPCPeeringConnection `xml:"vpcPeeringConnection"`
+}
+
+// CreateVPCPeeringConnection requests a peering connection between
+// the VPCs with the given ids. If peerOwnerId is empty, the peer VPC
+// must belong to the same account.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_CreateVpcPeeringConnection.html for more details.
+func (ec2 *EC2) CreateVPCPeeringConnection(vpcId, peerVPCId, peerOwnerId string) (resp *CreateVPCPeeringConnectionResp, err error) {
+	params := makeParamsCurrent("CreateVpcPeeringConnection")
+	params["VpcId"] = vpcId
+	params["PeerVpcId"] = peerVPCId
+	if peerOwnerId != "" {
+		params["PeerOwnerId"] = peerOwnerId
+	}
+	resp = &CreateVPCPeeringConnectionResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AcceptVPCPeeringConnectionResp is the response to an
+// AcceptVPCPeeringConnection request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AcceptVpcPeeringConnection.html for more details.
+type AcceptVPCPeeringConnectionResp struct {
+	RequestId            string               `xml:"requestId"`
+	VPCPeeringConnection VPCPeeringConnection `xml:"vpcPeeringConnection"`
+}
+
+// AcceptVPCPeeringConnection accepts the peering connection request
+// with the given id, which must be in the "pending-acceptance"
+// state.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_AcceptVpcPeeringConnection.html for more details.
+func (ec2 *EC2) AcceptVPCPeeringConnection(id string) (resp *AcceptVPCPeeringConnectionResp, err error) {
+	params := makeParamsCurrent("AcceptVpcPeeringConnection")
+	params["VpcPeeringConnectionId"] = id
+	resp = &AcceptVPCPeeringConnectionResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RejectVPCPeeringConnection rejects the peering connection request
+// with the given id, which must be in the "pending-acceptance"
+// state.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_RejectVpcPeeringConnection.html for more details.
+func (ec2 *EC2) RejectVPCPeeringConnection(id string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("RejectVpcPeeringConnection")
+	params["VpcPeeringConnectionId"] = id
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteVPCPeeringConnection deletes the peering connection with the
+// given id.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DeleteVpcPeeringConnection.html for more details.
+func (ec2 *EC2) DeleteVPCPeeringConnection(id string) (resp *SimpleResp, err error) {
+	params := makeParamsCurrent("DeleteVpcPeeringConnection")
+	params["VpcPeeringConnectionId"] = id
+	resp = &SimpleResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// VPCPeeringConnectionsResp is the response to a
+// VPCPeeringConnections request.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcPeeringConnections.html for more details.
+type VPCPeeringConnectionsResp struct {
+	RequestId             string                 `xml:"requestId"`
+	VPCPeeringConnections []VPCPeeringConnection `xml:"vpcPeeringConnectionSet>item"`
+}
+
+// VPCPeeringConnections describes one or more peering connections.
+// Both parameters are optional, and if specified will limit the
+// returned connections to the matching ids or filtering rules.
+//
+// See http://docs.aws.amazon.com/AWSEC2/latest/APIReference/API_DescribeVpcPeeringConnections.html for more details.
+func (ec2 *EC2) VPCPeeringConnections(ids []string, filter *Filter) (resp *VPCPeeringConnectionsResp, err error) {
+	params := makeParamsCurrent("DescribeVpcPeeringConnections")
+	for i, id := range ids {
+		params["VpcPeeringConnectionId."+strconv.Itoa(i+1)] = id
+	}
+	filter.addParams(params)
+
+	resp = &VPCPeeringConnectionsResp{}
+	err = ec2.query(params, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,169 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/ec2"
+)
+
+// VPC peering tests with example responses
+
+func (s *S) TestCreateVPCPeeringConnectionExample(c *C) {
+	testServer.Response(200, nil, CreateVpcPeeringConnectionExample)
+
+	resp, err := s.ec2.CreateVPCPeeringConnection("vpc-1a2b3c4d", "vpc-a1b2c3d4", "123456789012")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"CreateVpcPeeringConnection"})
+	c.Assert(req.Form["VpcId"], DeepEquals, []string{"vpc-1a2b3c4d"})
+	c.Assert(req.Form["PeerVpcId"], DeepEquals, []string{"vpc-a1b2c3d4"})
+	c.Assert(req.Form["PeerOwnerId"], DeepEquals, []string{"123456789012"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.VPCPeeringConnection, DeepEquals, ec2.VPCPeeringConnection{
+		Id: "pcx-73a5401a",
+		RequesterVPC: ec2.VPCPeeringConnectionVPC{
+			VPCId:     "vpc-1a2b3c4d",
+			OwnerId:   "777788889999",
+			CIDRBlock: "10.0.0.0/28",
+		},
+		AccepterVPC: ec2.VPCPeeringConnectionVPC{
+			VPCId:   "vpc-a1b2c3d4",
+			OwnerId: "123456789012",
+		},
+		Status: ec2.VPCPeeringConnectionStatus{
+			Code:    "initiating-request",
+			Message: "Initiating Request to 123456789012",
+		},
+		ExpirationTime: "2014-02-18T14:37:25.000Z",
+	})
+}
+
+func (s *S) TestCreateVPCPeeringConnectionOwnAccountExample(c *C) {
+	testServer.Response(200, nil, CreateVpcPeeringConnectionExample)
+
+	_, err := s.ec2.CreateVPCPeeringConnection("vpc-1a2b3c4d", "vpc-a1b2c3d4", "")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["PeerOwnerId"], IsNil)
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestAcceptVPCPeeringConnectionExample(c *C) {
+	testServer.Response(200, nil, AcceptVpcPeeringConnectionExample)
+
+	resp, err := s.ec2.AcceptVPCPeeringConnection("pcx-1a2b3c4d")
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"AcceptVpcPeeringConnection"})
+	c.Assert(req.Form["VpcPeeringConnectionId"], DeepEquals, []string{"pcx-1a2b3c4d"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.VPCPeeringConnection.Status.Code, Equals, "active")
+	c.Assert(resp.VPCPeeringConnection.AccepterVPC.CIDRBlock, Equals, "10.0.1.0/28")
+}
+
+func (s *S) TestVPCPeeringConnectionsExample(c *C) {
+	testServer.Response(200, nil, DescribeVpcPeeringConnectionsExample)
+
+	filter := ec2.NewFilter()
+	filter.Add("status-code", "pending-acceptance")
+	resp, err := s.ec2.VPCPeeringConnections([]string{"pcx-111aaa22"}, filter)
+	req := testServer.WaitRequest()
+
+	c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeVpcPeeringConnections"})
+	c.Assert(req.Form["VpcPeeringConnectionId.1"], DeepEquals, []string{"pcx-111aaa22"})
+	c.Assert(req.Form["Filter.1.Name"], DeepEquals, []string{"status-code"})
+	c.Assert(req.Form["Filter.1.Value.1"], DeepEquals, []string{"pending-acceptance"})
+
+	c.Assert(err, IsNil)
+	c.Assert(resp.VPCPeeringConnections, HasLen, 1)
+	p := resp.VPCPeeringConnections[0]
+	c.Assert(p.Id, Equals, "pcx-111aaa22")
+	c.Assert(p.RequesterVPC.CIDRBlock, Equals, "172.31.0.0/16")
+	c.Assert(p.AccepterVPC.OwnerId, Equals, "111122223333")
+	c.Assert(p.Status.Message, Equals, "Pending Acceptance by 111122223333")
+}
+
+// VPC peering tests run against either a local test server or live
+// on EC2.
+
+func (s *ServerTests) TestVPCPeeringConnections(c *C) {
+	vpc1, err := s.ec2.CreateVPC("10.14.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpc2, err := s.ec2.CreateVPC("10.15.0.0/16", "")
+	c.Assert(err, IsNil)
+	defer s.deleteVPCs(c, []string{vpc1.VPC.Id, vpc2.VPC.Id})
+
+	created, err := s.ec2.CreateVPCPeeringConnection(vpc1.VPC.Id, vpc2.VPC.Id, "")
+	c.Assert(err, IsNil)
+	pcxId := created.VPCPeeringConnection.Id
+	defer s.ec2.DeleteVPCPeeringConnection(pcxId)
+	c.Check(pcxId, Matches, "pcx-.+")
+	c.Check(created.VPCPeeringConnection.RequesterVPC.VPCId, Equals, vpc1.VPC.Id)
+	c.Check(created.VPCPeeringConnection.AccepterVPC.VPCId, Equals, vpc2.VPC.Id)
+
+	accepted, err := s.ec2.AcceptVPCPeeringConnection(pcxId)
+	c.Assert(err, IsNil)
+	c.Check(accepted.VPCPeeringConnection.Status.Code, Equals, "active")
+
+	_, err = s.ec2.RejectVPCPeeringConnection(pcxId)
+	c.Check(errorCode(err), Equals, "InvalidStateTransition")
+
+	f := ec2.NewFilter()
+	f.Add("requester-vpc-info.vpc-id", vpc1.VPC.Id)
+	resp, err := s.ec2.VPCPeeringConnections(nil, f)
+	c.Assert(err, IsNil)
+	c.Assert(resp.VPCPeeringConnections, HasLen, 1)
+	p := resp.VPCPeeringConnections[0]
+	c.Check(p.Id, Equals, pcxId)
+	c.Check(p.Status.Code, Equals, "active")
+	c.Check(p.AccepterVPC.CIDRBlock, Equals, "10.15.0.0/16")
+
+	_, err = s.ec2.DeleteVPCPeeringConnection(pcxId)
+	c.Assert(err, IsNil)
+	resp, err = s.ec2.VPCPeeringConnections([]string{pcxId}, nil)
+	c.Assert(err, IsNil)
+	c.Check(resp.VPCPeeringConnections[0].Status.Code, Equals, "deleted")
+}
+
+func (s *LocalServerSuite) TestVPCPeeringConnectionStates(c *C) {
+	vpc1, err := s.ec2.CreateVPC("10.16.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpc2, err := s.ec2.CreateVPC("10.16.128.0/17", "")
+	c.Assert(err, IsNil)
+
+	// Peering overlapping VPCs fails.
+	created, err := s.ec2.CreateVPCPeeringConnection(vpc1.VPC.Id, vpc2.VPC.Id, "")
+	c.Assert(err, IsNil)
+	c.Check(created.VPCPeeringConnection.Status, DeepEquals, ec2.VPCPeeringConnectionStatus{
+		Code:    "failed",
+		Message: "Overlapping CIDR range",
+	})
+	_, err = s.ec2.AcceptVPCPeeringConnection(created.VPCPeeringConnection.Id)
+	c.Check(errorCode(err), Equals, "InvalidStateTransition")
+
+	_, err = s.ec2.CreateVPCPeeringConnection(vpc1.VPC.Id, "vpc-999", "")
+	c.Check(errorCode(err), Equals, "InvalidVpcID.NotFound")
+
+	// Connections to other accounts can only be accepted by them.
+	created, err = s.ec2.CreateVPCPeeringConnection(vpc1.VPC.Id, "vpc-a1b2c3d4", "123456789012")
+	c.Assert(err, IsNil)
+	pcxId := created.VPCPeeringConnection.Id
+	c.Check(created.VPCPeeringConnection.Status.Code, Equals, "pending-acceptance")
+	_, err = s.ec2.AcceptVPCPeeringConnection(pcxId)
+	c.Check(errorCode(err), Equals, "OperationNotPermitted")
+	_, err = s.ec2.DeleteVPCPeeringConnection(pcxId)
+	c.Assert(err, IsNil)
+
+	_, err = s.ec2.VPCPeeringConnections([]string{"pcx-999"}, nil)
+	c.Check(errorCode(err), Equals, "InvalidVpcPeeringConnectionID.NotFound")
+}
@@ -0,0 +1,286 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/amz.v1/aws"
+)
+
+// DefaultWaitStrategy is a reasonable strategy to pass to the Wait
+// methods: most resources reach their awaited state within minutes.
+var DefaultWaitStrategy = aws.AttemptStrategy{
+	Total: 10 * time.Minute,
+	Delay: 5 * time.Second,
+}
+
+// WaitTimeoutError is returned by the Wait methods when a resource
+// does not reach the awaited state before the attempts of the wait
+// strategy are exhausted.
+type WaitTimeoutError struct {
+	// ResourceId holds the id of the awaited resource.
+	ResourceId string
+
+	// Want holds the awaited state.
+	Want string
+
+	// State holds the last observed state of the resource. It
+	// is empty if the resource was never found.
+	State string
+}
+
+func (err *WaitTimeoutError) Error() string {
+	if err.State == "" {
+		return fmt.Sprintf("timed out waiting for %s to be %s: not found", err.ResourceId, err.Want)
+	}
+	return fmt.Sprintf("timed out waiting for %s to be %s: last state %q", err.ResourceId, err.Want, err.State)
+}
+
+// WaitStateError is returned by the Wait methods when a resource
+// reaches a state from which the awaited state cannot be reached,
+// such as "terminated" when waiting for an instance to be running.
+type WaitStateError struct {
+	// ResourceId holds the id of the awaited resource.
+	ResourceId string
+
+	// Want holds the awaited state.
+	Want string
+
+	// State holds the state the resource reached.
+	State string
+}
+
+func (err *WaitStateError) Error() string {
+	return fmt.Sprintf("%s is %s; it will not be %s", err.ResourceId, err.State, err.Want)
+}
+
+// waiter describes how to wait for a resource to reach a state.
+type waiter struct {
+	id   string
+	want string
+
+	// failed holds the states from which want cannot be reached.
+	failed []string
+
+	// notFound holds the error code returned when the resource
+	// does not exist, which is only assumed to be temporary,
+	// because of eventual consistency.
+	notFound string
+
+	// gone, if not empty, is the state the resource is considered
+	// to be in once it does not exist.
+	gone string
+
+	// state returns the current state of the resource.
+	state func() (string, error)
+}
+
+// wait polls the state of the resource until it is the awaited one.
+func (w *waiter) wait(strategy aws.AttemptStrategy) error {
+	var last string
+	for a := strategy.Start(); a.Next(); {
+		state, err := w.state()
+		if err, ok := err.(*Error); ok && err.Code == w.notFound {
+			if w.gone == "" {
+				continue
+			}
+			state = w.gone
+		} else if err != nil {
+			return err
+		}
+		if state == w.want {
+			return nil
+		}
+		for _, f := range w.failed {
+			if state == f {
+				return &WaitStateError{ResourceId: w.id, Want: w.want, State: state}
+			}
+		}
+		last = state
+	}
+	return &WaitTimeoutError{ResourceId: w.id, Want: w.want, State: last}
+}
+
+// instanceState returns the name of the state of the instance with
+// the given id.
+func (ec2 *EC2) instanceState(id string) (string, error) {
+	resp, err := ec2.Instances([]string{id}, nil)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range resp.Reservations {
+		for _, inst := range r.Instances {
+			if inst.InstanceId == id {
+				return inst.State.Name, nil
+			}
+		}
+	}
+	return "", &Error{Code: "InvalidInstanceID.NotFound"}
+}
+
+func (ec2 *EC2) waitInstance(id, want string, failed []string, gone string, strategy aws.AttemptStrategy) error {
+	w := &waiter{
+		id:       id,
+		want:     want,
+		failed:   failed,
+		notFound: "InvalidInstanceID.NotFound",
+		gone:     gone,
+		state:    func() (string, error) { return ec2.instanceState(id) },
+	}
+	return w.wait(strategy)
+}
+
+// WaitInstanceRunning waits until the instance with the given id is
+// running, polling its state as decided by strategy.
+// It returns a *WaitTimeoutError if the instance is not running in
+// time, and a *WaitStateError if it is stopping or terminating.
+func (ec2 *EC2) WaitInstanceRunning(id string, strategy aws.AttemptStrategy) error {
+	return ec2.waitInstance(id, "running", []string{"shutting-down", "terminated", "stopping"}, "", strategy)
+}
+
+// WaitInstanceStopped waits until the instance with the given id is
+// stopped, polling its state as decided by strategy.
+// It returns a *WaitTimeoutError if the instance is not stopped in
+// time, and a *WaitStateError if it is starting or terminating.
+func (ec2 *EC2) WaitInstanceStopped(id string, strategy aws.AttemptStrategy) error {
+	return ec2.waitInstance(id, "stopped", []string{"pending", "shutting-down", "terminated"}, "", strategy)
+}
+
+// WaitInstanceTerminated waits until the instance with the given id
+// is terminated, polling its state as decided by strategy. An
+// instance that no longer exists is considered terminated.
+// It returns a *WaitTimeoutError if the instance is not terminated in
+// time, and a *WaitStateError if it is starting or stopping.
+func (ec2 *EC2) WaitInstanceTerminated(id string, strategy aws.AttemptStrategy) error {
+	return ec2.waitInstance(id, "terminated", []string{"pending", "stopping"}, "terminated", strategy)
+}
+
+// WaitSnapshotCompleted waits until the snapshot with the given id is
+// completed, polling its status as decided by strategy.
+// It returns a *WaitTimeoutError if the snapshot is not completed in
+// time, and a *WaitStateError if it fails.
+func (ec2 *EC2) WaitSnapshotCompleted(id string, strategy aws.AttemptStrategy) error {
+	w := &waiter{
+		id:       id,
+		want:     "completed",
+		failed:   []string{"error"},
+		notFound: "InvalidSnapshot.NotFound",
+		state: func() (string, error) {
+			resp, err := ec2.Snapshots([]string{id}, nil)
+			if err != nil {
+				return "", err
+			}
+			for _, s := range resp.Snapshots {
+				if s.Id == id {
+					return s.Status, nil
+				}
+			}
+			return "", &Error{Code: "InvalidSnapshot.NotFound"}
+		},
+	}
+	return w.wait(strategy)
+}
+
+func (ec2 *EC2) waitVolume(id, want string, strategy aws.AttemptStrategy) error {
+	w := &waiter{
+		id:       id,
+		want:     want,
+		failed:   []string{"deleting", "deleted", "error"},
+		notFound: "InvalidVolume.NotFound",
+		state: func() (string, error) {
+			resp, err := ec2.Volumes([]string{id}, nil)
+			if err != nil {
+				return "", err
+			}
+			for _, v := range resp.Volumes {
+				if v.Id == id {
+					return v.Status, nil
+				}
+			}
+			return "", &Error{Code: "InvalidVolume.NotFound"}
+		},
+	}
+	return w.wait(strategy)
+}
+
+// WaitVolumeAvailable waits until the volume with the given id is
+// available, polling its status as decided by strategy.
+// It returns a *WaitTimeoutError if the volume is not available in
+// time, and a *WaitStateError if it is deleted or fails.
+func (ec2 *EC2) WaitVolumeAvailable(id string, strategy aws.AttemptStrategy) error {
+	return ec2.waitVolume(id, "available", strategy)
+}
+
+// WaitVolumeInUse waits until the volume with the given id is
+// attached to an instance, polling its status as decided by
+// strategy.
+// It returns a *WaitTimeoutError if the volume is not in use in
+// time, and a *WaitStateError if it is deleted or fails.
+func (ec2 *EC2) WaitVolumeInUse(id string, strategy aws.AttemptStrategy) error {
+	return ec2.waitVolume(id, "in-use", strategy)
+}
+
+// WaitImageAvailable waits until the image with the given id is
+// available, polling its state as decided by strategy.
+// It returns a *WaitTimeoutError if the image is not available in
+// time, and a *WaitStateError if it fails or is deregistered.
+func (ec2 *EC2) WaitImageAvailable(id string, strategy aws.AttemptStrategy) error {
+	w := &waiter{
+		id:       id,
+		want:     "available",
+		failed:   []string{"failed", "deregistered", "error"},
+		notFound: "InvalidAMIID.NotFound",
+		state: func() (string, error) {
+			resp, err := ec2.Images([]string{id}, nil)
+			if err != nil {
+				return "", err
+			}
+			for _, image := range resp.Images {
+				if image.Id == id {
+					return image.State, nil
+				}
+			}
+			return "", &Error{Code: "InvalidAMIID.NotFound"}
+		},
+	}
+	return w.wait(strategy)
+}
+
+// WaitInterfaceAttached waits until the network interface with the
+// given id is attached to an instance, polling the status of its
+// attachment as decided by strategy. The state of an interface
+// without attachment is reported as "detached".
+// It returns a *WaitTimeoutError if the interface is not attached
+// in time.
+func (ec2 *EC2) WaitInterfaceAttached(id string, strategy aws.AttemptStrategy) error {
+	w := &waiter{
+		id:       id,
+		want:     "attached",
+		notFound: "InvalidNetworkInterfaceID.NotFound",
+		state: func() (string, error) {
+			resp, err := ec2.NetworkInterfaces([]string{id}, nil)
+			if err != nil {
+				return "", err
+			}
+			for _, iface := range resp.Interfaces {
+				if iface.Id != id {
+					continue
+				}
+				if iface.Attachment.Id == "" {
+					return "detached", nil
+				}
+				return iface.Attachment.Status, nil
+			}
+			return "", &Error{Code: "InvalidNetworkInterfaceID.NotFound"}
+		},
+	}
+	return w.wait(strategy)
+}
@@ -0,0 +1,148 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+//   https://wiki.ubuntu.com/goamz
+//
+// Copyright (c) 2014 Canonical Ltd.
+//
+
+package ec2_test
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/aws"
+	"gopkg.in/amz.v1/ec2"
+	"gopkg.in/amz.v1/ec2/ec2test"
+)
+
+// shortWait is a wait strategy for the tests, which must not be
+// used against live EC2.
+var shortWait = aws.AttemptStrategy{
+	Total: 50 * time.Millisecond,
+	Delay: 5 * time.Millisecond,
+}
+
+// Waiter tests with example responses
+
+func (s *S) TestWaitImageAvailableExample(c *C) {
+	testServer.Response(200, nil, strings.Replace(DescribeImagesExample, "available", "pending", 1))
+	testServer.Response(200, nil, DescribeImagesExample)
+
+	err := s.ec2.WaitImageAvailable("ami-a2469acf", aws.AttemptStrategy{Min: 2})
+	reqs := testServer.WaitRequests(2)
+
+	c.Assert(err, IsNil)
+	for _, req := range reqs {
+		c.Assert(req.Form["Action"], DeepEquals, []string{"DescribeImages"})
+		c.Assert(req.Form["ImageId.1"], DeepEquals, []string{"ami-a2469acf"})
+	}
+}
+
+func (s *S) TestWaitImageAvailableFailedExample(c *C) {
+	testServer.Response(200, nil, strings.Replace(DescribeImagesExample, "available", "failed", 1))
+
+	err := s.ec2.WaitImageAvailable("ami-a2469acf", aws.AttemptStrategy{Min: 2})
+	testServer.WaitRequest()
+
+	c.Assert(err, DeepEquals, &ec2.WaitStateError{
+		ResourceId: "ami-a2469acf",
+		Want:       "available",
+		State:      "failed",
+	})
+	c.Assert(err, ErrorMatches, `ami-a2469acf is failed; it will not be available`)
+}
+
+// Waiter tests run only against the local test server, which
+// controls the time resources take to change state.
+
+func (s *LocalServerSuite) TestWaitInstance(c *C) {
+	clock := ec2test.NewClock(time.Now())
+	s.srv.srv.SetTransitions(clock, time.Minute)
+	defer s.srv.srv.SetTransitions(nil, 0)
+
+	inst, err := s.ec2.RunInstances(&ec2.RunInstances{
+		ImageId:      imageId,
+		InstanceType: "t1.micro",
+	})
+	c.Assert(err, IsNil)
+	id := inst.Instances[0].InstanceId
+	defer terminateInstances(c, s.ec2, []string{id})
+
+	err = s.ec2.WaitInstanceRunning(id, shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: id, Want: "running", State: "pending"})
+	c.Assert(err, ErrorMatches, `timed out waiting for i-[0-9]+ to be running: last state "pending"`)
+	clock.Advance(time.Minute)
+	c.Assert(s.ec2.WaitInstanceRunning(id, shortWait), IsNil)
+
+	_, err = s.ec2.StopInstances(id)
+	c.Assert(err, IsNil)
+	err = s.ec2.WaitInstanceRunning(id, shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitStateError{ResourceId: id, Want: "running", State: "stopping"})
+	err = s.ec2.WaitInstanceStopped(id, shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: id, Want: "stopped", State: "stopping"})
+	clock.Advance(time.Minute)
+	c.Assert(s.ec2.WaitInstanceStopped(id, shortWait), IsNil)
+
+	_, err = s.ec2.TerminateInstances([]string{id})
+	c.Assert(err, IsNil)
+	err = s.ec2.WaitInstanceTerminated(id, shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: id, Want: "terminated", State: "shutting-down"})
+	clock.Advance(time.Minute)
+	c.Assert(s.ec2.WaitInstanceTerminated(id, shortWait), IsNil)
+
+	// Unknown instances are waited for, as they may not be
+	// visible yet, except when waiting for termination.
+	err = s.ec2.WaitInstanceRunning("i-999", shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: "i-999", Want: "running"})
+	c.Assert(err, ErrorMatches, `timed out waiting for i-999 to be running: not found`)
+	c.Assert(s.ec2.WaitInstanceTerminated("i-999", shortWait), IsNil)
+}
+
+func (s *LocalServerSuite) TestWaitVolumeAndSnapshot(c *C) {
+	ids := s.srv.srv.NewInstances(1, "t1.micro", imageId, ec2test.Running, nil)
+	defer terminateInstances(c, s.ec2, ids)
+
+	resp, err := s.ec2.CreateVolume(&ec2.CreateVolume{AvailZone: "us-east-1a", Size: 1})
+	c.Assert(err, IsNil)
+	volId := resp.Id
+	defer s.ec2.DeleteVolume(volId)
+
+	c.Assert(s.ec2.WaitVolumeAvailable(volId, shortWait), IsNil)
+	err = s.ec2.WaitVolumeInUse(volId, shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: volId, Want: "in-use", State: "available"})
+
+	_, err = s.ec2.AttachVolume(volId, ids[0], "/dev/sdh")
+	c.Assert(err, IsNil)
+	c.Assert(s.ec2.WaitVolumeInUse(volId, shortWait), IsNil)
+	_, err = s.ec2.DetachVolume(volId, "", "", false)
+	c.Assert(err, IsNil)
+
+	snap, err := s.ec2.CreateSnapshot(volId, "waiter test")
+	c.Assert(err, IsNil)
+	defer s.ec2.DeleteSnapshots([]string{snap.Id})
+	c.Assert(s.ec2.WaitSnapshotCompleted(snap.Id, shortWait), IsNil)
+}
+
+func (s *LocalServerSuite) TestWaitInterfaceAttached(c *C) {
+	vpcResp, err := s.ec2.CreateVPC("10.12.0.0/16", "")
+	c.Assert(err, IsNil)
+	vpcId := vpcResp.VPC.Id
+	subId := s.createSubnet(c, vpcId, "10.12.1.0/24", "").Subnet.Id
+	ids := s.srv.srv.NewInstancesVPC(vpcId, subId, 1, "t1.micro", imageId, ec2test.Running, nil)
+	defer terminateInstances(c, s.ec2, ids)
+
+	created, err := s.ec2.CreateNetworkInterface(ec2.CreateNetworkInterface{SubnetId: subId})
+	c.Assert(err, IsNil)
+	ifaceId := created.NetworkInterface.Id
+
+	err = s.ec2.WaitInterfaceAttached(ifaceId, shortWait)
+	c.Assert(err, DeepEquals, &ec2.WaitTimeoutError{ResourceId: ifaceId, Want: "attached", State: "detached"})
+
+	_, err = s.ec2.AttachNetworkInterface(ifaceId, ids[0], 1)
+	c.Assert(err, IsNil)
+	c.Assert(s.ec2.WaitInterfaceAttached(ifaceId, shortWait), IsNil)
+}
@@ -11,6 +11,7 @@ import (
 	"gopkg.in/amz.v1/aws"
 	"gopkg.in/amz.v1/iam"
 	"gopkg.in/amz.v1/iam/iamtest"
+	"gopkg.in/amz.v1/testutil/faults"
 )
 
 // LocalServer represents a local ec2test fake server.
@@ -43,3 +44,18 @@ func (s *LocalServerSuite) SetUpSuite(c *C) {
 	s.srv.SetUp(c)
 	s.ClientTests.iam = iam.New(s.srv.auth, s.srv.region)
 }
+
+func (s *LocalServerSuite) TestFaults(c *C) {
+	inj := s.srv.srv.Faults()
+	defer inj.Reset()
+	inj.Script("CreateUser", faults.InternalError)
+	_, err := s.iam.CreateUser("faulty", "/")
+	iamErr, ok := err.(*iam.Error)
+	c.Assert(ok, Equals, true)
+	c.Assert(iamErr.StatusCode, Equals, 500)
+	c.Assert(iamErr.Code, Equals, "InternalError")
+
+	// The failed request was not carried out.
+	_, err = s.iam.GetUser("faulty")
+	c.Assert(err, NotNil)
+}
@@ -16,8 +16,10 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"gopkg.in/amz.v1/iam"
+	"gopkg.in/amz.v1/testutil/faults"
 )
 
 type action struct {
@@ -36,6 +38,7 @@ type Server struct {
 	groups       []iam.Group
 	accessKeys   []iam.AccessKey
 	userPolicies []iam.UserPolicy
+	faults       *faults.Injector
 	mutex        sync.Mutex
 }
 
@@ -47,6 +50,7 @@ func NewServer() (*Server, error) {
 	srv := &Server{
 		listener: l,
 		url:      "http://" + l.Addr().String(),
+		faults:   faults.NewInjector(),
 	}
 	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		srv.serveHTTP(w, req)
@@ -64,6 +68,12 @@ func (srv *Server) URL() string {
 	return srv.url
 }
 
+// Faults returns the injector deciding the faults injected into
+// requests, which are chosen by IAM action name.
+func (srv *Server) Faults() *faults.Injector {
+	return srv.faults
+}
+
 type xmlErrors struct {
 	XMLName string `xml:"ErrorResponse"`
 	Error   iam.Error
@@ -79,9 +89,28 @@ func (srv *Server) error(w http.ResponseWriter, err *iam.Error) {
 
 func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 	req.ParseForm()
+	action := req.FormValue("Action")
+	fault := srv.faults.Next(action)
+	time.Sleep(fault.Delay)
+	if fault.Drop {
+		faults.Drop(w)
+		return
+	}
+	if fault.Truncate {
+		tw := faults.NewTruncatingWriter(w)
+		defer tw.Close()
+		w = tw
+	}
+	if fault.IsError() {
+		srv.error(w, &iam.Error{
+			StatusCode: fault.StatusCode,
+			Code:       fault.Code,
+			Message:    fault.Message,
+		})
+		return
+	}
 	srv.mutex.Lock()
 	defer srv.mutex.Unlock()
-	action := req.FormValue("Action")
 	if action == "" {
 		srv.error(w, &iam.Error{
 			StatusCode: 400,
@@ -402,10 +402,16 @@ func (s3 *S3) query(req *request, resp interface{}) error {
 	if err != nil {
 		return err
 	}
+	// Read the whole body before decoding it, so that a response
+	// cut short is reported as an error that may be retried.
+	data, err := ioutil.ReadAll(hresp.Body)
+	hresp.Body.Close()
+	if err != nil {
+		return err
+	}
 	if resp != nil {
-		err = xml.NewDecoder(hresp.Body).Decode(resp)
+		err = xml.Unmarshal(data, resp)
 	}
-	hresp.Body.Close()
 	return nil
 }
 
@@ -551,6 +557,8 @@ func shouldRetry(err error) bool {
 		return true
 	}
 	switch e := err.(type) {
+	case *url.Error:
+		return shouldRetry(e.Err)
 	case *net.DNSError:
 		return true
 	case *net.OpError:
@@ -560,7 +568,7 @@ func shouldRetry(err error) bool {
 		}
 	case *Error:
 		switch e.Code {
-		case "InternalError", "NoSuchUpload", "NoSuchBucket":
+		case "InternalError", "SlowDown", "NoSuchUpload", "NoSuchBucket":
 			return true
 		}
 	}
@@ -6,11 +6,19 @@
 package s3_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
 	. "gopkg.in/check.v1"
 
 	"gopkg.in/amz.v1/aws"
 	"gopkg.in/amz.v1/s3"
 	"gopkg.in/amz.v1/s3/s3test"
+	"gopkg.in/amz.v1/testutil/faults"
 )
 
 type LocalServer struct {
@@ -84,3 +92,403 @@ func (s *LocalServerSuite) TestBucketList(c *C) {
 func (s *LocalServerSuite) TestDoublePutBucket(c *C) {
 	s.clientTests.TestDoublePutBucket(c)
 }
+
+// DiskServerSuite runs tests against an s3test server
+// keeping its data on disk.
+type DiskServerSuite struct {
+	srv         LocalServer
+	dir         string
+	clientTests ClientTests
+}
+
+var _ = Suite(&DiskServerSuite{})
+
+func (s *DiskServerSuite) SetUpSuite(c *C) {
+	s.dir = c.MkDir()
+	s.startServer(c)
+}
+
+func (s *DiskServerSuite) TearDownSuite(c *C) {
+	s.srv.srv.Quit()
+}
+
+func (s *DiskServerSuite) startServer(c *C) {
+	storage, err := s3test.NewDiskStorage(s.dir)
+	c.Assert(err, IsNil)
+	s.srv.config = &s3test.Config{Storage: storage}
+	s.srv.SetUp(c)
+	s.clientTests.s3 = s3.New(s.srv.auth, s.srv.region)
+	s.clientTests.authIsBroken = true
+}
+
+func (s *DiskServerSuite) TearDownTest(c *C) {
+	s.clientTests.Cleanup()
+}
+
+func (s *DiskServerSuite) TestBasicFunctionality(c *C) {
+	s.clientTests.TestBasicFunctionality(c)
+}
+
+func (s *DiskServerSuite) TestBucketList(c *C) {
+	s.clientTests.TestBucketList(c)
+}
+
+func (s *DiskServerSuite) TestDataSurvivesRestart(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("dir/name", []byte("hello"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+
+	s.srv.srv.Quit()
+	s.startServer(c)
+	b = testBucket(s.clientTests.s3)
+
+	data, err := b.Get("dir/name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "hello")
+	resp, err := b.List("", "", "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Contents, HasLen, 1)
+	c.Assert(resp.Contents[0].Key, Equals, "dir/name")
+	c.Assert(resp.Contents[0].Size, Equals, int64(5))
+
+	err = b.Del("dir/name")
+	c.Assert(err, IsNil)
+	err = b.DelBucket()
+	c.Assert(err, IsNil)
+	s.srv.srv.Quit()
+	s.startServer(c)
+	s3.SetAttemptStrategy(&aws.AttemptStrategy{
+		Min:   3,
+		Delay: 10 * time.Millisecond,
+	})
+	defer s3.SetAttemptStrategy(nil)
+	_, err = testBucket(s.clientTests.s3).List("", "", "", 0)
+	c.Assert(err, ErrorMatches, "The specified bucket does not exist")
+}
+
+// AuthServerSuite runs tests against an s3test server
+// that checks request signatures.
+type AuthServerSuite struct {
+	srv         LocalServer
+	clientTests ClientTests
+}
+
+var _ = Suite(&AuthServerSuite{
+	srv: LocalServer{
+		auth: aws.Auth{AccessKey: "access-key", SecretKey: "secret-key"},
+		config: &s3test.Config{
+			Auth: []aws.Auth{
+				{AccessKey: "other-key", SecretKey: "other-secret"},
+				{AccessKey: "access-key", SecretKey: "secret-key"},
+			},
+		},
+	},
+})
+
+func (s *AuthServerSuite) SetUpSuite(c *C) {
+	s.srv.SetUp(c)
+	s.clientTests.s3 = s3.New(s.srv.auth, s.srv.region)
+	s.clientTests.Cleanup()
+}
+
+func (s *AuthServerSuite) TearDownSuite(c *C) {
+	s.srv.srv.Quit()
+}
+
+func (s *AuthServerSuite) TearDownTest(c *C) {
+	s.clientTests.Cleanup()
+}
+
+func (s *AuthServerSuite) TestBasicFunctionality(c *C) {
+	s.clientTests.TestBasicFunctionality(c)
+}
+
+func (s *AuthServerSuite) s3Error(c *C, err error) *s3.Error {
+	c.Assert(err, NotNil)
+	s3err, ok := err.(*s3.Error)
+	c.Assert(ok, Equals, true, Commentf("unexpected error %#v", err))
+	return s3err
+}
+
+func (s *AuthServerSuite) TestBadCredentials(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	auth := s.srv.auth
+	auth.SecretKey = "wrong"
+	_, err = s3.New(auth, s.srv.region).Bucket(b.Name).List("", "", "", 0)
+	s3err := s.s3Error(c, err)
+	c.Assert(s3err.StatusCode, Equals, 403)
+	c.Assert(s3err.Code, Equals, "SignatureDoesNotMatch")
+
+	auth.AccessKey = "unknown-key"
+	_, err = s3.New(auth, s.srv.region).Bucket(b.Name).List("", "", "", 0)
+	s3err = s.s3Error(c, err)
+	c.Assert(s3err.StatusCode, Equals, 403)
+	c.Assert(s3err.Code, Equals, "InvalidAccessKeyId")
+
+	// A presigned URL must carry a valid signature too.
+	err = b.Put("name", []byte("data"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	defer b.Del("name")
+	u := s3.New(auth, s.srv.region).Bucket(b.Name).SignedURL("name", time.Now().Add(time.Hour))
+	data, err := get(u)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Matches, "(?s).*InvalidAccessKeyId.*")
+}
+
+func (s *AuthServerSuite) TestAnonymousAccess(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = b.Put("private", []byte("data"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	defer b.Del("private")
+
+	resp, err := http.Get(b.URL("private"))
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, 403)
+	c.Assert(string(data), Matches, "(?s).*AccessDenied.*")
+
+	resp, err = http.Get(s.srv.srv.URL() + "/" + b.Name + "/")
+	c.Assert(err, IsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, Equals, 403)
+}
+
+func (s *AuthServerSuite) TestRequestTimeTooSkewed(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+
+	path := "/" + b.Name + "/"
+	headers := map[string][]string{
+		"Date": {time.Now().Add(-time.Hour).UTC().Format(time.RFC1123)},
+	}
+	s3.Sign(s.srv.auth, "GET", path, nil, headers)
+	req, err := http.NewRequest("GET", s.srv.srv.URL()+path, nil)
+	c.Assert(err, IsNil)
+	req.Header = headers
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, IsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, 403)
+	c.Assert(string(data), Matches, "(?s).*RequestTimeTooSkewed.*")
+}
+
+func (s *AuthServerSuite) TestSignV4(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	defer b.Del("name")
+
+	do := func(method, body string, auth aws.Auth, date time.Time) (int, string) {
+		req, err := http.NewRequest(method, s.srv.srv.URL()+"/"+b.Name+"/name", strings.NewReader(body))
+		c.Assert(err, IsNil)
+		req.Header.Set("Host", req.URL.Host)
+		req.Header.Set("X-Amz-Date", date.UTC().Format(aws.ISO8601BasicFormat))
+		err = aws.SignV4(req, auth, s.srv.region.Name)
+		c.Assert(err, IsNil)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, IsNil)
+		return resp.StatusCode, string(data)
+	}
+
+	code, _ := do("PUT", "hello", s.srv.auth, time.Now())
+	c.Assert(code, Equals, 200)
+	code, data := do("GET", "", s.srv.auth, time.Now())
+	c.Assert(code, Equals, 200)
+	c.Assert(data, Equals, "hello")
+
+	bad := s.srv.auth
+	bad.SecretKey = "wrong"
+	code, data = do("GET", "", bad, time.Now())
+	c.Assert(code, Equals, 403)
+	c.Assert(data, Matches, "(?s).*SignatureDoesNotMatch.*")
+
+	code, data = do("GET", "", s.srv.auth, time.Now().Add(time.Hour))
+	c.Assert(code, Equals, 403)
+	c.Assert(data, Matches, "(?s).*RequestTimeTooSkewed.*")
+}
+
+func (s *AuthServerSuite) TestSignV4PayloadHash(c *C) {
+	b := testBucket(s.clientTests.s3)
+	err := b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	defer b.Del("name")
+
+	// put signs a PUT of signed, with the given payload hash, and
+	// then sends body in its place.
+	put := func(signed, body, hash string) (int, string) {
+		req, err := http.NewRequest("PUT", s.srv.srv.URL()+"/"+b.Name+"/name", strings.NewReader(signed))
+		c.Assert(err, IsNil)
+		req.Header.Set("Host", req.URL.Host)
+		req.Header.Set("X-Amz-Date", time.Now().UTC().Format(aws.ISO8601BasicFormat))
+		req.Header.Set("X-Amz-Content-Sha256", hash)
+		err = aws.SignV4(req, s.srv.auth, s.srv.region.Name)
+		c.Assert(err, IsNil)
+		req.Body = ioutil.NopCloser(strings.NewReader(body))
+		req.ContentLength = int64(len(body))
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, IsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, IsNil)
+		return resp.StatusCode, string(data)
+	}
+	hash := func(data string) string {
+		sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(sum[:])
+	}
+
+	code, _ := put("hello", "hello", hash("hello"))
+	c.Assert(code, Equals, 200)
+
+	code, data := put("hello", "goodbye", hash("hello"))
+	c.Assert(code, Equals, 400)
+	c.Assert(data, Matches, "(?s).*XAmzContentSHA256Mismatch.*")
+
+	got, err := b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(got), Equals, "hello")
+}
+
+// FaultSuite checks how the client copes with the
+// faults injected by the s3test server.
+type FaultSuite struct {
+	srv LocalServer
+	b   *s3.Bucket
+}
+
+var _ = Suite(&FaultSuite{})
+
+func (s *FaultSuite) SetUpSuite(c *C) {
+	s.srv.SetUp(c)
+}
+
+func (s *FaultSuite) TearDownSuite(c *C) {
+	s.srv.srv.Quit()
+}
+
+func (s *FaultSuite) SetUpTest(c *C) {
+	s3.SetAttemptStrategy(&aws.AttemptStrategy{
+		Min:   3,
+		Delay: 10 * time.Millisecond,
+	})
+	s.b = testBucket(s3.New(s.srv.auth, s.srv.region))
+	err := s.b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	err = s.b.Put("name", []byte("hello"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+}
+
+func (s *FaultSuite) TearDownTest(c *C) {
+	s.srv.srv.SetConsistency(nil)
+	s.srv.srv.Faults().Reset()
+	s3.SetAttemptStrategy(nil)
+	s.b.Del("name")
+	killBucket(s.b)
+}
+
+func (s *FaultSuite) TestRetryErrors(c *C) {
+	inj := s.srv.srv.Faults()
+	inj.Script("GetBucket", faults.InternalError, faults.SlowDown)
+	resp, err := s.b.List("", "", "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Contents, HasLen, 1)
+	c.Assert(inj.Pending("GetBucket"), Equals, 0)
+}
+
+func (s *FaultSuite) TestRetryBrokenConnections(c *C) {
+	inj := s.srv.srv.Faults()
+	inj.Script("GetBucket", faults.Dropped, faults.Truncated)
+	resp, err := s.b.List("", "", "", 0)
+	c.Assert(err, IsNil)
+	c.Assert(resp.Contents, HasLen, 1)
+	c.Assert(inj.Pending("GetBucket"), Equals, 0)
+
+	inj.Script("DeleteBucket", faults.Dropped)
+	err = s.b.DelBucket()
+	c.Assert(err, ErrorMatches, "The bucket you tried to delete is not empty")
+}
+
+func (s *FaultSuite) TestGiveUp(c *C) {
+	inj := s.srv.srv.Faults()
+	inj.Random(faults.Any, 1, faults.InternalError)
+	_, err := s.b.List("", "", "", 0)
+	c.Assert(err, ErrorMatches, "We encountered an internal error. Please try again.")
+	c.Assert(err.(*s3.Error).StatusCode, Equals, 500)
+
+	// Errors that are not transient are not retried.
+	inj.Reset()
+	inj.Script(faults.Any, faults.Fault{StatusCode: 403, Code: "AccessDenied", Message: "Access Denied"})
+	_, err = s.b.Get("name")
+	c.Assert(err, ErrorMatches, "Access Denied")
+	data, err := s.b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "hello")
+}
+
+func (s *FaultSuite) TestLatency(c *C) {
+	s.srv.srv.Faults().Latency("GetObject", 100*time.Millisecond)
+	t0 := time.Now()
+	data, err := s.b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "hello")
+	c.Assert(time.Since(t0) >= 100*time.Millisecond, Equals, true)
+}
+
+func (s *FaultSuite) TestEventualConsistency(c *C) {
+	consistency := faults.NewConsistency(0, 1)
+	s.srv.srv.SetConsistency(consistency)
+
+	err := s.b.Put("new", []byte("new"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	defer s.b.Del("new")
+	_, err = s.b.Get("new")
+	c.Assert(err, ErrorMatches, "The specified key does not exist.")
+	data, err := s.b.Get("new")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "new")
+
+	err = s.b.Del("name")
+	c.Assert(err, IsNil)
+	data, err = s.b.Get("name")
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "hello")
+	_, err = s.b.Get("name")
+	c.Assert(err, ErrorMatches, "The specified key does not exist.")
+
+	err = s.b.Put("other", []byte("other"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	defer s.b.Del("other")
+	err = s.b.Del("new")
+	c.Assert(err, IsNil)
+	list := func() (keys []string) {
+		resp, err := s.b.List("", "", "", 0)
+		c.Assert(err, IsNil)
+		for _, k := range resp.Contents {
+			keys = append(keys, k.Key)
+		}
+		return keys
+	}
+	c.Assert(list(), DeepEquals, []string{"new"})
+	c.Assert(list(), DeepEquals, []string{"other"})
+
+	err = s.b.Put("settled", []byte("settled"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	defer s.b.Del("settled")
+	consistency.Settle()
+	c.Assert(list(), DeepEquals, []string{"other", "settled"})
+}
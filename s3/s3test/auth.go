@@ -0,0 +1,349 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package s3test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/amz.v1/aws"
+	"gopkg.in/amz.v1/s3"
+)
+
+// maxSkew holds the largest difference between the time of a
+// request and the server clock that S3 accepts.
+const maxSkew = 15 * time.Minute
+
+// subresources holds the query parameters that are part of the
+// resource signed with version 2 signatures (http://goo.gl/G1LrK).
+var subresources = map[string]bool{
+	"acl":                          true,
+	"location":                     true,
+	"logging":                      true,
+	"notification":                 true,
+	"partNumber":                   true,
+	"policy":                       true,
+	"requestPayment":               true,
+	"torrent":                      true,
+	"uploadId":                     true,
+	"uploads":                      true,
+	"versionId":                    true,
+	"versioning":                   true,
+	"versions":                     true,
+	"response-content-type":        true,
+	"response-content-language":    true,
+	"response-expires":             true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+}
+
+// authenticate checks the credentials of req when the server
+// has been configured with any, and fails with the error S3
+// would return if they are not acceptable. It returns whether
+// the request carries no credentials at all, in which case
+// access depends on the ACL of the resource.
+func (srv *Server) authenticate(req *http.Request) (anonymous bool) {
+	if !srv.config.authEnabled() {
+		return false
+	}
+	q := req.URL.Query()
+	h := req.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(h, "AWS4-HMAC-SHA256 "):
+		srv.checkV4(req, parseV4Header(h[len("AWS4-HMAC-SHA256 "):]), false)
+	case strings.HasPrefix(h, "AWS "):
+		srv.checkV2Header(req, h[len("AWS "):])
+	case h != "":
+		fatalf(400, "InvalidArgument", "Unsupported Authorization Type")
+	case q.Get("X-Amz-Algorithm") != "":
+		if q.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+			fatalf(400, "AuthorizationQueryParametersError", "X-Amz-Algorithm only supports \"AWS4-HMAC-SHA256\"")
+		}
+		srv.checkV4(req, map[string]string{
+			"Credential":    q.Get("X-Amz-Credential"),
+			"SignedHeaders": q.Get("X-Amz-SignedHeaders"),
+			"Signature":     q.Get("X-Amz-Signature"),
+		}, true)
+	case q.Get("Signature") != "":
+		srv.checkV2Query(req)
+	default:
+		return true
+	}
+	return false
+}
+
+func (srv *Server) secretKey(accessKey string) string {
+	secret, ok := srv.config.secretKey(accessKey)
+	if !ok {
+		fatalf(403, "InvalidAccessKeyId", "The AWS Access Key Id you provided does not exist in our records.")
+	}
+	return secret
+}
+
+func signatureMismatch() {
+	fatalf(403, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided. Check your key and signing method.")
+}
+
+func checkSkew(t time.Time) {
+	if d := time.Now().Sub(t); d > maxSkew || d < -maxSkew {
+		fatalf(403, "RequestTimeTooSkewed", "The difference between the request time and the current time is too large.")
+	}
+}
+
+func checkExpiry(t time.Time) {
+	if time.Now().After(t) {
+		fatalf(403, "AccessDenied", "Request has expired")
+	}
+}
+
+// checkV2Header checks a version 2 signature held in the
+// Authorization header, which has the form "AccessKey:Signature"
+// once the "AWS " prefix is removed.
+func (srv *Server) checkV2Header(req *http.Request, auth string) {
+	i := strings.LastIndex(auth, ":")
+	if i < 0 {
+		fatalf(400, "InvalidArgument", "AWS authorization header is invalid.  Expected AwsAccessKeyId:signature")
+	}
+	secret := srv.secretKey(auth[:i])
+	date := req.Header.Get("X-Amz-Date")
+	if date == "" {
+		date = req.Header.Get("Date")
+	}
+	t, err := parseTime(date)
+	if err != nil {
+		fatalf(403, "AccessDenied", "AWS authentication requires a valid Date or x-amz-date header")
+	}
+	if !hmac.Equal([]byte(auth[i+1:]), []byte(signV2(secret, req, ""))) {
+		signatureMismatch()
+	}
+	checkSkew(t)
+}
+
+// checkV2Query checks a version 2 signature held in the query
+// string of a presigned URL.
+func (srv *Server) checkV2Query(req *http.Request) {
+	q := req.URL.Query()
+	secret := srv.secretKey(q.Get("AWSAccessKeyId"))
+	expires, err := strconv.ParseInt(q.Get("Expires"), 10, 64)
+	if err != nil {
+		fatalf(403, "AccessDenied", "Query-string authentication requires the Signature, Expires and AWSAccessKeyId parameters")
+	}
+	if !hmac.Equal([]byte(q.Get("Signature")), []byte(signV2(secret, req, q.Get("Expires")))) {
+		signatureMismatch()
+	}
+	checkExpiry(time.Unix(expires, 0))
+}
+
+// signV2 returns the version 2 signature of req. If expires is
+// not empty, the request is a presigned one and expires replaces
+// the date in the string to sign.
+func signV2(secret string, req *http.Request, expires string) string {
+	var amz []string
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-amz-") {
+			amz = append(amz, k+":"+strings.Join(v, ",")+"\n")
+		}
+	}
+	sort.Strings(amz)
+	date := expires
+	if date == "" && req.Header.Get("X-Amz-Date") == "" {
+		date = req.Header.Get("Date")
+	}
+	var sub []string
+	for k, vs := range req.URL.Query() {
+		if !subresources[k] {
+			continue
+		}
+		for _, v := range vs {
+			if v == "" {
+				sub = append(sub, k)
+			} else {
+				sub = append(sub, k+"="+v)
+			}
+		}
+	}
+	resource := req.URL.Path
+	if len(sub) > 0 {
+		sort.Strings(sub)
+		resource += "?" + strings.Join(sub, "&")
+	}
+	payload := req.Method + "\n" +
+		req.Header.Get("Content-MD5") + "\n" +
+		req.Header.Get("Content-Type") + "\n" +
+		date + "\n" +
+		strings.Join(amz, "") +
+		resource
+	hash := hmac.New(sha1.New, []byte(secret))
+	hash.Write([]byte(payload))
+	return base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// parseV4Header parses the comma separated key=value pairs of a
+// version 4 Authorization header.
+func parseV4Header(h string) map[string]string {
+	fields := make(map[string]string)
+	for _, f := range strings.Split(h, ",") {
+		kv := strings.SplitN(strings.TrimSpace(f), "=", 2)
+		if len(kv) != 2 {
+			fatalf(400, "AuthorizationHeaderMalformed", "The authorization header is malformed; the authorization component %q is malformed.", f)
+		}
+		fields[kv[0]] = kv[1]
+	}
+	return fields
+}
+
+// checkV4 checks a version 4 signature, with its components held
+// in fields. If presigned is true they were taken from the query
+// string rather than from the Authorization header.
+func (srv *Server) checkV4(req *http.Request, fields map[string]string, presigned bool) {
+	// The credential has the form AccessKey/date/region/service/aws4_request.
+	cred := strings.Split(fields["Credential"], "/")
+	if len(cred) != 5 || cred[4] != "aws4_request" || fields["SignedHeaders"] == "" || fields["Signature"] == "" {
+		fatalf(400, "AuthorizationHeaderMalformed", "The authorization header is malformed; a non-empty Access Key (AKID), Credential, SignedHeaders and Signature must be provided.")
+	}
+	secret := srv.secretKey(cred[0])
+	q := req.URL.Query()
+	date := q.Get("X-Amz-Date")
+	if !presigned {
+		if date = req.Header.Get("X-Amz-Date"); date == "" {
+			date = req.Header.Get("Date")
+		}
+	}
+	t, err := parseTime(date)
+	if err != nil {
+		fatalf(403, "AccessDenied", "AWS authentication requires a valid Date or x-amz-date header")
+	}
+	if t.Format(aws.ISO8601BasicFormatShort) != cred[1] {
+		signatureMismatch()
+	}
+
+	// The body is hashed unless the client declines to sign it, so
+	// that a body which differs from the hash it was signed with
+	// is rejected.
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	var bodyHash string
+	switch {
+	case presigned:
+		payloadHash = "UNSIGNED-PAYLOAD"
+	case payloadHash != "UNSIGNED-PAYLOAD":
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			fatalf(400, "IncompleteBody", "cannot read request body: %v", err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		bodyHash = hexSHA256(data)
+		if payloadHash == "" {
+			payloadHash = bodyHash
+		}
+	}
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	var headers []string
+	for _, name := range signedHeaders {
+		var value string
+		if name == "host" {
+			value = req.Host
+		} else {
+			value = strings.Join(req.Header[http.CanonicalHeaderKey(name)], ",")
+		}
+		headers = append(headers, name+":"+strings.TrimSpace(value)+"\n")
+	}
+	delete(q, "X-Amz-Signature")
+	canonReq := req.Method + "\n" +
+		req.URL.EscapedPath() + "\n" +
+		canonicalQueryString(q) + "\n" +
+		strings.Join(headers, "") + "\n" +
+		fields["SignedHeaders"] + "\n" +
+		payloadHash
+	scope := strings.Join(cred[1:], "/")
+	strToSign := "AWS4-HMAC-SHA256\n" +
+		t.Format(aws.ISO8601BasicFormat) + "\n" +
+		scope + "\n" +
+		hexSHA256([]byte(canonReq))
+	key := []byte("AWS4" + secret)
+	for _, s := range cred[1:] {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, strToSign))
+	if !hmac.Equal([]byte(fields["Signature"]), []byte(signature)) {
+		signatureMismatch()
+	}
+	if bodyHash != "" && bodyHash != payloadHash {
+		fatalf(400, "XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.")
+	}
+	if presigned {
+		secs, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+		if err != nil || secs < 0 {
+			fatalf(400, "AuthorizationQueryParametersError", "X-Amz-Expires should be a number")
+		}
+		checkExpiry(t.Add(time.Duration(secs) * time.Second))
+	} else {
+		checkSkew(t)
+	}
+}
+
+func canonicalQueryString(q url.Values) string {
+	return strings.Replace(q.Encode(), "+", "%20", -1)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{aws.ISO8601BasicFormat, time.RFC1123, time.RFC1123Z} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return http.ParseTime(s)
+}
+
+// publicAccess reports whether the ACL of the resource r
+// allows anonymous requests with the given method.
+func publicAccess(r resource, method string) bool {
+	switch r := r.(type) {
+	case bucketResource:
+		if r.bucket == nil {
+			return false
+		}
+		switch method {
+		case "GET", "HEAD":
+			return r.bucket.ACL == s3.PublicRead || r.bucket.ACL == s3.PublicReadWrite
+		}
+	case objectResource:
+		switch method {
+		case "GET", "HEAD":
+			if r.object == nil {
+				return false
+			}
+			acl := s3.ACL(r.object.Meta.Get("X-Amz-Acl"))
+			return acl == s3.PublicRead || acl == s3.PublicReadWrite
+		case "PUT", "DELETE":
+			return r.bucket.ACL == s3.PublicReadWrite
+		}
+	}
+	return false
+}
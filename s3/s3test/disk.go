@@ -0,0 +1,170 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package s3test
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// diskStorage implements Storage on top of a directory. Each bucket
+// is kept in a directory of its own, holding its attributes in
+// bucket.json and its objects under the objects directory. As object
+// names may contain any characters, objects are stored in files named
+// after the SHA-1 sum of their name: a .data file with the contents
+// and a .json file with the attributes, including the actual name.
+type diskStorage struct {
+	dir string
+}
+
+// NewDiskStorage returns a Storage that keeps buckets and objects
+// in the given directory, creating it if necessary. Anything stored
+// by an earlier server using the same directory is made available
+// again.
+func NewDiskStorage(dir string) (Storage, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, fmt.Errorf("cannot create storage directory: %v", err)
+	}
+	return &diskStorage{dir}, nil
+}
+
+func (d *diskStorage) bucketDir(name string) string {
+	return filepath.Join(d.dir, name)
+}
+
+func (d *diskStorage) objectPath(bucket, name, ext string) string {
+	return filepath.Join(d.bucketDir(bucket), "objects", fmt.Sprintf("%x%s", sha1.Sum([]byte(name)), ext))
+}
+
+func (d *diskStorage) PutBucket(b *BucketInfo) error {
+	if err := os.MkdirAll(filepath.Join(d.bucketDir(b.Name), "objects"), 0777); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(d.bucketDir(b.Name), "bucket.json"), b)
+}
+
+func (d *diskStorage) Bucket(name string) (*BucketInfo, error) {
+	var b BucketInfo
+	if err := readJSON(filepath.Join(d.bucketDir(name), "bucket.json"), &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (d *diskStorage) DeleteBucket(name string) error {
+	if _, err := d.Bucket(name); err != nil {
+		return err
+	}
+	return os.RemoveAll(d.bucketDir(name))
+}
+
+func (d *diskStorage) PutObject(bucket string, obj *ObjectInfo, data []byte) error {
+	if _, err := d.Bucket(bucket); err != nil {
+		return err
+	}
+	if err := writeFile(d.objectPath(bucket, obj.Name, ".data"), data); err != nil {
+		return err
+	}
+	return writeJSON(d.objectPath(bucket, obj.Name, ".json"), obj)
+}
+
+func (d *diskStorage) Object(bucket, name string) (*ObjectInfo, error) {
+	var obj ObjectInfo
+	if err := readJSON(d.objectPath(bucket, name, ".json"), &obj); err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+func (d *diskStorage) ObjectData(bucket, name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(d.objectPath(bucket, name, ".data"))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (d *diskStorage) DeleteObject(bucket, name string) error {
+	// Remove the attributes first, so that a partially deleted
+	// object is no longer visible.
+	err := os.Remove(d.objectPath(bucket, name, ".json"))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(d.objectPath(bucket, name, ".data"))
+}
+
+func (d *diskStorage) Objects(bucket string) ([]*ObjectInfo, error) {
+	if _, err := d.Bucket(bucket); err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(filepath.Join(d.bucketDir(bucket), "objects"))
+	if err != nil {
+		return nil, err
+	}
+	var objs []*ObjectInfo
+	for _, info := range infos {
+		if !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+		var obj ObjectInfo
+		if err := readJSON(filepath.Join(d.bucketDir(bucket), "objects", info.Name()), &obj); err != nil {
+			return nil, err
+		}
+		objs = append(objs, &obj)
+	}
+	return objs, nil
+}
+
+func readJSON(path string, x interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, x); err != nil {
+		return fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, x interface{}) error {
+	data, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// writeFile writes data to the named file through a temporary file,
+// so that readers never observe partially written contents.
+func writeFile(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
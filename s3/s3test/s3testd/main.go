@@ -0,0 +1,65 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+
+// The s3testd command runs the fake S3 server from the s3test
+// package as a standalone process, so that it can be used by
+// programs outside of Go tests.
+//
+// Usage:
+//
+//	s3testd [-addr host:port] [-dir path] [-auth key:secret,...] [-409]
+//
+// When -dir is given, buckets and objects are kept in that
+// directory and survive restarts of the server; otherwise
+// they are kept in memory. When -auth is given, requests
+// must be signed with one of the listed credentials.
+package main
+
+import (
+	"flag"
+	"log"
+	"strings"
+
+	"gopkg.in/amz.v1/aws"
+	"gopkg.in/amz.v1/s3/s3test"
+)
+
+var (
+	addr     = flag.String("addr", "localhost:0", "address to listen on")
+	dir      = flag.String("dir", "", "directory to store buckets and objects in")
+	auth     = flag.String("auth", "", "comma separated access:secret key pairs to accept")
+	conflict = flag.Bool("409", false, "respond with 409 Conflict to PUT on an existing bucket")
+)
+
+func main() {
+	flag.Parse()
+	config := &s3test.Config{
+		Address:         *addr,
+		Send409Conflict: *conflict,
+	}
+	if *auth != "" {
+		for _, pair := range strings.Split(*auth, ",") {
+			i := strings.Index(pair, ":")
+			if i < 0 {
+				log.Fatalf("invalid credentials %q; expected access:secret", pair)
+			}
+			config.Auth = append(config.Auth, aws.Auth{AccessKey: pair[:i], SecretKey: pair[i+1:]})
+		}
+	}
+	if *dir != "" {
+		storage, err := s3test.NewDiskStorage(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.Storage = storage
+	}
+	srv, err := s3test.NewServer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("serving S3 on %s", srv.URL())
+	select {}
+}
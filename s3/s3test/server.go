@@ -24,7 +24,9 @@ import (
 	"sync"
 	"time"
 
+	"gopkg.in/amz.v1/aws"
 	"gopkg.in/amz.v1/s3"
+	"gopkg.in/amz.v1/testutil/faults"
 )
 
 const debug = false
@@ -56,6 +58,21 @@ type Config struct {
 	// all other regions.
 	// http://docs.amazonwebservices.com/AmazonS3/latest/API/ErrorResponses.html
 	Send409Conflict bool
+
+	// Storage holds the buckets and objects served. If it is nil,
+	// everything is kept in memory and lost when the server quits.
+	Storage Storage
+
+	// Address holds the TCP address the server listens on. It
+	// defaults to a random port on localhost.
+	Address string
+
+	// Auth holds the credentials known to the server. If it is
+	// not empty, requests must be signed with one of them, or be
+	// permitted anonymously by the ACL of the resource, and
+	// presigned URLs expire as they do in S3. Otherwise all
+	// requests are accepted regardless of their credentials.
+	Auth []aws.Auth
 }
 
 func (c *Config) send409Conflict() bool {
@@ -65,30 +82,58 @@ func (c *Config) send409Conflict() bool {
 	return false
 }
 
+func (c *Config) authEnabled() bool {
+	return c != nil && len(c.Auth) > 0
+}
+
+func (c *Config) secretKey(accessKey string) (string, bool) {
+	if c != nil {
+		for _, auth := range c.Auth {
+			if auth.AccessKey == accessKey {
+				return auth.SecretKey, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (c *Config) storage() Storage {
+	if c != nil && c.Storage != nil {
+		return c.Storage
+	}
+	return NewMemoryStorage()
+}
+
+func (c *Config) address() string {
+	if c != nil && c.Address != "" {
+		return c.Address
+	}
+	return "localhost:0"
+}
+
 // Server is a fake S3 server for testing purposes.
-// All of the data for the server is kept in memory.
+// Unless configured otherwise, all of the data for
+// the server is kept in memory.
 type Server struct {
 	url      string
 	reqId    int
 	listener net.Listener
 	mu       sync.Mutex
-	buckets  map[string]*bucket
+	storage  Storage
+	faults   *faults.Injector
 	config   *Config
-}
 
-type bucket struct {
-	name    string
-	acl     s3.ACL
-	ctime   time.Time
-	objects map[string]*object
+	// consistency is nil unless eventual consistency is emulated.
+	// Buckets are keyed by name, and objects by bucket name and
+	// object name joined by a slash. Deleted buckets linger as
+	// *BucketInfo and deleted objects as *deletedObject.
+	consistency *faults.Consistency
 }
 
-type object struct {
-	name     string
-	mtime    time.Time
-	meta     http.Header // metadata to return with requests.
-	checksum []byte      // also held as Content-MD5 in meta.
-	data     []byte
+// deletedObject holds an object whose deletion has not yet settled.
+type deletedObject struct {
+	info *ObjectInfo
+	data []byte
 }
 
 // A resource encapsulates the subject of an HTTP request.
@@ -102,14 +147,15 @@ type resource interface {
 }
 
 func NewServer(config *Config) (*Server, error) {
-	l, err := net.Listen("tcp", "localhost:0")
+	l, err := net.Listen("tcp", config.address())
 	if err != nil {
-		return nil, fmt.Errorf("cannot listen on localhost: %v", err)
+		return nil, fmt.Errorf("cannot listen on %s: %v", config.address(), err)
 	}
 	srv := &Server{
 		listener: l,
 		url:      "http://" + l.Addr().String(),
-		buckets:  make(map[string]*bucket),
+		storage:  config.storage(),
+		faults:   faults.NewInjector(),
 		config:   config,
 	}
 	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -128,6 +174,43 @@ func (srv *Server) URL() string {
 	return srv.url
 }
 
+// Faults returns the injector deciding the faults injected into
+// requests. Actions are named after the HTTP method and the kind
+// of resource requested, as in "GetObject" or "PutBucket".
+func (srv *Server) Faults() *faults.Injector {
+	return srv.faults
+}
+
+// SetConsistency makes the server emulate eventual consistency as
+// decided by c: new buckets and objects are not found until their
+// creation settles, and deleted ones are still found until their
+// deletion does. Overwriting an existing object takes effect
+// immediately. If c is nil, the server is strongly consistent.
+func (srv *Server) SetConsistency(c *faults.Consistency) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	srv.consistency = c
+}
+
+// actionName returns the name under which faults are
+// injected into req.
+func actionName(req *http.Request) string {
+	kind := "Service"
+	if m := pathRegexp.FindStringSubmatch(req.URL.Path); m != nil {
+		switch {
+		case m[4] != "":
+			kind = "Object"
+		case m[2] != "":
+			kind = "Bucket"
+		}
+	}
+	method := strings.ToLower(req.Method)
+	if method != "" {
+		method = strings.ToUpper(method[:1]) + method[1:]
+	}
+	return method + kind
+}
+
 func fatalf(code int, codeStr string, errf string, a ...interface{}) {
 	panic(&s3Error{
 		statusCode: code,
@@ -136,11 +219,68 @@ func fatalf(code int, codeStr string, errf string, a ...interface{}) {
 	})
 }
 
+// storageError reports an unexpected error from the server storage.
+func storageError(err error) {
+	fatalf(500, "InternalError", "storage error: %v", err)
+}
+
+// bucket returns the named bucket, or nil if it does not exist.
+func (srv *Server) bucket(name string) *BucketInfo {
+	b, err := srv.storage.Bucket(name)
+	if err == ErrNotFound {
+		if v, ok := srv.consistency.Lingering(name); ok {
+			return v.(*BucketInfo)
+		}
+		return nil
+	}
+	if err != nil {
+		storageError(err)
+	}
+	if srv.consistency.Hidden(name) {
+		return nil
+	}
+	return b
+}
+
+// object returns the named object, or nil if it does not exist.
+// If the deletion of the object has not yet settled, its contents
+// are returned as well, as the storage does not hold them anymore.
+func (srv *Server) object(bucket, name string) (*ObjectInfo, *deletedObject) {
+	key := bucket + "/" + name
+	obj, err := srv.storage.Object(bucket, name)
+	if err == ErrNotFound {
+		if v, ok := srv.consistency.Lingering(key); ok {
+			d := v.(*deletedObject)
+			return d.info, d
+		}
+		return nil, nil
+	}
+	if err != nil {
+		storageError(err)
+	}
+	if srv.consistency.Hidden(key) {
+		return nil, nil
+	}
+	return obj, nil
+}
+
 // serveHTTP serves the S3 protocol.
 func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 	// ignore error from ParseForm as it's usually spurious.
 	req.ParseForm()
 
+	fault := srv.faults.Next(actionName(req))
+	time.Sleep(fault.Delay)
+	if fault.Drop {
+		faults.Drop(w)
+		return
+	}
+	if fault.Truncate {
+		tw := faults.NewTruncatingWriter(w)
+		defer tw.Close()
+		w = tw
+	}
+
 	srv.mu.Lock()
 	defer srv.mu.Unlock()
 
@@ -161,7 +301,7 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		case *s3Error:
 			switch r := r.(type) {
 			case objectResource:
-				err.BucketName = r.bucket.name
+				err.BucketName = r.bucket.Name
 			case bucketResource:
 				err.BucketName = r.name
 			}
@@ -176,7 +316,14 @@ func (srv *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 	}()
 
+	if fault.IsError() {
+		fatalf(fault.StatusCode, fault.Code, "%s", fault.Message)
+	}
+	anonymous := srv.authenticate(req)
 	r = srv.resourceForURL(req.URL)
+	if anonymous && !publicAccess(r, req.Method) {
+		fatalf(403, "AccessDenied", "Access Denied")
+	}
 
 	var resp interface{}
 	switch req.Method {
@@ -243,7 +390,7 @@ func (srv *Server) resourceForURL(u *url.URL) (r resource) {
 	}
 	b := bucketResource{
 		name:   bucketName,
-		bucket: srv.buckets[bucketName],
+		bucket: srv.bucket(bucketName),
 	}
 	q := u.Query()
 	if objectName == "" {
@@ -268,9 +415,7 @@ func (srv *Server) resourceForURL(u *url.URL) (r resource) {
 			return nullResource{}
 		}
 	}
-	if obj := objr.bucket.objects[objr.name]; obj != nil {
-		objr.object = obj
-	}
+	objr.object, objr.deleted = srv.object(bucketName, objectName)
 	return objr
 }
 
@@ -291,7 +436,7 @@ const timeFormat = "2006-01-02T15:04:05.000Z07:00"
 
 type bucketResource struct {
 	name   string
-	bucket *bucket // non-nil if the bucket already exists.
+	bucket *BucketInfo // non-nil if the bucket already exists.
 }
 
 // GET on a bucket lists the objects in the bucket.
@@ -317,21 +462,33 @@ func (r bucketResource) get(a *action) interface{} {
 		return nil
 	}
 
+	// The bucket may have been deleted already if it still lingers.
+	all, err := a.srv.storage.Objects(r.name)
+	if err != nil && err != ErrNotFound {
+		storageError(err)
+	}
 	var objs orderedObjects
 
 	// first get all matching objects and arrange them in alphabetical order.
-	for name, obj := range r.bucket.objects {
-		if strings.HasPrefix(name, prefix) {
+	for _, obj := range all {
+		if strings.HasPrefix(obj.Name, prefix) && !a.srv.consistency.Hidden(r.name+"/"+obj.Name) {
 			objs = append(objs, obj)
 		}
 	}
+	for _, key := range a.srv.consistency.Deletions(r.name + "/") {
+		if v, ok := a.srv.consistency.Lingering(key); ok {
+			if obj := v.(*deletedObject).info; strings.HasPrefix(obj.Name, prefix) {
+				objs = append(objs, obj)
+			}
+		}
+	}
 	sort.Sort(objs)
 
 	if maxKeys <= 0 {
 		maxKeys = 1000
 	}
 	resp := &s3.ListResp{
-		Name:      r.bucket.name,
+		Name:      r.bucket.Name,
 		Prefix:    prefix,
 		Delimiter: delimiter,
 		Marker:    marker,
@@ -340,14 +497,14 @@ func (r bucketResource) get(a *action) interface{} {
 
 	var prefixes []string
 	for _, obj := range objs {
-		if !strings.HasPrefix(obj.name, prefix) {
+		if !strings.HasPrefix(obj.Name, prefix) {
 			continue
 		}
-		name := obj.name
+		name := obj.Name
 		isPrefix := false
 		if delimiter != "" {
-			if i := strings.Index(obj.name[len(prefix):], delimiter); i >= 0 {
-				name = obj.name[:len(prefix)+i+len(delimiter)]
+			if i := strings.Index(obj.Name[len(prefix):], delimiter); i >= 0 {
+				name = obj.Name[:len(prefix)+i+len(delimiter)]
 				if prefixes != nil && prefixes[len(prefixes)-1] == name {
 					continue
 				}
@@ -365,7 +522,7 @@ func (r bucketResource) get(a *action) interface{} {
 			prefixes = append(prefixes, name)
 		} else {
 			// Contents contains only keys not found in CommonPrefixes
-			resp.Contents = append(resp.Contents, obj.s3Key())
+			resp.Contents = append(resp.Contents, s3Key(obj))
 		}
 	}
 	resp.CommonPrefixes = prefixes
@@ -374,7 +531,7 @@ func (r bucketResource) get(a *action) interface{} {
 
 // orderedObjects holds a slice of objects that can be sorted
 // by name.
-type orderedObjects []*object
+type orderedObjects []*ObjectInfo
 
 func (s orderedObjects) Len() int {
 	return len(s)
@@ -383,15 +540,15 @@ func (s orderedObjects) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 func (s orderedObjects) Less(i, j int) bool {
-	return s[i].name < s[j].name
+	return s[i].Name < s[j].Name
 }
 
-func (obj *object) s3Key() s3.Key {
+func s3Key(obj *ObjectInfo) s3.Key {
 	return s3.Key{
-		Key:          obj.name,
-		LastModified: obj.mtime.Format(timeFormat),
-		Size:         int64(len(obj.data)),
-		ETag:         fmt.Sprintf(`"%x"`, obj.checksum),
+		Key:          obj.Name,
+		LastModified: obj.Mtime.Format(timeFormat),
+		Size:         obj.Size,
+		ETag:         fmt.Sprintf(`"%x"`, obj.Checksum),
 		// TODO StorageClass
 		// TODO Owner
 	}
@@ -399,14 +556,23 @@ func (obj *object) s3Key() s3.Key {
 
 // DELETE on a bucket deletes the bucket if it's not empty.
 func (r bucketResource) delete(a *action) interface{} {
-	b := r.bucket
-	if b == nil {
+	if r.bucket == nil {
 		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
 	}
-	if len(b.objects) > 0 {
+	objs, err := a.srv.storage.Objects(r.name)
+	if err == ErrNotFound {
+		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
+	}
+	if err != nil {
+		storageError(err)
+	}
+	if len(objs) > 0 {
 		fatalf(400, "BucketNotEmpty", "The bucket you tried to delete is not empty")
 	}
-	delete(a.srv.buckets, b.name)
+	if err := a.srv.storage.DeleteBucket(r.name); err != nil {
+		storageError(err)
+	}
+	a.srv.consistency.Deleted(r.name, r.bucket)
 	return nil
 }
 
@@ -422,18 +588,23 @@ func (r bucketResource) put(a *action) interface{} {
 			fatalf(400, "InvalidRequets", "The unspecified location constraint is incompatible for the region specific endpoint this request was sent to.")
 		}
 		// TODO validate acl
-		r.bucket = &bucket{
-			name: r.name,
+		r.bucket = &BucketInfo{
+			Name:  r.name,
+			Ctime: time.Now(),
 			// TODO default acl
-			objects: make(map[string]*object),
 		}
-		a.srv.buckets[r.name] = r.bucket
 		created = true
 	}
 	if !created && a.srv.config.send409Conflict() {
 		fatalf(409, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it.")
 	}
-	r.bucket.acl = s3.ACL(a.req.Header.Get("x-amz-acl"))
+	r.bucket.ACL = s3.ACL(a.req.Header.Get("x-amz-acl"))
+	if err := a.srv.storage.PutBucket(r.bucket); err != nil {
+		storageError(err)
+	}
+	if created {
+		a.srv.consistency.Created(r.name)
+	}
 	return nil
 }
 
@@ -491,8 +662,9 @@ var responseParams = map[string]bool{
 type objectResource struct {
 	name    string
 	version string
-	bucket  *bucket // always non-nil.
-	object  *object // may be nil.
+	bucket  *BucketInfo    // always non-nil.
+	object  *ObjectInfo    // may be nil.
+	deleted *deletedObject // non-nil if the object's deletion has not settled.
 }
 
 // GET on an object gets the contents of the object.
@@ -504,7 +676,7 @@ func (objr objectResource) get(a *action) interface{} {
 	}
 	h := a.w.Header()
 	// add metadata
-	for name, d := range obj.meta {
+	for name, d := range obj.Meta {
 		h[name] = d
 	}
 	// override header values in response to request parameters.
@@ -527,14 +699,24 @@ func (objr objectResource) get(a *action) interface{} {
 	// TODO If-None-Match
 	// TODO Connection: close ??
 	// TODO x-amz-request-id
-	h.Set("Content-Length", fmt.Sprint(len(obj.data)))
-	h.Set("ETag", hex.EncodeToString(obj.checksum))
-	h.Set("Last-Modified", obj.mtime.Format(time.RFC1123))
+	h.Set("Content-Length", fmt.Sprint(obj.Size))
+	h.Set("ETag", hex.EncodeToString(obj.Checksum))
+	h.Set("Last-Modified", obj.Mtime.Format(time.RFC1123))
 	if a.req.Method == "HEAD" {
 		return nil
 	}
+	var data []byte
+	if objr.deleted != nil {
+		data = objr.deleted.data
+	} else {
+		var err error
+		data, err = a.srv.storage.ObjectData(objr.bucket.Name, obj.Name)
+		if err != nil {
+			storageError(err)
+		}
+	}
 	// TODO avoid holding the lock when writing data.
-	_, err := a.w.Write(obj.data)
+	_, err := a.w.Write(data)
 	if err != nil {
 		// we can't do much except just log the fact.
 		log.Printf("error writing data: %v", err)
@@ -544,7 +726,7 @@ func (objr objectResource) get(a *action) interface{} {
 
 var metaHeaders = map[string]bool{
 	"Content-MD5":         true,
-	"x-amz-acl":           true,
+	"X-Amz-Acl":           true,
 	"Content-Type":        true,
 	"Content-Encoding":    true,
 	"Content-Disposition": true,
@@ -559,12 +741,14 @@ func (objr objectResource) put(a *action) interface{} {
 
 	// TODO is this correct, or should we erase all previous metadata?
 	obj := objr.object
-	if obj == nil {
-		obj = &object{
-			name: objr.name,
-			meta: make(http.Header),
+	if obj == nil || objr.deleted != nil {
+		obj = &ObjectInfo{
+			Name: objr.name,
 		}
 	}
+	if obj.Meta == nil {
+		obj.Meta = make(http.Header)
+	}
 
 	var expectHash []byte
 	if c := a.req.Header.Get("Content-MD5"); c != "" {
@@ -592,18 +776,38 @@ func (objr objectResource) put(a *action) interface{} {
 	for key, values := range a.req.Header {
 		key = http.CanonicalHeaderKey(key)
 		if metaHeaders[key] || strings.HasPrefix(key, "X-Amz-Meta-") {
-			obj.meta[key] = values
+			obj.Meta[key] = values
 		}
 	}
-	obj.data = data
-	obj.checksum = gotHash
-	obj.mtime = time.Now()
-	objr.bucket.objects[objr.name] = obj
+	obj.Size = int64(len(data))
+	obj.Checksum = gotHash
+	obj.Mtime = time.Now()
+	switch err := a.srv.storage.PutObject(objr.bucket.Name, obj, data); err {
+	case nil:
+	case ErrNotFound:
+		// The bucket was deleted, but it still lingers.
+		fatalf(404, "NoSuchBucket", "The specified bucket does not exist")
+	default:
+		storageError(err)
+	}
+	if objr.object == nil || objr.deleted != nil {
+		a.srv.consistency.Created(objr.bucket.Name + "/" + objr.name)
+	}
 	return nil
 }
 
 func (objr objectResource) delete(a *action) interface{} {
-	delete(objr.bucket.objects, objr.name)
+	if objr.object != nil && objr.deleted == nil && a.srv.consistency != nil {
+		data, err := a.srv.storage.ObjectData(objr.bucket.Name, objr.name)
+		if err != nil {
+			storageError(err)
+		}
+		a.srv.consistency.Deleted(objr.bucket.Name+"/"+objr.name, &deletedObject{objr.object, data})
+	}
+	err := a.srv.storage.DeleteObject(objr.bucket.Name, objr.name)
+	if err != nil && err != ErrNotFound {
+		storageError(err)
+	}
 	return nil
 }
 
@@ -0,0 +1,172 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package s3test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"gopkg.in/amz.v1/s3"
+)
+
+// ErrNotFound is returned by Storage methods when the requested
+// bucket or object does not exist.
+var ErrNotFound = errors.New("not found")
+
+// BucketInfo holds the attributes of a bucket kept in a Storage.
+type BucketInfo struct {
+	Name  string
+	ACL   s3.ACL
+	Ctime time.Time
+}
+
+// ObjectInfo holds the attributes of an object kept in a Storage.
+type ObjectInfo struct {
+	Name     string
+	Mtime    time.Time
+	Meta     http.Header // metadata to return with requests.
+	Checksum []byte      // also held as Content-MD5 in Meta.
+	Size     int64
+}
+
+// Storage holds the buckets and objects served by a Server.
+// The server serializes all calls, so implementations need not
+// be safe for concurrent use by more than one server.
+type Storage interface {
+	// PutBucket creates the bucket described by b, or updates
+	// its attributes if it already exists.
+	PutBucket(b *BucketInfo) error
+
+	// Bucket returns the named bucket.
+	Bucket(name string) (*BucketInfo, error)
+
+	// DeleteBucket removes the named bucket and anything in it.
+	DeleteBucket(name string) error
+
+	// PutObject stores obj along with its data in the named
+	// bucket, replacing any object with the same name.
+	PutObject(bucket string, obj *ObjectInfo, data []byte) error
+
+	// Object returns the attributes of the named object.
+	Object(bucket, name string) (*ObjectInfo, error)
+
+	// ObjectData returns the contents of the named object.
+	ObjectData(bucket, name string) ([]byte, error)
+
+	// DeleteObject removes the named object.
+	DeleteObject(bucket, name string) error
+
+	// Objects returns all objects in the named bucket, in no
+	// particular order.
+	Objects(bucket string) ([]*ObjectInfo, error)
+}
+
+// NewMemoryStorage returns a Storage that keeps everything in memory.
+// It is used by servers with no other storage configured.
+func NewMemoryStorage() Storage {
+	return memoryStorage{}
+}
+
+type memoryStorage map[string]*memoryBucket
+
+type memoryBucket struct {
+	info    BucketInfo
+	objects map[string]*memoryObject
+}
+
+type memoryObject struct {
+	info ObjectInfo
+	data []byte
+}
+
+func (m memoryStorage) PutBucket(b *BucketInfo) error {
+	if mb := m[b.Name]; mb != nil {
+		mb.info = *b
+		return nil
+	}
+	m[b.Name] = &memoryBucket{
+		info:    *b,
+		objects: make(map[string]*memoryObject),
+	}
+	return nil
+}
+
+func (m memoryStorage) Bucket(name string) (*BucketInfo, error) {
+	mb := m[name]
+	if mb == nil {
+		return nil, ErrNotFound
+	}
+	info := mb.info
+	return &info, nil
+}
+
+func (m memoryStorage) DeleteBucket(name string) error {
+	if m[name] == nil {
+		return ErrNotFound
+	}
+	delete(m, name)
+	return nil
+}
+
+func (m memoryStorage) PutObject(bucket string, obj *ObjectInfo, data []byte) error {
+	mb := m[bucket]
+	if mb == nil {
+		return ErrNotFound
+	}
+	mb.objects[obj.Name] = &memoryObject{*obj, data}
+	return nil
+}
+
+func (m memoryStorage) object(bucket, name string) (*memoryObject, error) {
+	mb := m[bucket]
+	if mb == nil {
+		return nil, ErrNotFound
+	}
+	mo := mb.objects[name]
+	if mo == nil {
+		return nil, ErrNotFound
+	}
+	return mo, nil
+}
+
+func (m memoryStorage) Object(bucket, name string) (*ObjectInfo, error) {
+	mo, err := m.object(bucket, name)
+	if err != nil {
+		return nil, err
+	}
+	info := mo.info
+	return &info, nil
+}
+
+func (m memoryStorage) ObjectData(bucket, name string) ([]byte, error) {
+	mo, err := m.object(bucket, name)
+	if err != nil {
+		return nil, err
+	}
+	return mo.data, nil
+}
+
+func (m memoryStorage) DeleteObject(bucket, name string) error {
+	if _, err := m.object(bucket, name); err != nil {
+		return err
+	}
+	delete(m[bucket].objects, name)
+	return nil
+}
+
+func (m memoryStorage) Objects(bucket string) ([]*ObjectInfo, error) {
+	mb := m[bucket]
+	if mb == nil {
+		return nil, ErrNotFound
+	}
+	objs := make([]*ObjectInfo, 0, len(mb.objects))
+	for _, mo := range mb.objects {
+		info := mo.info
+		objs = append(objs, &info)
+	}
+	return objs, nil
+}
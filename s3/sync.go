@@ -0,0 +1,503 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package s3
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncOp identifies the kind of change a sync action makes.
+type SyncOp string
+
+const (
+	// SyncPut uploads a local file to a key in the bucket.
+	SyncPut SyncOp = "put"
+	// SyncGet downloads a key in the bucket to a local file.
+	SyncGet SyncOp = "get"
+	// SyncDel deletes an extraneous key from the bucket.
+	SyncDel SyncOp = "del"
+	// SyncRemove removes an extraneous local file.
+	SyncRemove SyncOp = "remove"
+)
+
+// SyncOptions holds the options for synchronizing a local directory
+// with a bucket prefix. A nil *SyncOptions is equivalent to the zero
+// value.
+type SyncOptions struct {
+	// Delete causes keys (when syncing up) or local files (when
+	// syncing down) that have no counterpart on the other side
+	// to be removed.
+	Delete bool
+
+	// Include and Exclude hold path.Match patterns selecting
+	// which files take part in the sync. Patterns are matched
+	// against the slash-separated path relative to the directory
+	// (or the key relative to the prefix) and, for patterns
+	// without a slash, against its base name as well. If Include
+	// is not empty, only matching paths are considered. Exclude
+	// takes precedence over Include.
+	Include []string
+	Exclude []string
+
+	// Checksum causes files of equal size to be compared by their
+	// MD5 sum rather than by modification time. Keys uploaded in
+	// multiple parts have no usable MD5 ETag, and fall back to
+	// the modification time comparison.
+	Checksum bool
+
+	// Concurrency holds the maximum number of transfers run at
+	// once. It defaults to 4.
+	Concurrency int
+
+	// ACL is used for uploaded objects. It defaults to Private.
+	ACL ACL
+}
+
+// SyncAction describes a single change made by a sync.
+type SyncAction struct {
+	Op     SyncOp
+	Key    string // full key in the bucket.
+	Path   string // full path of the local file.
+	Size   int64  // size of the data transferred, if any.
+	Reason string // why the action is needed ("new", "size", ...).
+
+	mtime time.Time // modification time of the key, for downloads.
+}
+
+// SyncPlan holds the set of actions required to bring one side of a
+// sync in line with the other. A plan may be inspected before
+// applying it, which allows for dry runs.
+type SyncPlan struct {
+	Actions []SyncAction
+
+	bucket *Bucket
+	opts   SyncOptions
+}
+
+const defaultSyncConcurrency = 4
+
+// syncEntry holds what is known about a file or key on either side
+// of a sync, indexed by its path relative to the sync root.
+type syncEntry struct {
+	size  int64
+	mtime time.Time
+	etag  string // remote entries only, without quotes.
+	path  string // local entries only.
+	key   string // remote entries only.
+}
+
+// PlanSyncUp returns the plan for making the keys under prefix in b
+// mirror the files under the local directory dir, without applying
+// it. See SyncUp.
+func (b *Bucket) PlanSyncUp(dir, prefix string, opts *SyncOptions) (*SyncPlan, error) {
+	plan, local, remote, err := b.syncState(dir, prefix, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range sortedSyncNames(local) {
+		l := local[rel]
+		r, ok := remote[rel]
+		reason := "new"
+		if ok {
+			reason, err = plan.opts.differs(l, r, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if reason != "" {
+			plan.Actions = append(plan.Actions, SyncAction{
+				Op:     SyncPut,
+				Key:    syncKey(prefix, rel),
+				Path:   l.path,
+				Size:   l.size,
+				Reason: reason,
+			})
+		}
+	}
+	if plan.opts.Delete {
+		for _, rel := range sortedSyncNames(remote) {
+			if _, ok := local[rel]; !ok {
+				plan.Actions = append(plan.Actions, SyncAction{
+					Op:     SyncDel,
+					Key:    remote[rel].key,
+					Reason: "extraneous",
+				})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// PlanSyncDown returns the plan for making the files under the local
+// directory dir mirror the keys under prefix in b, without applying
+// it. See SyncDown.
+func (b *Bucket) PlanSyncDown(dir, prefix string, opts *SyncOptions) (*SyncPlan, error) {
+	plan, local, remote, err := b.syncState(dir, prefix, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range sortedSyncNames(remote) {
+		r := remote[rel]
+		l, ok := local[rel]
+		reason := "new"
+		if ok {
+			reason, err = plan.opts.differs(l, r, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if reason != "" {
+			plan.Actions = append(plan.Actions, SyncAction{
+				Op:     SyncGet,
+				Key:    r.key,
+				Path:   filepath.Join(dir, filepath.FromSlash(rel)),
+				Size:   r.size,
+				Reason: reason,
+				mtime:  r.mtime,
+			})
+		}
+	}
+	if plan.opts.Delete {
+		for _, rel := range sortedSyncNames(local) {
+			if _, ok := remote[rel]; !ok {
+				plan.Actions = append(plan.Actions, SyncAction{
+					Op:     SyncRemove,
+					Path:   local[rel].path,
+					Reason: "extraneous",
+				})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// SyncUp uploads the files under the local directory dir that are
+// missing or differ from the keys under prefix in b. A file differs
+// when its size is not the same as the key's, or, depending on
+// opts.Checksum, when its MD5 sum does not match the key's ETag or
+// it was modified after the key. If opts.Delete is set, keys under
+// prefix with no matching local file are deleted. The applied plan
+// is returned.
+//
+// A non-empty prefix is always treated as a directory, so "site" and
+// "site/" are equivalent.
+func (b *Bucket) SyncUp(dir, prefix string, opts *SyncOptions) (*SyncPlan, error) {
+	plan, err := b.PlanSyncUp(dir, prefix, opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, plan.Apply()
+}
+
+// SyncDown downloads the keys under prefix in b that are missing or
+// differ from the files under the local directory dir, creating
+// directories as necessary. Downloaded files have their modification
+// time set to the key's. If opts.Delete is set, local files with no
+// matching key are removed. The applied plan is returned.
+func (b *Bucket) SyncDown(dir, prefix string, opts *SyncOptions) (*SyncPlan, error) {
+	plan, err := b.PlanSyncDown(dir, prefix, opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, plan.Apply()
+}
+
+// Apply performs the actions in the plan, running up to
+// opts.Concurrency of them at once. It returns the first error
+// encountered, if any, after all started actions have finished.
+func (p *SyncPlan) Apply() error {
+	n := p.opts.Concurrency
+	if n <= 0 {
+		n = defaultSyncConcurrency
+	}
+	actions := make(chan SyncAction)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range actions {
+				if err := p.apply(a); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, a := range p.Actions {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		actions <- a
+	}
+	close(actions)
+	wg.Wait()
+	return firstErr
+}
+
+func (p *SyncPlan) apply(a SyncAction) error {
+	var err error
+	switch a.Op {
+	case SyncPut:
+		err = p.put(a)
+	case SyncGet:
+		err = p.get(a)
+	case SyncDel:
+		err = p.bucket.Del(a.Key)
+	case SyncRemove:
+		err = os.Remove(a.Path)
+	default:
+		err = fmt.Errorf("unknown operation")
+	}
+	if err != nil {
+		target := a.Key
+		if a.Op == SyncGet || a.Op == SyncRemove {
+			target = a.Path
+		}
+		return fmt.Errorf("cannot %s %q: %v", a.Op, target, err)
+	}
+	return nil
+}
+
+func (p *SyncPlan) put(a SyncAction) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	contType := mime.TypeByExtension(path.Ext(a.Key))
+	if contType == "" {
+		contType = "application/octet-stream"
+	}
+	return p.bucket.PutReader(a.Key, f, info.Size(), contType, p.opts.ACL)
+}
+
+func (p *SyncPlan) get(a SyncAction) error {
+	dir := filepath.Dir(a.Path)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	rc, err := p.bucket.GetReader(a.Key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// Write to a temporary file first so that an interrupted
+	// download never leaves a truncated file behind.
+	f, err := ioutil.TempFile(dir, ".goamz-sync-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, rc)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), a.Path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	// Keep the key's modification time, so the file is not
+	// considered changed by a later sync in either direction.
+	return os.Chtimes(a.Path, a.mtime, a.mtime)
+}
+
+// syncState returns an empty plan along with the entries found
+// locally under dir and remotely under prefix, both filtered
+// according to opts.
+func (b *Bucket) syncState(dir, prefix string, opts *SyncOptions) (plan *SyncPlan, local, remote map[string]*syncEntry, err error) {
+	plan = &SyncPlan{bucket: b}
+	if opts != nil {
+		plan.opts = *opts
+	}
+	if plan.opts.ACL == "" {
+		plan.opts.ACL = Private
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if local, err = plan.opts.localEntries(dir); err != nil {
+		return nil, nil, nil, err
+	}
+	if remote, err = plan.opts.remoteEntries(b, prefix); err != nil {
+		return nil, nil, nil, err
+	}
+	return plan, local, remote, nil
+}
+
+func (o *SyncOptions) localEntries(dir string) (map[string]*syncEntry, error) {
+	entries := make(map[string]*syncEntry)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				// Nothing there yet, which is fine when syncing down.
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if o.selected(rel) {
+			entries[rel] = &syncEntry{
+				size:  info.Size(),
+				mtime: info.ModTime(),
+				path:  p,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (o *SyncOptions) remoteEntries(b *Bucket, prefix string) (map[string]*syncEntry, error) {
+	entries := make(map[string]*syncEntry)
+	marker := ""
+	for {
+		resp, err := b.List(prefix, "", marker, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range resp.Contents {
+			marker = key.Key
+			rel := key.Key[len(prefix):]
+			if rel == "" || strings.HasSuffix(rel, "/") {
+				// Directory placeholders have no local counterpart.
+				continue
+			}
+			if clean := path.Clean(rel); clean != rel || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+				return nil, fmt.Errorf("key %q cannot be mapped to a local path", key.Key)
+			}
+			if !o.selected(rel) {
+				continue
+			}
+			mtime, err := time.Parse(time.RFC3339Nano, key.LastModified)
+			if err != nil {
+				return nil, fmt.Errorf("bad modification time for key %q: %v", key.Key, err)
+			}
+			entries[rel] = &syncEntry{
+				size:  key.Size,
+				mtime: mtime,
+				etag:  strings.Trim(key.ETag, `"`),
+				key:   key.Key,
+			}
+		}
+		if !resp.IsTruncated || len(resp.Contents) == 0 {
+			return entries, nil
+		}
+	}
+}
+
+// selected reports whether the relative path rel passes the include
+// and exclude patterns.
+func (o *SyncOptions) selected(rel string) bool {
+	if syncMatch(o.Exclude, rel) {
+		return false
+	}
+	return len(o.Include) == 0 || syncMatch(o.Include, rel)
+}
+
+func syncMatch(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// differs returns the reason why the local and remote entries are
+// considered different, or the empty string if they are not. When
+// up is true the local side is the source, otherwise the remote one.
+func (o *SyncOptions) differs(local, remote *syncEntry, up bool) (string, error) {
+	if local.size != remote.size {
+		return "size", nil
+	}
+	// ETags of multipart uploads are not MD5 sums of the content.
+	if o.Checksum && !strings.Contains(remote.etag, "-") {
+		sum, err := fileMD5(local.path)
+		if err != nil {
+			return "", err
+		}
+		if sum != remote.etag {
+			return "checksum", nil
+		}
+		return "", nil
+	}
+	if up && local.mtime.After(remote.mtime) || !up && remote.mtime.After(local.mtime) {
+		return "mtime", nil
+	}
+	return "", nil
+}
+
+func fileMD5(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func syncKey(prefix, rel string) string {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix + rel
+}
+
+func sortedSyncNames(entries map[string]*syncEntry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
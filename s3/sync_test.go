@@ -0,0 +1,241 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package s3_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"gopkg.in/amz.v1/aws"
+	"gopkg.in/amz.v1/s3"
+)
+
+// SyncSuite tests directory synchronization against the local
+// s3test server.
+type SyncSuite struct {
+	srv LocalServer
+	b   *s3.Bucket
+	dir string
+}
+
+var _ = Suite(&SyncSuite{})
+
+func (s *SyncSuite) SetUpSuite(c *C) {
+	s.srv.SetUp(c)
+}
+
+func (s *SyncSuite) TearDownSuite(c *C) {
+	s.srv.srv.Quit()
+}
+
+func (s *SyncSuite) SetUpTest(c *C) {
+	s.b = testBucket(s3.New(s.srv.auth, s.srv.region))
+	err := s.b.PutBucket(s3.Private)
+	c.Assert(err, IsNil)
+	s.dir = c.MkDir()
+}
+
+func (s *SyncSuite) TearDownTest(c *C) {
+	killBucket(s.b)
+}
+
+func (s *SyncSuite) writeFiles(c *C, files map[string]string) {
+	// Make the files look older than anything in the bucket.
+	old := time.Now().Add(-time.Hour)
+	for name, data := range files {
+		p := filepath.Join(s.dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(p), 0777)
+		c.Assert(err, IsNil)
+		err = ioutil.WriteFile(p, []byte(data), 0666)
+		c.Assert(err, IsNil)
+		err = os.Chtimes(p, old, old)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *SyncSuite) keys(c *C) map[string]string {
+	resp, err := s.b.List("", "", "", 0)
+	c.Assert(err, IsNil)
+	keys := make(map[string]string)
+	for _, k := range resp.Contents {
+		data, err := s.b.Get(k.Key)
+		c.Assert(err, IsNil)
+		keys[k.Key] = string(data)
+	}
+	return keys
+}
+
+func summary(plan *s3.SyncPlan) []string {
+	var ops []string
+	for _, a := range plan.Actions {
+		name := a.Key
+		if a.Op == s3.SyncRemove {
+			name = filepath.Base(a.Path)
+		}
+		ops = append(ops, string(a.Op)+" "+name+" "+a.Reason)
+	}
+	return ops
+}
+
+func (s *SyncSuite) TestSyncUp(c *C) {
+	s.writeFiles(c, map[string]string{
+		"index.html":     "hello",
+		"css/site.css":   "body {}",
+		"img/logo.png":   "png",
+		"img/logo.png~":  "backup",
+		"drafts/post.md": "draft",
+	})
+	err := s.b.Put("site/stale.html", []byte("gone"), "text/html", s3.Private)
+	c.Assert(err, IsNil)
+	err = s.b.Put("other/keep", []byte("keep"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+
+	opts := &s3.SyncOptions{
+		Delete:  true,
+		Exclude: []string{"*~", "drafts/*"},
+	}
+	plan, err := s.b.PlanSyncUp(s.dir, "site", opts)
+	c.Assert(err, IsNil)
+	c.Assert(summary(plan), DeepEquals, []string{
+		"put site/css/site.css new",
+		"put site/img/logo.png new",
+		"put site/index.html new",
+		"del site/stale.html extraneous",
+	})
+	// Planning alone changes nothing.
+	c.Assert(s.keys(c), HasLen, 2)
+
+	err = plan.Apply()
+	c.Assert(err, IsNil)
+	c.Assert(s.keys(c), DeepEquals, map[string]string{
+		"site/css/site.css": "body {}",
+		"site/img/logo.png": "png",
+		"site/index.html":   "hello",
+		"other/keep":        "keep",
+	})
+
+	// Nothing left to do the second time around.
+	plan, err = s.b.SyncUp(s.dir, "site/", opts)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Actions, HasLen, 0)
+
+	// A changed size is noticed even when the file looks older.
+	s.writeFiles(c, map[string]string{"index.html": "hello, world"})
+	plan, err = s.b.SyncUp(s.dir, "site", opts)
+	c.Assert(err, IsNil)
+	c.Assert(summary(plan), DeepEquals, []string{"put site/index.html size"})
+
+	// A modification with the same size is found by mtime...
+	p := filepath.Join(s.dir, "img", "logo.png")
+	err = ioutil.WriteFile(p, []byte("gif"), 0666)
+	c.Assert(err, IsNil)
+	future := time.Now().Add(time.Hour)
+	err = os.Chtimes(p, future, future)
+	c.Assert(err, IsNil)
+	plan, err = s.b.PlanSyncUp(s.dir, "site", opts)
+	c.Assert(err, IsNil)
+	c.Assert(summary(plan), DeepEquals, []string{"put site/img/logo.png mtime"})
+
+	// ... or by checksum, whatever the mtime.
+	s.writeFiles(c, map[string]string{"img/logo.png": "gif"})
+	checksum := *opts
+	checksum.Checksum = true
+	plan, err = s.b.SyncUp(s.dir, "site", &checksum)
+	c.Assert(err, IsNil)
+	c.Assert(summary(plan), DeepEquals, []string{"put site/img/logo.png checksum"})
+	c.Assert(s.keys(c)["site/img/logo.png"], Equals, "gif")
+}
+
+func (s *SyncSuite) TestSyncDown(c *C) {
+	for key, data := range map[string]string{
+		"site/index.html":    "hello",
+		"site/css/site.css":  "body {}",
+		"site/js/app.js":     "js",
+		"site/dir/":          "",
+		"sitemap/index.html": "not under prefix",
+	} {
+		err := s.b.Put(key, []byte(data), "text/plain", s3.Private)
+		c.Assert(err, IsNil)
+	}
+	s.writeFiles(c, map[string]string{
+		"index.html": "stale!",
+		"extra.txt":  "extra",
+	})
+
+	opts := &s3.SyncOptions{
+		Delete:      true,
+		Include:     []string{"*.html", "*.css", "*.txt"},
+		Concurrency: 2,
+	}
+	plan, err := s.b.SyncDown(s.dir, "site", opts)
+	c.Assert(err, IsNil)
+	c.Assert(summary(plan), DeepEquals, []string{
+		"get site/css/site.css new",
+		"get site/index.html size",
+		"remove extra.txt extraneous",
+	})
+
+	data, err := ioutil.ReadFile(filepath.Join(s.dir, "index.html"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "hello")
+	data, err = ioutil.ReadFile(filepath.Join(s.dir, "css", "site.css"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "body {}")
+	_, err = os.Stat(filepath.Join(s.dir, "extra.txt"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+	_, err = os.Stat(filepath.Join(s.dir, "js"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+
+	// Downloaded files carry the key's modification time, so
+	// neither direction sees any difference afterwards.
+	plan, err = s.b.PlanSyncDown(s.dir, "site", opts)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Actions, HasLen, 0)
+	plan, err = s.b.PlanSyncUp(s.dir, "site", opts)
+	c.Assert(err, IsNil)
+	c.Assert(plan.Actions, HasLen, 0)
+}
+
+func (s *SyncSuite) TestSyncDownNewDirectory(c *C) {
+	err := s.b.Put("a/b/c", []byte("data"), "text/plain", s3.Private)
+	c.Assert(err, IsNil)
+	dir := filepath.Join(s.dir, "new")
+	plan, err := s.b.SyncDown(dir, "", nil)
+	c.Assert(err, IsNil)
+	c.Assert(summary(plan), DeepEquals, []string{"get a/b/c new"})
+	data, err := ioutil.ReadFile(filepath.Join(dir, "a", "b", "c"))
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "data")
+}
+
+func (s *SyncSuite) TestSyncDownBadKey(c *C) {
+	for _, key := range []string{"site/..", "site/.", "site/../x", "site/a//b"} {
+		err := s.b.Put(key, []byte("data"), "text/plain", s3.Private)
+		c.Assert(err, IsNil)
+		_, err = s.b.PlanSyncDown(s.dir, "site/", nil)
+		c.Check(err, ErrorMatches, fmt.Sprintf("key %q cannot be mapped to a local path", key))
+		err = s.b.Del(key)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *SyncSuite) TestSyncUpError(c *C) {
+	s3.SetAttemptStrategy(&aws.AttemptStrategy{
+		Min:   3,
+		Delay: 10 * time.Millisecond,
+	})
+	defer s3.SetAttemptStrategy(nil)
+	s.writeFiles(c, map[string]string{"file": "data"})
+	err := s.b.DelBucket()
+	c.Assert(err, IsNil)
+	_, err = s.b.SyncUp(s.dir, "", nil)
+	c.Assert(err, ErrorMatches, "The specified bucket does not exist")
+}
@@ -0,0 +1,142 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+package faults
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Consistency emulates the eventual consistency of AWS services in
+// the fake servers. Changes recorded with Created and Deleted only
+// become visible to readers once they settle, that is after the
+// configured delay has passed or the entity has been read the
+// configured number of times, whichever comes first. Until then,
+// created entities are hidden and deleted ones linger.
+//
+// Entities are identified by keys chosen by each server. A nil
+// *Consistency is strongly consistent: every change settles
+// immediately.
+type Consistency struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	reads   int
+	changes map[string]*change
+}
+
+type change struct {
+	deleted bool
+	value   interface{}
+	time    time.Time
+	reads   int
+}
+
+// NewConsistency returns a Consistency where changes settle after
+// delay, or after being read reads times. A zero value disables
+// the corresponding condition; if both are zero, changes never
+// settle unless Settle is called.
+func NewConsistency(delay time.Duration, reads int) *Consistency {
+	return &Consistency{
+		delay:   delay,
+		reads:   reads,
+		changes: make(map[string]*change),
+	}
+}
+
+// Created records the creation of the entity with the given key.
+func (c *Consistency) Created(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes[key] = &change{time: time.Now()}
+}
+
+// Deleted records the deletion of the entity with the given key,
+// which lingers with the given value until the change settles.
+func (c *Consistency) Deleted(key string, value interface{}) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes[key] = &change{deleted: true, value: value, time: time.Now()}
+}
+
+// Settle makes all pending changes visible.
+func (c *Consistency) Settle() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = make(map[string]*change)
+}
+
+// read counts a read of key and returns its pending change, or nil
+// if it has settled. It must be called with c.mu held.
+func (c *Consistency) read(key string) *change {
+	ch := c.changes[key]
+	if ch == nil {
+		return nil
+	}
+	if c.delay > 0 && time.Since(ch.time) >= c.delay || c.reads > 0 && ch.reads >= c.reads {
+		delete(c.changes, key)
+		return nil
+	}
+	ch.reads++
+	return ch
+}
+
+// Hidden reports whether the creation of the entity with the given
+// key has not yet settled, counting a read of the entity.
+func (c *Consistency) Hidden(key string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := c.read(key)
+	return ch != nil && !ch.deleted
+}
+
+// Lingering returns the value recorded with the deletion of the
+// entity with the given key, and whether the deletion has not yet
+// settled, counting a read of the entity.
+func (c *Consistency) Lingering(key string) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := c.read(key)
+	if ch == nil || !ch.deleted {
+		return nil, false
+	}
+	return ch.value, true
+}
+
+// Deletions returns the sorted keys with the given prefix of the
+// entities whose deletion has not yet settled, without counting
+// any reads.
+func (c *Consistency) Deletions(prefix string) []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []string
+	for key, ch := range c.changes {
+		if ch.deleted && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
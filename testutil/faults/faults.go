@@ -0,0 +1,267 @@
+//
+// goamz - Go packages to interact with the Amazon Web Services.
+//
+// https://wiki.ubuntu.com/goamz
+//
+
+// The faults package implements fault injection for the fake
+// servers in s3test, ec2test and iamtest. Tests use it to make
+// chosen requests fail in the ways real AWS endpoints do, so that
+// the retry logic of the clients can be exercised.
+//
+// Faults are chosen per action: the EC2 or IAM action name
+// (for example "RunInstances"), or for S3 the HTTP method followed
+// by "Service", "Bucket" or "Object" (for example "PutObject" or
+// "GetBucket"). The action "*" matches any request.
+//
+// The package also emulates the eventual consistency of the
+// services; see Consistency.
+package faults
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Any matches requests for any action.
+const Any = "*"
+
+// Fault describes what goes wrong with a request.
+// The zero Fault lets the request be served normally.
+type Fault struct {
+	// Delay holds a time to wait before serving the request.
+	Delay time.Duration
+
+	// If Code is not empty, the request fails with an error
+	// holding Code, Message and StatusCode, formatted as
+	// the server's service does, without being carried out.
+	Code       string
+	Message    string
+	StatusCode int
+
+	// Drop causes the connection to be closed before anything
+	// is written in response, without carrying the request out.
+	Drop bool
+
+	// Truncate causes the request to be carried out, but the
+	// connection is closed when only half of the response body
+	// has been written.
+	Truncate bool
+}
+
+// IsError returns whether f makes the request fail with an error.
+func (f Fault) IsError() bool {
+	return f.Code != ""
+}
+
+var (
+	// InternalError is returned by all services on internal
+	// failures.
+	InternalError = Fault{
+		StatusCode: 500,
+		Code:       "InternalError",
+		Message:    "We encountered an internal error. Please try again.",
+	}
+
+	// SlowDown is returned by S3 when the request rate is too high.
+	SlowDown = Fault{
+		StatusCode: 503,
+		Code:       "SlowDown",
+		Message:    "Please reduce your request rate.",
+	}
+
+	// RequestLimitExceeded is returned by EC2 when the request
+	// rate is too high.
+	RequestLimitExceeded = Fault{
+		StatusCode: 503,
+		Code:       "RequestLimitExceeded",
+		Message:    "Request limit exceeded.",
+	}
+
+	// Dropped closes the connection without responding.
+	Dropped = Fault{Drop: true}
+
+	// Truncated closes the connection half way through the
+	// response body.
+	Truncated = Fault{Truncate: true}
+)
+
+type rule struct {
+	p     float64
+	fault Fault
+}
+
+// Injector decides the faults injected into the requests served by
+// a fake server. Scripted faults take precedence over random ones,
+// and faults for a specific action over those for Any. The zero value
+// is not usable; a nil *Injector injects no faults.
+type Injector struct {
+	mu      sync.Mutex
+	rand    *rand.Rand
+	scripts map[string][]Fault
+	rules   map[string][]rule
+	latency map[string]time.Duration
+}
+
+// NewInjector returns a new Injector with no faults configured.
+// Random faults are drawn from a source with a fixed seed, so that
+// tests are reproducible; see Seed.
+func NewInjector() *Injector {
+	inj := &Injector{rand: rand.New(rand.NewSource(1))}
+	inj.Reset()
+	return inj
+}
+
+// Reset removes all configured faults.
+func (inj *Injector) Reset() {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.scripts = make(map[string][]Fault)
+	inj.rules = make(map[string][]rule)
+	inj.latency = make(map[string]time.Duration)
+}
+
+// Seed seeds the source used to choose random faults.
+func (inj *Injector) Seed(seed int64) {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.rand.Seed(seed)
+}
+
+// Script appends faults to the sequence applied to the following
+// requests for action, one fault per request. Use the zero Fault
+// to let a request in the sequence through.
+func (inj *Injector) Script(action string, faults ...Fault) {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.scripts[action] = append(inj.scripts[action], faults...)
+}
+
+// Random makes requests for action fail with f with probability p.
+// When several random faults apply, the first one drawn wins.
+func (inj *Injector) Random(action string, p float64, f Fault) {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.rules[action] = append(inj.rules[action], rule{p, f})
+}
+
+// Latency delays all requests for action by d, in addition to the
+// delay of any fault injected.
+func (inj *Injector) Latency(action string, d time.Duration) {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	inj.latency[action] = d
+}
+
+// Pending returns the number of scripted faults that have not yet
+// been applied to requests for action.
+func (inj *Injector) Pending(action string) int {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return len(inj.scripts[action])
+}
+
+// Next returns the fault to inject into the next request for
+// action. It is called by the fake servers.
+func (inj *Injector) Next(action string) Fault {
+	if inj == nil {
+		return Fault{}
+	}
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	f, ok := inj.scripted(action)
+	if !ok {
+		f, ok = inj.scripted(Any)
+	}
+	if !ok {
+		f = inj.random(action, Any)
+	}
+	if d, ok := inj.latency[action]; ok {
+		f.Delay += d
+	} else {
+		f.Delay += inj.latency[Any]
+	}
+	return f
+}
+
+func (inj *Injector) scripted(action string) (Fault, bool) {
+	script := inj.scripts[action]
+	if len(script) == 0 {
+		return Fault{}, false
+	}
+	inj.scripts[action] = script[1:]
+	return script[0], true
+}
+
+func (inj *Injector) random(actions ...string) Fault {
+	for _, action := range actions {
+		for _, r := range inj.rules[action] {
+			if inj.rand.Float64() < r.p {
+				return r.fault
+			}
+		}
+	}
+	return Fault{}
+}
+
+// Drop closes the connection underlying w without writing
+// any response.
+func Drop(w http.ResponseWriter) {
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		panic(fmt.Errorf("cannot hijack connection: %v", err))
+	}
+	conn.Close()
+}
+
+// TruncatingWriter is an http.ResponseWriter that holds back the
+// response written to it, so that it can be sent incomplete.
+type TruncatingWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// NewTruncatingWriter returns a writer that sends what is written
+// to it through w, truncated, when Close is called.
+func NewTruncatingWriter(w http.ResponseWriter) *TruncatingWriter {
+	return &TruncatingWriter{
+		w:      w,
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (tw *TruncatingWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *TruncatingWriter) WriteHeader(status int) {
+	tw.status = status
+}
+
+func (tw *TruncatingWriter) Write(data []byte) (int, error) {
+	return tw.body.Write(data)
+}
+
+// Close sends the response written so far, announcing its full
+// length but including only the first half of its body, and
+// closes the connection.
+func (tw *TruncatingWriter) Close() error {
+	conn, buf, err := tw.w.(http.Hijacker).Hijack()
+	if err != nil {
+		return fmt.Errorf("cannot hijack connection: %v", err)
+	}
+	defer conn.Close()
+	tw.header.Set("Content-Length", fmt.Sprint(tw.body.Len()))
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", tw.status, http.StatusText(tw.status))
+	tw.header.Write(buf)
+	buf.WriteString("\r\n")
+	buf.Write(tw.body.Bytes()[:tw.body.Len()/2])
+	return buf.Flush()
+}